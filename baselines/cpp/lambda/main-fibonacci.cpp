@@ -1,10 +1,16 @@
 #include <aws/lambda-runtime/runtime.h>
+#include <cctype>
+#include <cmath>
+#include <cstdlib>
 #include <string>
 
 using namespace aws::lambda_runtime;
 
 // Fibonacci recursive implementation
 // Source: ruchy-book bench-007-fibonacci.c
+// Input: {"n": 0..40}, default 35.
+static const long DEFAULT_N = 35, MIN_N = 0, MAX_N = 40;
+
 int fibonacci(int n) {
     if (n <= 1) {
         return n;
@@ -12,13 +18,103 @@ int fibonacci(int n) {
     return fibonacci(n - 1) + fibonacci(n - 2);
 }
 
-static invocation_response handler(invocation_request const&)
+// input reads n from a payload of {}, {"n": <integer>} or null. The runtime
+// links no JSON library, so anything else is refused rather than parsed.
+// An integral number such as 35.0 is an integer, as in the other
+// baselines.
+static bool input(std::string const& payload, long& n, std::string& error)
 {
-    // Calculate fibonacci(35) - standard Lambda benchmark
-    int result = fibonacci(35);
+    size_t i = 0;
+    auto skip = [&]() { while (i < payload.size() && std::isspace(static_cast<unsigned char>(payload[i]))) i++; };
+    auto expect = [&](std::string const& token) {
+        skip();
+        if (payload.compare(i, token.size(), token) != 0) {
+            return false;
+        }
+        i += token.size();
+        return true;
+    };
+    n = DEFAULT_N;
+    skip();
+    if (i == payload.size() || expect("null")) {
+        skip();
+        if (i != payload.size()) {
+            error = "payload is not a JSON object";
+            return false;
+        }
+        return true;
+    }
+    if (!expect("{")) {
+        error = "payload is not a JSON object";
+        return false;
+    }
+    if (!expect("}")) {
+        if (!expect("\"n\"") || !expect(":")) {
+            error = "payload must be {} or {\"n\": <integer>}";
+            return false;
+        }
+        skip();
+        // Take the characters a JSON number can have, so strtod does not
+        // read hex, inf or nan.
+        size_t len = 0;
+        while (i + len < payload.size() && std::string("+-.0123456789eE").find(payload[i + len]) != std::string::npos) {
+            len++;
+        }
+        std::string number = payload.substr(i, len);
+        char* end = nullptr;
+        double v = std::strtod(number.c_str(), &end);
+        if (number.empty() || !(number[0] == '-' || std::isdigit(static_cast<unsigned char>(number[0])))
+            || end != number.c_str() + len || v != std::trunc(v)) {
+            error = "n must be an integer";
+            return false;
+        }
+        if (v < MIN_N || v > MAX_N) {
+            error = "n must be between " + std::to_string(MIN_N) + " and " + std::to_string(MAX_N);
+            return false;
+        }
+        n = static_cast<long>(v);
+        i += len;
+        if (!expect("}")) {
+            error = "payload must be {} or {\"n\": <integer>}";
+            return false;
+        }
+    }
+    skip();
+    if (i != payload.size()) {
+        error = "payload is not a JSON object";
+        return false;
+    }
+    return true;
+}
+
+// respond returns the {"statusCode", "body", "runtime"} object the other
+// baselines answer with. Bodies are built here, so escaping quotes and
+// backslashes is enough.
+static invocation_response respond(int status, std::string const& body)
+{
+    std::string quoted;
+    for (char c : body) {
+        if (c == '"' || c == '\\') {
+            quoted += '\\';
+        }
+        quoted += c;
+    }
+    return invocation_response::success(
+        "{\"statusCode\":" + std::to_string(status) + ",\"body\":\"" + quoted + "\",\"runtime\":\"cpp\"}",
+        "application/json");
+}
+
+static invocation_response handler(invocation_request const& request)
+{
+    // Calculate fibonacci(n), 35 unless the payload says otherwise - standard Lambda benchmark
+    long n;
+    std::string error;
+    if (!input(request.payload, n, error)) {
+        return respond(400, error);
+    }
+    int result = fibonacci(static_cast<int>(n));
 
-    std::string response = "fibonacci(35)=" + std::to_string(result);
-    return invocation_response::success(response, "text/plain");
+    return respond(200, "fibonacci(" + std::to_string(n) + ")=" + std::to_string(result));
 }
 
 int main()
@@ -1,16 +1,30 @@
 #!/bin/bash
 # Build Go baseline Lambda function
 # Source: lambda-perf go_on_provided_al2023
+# Usage: ./build.sh [handler.go] [amd64|arm64]
 
 set -euo pipefail
 
-echo "🔨 Building Go baseline Lambda..."
+readonly HANDLER="${1:-main.go}"
+readonly ARCH="${2:-amd64}"
 
-# Build for Linux ARM64 (or x86_64)
-GOOS=linux GOARCH=amd64 go build -tags lambda.norpc -o bootstrap main.go
+case "$ARCH" in
+    amd64) PACKAGE="function.zip" ;;
+    arm64) PACKAGE="function-arm64.zip" ;;
+    *)
+        echo "❌ Unknown architecture: $ARCH (expected amd64 or arm64)" >&2
+        exit 1
+        ;;
+esac
+
+echo "🔨 Building Go baseline Lambda ($HANDLER, linux/$ARCH)..."
+
+# Build for Linux x86_64 (amd64) or Graviton (arm64)
+GOOS=linux GOARCH="$ARCH" CGO_ENABLED=0 go build -tags lambda.norpc -o bootstrap "$HANDLER"
 
 # Create deployment package
-zip function.zip bootstrap
+rm -f "$PACKAGE"
+zip "$PACKAGE" bootstrap
 
-echo "✅ Go baseline built: function.zip"
-echo "Binary size: $(ls -lh function.zip | awk '{print $5}')"
+echo "✅ Go baseline built: $PACKAGE"
+echo "Binary size: $(ls -lh "$PACKAGE" | awk '{print $5}')"
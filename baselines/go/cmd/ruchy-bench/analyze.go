@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/sink"
+	"lambdaperf/pkg/stats"
+)
+
+func runAnalyze(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench analyze [flags] <run-id | results.json>")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	var db string
+	registerDB(fs, &db)
+	metricList := fs.String("metric", "", "comma-separated metrics to summarize (default: each result's headline metric)")
+	percentiles := fs.String("percentiles", "50,90,95,99", "comma-separated percentiles to report")
+	samplesOut := fs.String("samples", "", "also write every raw sample to this CSV file, one row per sample and metric")
+	out := fs.String("out", "", "also write the run, summarized afresh, to this results file (the history keeps the original)")
+	byCPU := fs.Bool("by-cpu", false, "split each result by the CPU generation of the hosts its samples ran on")
+	var sf statsFlags
+	sf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// Accept flags on either side of the run.
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("analyze needs a run ID or results file")
+	}
+	ref := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	ps, err := parsePercentiles(*percentiles)
+	if err != nil {
+		return err
+	}
+
+	run, err := loadRun(ctx, *root, db, ref)
+	if err != nil {
+		return err
+	}
+	opts := sf.options()
+	run.Summarize(opts)
+	if *byCPU {
+		var split []results.Result
+		for _, r := range run.Results {
+			split = append(split, r.ByCPU(opts)...)
+		}
+		run.Results = split
+	}
+	preferred := ""
+	if run.Mode == "coldstart" {
+		preferred = results.MetricInit
+	}
+	fmt.Printf("%s (%s, %d results), outliers: %s\n\n", run.ID, run.Mode, len(run.Results), describePolicy(sf, opts))
+	printAnalysis(run, splitList(*metricList), preferred, ps, opts)
+
+	if *samplesOut != "" {
+		if err := (sink.Samples{Path: *samplesOut}).Write(ctx, run); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "samples written to", *samplesOut)
+	}
+	if *out != "" {
+		if err := results.Write(*out, run); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "results written to", *out)
+	}
+	return nil
+}
+
+// loadRun reads ref as a results file when it names one, and otherwise
+// loads the stored run with that ID from the history database.
+func loadRun(ctx context.Context, root, db, ref string) (*results.Run, error) {
+	if _, err := os.Stat(ref); err == nil {
+		return results.Read(ref)
+	}
+	if root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		if root, err = discover.FindRoot(wd); err != nil {
+			return nil, err
+		}
+	}
+	s, err := openStore(ctx, root, db)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	return s.Run(ctx, ref)
+}
+
+func parsePercentiles(s string) ([]float64, error) {
+	var ps []float64
+	for _, v := range splitList(s) {
+		p, err := strconv.ParseFloat(v, 64)
+		if err != nil || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile %q: want 0-100", v)
+		}
+		ps = append(ps, p)
+	}
+	if len(ps) == 0 {
+		return nil, errors.New("-percentiles needs at least one percentile")
+	}
+	return ps, nil
+}
+
+// describePolicy names the outlier policy opts applies, with its
+// parameter.
+func describePolicy(sf statsFlags, opts stats.Options) string {
+	switch {
+	case opts.RejectOutliers:
+		return fmt.Sprintf("mad, modified z-score above %g", sf.madThreshold)
+	case opts.IQRFactor > 0:
+		return fmt.Sprintf("iqr, beyond %g interquartile ranges", opts.IQRFactor)
+	case opts.Trim > 0:
+		return fmt.Sprintf("trim, %g%% from each end", 100*opts.Trim)
+	}
+	return "none"
+}
+
+// printAnalysis shows each result's metrics summarized under opts, with
+// the percentiles ps of the values opts kept.
+func printAnalysis(run *results.Run, metrics []string, preferred string, ps []float64, opts stats.Options) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := []string{"KIND", "RUNTIME", "WORKLOAD", "ARCH", "MEMORY(MB)", "METRIC", "N", "REJECTED", "MEAN", "CI95", "STDDEV"}
+	for _, p := range ps {
+		header = append(header, "P"+strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, r := range run.Results {
+		arch, memory := r.Arch, "-"
+		if arch == "" {
+			arch = "-"
+		}
+		if m := r.Memory(); m > 0 {
+			memory = strconv.Itoa(int(m))
+		}
+		row := fmt.Sprintf("%s\t%s\t%s\t%s\t%s", r.Kind, runtimeLabel(r), r.Workload, arch, memory)
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\terror: %s\n", row, r.Error)
+			continue
+		}
+		ms := metrics
+		if len(ms) == 0 {
+			ms = []string{headlineMetric(r, preferred)}
+		}
+		for _, m := range ms {
+			s, ok := r.Stats[m]
+			if !ok {
+				fmt.Fprintf(w, "%s\t%s\t0\n", row, m)
+				continue
+			}
+			kept, _ := stats.Reject(r.Values(m), opts)
+			slices.Sort(kept)
+			cols := []string{row, m, strconv.Itoa(s.N), strconv.Itoa(s.Rejected), fmt.Sprintf("%.2f", s.Mean),
+				fmt.Sprintf("[%.2f, %.2f]", s.CILow, s.CIHigh), fmt.Sprintf("%.2f", s.StdDev)}
+			for _, p := range ps {
+				cols = append(cols, fmt.Sprintf("%.2f", stats.Percentile(kept, p)))
+			}
+			fmt.Fprintln(w, strings.Join(cols, "\t"))
+		}
+	}
+	w.Flush()
+}
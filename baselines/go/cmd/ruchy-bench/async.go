@@ -0,0 +1,280 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"lambdaperf/pkg/async"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/queue"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// asyncPoll is the pause between reads of the markers and the dead-letter
+// queue.
+const asyncPoll = 5 * time.Second
+
+func runAsync(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("async", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	n := fs.Int("events", 100, "events to submit per target")
+	fail := fs.Float64("fail", 0, "fraction of events the handler fails on every attempt, so Lambda retries and then dead-letters them")
+	retries := fs.Int("retries", async.MaxRetries, fmt.Sprintf("retries Lambda makes of a failed event, 0 to %d", async.MaxRetries))
+	bucket := fs.String("bucket", "", "bucket the handler writes its markers to (default: the fixture bucket ruchy-bench seed creates)")
+	dlq := fs.String("dlq", async.DefaultDLQ, "dead-letter queue of failed events, created by ruchy-bench seed")
+	timeout := fs.Duration("timeout", 10*time.Minute, "how long to wait for every event of a target to complete or be dead-lettered")
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return errors.New("-events must be at least 1")
+	}
+	if *fail < 0 || *fail > 1 {
+		return errors.New("-fail must be between 0 and 1")
+	}
+	if *retries < 0 || *retries > async.MaxRetries {
+		return fmt.Errorf("-retries must be between 0 and %d", async.MaxRetries)
+	}
+	if tf.snapStart {
+		return errors.New("the asynchronous invocation configuration here is $LATEST's; async does not support -snapstart")
+	}
+	tf.kind = string(discover.KindLambda)
+	if tf.workloads == "" {
+		tf.workloads = async.Workload
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	if *bucket, err = fixtureBucket(ctx, cfg, *bucket); err != nil {
+		return err
+	}
+	qc := &queue.Client{Config: cfg}
+	dlqURL, err := qc.URL(ctx, *dlq)
+	if err != nil {
+		return fmt.Errorf("%w (create it with ruchy-bench seed -workload async)", err)
+	}
+	dlqARN, err := qc.ARN(ctx, dlqURL)
+	if err != nil {
+		return err
+	}
+	client := lambda.NewFromConfig(cfg)
+	s3Client := s3.NewFromConfig(cfg)
+
+	run := results.NewRun("async", time.Now())
+	for _, t := range targets {
+		res := newResult(t)
+		res.Input = map[string]int{"events": *n, "retries": *retries}
+		fmt.Fprintf(os.Stderr, "%s: retrying failed events %d times, then sending them to %s\n", t.ID(), *retries, *dlq)
+		err := deploy.ConfigureAsync(ctx, client, res.Function, int32(*retries), dlqARN)
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "%s: %d events\n", t.ID(), *n)
+			res.Samples, err = asyncSamples(ctx, client, s3Client, qc, dlqURL, res.Function, async.Events(run.ID, *n, *fail, *bucket), *timeout)
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		run.Results = append(run.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printAsync(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return ctx.Err()
+}
+
+// asyncSamples submits events to fn as asynchronous invocations and
+// follows them through their markers and the dead-letter queue until each
+// has completed or been dead-lettered, or timeout has passed, returning a
+// sample per event. A completed event's client_ms is the time from its
+// submission to the end of the attempt that completed it; a dead-lettered
+// one's, the time until Lambda gave up on it.
+func asyncSamples(ctx context.Context, client *lambda.Client, s3Client *s3.Client, qc *queue.Client, dlqURL, fn string, events []async.Event, timeout time.Duration) ([]results.Sample, error) {
+	submitted := make([]time.Time, len(events))
+	for i, e := range events {
+		submitted[i] = time.Now()
+		if _, err := client.Invoke(ctx, &lambda.InvokeInput{
+			FunctionName:   aws.String(fn),
+			InvocationType: types.InvocationTypeEvent,
+			Payload:        e.Payload(),
+		}); err != nil {
+			return nil, fmt.Errorf("submit event %d: %w", i, err)
+		}
+	}
+
+	tr := async.NewTracker(events[0].Run, fn, len(events))
+	bucket, prefix := events[0].Bucket, async.Prefix(events[0].Run, fn)
+	read := map[string]bool{}
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(asyncPoll):
+		}
+		pages := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{Bucket: aws.String(bucket), Prefix: aws.String(prefix)})
+		for pages.HasMorePages() {
+			page, err := pages.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("list markers in s3://%s/%s: %w", bucket, prefix, err)
+			}
+			for _, obj := range page.Contents {
+				key := aws.ToString(obj.Key)
+				if read[key] {
+					continue
+				}
+				m, err := readMarker(ctx, s3Client, bucket, key)
+				if err != nil {
+					return nil, err
+				}
+				tr.AddMarker(m)
+				read[key] = true
+			}
+		}
+		if err := drainDeadLetters(ctx, qc, dlqURL, tr); err != nil {
+			return nil, err
+		}
+		pending := tr.Pending()
+		if pending == 0 || time.Now().After(deadline) {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d of %d events pending\n", fn, pending, len(events))
+	}
+
+	samples := make([]results.Sample, len(events))
+	for i := range samples {
+		attempts := tr.Attempts[i]
+		s := results.Sample{Iteration: i, Deliveries: len(attempts)}
+		since := func(ms int64) float64 { return float64(ms - submitted[i].UnixMilli()) }
+		if len(attempts) > 0 {
+			first := slices.MinFunc(attempts, func(a, b async.Marker) int { return cmp.Compare(a.StartedMS, b.StartedMS) })
+			s.QueuedMS = since(first.StartedMS)
+		}
+		if m, ok := tr.Completed(i); ok {
+			s.ClientMS, s.RequestID = since(m.FinishedMS), m.RequestID
+		} else if d := tr.DeadLetters[i]; d != nil {
+			s.DeadLetter, s.RequestID = d, d.RequestID
+			s.ClientMS = since(d.At.UnixMilli())
+			s.Error = fmt.Sprintf("dead-lettered after %d attempts: %s", d.Attempts, d.Condition)
+		} else {
+			s.Error = fmt.Sprintf("not completed within %s after %d attempts", timeout, s.Deliveries)
+		}
+		samples[i] = s
+	}
+	return samples, nil
+}
+
+// readMarker downloads and decodes the marker at key.
+func readMarker(ctx context.Context, client *s3.Client, bucket, key string) (async.Marker, error) {
+	obj, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return async.Marker{}, fmt.Errorf("get marker s3://%s/%s: %w", bucket, key, err)
+	}
+	defer obj.Body.Close()
+	var m async.Marker
+	if err := json.NewDecoder(obj.Body).Decode(&m); err != nil {
+		return async.Marker{}, fmt.Errorf("decode marker s3://%s/%s: %w", bucket, key, err)
+	}
+	return m, nil
+}
+
+// drainDeadLetters adds the records waiting in the dead-letter queue to
+// tr, deleting every record it reads: those of other runs or functions
+// are left over from earlier ones.
+func drainDeadLetters(ctx context.Context, qc *queue.Client, dlqURL string, tr *async.Tracker) error {
+	for {
+		msgs, err := qc.Receive(ctx, dlqURL, queue.MaxBatch, 0)
+		if err != nil || len(msgs) == 0 {
+			return err
+		}
+		for _, m := range msgs {
+			if d, err := async.ParseDeadLetter(m.Body); err == nil {
+				tr.AddDeadLetter(d)
+			}
+			if err := qc.DeleteMessage(ctx, dlqURL, m.ReceiptHandle); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// printAsync shows per function the end-to-end latency of completed
+// events, how long events queued before their first attempt, how many
+// needed a retry, and how many were dead-lettered and how long that took.
+func printAsync(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tOK\tE2E P50(ms)\tE2E P95(ms)\tQUEUED P50(ms)\tRETRIED\tDEAD-LETTERED\tDLQ P50(ms)\tATTEMPTS/DLQ")
+	for _, r := range run.Results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\terror: %s\n", r.Function, r.Error)
+			continue
+		}
+		var queued, dead []float64
+		retried, attempts := 0, 0
+		for _, s := range r.Samples {
+			if s.Deliveries > 0 {
+				queued = append(queued, s.QueuedMS)
+			}
+			if s.DeadLetter != nil {
+				dead = append(dead, s.ClientMS)
+				attempts += s.DeadLetter.Attempts
+			} else if s.Error == "" && s.Deliveries > 1 {
+				retried++
+			}
+		}
+		e2e := r.Stats[results.MetricClient]
+		p50 := func(xs []float64) string {
+			if len(xs) == 0 {
+				return "-"
+			}
+			slices.Sort(xs)
+			return fmt.Sprintf("%.0f", stats.Percentile(xs, 50))
+		}
+		p := func(v float64) string {
+			if e2e.N == 0 {
+				return "-"
+			}
+			return fmt.Sprintf("%.0f", v)
+		}
+		perDLQ := "-"
+		if len(dead) > 0 {
+			perDLQ = fmt.Sprintf("%.1f", float64(attempts)/float64(len(dead)))
+		}
+		fmt.Fprintf(w, "%s\t%d/%d\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n", r.Function, e2e.N, len(r.Samples), p(e2e.Median), p(e2e.P95),
+			p50(queued), retried, len(dead), p50(dead), perDLQ)
+	}
+	w.Flush()
+}
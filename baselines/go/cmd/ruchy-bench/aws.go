@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"lambdaperf/pkg/account"
+	"lambdaperf/pkg/budget"
+	"lambdaperf/pkg/matrix"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/tracing"
+)
+
+// loadAWSConfig loads the credentials bench.yaml's aws section gives the
+// region, the default credential chain without one, falling back to
+// us-east-1 like the deployment scripts do. Its clients' Lambda
+// control-plane calls share the region's -api-rate limit.
+func loadAWSConfig(ctx context.Context, region string) (aws.Config, error) {
+	if strings.Contains(region, ",") {
+		return aws.Config{}, fmt.Errorf("-region %s: only deploy, teardown, run and coldstart take several regions", region)
+	}
+	acct, err := harnessAccount()
+	if err != nil {
+		return aws.Config{}, err
+	}
+	creds := acct.For(region)
+	cfg, err := loadCredentials(ctx, region, creds)
+	// Without a -region, the region is the profile's, and may have
+	// credentials of its own.
+	if override := acct.For(cfg.Region); err == nil && region == "" && override != creds {
+		creds = override
+		cfg, err = loadCredentials(ctx, cfg.Region, creds)
+	}
+	if err != nil {
+		return aws.Config{}, err
+	}
+	cfg.Credentials = sharedCredentials(cfg, creds)
+	cfg.APIOptions = append(cfg.APIOptions, regionLimiter(cfg.Region).ControlPlane, budget.Middleware)
+	return cfg, nil
+}
+
+func loadCredentials(ctx context.Context, region string, creds account.Credentials) (aws.Config, error) {
+	opts := creds.LoadOptions()
+	if region != "" {
+		opts = append(opts, config.WithRegion(region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("load AWS config for %s: %w", creds, err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return cfg, nil
+}
+
+// harnessAccount reads the aws section of the repository's bench.yaml
+// once. Outside a repository, or without the file, it is the default
+// credential chain.
+var harnessAccount = sync.OnceValues(func() (account.Account, error) {
+	root, err := findRoot("")
+	if err != nil {
+		return account.Account{}, nil
+	}
+	m, err := matrix.Load(filepath.Join(root, matrix.Path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return account.Account{}, nil
+	}
+	if err != nil {
+		return account.Account{}, err
+	}
+	return m.AWS, nil
+})
+
+// credentialProviders holds the provider of each region's credentials,
+// so that every client of a region shares one assumed role session.
+var credentialProviders = struct {
+	sync.Mutex
+	m map[credentialsKey]aws.CredentialsProvider
+}{m: map[credentialsKey]aws.CredentialsProvider{}}
+
+type credentialsKey struct {
+	region string
+	creds  account.Credentials
+}
+
+// sharedCredentials returns the provider of creds in cfg's region, made
+// from cfg the first time.
+func sharedCredentials(cfg aws.Config, creds account.Credentials) aws.CredentialsProvider {
+	k := credentialsKey{cfg.Region, creds}
+	credentialProviders.Lock()
+	defer credentialProviders.Unlock()
+	p, ok := credentialProviders.m[k]
+	if !ok {
+		p = creds.Provider(cfg)
+		credentialProviders.m[k] = p
+	}
+	return p
+}
+
+// newLambdaClient returns a Lambda client with SDK retries disabled so
+// every recorded latency is a single attempt.
+func newLambdaClient(ctx context.Context, region string) (*lambda.Client, error) {
+	cfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return lambda.NewFromConfig(cfg, func(o *lambda.Options) { o.Retryer = aws.NopRetryer{} }), nil
+}
+
+func newLogsClient(ctx context.Context, region string) (*cloudwatchlogs.Client, error) {
+	cfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return cloudwatchlogs.NewFromConfig(cfg), nil
+}
+
+// regionList splits a -region flag of commands that take several regions.
+// Without one it is the AWS config's default alone, as "": results
+// measured there are recorded without a region, like those of every
+// other command.
+func regionList(flag string) []string {
+	if regions := splitList(flag); len(regions) > 0 {
+		return regions
+	}
+	return []string{""}
+}
+
+// eachRegion calls fn for every region concurrently, returning once all
+// calls have. Cold start and network variance differ by region, and
+// running them side by side keeps time of day out of the comparison.
+func eachRegion(regions []string, fn func(i int, region string)) {
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i, region)
+		}()
+	}
+	wg.Wait()
+}
+
+// inRegion qualifies a target or function name with region, if any.
+func inRegion(name, region string) string {
+	if region == "" {
+		return name
+	}
+	return name + " in " + region
+}
+
+// regionClients measure Lambda targets in one region.
+type regionClients struct {
+	region string
+	lambda *lambda.Client
+	traces *tracing.Fetcher       // with -tracing
+	logs   *cloudwatchlogs.Client // with -telemetry
+}
+
+// newRegionClients returns clients for every region, with trace and log
+// clients only if traced or telemetry are set.
+func newRegionClients(ctx context.Context, regions []string, traced, telemetry bool) ([]*regionClients, error) {
+	out := make([]*regionClients, len(regions))
+	for i, region := range regions {
+		rc := &regionClients{region: region}
+		var err error
+		if rc.lambda, err = newLambdaClient(ctx, region); err != nil {
+			return nil, err
+		}
+		if traced {
+			if rc.traces, err = newTraceFetcher(ctx, region); err != nil {
+				return nil, err
+			}
+		}
+		if telemetry {
+			if rc.logs, err = newLogsClient(ctx, region); err != nil {
+				return nil, err
+			}
+		}
+		out[i] = rc
+	}
+	return out, nil
+}
+
+// attach records the trace segments and telemetry of res's invocations
+// since start, for whichever the clients were made for.
+func (rc *regionClients) attach(ctx context.Context, res *results.Result, start time.Time) {
+	if rc.traces != nil {
+		attachTraces(ctx, rc.traces, res, start)
+	}
+	if rc.logs != nil {
+		attachTelemetry(ctx, rc.logs, res, start)
+	}
+}
+
+// inEachRegion runs measure for every region concurrently, returning the
+// results in region order, each tagged with its region.
+func inEachRegion(clients []*regionClients, measure func(rc *regionClients) results.Result) []results.Result {
+	regions := make([]string, len(clients))
+	for i, rc := range clients {
+		regions[i] = rc.region
+	}
+	out := make([]results.Result, len(clients))
+	eachRegion(regions, func(i int, region string) {
+		out[i] = measure(clients[i])
+		out[i].Region = region
+	})
+	return out
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"lambdaperf/pkg/report"
+	"lambdaperf/pkg/results"
+)
+
+func runBadges(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("badges", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench badges [flags] [results.json]")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	runtime := fs.String("runtime", "ruchy", "runtime the badges are about")
+	versus := fs.String("vs", "", "runtime to compare it with (default: the fastest other on each target)")
+	out := fs.String("out", "", "directory to write the badge files to (default: <root>/.bench/badges)")
+	var sf statsFlags
+	sf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	path := fs.Arg(0)
+	if path == "" || *out == "" {
+		dir, err := findRoot(*root)
+		if err != nil {
+			return err
+		}
+		if *out == "" {
+			*out = filepath.Join(dir, ".bench", "badges")
+		}
+		if path == "" {
+			if path, err = latestResults(filepath.Join(dir, ".bench", "results")); err != nil {
+				return err
+			}
+		}
+	}
+	run, err := results.Read(path)
+	if err != nil {
+		return err
+	}
+	run.Summarize(sf.options())
+
+	badges := report.Badges(run, report.BadgeOptions{Runtime: *runtime, Versus: *versus})
+	if len(badges) == 0 {
+		return fmt.Errorf("%s has no successful %s results to make badges of", path, *runtime)
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	for _, b := range badges {
+		data, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(*out, b.Name+".json")
+		if err := os.WriteFile(file, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s: %s\n", file, b.Label, b.Message)
+	}
+	return nil
+}
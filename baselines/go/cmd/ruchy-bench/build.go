@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lambdaperf/pkg/build"
+)
+
+func runBuild(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	outDir := fs.String("out", "", "artifact directory (default: <root>/.bench/build)")
+	verbose := fs.Bool("v", false, "show compiler and build script output")
+	var db string
+	registerDB(fs, &db)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	hdb, err := openHistory(ctx, root, db)
+	if err != nil {
+		return err
+	}
+	defer hdb.Close()
+
+	b := newBuilder(root, *outDir, *verbose)
+	for _, t := range targets {
+		a, err := b.Build(ctx, t)
+		if err != nil {
+			return err
+		}
+		if err := hdb.record(ctx, a); err != nil {
+			return err
+		}
+		switch {
+		case a.Image != "":
+			fmt.Printf("%-32s %s (%s)\n", t.ID(), a.Image, describeSizes(a.BinaryBytes, a.PackageBytes))
+		case a.Package != "":
+			fmt.Printf("%-32s %s (%s, sha256 %s)\n", t.ID(), a.Package, describeSizes(a.BinaryBytes, a.PackageBytes), a.SHA256)
+		default:
+			fmt.Printf("%-32s %v (%s)\n", t.ID(), a.Command, describeSizes(a.BinaryBytes, a.PackageBytes))
+		}
+	}
+	return nil
+}
+
+func newBuilder(root, outDir string, verbose bool) *build.Builder {
+	if outDir == "" {
+		outDir = filepath.Join(root, ".bench", "build")
+	}
+	b := &build.Builder{Root: root, OutDir: outDir}
+	if verbose {
+		b.Log = os.Stderr
+	}
+	return b
+}
+
+// describeSizes formats an artifact's binary and package sizes.
+func describeSizes(binary, pkg int64) string {
+	var parts []string
+	if binary > 0 {
+		parts = append(parts, "binary "+humanBytes(binary))
+	}
+	if pkg > 0 {
+		parts = append(parts, "package "+humanBytes(pkg))
+	}
+	if len(parts) == 0 {
+		return "size not measured"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// humanBytes formats n in binary units, as ls -lh does.
+func humanBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
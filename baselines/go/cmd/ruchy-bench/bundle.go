@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+
+	"lambdaperf/pkg/bundle"
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/report"
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/slo"
+)
+
+func runBundle(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("bundle", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench bundle [flags] <run-id | results.json>")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	var db string
+	registerDB(fs, &db)
+	out := fs.String("o", "", "output tarball (default: <run-id>.tar.gz)")
+	logs := fs.Bool("logs", true, "include the measured functions' CloudWatch log lines during the run")
+	logLines := fs.Int("log-lines", 1000, "most log lines to include per function")
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// Accept flags on either side of the run, as analyze does.
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("bundle needs a run ID or results file")
+	}
+	ref := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	run, err := loadRun(ctx, *root, db, ref)
+	if err != nil {
+		return err
+	}
+	run.Summarize(sf.options())
+	opts := report.Options{CostOptions: cf.options()}
+	if dir, err := findRoot(*root); err == nil {
+		m, err := manifest.Load(dir)
+		if err != nil {
+			return err
+		}
+		opts.SLOs = slo.Evaluate(run, m)
+	}
+	var rendered bytes.Buffer
+	if err := report.Markdown(&rendered, run, opts); err != nil {
+		return err
+	}
+	b := bundle.Bundle{Run: run, Report: rendered.Bytes()}
+	if *logs {
+		if b.Logs, err = logExcerpts(ctx, run, *logLines); err != nil {
+			return err
+		}
+	}
+
+	path := *out
+	if path == "" {
+		path = run.ID + ".tar.gz"
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := b.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "run %s bundled to %s, account IDs and ARNs scrubbed\n", run.ID, path)
+	return nil
+}
+
+// logExcerpts fetches up to max log lines of every function run measured,
+// from its start to its end, keyed by function and region. A function
+// whose logs cannot be read, as once its log group expired, is left out
+// with a warning.
+func logExcerpts(ctx context.Context, run *results.Run, max int) (map[string][]string, error) {
+	end := run.FinishedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	clients := map[string]*cloudwatchlogs.Client{}
+	excerpts := map[string][]string{}
+	for _, r := range run.Results {
+		name := inRegion(r.Function, r.Region)
+		if _, done := excerpts[name]; r.Kind != "lambda" || r.Function == "" || done {
+			continue
+		}
+		client, ok := clients[r.Region]
+		if !ok {
+			var err error
+			if client, err = newLogsClient(ctx, r.Region); err != nil {
+				return nil, err
+			}
+			clients[r.Region] = client
+		}
+		lines, err := reportparser.Excerpt(ctx, client, r.Function, run.StartedAt, end, max)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v; its logs are left out\n", name, err)
+			continue
+		}
+		excerpts[name] = lines
+	}
+	return excerpts, nil
+}
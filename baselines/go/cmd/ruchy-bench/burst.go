@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"lambdaperf/pkg/cwmetrics"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/loadgen"
+	"lambdaperf/pkg/results"
+)
+
+// metricsPoll paces the reads of CloudWatch while burst waits for it to
+// catch up with the invocations sent.
+const metricsPoll = 30 * time.Second
+
+func runBurst(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("burst", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	levelList := fs.String("levels", joinInts(loadgen.DefaultLevels), "comma-separated concurrency levels to ramp through, in order")
+	step := fs.Duration("step", 20*time.Second, "how long each concurrency level runs")
+	metricsWait := fs.Duration("metrics-wait", 5*time.Minute, "how long to wait for CloudWatch to report each function's throttles (0: skip CloudWatch)")
+	var pf payloadFlags
+	pf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	var bf budgetFlags
+	bf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	levels, err := parseLevels(*levelList)
+	if err != nil {
+		return err
+	}
+	if *step <= 0 || *metricsWait < 0 {
+		return errors.New("-step must be positive and -metrics-wait not negative")
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	// Retries stay off: the throttles are what the ramp is looking for.
+	client := lambda.NewFromConfig(cfg, func(o *lambda.Options) { o.Retryer = aws.NopRetryer{} })
+	metrics := &cwmetrics.Client{Config: cfg}
+
+	expected, err := expectedResults(root)
+	if err != nil {
+		return err
+	}
+
+	run := results.NewRun("burst", time.Now())
+	for _, t := range targets {
+		payload, err := pf.forTarget(t)
+		if err != nil {
+			return err
+		}
+		res := newResult(t)
+		fmt.Fprintf(os.Stderr, "%s: ramping through %v workers, %s each\n", res.Function, levels, *step)
+		start := time.Now()
+		out, err := loadgen.Ramp(ctx, &invoke.Lambda{Client: client, FunctionName: res.Function, Qualifier: t.Qualifier()},
+			loadgen.RampConfig{Levels: levels, Step: *step, Payload: payload, Expected: expected[t.Workload]})
+		if err != nil {
+			res.Error = err.Error()
+		}
+		res.Samples = loadgen.Samples(out)
+		res.Burst = &results.Burst{}
+		for _, l := range out {
+			res.Burst.Levels = append(res.Burst.Levels, burstLevel(l))
+		}
+		if *metricsWait > 0 && ctx.Err() == nil {
+			readMetrics(ctx, metrics, &res, start, *metricsWait)
+		}
+		run.Results = append(run.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printStats(run, results.MetricWarm, cf)
+	fmt.Println()
+	printBurst(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return context.Cause(ctx)
+}
+
+func parseLevels(s string) ([]int, error) {
+	var levels []int
+	for _, v := range splitList(s) {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid concurrency level %q: want a positive integer", v)
+		}
+		levels = append(levels, n)
+	}
+	if len(levels) == 0 {
+		return nil, errors.New("-levels needs at least one concurrency level")
+	}
+	return levels, nil
+}
+
+func joinInts(v []int) string {
+	s := make([]string, len(v))
+	for i, n := range v {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+func burstLevel(l loadgen.Level) results.BurstLevel {
+	b := results.BurstLevel{
+		Concurrency: l.Concurrency,
+		Requests:    len(l.Result.Samples),
+		AchievedRPS: l.Result.AchievedRPS(),
+		Throttled:   l.Result.Throttled,
+		ScaledUp:    l.Result.ScaledUp,
+		ScaleUpMS:   results.Milliseconds(l.Result.ScaleUp),
+		ClientP50MS: l.Result.Client.Quantile(0.5),
+		ClientP99MS: l.Result.Client.Quantile(0.99),
+	}
+	for _, s := range l.Result.Samples {
+		if s.Error != "" {
+			b.Errors++
+		}
+		if s.Cold {
+			b.Cold++
+		}
+	}
+	return b
+}
+
+// readMetrics records what CloudWatch saw of res's function since start.
+// CloudWatch publishes Lambda metrics a minute or more late, so it is read
+// until it accounts for every request Lambda accepted, or until wait runs
+// out, in which case the partial totals are kept and flagged.
+func readMetrics(ctx context.Context, c *cwmetrics.Client, res *results.Result, start time.Time, wait time.Duration) {
+	accepted := len(res.Samples)
+	for _, l := range res.Burst.Levels {
+		accepted -= l.Throttled
+	}
+	fmt.Fprintf(os.Stderr, "%s: waiting for CloudWatch metrics\n", res.Function)
+	deadline := time.Now().Add(wait)
+	for ctx.Err() == nil {
+		f, err := c.Function(ctx, res.Function, start, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", res.Function, err)
+			return
+		}
+		b := res.Burst
+		b.Metrics = true
+		b.Invocations, b.Throttles, b.PeakConcurrency = f.Invocations, f.Throttles, f.PeakConcurrency
+		if b.MetricsComplete = f.Invocations >= accepted; b.MetricsComplete || time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(metricsPoll):
+		}
+	}
+	if !res.Burst.MetricsComplete {
+		fmt.Fprintf(os.Stderr, "%s: CloudWatch reports %d of %d invocations so far; its throttle count may be low\n",
+			res.Function, res.Burst.Invocations, accepted)
+	}
+}
+
+// printBurst shows each level of every ramp, then the CloudWatch totals of
+// the functions it was read for.
+func printBurst(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tCONCURRENCY\tREQUESTS\tRPS\tERRORS\tTHROTTLED\tCOLD\tSCALE-UP(ms)\tCLIENT P50(ms)\tP99(ms)")
+	for _, r := range run.Results {
+		if r.Burst == nil || len(r.Burst.Levels) == 0 {
+			fmt.Fprintf(w, "%s\t-\terror: %s\n", r.Function, r.Error)
+			continue
+		}
+		for _, l := range r.Burst.Levels {
+			scaleUp := "-"
+			if l.ScaledUp {
+				scaleUp = fmt.Sprintf("%.0f", l.ScaleUpMS)
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%d\t%d\t%d\t%s\t%.2f\t%.2f\n", r.Function, l.Concurrency, l.Requests,
+				l.AchievedRPS, l.Errors, l.Throttled, l.Cold, scaleUp, l.ClientP50MS, l.ClientP99MS)
+		}
+	}
+	w.Flush()
+
+	var header bool
+	for _, r := range run.Results {
+		if r.Burst == nil || !r.Burst.Metrics {
+			continue
+		}
+		if !header {
+			fmt.Println()
+			w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "FUNCTION\tCW INVOCATIONS\tCW THROTTLES\tPEAK CONCURRENCY\tCOMPLETE")
+			header = true
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%t\n", r.Function, r.Burst.Invocations, r.Burst.Throttles,
+			r.Burst.PeakConcurrency, r.Burst.MetricsComplete)
+	}
+	if header {
+		w.Flush()
+	}
+}
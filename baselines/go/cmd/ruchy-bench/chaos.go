@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"maps"
+
+	"lambdaperf/pkg/chaos"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+)
+
+// chaosFlags set how much of a -chaos variant's environment the chaos
+// extension takes (-chaos-cpu, -chaos-memory).
+type chaosFlags struct {
+	config chaos.Config
+}
+
+func (f *chaosFlags) register(fs *flag.FlagSet) {
+	fs.Float64Var(&f.config.CPU, "chaos-cpu", chaos.Default.CPU, "share of each vCPU the chaos extension of -chaos variants spins for, 0 to 1")
+	fs.Float64Var(&f.config.Memory, "chaos-memory", chaos.Default.Memory, "share of the function's memory the chaos extension of -chaos variants holds, 0 to below 1")
+}
+
+// validate checks the shares.
+func (f *chaosFlags) validate() error {
+	return f.config.Validate()
+}
+
+// apply configures the chaos extension of t into c, if t is a chaos
+// variant. The extension layer itself is attached with the others.
+func (f *chaosFlags) apply(t discover.Target, c *deploy.Config) {
+	if !t.Chaos {
+		return
+	}
+	if c.Env == nil {
+		c.Env = map[string]string{}
+	}
+	maps.Copy(c.Env, f.config.Env())
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"lambdaperf/pkg/coldstart"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/pool"
+	"lambdaperf/pkg/results"
+)
+
+func runColdstart(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("coldstart", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	n := fs.Int("n", 10, "forced cold starts per function")
+	var pf payloadFlags
+	pf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "comma-separated AWS regions to measure in parallel (default: from AWS config)")
+	traced := fs.Bool("tracing", false, "break cold starts down by their X-Ray trace segments (deploy with -tracing first)")
+	telemetry := fs.Bool("telemetry", false, "record Telemetry API phase timings (deploy with -telemetry first)")
+	var rf retryFlags
+	rf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	var par parallelFlags
+	par.register(fs, "functions to cold start")
+	var bf budgetFlags
+	bf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if err := par.validate(); err != nil {
+		return err
+	}
+	if err := rf.validate(); err != nil {
+		return err
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	clients, err := newRegionClients(ctx, regionList(*region), *traced, *telemetry)
+	if err != nil {
+		return err
+	}
+
+	expected, err := expectedResults(root)
+	if err != nil {
+		return err
+	}
+
+	payloads := make([][]byte, len(targets))
+	for i, t := range targets {
+		if payloads[i], err = pf.forTarget(t); err != nil {
+			return err
+		}
+	}
+	run := results.NewRun("coldstart", time.Now())
+	measured := make([][]results.Result, len(targets))
+	pool.Each(ctx, len(targets), par.workers, func(i int) {
+		t := targets[i]
+		measured[i] = inEachRegion(clients, func(rc *regionClients) results.Result {
+			return coldstartTarget(ctx, rc, t, payloads[i], *n, expected[t.Workload], rf.backoff())
+		})
+	})
+	for _, m := range measured {
+		run.Results = append(run.Results, m...)
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printStats(run, results.MetricInit, cf)
+	if *traced {
+		fmt.Println()
+		printTraces(run)
+	}
+	if *telemetry {
+		fmt.Println()
+		printTelemetry(run)
+	}
+	printOverhead(run)
+	printGoInit(run)
+	printExtensionOverhead(run)
+	printVPCOverhead(run)
+	printChaos(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return context.Cause(ctx)
+}
+
+// coldstartTarget forces n cold starts of t in rc's region.
+func coldstartTarget(ctx context.Context, rc *regionClients, t discover.Target, payload []byte, n int, expected string, b invoke.Backoff) results.Result {
+	res := newResult(t)
+	r := &coldstart.Runner{Client: rc.lambda, FunctionName: res.Function, Qualifier: t.Qualifier(), Backoff: b,
+		Runtime: canaryRuntime(t), Expected: expected}
+	if t.SnapStart {
+		// Only a freshly published version is restored from a new
+		// snapshot; publishing takes a minute or more per sample.
+		d := &deploy.Deployer{Client: rc.lambda}
+		r.Publish = func(ctx context.Context) error {
+			_, err := d.Publish(ctx, res.Function, discover.SnapStartAlias)
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d forced cold starts\n", inRegion(res.Function, rc.region), n)
+	start := time.Now()
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		m, err := r.Measure(ctx, payload)
+		if err != nil {
+			res.Error = err.Error()
+			break
+		}
+		if !m.Cold() && m.Error == "" {
+			m.Error = "invocation was not a cold start (no Init or Restore Duration in REPORT line)"
+		}
+		res.Samples = append(res.Samples, results.Sample{
+			Iteration: i,
+			ClientMS:  m.ClientMS,
+			Error:     m.Error,
+			Retries:   m.Retries,
+			Excluded:  string(m.Excluded),
+		}.WithReport(m.Report).WithResponse(m.Response).Verify(expected))
+	}
+	rc.attach(ctx, &res, start)
+	return res
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"lambdaperf/pkg/compare"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/results"
+)
+
+func runCompare(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench compare -baseline <run-id> [flags] [results.json]")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	var db string
+	registerDB(fs, &db)
+	baselineID := fs.String("baseline", "", "ID of the stored run to compare against (required)")
+	currentID := fs.String("current", "", "ID of the stored run to check (default: the results file given, else the newest one)")
+	failOver := fs.String("fail-over", "5%", "p95 increase beyond which a significant slowdown fails, e.g. 5%")
+	alpha := fs.Float64("alpha", compare.DefaultAlpha, "significance level of the Mann-Whitney test a slowdown must pass")
+	metric := fs.String("metric", "", "metric to compare (default: warm_ms where both runs have it, else client_ms)")
+	var sf statsFlags
+	sf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *baselineID == "" {
+		fs.Usage()
+		return errors.New("compare needs -baseline")
+	}
+	threshold, err := parsePercent(*failOver)
+	if err != nil {
+		return fmt.Errorf("-fail-over: %w", err)
+	}
+	if *alpha <= 0 || *alpha >= 1 {
+		return errors.New("-alpha must be between 0 and 1")
+	}
+	if *currentID != "" && fs.NArg() > 0 {
+		return errors.New("give -current or a results file, not both")
+	}
+
+	if *root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if *root, err = discover.FindRoot(wd); err != nil {
+			return err
+		}
+	}
+	s, err := openStore(ctx, *root, db)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	baseline, err := s.Run(ctx, *baselineID)
+	if err != nil {
+		return err
+	}
+	var current *results.Run
+	if *currentID != "" {
+		current, err = s.Run(ctx, *currentID)
+	} else {
+		path := fs.Arg(0)
+		if path == "" {
+			if path, err = latestResults(filepath.Join(*root, ".bench", "results")); err != nil {
+				return err
+			}
+		}
+		current, err = results.Read(path)
+	}
+	if err != nil {
+		return err
+	}
+	if current.ID == baseline.ID {
+		return fmt.Errorf("run %s compared with itself", current.ID)
+	}
+	// Numbers that cannot be traced to the code and toolchains behind
+	// them settle nothing.
+	for _, run := range []*results.Run{baseline, current} {
+		if missing := run.MissingMetadata(); len(missing) > 0 {
+			return fmt.Errorf("run %s is missing metadata (%s); re-run it to compare", run.ID, strings.Join(missing, ", "))
+		}
+	}
+
+	cs := compare.Runs(baseline, current, compare.Options{
+		Threshold: threshold,
+		Alpha:     *alpha,
+		Metric:    *metric,
+		Stats:     sf.options(),
+	})
+	fmt.Printf("%s against baseline %s, failing over +%g%% p95 at p < %g:\n", current.ID, baseline.ID, 100*threshold, *alpha)
+	printMetadata(os.Stdout, baseline, current)
+	fmt.Println()
+	printComparisons(os.Stdout, cs)
+	failing := 0
+	for _, c := range cs {
+		if c.Failing() {
+			failing++
+		}
+	}
+	if failing > 0 {
+		return fmt.Errorf("%d of %d targets regressed or failed", failing, len(cs))
+	}
+	return nil
+}
+
+// printMetadata prints the commits, toolchain versions and package hashes
+// of the runs compared to w, marking those that differ.
+func printMetadata(w io.Writer, baseline, current *results.Run) {
+	b, c := baseline.Metadata, current.Metadata
+	line := func(name, base, cur string) {
+		mark := ""
+		if base != cur {
+			mark = "  (changed)"
+		}
+		fmt.Fprintf(w, "  %-14s %s -> %s%s\n", name, base, cur, mark)
+	}
+	commit := func(m *results.Metadata) string {
+		if m.Dirty {
+			return m.Commit + "-dirty"
+		}
+		return m.Commit
+	}
+	line("commit", commit(b), commit(c))
+	for _, rt := range slices.Sorted(maps.Keys(c.Toolchains)) {
+		if base, ok := b.Toolchains[rt]; ok {
+			line(rt, base, c.Toolchains[rt])
+		}
+	}
+	line("aws-lambda-go", b.LambdaGo, c.LambdaGo)
+	for _, fn := range slices.Sorted(maps.Keys(c.Packages)) {
+		if base, ok := b.Packages[fn]; ok {
+			line(fn, base, c.Packages[fn])
+		}
+	}
+}
+
+// parsePercent parses a percentage such as "5%" or "2.5" into a fraction.
+func parsePercent(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "%"), 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return v / 100, nil
+}
+
+func printComparisons(out io.Writer, cs []compare.Comparison) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tMETRIC\tBASE N\tBASE P95\tN\tP95\tCHANGE\tP-VALUE\tVERDICT")
+	for _, c := range cs {
+		switch c.Verdict {
+		case compare.Added, compare.Missing:
+			fmt.Fprintf(w, "%s\t%s\t\t\t\t\t\t\t%s\n", c.Target, c.Metric, c.Verdict)
+			continue
+		case compare.Failed:
+			fmt.Fprintf(w, "%s\t%s\t%d\t%.2f\t0\t-\t\t\t%s\n", c.Target, c.Metric, c.Baseline.N, c.Baseline.P95, c.Verdict)
+			continue
+		}
+		p := "-"
+		if c.Verdict != compare.Unchanged {
+			p = fmt.Sprintf("%.4f", c.P)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.2f\t%d\t%.2f\t%+.1f%%\t%s\t%s\n", c.Target, c.Metric, c.Baseline.N,
+			c.Baseline.P95, c.Current.N, c.Current.P95, 100*c.Change, p, c.Verdict)
+	}
+	w.Flush()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"lambdaperf/pkg/budget"
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/report"
+	"lambdaperf/pkg/results"
+)
+
+// costFlags controls the pricing assumptions behind USD columns.
+type costFlags struct {
+	monthly     float64
+	freeTier    bool
+	ephemeralMB int
+}
+
+func (f *costFlags) register(fs *flag.FlagSet) {
+	fs.Float64Var(&f.monthly, "monthly", report.DefaultCost.Monthly, "monthly invocation volume used for tiered pricing")
+	fs.BoolVar(&f.freeTier, "free-tier", false, "deduct the monthly free tier from costs")
+	fs.IntVar(&f.ephemeralMB, "ephemeral-mb", int(report.DefaultCost.EphemeralMB), "configured ephemeral storage in MB")
+}
+
+func (f *costFlags) options() report.CostOptions {
+	o := report.DefaultCost
+	o.Monthly, o.FreeTier, o.EphemeralMB = f.monthly, f.freeTier, int32(f.ephemeralMB)
+	return o
+}
+
+// perMillion formats the USD cost of one million invocations of r, or "-"
+// when r has no billed duration to price.
+func (f *costFlags) perMillion(r results.Result) string {
+	usd, ok := report.CostPerMillion(r, f.options())
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.4f", usd)
+}
+
+// price is the USD cost of a million invocations of u at the -monthly
+// volume and -ephemeral-mb; see powertune.Price.
+func (f *costFlags) price(u cost.Usage) (float64, error) {
+	o := f.options()
+	u.EphemeralMB = o.EphemeralMB
+	return o.Pricing.PerMillion(u, o.Monthly, o.FreeTier)
+}
+
+// budgetFlags cap what a command may spend (-max-cost-usd).
+type budgetFlags struct {
+	maxUSD float64
+}
+
+func (f *budgetFlags) register(fs *flag.FlagSet) {
+	fs.Float64Var(&f.maxUSD, "max-cost-usd", 0, "stop once the estimated Lambda spend reaches this many USD, keeping the results collected so far (default: no cap)")
+}
+
+func (f *budgetFlags) validate() error {
+	if f.maxUSD < 0 {
+		return errors.New("-max-cost-usd must not be negative")
+	}
+	return nil
+}
+
+// start returns ctx carrying the -max-cost-usd budget, canceled once the
+// invocations of every client loaded from it have spent it; see
+// budget.Middleware. Without the flag ctx is returned unchanged, though
+// a matrix may still cap it.
+func (f *budgetFlags) start(ctx context.Context) context.Context {
+	if f.maxUSD == 0 {
+		return ctx
+	}
+	ctx, _ = budget.New(ctx, f.maxUSD, cost.Default)
+	return ctx
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lambdaperf/pkg/compare"
+	"lambdaperf/pkg/matrix"
+	"lambdaperf/pkg/notify"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/sink"
+)
+
+// webhookEnv supplies -webhook's default, which keeps the webhook URL, a
+// credential, out of the process list.
+const webhookEnv = "RUCHY_BENCH_SLACK_WEBHOOK"
+
+// defaultNightlyPrefix is where in the -bucket nightly runs are stored
+// when it names no prefix.
+const defaultNightlyPrefix = "nightly/"
+
+func runDaemon(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench daemon -bucket BUCKET[/PREFIX] [flags] [-- run or matrix flags]")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	at := fs.String("at", "02:00", "UTC time of day to start each run, as HH:MM")
+	once := fs.Bool("once", false, "run once now and exit, for cron, systemd timers and other schedulers")
+	bucket := fs.String("bucket", "", "S3 bucket each run is stored in and compared from, with an optional key prefix (required; default prefix "+defaultNightlyPrefix+")")
+	topic := fs.String("sns-topic", "", "SNS topic ARN to post each run's summary to")
+	webhook := fs.String("webhook", "", "Slack incoming webhook URL to post each run's summary to (default: $"+webhookEnv+")")
+	failOver := fs.String("fail-over", "5%", "p95 increase beyond which a significant slowdown is reported as a regression")
+	useMatrix := fs.Bool("matrix", false, "run the "+matrix.Path+" matrix each time, with matrix flags such as -only after --, instead of run")
+	alpha := fs.Float64("alpha", compare.DefaultAlpha, "significance level of the Mann-Whitney test a slowdown must pass")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *bucket == "" {
+		fs.Usage()
+		return errors.New("daemon needs -bucket")
+	}
+	daily, err := time.Parse("15:04", *at)
+	if err != nil {
+		return fmt.Errorf("-at: want HH:MM, got %q", *at)
+	}
+	threshold, err := parsePercent(*failOver)
+	if err != nil {
+		return fmt.Errorf("-fail-over: %w", err)
+	}
+	if *alpha <= 0 || *alpha >= 1 {
+		return errors.New("-alpha must be between 0 and 1")
+	}
+	if *webhook == "" {
+		*webhook = os.Getenv(webhookEnv)
+	}
+	if *root, err = findRoot(*root); err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(ctx, "")
+	if err != nil {
+		return err
+	}
+	target := *bucket
+	if !strings.Contains(target, "/") {
+		target += "/" + defaultNightlyPrefix
+	}
+	d := &daemon{
+		root:    *root,
+		run:     runRun,
+		runArgs: fs.Args(),
+		store:   "s3=" + target,
+		archive: s3Sink(cfg, target),
+		options: compare.Options{Threshold: threshold, Alpha: *alpha},
+	}
+	if *useMatrix {
+		d.run = runMatrix
+	}
+	if *topic != "" {
+		d.notifiers = append(d.notifiers, notify.SNS{Config: cfg, TopicARN: *topic})
+	}
+	if *webhook != "" {
+		d.notifiers = append(d.notifiers, notify.Slack{URL: *webhook})
+	}
+	if len(d.notifiers) == 0 {
+		fmt.Fprintln(os.Stderr, "warning: no -sns-topic or -webhook; summaries are only printed")
+	}
+
+	if *once {
+		return d.cycle(ctx)
+	}
+	for {
+		next := nextDaily(time.Now(), daily)
+		fmt.Fprintf(os.Stderr, "next run at %s\n", next.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		// A failed run is reported and the next one still happens.
+		if err := d.cycle(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, "ruchy-bench daemon:", err)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// nextDaily returns the first time after now, in UTC, at the hour and
+// minute of daily.
+func nextDaily(now time.Time, daily time.Time) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), daily.Hour(), daily.Minute(), 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// daemon is one ruchy-bench daemon: what each scheduled run does.
+type daemon struct {
+	root string
+	// run is the command each cycle runs, run or matrix, with the
+	// daemon's own -root, -out and -sink and then runArgs.
+	run     func(context.Context, []string) error
+	runArgs []string
+	// store is the -sink value that appends each run to archive.
+	store     string
+	archive   sink.S3
+	options   compare.Options
+	notifiers []notify.Notifier
+}
+
+// cycle runs the matrix once, stores the run, compares it with the
+// previous stored run and posts the summary. Only a run that recorded
+// nothing, or a summary no notifier could post, is an error.
+func (d *daemon) cycle(ctx context.Context) error {
+	started := time.Now().UTC()
+	out := filepath.Join(d.root, ".bench", "nightly", started.Format("20060102T150405Z")+".json")
+	// The daemon's flags go first: matrix passes whatever follows a -- on
+	// to its groups' commands.
+	args := append([]string{"-root", d.root, "-out", out, "-sink", d.store}, d.runArgs...)
+	runErr := d.run(ctx, args)
+	ctx = context.WithoutCancel(ctx)
+
+	current, err := results.Read(out)
+	if err != nil {
+		err = errors.Join(runErr, err)
+		return errors.Join(err, d.post(ctx, fmt.Sprintf("ruchy-bench nightly %s: failed", started.Format("20060102T150405Z")), err.Error()))
+	}
+	baseline, err := d.archive.Latest(ctx, current.ID)
+	if err != nil {
+		// No comparison, but the run happened: still say so.
+		runErr = errors.Join(runErr, err)
+	}
+	subject, text := nightlySummary(baseline, current, d.options, runErr)
+	fmt.Println(subject)
+	fmt.Println(text)
+	return d.post(ctx, subject, text)
+}
+
+// post sends the summary through every notifier.
+func (d *daemon) post(ctx context.Context, subject, text string) error {
+	var errs []error
+	for _, n := range d.notifiers {
+		if err := n.Notify(ctx, subject, text); err != nil {
+			errs = append(errs, fmt.Errorf("notify: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// nightlySummary describes current against baseline, the run before it:
+// a subject counting regressions and failures, and the changed targets
+// with the commits and toolchains behind them. A nil baseline makes
+// current the first run, with nothing to compare.
+func nightlySummary(baseline, current *results.Run, o compare.Options, runErr error) (subject, text string) {
+	var b strings.Builder
+	if runErr != nil {
+		fmt.Fprintf(&b, "run reported: %v\n\n", runErr)
+	}
+	if baseline == nil {
+		fmt.Fprintf(&b, "%d results; no earlier run to compare with.\n", len(current.Results))
+		return fmt.Sprintf("ruchy-bench nightly %s: first run", current.ID), strings.TrimSpace(b.String())
+	}
+	cs := compare.Runs(baseline, current, o)
+	var regressed, failed int
+	var changed []compare.Comparison
+	for _, c := range cs {
+		switch c.Verdict {
+		case compare.Regressed:
+			regressed++
+		case compare.Failed:
+			failed++
+		}
+		if c.Verdict != compare.Unchanged {
+			changed = append(changed, c)
+		}
+	}
+	fmt.Fprintf(&b, "%s against %s, reporting over +%g%% p95 at p < %g:\n", current.ID, baseline.ID, 100*o.Threshold, o.Alpha)
+	if baseline.Metadata != nil && current.Metadata != nil {
+		printMetadata(&b, baseline, current)
+	}
+	b.WriteString("\n")
+	if len(changed) > 0 {
+		printComparisons(&b, changed)
+	}
+	fmt.Fprintf(&b, "%d of %d targets unchanged.\n", len(cs)-len(changed), len(cs))
+
+	status := "no regressions"
+	if regressed > 0 || failed > 0 {
+		status = fmt.Sprintf("%d regressed, %d failed", regressed, failed)
+	}
+	return fmt.Sprintf("ruchy-bench nightly %s: %s", current.ID, status), strings.TrimSpace(b.String())
+}
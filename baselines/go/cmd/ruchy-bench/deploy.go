@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/async"
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/canary"
+	"lambdaperf/pkg/configload"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/fixture"
+	"lambdaperf/pkg/lambdalog"
+	"lambdaperf/pkg/mockapi"
+	"lambdaperf/pkg/platform"
+	"lambdaperf/pkg/pool"
+	"lambdaperf/pkg/profiles"
+	"lambdaperf/pkg/vpc"
+)
+
+func runDeploy(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("deploy", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	all := fs.Bool("all", false, "deploy every discovered Lambda target")
+	memory := fs.Int("memory", deploy.DefaultMemoryMB, "memory size in MB")
+	timeout := fs.Int("timeout", deploy.DefaultTimeoutSec, "function timeout in seconds")
+	traced := fs.Bool("tracing", false, "enable active X-Ray tracing and grant the execution role write access to X-Ray")
+	telemetry := fs.Bool("telemetry", false, "attach the telemetry extension, which logs Telemetry API phase timings (zip packages only)")
+	runtimeMetrics := fs.Bool("runtime-metrics", false, "have Go baselines report heap, GC and goroutine metrics with every response")
+	histogram := fs.Duration("histogram", 0, "have Go baselines rerun the workload for this long in every invocation and report a histogram of the runs' times (raise -timeout to match)")
+	pprofBucket := fs.String("pprof", "", "build Go baselines with profiling and have them upload CPU and heap profiles of every invocation to this S3 bucket, in the deploy region (fetch them with the pprof command)")
+	secretsLayer := fs.String("secrets-layer", "", "comma-separated AWS Parameters and Secrets Lambda Extension layer version ARNs, one per region, attached to "+configload.ExtensionWorkload)
+	role := fs.String("role", "", "execution role ARN (default: create or reuse "+deploy.DefaultRoleName+")")
+	region := fs.String("region", "", "comma-separated AWS regions to deploy to in parallel (default: from AWS config)")
+	verify := fs.Bool("canary", true, "invoke every function once after deploying it and fail its deployment unless it returns the expected result as the runtime deployed")
+	verbose := fs.Bool("v", false, "show compiler and build script output")
+	force := fs.Bool("force", false, "rebuild every package and update every function, even those whose sources, package and configuration are unchanged since the last deploy")
+	var cf chaosFlags
+	cf.register(fs)
+	var par parallelFlags
+	par.register(fs, "targets to deploy")
+	var db string
+	registerDB(fs, &db)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := par.validate(); err != nil {
+		return err
+	}
+	if err := cf.validate(); err != nil {
+		return err
+	}
+	if *memory < 128 || *memory > 10240 {
+		return fmt.Errorf("invalid memory size %d: want 128-10240 MB", *memory)
+	}
+	if *pprofBucket != "" && len(regionList(*region)) > 1 {
+		// Handlers upload to the bucket through their own region's
+		// endpoint.
+		return errors.New("-pprof deploys to one region")
+	}
+	root, targets, err := resolveLambdaTargets(&tf, *all)
+	if err != nil {
+		return err
+	}
+	var expected map[string]string
+	if *verify {
+		if expected, err = expectedResults(root); err != nil {
+			return err
+		}
+	}
+	var (
+		regions []*regionDeployer
+		roles   *iam.Client // IAM is global: one role serves every region
+	)
+	for _, r := range regionList(*region) {
+		cfg, err := loadAWSConfig(ctx, r)
+		if err != nil {
+			return err
+		}
+		// Lifecycle calls keep the SDK's retries; only measured
+		// invocations need single attempts.
+		client := lambda.NewFromConfig(cfg)
+		if roles == nil {
+			roles = iam.NewFromConfig(cfg)
+		}
+		regions = append(regions, &regionDeployer{
+			region:   r,
+			client:   client,
+			d:        &deploy.Deployer{Client: client, Force: *force},
+			reg:      &deploy.Registry{Client: ecr.NewFromConfig(cfg), Repository: build.ImageRepository},
+			layers:   map[string]string{},
+			ec2:      &vpc.Client{Config: cfg},
+			expected: expected,
+		})
+		if arn, ok := deploy.LayerInRegion(splitList(*secretsLayer), cfg.Region); ok {
+			regions[len(regions)-1].secretsLayer = arn
+		}
+	}
+	roleARN := *role
+	if roleARN == "" {
+		if roleARN, err = deploy.EnsureRole(ctx, roles, deploy.DefaultRoleName); err != nil {
+			return err
+		}
+	}
+	// Role ARNs end in the role's name, after any path.
+	roleName := roleARN[strings.LastIndex(roleARN, "/")+1:]
+	if *traced {
+		if err := deploy.GrantTracing(ctx, roles, roleName); err != nil {
+			return err
+		}
+	}
+	if tf.vpc {
+		if err := deploy.GrantVPCAccess(ctx, roles, roleName); err != nil {
+			return err
+		}
+	}
+	if *pprofBucket != "" {
+		if err := deploy.GrantProfileUpload(ctx, roles, roleName, *pprofBucket, profiles.Prefix); err != nil {
+			return err
+		}
+	}
+	for _, rd := range regions {
+		rd.d.RoleARN = roleARN
+	}
+
+	hdb, err := openHistory(ctx, root, db)
+	if err != nil {
+		return err
+	}
+	defer hdb.Close()
+
+	b := newBuilder(root, "", *verbose)
+	b.Pprof = *pprofBucket != ""
+	b.Reuse = !*force
+	layerZips := map[string]string{}
+	var (
+		// mu serializes builds, which already use every core, and writes
+		// to the history database; deployments, which mostly wait for
+		// Lambda, run -parallel at a time.
+		mu     sync.Mutex
+		failed = make([]int, len(targets))
+	)
+	pool.Each(ctx, len(targets), par.workers, func(ti int) {
+		t := targets[ti]
+		mu.Lock()
+		// Build once; only the upload and the function are regional.
+		a, err := b.Build(ctx, t)
+		var exts []string
+		if t.Extension {
+			exts = append(exts, build.NoopExtension)
+		}
+		if t.Chaos {
+			exts = append(exts, build.ChaosExtension)
+		}
+		if *telemetry && t.SupportsExtension() {
+			exts = append(exts, build.TelemetryExtension)
+		} else if *telemetry {
+			fmt.Fprintf(os.Stderr, "%s: deploying without the telemetry extension: layers need a zip package\n", t.ID())
+		}
+		c := deploy.ConfigFor(t)
+		c.MemoryMB, c.TimeoutSec = int32(*memory), int32(*timeout)
+		c.Tracing = *traced
+		if t.Runtime == "go" || t.Runtime == discover.TinyGo {
+			c.Env = map[string]string{}
+			if *runtimeMetrics {
+				c.Env[lambdalog.RuntimeMetricsEnv] = "1"
+			}
+			if *histogram > 0 {
+				c.Env[lambdalog.HistogramEnv] = histogramMS(*histogram)
+			}
+			if *pprofBucket != "" {
+				c.Env[profiles.Env] = *pprofBucket
+			}
+		}
+		cf.apply(t, &c)
+		zips := map[string]string{}
+		for _, ext := range exts {
+			if err != nil {
+				break
+			}
+			zips[ext], err = extensionZip(ctx, b, ext, c.Arch, layerZips)
+		}
+		mu.Unlock()
+		if err != nil {
+			failed[ti] += len(regions)
+			fmt.Fprintf(os.Stderr, "%s: %v\n", t.ID(), err)
+			return
+		}
+
+		errs := make([]error, len(regions))
+		eachRegion(regionNames(regions), func(i int, region string) {
+			errs[i] = regions[i].deploy(ctx, t, a, c, exts, zips)
+		})
+		deployed := false
+		for i, err := range errs {
+			if err != nil {
+				failed[ti]++
+				fmt.Fprintf(os.Stderr, "%s: %v\n", inRegion(t.ID(), regions[i].region), err)
+			} else {
+				deployed = true
+			}
+		}
+		if deployed {
+			mu.Lock()
+			defer mu.Unlock()
+			if err := hdb.record(ctx, a); err != nil {
+				failed[ti]++
+				fmt.Fprintf(os.Stderr, "%s: %v\n", t.ID(), err)
+			}
+		}
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var n int
+	for _, f := range failed {
+		n += f
+	}
+	if n > 0 {
+		return fmt.Errorf("%d of %d deployments failed", n, len(targets)*len(regions))
+	}
+	return nil
+}
+
+// regionDeployer deploys targets to one region. Layers, images and
+// functions are all regional.
+type regionDeployer struct {
+	region string
+	client *lambda.Client
+	d      *deploy.Deployer
+	reg    *deploy.Registry
+	// mu guards layers and network, which targets deployed in parallel
+	// share.
+	mu sync.Mutex
+	// layers holds the ARNs of the extension layers published so far, by
+	// layer name.
+	layers map[string]string
+	// secretsLayer is the region's -secrets-layer ARN, if it was given.
+	secretsLayer string
+	ec2          *vpc.Client
+	// network is the region's harness VPC, once a -vpc target has looked
+	// it up.
+	network *vpc.Network
+	// expected holds the result of every workload, for the canary invoke
+	// after each deployment; nil skips it.
+	expected map[string]string
+}
+
+func regionNames(regions []*regionDeployer) []string {
+	names := make([]string, len(regions))
+	for i, rd := range regions {
+		names[i] = rd.region
+	}
+	return names
+}
+
+// deploy deploys the built artifact a of t with configuration c, and the
+// layers of exts from their zips.
+func (rd *regionDeployer) deploy(ctx context.Context, t discover.Target, a build.Artifact, c deploy.Config, exts []string, zips map[string]string) error {
+	c.Layers = nil
+	for _, ext := range exts {
+		arn, err := rd.layer(ctx, ext, c.Arch, zips[ext])
+		if err != nil {
+			return err
+		}
+		c.Layers = append(c.Layers, arn)
+	}
+	if t.Workload == configload.ExtensionWorkload {
+		if rd.secretsLayer == "" {
+			return fmt.Errorf("%s needs the AWS Parameters and Secrets Lambda Extension: pass its layer version ARN for the region with -secrets-layer", t.Workload)
+		}
+		if c.PackageType == types.PackageTypeImage {
+			return fmt.Errorf("%s needs the extension layer, which a container image cannot have", t.Workload)
+		}
+		c.Layers = append(c.Layers, rd.secretsLayer)
+	}
+	if t.VPC {
+		if err := rd.attach(ctx, t, &c); err != nil {
+			return err
+		}
+	}
+	l := rd.lambda(t)
+	l.Config = c
+	if err := l.Deploy(ctx, a); err != nil {
+		return err
+	}
+	verdict, err := rd.canary(ctx, t, l)
+	fmt.Printf("%-32s %s %s (%s, %d MB, %s%s)\n", t.ID(), l.Deployed, inRegion(l.Function+qualified(t), rd.region), c.Arch, c.MemoryMB,
+		describeSizes(a.BinaryBytes, a.PackageBytes), verdict)
+	return err
+}
+
+// lambda returns t's function in the region, configured as ConfigFor
+// would.
+func (rd *regionDeployer) lambda(t discover.Target) *platform.Lambda {
+	return &platform.Lambda{
+		Client:    rd.client,
+		Deployer:  rd.d,
+		Function:  t.FunctionName(),
+		Config:    deploy.ConfigFor(t),
+		Qualifier: t.Qualifier(),
+		Registry:  rd.reg,
+	}
+}
+
+// canary makes the verification invoke of t just deployed as l, returning
+// what to add to its deployment line. Targets run does not invoke are not
+// checked.
+func (rd *regionDeployer) canary(ctx context.Context, t discover.Target, l *platform.Lambda) (string, error) {
+	if rd.expected == nil || invokedElsewhere(t) != "" {
+		return "", nil
+	}
+	var pf payloadFlags
+	payload, err := pf.forTarget(t)
+	if err != nil {
+		return "", err
+	}
+	res, err := canary.Check(ctx, l, payload, t.Runtime, rd.expected[t.Workload])
+	switch {
+	case err != nil:
+		return "; canary failed", err
+	case res.Runtime == "":
+		return "; canary passed, runtime not reported", nil
+	}
+	return "; canary passed", nil
+}
+
+// reachesOut lists the workloads that call AWS APIs or the internet,
+// which VPC-attached functions can only do through a NAT gateway.
+var reachesOut = []string{fixture.S3Workload, fixture.DynamoDBWorkload, async.Workload, mockapi.Workload, configload.Workload, configload.ExtensionWorkload}
+
+// attach puts c in the region's harness VPC, which ruchy-bench vpc
+// creates.
+func (rd *regionDeployer) attach(ctx context.Context, t discover.Target, c *deploy.Config) error {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	if rd.network == nil {
+		n, err := rd.ec2.Find(ctx, vpc.DefaultName)
+		if err != nil {
+			return err
+		}
+		rd.network = &n
+	}
+	c.SubnetIDs = rd.network.SubnetIDs
+	c.SecurityGroupIDs = []string{rd.network.SecurityGroupID}
+	if rd.network.NATGatewayID == "" && slices.Contains(reachesOut, t.Workload) {
+		fmt.Fprintf(os.Stderr, "%s: the VPC has no NAT gateway, so the workload's calls out will time out; add one with ruchy-bench vpc -nat\n", inRegion(t.ID(), rd.region))
+	}
+	return nil
+}
+
+// layer returns the ARN of extension ext's layer for arch, publishing the
+// zip at pkg the first time it is asked for.
+func (rd *regionDeployer) layer(ctx context.Context, ext string, arch types.Architecture, pkg string) (string, error) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+	name := deploy.LayerName(ext, arch)
+	if arn, ok := rd.layers[name]; ok {
+		return arn, nil
+	}
+	arn, created, err := deploy.PublishLayer(ctx, rd.client, name, pkg, arch)
+	if err != nil {
+		return "", err
+	}
+	state := "up to date"
+	if created {
+		state = "published"
+	}
+	fmt.Printf("%-32s %s %s\n", "layer/"+name, state, arn)
+	rd.layers[name] = arn
+	return arn, nil
+}
+
+func runTeardown(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("teardown", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	all := fs.Bool("all", false, "delete every discovered Lambda target")
+	region := fs.String("region", "", "comma-separated AWS regions to delete from in parallel (default: from AWS config)")
+	var par parallelFlags
+	par.register(fs, "targets to delete per region")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := par.validate(); err != nil {
+		return err
+	}
+	_, targets, err := resolveLambdaTargets(&tf, *all)
+	if err != nil {
+		return err
+	}
+	var regions []*regionDeployer
+	for _, r := range regionList(*region) {
+		cfg, err := loadAWSConfig(ctx, r)
+		if err != nil {
+			return err
+		}
+		client := lambda.NewFromConfig(cfg)
+		regions = append(regions, &regionDeployer{
+			region: r,
+			client: client,
+			d:      &deploy.Deployer{Client: client},
+			reg:    &deploy.Registry{Client: ecr.NewFromConfig(cfg), Repository: build.ImageRepository},
+		})
+	}
+
+	// Each region deletes -parallel targets at a time.
+	errs := make([]error, len(regions)*len(targets))
+	eachRegion(regionNames(regions), func(i int, region string) {
+		pool.Each(ctx, len(targets), par.workers, func(ti int) {
+			t := targets[ti]
+			if err := regions[i].delete(ctx, t); err != nil {
+				errs[i*len(targets)+ti] = err
+				fmt.Fprintf(os.Stderr, "%s: %v\n", inRegion(t.ID(), region), err)
+			}
+		})
+	})
+	var failed int
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deletions failed", failed, len(targets)*len(regions))
+	}
+	return nil
+}
+
+// delete deletes t's function, and its image if it has one.
+func (rd *regionDeployer) delete(ctx context.Context, t discover.Target) error {
+	l := rd.lambda(t)
+	switch err := l.Destroy(ctx); {
+	case err != nil:
+		return err
+	case l.Deleted:
+		fmt.Printf("%-32s deleted %s\n", t.ID(), inRegion(l.Function, rd.region))
+	default:
+		fmt.Printf("%-32s %s not deployed\n", t.ID(), inRegion(l.Function, rd.region))
+	}
+	return nil
+}
+
+// extensionZip returns the path of extension ext's layer zip for arch,
+// building it the first time it is asked for.
+func extensionZip(ctx context.Context, b *build.Builder, ext string, arch types.Architecture, built map[string]string) (string, error) {
+	key := ext + "/" + string(arch)
+	if pkg, ok := built[key]; ok {
+		return pkg, nil
+	}
+	pkg, err := b.BuildExtension(ctx, ext, string(arch))
+	if err != nil {
+		return "", err
+	}
+	built[key] = pkg
+	return pkg, nil
+}
+
+// qualified is the ":qualifier" suffix of t's invocation target, if any.
+func qualified(t discover.Target) string {
+	if q := t.Qualifier(); q != "" {
+		return ":" + q
+	}
+	return ""
+}
+
+// resolveLambdaTargets selects Lambda targets, refusing to act on all of
+// them unless all is set: both commands touch real AWS resources.
+func resolveLambdaTargets(tf *targetFlags, all bool) (string, []discover.Target, error) {
+	if !all && tf.runtimes == "" && tf.workloads == "" {
+		return "", nil, errors.New("select targets with -runtime/-workload, or pass -all")
+	}
+	tf.kind = string(discover.KindLambda)
+	return tf.resolve()
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/edge"
+	"lambdaperf/pkg/results"
+)
+
+// defaultProbes spread the probes over four continents.
+var defaultProbes = []string{"us-east-1", "eu-west-1", "ap-northeast-1", "sa-east-1"}
+
+// Probes are deployed with room for a long series of round trips; each
+// waits on the network, not the CPU.
+const (
+	probeMemoryMB   = 256
+	probeTimeoutSec = 900
+)
+
+func runEdge(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("edge", flag.ContinueOnError)
+	// The edge functions are the Python baselines, and their regional
+	// twins the x86_64 zip functions ruchy-bench deploy makes of them.
+	tf := targetFlags{kind: string(discover.KindLambda), runtimes: edge.Runtime}
+	fs.StringVar(&tf.root, "root", "", "repository root (default: found by walking up from the working directory)")
+	fs.StringVar(&tf.workloads, "workload", "minimal,fibonacci", "comma-separated workloads to run at the edge: "+strings.Join(edge.Workloads(), ", "))
+	probes := fs.String("probes", strings.Join(defaultProbes, ","), "comma-separated regions to probe from, in parallel")
+	n := fs.Int("n", 20, "timed requests of each function per probe, after one that opens the connections")
+	deployEdge := fs.Bool("deploy", false, "deploy the edge functions, the CloudFront distribution and the probes instead of measuring")
+	del := fs.Bool("delete", false, "delete the distribution, the probes and, once CloudFront has removed their replicas, the edge functions")
+	verbose := fs.Bool("v", false, "show build output")
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *deployEdge && *del {
+		return errors.New("-deploy and -delete are mutually exclusive")
+	}
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	for _, w := range splitList(tf.workloads) {
+		if _, ok := edge.Inputs[w]; !ok {
+			return fmt.Errorf("%s does not run at the edge: want %s", w, strings.Join(edge.Workloads(), ", "))
+		}
+	}
+	regions := splitList(*probes)
+	if len(regions) == 0 {
+		return errors.New("-probes names no region")
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(ctx, edge.Region)
+	if err != nil {
+		return err
+	}
+	cf := &edge.Client{Config: cfg}
+	switch {
+	case *deployEdge:
+		return deployEdgeFunctions(ctx, root, targets, regions, cfg, cf, *verbose)
+	case *del:
+		return deleteEdgeFunctions(ctx, targets, regions, cfg, cf)
+	}
+
+	d, err := cf.Find(ctx)
+	if errors.Is(err, edge.ErrNotFound) {
+		return fmt.Errorf("%w: run ruchy-bench edge -deploy first", err)
+	}
+	if err != nil {
+		return err
+	}
+	if !d.Deployed() {
+		return fmt.Errorf("distribution %s is still deploying (%s); try again in a few minutes", d.ID, d.Status)
+	}
+	// Both functions live in us-east-1, whichever region measures them,
+	// so their runtimes are looked up there.
+	fns := lambda.NewFromConfig(cfg)
+	runtimes := map[string]string{}
+	for _, t := range targets {
+		for _, fn := range []string{t.FunctionName(), edge.FunctionName(t.FunctionName())} {
+			out, err := fns.GetFunction(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(fn)})
+			if err != nil {
+				return fmt.Errorf("%s: %w (deploy regional twins with ruchy-bench deploy -runtime python -region %s)", fn, err, edge.Region)
+			}
+			runtimes[fn] = lambdaRuntime(out)
+		}
+	}
+
+	run := results.NewRun("edge", time.Now())
+	perProbe := make([][]results.Result, len(regions))
+	pops := make([]map[string]string, len(regions))
+	eachRegion(regions, func(i int, region string) {
+		pops[i] = map[string]string{}
+		client, clientErr := newLambdaClient(ctx, region)
+		for _, t := range targets {
+			er, rr := edgeResult(t, region), newResult(t)
+			rr.Region, rr.Input = region, edge.Inputs[t.Workload]
+			err := clientErr
+			if err == nil {
+				fmt.Fprintf(os.Stderr, "%s: %s from %s, %d requests each\n", t.ID(), d.DomainName, region, *n)
+				pops[i][t.Workload], err = probe(ctx, client, t, d.DomainName, *n, &er, &rr)
+			}
+			if err != nil {
+				er.Error, rr.Error = err.Error(), err.Error()
+				fmt.Fprintf(os.Stderr, "%s: %v\n", inRegion(t.ID(), region), err)
+			}
+			er.LambdaRuntime, rr.LambdaRuntime = runtimes[er.Function], runtimes[rr.Function]
+			perProbe[i] = append(perProbe[i], er, rr)
+			if ctx.Err() != nil {
+				return
+			}
+		}
+	})
+	for _, rs := range perProbe {
+		run.Results = append(run.Results, rs...)
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printEdge(run, regions, pops)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return ctx.Err()
+}
+
+// edgeResult starts the result record of t's edge function as measured
+// from region.
+func edgeResult(t discover.Target, region string) results.Result {
+	r := newResult(t)
+	r.Function = edge.FunctionName(t.FunctionName())
+	r.Edge = true
+	r.MemoryMB = edge.MemoryMB
+	r.Region = region
+	r.Input = edge.Inputs[t.Workload]
+	return r
+}
+
+// probe has the region's probe request t's edge function at domain and
+// invoke its regional twin n+1 times each, recording the round trips in
+// er and rr, and returns the edge location that answered.
+func probe(ctx context.Context, client *lambda.Client, t discover.Target, domain string, n int, er, rr *results.Result) (string, error) {
+	payload, err := json.Marshal(edge.ProbeEvent{
+		URL:            edge.URL(domain, t.Workload),
+		Function:       t.FunctionName(),
+		FunctionRegion: edge.Region,
+		Payload:        edge.Payload(t.Workload),
+		N:              n,
+	})
+	if err != nil {
+		return "", err
+	}
+	out, err := client.Invoke(ctx, &lambda.InvokeInput{FunctionName: aws.String(edge.ProbeFunction), Payload: payload})
+	if err != nil {
+		return "", err
+	}
+	if out.FunctionError != nil {
+		return "", fmt.Errorf("%s: %s: %s", edge.ProbeFunction, aws.ToString(out.FunctionError), out.Payload)
+	}
+	var res edge.ProbeResult
+	if err := json.Unmarshal(out.Payload, &res); err != nil {
+		return "", fmt.Errorf("%s answered %s: %w", edge.ProbeFunction, out.Payload, err)
+	}
+	er.Samples, rr.Samples = probeSamples(res.Edge), probeSamples(res.Regional)
+	// Both ran the same handler on the same input.
+	if res.EdgeBody != res.RegionalBody {
+		er.Error = fmt.Sprintf("%s %q, and the regional function %q", results.WrongResult, res.EdgeBody, res.RegionalBody)
+	}
+	return res.POP, nil
+}
+
+// probeSamples records a probe's timings, the first of which opened the
+// connection and so counts as warm-up.
+func probeSamples(ts []edge.Timing) []results.Sample {
+	samples := make([]results.Sample, len(ts))
+	for i, t := range ts {
+		samples[i] = results.Sample{Iteration: i, ClientMS: t.MS, Warmup: i == 0, Error: t.Error}
+	}
+	return samples
+}
+
+// printEdge compares, per probe and workload, the median and p95 round
+// trip of the edge function with its regional twin's.
+func printEdge(run *results.Run, regions []string, pops []map[string]string) {
+	regional := map[string]results.Result{}
+	for _, r := range run.Results {
+		if !r.Edge {
+			regional[r.Region+"/"+r.Workload] = r
+		}
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PROBE\tPOP\tWORKLOAD\tEDGE P50(ms)\tP95\tREGIONAL P50(ms)\tP95\tEDGE - REGIONAL P50")
+	for _, r := range run.Results {
+		if !r.Edge {
+			continue
+		}
+		pop := pops[slices.Index(regions, r.Region)][r.Workload]
+		if pop == "" {
+			pop = "-"
+		}
+		e, g := r.Stats[results.MetricClient], regional[r.Region+"/"+r.Workload].Stats[results.MetricClient]
+		if e.N == 0 || g.N == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t-\t-\t-\t-\t-\n", r.Region, pop, r.Workload)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t%+.2f (%+.0f%%)\n", r.Region, pop, r.Workload,
+			e.Median, e.P95, g.Median, g.P95, e.Median-g.Median, 100*(e.Median-g.Median)/g.Median)
+	}
+	w.Flush()
+	fmt.Println("\nRound trips from each probe region: to the nearest CloudFront location for the edge, to us-east-1 for the regional function.")
+}
+
+// deployEdgeFunctions deploys and publishes targets' edge functions in
+// us-east-1, routes the distribution to them, and deploys a probe in
+// every region, which may invoke the regional twins.
+func deployEdgeFunctions(ctx context.Context, root string, targets []discover.Target, regions []string, cfg aws.Config, cf *edge.Client, verbose bool) error {
+	roles := iam.NewFromConfig(cfg)
+	roleARN, err := deploy.EnsureRole(ctx, roles, deploy.DefaultRoleName)
+	if err != nil {
+		return err
+	}
+	// Role ARNs end in the role's name, after any path.
+	roleName := roleARN[strings.LastIndex(roleARN, "/")+1:]
+	if err := deploy.TrustEdge(ctx, roles, roleName); err != nil {
+		return err
+	}
+	client := lambda.NewFromConfig(cfg)
+	d := &deploy.Deployer{Client: client, RoleARN: roleARN}
+	b := newBuilder(root, "", verbose)
+	versions := map[string]string{}
+	var twins []string
+	for _, t := range targets {
+		pkg, err := b.BuildEdge(ctx, t)
+		if err != nil {
+			return err
+		}
+		c := deploy.ConfigFor(t)
+		c.Handler, c.MemoryMB, c.TimeoutSec = edge.Handler, edge.MemoryMB, edge.TimeoutSec
+		fn := edge.FunctionName(t.FunctionName())
+		action, err := d.Deploy(ctx, fn, pkg, c)
+		if err != nil {
+			return err
+		}
+		version, err := d.Publish(ctx, fn, edge.Alias)
+		if err != nil {
+			return err
+		}
+		out, err := client.GetFunction(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(fn)})
+		if err != nil {
+			return fmt.Errorf("get %s: %w", fn, err)
+		}
+		arn := aws.ToString(out.Configuration.FunctionArn)
+		versions[t.Workload] = arn + ":" + version
+		// The twin is fn's namesake, in the same account and region.
+		twins = append(twins, strings.TrimSuffix(arn, fn)+t.FunctionName())
+		fmt.Printf("%-32s %s %s version %s\n", t.ID(), action, fn, version)
+	}
+	dist, err := cf.Ensure(ctx, versions)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("distribution %s: https://%s\n", dist.ID, dist.DomainName)
+
+	pkg, err := b.BuildProbe(ctx)
+	if err != nil {
+		return err
+	}
+	if err := deploy.GrantInvoke(ctx, roles, roleName, twins); err != nil {
+		return err
+	}
+	errs := make([]error, len(regions))
+	eachRegion(regions, func(i int, region string) {
+		rcfg, err := loadAWSConfig(ctx, region)
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		pd := &deploy.Deployer{Client: lambda.NewFromConfig(rcfg), RoleARN: roleARN}
+		action, err := pd.Deploy(ctx, edge.ProbeFunction, pkg, deploy.Config{
+			Runtime:     types.RuntimeProvidedal2023,
+			Handler:     "bootstrap",
+			Arch:        types.ArchitectureX8664,
+			MemoryMB:    probeMemoryMB,
+			TimeoutSec:  probeTimeoutSec,
+			EphemeralMB: deploy.DefaultEphemeralMB,
+		})
+		if err != nil {
+			errs[i] = fmt.Errorf("%s: %w", inRegion(edge.ProbeFunction, region), err)
+			return
+		}
+		fmt.Printf("%-32s %s %s\n", "probe", action, inRegion(edge.ProbeFunction, region))
+	})
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "waiting for CloudFront to deploy the distribution to every edge location, which takes a few minutes")
+	if _, err := cf.Wait(ctx, dist.ID); err != nil {
+		return err
+	}
+	fmt.Printf("distribution %s deployed; the regional twins are the functions ruchy-bench deploy -runtime python -region %s makes\n", dist.ID, edge.Region)
+	return nil
+}
+
+// deleteEdgeFunctions deletes the distribution and the probes, then tries
+// the edge functions, which Lambda refuses to delete until CloudFront has
+// removed their replicas, a few hours after the distribution is gone.
+func deleteEdgeFunctions(ctx context.Context, targets []discover.Target, regions []string, cfg aws.Config, cf *edge.Client) error {
+	fmt.Fprintln(os.Stderr, "disabling the distribution before deleting it, which takes a few minutes")
+	deleted, err := cf.Delete(ctx)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		fmt.Println("deleted distribution", edge.Name)
+	} else {
+		fmt.Println("no distribution", edge.Name)
+	}
+
+	failures := make([]int, len(regions))
+	eachRegion(regions, func(i int, region string) {
+		rcfg, err := loadAWSConfig(ctx, region)
+		if err == nil {
+			var deleted bool
+			deleted, err = (&deploy.Deployer{Client: lambda.NewFromConfig(rcfg)}).Delete(ctx, edge.ProbeFunction)
+			if err == nil && deleted {
+				fmt.Printf("%-32s deleted %s\n", "probe", inRegion(edge.ProbeFunction, region))
+			} else if err == nil {
+				fmt.Printf("%-32s %s not deployed\n", "probe", inRegion(edge.ProbeFunction, region))
+			}
+		}
+		if err != nil {
+			failures[i]++
+			fmt.Fprintf(os.Stderr, "%s: %v\n", inRegion(edge.ProbeFunction, region), err)
+		}
+	})
+
+	d := &deploy.Deployer{Client: lambda.NewFromConfig(cfg)}
+	var replicated int
+	for _, t := range targets {
+		fn := edge.FunctionName(t.FunctionName())
+		deleted, err := d.Delete(ctx, fn)
+		var invalid *types.InvalidParameterValueException
+		switch {
+		case errors.As(err, &invalid):
+			// "Lambda was unable to delete ... because it is a replicated
+			// function."
+			replicated++
+			fmt.Printf("%-32s %s is still replicated\n", t.ID(), fn)
+		case err != nil:
+			failures[0]++
+			fmt.Fprintf(os.Stderr, "%s: %v\n", t.ID(), err)
+		case deleted:
+			fmt.Printf("%-32s deleted %s\n", t.ID(), fn)
+		default:
+			fmt.Printf("%-32s %s not deployed\n", t.ID(), fn)
+		}
+	}
+	if replicated > 0 {
+		fmt.Fprintf(os.Stderr, "%d edge functions keep their replicas for a few hours after the distribution is deleted; run ruchy-bench edge -delete again then\n", replicated)
+	}
+	var failed int
+	for _, n := range failures {
+		failed += n
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d deletions failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/errorpath"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+func runErrors(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("errors", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	n := fs.Int("n", 10, "invocations per target")
+	var rf retryFlags
+	rf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	var bf budgetFlags
+	bf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if err := rf.validate(); err != nil {
+		return err
+	}
+	tf.kind = string(discover.KindLambda)
+	if tf.workloads == "" {
+		tf.workloads = strings.Join(errorpath.Workloads, ",")
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if !errorpath.Fails(t.Workload) {
+			return fmt.Errorf("%s: errors only invokes the %s workloads", t.ID(), strings.Join(errorpath.Workloads, ", "))
+		}
+	}
+	client, err := newLambdaClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	run := results.NewRun("errors", time.Now())
+	for _, t := range targets {
+		res := newResult(t)
+		inv := rf.wrap(&invoke.Lambda{Client: client, FunctionName: res.Function, Qualifier: t.Qualifier()})
+		fmt.Fprintf(os.Stderr, "%s: %d invocations\n", t.ID(), *n)
+		// No warm-up: invocations that crash or time out replace their
+		// environment, so there is no steady state to wait for, and
+		// whether the next one starts cold is part of the measurement.
+		res.Samples, _ = results.Collect(ctx, *n, stats.Warmup{}, stats.Precision{}, func(i int) results.Sample {
+			return errorSample(ctx, inv, t.Workload, i)
+		})
+		run.Results = append(run.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printErrors(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return context.Cause(ctx)
+}
+
+// errorSample makes one invocation of a failing workload. The sample
+// succeeds when the invocation failed the way the workload should; an
+// invocation that returned a response, or failed otherwise, is the error.
+func errorSample(ctx context.Context, inv invoke.Invoker, workload string, i int) results.Sample {
+	resp, err := inv.Invoke(ctx, []byte("{}"))
+	s := results.Sample{
+		Iteration: i,
+		ClientMS:  results.Milliseconds(resp.Elapsed),
+		Retries:   resp.Retries,
+		Response:  string(resp.Payload),
+	}
+	if r, ok := reportparser.Last(resp.LogTail); ok {
+		s = s.WithReport(r)
+	}
+	if err != nil {
+		s.Error, s.Excluded = err.Error(), string(invoke.Classify(err))
+		return s
+	}
+	surface := errorpath.Read(resp.FunctionError, resp.Payload, resp.LogTail)
+	s.Surface = &surface
+	if err := errorpath.Expect(workload, surface); err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+// printErrors shows, per failing workload and runtime, how long the caller
+// waited for the failure, how many invocations started cold, and the
+// surface most of them reported, then how each runtime's surface differs
+// from that of the first runtime measured on the same workload.
+func printErrors(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKLOAD\tRUNTIME\tN\tCLIENT P50(ms)\tDURATION P50(ms)\tCOLD(%)\tSURFACE")
+	type measured struct {
+		r       results.Result
+		surface errorpath.Surface
+	}
+	var rows []measured
+	for _, r := range run.Results {
+		client := r.Stats[results.MetricClient]
+		if client.N == 0 {
+			fmt.Fprintf(w, "%s\t%s\t0\terror: %s\n", r.Workload, runtimeLabel(r), resultError(r))
+			continue
+		}
+		duration := "-"
+		if d := r.Stats[results.MetricDuration]; d.N > 0 {
+			duration = fmt.Sprintf("%.2f", d.Median)
+		}
+		surface := commonSurface(r)
+		rows = append(rows, measured{r, surface})
+		fmt.Fprintf(w, "%s\t%s\t%d\t%.2f\t%s\t%.0f\t%s\n", r.Workload, runtimeLabel(r), client.N,
+			client.Median, duration, 100*coldShare(r), surface)
+	}
+	w.Flush()
+
+	first := map[string]measured{}
+	for _, m := range rows {
+		base, ok := first[m.r.Workload]
+		if !ok {
+			first[m.r.Workload] = m
+			continue
+		}
+		if diffs := errorpath.Differences(base.surface, m.surface); len(diffs) > 0 {
+			fmt.Printf("%s: %s and %s differ in %s\n", m.r.Workload, runtimeLabel(base.r), runtimeLabel(m.r), strings.Join(diffs, "; "))
+		} else {
+			fmt.Printf("%s: %s reports failures like %s\n", m.r.Workload, runtimeLabel(m.r), runtimeLabel(base.r))
+		}
+	}
+}
+
+// commonSurface returns the surface most successful samples of r
+// reported.
+func commonSurface(r results.Result) errorpath.Surface {
+	counts := map[string]int{}
+	var best errorpath.Surface
+	most := 0
+	for _, s := range r.Samples {
+		if s.Error != "" || s.Warmup || s.Surface == nil {
+			continue
+		}
+		key := s.Surface.String()
+		if counts[key]++; counts[key] > most {
+			best, most = *s.Surface, counts[key]
+		}
+	}
+	return best
+}
+
+// coldShare is the fraction of r's successful samples that started cold.
+func coldShare(r results.Result) float64 {
+	var cold, n int
+	for _, s := range r.Samples {
+		if s.Error != "" || s.Warmup {
+			continue
+		}
+		n++
+		if s.Cold {
+			cold++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(cold) / float64(n)
+}
+
+// resultError is why r has no successful samples.
+func resultError(r results.Result) string {
+	if r.Error != "" {
+		return r.Error
+	}
+	for _, s := range r.Samples {
+		if s.Error != "" {
+			return s.Error
+		}
+	}
+	return "no samples"
+}
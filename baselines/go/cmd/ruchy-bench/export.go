@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/lambdalog"
+)
+
+func runExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	all := fs.Bool("all", false, "export every discovered Lambda target")
+	format := fs.String("format", "terraform", "infrastructure code to write (only terraform)")
+	memory := fs.String("memory", strconv.Itoa(deploy.DefaultMemoryMB), "comma-separated memory sizes in MB; each size past one gets its own functions")
+	timeout := fs.Int("timeout", deploy.DefaultTimeoutSec, "function timeout in seconds")
+	traced := fs.Bool("tracing", false, "enable active X-Ray tracing and grant the execution role write access to X-Ray")
+	telemetry := fs.Bool("telemetry", false, "attach the telemetry extension, which logs Telemetry API phase timings (zip packages only)")
+	runtimeMetrics := fs.Bool("runtime-metrics", false, "have Go baselines report heap, GC and goroutine metrics with every response")
+	histogram := fs.Duration("histogram", 0, "have Go baselines rerun the workload for this long in every invocation and report a histogram of the runs' times")
+	role := fs.String("role", "", "existing execution role ARN (default: declare "+deploy.DefaultRoleName+")")
+	out := fs.String("out", "", "directory to write main.tf and the packages it deploys to (default: <root>/.bench/terraform)")
+	verbose := fs.Bool("v", false, "show compiler and build script output")
+	var cf chaosFlags
+	cf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := cf.validate(); err != nil {
+		return err
+	}
+	if *format != "terraform" {
+		return fmt.Errorf("unsupported -format %q: want terraform", *format)
+	}
+	sizes, err := parseSizes(*memory)
+	if err != nil {
+		return err
+	}
+	root, targets, err := resolveLambdaTargets(&tf, *all)
+	if err != nil {
+		return err
+	}
+	if tf.vpc {
+		// The harness VPC is made by ruchy-bench vpc, not Terraform.
+		return errors.New("export does not support -vpc")
+	}
+	dir := *out
+	if dir == "" {
+		dir = filepath.Join(root, ".bench", "terraform")
+	}
+	// Packages are built inside the module, so it can be applied, or
+	// committed, as a whole.
+	b := newBuilder(root, filepath.Join(dir, "packages"), *verbose)
+	rel := func(path string) (string, error) { return filepath.Rel(dir, path) }
+
+	fleet := deploy.Fleet{RoleARN: *role, ImageRepository: build.ImageRepository}
+	layerZips := map[string]string{}
+	for _, t := range targets {
+		a, err := b.Build(ctx, t)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.ID(), err)
+		}
+		c := deploy.ConfigFor(t)
+		c.TimeoutSec = int32(*timeout)
+		c.Tracing = *traced
+		if t.Runtime == "go" || t.Runtime == "tinygo" {
+			c.Env = map[string]string{}
+			if *runtimeMetrics {
+				c.Env[lambdalog.RuntimeMetricsEnv] = "1"
+			}
+			if *histogram > 0 {
+				c.Env[lambdalog.HistogramEnv] = histogramMS(*histogram)
+			}
+		}
+		cf.apply(t, &c)
+		var exts []string
+		if t.Extension {
+			exts = append(exts, build.NoopExtension)
+		}
+		if t.Chaos {
+			exts = append(exts, build.ChaosExtension)
+		}
+		if *telemetry && t.SupportsExtension() {
+			exts = append(exts, build.TelemetryExtension)
+		} else if *telemetry {
+			fmt.Fprintf(os.Stderr, "%s: exporting without the telemetry extension: layers need a zip package\n", t.ID())
+		}
+		var layers []string
+		for _, ext := range exts {
+			zip, err := extensionZip(ctx, b, ext, c.Arch, layerZips)
+			if err != nil {
+				return fmt.Errorf("%s: %w", t.ID(), err)
+			}
+			name := deploy.LayerName(ext, c.Arch)
+			layers = append(layers, name)
+			if !slices.ContainsFunc(fleet.Layers, func(l deploy.FleetLayer) bool { return l.Name == name }) {
+				pkg, err := rel(zip)
+				if err != nil {
+					return err
+				}
+				fleet.Layers = append(fleet.Layers, deploy.FleetLayer{Name: name, Package: pkg, Arch: c.Arch})
+			}
+		}
+		var pkg string
+		if a.Image == "" {
+			if pkg, err = rel(a.Package); err != nil {
+				return err
+			}
+		}
+		for _, mb := range sizes {
+			c.MemoryMB = mb
+			fn := deploy.FleetFunction{
+				Name:     t.FunctionName(),
+				Config:   c,
+				Package:  pkg,
+				ImageTag: t.FunctionName(),
+				Layers:   layers,
+			}
+			// The other commands measure functions by target name, so a
+			// single size keeps it.
+			if len(sizes) > 1 {
+				fn.Name += fmt.Sprintf("-%dmb", mb)
+			}
+			fleet.Functions = append(fleet.Functions, fn)
+		}
+	}
+
+	path := filepath.Join(dir, "main.tf")
+	if err := os.WriteFile(path, deploy.Terraform(fleet), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d functions written to %s\n", len(fleet.Functions), path)
+	return nil
+}
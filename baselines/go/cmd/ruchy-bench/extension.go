@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"lambdaperf/pkg/results"
+)
+
+// variantPair is a result measured with a variant of its target, such as
+// the extension attached, and its twin without.
+type variantPair struct{ bare, variant results.Result }
+
+// variantPairs pairs every successful result of run whose variant flag
+// is set with its twin in the same run, which differs only in the flag.
+func variantPairs(run *results.Run, set func(*results.Result) *bool) []variantPair {
+	key := func(r results.Result) string {
+		*set(&r) = false
+		return variantKey(r)
+	}
+	bare := map[string]results.Result{}
+	for _, r := range run.Results {
+		if !*set(&r) && r.Error == "" {
+			bare[key(r)] = r
+		}
+	}
+	var pairs []variantPair
+	for _, r := range run.Results {
+		if b, ok := bare[key(r)]; ok && *set(&r) && r.Error == "" {
+			pairs = append(pairs, variantPair{b, r})
+		}
+	}
+	return pairs
+}
+
+// variantKey identifies r's target and configuration, variants included.
+func variantKey(r results.Result) string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%d/%s/%t/%t/%t/%t/%s", r.Kind, r.Runtime, r.Workload, r.Arch, r.Package, r.MemoryMB, r.Region,
+		r.SnapStart, r.Extension, r.VPC, r.Chaos, r.Serializer)
+}
+
+// printExtensionOverhead compares every result measured with the
+// extension attached (-extension) with its bare twin in the same run:
+// median init duration, median warm duration (or duration, for cold
+// starts) and peak memory, each with the difference the extension made.
+// It prints nothing when the run has no such pairs.
+func printExtensionOverhead(run *results.Run) {
+	pairs := variantPairs(run, func(r *results.Result) *bool { return &r.Extension })
+	if len(pairs) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tINIT P50(ms)\t+EXT\tWARM P50(ms)\t+EXT\tMAX MEM(MB)\t+EXT")
+	for _, p := range pairs {
+		init := overhead(p.bare.Stats[results.MetricInit].Median, p.variant.Stats[results.MetricInit].Median, p.variant.Stats[results.MetricInit].N)
+		warm := overhead(p.bare.Stats[warmMetric(p)].Median, p.variant.Stats[warmMetric(p)].Median, p.variant.Stats[warmMetric(p)].N)
+		mem := overhead(p.bare.Stats[results.MetricMaxMemory].Max, p.variant.Stats[results.MetricMaxMemory].Max, p.variant.Stats[results.MetricMaxMemory].N)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", inRegion(p.variant.Function, p.variant.Region), init, warm, mem)
+	}
+	w.Flush()
+}
+
+// printVPCOverhead compares every result of a function attached to the
+// harness VPC (-vpc) with its twin outside it in the same run: median
+// init duration, median warm duration (or duration, for cold starts) and
+// median client-side latency, the last of which includes whatever the
+// VPC adds in front of the function. It prints nothing when the run has
+// no such pairs.
+func printVPCOverhead(run *results.Run) {
+	pairs := variantPairs(run, func(r *results.Result) *bool { return &r.VPC })
+	if len(pairs) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tINIT P50(ms)\t+VPC\tWARM P50(ms)\t+VPC\tCLIENT P50(ms)\t+VPC")
+	for _, p := range pairs {
+		init := overhead(p.bare.Stats[results.MetricInit].Median, p.variant.Stats[results.MetricInit].Median, p.variant.Stats[results.MetricInit].N)
+		warm := overhead(p.bare.Stats[warmMetric(p)].Median, p.variant.Stats[warmMetric(p)].Median, p.variant.Stats[warmMetric(p)].N)
+		client := overhead(p.bare.Stats[results.MetricClient].Median, p.variant.Stats[results.MetricClient].Median, p.variant.Stats[results.MetricClient].N)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", inRegion(p.variant.Function, p.variant.Region), init, warm, client)
+	}
+	w.Flush()
+}
+
+// printChaos compares every result measured under the chaos extension
+// (-chaos) with its twin alone in the same run: median and p99 warm
+// duration (or duration, for cold starts), which say how gracefully the
+// runtime degrades under contention, and the share of invocations that
+// failed under it, which says whether it degraded at all gracefully. It
+// prints nothing when the run has no such pairs.
+func printChaos(run *results.Run) {
+	pairs := variantPairs(run, func(r *results.Result) *bool { return &r.Chaos })
+	if len(pairs) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tWARM P50(ms)\t+CHAOS\tWARM P99(ms)\t+CHAOS\tFAILED\tUNDER CHAOS")
+	for _, p := range pairs {
+		m := warmMetric(p)
+		p50 := overhead(p.bare.Stats[m].Median, p.variant.Stats[m].Median, p.variant.Stats[m].N)
+		p99 := overhead(p.bare.Stats[m].P99, p.variant.Stats[m].P99, p.variant.Stats[m].N)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", inRegion(p.variant.Function, p.variant.Region), p50, p99,
+			failedShare(p.bare), failedShare(p.variant))
+	}
+	w.Flush()
+}
+
+// failedShare is the percentage of r's samples that failed.
+func failedShare(r results.Result) string {
+	if len(r.Samples) == 0 {
+		return "-"
+	}
+	failed := 0
+	for _, s := range r.Samples {
+		if s.Error != "" {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(failed)/float64(len(r.Samples)))
+}
+
+// warmMetric is the warm duration metric for p, or the duration for cold
+// starts, which have none.
+func warmMetric(p variantPair) string {
+	if p.variant.Stats[results.MetricWarm].N == 0 {
+		return results.MetricDuration
+	}
+	return results.MetricWarm
+}
+
+// overhead formats a bare value and the variant's difference to it as
+// two columns, or dashes when the variant result has no samples of it.
+func overhead(bare, variant float64, n int) string {
+	if n == 0 || bare == 0 {
+		return "-\t-"
+	}
+	return fmt.Sprintf("%.2f\t%+.2f (%+.0f%%)", bare, variant-bare, 100*(variant-bare)/bare)
+}
+
+// printSerializers compares every result of a Go baseline built with
+// another JSON serializer (-serializer) with its encoding/json twin in the
+// same run: median duration, mean event decoding and median client-side
+// latency. It prints nothing when the run has no such pairs.
+func printSerializers(run *results.Run) {
+	std := map[string]results.Result{}
+	for _, r := range run.Results {
+		if r.Serializer == "" && r.Error == "" {
+			std[variantKey(r)] = r
+		}
+	}
+	var pairs []variantPair
+	for _, r := range run.Results {
+		bare := r
+		bare.Serializer = ""
+		if b, ok := std[variantKey(bare)]; ok && r.Serializer != "" && r.Error == "" {
+			pairs = append(pairs, variantPair{b, r})
+		}
+	}
+	if len(pairs) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tSERIALIZER\tDURATION P50(ms)\tVS STD\tDECODE MEAN(ms)\tVS STD\tCLIENT P50(ms)\tVS STD")
+	for _, p := range pairs {
+		duration := overhead(p.bare.Stats[results.MetricDuration].Median, p.variant.Stats[results.MetricDuration].Median, p.variant.Stats[results.MetricDuration].N)
+		decode := overhead(p.bare.Stats[results.MetricDecode].Mean, p.variant.Stats[results.MetricDecode].Mean, p.variant.Stats[results.MetricDecode].N)
+		client := overhead(p.bare.Stats[results.MetricClient].Median, p.variant.Stats[results.MetricClient].Median, p.variant.Stats[results.MetricClient].N)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", inRegion(p.variant.Function, p.variant.Region), p.variant.Serializer, duration, decode, client)
+	}
+	w.Flush()
+}
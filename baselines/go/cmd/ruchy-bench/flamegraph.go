@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/flamegraph"
+	"lambdaperf/pkg/profiles"
+)
+
+func runFlamegraph(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("flamegraph", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench flamegraph [flags] WORKLOAD")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	runtimes := fs.String("runtime", "go,rust,ruchy", "comma-separated runtimes to draw")
+	files := map[string][]string{}
+	fs.Func("profile", "RUNTIME=FILE: a runtime's profile, as pprof, perf script, DTrace or folded stacks output (repeatable; a runtime's files are merged)", func(v string) error {
+		rt, file, ok := strings.Cut(v, "=")
+		if !ok || rt == "" || file == "" {
+			return fmt.Errorf("want RUNTIME=FILE, got %q", v)
+		}
+		files[rt] = append(files[rt], file)
+		return nil
+	})
+	bucket := fs.String("bucket", "", "S3 bucket to take the Go baseline's CPU profiles from, uploaded by a deploy -pprof function")
+	last := fs.Int("last", 0, "use only the newest N profiles from -bucket (default: all)")
+	record := fs.Bool("record", false, "profile the local target of runtimes without a -profile with perf record")
+	n := fs.Int("n", 20, "runs to record with -record")
+	out := fs.String("o", "", "output directory (default: <root>/benchmarks/reports/flamegraphs)")
+	region := fs.String("region", "", "AWS region of -bucket (default: from AWS config)")
+	verbose := fs.Bool("v", false, "show compiler and perf output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// Accept flags on either side of the workload.
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("flamegraph needs a workload")
+	}
+	workload := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if *n < 1 {
+		return fmt.Errorf("invalid -n %d", *n)
+	}
+	dir, err := findRoot(*root)
+	if err != nil {
+		return err
+	}
+	all, err := discover.Discover(dir)
+	if err != nil {
+		return err
+	}
+	// target returns the default variant of runtime's workload of kind.
+	target := func(kind discover.Kind, runtime string) (discover.Target, bool) {
+		ts := discover.Filter(all, kind, []string{runtime}, []string{workload})
+		ts = discover.WithPackages(discover.WithArchs(ts, nil), nil)
+		if len(ts) == 0 {
+			return discover.Target{}, false
+		}
+		return ts[0], true
+	}
+	if *out == "" {
+		*out = filepath.Join(dir, "benchmarks", "reports", "flamegraphs")
+	}
+	graphDir := filepath.Join(*out, workload)
+	if err := os.MkdirAll(graphDir, 0o755); err != nil {
+		return err
+	}
+	var s3Client profiles.API
+	b := newBuilder(dir, "", *verbose)
+
+	var graphs []flamegraph.Graph
+	for _, rt := range splitList(*runtimes) {
+		var (
+			p      flamegraph.Profile
+			source string
+			err    error
+		)
+		switch {
+		case len(files[rt]) > 0:
+			p, err = parseProfiles(files[rt])
+			source = strings.Join(files[rt], ", ")
+		case rt == "go" && *bucket != "":
+			t, ok := target(discover.KindLambda, rt)
+			if !ok {
+				err = fmt.Errorf("no Lambda target for %s", workload)
+				break
+			}
+			if s3Client == nil {
+				cfg, err := loadAWSConfig(ctx, *region)
+				if err != nil {
+					return err
+				}
+				s3Client = s3.NewFromConfig(cfg)
+			}
+			var paths []string
+			paths, err = profiles.Fetch(ctx, s3Client, *bucket, t.FunctionName(), profiles.CPU, filepath.Join(dir, ".bench"), *last)
+			if err == nil && len(paths) == 0 {
+				err = fmt.Errorf("no CPU profiles of %s in s3://%s; deploy it with -pprof %s and invoke it", t.FunctionName(), *bucket, *bucket)
+			}
+			if err == nil {
+				p, err = parseProfiles(paths)
+				source = fmt.Sprintf("%d pprof CPU profiles of %s", len(paths), t.FunctionName())
+			}
+		case *record:
+			t, ok := target(discover.KindLocal, rt)
+			if !ok {
+				err = fmt.Errorf("no local target for %s", workload)
+				break
+			}
+			p, err = recordPerf(ctx, b, t, *n, *verbose)
+			source = fmt.Sprintf("perf record, %d runs of %s", *n, t.ID())
+		default:
+			hint := "-profile " + rt + "=FILE or -record"
+			if rt == "go" {
+				hint = "-profile go=FILE, -bucket or -record"
+			}
+			err = errors.New("no profile; pass " + hint)
+		}
+		if err == nil {
+			err = writeGraph(filepath.Join(graphDir, rt+".svg"), rt+" "+workload, p)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", rt, err)
+			continue
+		}
+		graphs = append(graphs, flamegraph.Graph{Runtime: rt, File: rt + ".svg", Source: source})
+	}
+	if len(graphs) == 0 {
+		return errors.New("no flame graphs drawn")
+	}
+	page := filepath.Join(graphDir, "index.html")
+	f, err := os.Create(page)
+	if err != nil {
+		return err
+	}
+	if err := flamegraph.Page(f, workload, graphs); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Println(page)
+	return nil
+}
+
+// parseProfiles reads and merges the profiles at paths.
+func parseProfiles(paths []string) (flamegraph.Profile, error) {
+	var ps []flamegraph.Profile
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return flamegraph.Profile{}, err
+		}
+		p, err := flamegraph.Parse(data)
+		if err != nil {
+			return flamegraph.Profile{}, fmt.Errorf("%s: %w", path, err)
+		}
+		ps = append(ps, p)
+	}
+	return flamegraph.Merge(ps...), nil
+}
+
+// recordPerf builds local target t and profiles n runs of it with perf
+// record. DWARF call graphs unwind the Rust and Ruchy binaries, which are
+// built without frame pointers.
+func recordPerf(ctx context.Context, b *build.Builder, t discover.Target, n int, verbose bool) (flamegraph.Profile, error) {
+	if _, err := exec.LookPath("perf"); err != nil {
+		return flamegraph.Profile{}, errors.New("-record needs perf (linux-tools)")
+	}
+	a, err := b.Build(ctx, t)
+	if err != nil {
+		return flamegraph.Profile{}, err
+	}
+	tmp, err := os.MkdirTemp("", "ruchy-bench-perf-")
+	if err != nil {
+		return flamegraph.Profile{}, err
+	}
+	defer os.RemoveAll(tmp)
+	var ps []flamegraph.Profile
+	for i := range n {
+		data := filepath.Join(tmp, fmt.Sprintf("%d.data", i))
+		rec := exec.CommandContext(ctx, "perf", append([]string{"record", "-F", "999", "--call-graph", "dwarf", "-q", "-o", data, "--"}, a.Command...)...)
+		rec.Dir = t.Dir
+		if verbose {
+			rec.Stderr = os.Stderr
+		}
+		if err := rec.Run(); err != nil {
+			return flamegraph.Profile{}, fmt.Errorf("perf record: %w", err)
+		}
+		script, err := exec.CommandContext(ctx, "perf", "script", "-i", data).Output()
+		if err != nil {
+			return flamegraph.Profile{}, fmt.Errorf("perf script: %w", err)
+		}
+		p, err := flamegraph.ParsePerf(bytes.NewReader(script))
+		if err != nil {
+			return flamegraph.Profile{}, err
+		}
+		ps = append(ps, p)
+	}
+	return flamegraph.Merge(ps...), nil
+}
+
+func writeGraph(path, title string, p flamegraph.Profile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := flamegraph.SVG(f, title, p); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/gc"
+	"lambdaperf/pkg/keepwarm"
+	"lambdaperf/pkg/queue"
+	"lambdaperf/pkg/stepfn"
+)
+
+func runGC(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	region := fs.String("region", "", "comma-separated AWS regions to collect from in parallel (default: from AWS config)")
+	dryRun := fs.Bool("dry-run", false, "list the harness resources without deleting them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var (
+		collectors []*gc.Collector
+		names      []string
+		// Roles are global; they go last, once no region's functions run
+		// as them.
+		roles *gc.Collector
+	)
+	for _, r := range regionList(*region) {
+		cfg, err := loadAWSConfig(ctx, r)
+		if err != nil {
+			return err
+		}
+		collectors = append(collectors, &gc.Collector{
+			Region:        cfg.Region,
+			StateMachines: &stepfn.Client{Config: cfg},
+			Rules:         &keepwarm.Client{Config: cfg},
+			Lambda:        lambda.NewFromConfig(cfg),
+			Logs:          cloudwatchlogs.NewFromConfig(cfg),
+			ECR:           ecr.NewFromConfig(cfg),
+			Queues:        &queue.Client{Config: cfg},
+			DynamoDB:      dynamodb.NewFromConfig(cfg),
+			S3:            s3.NewFromConfig(cfg),
+			Prefixes:      discover.FunctionPrefixes,
+		})
+		names = append(names, cfg.Region)
+		if roles == nil {
+			roles = &gc.Collector{IAM: iam.NewFromConfig(cfg)}
+		}
+	}
+
+	found := make([][]gc.Resource, len(collectors))
+	errs := make([]error, len(collectors))
+	eachRegion(names, func(i int, _ string) {
+		found[i], errs[i] = collectors[i].Find(ctx)
+	})
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("%s: %w", names[i], err)
+		}
+	}
+	global, err := roles.Find(ctx)
+	if err != nil {
+		return err
+	}
+
+	all := append(found, global)
+	var total, failed int
+	for _, rs := range all {
+		total += len(rs)
+	}
+	if total == 0 {
+		fmt.Println("no harness resources found")
+		return nil
+	}
+	if *dryRun {
+		for _, rs := range all {
+			for _, r := range rs {
+				fmt.Printf("would delete %s %s\n", r.Kind, inRegion(r.Name, r.Region))
+			}
+		}
+		fmt.Printf("dry run: %d resource(s) found, nothing was deleted\n", total)
+		return nil
+	}
+	// Each region deletes its resources in order, functions first.
+	del := func(c *gc.Collector, rs []gc.Resource) int {
+		var n int
+		for _, r := range rs {
+			if err := c.Delete(ctx, r); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", inRegion(r.Name, r.Region), err)
+				n++
+				continue
+			}
+			fmt.Printf("deleted %s %s\n", r.Kind, inRegion(r.Name, r.Region))
+		}
+		return n
+	}
+	fails := make([]int, len(collectors))
+	eachRegion(names, func(i int, _ string) {
+		fails[i] = del(collectors[i], found[i])
+	})
+	for _, n := range fails {
+		failed += n
+	}
+	failed += del(roles, global)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deletions failed", failed, total)
+	}
+	return nil
+}
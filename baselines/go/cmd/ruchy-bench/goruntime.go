@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/results"
+)
+
+// printGoRuntime shows the mean Go runtime activity per invocation of
+// results from Go baselines deployed with -runtime-metrics. GC SHARE is
+// the part of the mean duration, or client
+// time where there is no REPORT line, spent in stop-the-world GC pauses: a
+// large one says the collector, not the code, is what a faster runtime
+// beats. It prints nothing when no result has the metrics.
+func printGoRuntime(run *results.Run) {
+	var rows []results.Result
+	for _, r := range run.Results {
+		if r.Stats[results.MetricGoAllocBytes].N > 0 {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tALLOC(KB)\tALLOCS\tGC CYCLES\tGC PAUSE(ms)\tGC SHARE\tHEAP(MB)\tGOROUTINES")
+	for _, r := range rows {
+		mean := func(metric string) float64 { return r.Stats[metric].Mean }
+		share, d := "-", mean(results.MetricDuration)
+		if d == 0 {
+			d = mean(results.MetricClient)
+		}
+		if d > 0 {
+			share = fmt.Sprintf("%.1f%%", 100*mean(results.MetricGoGCPause)/d)
+		}
+		fmt.Fprintf(w, "%s\t%.1f\t%.0f\t%.2f\t%.3f\t%s\t%.1f\t%.0f\n", r.Function,
+			mean(results.MetricGoAllocBytes)/1024, mean(results.MetricGoAllocs), mean(results.MetricGoGCCycles),
+			mean(results.MetricGoGCPause), share, mean(results.MetricGoHeapBytes)/(1<<20), mean(results.MetricGoGoroutines))
+	}
+	w.Flush()
+}
+
+// printGoInit splits the mean Init Duration of Go baselines' cold starts
+// by what the baselines measured themselves: TO HANDLER runs from the
+// handler package's initialization to the first handler entry, FIRST
+// DECODE is the first event's decoding, which runs after Init Duration
+// ends. The rest of INIT is the process start and the Go runtime's and
+// imported packages' initialization. It prints nothing when no result has
+// the metrics.
+func printGoInit(run *results.Run) {
+	var rows []results.Result
+	for _, r := range run.Results {
+		if r.Stats[results.MetricGoInitToHandler].N > 0 {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tCOLD STARTS\tINIT(ms)\tTO HANDLER(ms)\tFIRST DECODE(ms)")
+	for _, r := range rows {
+		initMS := "-"
+		if s := r.Stats[results.MetricInit]; s.N > 0 {
+			initMS = fmt.Sprintf("%.2f", s.Mean)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%.3f\t%.3f\n", r.Function, r.Stats[results.MetricGoInitToHandler].N, initMS,
+			r.Stats[results.MetricGoInitToHandler].Mean, r.Stats[results.MetricGoFirstDecode].Mean)
+	}
+	w.Flush()
+}
+
+// printDecode shows how much of the invocations of Go baselines went to
+// decoding the event into the workload's type: SHARE is the mean decode
+// time's part of the mean duration, or client time where there is no
+// REPORT line. A large one on small invocations is what a generated decoder
+// saves over reflection. It prints nothing when no result has the metric.
+func printDecode(run *results.Run) {
+	var rows []results.Result
+	for _, r := range run.Results {
+		if r.Stats[results.MetricDecode].N > 0 {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tDECODE P50(ms)\tDECODE MEAN(ms)\tDURATION P50(ms)\tSHARE")
+	for _, r := range rows {
+		decode := r.Stats[results.MetricDecode]
+		d := r.Stats[results.MetricDuration]
+		if d.N == 0 {
+			d = r.Stats[results.MetricClient]
+		}
+		share := "-"
+		if d.Mean > 0 {
+			share = fmt.Sprintf("%.1f%%", 100*decode.Mean/d.Mean)
+		}
+		fmt.Fprintf(w, "%s\t%.3f\t%.3f\t%.2f\t%s\n", r.Function, decode.Median, decode.Mean, d.Median, share)
+	}
+	w.Flush()
+}
+
+// printIterations shows the times of the workload's runs within the
+// invocations of Go baselines deployed with -histogram, over every run of
+// every invocation: the spread of the work itself, under the noise that
+// separates invocations. CV is the standard deviation's part of the mean.
+// It prints nothing when no result has a histogram.
+func printIterations(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	header := false
+	for _, r := range run.Results {
+		h := r.Iterations()
+		if h == nil || h.Count == 0 {
+			continue
+		}
+		if !header {
+			fmt.Println()
+			fmt.Fprintln(w, "FUNCTION\tRUNS\tMIN(ms)\tP50(ms)\tP99(ms)\tP99.9(ms)\tMAX(ms)\tCV")
+			header = true
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.3f\t%.3f\t%.3f\t%.3f\t%.3f\t%.1f%%\n", r.Function, h.Count, float64(h.Min)/1e6,
+			h.QuantileMS(0.5), h.QuantileMS(0.99), h.QuantileMS(0.999), float64(h.Max)/1e6, 100*h.StddevMS()/h.MeanMS())
+	}
+	w.Flush()
+}
+
+// histogramMS is d as the milliseconds lambdalog.HistogramEnv takes.
+func histogramMS(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/store"
+)
+
+func runHistory(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench history [flags] <workload>")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	var db string
+	registerDB(fs, &db)
+	runtimes := fs.String("runtime", "", "comma-separated runtimes to include")
+	kind := fs.String("kind", "", "only include local or lambda results")
+	arch := fs.String("arch", "", "only include this architecture")
+	metric := fs.String("metric", "", "metric to trend (default: each result's headline metric)")
+	since := fs.Duration("since", 0, "only include runs started within this window")
+	limit := fs.Int("limit", 20, "most recent runs to include (0 for all)")
+	var sf statsFlags
+	sf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	// Accept flags on either side of the workload.
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("history needs a workload")
+	}
+	workload := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if *kind != "" && *kind != string(discover.KindLocal) && *kind != string(discover.KindLambda) {
+		return fmt.Errorf("unknown kind %q", *kind)
+	}
+
+	if *root == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if *root, err = discover.FindRoot(wd); err != nil {
+			return err
+		}
+	}
+	s, err := openStore(ctx, *root, db)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	q := store.Query{
+		Workload: workload,
+		Runtimes: splitList(*runtimes),
+		Kind:     *kind,
+		Arch:     *arch,
+		Limit:    *limit,
+	}
+	if *since > 0 {
+		q.Since = time.Now().Add(-*since)
+	}
+	entries, err := s.History(ctx, q)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no recorded results for workload %q", workload)
+	}
+	printHistory(entries, *metric, sf)
+	return nil
+}
+
+// printHistory groups entries into series (one per kind, runtime, arch,
+// memory size, package type, SnapStart, extension and provisioned
+// concurrency setting) and prints each run's median with the change from
+// the series' previous run.
+func printHistory(entries []store.Entry, metric string, sf statsFlags) {
+	type key struct {
+		// runtime is the runtimeLabel, which carries the package type,
+		// SnapStart and extension.
+		kind, runtime, arch string
+		mem                 int32
+		provisioned         int32
+	}
+	var (
+		order  []key
+		series = map[key][]store.Entry{}
+	)
+	for _, e := range entries {
+		r := e.Result
+		k := key{r.Kind, runtimeLabel(r), r.Arch, r.Memory(), r.ProvisionedConcurrency}
+		if _, ok := series[k]; !ok {
+			order = append(order, k)
+		}
+		series[k] = append(series[k], e)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRUNTIME\tARCH\tMEMORY(MB)\tRUN\tMODE\tMETRIC\tN\tMEDIAN\tP95\tCHANGE")
+	for _, k := range order {
+		runtime, arch, mem := k.runtime, k.arch, "-"
+		if k.provisioned > 0 {
+			runtime += fmt.Sprintf("+pc%d", k.provisioned)
+		}
+		if arch == "" {
+			arch = "-"
+		}
+		if k.mem != 0 {
+			mem = fmt.Sprint(k.mem)
+		}
+		var (
+			prev       float64
+			prevMetric string
+		)
+		for _, e := range series[k] {
+			r := e.Result
+			prefix := fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s", k.kind, runtime, arch, mem, e.RunID, e.Mode)
+			if r.Error != "" {
+				fmt.Fprintf(w, "%s\terror: %s\n", prefix, r.Error)
+				continue
+			}
+			r.Summarize(sf.options())
+			m := metric
+			if m == "" {
+				m = headlineMetric(r, "")
+			}
+			st, ok := r.Stats[m]
+			if !ok {
+				fmt.Fprintf(w, "%s\t%s\t0\n", prefix, m)
+				continue
+			}
+			change := "-"
+			if prev > 0 && m == prevMetric {
+				change = fmt.Sprintf("%+.1f%%", (st.Median-prev)/prev*100)
+			}
+			prev, prevMetric = st.Median, m
+			fmt.Fprintf(w, "%s\t%s\t%d\t%.2f\t%.2f\t%s\n", prefix, m, st.N, st.Median, st.P95, change)
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"lambdaperf/pkg/hyperfine"
+	"lambdaperf/pkg/results"
+)
+
+func runImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench import [flags] <hyperfine-export.json>")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	workload := fs.String("workload", "", "workload of benchmarks without a \"workload\" parameter")
+	at := fs.String("at", "", "when the benchmarks ran, RFC 3339 (default: the file's modification time)")
+	var of outputFlags
+	of.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("import needs one hyperfine JSON export")
+	}
+	path := fs.Arg(0)
+
+	started, err := importTime(path, *at)
+	if err != nil {
+		return err
+	}
+	e, err := hyperfine.Read(path)
+	if err != nil {
+		return err
+	}
+	run, err := hyperfine.ToRun(e, *workload, started)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	run.Summarize(sf.options())
+	// What the benchmarks measured is not known here; the metadata of the
+	// checkout importing them would be a guess.
+	run.Metadata = &results.Metadata{}
+	if *root, err = findRoot(*root); err != nil {
+		return err
+	}
+	out, err := of.save(ctx, *root, run)
+	if err != nil {
+		return err
+	}
+	printStats(run, "", cf)
+	fmt.Fprintln(os.Stderr, "results written to", out)
+	return nil
+}
+
+func importTime(path, at string) (time.Time, error) {
+	if at != "" {
+		t, err := time.Parse(time.RFC3339, at)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("-at: %w", err)
+		}
+		return t, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
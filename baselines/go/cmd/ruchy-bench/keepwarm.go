@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"lambdaperf/pkg/coldstart"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/keepwarm"
+	"lambdaperf/pkg/platform"
+	"lambdaperf/pkg/pool"
+	"lambdaperf/pkg/results"
+)
+
+func runKeepWarm(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("keepwarm", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	intervalList := fs.String("intervals", keepwarm.DefaultIntervals, "comma-separated keep-warm ping intervals to compare, in whole minutes, off for no pings")
+	duration := fs.Duration("duration", time.Hour, "how long sporadic traffic runs at each interval")
+	gap := fs.Duration("mean-gap", 10*time.Minute, "mean time between sporadic requests, which arrive at random (Poisson) times")
+	seed := fs.Uint64("seed", 1, "seed of the request times, which are the same for every function and interval")
+	var pf payloadFlags
+	pf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	intervals, err := keepwarm.ParseIntervals(*intervalList)
+	if err != nil {
+		return err
+	}
+	if *gap <= 0 || *duration <= 0 {
+		return errors.New("-duration and -mean-gap must be positive")
+	}
+	arrivals := keepwarm.Arrivals(*seed, *gap, *duration)
+	if len(arrivals) == 0 {
+		return fmt.Errorf("no request arrives within -duration %s at -mean-gap %s", *duration, *gap)
+	}
+	if tf.snapStart {
+		return errors.New("rules and forced cold starts address $LATEST; keepwarm does not support -snapstart")
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	expected, err := expectedResults(root)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	client := lambda.NewFromConfig(cfg)
+	events := &keepwarm.Client{Config: cfg}
+
+	run := results.NewRun("keepwarm", time.Now())
+	var (
+		live     []discover.Target
+		arns     []string
+		payloads [][]byte
+	)
+	for _, t := range targets {
+		res := newResult(t)
+		if why := invokedElsewhere(t); why != "" {
+			res.Error = why
+			run.Results = append(run.Results, res)
+			continue
+		}
+		payload, err := pf.forTarget(t)
+		if err != nil {
+			return err
+		}
+		fn, err := client.GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{FunctionName: aws.String(res.Function)})
+		if err != nil {
+			res.Error = fmt.Sprintf("get configuration of %s: %v", res.Function, err)
+			run.Results = append(run.Results, res)
+			continue
+		}
+		live, arns, payloads = append(live, t), append(arns, aws.ToString(fn.FunctionArn)), append(payloads, payload)
+	}
+	// The rules outlive an interrupted run unless removed; gc finds
+	// any that are left.
+	defer func() {
+		ctx := context.WithoutCancel(ctx)
+		for _, t := range live {
+			fn := t.FunctionName()
+			if err := events.Remove(ctx, keepwarm.RuleName(fn)); err != nil {
+				fmt.Fprintln(os.Stderr, "warning:", err)
+			}
+			if err := deploy.RevokeSchedule(ctx, client, fn); err != nil {
+				fmt.Fprintln(os.Stderr, "warning:", err)
+			}
+		}
+	}()
+
+	for _, interval := range intervals {
+		fmt.Fprintf(os.Stderr, "keep-warm %s: %d requests over %s to %d functions\n", keepwarm.Label(interval), len(arrivals), *duration, len(live))
+		phase := make([]results.Result, len(live))
+		// Every function sees the traffic at once, so the phase takes
+		// -duration however many are measured.
+		pool.Each(ctx, len(live), len(live), func(i int) {
+			t := live[i]
+			res := newResult(t)
+			res.Input = map[string]int{
+				"keepwarm_s": int(interval / time.Second),
+				"mean_gap_s": int(*gap / time.Second),
+				"requests":   len(arrivals),
+			}
+			if err := keepWarmPhase(ctx, client, events, &res, arns[i], payloads[i], interval, arrivals, expected[t.Workload]); err != nil {
+				res.Error = err.Error()
+			}
+			phase[i] = res
+		})
+		for _, res := range phase {
+			// An interrupted phase leaves the functions it never reached
+			// out.
+			if res.Function != "" {
+				run.Results = append(run.Results, res)
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printKeepWarm(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return ctx.Err()
+}
+
+// keepWarmPhase pings res's function every interval, or stops pinging it
+// when interval is zero, forces a cold start so that every phase begins
+// without warm environments, and then invokes it at each of arrivals,
+// recording a sample per request.
+func keepWarmPhase(ctx context.Context, client *lambda.Client, events *keepwarm.Client, res *results.Result, arn string, payload []byte, interval time.Duration, arrivals []time.Duration, expected string) error {
+	fn, rule := res.Function, keepwarm.RuleName(res.Function)
+	if interval > 0 {
+		ruleARN, err := events.Schedule(ctx, rule, interval, arn, payload)
+		if err != nil {
+			return err
+		}
+		if err := deploy.AllowSchedule(ctx, client, fn, ruleARN); err != nil {
+			return err
+		}
+	} else {
+		if err := events.Remove(ctx, rule); err != nil {
+			return err
+		}
+		if err := deploy.RevokeSchedule(ctx, client, fn); err != nil {
+			return err
+		}
+	}
+	if err := (&coldstart.Runner{Client: client, FunctionName: fn}).Force(ctx); err != nil {
+		return err
+	}
+	l := &platform.Lambda{Client: client, Function: fn}
+	start := time.Now()
+	for i, at := range arrivals {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(start.Add(at))):
+		}
+		res.Samples = append(res.Samples, platform.Sample(ctx, l, payload, i, expected))
+	}
+	return nil
+}
+
+// printKeepWarm shows per function and ping interval how many of the
+// sporadic requests started cold, how that compares with no pings, and
+// what the requests took.
+func printKeepWarm(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tKEEP-WARM\tOK\tCOLD\tCOLD RATE\tVS OFF\tCLIENT P50(ms)\tCLIENT P95(ms)\tINIT MEAN(ms)")
+	// Without pings, per function.
+	off := map[string]float64{}
+	rate := func(r results.Result) (cold, ok int) {
+		for _, s := range r.Samples {
+			if s.Error == "" && s.RequestID != "" {
+				ok++
+				if s.Cold {
+					cold++
+				}
+			}
+		}
+		return cold, ok
+	}
+	for _, r := range run.Results {
+		if cold, ok := rate(r); r.Error == "" && r.Input["keepwarm_s"] == 0 && ok > 0 {
+			off[r.Function] = float64(cold) / float64(ok)
+		}
+	}
+	for _, r := range run.Results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\t-\terror: %s\n", r.Function, r.Error)
+			continue
+		}
+		label := keepwarm.Label(time.Duration(r.Input["keepwarm_s"]) * time.Second)
+		cold, ok := rate(r)
+		if ok == 0 {
+			fmt.Fprintf(w, "%s\t%s\t0/%d\n", r.Function, label, len(r.Samples))
+			continue
+		}
+		share := float64(cold) / float64(ok)
+		vs := "-"
+		if base, found := off[r.Function]; found && r.Input["keepwarm_s"] > 0 {
+			vs = fmt.Sprintf("%+.0f pp", 100*(share-base))
+		}
+		initMS := "-"
+		if s := r.Stats[results.MetricInit]; s.N > 0 {
+			initMS = fmt.Sprintf("%.1f", s.Mean)
+		}
+		client := r.Stats[results.MetricClient]
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%d\t%.0f%%\t%s\t%.1f\t%.1f\t%s\n", r.Function, label, ok, len(r.Samples), cold, 100*share, vs,
+			client.Median, client.P95, initMS)
+	}
+	w.Flush()
+	warnWrongResults(run)
+}
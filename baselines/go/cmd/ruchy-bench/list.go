@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"lambdaperf/pkg/discover"
+)
+
+func runList(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRUNTIME\tWORKLOAD\tARCH\tFUNCTION\tSOURCE")
+	for _, t := range targets {
+		fn, arch := "-", "-"
+		if t.Kind == discover.KindLambda {
+			fn, arch = t.FunctionName(), t.Arch
+		}
+		src, err := filepath.Rel(root, t.Source)
+		if err != nil {
+			src = t.Source
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.Kind, t.Runtime, t.Workload, arch, fn, src)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/loadgen"
+	"lambdaperf/pkg/results"
+)
+
+func runLoad(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("load", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	workers := fs.Int("workers", 10, "concurrent invokers per function")
+	rps := fs.Float64("rps", 0, "target requests per second across workers (0: as fast as replies allow)")
+	duration := fs.Duration("duration", 30*time.Second, "how long to generate load per function")
+	var pf payloadFlags
+	pf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	var bf budgetFlags
+	bf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *workers < 1 || *duration <= 0 {
+		return errors.New("-workers and -duration must be positive")
+	}
+	if *rps < 0 {
+		return errors.New("-rps must not be negative")
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	// Retries stay off: throttling is one of the things load exposes.
+	client, err := newLambdaClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	expected, err := expectedResults(root)
+	if err != nil {
+		return err
+	}
+
+	run := results.NewRun("load", time.Now())
+	for _, t := range targets {
+		payload, err := pf.forTarget(t)
+		if err != nil {
+			return err
+		}
+		res := newResult(t)
+		g := &loadgen.Generator{
+			Invoker: &invoke.Lambda{Client: client, FunctionName: res.Function, Qualifier: t.Qualifier()},
+			Config: loadgen.Config{
+				Workers:  *workers,
+				RPS:      *rps,
+				Duration: *duration,
+				Payload:  payload,
+				Expected: expected[t.Workload],
+			},
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d workers for %s\n", res.Function, *workers, *duration)
+		out, err := g.Run(ctx)
+		if err != nil {
+			res.Error = err.Error()
+		}
+		res.Samples = out.Samples
+		res.Load = &results.Load{
+			Workers:         *workers,
+			TargetRPS:       *rps,
+			AchievedRPS:     out.AchievedRPS(),
+			DurationMS:      results.Milliseconds(out.Elapsed),
+			Missed:          out.Missed,
+			Throttled:       out.Throttled,
+			ClientP50MS:     out.Client.Quantile(0.5),
+			ClientP99MS:     out.Client.Quantile(0.99),
+			ClientP999MS:    out.Client.Quantile(0.999),
+			ClientHistogram: out.Client.Buckets(),
+		}
+		run.Results = append(run.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printStats(run, results.MetricWarm, cf)
+	fmt.Println()
+	printLoad(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return context.Cause(ctx)
+}
+
+// printLoad shows what rate each function sustained and how its client
+// latency tail and cold starts looked under concurrency.
+func printLoad(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tWORKERS\tREQUESTS\tRPS\tERRORS\tTHROTTLED\tMISSED\tCOLD\tCLIENT P50(ms)\tP99(ms)\tP99.9(ms)")
+	for _, r := range run.Results {
+		if r.Load == nil {
+			fmt.Fprintf(w, "%s\t-\terror: %s\n", r.Function, r.Error)
+			continue
+		}
+		var errs, cold int
+		for _, s := range r.Samples {
+			if s.Error != "" {
+				errs++
+			}
+			if s.Cold {
+				cold++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%d\t%d\t%d\t%d\t%.2f\t%.2f\t%.2f\n", r.Function, r.Load.Workers,
+			len(r.Samples), r.Load.AchievedRPS, errs, r.Load.Throttled, r.Load.Missed, cold,
+			r.Load.ClientP50MS, r.Load.ClientP99MS, r.Load.ClientP999MS)
+	}
+	w.Flush()
+}
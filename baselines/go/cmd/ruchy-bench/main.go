@@ -0,0 +1,255 @@
+// Command ruchy-bench discovers, builds and invokes the Ruchy Lambda
+// benchmark targets and writes a consolidated results file.
+//
+// Usage:
+//
+//	ruchy-bench <command> [flags]
+//
+// Run "ruchy-bench help" for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/results"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"list", "list discovered benchmark targets", runList},
+		{"build", "build targets into local binaries or Lambda zips", runBuild},
+		{"deploy", "build and create or update Lambda functions for targets", runDeploy},
+		{"teardown", "delete deployed Lambda functions for targets", runTeardown},
+		{"gc", "find and delete every tagged harness resource, including those crashed runs left behind", runGC},
+		{"export", "write the functions deploy would create as a Terraform configuration", runExport},
+		{"seed", "provision workload fixtures: the S3 object and event, DynamoDB table and SQS queue", runSeed},
+		{"vpc", "create or delete the VPC, subnets and security group -vpc targets are deployed into", runVPC},
+		{"plan", "estimate the invocations, duration and cost of a run, coldstart, sweep or scale without touching AWS", runPlan},
+		{"run", "invoke targets N times and write a results file", runRun},
+		{"matrix", "run the groups of the bench.yaml matrix selected by tag, recording them as one run", runMatrix},
+		{"coldstart", "force cold starts on deployed functions and record init duration", runColdstart},
+		{"reports", "fetch and parse REPORT lines from CloudWatch Logs", runReports},
+		{"pprof", "download the CPU or heap profiles Go baselines deployed with -pprof uploaded, and open them with go tool pprof", runPprof},
+		{"flamegraph", "draw side-by-side flame graphs of a workload from Go pprof profiles and perf or DTrace output for Rust and Ruchy", runFlamegraph},
+		{"provisioned", "burst-invoke functions with provisioned concurrency and measure spillover", runProvisioned},
+		{"load", "drive deployed functions from concurrent workers at a target request rate", runLoad},
+		{"burst", "ramp concurrent invocations up to 1000 and record per-level latency, scale-up time and throttles", runBurst},
+		{"record", "turn a JSON lines file or Logs Insights export of real invocation payloads into a trace for replay", runRecord},
+		{"replay", "replay a recorded trace of payloads against deployed functions at its original or an accelerated pace", runReplay},
+		{"sweep", "benchmark deployed functions across memory sizes", runSweep},
+		{"scale", "benchmark deployed functions across workload input sizes", runScale},
+		{"payloads", "benchmark the echo workload with 1 KB to 6 MB payloads for JSON marshaling cost by size", runPayloads},
+		{"storage", "benchmark the tmpio workload across ephemeral storage sizes for /tmp write and read throughput", runStorage},
+		{"errors", "invoke the panic, error and timeout workloads and compare how each runtime reports failures", runErrors},
+		{"stream", "measure time to first byte and transfer time of response-streaming function URLs", runStream},
+		{"edge", "deploy Python baselines as Lambda@Edge functions and compare their latency with regional invocations from probes worldwide", runEdge},
+		{"sqs", "send messages through the seeded queue and measure end-to-end batch processing latency", runSQS},
+		{"async", "invoke functions asynchronously and measure queueing, end-to-end latency, retries and dead-lettering", runAsync},
+		{"stepfunctions", "chain invocations of a baseline in an Express Step Functions state machine and measure the per-transition overhead", runStepFunctions},
+		{"keepwarm", "ping functions from EventBridge rules at several intervals and compare the cold-start rates of sporadic traffic", runKeepWarm},
+		{"report", "render a results file as a Markdown table or HTML page with charts", runReport},
+		{"validate", "check results files against the published JSON schema, migrating older versions with -migrate", runValidate},
+		{"badges", "write shields.io endpoint badges of cold start, warm latency and binary size from a results file", runBadges},
+		{"history", "show a workload's recorded results over time", runHistory},
+		{"analyze", "re-summarize a stored run's raw samples under another outlier policy or percentiles, and export them", runAnalyze},
+		{"bundle", "pack a run's raw samples, metadata, log excerpts and report into a tarball with account IDs and ARNs scrubbed", runBundle},
+		{"compare", "fail when a run's p95 regressed significantly against a stored baseline run", runCompare},
+		{"daemon", "run the matrix nightly, store each run in S3 and post its comparison with the last to SNS or Slack", runDaemon},
+		{"scaffold", "generate the handler skeleton, manifest entry and Makefile parity target for a new runtime or workload", runScaffold},
+		{"verify-parity", "check every workload is implemented alike by each runtime the manifest lists", runVerifyParity},
+		{"import", "import a hyperfine JSON export into the history database", runImport},
+		{"sync", "merge the local history database with a shared one in S3, both ways", runSync},
+	}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := dispatch(ctx, os.Args[1:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "ruchy-bench:", err)
+			notifyFailure(ctx, os.Args[1], err)
+		}
+		os.Exit(1)
+	}
+}
+
+func dispatch(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage()
+		return nil
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(ctx, args[1:])
+		}
+	}
+	usage()
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: ruchy-bench <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.summary)
+	}
+}
+
+// targetFlags selects targets; shared by every command that operates on
+// discovered targets.
+type targetFlags struct {
+	root      string
+	kind      string
+	runtimes  string
+	workloads string
+	archs     string
+	packages  string
+	snapStart bool
+	extension bool
+	vpc       bool
+	chaos     bool
+	// serializers are the -serializer values.
+	serializers string
+}
+
+func (f *targetFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.root, "root", "", "repository root (default: found by walking up from the working directory)")
+	fs.StringVar(&f.kind, "kind", "", "target kind: local or lambda (default: all)")
+	fs.StringVar(&f.runtimes, "runtime", "", "comma-separated runtimes to include (default: all)")
+	fs.StringVar(&f.workloads, "workload", "", "comma-separated workloads to include (default: all)")
+	fs.StringVar(&f.archs, "arch", "", "comma-separated Lambda architectures: x86_64, arm64 (default: x86_64)")
+	fs.StringVar(&f.packages, "package", "", "comma-separated Lambda package types: zip, image (default: zip)")
+	fs.BoolVar(&f.snapStart, "snapstart", false, "use SnapStart variants of targets that support it (python)")
+	fs.BoolVar(&f.extension, "extension", false, "add a variant of each zip Lambda target with the noop-telemetry extension attached")
+	fs.BoolVar(&f.vpc, "vpc", false, "add a variant of each Lambda target attached to the harness VPC (see ruchy-bench vpc)")
+	fs.BoolVar(&f.chaos, "chaos", false, "add a variant of each zip Lambda target with the chaos extension contending for its CPU and memory")
+	fs.StringVar(&f.serializers, "serializer", "", "comma-separated JSON serializers of the JSON-heavy Go Lambda baselines: std, jsoniter, easyjson (default: std, encoding/json)")
+}
+
+// resolve returns the repository root and the selected targets.
+func (f *targetFlags) resolve() (string, []discover.Target, error) {
+	root, err := findRoot(f.root)
+	if err != nil {
+		return "", nil, err
+	}
+	all, err := discover.Discover(root)
+	if err != nil {
+		return "", nil, err
+	}
+	archs := splitList(f.archs)
+	for _, a := range archs {
+		if a != discover.ArchX86 && a != discover.ArchARM64 {
+			return "", nil, fmt.Errorf("unknown architecture %q", a)
+		}
+	}
+	pkgs := splitList(f.packages)
+	for _, p := range pkgs {
+		if p != discover.PackageZip && p != discover.PackageImage {
+			return "", nil, fmt.Errorf("unknown package type %q", p)
+		}
+	}
+	serializers := splitList(f.serializers)
+	for _, s := range serializers {
+		if s != discover.SerializerStd && s != discover.SerializerJSONIter && s != discover.SerializerEasyJSON {
+			return "", nil, fmt.Errorf("unknown serializer %q", s)
+		}
+	}
+	targets := discover.Filter(all, discover.Kind(f.kind), splitList(f.runtimes), splitList(f.workloads))
+	targets = discover.WithArchs(targets, archs)
+	targets = discover.WithPackages(targets, pkgs)
+	targets = discover.WithSerializers(targets, serializers)
+	if f.snapStart {
+		targets = discover.WithSnapStart(targets)
+	}
+	if f.extension {
+		targets = discover.WithExtension(targets)
+	}
+	if f.vpc {
+		targets = discover.WithVPC(targets)
+	}
+	if f.chaos {
+		targets = discover.WithChaos(targets)
+	}
+	if len(targets) == 0 {
+		return "", nil, errors.New("no targets match the given filters")
+	}
+	return root, targets, nil
+}
+
+// findRoot returns root, or the repository root above the working
+// directory when root is empty.
+func findRoot(root string) (string, error) {
+	if root != "" {
+		return root, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return discover.FindRoot(wd)
+}
+
+// expectedResults maps every workload the manifest declares to the result
+// its handlers must return. Commands that measure Lambda functions fail
+// each invocation that returns anything else, so a broken deployment
+// cannot post good numbers.
+func expectedResults(root string) (map[string]string, error) {
+	m, err := manifest.Load(root)
+	if err != nil {
+		return nil, err
+	}
+	expected := map[string]string{}
+	for _, w := range m.Workloads {
+		expected[w.Name] = w.Expected
+	}
+	return expected, nil
+}
+
+// newResult starts the result record of a target.
+func newResult(t discover.Target) results.Result {
+	r := results.Result{
+		Runtime:    t.Runtime,
+		Workload:   t.Workload,
+		Kind:       string(t.Kind),
+		Arch:       t.Arch,
+		SnapStart:  t.SnapStart,
+		Extension:  t.Extension,
+		VPC:        t.VPC,
+		Chaos:      t.Chaos,
+		Serializer: t.Serializer,
+		Package:    t.Package,
+	}
+	if t.Kind == discover.KindLambda {
+		r.Function = t.FunctionName()
+	}
+	return r
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/matrix"
+	"lambdaperf/pkg/progress"
+	"lambdaperf/pkg/results"
+)
+
+func runMatrix(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("matrix", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench matrix [flags] [-- run and sweep flags]")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	file := fs.String("matrix", "", "matrix file (default: <root>/"+matrix.Path+")")
+	only := fs.String("only", "", "comma-separated tags or group names to run (default: every group)")
+	skip := fs.String("skip", "", "comma-separated tags or group names to leave out")
+	dryRun := fs.Bool("dry-run", false, "print the commands each group runs, without running them")
+	var of outputFlags
+	of.register(fs)
+	var bf budgetFlags
+	bf.register(fs)
+	var pgf progressFlags
+	pgf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	dir, err := findRoot(*root)
+	if err != nil {
+		return err
+	}
+	if *file == "" {
+		*file = filepath.Join(dir, matrix.Path)
+	}
+	m, err := matrix.Load(*file)
+	if err != nil {
+		return err
+	}
+	man, err := manifest.Load(dir)
+	if err != nil {
+		return err
+	}
+	if err := m.Check(man); err != nil {
+		return err
+	}
+	groups, err := m.Select(splitList(*only), splitList(*skip))
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return errors.New("-only and -skip leave no groups")
+	}
+
+	run := results.NewRun("matrix", time.Now())
+	// Each group's command writes its own results file; the matrix run
+	// collects them and alone is recorded in the history and sinks.
+	tmp := filepath.Join(dir, ".bench", "matrix", run.ID)
+	total := 0
+	for _, g := range groups {
+		total += len(matrixSteps(g))
+	}
+	// The steps share one progress board, which counts them off.
+	var board *progress.Board
+	if !*dryRun {
+		var stop func()
+		ctx, stop = pgf.start(ctx)
+		defer stop()
+		board = progress.From(ctx)
+	}
+	var failed []string
+	done := 0
+	for _, g := range groups {
+		steps := matrixSteps(g)
+		for i, step := range steps {
+			done++
+			name := g.Name
+			if len(steps) > 1 {
+				name = fmt.Sprintf("%s-%d", g.Name, i+1)
+			}
+			out := filepath.Join(tmp, name+".json")
+			argv := append(slices.Clone(step.args), "-root", dir, "-out", out, "-db", "none")
+			argv = append(argv, fs.Args()...)
+			fmt.Fprintf(os.Stderr, "%s: ruchy-bench %s %s\n", name, step.command, strings.Join(argv, " "))
+			if *dryRun {
+				continue
+			}
+			board.Step(done, total, name)
+			err := step.run(ctx, argv)
+			if r, rerr := results.Read(out); rerr == nil {
+				run.Results = append(run.Results, r.Results...)
+			} else if err == nil {
+				err = rerr
+			}
+			if err != nil {
+				failed = append(failed, name)
+				fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if *dryRun {
+		return nil
+	}
+	run.FinishedAt = time.Now().UTC()
+	if len(run.Results) == 0 {
+		return errors.New("no group recorded results")
+	}
+	path, err := of.save(ctx, dir, run)
+	if err != nil {
+		return err
+	}
+	os.RemoveAll(tmp)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	// A spent budget or an interrupt fails the step it stopped; say so
+	// rather than list it.
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of the matrix's steps failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// matrixStep is one command a group runs.
+type matrixStep struct {
+	command string
+	run     func(context.Context, []string) error
+	args    []string
+}
+
+// matrixSteps are the commands that measure g: run for local groups and
+// lambda groups at their deployed memory size, which measures every
+// region at once, and otherwise sweep, once per region.
+func matrixSteps(g matrix.Group) []matrixStep {
+	args := []string{"-kind", string(g.Kind), "-workload", strings.Join(g.Workloads, ",")}
+	if len(g.Runtimes) > 0 {
+		args = append(args, "-runtime", strings.Join(g.Runtimes, ","))
+	}
+	if g.Samples > 0 {
+		args = append(args, "-n", strconv.Itoa(g.Samples))
+	}
+	if len(g.Archs) > 0 {
+		args = append(args, "-arch", strings.Join(g.Archs, ","))
+	}
+	if len(g.MemoryMB) == 0 {
+		if len(g.Regions) > 0 {
+			args = append(args, "-region", strings.Join(g.Regions, ","))
+		}
+		return []matrixStep{{"run", runRun, args}}
+	}
+	sizes := make([]string, len(g.MemoryMB))
+	for i, mb := range g.MemoryMB {
+		sizes[i] = strconv.Itoa(int(mb))
+	}
+	args = append(args, "-sizes", strings.Join(sizes, ","))
+	regions := g.Regions
+	if len(regions) == 0 {
+		return []matrixStep{{"sweep", runSweep, args}}
+	}
+	var steps []matrixStep
+	for _, r := range regions {
+		steps = append(steps, matrixStep{"sweep", runSweep, append(slices.Clone(args), "-region", r)})
+	}
+	return steps
+}
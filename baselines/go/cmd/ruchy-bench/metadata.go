@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/results"
+)
+
+// captureMetadata records run's provenance: root's commit, the toolchain
+// versions of the required runtimes and of every runtime measured, the
+// aws-lambda-go version, and the Lambda runtime and package hash of each
+// Lambda result.
+// What cannot be found is left out, with a warning, for
+// Run.MissingMetadata to report.
+func captureMetadata(ctx context.Context, root string, run *results.Run) {
+	m := &results.Metadata{Toolchains: map[string]string{}}
+	run.Metadata = m
+	var err error
+	if m.Commit, m.Dirty, err = gitCommit(ctx, root); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: metadata:", err)
+	}
+	runtimes := slices.Clone(results.RequiredToolchains)
+	for _, r := range run.Results {
+		if !slices.Contains(runtimes, r.Runtime) {
+			runtimes = append(runtimes, r.Runtime)
+		}
+	}
+	for _, rt := range runtimes {
+		v, err := build.ToolchainVersion(ctx, rt)
+		if err == nil {
+			m.Toolchains[rt] = v
+		} else if slices.Contains(results.RequiredToolchains, rt) {
+			fmt.Fprintln(os.Stderr, "warning: metadata:", err)
+		}
+	}
+	if m.LambdaGo, err = build.LambdaGoVersion(ctx, root); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: metadata:", err)
+	}
+	if err := lambdaRuntimes(ctx, run); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: metadata:", err)
+	}
+}
+
+// gitCommit returns the full revision root is checked out at and whether
+// its work tree has changes.
+func gitCommit(ctx context.Context, root string) (string, bool, error) {
+	out, err := exec.CommandContext(ctx, "git", "-C", root, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("git rev-parse: %w", err)
+	}
+	status, err := exec.CommandContext(ctx, "git", "-C", root, "status", "--porcelain").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("git status: %w", err)
+	}
+	return strings.TrimSpace(string(out)), len(status) > 0, nil
+}
+
+// lambdaRuntimes sets LambdaRuntime on every Lambda result that has none
+// and records the package hash of every function in run's metadata,
+// asking Lambda once per function and region.
+func lambdaRuntimes(ctx context.Context, run *results.Run) error {
+	type function struct{ name, region string }
+	byFunction := map[function][]*results.Result{}
+	m := run.Metadata
+	if m.Packages == nil {
+		m.Packages = map[string]string{}
+	}
+	for i := range run.Results {
+		r := &run.Results[i]
+		if r.Kind == "lambda" && (r.LambdaRuntime == "" || m.Packages[r.Function] == "") && r.Function != "" {
+			f := function{r.Function, r.Region}
+			byFunction[f] = append(byFunction[f], r)
+		}
+	}
+	clients := map[string]*lambda.Client{}
+	for f := range byFunction {
+		if clients[f.region] != nil {
+			continue
+		}
+		cfg, err := loadAWSConfig(ctx, f.region)
+		if err != nil {
+			return err
+		}
+		clients[f.region] = lambda.NewFromConfig(cfg)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for f, rs := range byFunction {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := clients[f.region].GetFunction(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(f.name)})
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("lambda runtime of %s: %w", inRegion(f.name, f.region), err))
+				mu.Unlock()
+				return
+			}
+			id := lambdaRuntime(out)
+			for _, r := range rs {
+				if r.LambdaRuntime == "" {
+					r.LambdaRuntime = id
+				}
+			}
+			if out.Configuration == nil {
+				return
+			}
+			sum := aws.ToString(out.Configuration.CodeSha256)
+			mu.Lock()
+			defer mu.Unlock()
+			// Regions deployed from one build agree; when they do not,
+			// the hash names no single package.
+			if prev, ok := m.Packages[f.name]; ok && prev != sum {
+				errs = append(errs, fmt.Errorf("package of %s differs across regions", f.name))
+				sum = ""
+			}
+			m.Packages[f.name] = sum
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// lambdaRuntime renders the runtime a function runs on for
+// Result.LambdaRuntime.
+func lambdaRuntime(out *lambda.GetFunctionOutput) string {
+	c := out.Configuration
+	if c == nil {
+		return ""
+	}
+	if c.PackageType == types.PackageTypeImage {
+		if out.Code != nil {
+			return aws.ToString(out.Code.ResolvedImageUri)
+		}
+		return ""
+	}
+	id := string(c.Runtime)
+	if v := c.RuntimeVersionConfig; v != nil && aws.ToString(v.RuntimeVersionArn) != "" {
+		id += " " + aws.ToString(v.RuntimeVersionArn)
+	}
+	return id
+}
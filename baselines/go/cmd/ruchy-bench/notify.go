@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"lambdaperf/pkg/compare"
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/notify"
+	"lambdaperf/pkg/results"
+)
+
+// notifyOptions are what a webhook sink compares a run with its
+// predecessor by: compare's and daemon's defaults.
+var notifyOptions = compare.Options{Threshold: 0.05, Alpha: compare.DefaultAlpha}
+
+// webhooks holds the -sink slack and discord webhooks of commands that
+// have not yet posted a run, so that one failing before it has a run to
+// save still reports it; see notifyFailure.
+var webhooks = map[sinkSpec]bool{}
+
+// notifier returns the notifier a slack or discord -sink posts through.
+// A slack sink without a URL takes daemon's $RUCHY_BENCH_SLACK_WEBHOOK.
+func (s sinkSpec) notifier() (notify.Notifier, error) {
+	if s.kind == "discord" {
+		return notify.Discord{URL: s.target}, nil
+	}
+	url := s.target
+	if url == "" {
+		url = os.Getenv(webhookEnv)
+	}
+	if url == "" {
+		return nil, fmt.Errorf("sink slack needs a webhook URL, as slack=URL or $%s", webhookEnv)
+	}
+	return notify.Slack{URL: url}, nil
+}
+
+// notifySink posts a summary of each saved run through a webhook: the
+// fastest runtime per workload, regressions against the previous run of
+// the same mode in the history database, failures and the estimated
+// cost, so a scheduled run needs no one to go and fetch its results.
+type notifySink struct {
+	notifier notify.Notifier
+	// history is where the previous run is looked up; nil (-db none)
+	// leaves nothing to compare with.
+	history *historyDB
+	// stopped is why the command stopped early, nil when it did not.
+	stopped error
+}
+
+func (s *notifySink) Write(ctx context.Context, run *results.Run) error {
+	var baseline *results.Run
+	var err error
+	if s.history != nil {
+		baseline, err = s.history.s.Previous(ctx, run.Mode, run.ID, run.StartedAt)
+	}
+	subject, text := runSummary(baseline, run, notifyOptions, errors.Join(s.stopped, err))
+	return s.notifier.Notify(ctx, subject, text)
+}
+
+// runSummary describes run for a chat message: a subject with its status
+// and a text led by its cost, with the fastest runtime on each workload,
+// the regressions and failures against baseline and the errors of
+// failed targets. A nil baseline leaves the comparison out; runErr is
+// reported first.
+func runSummary(baseline, run *results.Run, o compare.Options, runErr error) (subject, text string) {
+	var b strings.Builder
+	failed := 0
+	for _, r := range run.Results {
+		if r.Error != "" {
+			failed++
+		}
+	}
+	fmt.Fprintf(&b, "%d results in %s", len(run.Results), run.FinishedAt.Sub(run.StartedAt).Round(time.Second))
+	if usd, ok := runCost(run, cost.Default); ok {
+		fmt.Fprintf(&b, ", an estimated $%.4f of Lambda usage", usd)
+	}
+	b.WriteString(".\n")
+	if runErr != nil {
+		fmt.Fprintf(&b, "stopped: %v\n", runErr)
+	}
+	if ws := compare.Winners(run, o); len(ws) > 0 {
+		b.WriteString("\nfastest runtime per workload (median):\n")
+		for _, w := range ws {
+			fmt.Fprintf(&b, "  %s: %s %.2f ms", w.Target, w.Runtime, w.Median)
+			if w.RunnerUp != "" {
+				fmt.Fprintf(&b, ", then %s %.2f ms", w.RunnerUp, w.RunnerUpMedian)
+				if !w.Pair.Significant(o.Alpha) {
+					b.WriteString(", not significant")
+				}
+			}
+			fmt.Fprintf(&b, " (%s)\n", w.Metric)
+		}
+	}
+
+	status := "no regressions"
+	if baseline == nil {
+		status = "nothing to compare with"
+	} else {
+		cs := compare.Runs(baseline, run, o)
+		var regressed, improved []compare.Comparison
+		for _, c := range cs {
+			switch c.Verdict {
+			case compare.Regressed, compare.Failed:
+				regressed = append(regressed, c)
+			case compare.Improved:
+				improved = append(improved, c)
+			}
+		}
+		fmt.Fprintf(&b, "\nagainst %s, reporting over +%g%% p95 at p < %g: %d regressed or failed, %d improved, of %d targets\n",
+			baseline.ID, 100*o.Threshold, o.Alpha, len(regressed), len(improved), len(cs))
+		if len(regressed) > 0 {
+			printComparisons(&b, regressed)
+			status = fmt.Sprintf("%d regressed or failed", len(regressed))
+		}
+	}
+	if failed > 0 {
+		b.WriteString("\nfailed:\n")
+		for _, r := range run.Results {
+			if r.Error != "" {
+				fmt.Fprintf(&b, "  %s: %s\n", compare.Target(r), r.Error)
+			}
+		}
+	}
+	switch {
+	case failed == len(run.Results):
+		status = "failed"
+	case runErr != nil:
+		status = "stopped early, " + status
+	}
+	return fmt.Sprintf("ruchy-bench %s %s: %s", run.Mode, run.ID, status), strings.TrimSpace(b.String())
+}
+
+// runCost estimates what run's Lambda invocations cost from their REPORT
+// lines, priced on demand at p without the free tier. ok is false when
+// no invocation had a REPORT line, as in a local run.
+func runCost(run *results.Run, p cost.Pricing) (total float64, ok bool) {
+	for _, r := range run.Results {
+		for _, s := range r.Samples {
+			if s.RequestID == "" {
+				continue
+			}
+			ok = true
+			u := cost.Usage{Arch: r.Arch, MemoryMB: int32(s.MemorySizeMB), BilledMS: s.BilledMS, Invocations: 1}
+			if u.MemoryMB == 0 {
+				u.MemoryMB = r.Memory()
+			}
+			b, err := p.Estimate(u, false)
+			if err != nil {
+				b.Total = p.PerRequest
+			}
+			total += b.Total
+		}
+	}
+	return total, ok
+}
+
+// notifyFailure posts that command failed with err to the webhooks that
+// have not posted a run of it.
+func notifyFailure(ctx context.Context, command string, err error) {
+	for spec := range webhooks {
+		n, nerr := spec.notifier()
+		if nerr == nil {
+			nerr = n.Notify(context.WithoutCancel(ctx), fmt.Sprintf("ruchy-bench %s: failed", command), err.Error())
+		}
+		if nerr != nil {
+			fmt.Fprintf(os.Stderr, "ruchy-bench: sink %s: %v\n", spec.kind, nerr)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/cwmetrics"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/sink"
+	"lambdaperf/pkg/store"
+)
+
+// outputFlags controls where a run is recorded: a results file for the run
+// itself, the history database shared by every run and any other sinks.
+type outputFlags struct {
+	out   string
+	db    string
+	sinks []sinkSpec
+}
+
+// sinkSpec is one -sink value: a sink kind and its target.
+type sinkSpec struct{ kind, target string }
+
+func (f *outputFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.out, "out", "", "results file (default: <root>/.bench/results/<run-id>.json)")
+	registerDB(fs, &f.db)
+	fs.Func("sink", "also write the run to `kind=target`, comma-separated or repeated: json=PATH, csv=PATH, samples=PATH (every raw sample as CSV), "+
+		"grafana=PATH, pushgateway=URL, s3=BUCKET[/PREFIX], cloudwatch[=NAMESPACE], or slack[=WEBHOOK] or discord=WEBHOOK to post a summary, or the failure", func(v string) error {
+		for _, spec := range splitList(v) {
+			kind, target, _ := strings.Cut(spec, "=")
+			switch {
+			case kind == "cloudwatch" || kind == "slack":
+			case kind != "json" && kind != "csv" && kind != "samples" && kind != "grafana" && kind != "pushgateway" && kind != "s3" && kind != "discord":
+				return fmt.Errorf("unknown sink %q: want json, csv, samples, grafana, pushgateway, s3, cloudwatch, slack or discord", kind)
+			case target == "":
+				return fmt.Errorf("sink %s needs a target, as %s=...", kind, kind)
+			}
+			s := sinkSpec{kind, target}
+			if kind == "slack" || kind == "discord" {
+				webhooks[s] = true
+			}
+			f.sinks = append(f.sinks, s)
+		}
+		return nil
+	})
+}
+
+// open returns the sink spec describes for run. Grafana annotates the run
+// with its metadata's commit and toolchain versions; S3 and CloudWatch
+// use the AWS config's default region. save gives a webhook sink the
+// history to compare with.
+func (s sinkSpec) open(ctx context.Context, run *results.Run) (sink.Sink, error) {
+	switch s.kind {
+	case "json":
+		return sink.JSON{Path: s.target}, nil
+	case "csv":
+		return sink.CSV{Path: s.target}, nil
+	case "samples":
+		return sink.Samples{Path: s.target}, nil
+	case "grafana":
+		g := sink.Grafana{Path: s.target}
+		if m := run.Metadata; m != nil {
+			g.Commit, g.Versions = m.Commit[:min(len(m.Commit), 7)], m.Toolchains
+			if m.Dirty {
+				g.Commit += "-dirty"
+			}
+		}
+		return g, nil
+	case "pushgateway":
+		return sink.Pushgateway{URL: s.target}, nil
+	case "slack", "discord":
+		n, err := s.notifier()
+		if err != nil {
+			return nil, err
+		}
+		return &notifySink{notifier: n}, nil
+	}
+	cfg, err := loadAWSConfig(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if s.kind == "s3" {
+		return s3Sink(cfg, s.target), nil
+	}
+	return sink.CloudWatch{Client: &cwmetrics.Client{Config: cfg}, Namespace: s.target}, nil
+}
+
+// s3Sink returns the S3 sink of an s3=BUCKET[/PREFIX] target. A prefix
+// is a directory: it always ends in a slash.
+func s3Sink(cfg aws.Config, target string) sink.S3 {
+	bucket, prefix, _ := strings.Cut(target, "/")
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return sink.S3{Client: s3.NewFromConfig(cfg), Bucket: bucket, Prefix: prefix}
+}
+
+func registerDB(fs *flag.FlagSet, db *string) {
+	fs.StringVar(db, "db", "", "history database (default: <root>/.bench/results.db; s3://BUCKET/KEY shares one in S3; \"none\" disables)")
+}
+
+// sharedDB returns the shared database db names, or nil when db is a
+// local path. Its local copy is kept under <root>/.bench/shared.
+func sharedDB(ctx context.Context, root, db string) (*store.Shared, error) {
+	loc, ok := strings.CutPrefix(db, "s3://")
+	if !ok {
+		return nil, nil
+	}
+	bucket, key, _ := strings.Cut(loc, "/")
+	if bucket == "" || key == "" || strings.HasSuffix(key, "/") {
+		return nil, fmt.Errorf("-db %s: want s3://BUCKET/KEY", db)
+	}
+	cfg, err := loadAWSConfig(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return &store.Shared{
+		Client: s3.NewFromConfig(cfg),
+		Bucket: bucket,
+		Key:    key,
+		Path:   filepath.Join(root, ".bench", "shared", bucket, filepath.FromSlash(key)),
+	}, nil
+}
+
+// openStore opens the history database for reading. A shared one is
+// downloaded first, so reads see every machine's runs.
+func openStore(ctx context.Context, root, db string) (*store.Store, error) {
+	sh, err := sharedDB(ctx, root, db)
+	if err != nil || sh == nil {
+		if err != nil {
+			return nil, err
+		}
+		return store.Open(dbPath(root, db))
+	}
+	if _, err := sh.Pull(ctx); err != nil {
+		return nil, err
+	}
+	return store.Open(sh.Path)
+}
+
+func dbPath(root, db string) string {
+	if db == "" {
+		return filepath.Join(root, ".bench", "results.db")
+	}
+	return db
+}
+
+// save writes run to its results file, appends it to the history
+// database and writes it to every -sink, returning the results file path.
+// Runs without metadata have it captured, and results without artifact
+// sizes get those of the target's last recorded build, first.
+func (f *outputFlags) save(ctx context.Context, root string, run *results.Run) (string, error) {
+	path := f.out
+	if path == "" {
+		path = filepath.Join(root, ".bench", "results", run.ID+".json")
+	}
+	// The run may have been interrupted; record what was collected.
+	stopped := context.Cause(ctx)
+	ctx = context.WithoutCancel(ctx)
+	if run.Metadata == nil {
+		captureMetadata(ctx, root, run)
+	}
+	hdb, err := openHistory(ctx, root, f.db)
+	if err == nil {
+		defer hdb.Close()
+		err = hdb.fill(ctx, run)
+	}
+	if err := (sink.JSON{Path: path}).Write(ctx, run); err != nil {
+		return "", err
+	}
+	if err != nil {
+		return path, err
+	}
+	if hdb != nil {
+		if err := hdb.save(ctx, run); err != nil {
+			return path, fmt.Errorf("record history: %w", err)
+		}
+	}
+	var errs []error
+	for _, spec := range f.sinks {
+		s, err := spec.open(ctx, run)
+		if n, ok := s.(*notifySink); ok {
+			n.history, n.stopped = hdb, stopped
+			delete(webhooks, spec)
+		}
+		if err == nil {
+			err = s.Write(ctx, run)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", spec.kind, err))
+		}
+	}
+	return path, errors.Join(errs...)
+}
+
+// historyDB is the history database as the commands that build use it: to
+// record artifact sizes. Lambda runs do not build, so their results pick up
+// the sizes of the last recorded build when saved; see outputFlags.save. A
+// nil historyDB (-db none) records nothing.
+type historyDB struct {
+	s *store.Store
+	// shared is the S3 database s is a copy of, when -db names one.
+	// Writes go to it, and s is reopened on the copy they leave.
+	shared *store.Shared
+}
+
+func openHistory(ctx context.Context, root, db string) (*historyDB, error) {
+	if db == "none" {
+		return nil, nil
+	}
+	sh, err := sharedDB(ctx, root, db)
+	if err != nil {
+		return nil, err
+	}
+	path := dbPath(root, db)
+	if sh != nil {
+		if _, err := sh.Pull(ctx); err != nil {
+			return nil, err
+		}
+		path = sh.Path
+	}
+	s, err := store.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &historyDB{s: s, shared: sh}, nil
+}
+
+// write applies change to the database, through a shared one's Update.
+func (h *historyDB) write(ctx context.Context, change func(*store.Store) error) error {
+	if h.shared == nil {
+		return change(h.s)
+	}
+	if err := h.s.Close(); err != nil {
+		return err
+	}
+	err := h.shared.Update(ctx, change)
+	s, oerr := store.Open(h.shared.Path)
+	if oerr != nil {
+		return errors.Join(err, oerr)
+	}
+	h.s = s
+	return err
+}
+
+// save records run.
+func (h *historyDB) save(ctx context.Context, run *results.Run) error {
+	return h.write(ctx, func(s *store.Store) error { return s.Save(ctx, run) })
+}
+
+func (h *historyDB) record(ctx context.Context, a build.Artifact) error {
+	if h == nil {
+		return nil
+	}
+	artifact := store.Artifact{
+		Kind:         string(a.Target.Kind),
+		Runtime:      a.Target.Runtime,
+		Workload:     a.Target.Workload,
+		Arch:         a.Target.Arch,
+		Package:      a.Target.Package,
+		BuiltAt:      time.Now(),
+		BinaryBytes:  a.BinaryBytes,
+		PackageBytes: a.PackageBytes,
+	}
+	return h.write(ctx, func(s *store.Store) error { return s.SaveArtifact(ctx, artifact) })
+}
+
+// fill copies the last recorded sizes into results that have none.
+func (h *historyDB) fill(ctx context.Context, run *results.Run) error {
+	for i := range run.Results {
+		r := &run.Results[i]
+		if h == nil || r.BinaryBytes != 0 || r.PackageBytes != 0 {
+			continue
+		}
+		a, ok, err := h.s.LatestArtifact(ctx, r.Kind, r.Runtime, r.Workload, r.Arch, r.Package)
+		if err != nil {
+			return err
+		}
+		if ok {
+			r.BinaryBytes, r.PackageBytes = a.BinaryBytes, a.PackageBytes
+		}
+	}
+	return nil
+}
+
+func (h *historyDB) Close() error {
+	if h == nil {
+		return nil
+	}
+	return h.s.Close()
+}
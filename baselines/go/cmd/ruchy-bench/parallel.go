@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"sync"
+
+	"lambdaperf/pkg/pool"
+)
+
+// parallelFlags bound how many targets a command works on at once
+// (-parallel) and how fast they may call Lambda's control plane between
+// them (-api-rate).
+type parallelFlags struct {
+	workers int
+	rate    float64
+}
+
+// register adds the flags; what says what -parallel runs at once.
+func (f *parallelFlags) register(fs *flag.FlagSet, what string) {
+	fs.IntVar(&f.workers, "parallel", pool.DefaultWorkers, what+" at once")
+	fs.Float64Var(&f.rate, "api-rate", pool.DefaultRate, "Lambda control-plane requests per second per region, shared by the -parallel workers (invocations are not limited)")
+}
+
+// validate checks the flags and sets the rate every AWS config loaded
+// from then on is limited to.
+func (f *parallelFlags) validate() error {
+	if f.workers < 1 {
+		return errors.New("-parallel must be at least 1")
+	}
+	if f.rate <= 0 {
+		return errors.New("-api-rate must be positive")
+	}
+	limits.mu.Lock()
+	defer limits.mu.Unlock()
+	if limits.rate != f.rate {
+		limits.rate, limits.byRegion = f.rate, nil
+	}
+	return nil
+}
+
+// limits holds the control-plane limiter of each region, which every
+// client of the region shares: Lambda's quota is per account and region,
+// not per client.
+var limits = struct {
+	mu       sync.Mutex
+	rate     float64
+	byRegion map[string]*pool.Limiter
+}{rate: pool.DefaultRate}
+
+// regionLimiter returns region's control-plane limiter.
+func regionLimiter(region string) *pool.Limiter {
+	limits.mu.Lock()
+	defer limits.mu.Unlock()
+	if limits.byRegion == nil {
+		limits.byRegion = map[string]*pool.Limiter{}
+	}
+	l, ok := limits.byRegion[region]
+	if !ok {
+		l = pool.NewLimiter(limits.rate)
+		limits.byRegion[region] = l
+	}
+	return l
+}
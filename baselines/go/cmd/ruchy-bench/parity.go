@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/localbench"
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/results"
+)
+
+// parityCheck is the verdict on one implementation.
+type parityCheck struct {
+	kind              discover.Kind
+	runtime, workload string
+	status            string // ok, FAIL or skip
+	detail            string
+}
+
+func runVerifyParity(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("verify-parity", flag.ContinueOnError)
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	kind := fs.String("kind", "", "only check local or lambda implementations")
+	runtimes := fs.String("runtime", "", "comma-separated runtimes to check (default: all)")
+	workloads := fs.String("workload", "", "comma-separated workloads to check (default: all)")
+	invokeLambda := fs.Bool("invoke", false, "also invoke the deployed Lambda functions and check their responses")
+	region := fs.String("region", "", "AWS region for -invoke (default: from AWS config)")
+	var pf payloadFlags
+	pf.register(fs)
+	verbose := fs.Bool("v", false, "show compiler and build script output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var err error
+	if *root, err = findRoot(*root); err != nil {
+		return err
+	}
+	m, err := manifest.Load(*root)
+	if err != nil {
+		return err
+	}
+	all, err := discover.Discover(*root)
+	if err != nil {
+		return err
+	}
+	selects := func(k discover.Kind, runtime, workload string) bool {
+		return (*kind == "" || discover.Kind(*kind) == k) &&
+			(*runtimes == "" || slices.Contains(splitList(*runtimes), runtime)) &&
+			(*workloads == "" || slices.Contains(splitList(*workloads), workload))
+	}
+
+	var checks []parityCheck
+	for _, g := range m.Coverage(all) {
+		if selects(g.Kind, g.Runtime, g.Workload) {
+			detail := "implemented but not declared in " + manifest.Path
+			if g.Missing {
+				detail = "declared in " + manifest.Path + " but not implemented"
+			}
+			checks = append(checks, parityCheck{g.Kind, g.Runtime, g.Workload, "FAIL", detail})
+		}
+	}
+
+	b := newBuilder(*root, "", *verbose)
+	var client *lambda.Client
+	for _, t := range all {
+		w, ok := m.Workload(t.Workload)
+		if !ok || !w.Implements(t.Kind, t.Runtime) || !selects(t.Kind, t.Runtime, t.Workload) {
+			continue
+		}
+		c := parityCheck{kind: t.Kind, runtime: t.Runtime, workload: t.Workload}
+		switch documented, err := documentedResult(t.Source); {
+		case err != nil:
+			c.status, c.detail = "FAIL", err.Error()
+		case w.Expected == "":
+			c.status, c.detail = "ok", "no fixed result to check"
+		case documented != "" && documented != w.Expected:
+			c.status, c.detail = "FAIL", fmt.Sprintf("source documents %q, manifest expects %q", documented, w.Expected)
+		case t.Kind == discover.KindLocal:
+			c.status, c.detail = checkLocal(ctx, b, t, w)
+		case *invokeLambda:
+			if client == nil {
+				if client, err = newLambdaClient(ctx, *region); err != nil {
+					return err
+				}
+			}
+			payload, err := pf.forTarget(t)
+			if err != nil {
+				return err
+			}
+			inv := &invoke.Lambda{Client: client, FunctionName: t.FunctionName()}
+			c.status, c.detail = checkLambda(ctx, inv, payload, w)
+		default:
+			c.status, c.detail = "skip", "not invoked; pass -invoke to check the deployed function"
+		}
+		checks = append(checks, c)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+
+	sort.SliceStable(checks, func(i, j int) bool {
+		a, b := checks[i], checks[j]
+		if a.workload != b.workload {
+			return a.workload < b.workload
+		}
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		return a.runtime < b.runtime
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tWORKLOAD\tKIND\tRUNTIME\tDETAIL")
+	failed := 0
+	for _, c := range checks {
+		if c.status == "FAIL" {
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.status, c.workload, c.kind, c.runtime, c.detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("parity check failed for %d of %d implementations", failed, len(checks))
+	}
+	fmt.Fprintf(os.Stderr, "%d implementations checked against %s\n", len(checks), manifest.Path)
+	return nil
+}
+
+// checkLocal builds and runs a local implementation once. A target that
+// cannot be built or started here (its toolchain is not installed) is
+// skipped rather than failed: nothing is known about its result.
+func checkLocal(ctx context.Context, b *build.Builder, t discover.Target, w manifest.Workload) (string, string) {
+	a, err := b.Build(ctx, t)
+	if err != nil {
+		return "skip", "cannot build: " + firstLine(err.Error())
+	}
+	r := &localbench.Runner{Command: a.Command, Dir: t.Dir, Expected: w.Expected}
+	if _, err := r.Run(ctx); errors.Is(err, exec.ErrNotFound) {
+		return "skip", "cannot run: " + firstLine(err.Error())
+	} else if err != nil {
+		return "FAIL", firstLine(err.Error())
+	}
+	return "ok", w.Expected
+}
+
+// checkLambda invokes a deployed function once and compares the result in
+// its response.
+func checkLambda(ctx context.Context, inv invoke.Invoker, payload []byte, w manifest.Workload) (string, string) {
+	resp, err := inv.Invoke(ctx, payload)
+	switch {
+	case err != nil:
+		return "FAIL", firstLine(err.Error())
+	case resp.FunctionError != "":
+		return "FAIL", "function error: " + resp.FunctionError
+	}
+	if got := results.Body(resp.Payload); got != w.Expected {
+		return "FAIL", fmt.Sprintf("returned %q, want %q", got, w.Expected)
+	}
+	return "ok", w.Expected
+}
+
+// documentedResult returns the "Expected result:" a source file states in
+// any of its comments, or "" when it states none.
+func documentedResult(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		text, ok := strings.CutPrefix(line, "//")
+		if !ok {
+			if text, ok = strings.CutPrefix(line, "#"); !ok {
+				continue
+			}
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(text), "Expected result:"); ok {
+			return strings.TrimSpace(v), nil
+		}
+	}
+	return "", sc.Err()
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"lambdaperf/pkg/discover"
+)
+
+// payloadFlags selects what each target is invoked with.
+type payloadFlags struct {
+	payload string
+}
+
+func (f *payloadFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.payload, "payload", "", "invocation payload: JSON, or @file (default: the workload's event fixture, else {})")
+}
+
+// forTarget returns the payload for t. Without -payload, workloads that
+// expect a trigger event are invoked with their fixture from
+// baselines/events/ and everything else with an empty object.
+func (f *payloadFlags) forTarget(t discover.Target) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(f.payload, "@"):
+		return os.ReadFile(f.payload[1:])
+	case f.payload != "":
+		return []byte(f.payload), nil
+	case t.Event != "":
+		return os.ReadFile(t.Event)
+	}
+	return []byte("{}"), nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/fixture"
+	"lambdaperf/pkg/platform"
+	"lambdaperf/pkg/results"
+)
+
+// payloadInput is the Result.Input entry a payloads result records its
+// payload size in.
+const payloadInput = "payload_bytes"
+
+func runPayloads(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("payloads", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	sizes := fs.String("sizes", "", "comma-separated payload sizes in bytes, KB or MB, or max for the largest Lambda accepts (default: 1KB,64KB,256KB,1MB,max)")
+	n := fs.Int("n", 10, "invocations per payload size")
+	var wf warmupFlags
+	wf.register(fs)
+	var rf retryFlags
+	rf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	var bf budgetFlags
+	bf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if err := wf.validate(); err != nil {
+		return err
+	}
+	if err := rf.validate(); err != nil {
+		return err
+	}
+	list, err := parsePayloadSizes(*sizes)
+	if err != nil {
+		return err
+	}
+	tf.kind = string(discover.KindLambda)
+	if tf.workloads == "" {
+		tf.workloads = fixture.EchoWorkload
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if t.Workload != fixture.EchoWorkload {
+			// Other handlers would do their own work on top, or refuse
+			// the document outright.
+			return fmt.Errorf("%s: payloads only invokes the %s workload", t.ID(), fixture.EchoWorkload)
+		}
+	}
+	client, err := newLambdaClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+	payloads := make(map[int][]byte, len(list))
+	for _, size := range list {
+		payloads[size] = fixture.Payload(size)
+	}
+
+	run := results.NewRun("payloads", time.Now())
+	for _, t := range targets {
+		fmt.Fprintf(os.Stderr, "%s: %s payloads, %d invocations each\n", t.ID(), payloadLabels(list), *n)
+		run.Results = append(run.Results, payloadTarget(ctx, client, t, list, payloads, *n, &wf, &rf)...)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printPayloads(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return context.Cause(ctx)
+}
+
+// payloadTarget benchmarks t with the payload of every size, each checked
+// against the result the echo workload must report for it.
+func payloadTarget(ctx context.Context, client *lambda.Client, t discover.Target, sizes []int,
+	payloads map[int][]byte, n int, wf *warmupFlags, rf *retryFlags) []results.Result {
+	l := &platform.Lambda{Client: client, Function: t.FunctionName(), Qualifier: t.Qualifier(), Backoff: rf.backoff()}
+	var out []results.Result
+	for _, size := range sizes {
+		res := newResult(t)
+		res.Input = map[string]int{payloadInput: size}
+		payload := payloads[size]
+		var steady bool
+		res.Samples, steady = collect(ctx, l, payload, n, wf.warmup(), wf.precision(), fixture.EchoResult(payload))
+		wf.report(fmt.Sprintf("%s %s", t.ID(), payloadLabel(size)), &res, steady)
+		out = append(out, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return out
+}
+
+// parsePayloadSizes parses -sizes: byte counts with an optional KB or MB
+// suffix, in units of 1024, or max.
+func parsePayloadSizes(s string) ([]int, error) {
+	list := splitList(s)
+	if len(list) == 0 {
+		return fixture.PayloadSizes, nil
+	}
+	var sizes []int
+	for _, v := range list {
+		size, err := parsePayloadSize(v)
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, size)
+	}
+	slices.Sort(sizes)
+	return slices.Compact(sizes), nil
+}
+
+func parsePayloadSize(v string) (int, error) {
+	if strings.EqualFold(v, "max") {
+		return fixture.MaxPayload, nil
+	}
+	num, unit := v, 1
+	switch upper := strings.ToUpper(v); {
+	case strings.HasSuffix(upper, "MB"):
+		num, unit = v[:len(v)-2], 1<<20
+	case strings.HasSuffix(upper, "KB"):
+		num, unit = v[:len(v)-2], 1<<10
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(num))
+	if err != nil {
+		return 0, fmt.Errorf("invalid payload size %q: want bytes, KB, MB or max", v)
+	}
+	size := n * unit
+	if size < fixture.MinPayload || size > fixture.MaxPayload {
+		return 0, fmt.Errorf("payload size %q: want %d to %d bytes (max); Lambda refuses requests of 6 MB or more",
+			v, fixture.MinPayload, fixture.MaxPayload)
+	}
+	return size, nil
+}
+
+// payloadLabel renders size the way -sizes takes it.
+func payloadLabel(size int) string {
+	switch {
+	case size == fixture.MaxPayload:
+		return "max"
+	case size%(1<<20) == 0:
+		return strconv.Itoa(size>>20) + "MB"
+	case size%(1<<10) == 0:
+		return strconv.Itoa(size>>10) + "KB"
+	}
+	return strconv.Itoa(size)
+}
+
+func payloadLabels(sizes []int) string {
+	labels := make([]string, len(sizes))
+	for i, size := range sizes {
+		labels[i] = payloadLabel(size)
+	}
+	return strings.Join(labels, ",")
+}
+
+// printPayloads shows each function's curve against payload size: the
+// median duration Lambda reports, which is the runtime decoding and
+// encoding the document, and the median client time, which adds sending
+// it over the network.
+func printPayloads(run *results.Run) {
+	label := func(size int) string { return fmt.Sprintf("%s (%d B)", payloadLabel(size), size) }
+	printCurve(run, payloadInput, "PAYLOAD", results.MetricDuration, "duration p50 (ms)", label)
+	fmt.Println()
+	printCurve(run, payloadInput, "PAYLOAD", results.MetricClient, "client p50 (ms), upload included", label)
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/plan"
+	"lambdaperf/pkg/pool"
+	"lambdaperf/pkg/store"
+)
+
+// plannable lists the commands plan can estimate, the first by default.
+var plannable = []string{"run", "coldstart", "sweep", "scale"}
+
+func runPlan(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: ruchy-bench plan [%s] [flags]\n", strings.Join(plannable, "|"))
+		fs.PrintDefaults()
+	}
+	mode := plannable[0]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		mode, args = args[0], args[1:]
+	}
+	if !slices.Contains(plannable, mode) {
+		return fmt.Errorf("cannot plan %q: want one of %s", mode, strings.Join(plannable, ", "))
+	}
+	var tf targetFlags
+	tf.register(fs)
+	n := fs.Int("n", 10, "invocations per target, cold starts for coldstart, as given to the planned command")
+	var wf warmupFlags
+	wf.register(fs)
+	region := fs.String("region", "", "comma-separated AWS regions, as given to the planned command (default: from AWS config)")
+	sizes := fs.String("sizes", "", "sweep: comma-separated memory sizes in MB (default: 128,256,512,1024,1769,3008)")
+	input := fs.String("input", "", "scale: workload input and the values to sweep it over, e.g. n=25,30,35,40")
+	parallel := fs.Int("parallel", pool.DefaultWorkers, "run, coldstart, sweep: functions measured at once, as given to the planned command")
+	var db string
+	registerDB(fs, &db)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if err := wf.validate(); err != nil {
+		return err
+	}
+	if *parallel < 1 {
+		return errors.New("-parallel must be at least 1")
+	}
+	workers := *parallel
+	if mode == "scale" {
+		// scale measures one target at a time.
+		workers = 1
+	}
+	regions := regionList(*region)
+	if (mode == "sweep" || mode == "scale") && len(regions) > 1 {
+		return fmt.Errorf("%s measures a single region", mode)
+	}
+	if mode != "run" {
+		tf.kind = string(discover.KindLambda)
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	if mode == "sweep" && tf.snapStart {
+		return errors.New("sweep does not support -snapstart")
+	}
+
+	e := &estimator{ctx: ctx}
+	if db != "none" {
+		if s, err := openStore(ctx, root, db); err != nil {
+			fmt.Fprintln(os.Stderr, "warning: no history to estimate from:", err)
+		} else {
+			defer s.Close()
+			e.store = s
+		}
+	}
+	// Steady-state detection may warm up to -max-warmup, and -precision
+	// record up to -max-n; plan for both.
+	warmup, recorded := wf.min, *n
+	if wf.cv > 0 {
+		warmup = wf.max
+	}
+	if wf.precise > 0 {
+		recorded = max(recorded, wf.maxN)
+	}
+
+	var items []plan.Item
+	switch mode {
+	case "run":
+		for _, t := range targets {
+			if t.Kind == discover.KindLocal {
+				items = append(items, plan.Item{Label: t.ID(), Invocations: warmup + recorded, Per: e.per(t, 0, "")})
+				continue
+			}
+			for _, r := range regions {
+				// The canary invoke comes first; a freshly deployed
+				// function starts cold once.
+				items = append(items, lambdaItem(t, r, deploy.DefaultMemoryMB, 1+warmup+recorded, 1, e.per(t, deploy.DefaultMemoryMB, "")))
+			}
+		}
+	case "coldstart":
+		for _, t := range targets {
+			for _, r := range regions {
+				it := lambdaItem(t, r, deploy.DefaultMemoryMB, *n, *n, e.per(t, deploy.DefaultMemoryMB, ""))
+				it.Updates = *n
+				if t.SnapStart {
+					it.Publishes = *n
+				}
+				items = append(items, it)
+			}
+		}
+	case "sweep":
+		memSizes, err := parseSizes(*sizes)
+		if err != nil {
+			return err
+		}
+		for _, t := range targets {
+			for _, size := range memSizes {
+				// One cold invocation follows each memory update.
+				it := lambdaItem(t, regions[0], size, warmup+recorded+1, 1, e.per(t, size, ""))
+				it.Label = fmt.Sprintf("%s %d MB", t.ID(), size)
+				it.Updates = 1
+				items = append(items, it)
+			}
+			// The original size is restored at the end.
+			items[len(items)-1].Updates++
+		}
+	case "scale":
+		name, values, err := parseInput(*input)
+		if err != nil {
+			return err
+		}
+		m, err := manifest.Load(root)
+		if err != nil {
+			return err
+		}
+		for _, t := range targets {
+			w, _ := m.Workload(t.Workload)
+			if in, ok := w.Inputs[name]; !ok || !in.Accepts(t.Runtime) {
+				continue
+			}
+			for _, v := range values {
+				label := fmt.Sprintf("%s=%d", name, v)
+				it := lambdaItem(t, regions[0], deploy.DefaultMemoryMB, warmup+recorded, 0, e.per(t, deploy.DefaultMemoryMB, label))
+				it.Label = t.ID() + " " + label
+				items = append(items, it)
+			}
+		}
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("%s would benchmark nothing: no selected target matches", mode)
+	}
+	return printPlan(mode, items, workers)
+}
+
+func lambdaItem(t discover.Target, region string, memoryMB int32, invocations, cold int, per plan.Per) plan.Item {
+	return plan.Item{
+		Label:       t.ID(),
+		Function:    t.FunctionName(),
+		Region:      region,
+		Arch:        t.Arch,
+		MemoryMB:    memoryMB,
+		Invocations: invocations,
+		ColdStarts:  cold,
+		Per:         per,
+	}
+}
+
+// estimator looks up what a target's invocations took in the history
+// database, falling back to plan.Default.
+type estimator struct {
+	ctx   context.Context
+	store *store.Store
+}
+
+// per estimates t at memoryMB with the input label input, "" for the
+// workload's default. The most recent result at that size and input is
+// preferred; one at another size is scaled to it.
+func (e *estimator) per(t discover.Target, memoryMB int32, input string) plan.Per {
+	// The default holds at the size functions are deployed with.
+	def := plan.Default.Scale(deploy.DefaultMemoryMB, memoryMB)
+	if t.Kind == discover.KindLocal {
+		def = plan.Default
+	}
+	if e.store == nil {
+		return def
+	}
+	entries, err := e.store.History(e.ctx, store.Query{
+		Workload: t.Workload,
+		Runtimes: []string{t.Runtime},
+		Kind:     string(t.Kind),
+		Arch:     t.Arch,
+		Limit:    20,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "warning: history of", t.ID()+":", err)
+		return def
+	}
+	var fallback *store.Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		r := entries[i].Result
+		if r.Package != t.Package || r.SnapStart != t.SnapStart || r.Extension != t.Extension || r.VPC != t.VPC || r.Chaos != t.Chaos || r.Serializer != t.Serializer || r.Edge || r.InputLabel() != input {
+			continue
+		}
+		if r.Memory() == memoryMB {
+			if p, ok := plan.Observe(r, entries[i].RunID); ok {
+				return p
+			}
+		} else if fallback == nil {
+			fallback = &entries[i]
+		}
+	}
+	if fallback != nil {
+		mem := fallback.Result.Memory()
+		if p, ok := plan.Observe(fallback.Result, fmt.Sprintf("%s at %d MB", fallback.RunID, mem)); ok {
+			return p.Scale(mem, memoryMB)
+		}
+	}
+	return def
+}
+
+func printPlan(mode string, items []plan.Item, workers int) error {
+	type function struct{ name, region string }
+	var (
+		functions          = map[function]bool{}
+		regions            = map[string]bool{}
+		invocations, cold  int
+		updates, published int
+		usd                float64
+	)
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tFUNCTION\tMEMORY(MB)\tINVOCATIONS\tCOLD\tUPDATES\tPUBLISHES\tEST. TIME\tEST. USD\tBASED ON")
+	for _, it := range items {
+		c, err := it.Cost(cost.Default)
+		if err != nil {
+			return err
+		}
+		fn, mem := "-", "-"
+		if it.Function != "" {
+			fn, mem = inRegion(it.Function, it.Region), fmt.Sprint(it.MemoryMB)
+			functions[function{it.Function, it.Region}] = true
+			regions[it.Region] = true
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t%.6f\t%s\n", it.Label, fn, mem,
+			it.Invocations, it.ColdStarts, it.Updates, it.Publishes, it.Duration().Round(time.Second), c, it.Per.Source)
+		invocations += it.Invocations
+		cold += it.ColdStarts
+		updates += it.Updates
+		published += it.Publishes
+		usd += c
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%s: %d function(s) in %d region(s), %d invocations (%d cold), %d configuration updates, %d versions published; about %s and $%.6f\n",
+		mode, len(functions), len(regions), invocations, cold, updates, published, plan.Duration(items, workers).Round(time.Second), usd)
+	fmt.Println("The cost covers Lambda requests and compute only, not CloudWatch Logs, X-Ray or data transfer.")
+	if len(functions) > 0 {
+		fmt.Println("Each function must be deployed first; nothing was deployed or invoked.")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/profiles"
+)
+
+func runPprof(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("pprof", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	bucket := fs.String("bucket", "", "S3 bucket the targets were deployed with -pprof to upload to (required)")
+	kind := fs.String("type", profiles.CPU, "profile type: cpu or heap")
+	last := fs.Int("last", 0, "fetch only each target's newest N profiles (default: all)")
+	dir := fs.String("dir", "", "download directory (default: <root>/.bench)")
+	open := fs.Bool("open", false, "open each target's profiles, merged, in go tool pprof's web UI")
+	region := fs.String("region", "", "AWS region of the bucket (default: from AWS config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *bucket == "" {
+		return errors.New("pprof needs -bucket")
+	}
+	if *kind != profiles.CPU && *kind != profiles.Heap {
+		return fmt.Errorf("invalid -type %q: want cpu or heap", *kind)
+	}
+	if tf.runtimes == "" {
+		tf.runtimes = "go"
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	if *dir == "" {
+		*dir = filepath.Join(root, ".bench")
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	client := s3.NewFromConfig(cfg)
+
+	var failed int
+	for _, t := range targets {
+		if t.Runtime != "go" {
+			fmt.Fprintf(os.Stderr, "%s: only Go baselines are profiled\n", t.ID())
+			continue
+		}
+		paths, err := profiles.Fetch(ctx, client, *bucket, t.FunctionName(), *kind, *dir, *last)
+		if err == nil && len(paths) == 0 {
+			err = fmt.Errorf("no %s profiles in s3://%s/%s; deploy with -pprof %s and invoke it", *kind, *bucket, profiles.Prefix+t.FunctionName(), *bucket)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "%s: %v\n", t.ID(), err)
+			continue
+		}
+		fmt.Printf("%s: %d %s profiles in %s\n", t.ID(), len(paths), *kind, filepath.Dir(filepath.Dir(paths[0])))
+		if !*open {
+			continue
+		}
+		// go tool pprof merges the profiles, and serves them until
+		// interrupted.
+		cmd := exec.CommandContext(ctx, "go", append([]string{"tool", "pprof", "-http=localhost:0"}, paths...)...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil && ctx.Err() == nil {
+			return fmt.Errorf("go tool pprof: %w", err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d targets failed", failed, len(targets))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"sync"
+
+	"lambdaperf/pkg/progress"
+)
+
+// progressFlags choose how a long command reports its progress (-plain).
+type progressFlags struct {
+	plain bool
+}
+
+func (f *progressFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.plain, "plain", false, "log progress as a line per target every 30s instead of redrawing it live; the default when stderr is not a terminal, as in CI")
+}
+
+// start returns ctx carrying a progress board and a function that stops
+// it. A live board takes over stderr, and stdout when it is the same
+// terminal, until then; see capture. A command run by a matrix shares
+// the matrix's board, and ctx is returned unchanged.
+func (f *progressFlags) start(ctx context.Context) (context.Context, func()) {
+	if progress.From(ctx) != nil {
+		return ctx, func() {}
+	}
+	width := 0
+	if !f.plain {
+		width = progress.Width(os.Stderr)
+	}
+	b := progress.New(os.Stderr, width)
+	restore := func() {}
+	if b.Live() {
+		restore = capture(b)
+	}
+	b.Start()
+	return progress.NewContext(ctx, b), func() {
+		restore()
+		b.Stop()
+	}
+}
+
+// capture points os.Stderr, and os.Stdout when it is a terminal, at
+// pipes into b, so that what the command prints, builds it runs
+// included, scrolls above the live board instead of through it. restore
+// puts them back once everything written has reached b.
+func capture(b *progress.Board) (restore func()) {
+	var (
+		wg   sync.WaitGroup
+		undo []func()
+	)
+	swap := func(f **os.File) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return
+		}
+		orig := *f
+		*f = w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			io.Copy(b, r)
+			r.Close()
+		}()
+		undo = append(undo, func() {
+			*f = orig
+			w.Close()
+		})
+	}
+	if progress.Width(os.Stdout) > 0 {
+		swap(&os.Stdout)
+	}
+	swap(&os.Stderr)
+	return func() {
+		for _, u := range undo {
+			u()
+		}
+		wg.Wait()
+	}
+}
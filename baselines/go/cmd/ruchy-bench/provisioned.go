@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/provisioned"
+	"lambdaperf/pkg/results"
+)
+
+func runProvisioned(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("provisioned", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	concurrency := fs.Int("concurrency", 5, "provisioned environments per function")
+	burst := fs.Int("burst", 0, "simultaneous invocations per round (default: twice -concurrency)")
+	rounds := fs.Int("rounds", 5, "bursts per function")
+	var pf payloadFlags
+	pf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	var bf budgetFlags
+	bf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *concurrency < 1 || *rounds < 1 {
+		return errors.New("-concurrency and -rounds must be at least 1")
+	}
+	if *burst < 0 {
+		return errors.New("-burst must not be negative")
+	}
+	if *burst == 0 {
+		*burst = 2 * *concurrency
+	}
+	if tf.snapStart {
+		return errors.New("provisioned does not support -snapstart")
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	// Retries stay off: throttled invocations during a burst are part of
+	// the measurement.
+	client, err := newLambdaClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	expected, err := expectedResults(root)
+	if err != nil {
+		return err
+	}
+
+	run := results.NewRun("provisioned", time.Now())
+	d := &deploy.Deployer{Client: client}
+	for _, t := range targets {
+		payload, err := pf.forTarget(t)
+		if err != nil {
+			return err
+		}
+		res := newResult(t)
+		res.ProvisionedConcurrency = int32(*concurrency)
+		fmt.Fprintf(os.Stderr, "%s: publishing and provisioning %d environments\n", res.Function, *concurrency)
+		if _, err := d.Publish(ctx, res.Function, provisioned.Alias); err != nil {
+			res.Error = err.Error()
+			run.Results = append(run.Results, res)
+			continue
+		}
+		r := &provisioned.Runner{
+			Client:       client,
+			FunctionName: res.Function,
+			Concurrency:  int32(*concurrency),
+			Burst:        *burst,
+			Rounds:       *rounds,
+			Payload:      payload,
+			Expected:     expected[t.Workload],
+			Runtime:      canaryRuntime(t),
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d bursts of %d\n", res.Function, *rounds, *burst)
+		res.Samples, err = r.Run(ctx)
+		if err != nil {
+			res.Error = err.Error()
+		}
+		run.Results = append(run.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printStats(run, results.MetricWarm, cf)
+	fmt.Println()
+	printSpillover(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return context.Cause(ctx)
+}
+
+// printSpillover shows how many burst invocations overflowed the
+// provisioned environments and what that cost in latency.
+func printSpillover(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tPROVISIONED\tSPILLOVER\tWARM P50(ms)\tWARM P99(ms)\tCLIENT P99(ms)\tSPILLOVER INIT(ms)")
+	for _, r := range run.Results {
+		if r.Error != "" && len(r.Samples) == 0 {
+			fmt.Fprintf(w, "%s\t%d\terror: %s\n", r.Function, r.ProvisionedConcurrency, r.Error)
+			continue
+		}
+		spilled, total := provisioned.Spillover(r.Samples)
+		warm, client, cold := r.Stats[results.MetricWarm], r.Stats[results.MetricClient], r.Stats[results.MetricInit]
+		initMS := "-"
+		if cold.N > 0 {
+			initMS = fmt.Sprintf("%.2f", cold.Mean)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d/%d\t%.2f\t%.2f\t%.2f\t%s\n", r.Function, r.ProvisionedConcurrency,
+			spilled, total, warm.Median, warm.P99, client.P99, initMS)
+	}
+	w.Flush()
+}
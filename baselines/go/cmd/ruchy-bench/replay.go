@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/loadgen"
+	"lambdaperf/pkg/results"
+)
+
+func runRecord(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench record [flags] <payloads.jsonl | logs-insights.json>")
+		fs.PrintDefaults()
+	}
+	out := fs.String("out", "", "trace file to write, as JSON lines (required)")
+	limit := fs.Int("limit", 0, "keep only the first N events (0: all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" || fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("record needs -out and one export")
+	}
+	if *limit < 0 {
+		return errors.New("-limit must not be negative")
+	}
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	events, err := loadgen.ReadTrace(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+	if *limit > 0 && len(events) > *limit {
+		events = events[:*limit]
+	}
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		return err
+	}
+	if f, err = os.Create(*out); err != nil {
+		return err
+	}
+	if err := loadgen.WriteTrace(f, events); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d events over %s, peak %d in one second, written to %s\n",
+		len(events), loadgen.Span(events).Round(time.Millisecond), peakPerSecond(events), *out)
+	return nil
+}
+
+// peakPerSecond is the most events of a trace that arrived within one
+// second of each other.
+func peakPerSecond(events []loadgen.Event) int {
+	peak, lo := 0, 0
+	for hi, e := range events {
+		for e.At-events[lo].At >= time.Second {
+			lo++
+		}
+		peak = max(peak, hi-lo+1)
+	}
+	return peak
+}
+
+func runReplay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	trace := fs.String("trace", "", "recorded payloads to replay: a record trace, JSON lines with timestamps or a Logs Insights export (required)")
+	speed := fs.Float64("speed", 1, "pace relative to the recording: 1 replays the original gaps, 10 ten times as fast")
+	workers := fs.Int("workers", 0, "most requests in flight per function; events due beyond it start late (0: no cap)")
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	var bf budgetFlags
+	bf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *trace == "" {
+		return errors.New("replay needs -trace")
+	}
+	if *speed <= 0 || *workers < 0 {
+		return errors.New("-speed must be positive and -workers not negative")
+	}
+	f, err := os.Open(*trace)
+	if err != nil {
+		return err
+	}
+	events, err := loadgen.ReadTrace(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", *trace, err)
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	// Retries stay off, as under load: throttling is part of the shape.
+	client, err := newLambdaClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	span := loadgen.Span(events)
+	rate := 0.0
+	if span > 0 {
+		rate = float64(len(events)) / (span.Seconds() / *speed)
+	}
+	run := results.NewRun("replay", time.Now())
+	for _, t := range targets {
+		res := newResult(t)
+		fmt.Fprintf(os.Stderr, "%s: %d events over %s\n", res.Function, len(events), time.Duration(float64(span) / *speed).Round(time.Millisecond))
+		// Recorded payloads ask for results of their own, so responses
+		// are not checked against the manifest; refusals still fail.
+		out, err := loadgen.Replay(ctx, &invoke.Lambda{Client: client, FunctionName: res.Function, Qualifier: t.Qualifier()},
+			loadgen.ReplayConfig{Events: events, Speed: *speed, Workers: *workers})
+		if err != nil {
+			res.Error = err.Error()
+		}
+		res.Samples = out.Samples
+		for i, s := range res.Samples {
+			if code := statusCode(s.Response); s.Error == "" && code != 0 && code != 200 {
+				res.Samples[i].Error = fmt.Sprintf("status %d: %s", code, results.Body([]byte(s.Response)))
+			}
+		}
+		res.Load = &results.Load{
+			Workers:         *workers,
+			TargetRPS:       rate,
+			AchievedRPS:     out.AchievedRPS(),
+			DurationMS:      results.Milliseconds(out.Elapsed),
+			Throttled:       out.Throttled,
+			ClientP50MS:     out.Client.Quantile(0.5),
+			ClientP99MS:     out.Client.Quantile(0.99),
+			ClientP999MS:    out.Client.Quantile(0.999),
+			ClientHistogram: out.Client.Buckets(),
+		}
+		res.Replay = &results.Replay{
+			Trace:    *trace,
+			Events:   len(events),
+			SpanMS:   results.Milliseconds(span),
+			Speed:    *speed,
+			Late:     out.Late,
+			MaxLagMS: results.Milliseconds(out.MaxLag),
+		}
+		run.Results = append(run.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printStats(run, results.MetricWarm, cf)
+	fmt.Println()
+	printReplay(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return context.Cause(ctx)
+}
+
+// printReplay shows how each function served the replayed traffic: how
+// many events it answered, how many it threw back and how many started
+// late, its cold starts and its client latency tail.
+func printReplay(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tEVENTS\tSPEED\tREQUESTS\tERRORS\tTHROTTLED\tLATE\tMAX LAG(ms)\tCOLD\tCLIENT P50(ms)\tP99(ms)\tP99.9(ms)")
+	for _, r := range run.Results {
+		if r.Replay == nil {
+			fmt.Fprintf(w, "%s\t-\terror: %s\n", r.Function, r.Error)
+			continue
+		}
+		var errs, cold int
+		for _, s := range r.Samples {
+			if s.Error != "" {
+				errs++
+			}
+			if s.Cold {
+				cold++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%gx\t%d\t%d\t%d\t%d\t%.0f\t%d\t%.2f\t%.2f\t%.2f\n", r.Function, r.Replay.Events, r.Replay.Speed,
+			len(r.Samples), errs, r.Load.Throttled, r.Replay.Late, r.Replay.MaxLagMS, cold,
+			r.Load.ClientP50MS, r.Load.ClientP99MS, r.Load.ClientP999MS)
+	}
+	w.Flush()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"lambdaperf/pkg/compare"
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/report"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/slo"
+)
+
+func runReport(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench report [flags] [results.json]")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	format := fs.String("format", "md", "output format: md or html")
+	out := fs.String("o", "", "output file (default: stdout)")
+	gate := fs.String("gate", "ruchy", "comma-separated runtimes whose missed SLOs fail the command; empty for none")
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	render := report.Markdown
+	switch *format {
+	case "md", "markdown":
+	case "html":
+		render = report.HTML
+	default:
+		return fmt.Errorf("unknown format %q: want md or html", *format)
+	}
+
+	path := fs.Arg(0)
+	dir, rootErr := findRoot(*root)
+	if path == "" {
+		if rootErr != nil {
+			return rootErr
+		}
+		var err error
+		if path, err = latestResults(filepath.Join(dir, ".bench", "results")); err != nil {
+			return err
+		}
+	}
+	run, err := results.Read(path)
+	if err != nil {
+		return err
+	}
+	run.Summarize(sf.options())
+	// A results file read outside the repository has no manifest to
+	// check it against; it still renders.
+	opts := report.Options{CostOptions: cf.options()}
+	if rootErr == nil {
+		m, err := manifest.Load(dir)
+		if err != nil {
+			return err
+		}
+		opts.SLOs = slo.Evaluate(run, m)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := render(w, run, opts); err != nil {
+		return err
+	}
+	if *out != "" {
+		fmt.Fprintln(os.Stderr, "report written to", *out)
+	}
+	return gateSLOs(opts.SLOs, splitList(*gate))
+}
+
+// gateSLOs fails when any of the runtimes missed an SLO, naming each
+// missed one on stderr.
+func gateSLOs(checks []slo.Check, runtimes []string) error {
+	missed, checked := 0, 0
+	for _, c := range checks {
+		if !slices.Contains(runtimes, c.Result.Runtime) {
+			continue
+		}
+		checked++
+		if !c.Met() {
+			missed++
+			fmt.Fprintf(os.Stderr, "SLO missed: %s: %s\n", compare.Target(c.Result), c.SLO)
+		}
+	}
+	if missed > 0 {
+		return fmt.Errorf("%d of %d SLO checks of %s missed", missed, checked, strings.Join(runtimes, ", "))
+	}
+	return nil
+}
+
+// latestResults returns the newest results file in dir. Run IDs are UTC
+// timestamps, so the lexically greatest name is the most recent run.
+func latestResults(dir string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("no results files in %s; run a benchmark first", dir)
+	}
+	sort.Strings(matches)
+	return matches[len(matches)-1], nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/lambdalog"
+	"lambdaperf/pkg/reportparser"
+)
+
+// invocationReport is a REPORT line joined by request ID with the
+// handler's invocation line, for baselines that log one.
+type invocationReport struct {
+	reportparser.Report
+	Invocation *lambdalog.Entry `json:",omitempty"`
+}
+
+func runReports(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reports", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	since := fs.Duration("since", time.Hour, "how far back to read the log groups")
+	asJSON := fs.Bool("json", false, "print reports as JSON instead of a table")
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	tf.kind = string(discover.KindLambda)
+	_, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	client, err := newLogsClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	end := time.Now()
+	all := map[string][]invocationReport{}
+	for _, t := range targets {
+		fn := t.FunctionName()
+		reports, err := reportparser.Fetch(ctx, client, fn, end.Add(-*since), end)
+		var entries map[string]lambdalog.Entry
+		if err == nil {
+			entries, err = reportparser.FetchInvocations(ctx, client, fn, end.Add(-*since), end)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", fn, err)
+			continue
+		}
+		for _, r := range reports {
+			ir := invocationReport{Report: r}
+			if e, ok := entries[r.RequestID]; ok {
+				ir.Invocation = &e
+			}
+			all[fn] = append(all[fn], ir)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(all)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tTIME\tDURATION(ms)\tBILLED(ms)\tMEMORY(MB)\tMAX USED(MB)\tINIT(ms)\tRESTORE(ms)\tHANDLER(ms)\tGC PAUSE(ms)\tPARAMS")
+	for _, t := range targets {
+		for _, r := range all[t.FunctionName()] {
+			initMS, restoreMS := "-", "-"
+			if r.InitDurationMS > 0 {
+				initMS = fmt.Sprintf("%.2f", r.InitDurationMS)
+			}
+			if r.Restored() {
+				restoreMS = fmt.Sprintf("%.2f", r.RestoreDurationMS)
+			}
+			handlerMS, gcMS, params := "-", "-", "-"
+			if e := r.Invocation; e != nil {
+				handlerMS, params = fmt.Sprintf("%.2f", e.DurationMS), formatParams(e.Params)
+				if e.Go != nil {
+					gcMS = fmt.Sprintf("%.3f", e.Go.GCPauseMS)
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%.2f\t%.0f\t%d\t%d\t%s\t%s\t%s\t%s\t%s\n", t.FunctionName(),
+				r.Timestamp.Format(time.RFC3339), r.DurationMS, r.BilledDurationMS,
+				r.MemorySizeMB, r.MaxMemoryUsedMB, initMS, restoreMS, handlerMS, gcMS, params)
+		}
+	}
+	return w.Flush()
+}
+
+// formatParams renders params as sorted key=value pairs, values in JSON.
+func formatParams(params lambdalog.Params) string {
+	if len(params) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(params))
+	for k, v := range params {
+		// JSON prints decoded float64s without %v's exponent (1e+07).
+		value, _ := json.Marshal(v)
+		pairs = append(pairs, k+"="+string(value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"time"
+
+	"lambdaperf/pkg/invoke"
+)
+
+// retryFlags configure the retries of Lambda invocations, and of the
+// configuration updates around them, that fail transiently: throttled,
+// answered with a 5xx or timed out. Invocations still failing after them
+// are recorded as excluded samples; see results.Sample.Excluded.
+type retryFlags struct {
+	retries int
+	base    time.Duration
+}
+
+func (f *retryFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.retries, "retries", invoke.DefaultBackoff.Attempts-1, "retries of an invocation that was throttled, failed with a 5xx or timed out")
+	fs.DurationVar(&f.base, "retry-backoff", invoke.DefaultBackoff.Base, "most jittered wait before the first retry, doubling with each retry up to 5s")
+}
+
+func (f *retryFlags) validate() error {
+	if f.retries < 0 {
+		return errors.New("-retries must not be negative")
+	}
+	return nil
+}
+
+func (f *retryFlags) backoff() invoke.Backoff {
+	return invoke.Backoff{Attempts: f.retries + 1, Base: f.base, Max: invoke.DefaultBackoff.Max}
+}
+
+// wrap retries inv's invocations.
+func (f *retryFlags) wrap(inv invoke.Invoker) invoke.Invoker {
+	return &invoke.Retry{Invoker: inv, Backoff: f.backoff()}
+}
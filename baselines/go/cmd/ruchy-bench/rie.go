@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/platform"
+	"lambdaperf/pkg/progress"
+	"lambdaperf/pkg/results"
+)
+
+// emulate measures a Lambda target without AWS: it builds the target's
+// container image, deploys it to the Docker platform, which runs it under
+// the Runtime Interface Emulator, and invokes it n times. The container is
+// new, so the first invocation is its cold start.
+func emulate(ctx context.Context, b *build.Builder, t discover.Target, payload []byte, n int, wf *warmupFlags, expected string, res *results.Result) error {
+	t.Package = discover.PackageImage
+	a, err := b.Build(ctx, t)
+	if err != nil {
+		return err
+	}
+	res.BinaryBytes, res.PackageBytes = a.BinaryBytes, a.PackageBytes
+	d := &platform.Docker{Arch: build.GoArch(t.Arch), Env: map[string]string{
+		"AWS_LAMBDA_FUNCTION_NAME":        t.FunctionName(),
+		"AWS_LAMBDA_FUNCTION_MEMORY_SIZE": strconv.Itoa(deploy.DefaultMemoryMB),
+	}}
+	if err := d.Deploy(ctx, a); err != nil {
+		return err
+	}
+	defer d.Destroy(context.WithoutCancel(ctx))
+
+	fmt.Fprintf(os.Stderr, "%s: %d invocations under the emulator\n", t.ID(), n)
+	var steady bool
+	tctx, task := progress.Track(ctx, t.ID(), n)
+	res.Samples, steady = collect(tctx, d, payload, n, wf.warmup(), wf.precision(), expected)
+	task.Finish()
+	wf.report(t.ID(), res, steady)
+	return nil
+}
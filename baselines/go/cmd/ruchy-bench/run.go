@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/canary"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/errorpath"
+	"lambdaperf/pkg/hyperfine"
+	"lambdaperf/pkg/localbench"
+	"lambdaperf/pkg/platform"
+	"lambdaperf/pkg/pool"
+	"lambdaperf/pkg/progress"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+func runRun(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	n := fs.Int("n", 10, "invocations per target")
+	var pf payloadFlags
+	pf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "comma-separated AWS regions to measure lambda targets in, in parallel (default: from AWS config)")
+	verbose := fs.Bool("v", false, "show compiler and build script output")
+	exportJSON := fs.String("export-json", "", "also write local results to this file in hyperfine's JSON format")
+	emulated := fs.Bool("rie", false, "run Lambda targets locally in their container image under the Runtime Interface Emulator instead of on AWS")
+	traced := fs.Bool("tracing", false, "break Lambda invocations down by their X-Ray trace segments (deploy with -tracing first)")
+	telemetry := fs.Bool("telemetry", false, "record Telemetry API phase timings of Lambda invocations (deploy with -telemetry first)")
+	var wf warmupFlags
+	wf.register(fs)
+	var rf retryFlags
+	rf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	var sbf sandboxFlags
+	sbf.register(fs)
+	var par parallelFlags
+	par.register(fs, "Lambda targets to measure")
+	var bf budgetFlags
+	bf.register(fs)
+	var pgf progressFlags
+	pgf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if err := par.validate(); err != nil {
+		return err
+	}
+	if err := sbf.validate(); err != nil {
+		return err
+	}
+	if err := wf.validate(); err != nil {
+		return err
+	}
+	if err := rf.validate(); err != nil {
+		return err
+	}
+	if *emulated && (tf.snapStart || tf.packages != "") {
+		return errors.New("-rie always runs the container image; it does not support -snapstart or -package")
+	}
+	if *emulated && (*traced || *telemetry) {
+		return errors.New("-tracing and -telemetry need functions deployed on AWS; they do not support -rie")
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+
+	expected, err := expectedResults(root)
+	if err != nil {
+		return err
+	}
+
+	mode := runMode(targets)
+	if *emulated && mode == string(discover.KindLambda) {
+		mode = string(discover.KindRIE)
+	}
+	run := results.NewRun(mode, time.Now())
+	ctx, stopProgress := pgf.start(ctx)
+	defer stopProgress()
+	payloads := make([][]byte, len(targets))
+	for i, t := range targets {
+		if payloads[i], err = pf.forTarget(t); err != nil {
+			return err
+		}
+	}
+	var (
+		b = newBuilder(root, "", *verbose)
+		// measured holds each target's results, one per region for
+		// Lambda targets.
+		measured = make([][]results.Result, len(targets))
+		remote   []int
+	)
+	// Local and emulated targets run one at a time, so that they do not
+	// compete for the host's CPUs; Lambda targets then run -parallel at a
+	// time.
+	for i, t := range targets {
+		if t.Kind == discover.KindLambda && !*emulated {
+			remote = append(remote, i)
+			continue
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		res := newResult(t)
+		switch t.Kind {
+		case discover.KindLocal:
+			a, err := b.Build(ctx, t)
+			if err == nil {
+				res.BinaryBytes, res.PackageBytes = a.BinaryBytes, a.PackageBytes
+				r := &localbench.Runner{Command: a.Command, Dir: t.Dir, Stdin: payloads[i], Warmup: wf.warmup(), Precision: wf.precision()}
+				r.Expected, err = localbench.Expected(t.Source)
+				var stop func()
+				if err == nil {
+					stop, err = sbf.start(ctx, b, t, a, r, &res)
+				}
+				if err == nil {
+					fmt.Fprintf(os.Stderr, "%s: %d runs\n", t.ID(), *n)
+					tctx, task := progress.Track(ctx, t.ID(), *n)
+					var steady bool
+					res.Samples, steady = r.Samples(tctx, *n)
+					task.Finish()
+					wf.report(t.ID(), &res, steady)
+					stop()
+				}
+			}
+			if err != nil {
+				res.Error = err.Error()
+			}
+		case discover.KindLambda:
+			res.Kind, res.Function = string(discover.KindRIE), ""
+			if err := emulate(ctx, b, t, payloads[i], *n, &wf, expected[t.Workload], &res); err != nil {
+				res.Error = err.Error()
+			}
+		}
+		measured[i] = []results.Result{res}
+	}
+	if len(remote) > 0 && ctx.Err() == nil {
+		clients, err := newRegionClients(ctx, regionList(*region), *traced, *telemetry)
+		if err != nil {
+			return err
+		}
+		pool.Each(ctx, len(remote), par.workers, func(j int) {
+			i := remote[j]
+			t := targets[i]
+			res := newResult(t)
+			if why := invokedElsewhere(t); why != "" {
+				res.Error = why
+			} else {
+				measured[i] = inEachRegion(clients, func(rc *regionClients) results.Result {
+					res := newResult(t)
+					l := &platform.Lambda{Client: rc.lambda, Function: res.Function, Qualifier: t.Qualifier(), Backoff: rf.backoff()}
+					id := inRegion(t.ID(), rc.region)
+					if _, err := canary.Check(ctx, l, payloads[i], t.Runtime, expected[t.Workload]); err != nil {
+						fmt.Fprintf(os.Stderr, "%s: %v; not measured\n", id, err)
+						res.Error = err.Error()
+						return res
+					}
+					fmt.Fprintf(os.Stderr, "%s: %d invocations\n", id, *n)
+					start := time.Now()
+					tctx, task := progress.Track(ctx, id, *n)
+					var steady bool
+					res.Samples, steady = collect(tctx, l, payloads[i], *n, wf.warmup(), wf.precision(), expected[t.Workload])
+					task.Finish()
+					wf.report(id, &res, steady)
+					rc.attach(ctx, &res, start)
+					return res
+				})
+				return
+			}
+			measured[i] = []results.Result{res}
+		})
+	}
+	for _, m := range measured {
+		run.Results = append(run.Results, m...)
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printStats(run, "", cf)
+	if run.Mode != string(discover.KindLambda) && run.Mode != string(discover.KindRIE) {
+		fmt.Println()
+		printCounters(run)
+	}
+	if *traced {
+		fmt.Println()
+		printTraces(run)
+	}
+	if *telemetry {
+		fmt.Println()
+		printTelemetry(run)
+	}
+	printOverhead(run)
+	printThroughput(run)
+	printHTTP(run)
+	printGoRuntime(run)
+	printGoInit(run)
+	printDecode(run)
+	printIterations(run)
+	printSerializers(run)
+	printExtensionOverhead(run)
+	printVPCOverhead(run)
+	printChaos(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	if *exportJSON != "" {
+		if err := hyperfine.Write(*exportJSON, hyperfine.FromRun(run)); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "hyperfine export written to", *exportJSON)
+	}
+	return context.Cause(ctx)
+}
+
+// invokedElsewhere returns why run does not invoke the Lambda target t
+// itself, naming the command that measures it, or "" when it does.
+func invokedElsewhere(t discover.Target) string {
+	switch {
+	case t.Workload == deploy.StreamWorkload:
+		// Invoke buffers responses up to 6 MB, less than the stream
+		// handler sends.
+		return "streams through its function URL; measure it with ruchy-bench stream"
+	case errorpath.Fails(t.Workload):
+		return "fails by design; measure it with ruchy-bench errors"
+	}
+	return ""
+}
+
+// canaryRuntime is the runtime the canary invoke checks t against, or ""
+// for targets that are invoked elsewhere and so not checked.
+func canaryRuntime(t discover.Target) string {
+	if invokedElsewhere(t) != "" {
+		return ""
+	}
+	return t.Runtime
+}
+
+// collect warms up and then performs n or, to reach p, more sequential
+// invocations of t, recording failures, wrong results included, as
+// samples rather than aborting the target.
+func collect(ctx context.Context, t platform.Target, payload []byte, n int, w stats.Warmup, p stats.Precision, expected string) ([]results.Sample, bool) {
+	return results.Collect(ctx, n, w, p, func(i int) results.Sample {
+		return platform.Sample(ctx, t, payload, i, expected)
+	})
+}
+
+// printCounters shows the peak RSS and hardware counters of local results,
+// as means over the successful runs.
+func printCounters(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUNTIME\tWORKLOAD\tMAX RSS(MB)\tINSTRUCTIONS\tCYCLES\tIPC\tCACHE MISSES\tMISS RATE\tBRANCH MISSES")
+	counted := false
+	for _, r := range run.Results {
+		if r.Kind != string(discover.KindLocal) || r.Stats[results.MetricClient].N == 0 {
+			continue
+		}
+		_, ok := r.Stats[results.MetricInstructions]
+		counted = counted || ok
+		mean := func(metric string) (float64, bool) {
+			s, ok := r.Stats[metric]
+			return s.Mean, ok
+		}
+		count := func(metric string) string {
+			if v, ok := mean(metric); ok {
+				return fmt.Sprintf("%.3g", v)
+			}
+			return "-"
+		}
+		rss, ipc, missRate := "-", "-", "-"
+		if v, ok := mean(results.MetricRSS); ok {
+			rss = fmt.Sprintf("%.1f", v/1024)
+		}
+		ins, ok1 := mean(results.MetricInstructions)
+		cyc, ok2 := mean(results.MetricCycles)
+		if ok1 && ok2 && cyc > 0 {
+			ipc = fmt.Sprintf("%.2f", ins/cyc)
+		}
+		misses, ok1 := mean(results.MetricCacheMisses)
+		refs, ok2 := mean(results.MetricCacheRefs)
+		if ok1 && ok2 && refs > 0 {
+			missRate = fmt.Sprintf("%.1f%%", 100*misses/refs)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Runtime, r.Workload, rss,
+			count(results.MetricInstructions), count(results.MetricCycles), ipc,
+			count(results.MetricCacheMisses), missRate, count(results.MetricBranchMisses))
+	}
+	w.Flush()
+	if !counted {
+		fmt.Fprintln(os.Stderr, "no hardware counters: this machine or kernel does not expose them to perf_event_open")
+	}
+}
+
+func runMode(targets []discover.Target) string {
+	mode := string(targets[0].Kind)
+	for _, t := range targets[1:] {
+		if string(t.Kind) != mode {
+			return "mixed"
+		}
+	}
+	return mode
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/localbench"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/sandbox"
+)
+
+// sandboxFlags puts local targets in a sandbox shaped like a Lambda
+// function (-sandbox).
+type sandboxFlags struct {
+	backend  string
+	memoryMB int
+	// cgroup is the delegated cgroup -sandbox cgroup creates groups in.
+	cgroup string
+	// timer is the timer binary, once built.
+	timer string
+}
+
+func (f *sandboxFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.backend, "sandbox", "", "run local targets in a sandbox capped like a Lambda function: cgroup, container, gvisor or firecracker (see pkg/sandbox)")
+	fs.IntVar(&f.memoryMB, "sandbox-memory", deploy.DefaultMemoryMB, "Lambda memory size in MB the sandbox's memory and vCPU share match")
+	fs.StringVar(&f.cgroup, "sandbox-cgroup", "/sys/fs/cgroup/ruchy-bench", "writable cgroup v2 directory, holding no processes, that -sandbox cgroup creates its groups in")
+}
+
+func (f *sandboxFlags) validate() error {
+	if f.backend == "" {
+		return nil
+	}
+	if _, err := sandbox.Parse(f.backend); err != nil {
+		return err
+	}
+	if f.memoryMB < 128 || f.memoryMB > 10240 {
+		return fmt.Errorf("invalid -sandbox-memory %d: want 128-10240 MB", f.memoryMB)
+	}
+	if runtime.GOOS != "linux" {
+		// Local targets are built for the host, and sandboxes run Linux.
+		return errors.New("-sandbox needs a Linux host")
+	}
+	return nil
+}
+
+// start starts a sandbox for t's artifact a and has r run in it,
+// recording the sandbox on res. The returned function stops it. Without
+// -sandbox it does nothing.
+func (f *sandboxFlags) start(ctx context.Context, b *build.Builder, t discover.Target, a build.Artifact, r *localbench.Runner, res *results.Result) (stop func(), err error) {
+	if f.backend == "" {
+		return func() {}, nil
+	}
+	backend, _ := sandbox.Parse(f.backend)
+	res.Sandbox, res.MemoryMB = f.backend, int32(f.memoryMB)
+	vcpus := sandbox.VCPUs(int32(f.memoryMB))
+	if backend == sandbox.Cgroup {
+		cg, err := localbench.NewCgroup(f.cgroup, vcpus)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "%s: cgroup %s, %s vCPUs\n", t.ID(), cg.Dir(), strconv.FormatFloat(vcpus, 'f', 3, 64))
+		r.Cgroup = cg
+		return func() { cg.Close() }, nil
+	}
+	if f.timer == "" {
+		if f.timer, err = b.BuildTimer(ctx); err != nil {
+			return nil, err
+		}
+	}
+	sb, err := sandbox.Start(ctx, sandbox.Options{
+		Backend:  backend,
+		MemoryMB: int32(f.memoryMB),
+		Image:    sandbox.Image(t.Runtime),
+		Timer:    f.timer,
+		Mounts:   sandbox.Mounts(a.Command, t.Dir),
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s sandbox %s, %d MB and %s vCPUs\n", t.ID(), f.backend, sb.ID[:min(12, len(sb.ID))], f.memoryMB,
+		strconv.FormatFloat(vcpus, 'f', 3, 64))
+	r.Exec = sb.Exec
+	return func() { sb.Stop(context.WithoutCancel(ctx)) }, nil
+}
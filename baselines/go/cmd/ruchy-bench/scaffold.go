@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/scaffold"
+)
+
+func runScaffold(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("scaffold", flag.ContinueOnError)
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	kind := fs.String("kind", string(discover.KindLambda), "scaffold a local or lambda implementation")
+	runtime := fs.String("runtime", "", "runtime to implement the workload in")
+	workload := fs.String("workload", "", "workload to implement, declared in the manifest if it is new")
+	dryRun := fs.Bool("dry-run", false, "print the files that would be created or updated without writing them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *runtime == "" || *workload == "" {
+		return errors.New("-runtime and -workload are required")
+	}
+	k := discover.Kind(*kind)
+	if k != discover.KindLocal && k != discover.KindLambda {
+		return fmt.Errorf("-kind must be local or lambda, not %q", *kind)
+	}
+	var err error
+	if *root, err = findRoot(*root); err != nil {
+		return err
+	}
+	o := scaffold.Options{Kind: k, Runtime: *runtime, Workload: *workload}
+	changes, err := scaffold.Generate(*root, o)
+	if err != nil {
+		return err
+	}
+	if !*dryRun {
+		if err := scaffold.Write(*root, changes); err != nil {
+			return err
+		}
+	}
+	for _, c := range changes {
+		verb := "updated"
+		if c.Created {
+			verb = "created"
+		}
+		fmt.Printf("%s %s\n", verb, c.Path)
+	}
+	if *dryRun {
+		fmt.Println("dry run: nothing was written")
+		return nil
+	}
+	fmt.Printf("Implement the workload, then check it with: make parity-%s-%s\n", *runtime, *workload)
+	return nil
+}
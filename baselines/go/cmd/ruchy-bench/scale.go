@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/platform"
+	"lambdaperf/pkg/results"
+)
+
+func runScale(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("scale", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	input := fs.String("input", "", "workload input and the comma-separated values to sweep it over, e.g. n=25,30,35,40 (required)")
+	n := fs.Int("n", 10, "invocations per input value")
+	var wf warmupFlags
+	wf.register(fs)
+	var rf retryFlags
+	rf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	var bf budgetFlags
+	bf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if err := wf.validate(); err != nil {
+		return err
+	}
+	if err := rf.validate(); err != nil {
+		return err
+	}
+	name, values, err := parseInput(*input)
+	if err != nil {
+		return err
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	m, err := manifest.Load(root)
+	if err != nil {
+		return err
+	}
+
+	// Check every target before invoking any, so a typo does not surface
+	// halfway through a sweep.
+	type scaled struct {
+		t discover.Target
+		w manifest.Workload
+	}
+	var sweep []scaled
+	for _, t := range targets {
+		w, _ := m.Workload(t.Workload)
+		in, ok := w.Inputs[name]
+		switch {
+		case !ok:
+			return fmt.Errorf("workload %s has no input %q", t.Workload, name)
+		case !in.Accepts(t.Runtime):
+			fmt.Fprintf(os.Stderr, "%s: skipped, its %s is fixed at %d\n", t.ID(), name, in.Default)
+			continue
+		}
+		for _, v := range values {
+			if v < in.Min || v > in.Max {
+				return fmt.Errorf("%s %s=%d: outside %d..%d", t.Workload, name, v, in.Min, in.Max)
+			}
+		}
+		sweep = append(sweep, scaled{t, w})
+	}
+	if len(sweep) == 0 {
+		return fmt.Errorf("no selected target reads %s from its payload", name)
+	}
+	client, err := newLambdaClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	run := results.NewRun("scale", time.Now())
+	for _, s := range sweep {
+		fmt.Fprintf(os.Stderr, "%s: %s=%v, %d invocations each\n", s.t.ID(), name, values, *n)
+		res := scaleTarget(ctx, client, s.t, s.w, name, values, *n, &wf, &rf)
+		run.Results = append(run.Results, res...)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printScale(run, name)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	if err := context.Cause(ctx); err != nil {
+		return err
+	}
+	return agree(run)
+}
+
+// scaleTarget benchmarks t at every value of the input. Only the default
+// has an expected result in the manifest; agree compares the others across
+// runtimes.
+func scaleTarget(ctx context.Context, client *lambda.Client, t discover.Target, w manifest.Workload,
+	name string, values []int, n int, wf *warmupFlags, rf *retryFlags) []results.Result {
+	l := &platform.Lambda{Client: client, Function: t.FunctionName(), Qualifier: t.Qualifier(), Backoff: rf.backoff()}
+	var out []results.Result
+	for _, v := range values {
+		expected := ""
+		if v == w.Inputs[name].Default {
+			expected = w.Expected
+		}
+		res := newResult(t)
+		res.Input = map[string]int{name: v}
+		payload := fmt.Appendf(nil, `{%q: %d}`, name, v)
+		var steady bool
+		res.Samples, steady = collect(ctx, l, payload, n, wf.warmup(), wf.precision(), expected)
+		wf.report(fmt.Sprintf("%s %s=%d", t.ID(), name, v), &res, steady)
+		for i, s := range res.Samples {
+			if code := statusCode(s.Response); s.Error == "" && code != 0 && code != 200 {
+				res.Samples[i].Error = fmt.Sprintf("status %d: %s", code, results.Body([]byte(s.Response)))
+			}
+		}
+		out = append(out, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return out
+}
+
+// parseInput parses -input: a name, "=", and comma-separated integers.
+func parseInput(s string) (string, []int, error) {
+	name, list, ok := strings.Cut(s, "=")
+	if !ok || name == "" || list == "" {
+		return "", nil, errors.New("-input is required, as name=v1,v2,...")
+	}
+	var values []int
+	for _, f := range splitList(list) {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return "", nil, fmt.Errorf("-input %s: %q is not an integer", name, f)
+		}
+		values = append(values, v)
+	}
+	slices.Sort(values)
+	return name, slices.Compact(values), nil
+}
+
+// statusCode is the statusCode of an API-style response, zero for
+// responses without one.
+func statusCode(response string) int {
+	var r struct {
+		StatusCode int `json:"statusCode"`
+	}
+	json.Unmarshal([]byte(response), &r)
+	return r.StatusCode
+}
+
+// agree reports results of a workload that disagree at the same input:
+// one runtime computing something else makes the curve meaningless there.
+func agree(run *results.Run) error {
+	type point struct{ workload, input string }
+	seen := map[point]results.Result{}
+	bodies := map[point]string{}
+	var errs []error
+	for _, r := range run.Results {
+		body, ok := firstBody(r)
+		if !ok {
+			continue
+		}
+		p := point{r.Workload, r.InputLabel()}
+		if first, ok := seen[p]; !ok {
+			seen[p], bodies[p] = r, body
+		} else if body != bodies[p] {
+			errs = append(errs, fmt.Errorf("%s %s: %s returned %q, %s %q", r.Workload, p.input,
+				first.Function, truncate(bodies[p], 60), r.Function, truncate(body, 60)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func firstBody(r results.Result) (string, bool) {
+	for _, s := range r.Samples {
+		if s.Error == "" {
+			return results.Body([]byte(s.Response)), true
+		}
+	}
+	return "", false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// printScale shows the warm p50 per function and input value, one table
+// per workload: the scaling curve of each runtime, side by side.
+func printScale(run *results.Run, name string) {
+	printCurve(run, name, strings.ToUpper(name), results.MetricWarm, "warm p50 (ms)", strconv.Itoa)
+}
+
+// printCurve shows the median of metric per function and value of the
+// input name, one table per workload under a heading per title, with
+// the values in the first column, headed column and formatted by label.
+func printCurve(run *results.Run, name, column, metric, title string, label func(int) string) {
+	var workloads, functions []string
+	cell := map[[3]string]string{}
+	var values []int
+	for _, r := range run.Results {
+		if !slices.Contains(workloads, r.Workload) {
+			workloads = append(workloads, r.Workload)
+		}
+		if !slices.Contains(functions, r.Function) {
+			functions = append(functions, r.Function)
+		}
+		v := r.Input[name]
+		if !slices.Contains(values, v) {
+			values = append(values, v)
+		}
+		c := "-"
+		switch s := r.Stats[metric]; {
+		case r.Error != "":
+			c = "error"
+		case s.N > 0:
+			c = fmt.Sprintf("%.2f", s.Median)
+		}
+		cell[[3]string{r.Workload, r.Function, strconv.Itoa(v)}] = c
+	}
+	slices.Sort(values)
+	for i, wl := range workloads {
+		if i > 0 {
+			fmt.Println()
+		}
+		var cols []string
+		for _, f := range functions {
+			for _, v := range values {
+				if _, ok := cell[[3]string{wl, f, strconv.Itoa(v)}]; ok {
+					cols = append(cols, f)
+					break
+				}
+			}
+		}
+		fmt.Printf("%s, %s:\n", wl, title)
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, column+"\t"+strings.Join(cols, "\t"))
+		for _, v := range values {
+			row := []string{label(v)}
+			for _, f := range cols {
+				c, ok := cell[[3]string{wl, f, strconv.Itoa(v)}]
+				if !ok {
+					c = "-"
+				}
+				row = append(row, c)
+			}
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		w.Flush()
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	"lambdaperf/pkg/async"
+	"lambdaperf/pkg/configload"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/fixture"
+	"lambdaperf/pkg/mockapi"
+	"lambdaperf/pkg/queue"
+)
+
+func runSeed(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	workloads := fs.String("workload", fixture.S3Workload+","+fixture.DynamoDBWorkload+","+queue.Workload+","+async.Workload+","+mockapi.Workload+","+configload.Workload, "comma-separated workloads to seed")
+	bucket := fs.String("bucket", "", "s3 fixture bucket, also holding the async workload's markers (default: ruchy-bench-<account>-<region>, created if missing)")
+	size := fs.Int("size", fixture.DefaultSize, "s3 fixture size in bytes")
+	role := fs.String("role", deploy.DefaultRoleName, "execution role to grant access to the fixtures (\"none\" skips)")
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *size < 1 {
+		return fmt.Errorf("invalid fixture size %d", *size)
+	}
+	dir, err := findRoot(*root)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	iamClient := iam.NewFromConfig(cfg)
+	if *role != "none" {
+		if _, err := deploy.EnsureRole(ctx, iamClient, *role); err != nil {
+			return err
+		}
+	}
+
+	for _, w := range splitList(*workloads) {
+		switch w {
+		case fixture.S3Workload:
+			err = seedS3(ctx, cfg, dir, *bucket, *size, *role, iamClient)
+		case fixture.DynamoDBWorkload:
+			err = seedTable(ctx, cfg, fixture.DefaultTable, *role, iamClient)
+		case queue.Workload:
+			err = seedQueue(ctx, cfg, queue.DefaultQueue, *role, iamClient)
+		case async.Workload:
+			err = seedAsync(ctx, cfg, *bucket, async.DefaultDLQ, *role, iamClient)
+		case mockapi.Workload:
+			err = seedMockAPI(ctx, cfg, dir, mockapi.DefaultAPI)
+		case configload.Workload, configload.ExtensionWorkload:
+			err = seedConfig(ctx, cfg, *role, iamClient)
+		default:
+			err = fmt.Errorf("workload %q has no fixture to seed", w)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedS3 uploads the S3 fixture and writes the event pointing at it.
+func seedS3(ctx context.Context, cfg aws.Config, root, bucket string, size int, role string, grants deploy.RolePolicyAPI) error {
+	bucket, err := fixtureBucket(ctx, cfg, bucket)
+	if err != nil {
+		return err
+	}
+	obj, uploaded, err := fixture.Seed(ctx, s3.NewFromConfig(cfg), bucket, cfg.Region, size)
+	if err != nil {
+		return err
+	}
+	state := "up to date"
+	if uploaded {
+		state = "uploaded"
+	}
+	fmt.Printf("s3://%s/%s %s (%d bytes, sha256 %s)\n", obj.Bucket, obj.Key, state, obj.Size, obj.Digest)
+
+	if role != "none" {
+		if err := deploy.GrantBucketRead(ctx, grants, role, obj.Bucket); err != nil {
+			return err
+		}
+		fmt.Printf("%s can read s3://%s\n", role, obj.Bucket)
+	}
+
+	return writeEvent(root, fixture.S3Workload, fixture.Event(obj, cfg.Region, time.Now()))
+}
+
+// fixtureBucket returns bucket, or when it is empty the account's
+// default fixture bucket in cfg's region.
+func fixtureBucket(ctx context.Context, cfg aws.Config, bucket string) (string, error) {
+	if bucket != "" {
+		return bucket, nil
+	}
+	id, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("look up account: %w", err)
+	}
+	return fmt.Sprintf("ruchy-bench-%s-%s", aws.ToString(id.Account), cfg.Region), nil
+}
+
+// writeEvent writes the event invoking workload against the fixtures
+// seeded in the account, where discover picks it up.
+func writeEvent(root, workload string, event any) error {
+	data, err := json.MarshalIndent(event, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(root, filepath.FromSlash(discover.GeneratedEventsDir), workload+".json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	fmt.Println("event written to", path)
+	return nil
+}
+
+// seedTable provisions and fills the dynamodb workload's table.
+func seedTable(ctx context.Context, cfg aws.Config, table, role string, grants deploy.RolePolicyAPI) error {
+	arn, created, err := fixture.SeedTable(ctx, dynamodb.NewFromConfig(cfg), table)
+	if err != nil {
+		return err
+	}
+	state := "existing"
+	if created {
+		state = "created"
+	}
+	fmt.Printf("dynamodb table %s %s, %d items written\n", table, state, fixture.TableItems)
+	if role != "none" {
+		if err := deploy.GrantTableAccess(ctx, grants, role, arn); err != nil {
+			return err
+		}
+		fmt.Printf("%s can read and write %s\n", role, table)
+	}
+	return nil
+}
+
+// seedQueue creates the sqs workload's queue. Lambda requires a queue's
+// visibility timeout to be at least the timeout of the functions it
+// triggers, so the queue's is the deployed functions' timeout; it is also
+// how long a failed message waits before being redelivered.
+func seedQueue(ctx context.Context, cfg aws.Config, name, role string, grants deploy.RolePolicyAPI) error {
+	c := &queue.Client{Config: cfg}
+	u, err := c.Create(ctx, name, deploy.DefaultTimeoutSec*time.Second)
+	if err != nil {
+		return err
+	}
+	arn, err := c.ARN(ctx, u)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("sqs queue %s ready\n", u)
+	if role != "none" {
+		if err := deploy.GrantQueueConsume(ctx, grants, role, arn); err != nil {
+			return err
+		}
+		fmt.Printf("%s can consume %s\n", role, name)
+	}
+	return nil
+}
+
+// seedAsync creates the async workload's dead-letter queue, and the
+// fixture bucket its markers go to unless it exists. The queue's
+// visibility timeout only matters to the harness, which deletes each
+// record it reads.
+func seedAsync(ctx context.Context, cfg aws.Config, bucket, dlq, role string, grants deploy.RolePolicyAPI) error {
+	bucket, err := fixtureBucket(ctx, cfg, bucket)
+	if err != nil {
+		return err
+	}
+	if err := fixture.EnsureBucket(ctx, s3.NewFromConfig(cfg), bucket, cfg.Region); err != nil {
+		return err
+	}
+	c := &queue.Client{Config: cfg}
+	u, err := c.Create(ctx, dlq, 30*time.Second)
+	if err != nil {
+		return err
+	}
+	arn, err := c.ARN(ctx, u)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("s3://%s ready for markers, dead-letter queue %s ready\n", bucket, u)
+	if role != "none" {
+		if err := deploy.GrantAsync(ctx, grants, role, bucket, async.MarkerPrefix, arn); err != nil {
+			return err
+		}
+		fmt.Printf("%s can write markers and dead-letter to %s\n", role, dlq)
+	}
+	return nil
+}
+
+// seedMockAPI stands up the httpclient workload's mock endpoint and
+// writes the event pointing the handler at it. The handler's requests
+// are unsigned, so the execution role needs no grant.
+func seedMockAPI(ctx context.Context, cfg aws.Config, root, name string) error {
+	u, created, err := (&mockapi.Client{Config: cfg}).Ensure(ctx, name)
+	if err != nil {
+		return err
+	}
+	state := "existing"
+	if created {
+		state = "created"
+	}
+	fmt.Printf("mock api %s %s at %s\n", name, state, u)
+	return writeEvent(root, mockapi.Workload, mockapi.Event{URL: u})
+}
+
+// seedConfig writes the secrets and parameters the configload workloads
+// load, both of which read the same ones.
+func seedConfig(ctx context.Context, cfg aws.Config, role string, grants deploy.RolePolicyAPI) error {
+	if err := configload.Seed(ctx, &configload.Client{Config: cfg}); err != nil {
+		return err
+	}
+	fmt.Printf("%d secrets and %d parameters written under %s\n", configload.Secrets, configload.Parameters, configload.Prefix)
+	if role != "none" {
+		if err := deploy.GrantConfigRead(ctx, grants, role, configload.Prefix); err != nil {
+			return err
+		}
+		fmt.Printf("%s can read them\n", role)
+	}
+	return nil
+}
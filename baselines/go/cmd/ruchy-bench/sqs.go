@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/queue"
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// sqsPoll is the pause between reads of a consumer's log group. Lines
+// take a few seconds to become searchable anyway.
+const sqsPoll = 5 * time.Second
+
+func runSQS(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sqs", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	n := fs.Int("messages", 100, "messages to send per target")
+	batch := fs.Int("batch", queue.MaxBatch, "largest batch the event source mapping delivers, 1 to 10")
+	fail := fs.Float64("fail", 0, "fraction of messages the handler fails on first delivery, so SQS redelivers them")
+	size := fs.Int("size", 1024, "bytes of data per message for the handler to hash")
+	name := fs.String("queue", queue.DefaultQueue, "queue to send through, created by ruchy-bench seed")
+	timeout := fs.Duration("timeout", 5*time.Minute, "how long to wait for every message of a target to be processed")
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return errors.New("-messages must be at least 1")
+	}
+	if *batch < 1 || *batch > queue.MaxBatch {
+		return fmt.Errorf("-batch must be between 1 and %d", queue.MaxBatch)
+	}
+	if *fail < 0 || *fail > 1 {
+		return errors.New("-fail must be between 0 and 1")
+	}
+	if *size < 0 {
+		return errors.New("-size must not be negative")
+	}
+	if tf.snapStart {
+		return errors.New("event source mappings here invoke $LATEST; sqs does not support -snapstart")
+	}
+	tf.kind = string(discover.KindLambda)
+	if tf.workloads == "" {
+		tf.workloads = queue.Workload
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	qc := &queue.Client{Config: cfg}
+	queueURL, err := qc.URL(ctx, *name)
+	if err != nil {
+		return fmt.Errorf("%w (create it with ruchy-bench seed -workload sqs)", err)
+	}
+	queueARN, err := qc.ARN(ctx, queueURL)
+	if err != nil {
+		return err
+	}
+	client := lambda.NewFromConfig(cfg)
+	logs := cloudwatchlogs.NewFromConfig(cfg)
+
+	run := results.NewRun("sqs", time.Now())
+	for _, t := range targets {
+		res := newResult(t)
+		res.Input = map[string]int{"messages": *n, "batch": *batch, "size": *size}
+		fmt.Fprintf(os.Stderr, "%s: subscribing to %s\n", t.ID(), *name)
+		err := deploy.Subscribe(ctx, client, res.Function, queueARN, int32(*batch))
+		if err == nil {
+			fmt.Fprintf(os.Stderr, "%s: %d messages\n", t.ID(), *n)
+			res.Samples, err = sqsSamples(ctx, qc, logs, queueURL, res.Function, queue.Messages(run.ID, *n, *size, *fail), *timeout)
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		run.Results = append(run.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printSQS(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return ctx.Err()
+}
+
+// sqsSamples sends msgs and follows them through fn's log group until
+// each has been processed or timeout has passed, returning a sample per
+// message. A sample's client_ms is the message's end-to-end latency and
+// its REPORT metrics are those of the invocation that processed it, which
+// it shares with the rest of its batch.
+func sqsSamples(ctx context.Context, qc *queue.Client, logs cloudwatchlogs.FilterLogEventsAPIClient, queueURL, fn string, msgs []queue.Message, timeout time.Duration) ([]results.Sample, error) {
+	// The log search starts a little early to allow for clock skew.
+	start := time.Now().Add(-time.Minute)
+	var ids []string
+	for i := 0; i < len(msgs); i += queue.MaxBatch {
+		var bodies []string
+		for _, m := range msgs[i:min(i+queue.MaxBatch, len(msgs))] {
+			bodies = append(bodies, m.Body())
+		}
+		sent, err := qc.SendBatch(ctx, queueURL, bodies)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, sent...)
+	}
+
+	tr := queue.NewTracker(ids)
+	reports := map[string]reportparser.Report{}
+	deadline := time.Now().Add(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(sqsPoll):
+		}
+		now := time.Now()
+		entries, err := reportparser.FetchInvocations(ctx, logs, fn, start, now)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			tr.Add(e)
+		}
+		rs, err := reportparser.Fetch(ctx, logs, fn, start, now)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rs {
+			reports[r.RequestID] = r
+		}
+		pending := tr.Pending()
+		if (pending == 0 && reported(tr, reports)) || now.After(deadline) {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d of %d messages pending\n", fn, pending, len(msgs))
+	}
+
+	samples := make([]results.Sample, len(msgs))
+	for i := range samples {
+		s := results.Sample{Iteration: i, Deliveries: len(tr.Deliveries[i])}
+		d, ok := tr.Processed(i)
+		if !ok {
+			s.Error = fmt.Sprintf("not processed within %s after %d deliveries", timeout, s.Deliveries)
+		} else {
+			s.ClientMS, s.RequestID = d.AgeMS, d.RequestID
+			if r, ok := reports[d.RequestID]; ok {
+				s = s.WithReport(r)
+			}
+		}
+		samples[i] = s
+	}
+	return samples, nil
+}
+
+// reported reports whether the REPORT line of every invocation that
+// processed a message has been read.
+func reported(tr *queue.Tracker, reports map[string]reportparser.Report) bool {
+	for i := range tr.Deliveries {
+		if d, ok := tr.Processed(i); ok {
+			if _, ok := reports[d.RequestID]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// printSQS shows end-to-end latency per function, overall and of the
+// messages delivered once, with how many were redelivered and how full
+// the batches that processed them were.
+func printSQS(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tOK\tE2E P50(ms)\tE2E P95(ms)\tFIRST-TRY P95(ms)\tREDELIVERED\tINVOCATIONS\tMSGS/BATCH")
+	for _, r := range run.Results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\terror: %s\n", r.Function, r.Error)
+			continue
+		}
+		e2e := r.Stats[results.MetricClient]
+		if e2e.N == 0 {
+			fmt.Fprintf(w, "%s\t0/%d\n", r.Function, len(r.Samples))
+			continue
+		}
+		var firstTry []float64
+		redelivered := 0
+		invocations := map[string]bool{}
+		for _, s := range r.Samples {
+			if s.Error != "" {
+				continue
+			}
+			invocations[s.RequestID] = true
+			if s.Deliveries > 1 {
+				redelivered++
+			} else {
+				firstTry = append(firstTry, s.ClientMS)
+			}
+		}
+		first := "-"
+		if len(firstTry) > 0 {
+			slices.Sort(firstTry)
+			first = fmt.Sprintf("%.1f", stats.Percentile(firstTry, 95))
+		}
+		fmt.Fprintf(w, "%s\t%d/%d\t%.1f\t%.1f\t%s\t%d\t%d\t%.1f\n", r.Function, e2e.N, len(r.Samples), e2e.Median, e2e.P95,
+			first, redelivered, len(invocations), float64(e2e.N)/float64(len(invocations)))
+	}
+	w.Flush()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stepfn"
+)
+
+func runStepFunctions(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("stepfunctions", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	steps := fs.Int("steps", stepfn.DefaultSteps, "invocations each state machine chains")
+	n := fs.Int("n", 10, "executions per target")
+	var pf payloadFlags
+	pf.register(fs)
+	var wf warmupFlags
+	wf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if *steps < 1 {
+		return errors.New("-steps must be at least 1")
+	}
+	if err := wf.validate(); err != nil {
+		return err
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	expected, err := expectedResults(root)
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	roleARN, err := deploy.EnsureStatesRole(ctx, iam.NewFromConfig(cfg), stepfn.RoleName, discover.FunctionPrefixes)
+	if err != nil {
+		return err
+	}
+	sc := &stepfn.Client{Config: cfg}
+
+	run := results.NewRun("stepfunctions", time.Now())
+	for _, t := range targets {
+		res := newResult(t)
+		res.Input = map[string]int{"steps": *steps}
+		if why := invokedElsewhere(t); why != "" {
+			res.Error = why
+			run.Results = append(run.Results, res)
+			continue
+		}
+		arn, input, err := stateMachine(ctx, sc, t, res.Function, roleARN, *steps, &pf)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %d executions of %d steps\n", t.ID(), *n, *steps)
+			var steady bool
+			res.Samples, steady = results.Collect(ctx, *n, wf.warmup(), wf.precision(), func(i int) results.Sample {
+				return executionSample(ctx, sc, arn, input, i, *steps, expected[t.Workload])
+			})
+			wf.report(t.ID(), &res, steady)
+		}
+		run.Results = append(run.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printStepFunctions(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return ctx.Err()
+}
+
+// stateMachine creates or updates the state machine chaining steps
+// invocations of fn, the function of t, returning its ARN and the
+// execution input: t's payload, which must be a JSON object.
+func stateMachine(ctx context.Context, sc *stepfn.Client, t discover.Target, fn, roleARN string, steps int, pf *payloadFlags) (string, []byte, error) {
+	input, err := pf.forTarget(t)
+	if err != nil {
+		return "", nil, err
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(input, &obj); err != nil {
+		return "", nil, fmt.Errorf("execution input must be a JSON object: %w", err)
+	}
+	function := fn
+	if q := t.Qualifier(); q != "" {
+		function += ":" + q
+	}
+	def, err := stepfn.Definition(function, steps)
+	if err != nil {
+		return "", nil, err
+	}
+	arn, err := sc.Ensure(ctx, stepfn.Name(fn), def, roleARN)
+	return arn, input, err
+}
+
+// executionSample runs execution i synchronously. Its client_ms is the
+// StartSyncExecution round trip; the breakdown of the execution's own
+// duration goes into the sample's orchestration metrics, and the last
+// step's response is verified against expected.
+func executionSample(ctx context.Context, sc *stepfn.Client, arn string, input []byte, i, steps int, expected string) results.Sample {
+	start := time.Now()
+	e, err := sc.StartSync(ctx, arn, input)
+	s := results.Sample{Iteration: i, ClientMS: results.Milliseconds(time.Since(start))}
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	b, err := stepfn.Analyze(e, steps)
+	if err != nil {
+		s.Error = err.Error()
+		return s
+	}
+	s.Cold = b.Cold > 0
+	return s.WithExecution(b).Verify(expected)
+}
+
+// printStepFunctions shows per function how long executions took, how
+// much of that the steps spent in the function, and what is left per
+// transition: the orchestration overhead each extra state adds.
+func printStepFunctions(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tOK\tSTEPS\tEXEC P50(ms)\tEXEC P95(ms)\tIN FUNCTION P50(ms)\tPER STEP P50(ms)\tPER STEP P95(ms)\tCLIENT P50(ms)")
+	for _, r := range run.Results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\terror: %s\n", r.Function, r.Error)
+			continue
+		}
+		exec, transition := r.Stats[results.MetricSFNExecution], r.Stats[results.MetricSFNTransition]
+		if exec.N == 0 {
+			fmt.Fprintf(w, "%s\t0/%d\n", r.Function, len(r.Samples))
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d/%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\n", r.Function, exec.N, len(r.Samples), r.Input["steps"],
+			exec.Median, exec.P95, r.Stats[results.MetricSFNSteps].Median, transition.Median, transition.P95, r.Stats[results.MetricClient].Median)
+	}
+	w.Flush()
+	warnWrongResults(run)
+}
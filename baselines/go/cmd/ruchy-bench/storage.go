@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/sweep"
+)
+
+// storageInput is the Result.Input entry a storage result records its
+// ephemeral storage size in, next to the workload's own mb.
+const storageInput = "ephemeral_mb"
+
+func runStorage(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("storage", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	sizes := fs.String("sizes", "", "comma-separated ephemeral storage sizes in MB, 512-10240 (default: those of 512,1024,2048,4096,10240 larger than -mb)")
+	mb := fs.Int("mb", 512, "MB the workload writes to /tmp and reads back")
+	n := fs.Int("n", 5, "warm invocations per storage size")
+	var wf warmupFlags
+	wf.register(fs)
+	var rf retryFlags
+	rf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	var bf budgetFlags
+	bf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if *mb < 1 || *mb > 10240 {
+		return errors.New("-mb must be between 1 and 10240")
+	}
+	if err := wf.validate(); err != nil {
+		return err
+	}
+	if err := rf.validate(); err != nil {
+		return err
+	}
+	list, err := parseStorageSizes(*sizes, *mb)
+	if err != nil {
+		return err
+	}
+	tf.kind = string(discover.KindLambda)
+	if tf.workloads == "" {
+		tf.workloads = deploy.TmpIOWorkload
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if t.Workload != deploy.TmpIOWorkload {
+			return fmt.Errorf("%s: storage only invokes the %s workload", t.ID(), deploy.TmpIOWorkload)
+		}
+	}
+	if tf.snapStart {
+		// As with sweep, configuration changes only reach $LATEST.
+		return errors.New("storage does not support -snapstart")
+	}
+	client, err := newLambdaClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(map[string]int{"mb": *mb})
+	if err != nil {
+		return err
+	}
+
+	run := results.NewRun("storage", time.Now())
+	for _, t := range targets {
+		fmt.Fprintf(os.Stderr, "%s: %d MB through %v MB of ephemeral storage\n", t.FunctionName(), *mb, list)
+		r := &sweep.Runner{
+			Client:       client,
+			FunctionName: t.FunctionName(),
+			Sizes:        list,
+			Ephemeral:    true,
+			Invocations:  *n,
+			Warmup:       wf.warmup(),
+			Precision:    wf.precision(),
+			Payload:      payload,
+			Expected:     tmpioResult(*mb),
+			Runtime:      canaryRuntime(t),
+			Backoff:      rf.backoff(),
+		}
+		points, err := r.Run(ctx)
+		for _, p := range points {
+			res := newResult(t)
+			res.MemoryMB = p.MemoryMB
+			res.Input = map[string]int{"mb": *mb, storageInput: int(p.EphemeralMB)}
+			res.Samples = p.Samples
+			wf.check(fmt.Sprintf("%s at %d MB of storage", t.FunctionName(), p.EphemeralMB), &res)
+			run.Results = append(run.Results, res)
+		}
+		if err != nil {
+			res := newResult(t)
+			res.Error = err.Error()
+			run.Results = append(run.Results, res)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printStorage(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return context.Cause(ctx)
+}
+
+// tmpioResult is the result the tmpio workload reports for mb MB: the
+// number of 4 MB chunks, the last of them possibly partial.
+func tmpioResult(mb int) string {
+	return fmt.Sprintf("tmpio(%d)=%d chunks", mb, (mb+3)/4)
+}
+
+// parseStorageSizes parses -sizes. Every size must be larger than the mb
+// MB file, which /tmp could not hold alongside anything else; the
+// defaults are the sizes that are.
+func parseStorageSizes(s string, mb int) ([]int32, error) {
+	list := splitList(s)
+	if len(list) == 0 {
+		var sizes []int32
+		for _, size := range sweep.DefaultEphemeralSizes {
+			if int(size) > mb {
+				sizes = append(sizes, size)
+			}
+		}
+		if len(sizes) == 0 {
+			return nil, fmt.Errorf("no ephemeral storage size holds %d MB", mb)
+		}
+		return sizes, nil
+	}
+	var sizes []int32
+	for _, v := range list {
+		size, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || size < 512 || size > 10240 {
+			return nil, fmt.Errorf("invalid ephemeral storage size %q: want 512-10240 MB", v)
+		}
+		if int(size) <= mb {
+			return nil, fmt.Errorf("ephemeral storage of %d MB cannot hold the %d MB file; lower -mb", size, mb)
+		}
+		sizes = append(sizes, int32(size))
+	}
+	slices.Sort(sizes)
+	return slices.Compact(sizes), nil
+}
+
+// printStorage shows each function's median write and read throughput
+// against its ephemeral storage size.
+func printStorage(run *results.Run) {
+	label := func(mb int) string { return fmt.Sprintf("%d MB", mb) }
+	printCurve(run, storageInput, "STORAGE", results.MetricWriteThroughput, "write p50 (MB/s)", label)
+	fmt.Println()
+	printCurve(run, storageInput, "STORAGE", results.MetricReadThroughput, "read p50 (MB/s)", label)
+}
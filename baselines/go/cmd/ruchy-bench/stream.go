@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/results"
+)
+
+func runStream(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("stream", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	n := fs.Int("n", 10, "requests per target")
+	size := fs.Int("bytes", 10<<20, "response body size to request, in bytes")
+	chunk := fs.Int("chunk", 64<<10, "size of each write the handler makes, in bytes")
+	var wf warmupFlags
+	wf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if *size < 1 || *chunk < 1 {
+		return errors.New("-bytes and -chunk must be positive")
+	}
+	if err := wf.validate(); err != nil {
+		return err
+	}
+	if tf.snapStart {
+		return errors.New("function URLs address $LATEST; stream does not support -snapstart")
+	}
+	tf.kind = string(discover.KindLambda)
+	if tf.workloads == "" {
+		tf.workloads = deploy.StreamWorkload
+	}
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	client := lambda.NewFromConfig(cfg)
+	query := url.Values{"bytes": {strconv.Itoa(*size)}, "chunk": {strconv.Itoa(*chunk)}}.Encode()
+
+	run := results.NewRun("stream", time.Now())
+	for _, t := range targets {
+		res := newResult(t)
+		res.Input = map[string]int{"bytes": *size, "chunk": *chunk}
+		u, err := deploy.URL(ctx, client, res.Function)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			inv := &invoke.FunctionURL{URL: u + "?" + query, Credentials: cfg.Credentials, Region: cfg.Region}
+			fmt.Fprintf(os.Stderr, "%s: %d requests for %d bytes\n", t.ID(), *n, *size)
+			var steady bool
+			res.Samples, steady = results.Collect(ctx, *n, wf.warmup(), wf.precision(), func(i int) results.Sample {
+				return streamSample(ctx, inv, i, int64(*size))
+			})
+			wf.report(t.ID(), &res, steady)
+		}
+		run.Results = append(run.Results, res)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printStream(run)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return ctx.Err()
+}
+
+// streamSample makes request i. A body of any other length than the one
+// requested is a wrong result: the transfer was cut short or the handler
+// ignored the request.
+func streamSample(ctx context.Context, inv *invoke.FunctionURL, i int, want int64) results.Sample {
+	resp, err := inv.Invoke(ctx, nil)
+	s := results.Sample{
+		Iteration: i,
+		ClientMS:  results.Milliseconds(resp.Elapsed),
+		TTFBMS:    results.Milliseconds(resp.FirstByte),
+	}
+	switch {
+	case err != nil:
+		s.Error = err.Error()
+	case resp.Bytes != want:
+		s.Error = fmt.Sprintf("%s: %d bytes, want %d", results.WrongResult, resp.Bytes, want)
+	}
+	return s
+}
+
+// printStream shows time to first byte and total transfer time per
+// function, with the throughput the median transfer achieved. A runtime
+// that buffers the body before sending any of it has a TTFB close to its
+// total.
+func printStream(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tOK\tTTFB P50(ms)\tTTFB P95(ms)\tTOTAL P50(ms)\tTOTAL P95(ms)\tTTFB SHARE\tMB/s")
+	for _, r := range run.Results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\terror: %s\n", r.Function, r.Error)
+			continue
+		}
+		ttfb, total := r.Stats[results.MetricTTFB], r.Stats[results.MetricClient]
+		if total.N == 0 {
+			fmt.Fprintf(w, "%s\t0/%d\n", r.Function, len(r.Samples))
+			continue
+		}
+		mbps := float64(r.Input["bytes"]) / (1 << 20) / (total.Median / 1000)
+		fmt.Fprintf(w, "%s\t%d/%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.0f%%\t%.1f\n", r.Function, total.N, len(r.Samples),
+			ttfb.Median, ttfb.P95, total.Median, total.P95, 100*ttfb.Median/total.Median, mbps)
+	}
+	w.Flush()
+	warnWrongResults(run)
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/report"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// statsFlags controls how samples are summarized.
+type statsFlags struct {
+	// outliers is the -outliers policy; -reject-outliers is mad.
+	outliers     string
+	madThreshold float64
+	iqrFactor    float64
+	trim         float64
+	// stratify summarizes bimodal latency metrics by their larger mode.
+	stratify bool
+}
+
+// outlierPolicies are the values of -outliers.
+var outlierPolicies = []string{"none", "mad", "iqr", "trim"}
+
+func (f *statsFlags) register(fs *flag.FlagSet) {
+	fs.Func("outliers", "outlier policy applied before summarizing: none, mad (modified z-score), iqr (Tukey's fences) or trim (drop each end)", func(v string) error {
+		if !slices.Contains(outlierPolicies, v) {
+			return fmt.Errorf("unknown policy %q: want %s", v, strings.Join(outlierPolicies, ", "))
+		}
+		f.outliers = v
+		return nil
+	})
+	fs.BoolFunc("reject-outliers", "drop MAD-based outliers before summarizing (-outliers mad)", func(v string) error {
+		reject, err := strconv.ParseBool(v)
+		if reject {
+			f.outliers = "mad"
+		}
+		return err
+	})
+	fs.Float64Var(&f.madThreshold, "mad-threshold", stats.DefaultMADThreshold, "modified z-score cut-off for -outliers mad")
+	f.iqrFactor, f.trim = stats.DefaultIQRFactor, 0.05
+	fs.Func("iqr-factor", "interquartile ranges beyond the quartiles that -outliers iqr drops values at (default 1.5)", func(v string) error {
+		return parseFloatIn(v, &f.iqrFactor, 0, math.Inf(1))
+	})
+	fs.Func("trim", "fraction of the values -outliers trim drops from each end, below 0.5 (default 0.05)", func(v string) error {
+		return parseFloatIn(v, &f.trim, 0, 0.5)
+	})
+	fs.BoolVar(&f.stratify, "stratify", false, "summarize latency the dip test finds bimodal by its larger mode alone, setting the other aside as platform variance")
+}
+
+// parseFloatIn parses v into x, which must be above lo and below hi.
+func parseFloatIn(v string, x *float64, lo, hi float64) error {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return err
+	}
+	if f <= lo || f >= hi {
+		return fmt.Errorf("%v is out of range", f)
+	}
+	*x = f
+	return nil
+}
+
+func (f *statsFlags) options() stats.Options {
+	opts := stats.Options{Stratify: f.stratify}
+	switch f.outliers {
+	case "mad":
+		opts.RejectOutliers, opts.MADThreshold = true, f.madThreshold
+	case "iqr":
+		opts.IQRFactor = f.iqrFactor
+	case "trim":
+		opts.Trim = f.trim
+	}
+	return opts
+}
+
+// headlineMetric picks the metric a result is reported by: preferred when
+// present, otherwise the server-side duration, otherwise client time.
+// SnapStart results have a restore rather than an init duration, which
+// stands in for init when that is preferred.
+func headlineMetric(r results.Result, preferred string) string {
+	candidates := []string{preferred, results.MetricDuration}
+	if preferred == results.MetricInit {
+		candidates = []string{results.MetricInit, results.MetricRestore, results.MetricDuration}
+	}
+	for _, m := range candidates {
+		if _, ok := r.Stats[m]; ok && m != "" {
+			return m
+		}
+	}
+	return results.MetricClient
+}
+
+// runtimeLabel marks runtimes measured from a container image, under
+// SnapStart or with the extension attached, the region of results that
+// name one and the CPU generation of results split by it.
+func runtimeLabel(r results.Result) string {
+	runtime := r.Runtime
+	if r.Package == discover.PackageImage {
+		runtime += "+image"
+	}
+	if r.SnapStart {
+		runtime += "+snapstart"
+	}
+	if r.Extension {
+		runtime += "+ext"
+	}
+	if r.VPC {
+		runtime += "+vpc"
+	}
+	if r.Chaos {
+		runtime += "+chaos"
+	}
+	if r.Serializer != "" {
+		runtime += "+" + r.Serializer
+	}
+	if r.Edge {
+		runtime += "+edge"
+	}
+	if r.Sandbox != "" {
+		runtime += "+" + r.Sandbox
+	}
+	if r.Region != "" {
+		runtime += "@" + r.Region
+	}
+	if r.CPUGeneration != "" {
+		runtime += "[" + r.CPUGeneration + "]"
+	}
+	return runtime
+}
+
+// printStats writes one row per result using its summarized Stats, with
+// the mean handler-reported SDK time where there is one, the highest max
+// memory used Lambda reported and the estimated cost of a million
+// invocations.
+func printStats(run *results.Run, preferred string, cf costFlags) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRUNTIME\tWORKLOAD\tARCH\tOK\tMETRIC\tMEAN\tMEDIAN\tP95\tP99\tSTDDEV\tMIN\tMAX\tCI95\tSDK(ms)\tMAX MEM(MB)\tUSD/1M")
+	for _, r := range run.Results {
+		arch := r.Arch
+		if arch == "" {
+			arch = "-"
+		}
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t-\terror: %s\n", r.Kind, runtimeLabel(r), r.Workload, arch, r.Error)
+			continue
+		}
+		metric := headlineMetric(r, preferred)
+		s, ok := r.Stats[metric]
+		if !ok {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t0/%d\t%s\n", r.Kind, runtimeLabel(r), r.Workload, arch, len(r.Samples), metric)
+			continue
+		}
+		sdk, mem := "-", "-"
+		if st, ok := r.Stats[results.MetricSDK]; ok {
+			sdk = fmt.Sprintf("%.2f", st.Mean)
+		}
+		if st, ok := r.Stats[results.MetricMaxMemory]; ok {
+			mem = fmt.Sprintf("%.0f", st.Max)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d/%d\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t[%.2f, %.2f]\t%s\t%s\t%s\n",
+			r.Kind, runtimeLabel(r), r.Workload, arch, s.N, len(r.Samples), metric,
+			s.Mean, s.Median, s.P95, s.P99, s.StdDev, s.Min, s.Max, s.CILow, s.CIHigh, sdk, mem, cf.perMillion(r))
+	}
+	w.Flush()
+	warnWrongResults(run)
+	warnBimodal(run)
+	warnMixedCPUs(run, preferred)
+	warnExcluded(run)
+}
+
+// warnWrongResults flags results whose handler returned the wrong answer.
+// Those samples are already excluded from every statistic, but a
+// deployment that is partly or wholly broken needs fixing, not averaging.
+func warnWrongResults(run *results.Run) {
+	for _, r := range run.Results {
+		wrong, first := 0, ""
+		for _, s := range r.Samples {
+			if s.Wrong() {
+				if wrong == 0 {
+					first = s.Error
+				}
+				wrong++
+			}
+		}
+		if wrong > 0 {
+			fmt.Fprintf(os.Stderr, "warning: %s/%s: %d of %d responses discarded: %s\n",
+				runtimeLabel(r), r.Workload, wrong, len(r.Samples), first)
+		}
+	}
+}
+
+// warnBimodal flags results whose latency falls into two modes. Lambda
+// places a function's environments on hosts of more than one generation,
+// and beside neighbors that sometimes contend for them; one runtime whose
+// environments split two ways and another whose did not differ by where
+// they ran, not by what they are.
+func warnBimodal(run *results.Run) {
+	for _, r := range run.Results {
+		for _, metric := range results.ModalMetrics {
+			m, ok := r.Modes[metric]
+			if !ok {
+				continue
+			}
+			action := "-stratify summarizes the larger mode alone"
+			if r.Stats[metric].Stratified > 0 {
+				action = fmt.Sprintf("%d samples of the smaller mode set aside", r.Stats[metric].Stratified)
+			}
+			fmt.Fprintf(os.Stderr, "warning: %s/%s: %s is bimodal (dip %.3f, p %.3f): %.0f%% near %.2f, %.0f%% near %.2f; likely host placement or noisy neighbors, not the runtime; %s\n",
+				runtimeLabel(r), r.Workload, metric, m.Dip, m.P, 100*m.LowShare, m.Low.Median, 100*(1-m.LowShare), m.High.Median, action)
+		}
+	}
+}
+
+// warnMixedCPUs notes results whose samples ran on hosts of more than one
+// CPU generation, with the median of each generation's: part of the
+// result's spread is the hardware's.
+func warnMixedCPUs(run *results.Run, preferred string) {
+	for _, r := range run.Results {
+		if r.Error != "" || len(r.CPUGenerations()) < 2 {
+			continue
+		}
+		metric := headlineMetric(r, preferred)
+		var parts []string
+		for _, g := range r.ByCPU(stats.Options{}) {
+			s := g.Stats[metric]
+			parts = append(parts, fmt.Sprintf("%s %d (median %.2f)", g.CPUGeneration, s.N, s.Median))
+		}
+		fmt.Fprintf(os.Stderr, "note: %s/%s: %s samples ran on %d CPU generations: %s; analyze -by-cpu splits them\n",
+			runtimeLabel(r), r.Workload, metric, len(parts), strings.Join(parts, ", "))
+	}
+}
+
+// warnExcluded flags results with samples excluded after failing
+// transiently, and with retried ones: the run went on without them, but
+// their targets were measured on fewer samples than asked for.
+func warnExcluded(run *results.Run) {
+	for _, r := range report.Exclusions(run) {
+		fmt.Fprintf(os.Stderr, "note: %s: %s of %d samples excluded as transient failures, after %d retries\n",
+			r.Label, r.Breakdown(), r.Samples, r.Retries)
+	}
+}
+
+// printThroughput shows the MB/s of results whose handler reported the
+// bytes it processed, over the REPORT duration or client time. It prints
+// nothing when no result has the metric.
+func printThroughput(run *results.Run) {
+	var rows []results.Result
+	for _, r := range run.Results {
+		if r.Stats[results.MetricThroughput].N > 0 {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRUNTIME\tWORKLOAD\tMB\tMEDIAN(MB/s)\tMEAN(MB/s)\tMIN(MB/s)\tMAX(MB/s)")
+	for _, r := range rows {
+		s := r.Stats[results.MetricThroughput]
+		mb := "-"
+		for _, sm := range r.Samples {
+			if sm.Bytes > 0 {
+				mb = fmt.Sprintf("%.1f", float64(sm.Bytes)/(1<<20))
+				break
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%.1f\t%.1f\t%.1f\t%.1f\n", r.Kind, runtimeLabel(r), r.Workload, mb, s.Median, s.Mean, s.Min, s.Max)
+	}
+	w.Flush()
+}
+
+// printOverhead splits the client time of results with REPORT lines into
+// what Lambda reports spending on the invocation and the rest, the
+// network and the service's front end between the harness and the
+// function. Warm invocations are shown apart from cold starts, whose
+// overhead also covers placing a new execution environment. It prints
+// nothing when no result has the metrics.
+func printOverhead(run *results.Run) {
+	var rows []results.Result
+	for _, r := range run.Results {
+		if r.Stats[results.MetricOverhead].N > 0 {
+			rows = append(rows, r)
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRUNTIME\tWORKLOAD\tWARM N\tCLIENT P50(ms)\tSERVER P50(ms)\tOVERHEAD P50(ms)\tOVERHEAD P95(ms)\tOVERHEAD SHARE\tCOLD OVERHEAD(ms)")
+	for _, r := range rows {
+		var (
+			warm struct{ client, server, overhead []float64 }
+			cold []float64
+		)
+		for _, sm := range r.Samples {
+			overhead, ok := sm.Value(results.MetricOverhead)
+			if !ok || sm.Error != "" {
+				continue
+			}
+			if sm.Cold {
+				cold = append(cold, overhead)
+				continue
+			}
+			if sm.Warmup {
+				continue
+			}
+			server, _ := sm.Value(results.MetricServer)
+			warm.client = append(warm.client, sm.ClientMS)
+			warm.server = append(warm.server, server)
+			warm.overhead = append(warm.overhead, overhead)
+		}
+		coldCell := "-"
+		if len(cold) > 0 {
+			coldCell = fmt.Sprintf("%.1f", stats.Mean(cold))
+		}
+		if len(warm.client) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t%s\t0\t-\t-\t-\t-\t-\t%s\n", r.Kind, runtimeLabel(r), r.Workload, coldCell)
+			continue
+		}
+		client, overhead := stats.Median(warm.client), stats.Median(warm.overhead)
+		sorted := slices.Sorted(slices.Values(warm.overhead))
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.0f%%\t%s\n", r.Kind, runtimeLabel(r), r.Workload,
+			len(warm.client), client, stats.Median(warm.server), overhead, stats.Percentile(sorted, 95), 100*overhead/client, coldCell)
+	}
+	w.Flush()
+}
+
+// printHTTP shows what the requests of results whose handler traced them
+// cost in connections, with the cold start apart: it has no idle
+// connection to reuse, while a warm invocation opens only the ones the
+// endpoint closed since the last. It prints nothing when no result has
+// the metrics.
+func printHTTP(run *results.Run) {
+	type side struct {
+		n                   int
+		requests, conns, ms float64
+	}
+	var rows []results.Result
+	for _, r := range run.Results {
+		for _, sm := range r.Samples {
+			if sm.HTTP != nil {
+				rows = append(rows, r)
+				break
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tRUNTIME\tWORKLOAD\tREQUESTS\tCOLD NEW CONNS\tCOLD TLS(ms)\tWARM NEW CONNS\tWARM TLS(ms)")
+	for _, r := range rows {
+		var cold, warm side
+		for _, sm := range r.Samples {
+			// Like the statistics, warm numbers leave warm-up out.
+			if sm.HTTP == nil || sm.Error != "" || sm.Warmup && !sm.Cold {
+				continue
+			}
+			s := &warm
+			if sm.Cold {
+				s = &cold
+			}
+			s.n++
+			s.requests += sm.HTTP[results.MetricHTTPRequests]
+			s.conns += sm.HTTP[results.MetricHTTPNewConns]
+			s.ms += sm.HTTP[results.MetricHTTPTLS]
+		}
+		cell := func(s side, v float64, format string) string {
+			if s.n == 0 {
+				return "-"
+			}
+			return fmt.Sprintf(format, v/float64(s.n))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Kind, runtimeLabel(r), r.Workload,
+			cell(side{n: cold.n + warm.n}, cold.requests+warm.requests, "%.0f"),
+			cell(cold, cold.conns, "%.1f"), cell(cold, cold.ms, "%.1f"), cell(warm, warm.conns, "%.1f"), cell(warm, warm.ms, "%.1f"))
+	}
+	w.Flush()
+}
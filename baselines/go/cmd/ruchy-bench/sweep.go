@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/pool"
+	"lambdaperf/pkg/powertune"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/sweep"
+)
+
+func runSweep(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sweep", flag.ContinueOnError)
+	var tf targetFlags
+	tf.register(fs)
+	var sf statsFlags
+	sf.register(fs)
+	var cf costFlags
+	cf.register(fs)
+	sizes := fs.String("sizes", "", "comma-separated memory sizes in MB (default: 128,256,512,1024,1769,3008)")
+	n := fs.Int("n", 10, "warm invocations per memory size")
+	var wf warmupFlags
+	wf.register(fs)
+	var rf retryFlags
+	rf.register(fs)
+	var pf payloadFlags
+	pf.register(fs)
+	var of outputFlags
+	of.register(fs)
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	strategy := fs.String("strategy", "", "choose each function's memory size as aws-lambda-power-tuning does, minimizing cost, speed or a balanced blend (default: off)")
+	weight := fs.Float64("weight", powertune.DefaultWeight, "share of cost in -strategy balanced's blend, from 0 (speed) to 1 (cost)")
+	var par parallelFlags
+	par.register(fs, "functions to sweep")
+	var bf budgetFlags
+	bf.register(fs)
+	var pgf progressFlags
+	pgf.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := bf.validate(); err != nil {
+		return err
+	}
+	ctx = bf.start(ctx)
+	if *n < 1 {
+		return errors.New("-n must be at least 1")
+	}
+	if err := par.validate(); err != nil {
+		return err
+	}
+	if err := wf.validate(); err != nil {
+		return err
+	}
+	if err := rf.validate(); err != nil {
+		return err
+	}
+	if *strategy != "" {
+		if _, err := powertune.Parse(*strategy); err != nil {
+			return err
+		}
+	}
+	if *weight < 0 || *weight > 1 {
+		return errors.New("-weight must be between 0 and 1")
+	}
+	memSizes, err := parseSizes(*sizes)
+	if err != nil {
+		return err
+	}
+	tf.kind = string(discover.KindLambda)
+	root, targets, err := tf.resolve()
+	if err != nil {
+		return err
+	}
+	if tf.snapStart {
+		// Memory changes only reach $LATEST; a SnapStart version would
+		// need republishing at every size.
+		return errors.New("sweep does not support -snapstart")
+	}
+	client, err := newLambdaClient(ctx, *region)
+	if err != nil {
+		return err
+	}
+
+	expected, err := expectedResults(root)
+	if err != nil {
+		return err
+	}
+
+	payloads := make([][]byte, len(targets))
+	for i, t := range targets {
+		if payloads[i], err = pf.forTarget(t); err != nil {
+			return err
+		}
+	}
+	run := results.NewRun("sweep", time.Now())
+	ctx, stopProgress := pgf.start(ctx)
+	defer stopProgress()
+	// Each function steps through the sizes on its own; -parallel of
+	// them do so at once.
+	swept := make([][]results.Result, len(targets))
+	pool.Each(ctx, len(targets), par.workers, func(i int) {
+		t := targets[i]
+		fmt.Fprintf(os.Stderr, "%s: sweeping %v MB\n", t.FunctionName(), memSizes)
+		r := &sweep.Runner{
+			Client:       client,
+			FunctionName: t.FunctionName(),
+			Sizes:        memSizes,
+			Invocations:  *n,
+			Warmup:       wf.warmup(),
+			Precision:    wf.precision(),
+			Payload:      payloads[i],
+			Expected:     expected[t.Workload],
+			Runtime:      canaryRuntime(t),
+			Backoff:      rf.backoff(),
+		}
+		points, err := r.Run(ctx)
+		for _, p := range points {
+			res := newResult(t)
+			res.MemoryMB = p.MemoryMB
+			res.Samples = p.Samples
+			wf.check(fmt.Sprintf("%s at %d MB", t.FunctionName(), p.MemoryMB), &res)
+			swept[i] = append(swept[i], res)
+		}
+		if err != nil {
+			res := newResult(t)
+			res.Error = err.Error()
+			swept[i] = append(swept[i], res)
+		}
+	})
+	if *strategy != "" {
+		for _, s := range swept {
+			if err := tune(s, powertune.Strategy(*strategy), *weight, cf); err != nil {
+				return err
+			}
+		}
+	}
+	for _, s := range swept {
+		run.Results = append(run.Results, s...)
+	}
+	run.FinishedAt = time.Now().UTC()
+	run.Summarize(sf.options())
+
+	path, err := of.save(ctx, root, run)
+	if err != nil {
+		return err
+	}
+	printSweep(run, cf)
+	fmt.Fprintln(os.Stderr, "results written to", path)
+	return context.Cause(ctx)
+}
+
+// tune marks the result of one function's sweep at the memory size s
+// chooses.
+func tune(swept []results.Result, s powertune.Strategy, weight float64, cf costFlags) error {
+	var (
+		points []powertune.Point
+		at     []int
+	)
+	for i, r := range swept {
+		if r.Error != "" {
+			continue
+		}
+		p, ok, err := powertune.FromResult(r, cf.price)
+		if err != nil {
+			return err
+		}
+		if ok {
+			points, at = append(points, p), append(at, i)
+		}
+	}
+	best := powertune.Optimum(points, s, weight)
+	if best < 0 {
+		return nil
+	}
+	swept[at[best]].Optimum = string(s)
+	p := points[best]
+	fmt.Fprintf(os.Stderr, "%s: %s optimum %d MB, %.2f ms trimmed mean at $%.4f per 1M\n", swept[at[best]].Function, s, p.MemoryMB, p.DurationMS, p.USD)
+	return nil
+}
+
+func parseSizes(s string) ([]int32, error) {
+	list := splitList(s)
+	if len(list) == 0 {
+		return sweep.DefaultSizes, nil
+	}
+	sizes := make([]int32, 0, len(list))
+	for _, v := range list {
+		mb, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || mb < 128 || mb > 10240 {
+			return nil, fmt.Errorf("invalid memory size %q: want 128-10240 MB", v)
+		}
+		sizes = append(sizes, int32(mb))
+	}
+	return sizes, nil
+}
+
+func printSweep(run *results.Run, cf costFlags) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tMEMORY(MB)\tWARM P50(ms)\tWARM P99(ms)\tBILLED MEAN(ms)\tINIT(ms)\tUSD/1M\tOPTIMUM")
+	for _, r := range run.Results {
+		if r.Error != "" {
+			fmt.Fprintf(w, "%s\t-\terror: %s\n", r.Function, r.Error)
+			continue
+		}
+		warm, billed, cold := r.Stats[results.MetricWarm], r.Stats[results.MetricBilled], r.Stats[results.MetricInit]
+		initMS := "-"
+		if cold.N > 0 {
+			initMS = fmt.Sprintf("%.2f", cold.Mean)
+		}
+		optimum := "-"
+		if r.Optimum != "" {
+			optimum = r.Optimum
+		}
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%.2f\t%.2f\t%s\t%s\t%s\n", r.Function, r.MemoryMB,
+			warm.Median, warm.P99, billed.Mean, initMS, cf.perMillion(r), optimum)
+	}
+	w.Flush()
+}
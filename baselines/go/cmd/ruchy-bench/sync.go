@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"lambdaperf/pkg/store"
+)
+
+func runSync(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("sync", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench sync -db s3://BUCKET/KEY [flags]")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	db := fs.String("db", "", "shared history database to merge with, as s3://BUCKET/KEY (required)")
+	local := fs.String("local", "", "local history database (default: <root>/.bench/results.db)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *db == "" {
+		fs.Usage()
+		return errors.New("sync needs -db")
+	}
+	var err error
+	if *root, err = findRoot(*root); err != nil {
+		return err
+	}
+	sh, err := sharedDB(ctx, *root, *db)
+	if err != nil {
+		return err
+	}
+	if sh == nil {
+		return fmt.Errorf("-db %s is not an s3:// database", *db)
+	}
+	l, err := store.Open(dbPath(*root, *local))
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	var pushedRuns, pushedArtifacts int
+	if err := sh.Update(ctx, func(s *store.Store) error {
+		pushedRuns, pushedArtifacts, err = s.Merge(ctx, l)
+		return err
+	}); err != nil {
+		return err
+	}
+	s, err := store.Open(sh.Path)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	pulledRuns, pulledArtifacts, err := l.Merge(ctx, s)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "pushed %d runs and %d builds to %s, pulled %d runs and %d builds\n",
+		pushedRuns, pushedArtifacts, sh.Location(), pulledRuns, pulledArtifacts)
+	return nil
+}
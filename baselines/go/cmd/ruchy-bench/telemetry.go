@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/results"
+)
+
+// telemetryPoll and telemetryWait pace the reads of a function's log group
+// for the telemetry extension's lines, which CloudWatch Logs takes seconds
+// to make searchable.
+const (
+	telemetryPoll = 5 * time.Second
+	telemetryWait = time.Minute
+)
+
+// attachTelemetry waits for the telemetry lines of res's invocations, made
+// since start, and records their phase timings on the samples it finds
+// them for. Functions deployed without the extension never log any, so a
+// missing line, like a failed lookup, is reported and leaves the samples
+// as they are.
+func attachTelemetry(ctx context.Context, logs cloudwatchlogs.FilterLogEventsAPIClient, res *results.Result, start time.Time) {
+	want := map[string]bool{}
+	for _, s := range res.Samples {
+		if s.RequestID != "" {
+			want[s.RequestID] = true
+		}
+	}
+	if len(want) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: waiting for telemetry\n", res.Function)
+	// The log search starts a little early to allow for clock skew.
+	from, deadline := start.Add(-time.Minute), time.Now().Add(telemetryWait)
+	var found int
+	for ctx.Err() == nil {
+		lines, err := reportparser.FetchTelemetry(ctx, logs, res.Function, from, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: telemetry: %v\n", res.Function, err)
+			return
+		}
+		found = 0
+		for i, s := range res.Samples {
+			if l, ok := lines[s.RequestID]; ok {
+				res.Samples[i] = s.WithTelemetry(l)
+				found++
+			}
+		}
+		if found == len(want) || time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(telemetryPoll):
+		}
+	}
+	if found < len(want) {
+		fmt.Fprintf(os.Stderr, "%s: telemetry for %d of %d invocations (deploy with -telemetry first)\n", res.Function, found, len(want))
+	}
+}
+
+// printTelemetry shows the mean Telemetry API phase timings of results
+// with telemetry, with how many of their invocations had it.
+func printTelemetry(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUNTIME\tWORKLOAD\tRECORDED\tINIT(ms)\tRUNTIME(ms)\tRESPONSE LATENCY(ms)\tRESPONSE(ms)\tOVERHEAD(ms)")
+	for _, r := range run.Results {
+		recorded := r.Stats[results.MetricTelemetryRuntime].N
+		if recorded == 0 {
+			continue
+		}
+		mean := func(metric string) string {
+			if s, ok := r.Stats[metric]; ok {
+				return fmt.Sprintf("%.3f", s.Mean)
+			}
+			return "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\t%s\t%s\t%s\t%s\n", runtimeLabel(r), r.Workload,
+			recorded, len(r.Samples), mean(results.MetricTelemetryInit), mean(results.MetricTelemetryRuntime),
+			mean(results.MetricTelemetryResponseLatency), mean(results.MetricTelemetryResponse),
+			mean(results.MetricTelemetryOverhead))
+	}
+	w.Flush()
+}
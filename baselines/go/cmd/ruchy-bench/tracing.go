@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/tracing"
+)
+
+func newTraceFetcher(ctx context.Context, region string) (*tracing.Fetcher, error) {
+	cfg, err := loadAWSConfig(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return &tracing.Fetcher{API: &tracing.Client{Config: cfg}}, nil
+}
+
+// attachTraces waits for the X-Ray traces of res's invocations, made since
+// start, and records their segment breakdowns on the samples it finds
+// them for. A failed lookup is reported but leaves the samples as they
+// are: the latencies stand without their breakdown.
+func attachTraces(ctx context.Context, f *tracing.Fetcher, res *results.Result, start time.Time) {
+	var ids []string
+	for _, s := range res.Samples {
+		if s.RequestID != "" {
+			ids = append(ids, s.RequestID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: waiting for X-Ray traces\n", res.Function)
+	found, err := f.Fetch(ctx, res.Function, start, ids)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: traces: %v\n", res.Function, err)
+	}
+	for i, s := range res.Samples {
+		if b, ok := found[s.RequestID]; ok {
+			res.Samples[i] = s.WithTrace(b)
+		}
+	}
+}
+
+// printTraces shows the mean X-Ray segment durations of traced results,
+// with how many of their invocations were sampled.
+func printTraces(run *results.Run) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUNTIME\tWORKLOAD\tTRACED\tINIT(ms)\tINVOCATION(ms)\tDOWNSTREAM(ms)\tOVERHEAD(ms)")
+	for _, r := range run.Results {
+		traced := r.Stats[results.MetricTraceInvocation].N
+		if traced == 0 {
+			continue
+		}
+		mean := func(metric string) string {
+			if s, ok := r.Stats[metric]; ok {
+				return fmt.Sprintf("%.2f", s.Mean)
+			}
+			return "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\t%s\t%s\t%s\n", runtimeLabel(r), r.Workload,
+			traced, len(r.Samples), mean(results.MetricTraceInit), mean(results.MetricTraceInvocation),
+			mean(results.MetricTraceDownstream), mean(results.MetricTraceOverhead))
+	}
+	w.Flush()
+}
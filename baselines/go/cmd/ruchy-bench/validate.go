@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"lambdaperf/pkg/schema"
+)
+
+func runValidate(_ context.Context, args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: ruchy-bench validate [flags] [results.json ...]")
+		fs.PrintDefaults()
+	}
+	root := fs.String("root", "", "repository root (default: found by walking up from the working directory)")
+	migrate := fs.Bool("migrate", false, "rewrite files of older schema versions as the current one")
+	print := fs.Bool("schema", false, "print the results JSON schema and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *print {
+		_, err := os.Stdout.Write(schema.Document)
+		return err
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		dir, err := findRoot(*root)
+		if err != nil {
+			return err
+		}
+		if paths, err = filepath.Glob(filepath.Join(dir, ".bench", "results", "*.json")); err != nil {
+			return err
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no results files in %s; run a benchmark first", filepath.Join(dir, ".bench", "results"))
+		}
+	}
+
+	invalid := 0
+	for _, path := range paths {
+		msg, err := validateFile(path, *migrate)
+		var serr *schema.Error
+		switch {
+		case errors.As(err, &serr):
+			invalid++
+			fmt.Fprintf(os.Stderr, "%s: %d problems\n", path, len(serr.Problems))
+			for _, p := range serr.Problems {
+				fmt.Fprintln(os.Stderr, "  "+p)
+			}
+			continue
+		case err != nil:
+			invalid++
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("%s: %s\n", path, msg)
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d results files do not match schema version %d", invalid, len(paths), schema.Version)
+	}
+	return nil
+}
+
+// validateFile checks one results file against the schema, upgraded to
+// the current version first, and with migrate rewrites it so.
+func validateFile(path string, migrate bool) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	upgraded, from, err := schema.Upgrade(data)
+	if err != nil {
+		return "", err
+	}
+	if err := schema.Validate(upgraded); err != nil {
+		return "", err
+	}
+	switch {
+	case from == schema.Version:
+		return fmt.Sprintf("ok, schema version %d", from), nil
+	case !migrate:
+		return fmt.Sprintf("ok, schema version %d; -migrate rewrites it as version %d", from, schema.Version), nil
+	}
+	if err := os.WriteFile(path, append(upgraded, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("migrated from schema version %d to %d", from, schema.Version), nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"lambdaperf/pkg/vpc"
+)
+
+func runVPC(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("vpc", flag.ContinueOnError)
+	nat := fs.Bool("nat", false, "add a NAT gateway so VPC-attached workloads can call AWS APIs and the internet (billed hourly until deleted)")
+	del := fs.Bool("delete", false, "delete the VPC instead, once ruchy-bench teardown -vpc has deleted its functions")
+	region := fs.String("region", "", "AWS region (default: from AWS config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *nat && *del {
+		return errors.New("-nat and -delete are mutually exclusive")
+	}
+	cfg, err := loadAWSConfig(ctx, *region)
+	if err != nil {
+		return err
+	}
+	c := &vpc.Client{Config: cfg}
+	if *del {
+		deleted, err := c.Delete(ctx, vpc.DefaultName)
+		if err != nil {
+			return err
+		}
+		if !deleted {
+			fmt.Printf("no VPC %s in %s\n", vpc.DefaultName, cfg.Region)
+			return nil
+		}
+		fmt.Printf("deleted VPC %s in %s\n", vpc.DefaultName, cfg.Region)
+		return nil
+	}
+	if *nat {
+		fmt.Fprintln(os.Stderr, "a new NAT gateway takes a few minutes to become available")
+	}
+	n, err := c.Ensure(ctx, vpc.DefaultName, *nat)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("VPC %s in %s: %s\n", n.Name, cfg.Region, n.VPCID)
+	fmt.Printf("  subnets         %s\n", strings.Join(n.SubnetIDs, ", "))
+	fmt.Printf("  security group  %s\n", n.SecurityGroupID)
+	if n.NATGatewayID != "" {
+		fmt.Printf("  NAT gateway     %s (billed hourly; ruchy-bench vpc -delete removes it)\n", n.NATGatewayID)
+	} else {
+		fmt.Println("  NAT gateway     none: functions reach neither AWS APIs nor the internet")
+	}
+	return nil
+}
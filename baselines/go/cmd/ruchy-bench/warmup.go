@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// warmupFlags configure the invocations made before recording and, with
+// -precision, how far recording extends past -n; see results.Collect.
+type warmupFlags struct {
+	min, window, max int
+	cv               float64
+	precise          float64
+	maxN             int
+}
+
+func (f *warmupFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&f.min, "warmup", 0, "invocations to make and leave out of the statistics before recording")
+	fs.Float64Var(&f.cv, "steady-cv", 0, "after -warmup, keep warming up until the coefficient of variation of the last -steady-window durations is at most this, e.g. 0.05 (default: off)")
+	fs.IntVar(&f.window, "steady-window", stats.DefaultSteadyWindow, "invocations the -steady-cv coefficient of variation is computed over")
+	fs.IntVar(&f.max, "max-warmup", stats.DefaultMaxWarmup, "most warm-up invocations per target before recording regardless")
+	fs.Float64Var(&f.precise, "precision", 0, "after -n, keep recording until the 95% confidence interval of the median is within this fraction of it either way, e.g. 0.02 for ±2% (default: off)")
+	fs.IntVar(&f.maxN, "max-n", stats.DefaultMaxSamples, "most samples per target -precision records before flagging it imprecise")
+}
+
+func (f *warmupFlags) validate() error {
+	switch {
+	case f.min < 0:
+		return errors.New("-warmup must not be negative")
+	case f.cv < 0:
+		return errors.New("-steady-cv must not be negative")
+	case f.window < 2:
+		return errors.New("-steady-window must be at least 2")
+	case f.max < f.min:
+		return errors.New("-max-warmup must be at least -warmup")
+	case f.precise < 0 || f.precise >= 1:
+		return errors.New("-precision must be a fraction between 0 and 1")
+	case f.maxN < 1:
+		return errors.New("-max-n must be at least 1")
+	}
+	return nil
+}
+
+func (f *warmupFlags) warmup() stats.Warmup {
+	return stats.Warmup{Min: f.min, CV: f.cv, Window: f.window, Max: f.max}
+}
+
+func (f *warmupFlags) precision() stats.Precision {
+	return stats.Precision{Target: f.precise, Max: f.maxN}
+}
+
+// report tells how far res warmed up, warning when steady-state detection
+// gave up, and checks its precision.
+func (f *warmupFlags) report(id string, res *results.Result, steady bool) {
+	n := 0
+	for _, s := range res.Samples {
+		if s.Warmup {
+			n++
+		}
+	}
+	switch {
+	case f.cv > 0 && !steady:
+		fmt.Fprintf(os.Stderr, "warning: %s: no steady state (CV <= %g) after %d warm-up invocations; recorded anyway\n", id, f.cv, n)
+	case n > 0:
+		fmt.Fprintf(os.Stderr, "%s: warmed up in %d invocations\n", id, n)
+	}
+	f.check(id, res)
+}
+
+// check flags res imprecise when -precision extended it to -max-n without
+// the median's interval narrowing enough.
+func (f *warmupFlags) check(id string, res *results.Result) {
+	if f.precise == 0 || res.Error != "" {
+		return
+	}
+	ok, width := results.Precise(res.Samples, f.precision())
+	recorded := 0
+	for _, s := range res.Samples {
+		if !s.Warmup {
+			recorded++
+		}
+	}
+	if !ok {
+		res.Imprecise = true
+		fmt.Fprintf(os.Stderr, "warning: %s: median known to ±%.1f%% after %d samples, short of ±%g%%; flagged imprecise\n",
+			id, 100*width, recorded, 100*f.precise)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: median known to ±%.1f%% after %d samples\n", id, 100*width, recorded)
+}
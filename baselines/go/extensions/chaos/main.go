@@ -0,0 +1,53 @@
+// Command chaos is the external Lambda extension that contends with the
+// runtime for its execution environment; see pkg/chaos. ruchy-bench
+// deploy attaches it to -chaos variants, with the shares of CPU and
+// memory it takes in BENCH_CHAOS_CPU and BENCH_CHAOS_MEMORY.
+//
+// It takes the memory during init, which Init Duration then includes,
+// and spins from then until SHUTDOWN. It asks for the next event as soon
+// as it has one, so Lambda never waits on it to freeze the environment:
+// any difference in Duration is the runtime's under contention.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+
+	"lambdaperf/pkg/chaos"
+	"lambdaperf/pkg/telemetryext"
+)
+
+func main() {
+	c, err := chaos.FromEnv()
+	if err != nil {
+		fail(err)
+	}
+	ext, err := telemetryext.Register("http://"+os.Getenv("AWS_LAMBDA_RUNTIME_API"), filepath.Base(os.Args[0]))
+	if err != nil {
+		fail(err)
+	}
+	memoryMB, _ := strconv.Atoi(os.Getenv("AWS_LAMBDA_FUNCTION_MEMORY_SIZE"))
+	held := chaos.Hold(c.Memory, memoryMB)
+	go chaos.Burn(context.Background(), c.CPU, runtime.NumCPU())
+	for {
+		ev, err := ext.Next()
+		if err != nil {
+			fail(err)
+		}
+		if ev.EventType == "SHUTDOWN" {
+			runtime.KeepAlive(held)
+			return
+		}
+	}
+}
+
+// fail exits; Lambda then fails the init phase with the message in the
+// function's logs.
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "chaos:", err)
+	os.Exit(1)
+}
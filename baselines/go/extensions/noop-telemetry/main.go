@@ -0,0 +1,116 @@
+// Command noop-telemetry is a minimal external Lambda extension that does
+// what observability extensions do with none of their work: it registers
+// for INVOKE and SHUTDOWN events, subscribes to the Telemetry API and
+// discards every batch Lambda posts to it. Deploying a baseline with and
+// without it (ruchy-bench deploy -extension) measures the floor an
+// extension adds to cold starts and invocations: another process to start
+// during init, and an event loop Lambda waits on before freezing the
+// environment.
+//
+// It is packaged as a layer with the binary at extensions/noop-telemetry,
+// the name it registers under; see build.NoopExtension. Like
+// main-runtimeapi.go it depends on net/http alone.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// listenAddr is where Lambda delivers telemetry: the sandbox hostname
+// resolves inside the execution environment only.
+const listenAddr = "sandbox.localdomain:4243"
+
+// subscription is the Telemetry API subscription, with the buffering
+// bounds the Telemetry API documents as defaults.
+const subscription = `{"schemaVersion":"2022-12-13","types":["platform","function"],` +
+	`"buffering":{"maxItems":1000,"maxBytes":262144,"timeoutMs":1000},` +
+	`"destination":{"protocol":"HTTP","URI":"http://` + listenAddr + `"}}`
+
+func main() {
+	api := "http://" + os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	id, err := register(api, filepath.Base(os.Args[0]))
+	if err != nil {
+		fail(err)
+	}
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		fail(err)
+	}
+	go http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	if err := call(http.MethodPut, api+"/2022-07-01/telemetry", id, []byte(subscription), nil); err != nil {
+		fail(fmt.Errorf("subscribe to telemetry: %w", err))
+	}
+	for {
+		var event struct {
+			EventType string `json:"eventType"`
+		}
+		if err := call(http.MethodGet, api+"/2020-01-01/extension/event/next", id, nil, &event); err != nil {
+			fail(fmt.Errorf("next event: %w", err))
+		}
+		if event.EventType == "SHUTDOWN" {
+			return
+		}
+	}
+}
+
+// register registers the extension, returning the identifier every later
+// call carries.
+func register(api, name string) (string, error) {
+	req, err := http.NewRequest(http.MethodPost, api+"/2020-01-01/extension/register", bytes.NewReader([]byte(`{"events":["INVOKE","SHUTDOWN"]}`)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Lambda-Extension-Name", name)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("register: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("register: %s", resp.Status)
+	}
+	return resp.Header.Get("Lambda-Extension-Identifier"), nil
+}
+
+// call sends a request as the extension and decodes the response into
+// out, if not nil.
+func call(method, url, id string, body []byte, out any) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Lambda-Extension-Identifier", id)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// fail exits; Lambda then fails the init phase with the message in the
+// function's logs.
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "noop-telemetry:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,65 @@
+// Command telemetry is the external Lambda extension that records each
+// invocation's Telemetry API phase timings in the function's log group;
+// see pkg/telemetryext. ruchy-bench deploy -telemetry attaches it, and
+// coldstart and run -telemetry read its lines back.
+//
+// Lambda freezes an environment once the runtime and every extension have
+// asked for the next event, and telemetry for an invocation is only
+// delivered after its response. So after each INVOKE event the extension
+// waits for the invocation's platform.runtimeDone before asking for the
+// next one, or its line would wait for the environment's next thaw. That
+// wait is extension time in the REPORT line's Duration: measure latency
+// without this extension attached.
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"lambdaperf/pkg/telemetryext"
+)
+
+// flushWait is how long a SHUTDOWN waits for the last buffered batch:
+// comfortably more than the subscription's buffering timeout.
+const flushWait = 100 * time.Millisecond
+
+func main() {
+	ext, err := telemetryext.Register("http://"+os.Getenv("AWS_LAMBDA_RUNTIME_API"), filepath.Base(os.Args[0]))
+	if err != nil {
+		fail(err)
+	}
+	ln, err := net.Listen("tcp", telemetryext.ListenAddr)
+	if err != nil {
+		fail(err)
+	}
+	c := telemetryext.NewCollector(os.Stdout)
+	go http.Serve(ln, c)
+	if err := ext.Subscribe("http://" + telemetryext.ListenAddr); err != nil {
+		fail(err)
+	}
+	for {
+		ev, err := ext.Next()
+		if err != nil {
+			fail(err)
+		}
+		if ev.EventType == "SHUTDOWN" {
+			time.Sleep(flushWait)
+			return
+		}
+		select {
+		case <-c.Done(ev.RequestID):
+		case <-time.After(time.Until(ev.Deadline())):
+		}
+	}
+}
+
+// fail exits; Lambda then fails the init phase with the message in the
+// function's logs.
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "telemetry:", err)
+	os.Exit(1)
+}
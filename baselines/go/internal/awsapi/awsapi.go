@@ -0,0 +1,220 @@
+// Package awsapi sends signed requests to AWS services in their JSON,
+// REST and query protocols. The harness calls a handful of operations on
+// many services, and a package per service implementing just those on a
+// Client spares it a service module of the SDK for each. Only Lambda,
+// CloudWatch Logs, S3, DynamoDB, ECR, IAM and STS go through the SDK.
+package awsapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Client sends requests to one service, signed with the config's
+// credentials. Requests go unsigned when it has none, as they do to the
+// fake servers of tests.
+type Client struct {
+	Config aws.Config
+	// Service is the name requests are signed for, such as "sqs", and
+	// the first label of the default endpoint's host.
+	Service string
+	// Region is the region requests are signed for and sent to; empty
+	// means the config's.
+	Region string
+	// Endpoint overrides https://<Service>.<Region>.amazonaws.com.
+	Endpoint string
+	// HTTP is the client requests are sent with; nil means
+	// http.DefaultClient.
+	HTTP *http.Client
+}
+
+// Error is an error response from a service.
+type Error struct {
+	// Code is the error type or code without its namespace, such as
+	// "QueueDoesNotExist" or "DependencyViolation".
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string { return e.Code + ": " + e.Message }
+
+func (c *Client) region() string {
+	if c.Region != "" {
+		return c.Region
+	}
+	return c.Config.Region
+}
+
+// URL is the service's endpoint followed by path.
+func (c *Client) URL(path string) string {
+	if c.Endpoint != "" {
+		return c.Endpoint + path
+	}
+	return "https://" + c.Service + "." + c.region() + ".amazonaws.com" + path
+}
+
+// Sign signs req, whose body is body, with SigV4 for the service and
+// region. It leaves req alone when the config has no credentials.
+func (c *Client) Sign(req *http.Request, body []byte) error {
+	if c.Config.Credentials == nil {
+		return nil
+	}
+	creds, err := c.Config.Credentials.Retrieve(req.Context())
+	if err != nil {
+		return fmt.Errorf("retrieve credentials: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	if err := v4.NewSigner().SignHTTP(req.Context(), creds, req, hex.EncodeToString(sum[:]), c.Service, c.region(), time.Now()); err != nil {
+		return fmt.Errorf("sign request: %w", err)
+	}
+	return nil
+}
+
+// Do signs req, whose body is body, sends it and reads the response. A
+// status other than 2xx is returned as an *Error when the response says
+// which error it is, and as the status and body when it does not.
+func (c *Client) Do(req *http.Request, body []byte) (*http.Response, []byte, error) {
+	if err := c.Sign(req, body); err != nil {
+		return nil, nil, err
+	}
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return resp, data, decodeError(resp, data)
+	}
+	return resp, data, nil
+}
+
+// decodeError reads the error in a failed response, wherever its
+// protocol puts it.
+func decodeError(resp *http.Response, data []byte) error {
+	var code, message string
+	if xmlErr := (struct {
+		Code    string `xml:"Error>Code"`
+		Message string `xml:"Error>Message"`
+		// EC2 nests its error one level deeper.
+		EC2Code    string `xml:"Errors>Error>Code"`
+		EC2Message string `xml:"Errors>Error>Message"`
+	}{}); xml.Unmarshal(data, &xmlErr) == nil {
+		code, message = xmlErr.Code+xmlErr.EC2Code, xmlErr.Message+xmlErr.EC2Message
+	} else {
+		var jsonErr struct {
+			Type string `json:"__type"`
+			// Keys match "message" and "Message" alike.
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &jsonErr)
+		code, message = jsonErr.Type, jsonErr.Message
+		if code == "" {
+			// REST services name it in a header, as in
+			// NotFoundException:http://internal.amazon.com/coral/...
+			code, _, _ = strings.Cut(resp.Header.Get("X-Amzn-ErrorType"), ":")
+		}
+	}
+	if code == "" {
+		if message == "" {
+			message = string(bytes.TrimSpace(data))
+		}
+		return fmt.Errorf("%s: %s", resp.Status, message)
+	}
+	// Types may be namespaced, as in com.amazonaws.sqs#QueueDoesNotExist.
+	return &Error{Code: code[strings.LastIndex(code, "#")+1:], Message: message}
+}
+
+// JSON calls the operation target, such as "AmazonSQS.CreateQueue", in
+// version ("1.0" or "1.1") of the JSON protocol with input in, decoding
+// its output into out. A nil out, or an empty response, decodes nothing.
+func (c *Client) JSON(ctx context.Context, version, target string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL("/"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-"+version)
+	req.Header.Set("X-Amz-Target", target)
+	_, data, err := c.Do(req, body)
+	if err != nil {
+		return err
+	}
+	return decode(json.Unmarshal, data, out)
+}
+
+// REST sends in, if not nil, as the JSON body of a method request to
+// path, decoding the response into out as JSON does.
+func (c *Client) REST(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.URL(path), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	_, data, err := c.Do(req, body)
+	if err != nil {
+		return err
+	}
+	return decode(json.Unmarshal, data, out)
+}
+
+// Query calls action of API version in the query protocol with the
+// flattened parameters params, decoding the XML response into out as JSON
+// does.
+func (c *Client) Query(ctx context.Context, version, action string, params map[string]string, out any) error {
+	form := url.Values{"Action": {action}, "Version": {version}}
+	for k, v := range params {
+		form.Set(k, v)
+	}
+	body := []byte(form.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL("/"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	_, data, err := c.Do(req, body)
+	if err != nil {
+		return err
+	}
+	return decode(xml.Unmarshal, data, out)
+}
+
+func decode(unmarshal func([]byte, any) error, data []byte, out any) error {
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	return unmarshal(data, out)
+}
@@ -0,0 +1,128 @@
+package awsapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestProtocols(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Header.Get("X-Amz-Target") == "AmazonSQS.GetQueueUrl":
+			if r.Header.Get("Content-Type") != "application/x-amz-json-1.0" || !strings.Contains(string(body), `"QueueName":"q"`) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"QueueUrl":"https://sqs/q"}`))
+		case r.URL.Path == "/restapis/a1":
+			if r.Method != http.MethodPatch || r.Header.Get("Accept") != "application/json" || string(body) != `{"name":"n"}` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"id":"a1"}`))
+		case r.URL.Path == "/restapis/empty":
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			form, _ := url.ParseQuery(string(body))
+			if form.Get("Action") != "DescribeVpcs" || form.Get("Version") != "2016-11-15" || form.Get("VpcId.1") != "vpc-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`<DescribeVpcsResponse><vpcSet><item><vpcId>vpc-1</vpcId></item></vpcSet></DescribeVpcsResponse>`))
+		}
+	}))
+	defer srv.Close()
+	c := &Client{Service: "test", Endpoint: srv.URL}
+	ctx := context.Background()
+
+	var queue struct{ QueueUrl string }
+	if err := c.JSON(ctx, "1.0", "AmazonSQS.GetQueueUrl", map[string]string{"QueueName": "q"}, &queue); err != nil || queue.QueueUrl != "https://sqs/q" {
+		t.Errorf("JSON = %+v, %v", queue, err)
+	}
+	var api struct {
+		ID string `json:"id"`
+	}
+	if err := c.REST(ctx, http.MethodPatch, "/restapis/a1", map[string]string{"name": "n"}, &api); err != nil || api.ID != "a1" {
+		t.Errorf("REST = %+v, %v", api, err)
+	}
+	if err := c.REST(ctx, http.MethodDelete, "/restapis/empty", nil, &api); err != nil {
+		t.Errorf("REST with an empty response: %v", err)
+	}
+	var vpcs struct {
+		IDs []string `xml:"vpcSet>item>vpcId"`
+	}
+	if err := c.Query(ctx, "2016-11-15", "DescribeVpcs", map[string]string{"VpcId.1": "vpc-1"}, &vpcs); err != nil || len(vpcs.IDs) != 1 || vpcs.IDs[0] != "vpc-1" {
+		t.Errorf("Query = %+v, %v", vpcs, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		body   string
+		code   string
+	}{
+		{"JSON", "", `{"__type":"com.amazonaws.sqs#QueueDoesNotExist","message":"gone"}`, "QueueDoesNotExist"},
+		{"header", "NotFoundException:http://internal.amazon.com/coral/", `{"message":"gone"}`, "NotFoundException"},
+		{"query", "", `<ErrorResponse><Error><Code>NotFound</Code><Message>gone</Message></Error></ErrorResponse>`, "NotFound"},
+		{"EC2", "", `<Response><Errors><Error><Code>DependencyViolation</Code><Message>gone</Message></Error></Errors></Response>`, "DependencyViolation"},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.header != "" {
+				w.Header().Set("X-Amzn-ErrorType", tt.header)
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(tt.body))
+		}))
+		err := (&Client{Endpoint: srv.URL}).REST(context.Background(), http.MethodGet, "/", nil, nil)
+		srv.Close()
+		var e *Error
+		if !errors.As(err, &e) || e.Code != tt.code || e.Message != "gone" {
+			t.Errorf("%s error = %v, want %s: gone", tt.name, err, tt.code)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream down", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	err := (&Client{Endpoint: srv.URL}).REST(context.Background(), http.MethodGet, "/", nil, nil)
+	var e *Error
+	if errors.As(err, &e) || err == nil || !strings.Contains(err.Error(), "502 Bad Gateway: upstream down") {
+		t.Errorf("unnamed error = %v, want the status and body", err)
+	}
+}
+
+func TestSign(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+	c := &Client{Service: "sqs", Region: "eu-west-1", Endpoint: srv.URL}
+	if err := c.JSON(context.Background(), "1.0", "AmazonSQS.ListQueues", struct{}{}, nil); err != nil || auth != "" {
+		t.Errorf("no credentials: auth %q, %v", auth, err)
+	}
+	c.Config = aws.Config{Region: "us-east-1", Credentials: credentials.NewStaticCredentialsProvider("AKID", "secret", "")}
+	if err := c.JSON(context.Background(), "1.0", "AmazonSQS.ListQueues", struct{}{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(auth, "Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/sqs/aws4_request") {
+		t.Errorf("Authorization = %q, want signed for sqs in eu-west-1", auth)
+	}
+	if got := (&Client{Service: "sqs", Config: c.Config}).URL("/"); got != "https://sqs.us-east-1.amazonaws.com/" {
+		t.Errorf("URL = %q", got)
+	}
+}
@@ -0,0 +1,44 @@
+// Package codec is the JSON serializer of the Go baselines, chosen at
+// build time so a report can set Go's best-case serialization beside
+// encoding/json's. Without a tag it is encoding/json; the jsoniter tag
+// selects json-iterator's standard-library-compatible configuration and the
+// easyjson tag easyjson, whose code is generated per type ahead of time and
+// which falls back to encoding/json for types without it. ruchy-bench
+// builds the -serializer variants of targets with these tags; see
+// discover.Serializers.
+package codec
+
+//go:generate go run github.com/mailru/easyjson/easyjson -all -build_tags easyjson -output_filename document_easyjson.go codec.go
+
+// Document is the json workload's document, for the serializers that
+// decode into named types only. Its fields are in key order, so it
+// encodes to the same bytes as the generic value encoding/json sorts the
+// keys of.
+type Document struct {
+	Records []Record `json:"records"`
+	Version int      `json:"version"`
+}
+
+// Record is one of a Document's records.
+type Record struct {
+	Active   bool     `json:"active"`
+	Children []Child  `json:"children"`
+	ID       int      `json:"id"`
+	Metrics  Metrics  `json:"metrics"`
+	Name     string   `json:"name"`
+	Tags     []string `json:"tags"`
+}
+
+// Child is one of a Record's children.
+type Child struct {
+	Depth int    `json:"depth"`
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+}
+
+// Metrics are a Record's metrics.
+type Metrics struct {
+	Buckets []int `json:"buckets"`
+	Count   int   `json:"count"`
+	Score   int   `json:"score"`
+}
@@ -0,0 +1,36 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestRoundTrip checks that the serializer re-encodes a document, as the
+// json and echo workloads do, to the bytes encoding/json made of it:
+// otherwise the variants' results could not be compared.
+func TestRoundTrip(t *testing.T) {
+	in := []byte(`{"records":[{"active":true,"children":[{"depth":0,"id":0,"label":"child-0-0"}],"id":0,` +
+		`"metrics":{"buckets":[0,0,0,0],"count":0,"score":0},"name":"record-\u003c0\u003e","tags":["t0","t0","t0"]}],"version":1}`)
+	doc := NewDocument()
+	if err := Unmarshal(in, doc); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, in) {
+		t.Errorf("%s round trip = %s", Name, out)
+	}
+	var generic any
+	if err := Unmarshal(in, &generic); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := Marshal(generic); err != nil || !bytes.Equal(out, in) {
+		t.Errorf("%s generic round trip = %s, %v", Name, out, err)
+	}
+	if !json.Valid(out) {
+		t.Errorf("%s encoded invalid JSON", Name)
+	}
+}
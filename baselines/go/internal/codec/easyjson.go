@@ -0,0 +1,34 @@
+//go:build easyjson && !jsoniter
+
+package codec
+
+import (
+	"encoding/json"
+
+	"github.com/mailru/easyjson"
+)
+
+// Name is the serializer the baseline was built with.
+const Name = "easyjson"
+
+// Marshal encodes v with its generated code, or with encoding/json if it
+// has none.
+func Marshal(v any) ([]byte, error) {
+	if m, ok := v.(easyjson.Marshaler); ok {
+		return easyjson.Marshal(m)
+	}
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes data into v with its generated code, or with
+// encoding/json if it has none.
+func Unmarshal(data []byte, v any) error {
+	if u, ok := v.(easyjson.Unmarshaler); ok {
+		return easyjson.Unmarshal(data, u)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// NewDocument returns what the json workload decodes its document into:
+// a *Document, since easyjson generates code for named types only.
+func NewDocument() any { return new(Document) }
@@ -0,0 +1,24 @@
+//go:build jsoniter && !easyjson
+
+package codec
+
+import jsoniter "github.com/json-iterator/go"
+
+// Name is the serializer the baseline was built with.
+const Name = "jsoniter"
+
+// The standard-library-compatible configuration sorts map keys and
+// escapes HTML as encoding/json does, so every variant encodes a document
+// to the same bytes.
+var api = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// Marshal encodes v.
+func Marshal(v any) ([]byte, error) { return api.Marshal(v) }
+
+// Unmarshal decodes data into v.
+func Unmarshal(data []byte, v any) error { return api.Unmarshal(data, v) }
+
+// NewDocument returns what the json workload decodes its document into:
+// a generic value, which json-iterator decodes without building reflection
+// paths per call.
+func NewDocument() any { return new(any) }
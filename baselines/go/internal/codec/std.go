@@ -0,0 +1,18 @@
+//go:build !jsoniter && !easyjson
+
+package codec
+
+import "encoding/json"
+
+// Name is the serializer the baseline was built with.
+const Name = "encoding/json"
+
+// Marshal encodes v.
+func Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal decodes data into v.
+func Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// NewDocument returns what the json workload decodes its document into:
+// a generic value, decoded by reflection.
+func NewDocument() any { return new(any) }
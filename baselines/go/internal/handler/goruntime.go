@@ -0,0 +1,103 @@
+//go:build !tinygo
+
+package handler
+
+import (
+	"math"
+	"os"
+	"runtime/metrics"
+
+	"lambdaperf/pkg/lambdalog"
+)
+
+// The runtime/metrics read around each invocation when
+// lambdalog.RuntimeMetricsEnv is set. Reading them does not stop the world.
+const (
+	metricAllocBytes = "/gc/heap/allocs:bytes"
+	metricAllocs     = "/gc/heap/allocs:objects"
+	metricGCCycles   = "/gc/cycles/total:gc-cycles"
+	metricGCPauses   = "/sched/pauses/total/gc:seconds"
+	metricGoroutines = "/sched/goroutines:goroutines"
+	metricHeapBytes  = "/memory/classes/heap/objects:bytes"
+)
+
+// sampleRuntime is read once: the environment of a function only changes
+// with a deploy, which starts new execution environments.
+var sampleRuntime = os.Getenv(lambdalog.RuntimeMetricsEnv) == "1"
+
+// runtimeSample is one reading of the metrics, in the order of the names
+// above.
+type runtimeSample []metrics.Sample
+
+func readRuntime() runtimeSample {
+	s := runtimeSample{
+		{Name: metricAllocBytes},
+		{Name: metricAllocs},
+		{Name: metricGCCycles},
+		{Name: metricGCPauses},
+		{Name: metricGoroutines},
+		{Name: metricHeapBytes},
+	}
+	metrics.Read(s)
+	return s
+}
+
+// since is what the runtime did between before and s.
+func (s runtimeSample) since(before runtimeSample) *lambdalog.GoRuntime {
+	return &lambdalog.GoRuntime{
+		AllocBytes: delta(before[0], s[0]),
+		Allocs:     delta(before[1], s[1]),
+		GCCycles:   delta(before[2], s[2]),
+		GCPauseMS:  1000 * pauses(before[3], s[3]),
+		Goroutines: value(s[4]),
+		HeapBytes:  value(s[5]),
+	}
+}
+
+// value is a scalar metric as a float, zero for one this Go version does
+// not support.
+func value(s metrics.Sample) float64 {
+	switch s.Value.Kind() {
+	case metrics.KindUint64:
+		return float64(s.Value.Uint64())
+	case metrics.KindFloat64:
+		return s.Value.Float64()
+	}
+	return 0
+}
+
+func delta(before, after metrics.Sample) float64 {
+	return value(after) - value(before)
+}
+
+// pauses estimates the total seconds of the pauses recorded between two
+// readings of a pause histogram, counting each at its bucket's midpoint,
+// or at its finite bound for the open-ended buckets.
+func pauses(before, after metrics.Sample) float64 {
+	if before.Value.Kind() != metrics.KindFloat64Histogram || after.Value.Kind() != metrics.KindFloat64Histogram {
+		return 0
+	}
+	b, a := before.Value.Float64Histogram(), after.Value.Float64Histogram()
+	if len(a.Counts) != len(b.Counts) {
+		return 0
+	}
+	var total float64
+	for i, n := range a.Counts {
+		n -= b.Counts[i]
+		if n == 0 {
+			continue
+		}
+		lo, hi := a.Buckets[i], a.Buckets[i+1]
+		var mid float64
+		switch {
+		case math.IsInf(lo, -1):
+			mid = hi
+		case math.IsInf(hi, 1):
+			mid = lo
+		default:
+			mid = (lo + hi) / 2
+		}
+		total += float64(n) * mid
+	}
+	return total
+}
@@ -0,0 +1,40 @@
+//go:build tinygo
+
+package handler
+
+import (
+	"os"
+	"runtime"
+
+	"lambdaperf/pkg/lambdalog"
+)
+
+// TinyGo has no runtime/metrics, so a TinyGo build samples
+// runtime.ReadMemStats, which its collector keeps, instead. A reading
+// stops the world, unlike a Go build's.
+var sampleRuntime = os.Getenv(lambdalog.RuntimeMetricsEnv) == "1"
+
+// memSample is one reading of the memory statistics, and runtimeSample
+// what the handler holds one as: nil when it did not sample.
+type memSample struct{ runtime.MemStats }
+
+type runtimeSample = *memSample
+
+func readRuntime() runtimeSample {
+	s := &memSample{}
+	runtime.ReadMemStats(&s.MemStats)
+	return s
+}
+
+// since is what the runtime did between before and s. GCPauseMS stays
+// zero where TinyGo's collector records no pause times.
+func (s *memSample) since(before *memSample) *lambdalog.GoRuntime {
+	return &lambdalog.GoRuntime{
+		AllocBytes: float64(s.TotalAlloc - before.TotalAlloc),
+		Allocs:     float64(s.Mallocs - before.Mallocs),
+		GCCycles:   float64(s.NumGC - before.NumGC),
+		GCPauseMS:  float64(s.PauseTotalNs-before.PauseTotalNs) / 1e6,
+		Goroutines: float64(runtime.NumGoroutine()),
+		HeapBytes:  float64(s.HeapAlloc),
+	}
+}
@@ -0,0 +1,392 @@
+// Package handler is what the Go baselines share: a workload supplies the
+// function computing its result, and Start runs it as the Lambda handler.
+// Start responds with the {"statusCode", "body"} object ruchy-bench reads
+// results from, turns errors into function errors or error statuses, logs
+// the pkg/lambdalog invocation line and reports the time spent decoding
+// the event, SDK time and, for workloads that call Processed, Trace or
+// TimeWrite and TimeRead, the bytes processed, what their HTTP requests
+// cost in connections and their file I/O throughput. Workloads with
+// Inputs read them from the payload, so {"n": 30} sizes a run without a
+// rebuild. Deployed with lambdalog.HistogramEnv, Start reruns the
+// workload for a fixed time in every invocation and reports a histogram
+// of the runs. Built with the pprof tag, Start also profiles every invocation;
+// see pkg/profiles. Built with TinyGo, Start speaks the Runtime API itself
+// rather than through aws-lambda-go; see start_tinygo.go.
+//
+// main.go and main-runtimeapi.go do not use it: the first is lambda-perf's
+// handler verbatim and the second links nothing beyond net/http.
+package handler
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"lambdaperf/internal/codec"
+	"lambdaperf/pkg/lambdalog"
+)
+
+// Params are a workload's fixed inputs.
+type Params = lambdalog.Params
+
+// NoEvent is the event type of workloads that ignore the payload; it
+// accepts any JSON.
+type NoEvent = json.RawMessage
+
+// Input is an integer the payload may set, such as the n of {"n": 30}.
+// The bounds keep a request from running the function out of time or
+// memory; they match the workload's inputs in benchmarks/manifest.yaml.
+type Input struct {
+	Default, Min, Max int
+}
+
+// Args is the event type of workloads with Inputs: every input's value,
+// from the payload or defaulted.
+type Args map[string]int
+
+// Workload is one baseline, run by Start.
+type Workload[E any] struct {
+	// Name is the workload name, the <workload> of main-<workload>.go.
+	Name string
+	// Params are logged with every invocation, along with the Args.
+	Params Params
+	// Inputs are the payload fields a workload with the Args event type
+	// reads, by name. A payload with any other field, or a value that is
+	// not an integer within bounds, is refused with status 400.
+	Inputs map[string]Input
+	// ContentType, when set, is sent as the Content-Type header: API
+	// Gateway and function URL events expect one.
+	ContentType string
+	// Runtime, when set, is reported as the response's Runtime in place
+	// of the handler's own: wasmrt reports the runtime of the modules it
+	// runs rather than that of its Go bootstrap.
+	Runtime string
+	// Run returns the response body for the event.
+	Run func(ctx context.Context, event E) (string, error)
+}
+
+// Response is what Start's handler returns. Its shape is also an API
+// Gateway proxy and function URL response.
+type Response struct {
+	StatusCode int               `json:"statusCode"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+	// Runtime is "go", or "tinygo" in a TinyGo build, unless the
+	// workload sets its own: ruchy-bench's canary invoke checks it
+	// against the runtime it deployed; see pkg/canary.
+	Runtime string `json:"runtime"`
+	// DecodeMS is the time spent decoding the payload into the
+	// workload's event type, picked up by ruchy-bench as the decode_ms
+	// metric.
+	DecodeMS float64 `json:"decode_ms,omitempty"`
+	// SDKMS is the time spent in calls wrapped by Time, picked up by
+	// ruchy-bench as the sdk_ms metric.
+	SDKMS float64 `json:"sdk_ms,omitempty"`
+	// Bytes is the amount of data reported with Processed, which
+	// ruchy-bench divides by the duration for the throughput_mb_s metric.
+	Bytes int64 `json:"bytes,omitempty"`
+	// HTTP is what the requests made with Trace did, if there were any.
+	HTTP *HTTP `json:"http,omitempty"`
+	// IO is the throughput of the file I/O timed with TimeWrite and
+	// TimeRead, if there was any.
+	IO *IO `json:"io,omitempty"`
+	// GoRuntime is what the Go runtime did during the invocation, when
+	// lambdalog.RuntimeMetricsEnv is set; ruchy-bench records it as the
+	// go_* metrics.
+	GoRuntime *lambdalog.GoRuntime `json:"go_runtime,omitempty"`
+	// GoInit splits the Go side of the cold start on an environment's
+	// first invocation; ruchy-bench records it as the go_init_* and
+	// go_first_decode metrics.
+	GoInit *lambdalog.GoInit `json:"go_init,omitempty"`
+	// Host is the hardware the execution environment runs on, which
+	// ruchy-bench groups samples by; see lambdalog.Host.
+	Host *lambdalog.Host `json:"host,omitempty"`
+	// Histogram holds the times of the workload's runs when
+	// lambdalog.HistogramEnv is set; ruchy-bench records it as the
+	// iteration_* metrics.
+	Histogram *lambdalog.Histogram `json:"histogram,omitempty"`
+}
+
+// StatusError is an error Start answers with a response of its status
+// and message rather than a function error: the request was understood
+// and refused.
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string { return fmt.Sprintf("status %d: %s", e.Code, e.Message) }
+
+// Status returns a StatusError.
+func Status(code int, format string, args ...any) error {
+	return &StatusError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Result formats a result the way every workload reports it:
+// name(input)=output.
+func Result(name string, input, output any) string {
+	return fmt.Sprintf("%s(%v)=%v", name, input, output)
+}
+
+type sdkKey struct{}
+
+// Time runs call, adding its duration to the invocation's SDK time. ctx
+// must be the one Run was given.
+func Time(ctx context.Context, call func() error) error {
+	start := time.Now()
+	err := call()
+	if total, ok := ctx.Value(sdkKey{}).(*time.Duration); ok {
+		*total += time.Since(start)
+	}
+	return err
+}
+
+type bytesKey struct{}
+
+// Processed adds n to the bytes the invocation reports having processed.
+// ctx must be the one Run was given.
+func Processed(ctx context.Context, n int) {
+	if total, ok := ctx.Value(bytesKey{}).(*int64); ok {
+		*total += int64(n)
+	}
+}
+
+// HTTP is what an invocation's traced requests did: how many were made,
+// how many new connections they opened rather than reusing one left idle
+// by an earlier request or invocation, and the time spent in the TLS
+// handshakes of those new connections. Like lambdalog.GoRuntime its field
+// names are results.Metric* names.
+type HTTP struct {
+	Requests int     `json:"http_requests"`
+	NewConns int     `json:"http_new_conns"`
+	TLSMS    float64 `json:"http_tls_ms"`
+}
+
+type httpKey struct{}
+
+// httpStats is HTTP as traces add to it, from whichever goroutines the
+// transport runs them on.
+type httpStats struct {
+	mu  sync.Mutex
+	sum HTTP
+	tls time.Duration
+}
+
+// report returns the invocation's HTTP, nil if it traced no requests.
+func (s *httpStats) report() *HTTP {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sum.Requests == 0 {
+		return nil
+	}
+	h := s.sum
+	h.TLSMS = float64(s.tls.Microseconds()) / 1000
+	return &h
+}
+
+// IO is the throughput of an invocation's timed file I/O: MB (2^20
+// bytes) written or read per second spent writing or reading. Like HTTP
+// its field names are results.Metric* names.
+type IO struct {
+	WriteMBs float64 `json:"write_mb_s,omitempty"`
+	ReadMBs  float64 `json:"read_mb_s,omitempty"`
+}
+
+type ioKey struct{}
+
+// ioStats is IO as TimeWrite and TimeRead add to it.
+type ioStats struct {
+	written, read    int64
+	writing, reading time.Duration
+}
+
+// TimeWrite runs call, which writes n bytes, adding them and its duration
+// to the invocation's write throughput. ctx must be the one Run was given.
+func TimeWrite(ctx context.Context, n int64, call func() error) error {
+	return timeIO(ctx, call, func(s *ioStats, d time.Duration) { s.written, s.writing = s.written+n, s.writing+d })
+}
+
+// TimeRead is TimeWrite for reads.
+func TimeRead(ctx context.Context, n int64, call func() error) error {
+	return timeIO(ctx, call, func(s *ioStats, d time.Duration) { s.read, s.reading = s.read+n, s.reading+d })
+}
+
+func timeIO(ctx context.Context, call func() error, add func(*ioStats, time.Duration)) error {
+	start := time.Now()
+	err := call()
+	if stats, ok := ctx.Value(ioKey{}).(*ioStats); ok && err == nil {
+		add(stats, time.Since(start))
+	}
+	return err
+}
+
+// report returns the invocation's IO, nil if it timed none.
+func (s *ioStats) report() *IO {
+	if s.writing == 0 && s.reading == 0 {
+		return nil
+	}
+	rate := func(n int64, d time.Duration) float64 {
+		if d == 0 {
+			return 0
+		}
+		return float64(n) / (1 << 20) / d.Seconds()
+	}
+	return &IO{WriteMBs: rate(s.written, s.writing), ReadMBs: rate(s.read, s.reading)}
+}
+
+// initialized is taken while the handler package initializes: after the
+// Go runtime and the packages it imports, such as encoding/json and
+// aws-lambda-go, and before package main. time.Now readings carry the
+// monotonic clock, so differences from it are unaffected by clock steps.
+var initialized = time.Now()
+
+// handler wraps Run into the Lambda handler. The invocation line is
+// logged around Run, so refusals with a StatusError are logged as errors.
+// The runtime, when sampled, is read on either side of the whole
+// invocation, event decoding included. The handler's first call, which
+// in Lambda is the first of its execution environment, also reports
+// GoInit. Every call reports its event decoding, timed around the
+// unmarshal into the event type: aws-lambda-go hands the handler the
+// payload as raw bytes, so that unmarshal is all the reflection-based
+// decoding there is. The host is read once, with the handler built ahead
+// of the first event, which keeps procfs out of every invocation's
+// duration. With a histogram budget the workload's reruns are in the
+// duration too; see run. In a pprof build the profiles are uploaded after the
+// invocation line is logged, which keeps the upload out of the logged
+// duration.
+func (w Workload[E]) handler() func(context.Context, json.RawMessage) (Response, error) {
+	var called atomic.Bool
+	host := lambdalog.ReadHost()
+	return func(ctx context.Context, payload json.RawMessage) (Response, error) {
+		entered := time.Now()
+		var before runtimeSample
+		if sampleRuntime {
+			before = readRuntime()
+		}
+		start := time.Now()
+		var (
+			sdk, decode time.Duration
+			processed   int64
+			requests    httpStats
+			files       ioStats
+		)
+		ctx = context.WithValue(context.WithValue(ctx, sdkKey{}, &sdk), bytesKey{}, &processed)
+		ctx = context.WithValue(context.WithValue(ctx, httpKey{}, &requests), ioKey{}, &files)
+		var hist *lambdalog.Histogram
+		if histogramBudget > 0 {
+			hist = &lambdalog.Histogram{}
+			ctx = context.WithValue(ctx, histogramKey{}, hist)
+		}
+		finish := profile(ctx)
+		body, params, err := w.invoke(context.WithValue(ctx, decodeKey{}, &decode), payload)
+		upload := finish()
+		entry := lambdalog.Entry{Workload: w.Name, Params: params, DecodeMS: float64(decode) / float64(time.Millisecond), Host: host}
+		if before != nil {
+			entry.Go = readRuntime().since(before)
+		}
+		if !called.Swap(true) {
+			entry.Init = &lambdalog.GoInit{
+				ToHandlerMS: float64(entered.Sub(initialized).Microseconds()) / 1000,
+				DecodeMS:    float64(decode.Microseconds()) / 1000,
+			}
+		}
+		lambdalog.Log(ctx, entry, start, err)
+		upload()
+		resp := Response{StatusCode: 200, Body: body, Runtime: cmp.Or(w.Runtime, runtimeName), DecodeMS: entry.DecodeMS, SDKMS: float64(sdk.Microseconds()) / 1000, Bytes: processed, HTTP: requests.report(), IO: files.report(), GoRuntime: entry.Go, GoInit: entry.Init, Host: host, Histogram: hist}
+		var status *StatusError
+		switch {
+		case errors.As(err, &status):
+			resp.StatusCode, resp.Body = status.Code, status.Message
+		case err != nil:
+			return Response{}, err
+		}
+		if w.ContentType != "" {
+			resp.Headers = map[string]string{"Content-Type": w.ContentType}
+		}
+		return resp, nil
+	}
+}
+
+type decodeKey struct{}
+
+// invoke decodes the event and runs the workload, returning the params
+// to log. The decoding time is added to the duration in ctx under
+// decodeKey.
+func (w Workload[E]) invoke(ctx context.Context, payload json.RawMessage) (string, Params, error) {
+	var event E
+	decoded := func(start time.Time) {
+		if total, ok := ctx.Value(decodeKey{}).(*time.Duration); ok {
+			*total += time.Since(start)
+		}
+	}
+	start := time.Now()
+	if args, ok := any(&event).(*Args); ok {
+		var err error
+		*args, err = w.args(payload)
+		decoded(start)
+		if err != nil {
+			return "", w.Params, err
+		}
+		params := maps.Clone(w.Params)
+		if params == nil {
+			params = Params{}
+		}
+		for name, v := range *args {
+			params[name] = v
+		}
+		body, err := w.run(ctx, event)
+		return body, params, err
+	}
+	if len(payload) > 0 {
+		err := codec.Unmarshal(payload, &event)
+		decoded(start)
+		if err != nil {
+			return "", w.Params, fmt.Errorf("decode event: %w", err)
+		}
+	}
+	body, err := w.run(ctx, event)
+	return body, w.Params, err
+}
+
+// args resolves the Inputs from payload. An empty or null payload runs
+// every default.
+func (w Workload[E]) args(payload json.RawMessage) (Args, error) {
+	var fields map[string]json.RawMessage
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return nil, Status(400, "payload is not a JSON object")
+		}
+	}
+	for name := range fields {
+		if _, ok := w.Inputs[name]; !ok {
+			return nil, Status(400, "unknown input %q", name)
+		}
+	}
+	args := Args{}
+	for name, in := range w.Inputs {
+		raw, ok := fields[name]
+		if !ok {
+			args[name] = in.Default
+			continue
+		}
+		// A null leaves v alone rather than failing, so it is refused by
+		// name.
+		var v float64
+		if err := json.Unmarshal(raw, &v); err != nil || v != math.Trunc(v) || bytes.Equal(raw, []byte("null")) {
+			return nil, Status(400, "%s must be an integer", name)
+		}
+		if v < float64(in.Min) || v > float64(in.Max) {
+			return nil, Status(400, "%s must be between %d and %d", name, in.Min, in.Max)
+		}
+		args[name] = int(v)
+	}
+	return args, nil
+}
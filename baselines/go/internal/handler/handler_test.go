@@ -0,0 +1,251 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"lambdaperf/pkg/lambdalog"
+)
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	w := Workload[NoEvent]{
+		Name:   "fibonacci",
+		Params: Params{"n": 35},
+		Run: func(ctx context.Context, _ NoEvent) (string, error) {
+			return Result("fibonacci", 35, 9227465), nil
+		},
+	}
+	h := w.handler()
+	resp, err := h(ctx, nil)
+	if err != nil || resp.StatusCode != 200 || resp.Body != "fibonacci(35)=9227465" || resp.Headers != nil {
+		t.Errorf("response = %+v, %v", resp, err)
+	}
+	if resp.GoInit == nil || resp.GoInit.ToHandlerMS <= 0 {
+		t.Errorf("first response reports init %+v", resp.GoInit)
+	}
+	// Only the first invocation reports init; every one reports the
+	// host, which depends on the machine running the test.
+	resp, _ = h(ctx, nil)
+	if !reflect.DeepEqual(resp.Host, lambdalog.ReadHost()) {
+		t.Errorf("host %+v, want %+v", resp.Host, lambdalog.ReadHost())
+	}
+	resp.Host = nil
+	data, _ := json.Marshal(resp)
+	if string(data) != `{"statusCode":200,"body":"fibonacci(35)=9227465","runtime":"go"}` {
+		t.Errorf("encoded as %s", data)
+	}
+	w.Runtime = "wasm"
+	if resp, _ := w.handler()(ctx, nil); resp.Runtime != "wasm" {
+		t.Errorf("runtime %q, want the workload's", resp.Runtime)
+	}
+
+	w.Run = func(context.Context, NoEvent) (string, error) { return "", Status(400, "no %s records", "S3") }
+	w.ContentType = "text/plain"
+	if resp, err := w.handler()(ctx, nil); err != nil || resp.StatusCode != 400 || resp.Body != "no S3 records" ||
+		resp.Headers["Content-Type"] != "text/plain" {
+		t.Errorf("refusal = %+v, %v", resp, err)
+	}
+
+	w.Run = func(context.Context, NoEvent) (string, error) { return "", errors.New("access denied") }
+	if _, err := w.handler()(ctx, nil); err == nil {
+		t.Error("error not returned as a function error")
+	}
+}
+
+func TestTime(t *testing.T) {
+	w := Workload[NoEvent]{Name: "dynamodb", Run: func(ctx context.Context, _ NoEvent) (string, error) {
+		err := Time(ctx, func() error {
+			time.Sleep(2 * time.Millisecond)
+			return nil
+		})
+		return "ok", err
+	}}
+	resp, err := w.handler()(context.Background(), nil)
+	if err != nil || resp.SDKMS < 2 {
+		t.Errorf("response = %+v, %v; want at least 2 ms of SDK time", resp, err)
+	}
+	if err := Time(context.Background(), func() error { return errors.New("throttled") }); err == nil {
+		t.Error("Time swallowed the call's error")
+	}
+}
+
+func TestTrace(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	client := srv.Client()
+	w := Workload[NoEvent]{Name: "httpclient", Run: func(ctx context.Context, _ NoEvent) (string, error) {
+		for range 3 {
+			req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, Trace(ctx)), http.MethodGet, srv.URL, nil)
+			if err != nil {
+				return "", err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return "", err
+			}
+			// Reading to EOF hands the connection back before the next
+			// request asks for one.
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		return "ok", nil
+	}}
+	h := w.handler()
+	resp, err := h(context.Background(), nil)
+	if err != nil || resp.HTTP == nil || resp.HTTP.Requests != 3 || resp.HTTP.NewConns != 1 || resp.HTTP.TLSMS <= 0 {
+		t.Fatalf("response = %+v, %v; want 3 requests on 1 new connection", resp.HTTP, err)
+	}
+	// The next invocation reuses the idle connection.
+	if resp, err = h(context.Background(), nil); err != nil || resp.HTTP.NewConns != 0 || resp.HTTP.TLSMS != 0 {
+		t.Errorf("second response = %+v, %v; want the connection reused", resp.HTTP, err)
+	}
+
+	w.Run = func(context.Context, NoEvent) (string, error) { return "ok", nil }
+	if resp, _ := w.handler()(context.Background(), nil); resp.HTTP != nil {
+		t.Errorf("untraced invocation reports %+v", resp.HTTP)
+	}
+}
+
+func TestTimeIO(t *testing.T) {
+	w := Workload[NoEvent]{Name: "tmpio", Run: func(ctx context.Context, _ NoEvent) (string, error) {
+		if err := TimeWrite(ctx, 8<<20, func() error { time.Sleep(10 * time.Millisecond); return nil }); err != nil {
+			return "", err
+		}
+		if err := TimeRead(ctx, 1<<20, func() error { return errors.New("short read") }); err == nil {
+			return "", errors.New("TimeRead swallowed the call's error")
+		}
+		return "ok", nil
+	}}
+	resp, err := w.handler()(context.Background(), nil)
+	if err != nil || resp.IO == nil || resp.IO.WriteMBs <= 0 || resp.IO.WriteMBs > 800 || resp.IO.ReadMBs != 0 {
+		t.Fatalf("response = %+v, %v; want 8 MB written in at least 10 ms and the failed read left out", resp.IO, err)
+	}
+
+	w.Run = func(context.Context, NoEvent) (string, error) { return "ok", nil }
+	if resp, _ := w.handler()(context.Background(), nil); resp.IO != nil {
+		t.Errorf("invocation without timed I/O reports %+v", resp.IO)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	w := Workload[Args]{
+		Name:   "fibonacci",
+		Inputs: map[string]Input{"n": {Default: 35, Min: 0, Max: 40}},
+		Run: func(_ context.Context, args Args) (string, error) {
+			return Result("fibonacci", args["n"], "?"), nil
+		},
+	}
+	for payload, want := range map[string]string{
+		``:            "fibonacci(35)=?",
+		`{}`:          "fibonacci(35)=?",
+		`null`:        "fibonacci(35)=?",
+		`{"n": 30}`:   "fibonacci(30)=?",
+		`{"n": 3e1}`:  "fibonacci(30)=?",
+		`{"n": 30.0}`: "fibonacci(30)=?",
+	} {
+		resp, err := w.handler()(context.Background(), json.RawMessage(payload))
+		if err != nil || resp.StatusCode != 200 || resp.Body != want {
+			t.Errorf("payload %q: response = %+v, %v; want %q", payload, resp, err, want)
+		}
+	}
+	for payload, want := range map[string]string{
+		`[30]`:        "payload is not a JSON object",
+		`{"n": 41}`:   "n must be between 0 and 40",
+		`{"n": -1}`:   "n must be between 0 and 40",
+		`{"n": 2.5}`:  "n must be an integer",
+		`{"n": "30"}`: "n must be an integer",
+		`{"n": null}`: "n must be an integer",
+		`{"N": 30}`:   `unknown input "N"`,
+	} {
+		resp, err := w.handler()(context.Background(), json.RawMessage(payload))
+		if err != nil || resp.StatusCode != 400 || resp.Body != want {
+			t.Errorf("payload %q: response = %+v, %v; want 400 %q", payload, resp, err, want)
+		}
+	}
+}
+
+func TestInitSplit(t *testing.T) {
+	type event struct{ Records []map[string]string }
+	w := Workload[event]{Name: "batch", Run: func(_ context.Context, e event) (string, error) {
+		return Result("records", "", len(e.Records)), nil
+	}}
+	payload := json.RawMessage(`{"Records":[` + strings.Repeat(`{"body":"hello","messageId":"m"},`, 999) + `{}]}`)
+	h := w.handler()
+	resp, err := h(context.Background(), payload)
+	if err != nil || resp.Body != "records()=1000" || resp.GoInit == nil || resp.GoInit.DecodeMS <= 0 {
+		t.Fatalf("first response = %+v (init %+v), %v", resp, resp.GoInit, err)
+	}
+	if resp, _ := h(context.Background(), payload); resp.GoInit != nil || resp.DecodeMS <= 0 {
+		t.Errorf("second invocation reports init %+v, decode %g ms", resp.GoInit, resp.DecodeMS)
+	}
+}
+
+func TestRuntimeSampling(t *testing.T) {
+	defer func(on bool) { sampleRuntime = on }(sampleRuntime)
+	sampleRuntime = true
+	var sink [][]byte
+	w := Workload[NoEvent]{Name: "alloc", Run: func(context.Context, NoEvent) (string, error) {
+		for range 1000 {
+			sink = append(sink, make([]byte, 1024))
+		}
+		runtime.GC()
+		return "ok", nil
+	}}
+	resp, err := w.handler()(context.Background(), nil)
+	g := resp.GoRuntime
+	if err != nil || g == nil {
+		t.Fatalf("response = %+v, %v; want Go runtime stats", resp, err)
+	}
+	if g.AllocBytes < 1000*1024 || g.Allocs < 1000 || g.GCCycles < 1 || g.Goroutines < 1 || g.HeapBytes <= 0 {
+		t.Errorf("stats = %+v", *g)
+	}
+	if g.GCPauseMS <= 0 {
+		t.Errorf("GC pause = %g ms after a forced GC", g.GCPauseMS)
+	}
+
+	sampleRuntime = false
+	if resp, _ := w.handler()(context.Background(), nil); resp.GoRuntime != nil {
+		t.Error("runtime sampled with sampling off")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	defer func(d time.Duration) { histogramBudget = d }(histogramBudget)
+	histogramBudget = 20 * time.Millisecond
+	runs := 0
+	w := Workload[NoEvent]{Name: "spin", Run: func(ctx context.Context, _ NoEvent) (string, error) {
+		runs++
+		Processed(ctx, 100)
+		time.Sleep(time.Millisecond)
+		return "ok", nil
+	}}
+	resp, err := w.handler()(context.Background(), nil)
+	h := resp.Histogram
+	if err != nil || h == nil {
+		t.Fatalf("response = %+v, %v; want a histogram", resp, err)
+	}
+	if h.Count != int64(runs) || runs < 2 || h.BudgetMS != 20 || h.QuantileMS(0.5) < 1 {
+		t.Errorf("%d runs, histogram of %d with budget %g ms and median %g ms", runs, h.Count, h.BudgetMS, h.QuantileMS(0.5))
+	}
+	if resp.Body != "ok" || resp.Bytes != 100 {
+		t.Errorf("body %q, bytes %d; want the first run's", resp.Body, resp.Bytes)
+	}
+
+	histogramBudget = 0
+	if resp, _ := w.handler()(context.Background(), nil); resp.Histogram != nil {
+		t.Error("histogram recorded without a budget")
+	}
+}
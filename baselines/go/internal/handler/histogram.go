@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"lambdaperf/pkg/lambdalog"
+)
+
+// histogramBudget is how long each invocation reruns the workload for
+// its histogram, zero when lambdalog.HistogramEnv is unset. Like
+// sampleRuntime it is read once.
+var histogramBudget = func() time.Duration {
+	ms, err := strconv.ParseFloat(os.Getenv(lambdalog.HistogramEnv), 64)
+	if err != nil || ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}()
+
+type histogramKey struct{}
+
+// run runs the workload once and, when ctx holds a histogram, reruns it
+// on the same event until histogramBudget has passed since the first
+// run started, recording every run's time. The first run's body and
+// error are what the invocation responds with; the reruns stop at the
+// first that fails. Reruns report their SDK time, bytes, HTTP and I/O to
+// totals of their own, which are dropped, so the response's are still
+// one run's.
+func (w Workload[E]) run(ctx context.Context, event E) (string, error) {
+	h, ok := ctx.Value(histogramKey{}).(*lambdalog.Histogram)
+	if !ok {
+		return w.Run(ctx, event)
+	}
+	start := time.Now()
+	body, err := w.Run(ctx, event)
+	h.Record(time.Since(start))
+	if err != nil {
+		return body, err
+	}
+	rerun := context.WithValue(context.WithValue(ctx, sdkKey{}, new(time.Duration)), bytesKey{}, new(int64))
+	rerun = context.WithValue(context.WithValue(rerun, httpKey{}, &httpStats{}), ioKey{}, &ioStats{})
+	for time.Since(start) < histogramBudget {
+		began := time.Now()
+		if _, err := w.Run(rerun, event); err != nil {
+			break
+		}
+		h.Record(time.Since(began))
+	}
+	h.BudgetMS = float64(histogramBudget.Microseconds()) / 1000
+	return body, nil
+}
@@ -0,0 +1,104 @@
+//go:build pprof
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"lambdaperf/pkg/profiles"
+)
+
+// Only handlers built with the pprof tag can profile: runtime/pprof and
+// the S3 client would otherwise weigh on every baseline's package size and
+// cold start. Like the runtime sample, the bucket is read once.
+var profileBucket = os.Getenv(profiles.Env)
+
+var profileClient = sync.OnceValues(func() (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg), nil
+})
+
+// profile starts a CPU profile of the invocation in /tmp when
+// profiles.Env names a bucket, and finish stops it. The upload finish
+// returns writes a heap profile beside it, sends both to the bucket and
+// removes them. Failures are logged rather than failing the invocation.
+func profile(ctx context.Context) (finish func() (upload func())) {
+	none := func() (upload func()) { return func() {} }
+	if profileBucket == "" {
+		return none
+	}
+	id := "unknown"
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		id = lc.AwsRequestID
+	}
+	dir := filepath.Join(os.TempDir(), "pprof", id)
+	path := func(kind string) string { return filepath.Join(dir, kind+".pprof") }
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		logProfile(err)
+		return none
+	}
+	cpu, err := os.Create(path(profiles.CPU))
+	if err == nil {
+		err = pprof.StartCPUProfile(cpu)
+	}
+	if err != nil {
+		logProfile(err)
+		return none
+	}
+	return func() (upload func()) {
+		pprof.StopCPUProfile()
+		cpu.Close()
+		return func() {
+			defer os.RemoveAll(dir)
+			// The heap profile is as of the last collection; collect now
+			// so it shows what the invocation left live.
+			runtime.GC()
+			heap, err := os.Create(path(profiles.Heap))
+			if err == nil {
+				err = pprof.WriteHeapProfile(heap)
+				heap.Close()
+			}
+			if err != nil {
+				logProfile(err)
+			}
+			client, err := profileClient()
+			if err != nil {
+				logProfile(err)
+				return
+			}
+			for _, kind := range profiles.Kinds {
+				if err := put(ctx, client, profiles.Key(lambdacontext.FunctionName, id, kind), path(kind)); err != nil {
+					logProfile(err)
+				}
+			}
+		}
+	}
+}
+
+func put(ctx context.Context, client *s3.Client, key, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String(profileBucket), Key: aws.String(key), Body: f})
+	return err
+}
+
+func logProfile(err error) {
+	fmt.Fprintf(os.Stderr, "pprof: %v\n", err)
+}
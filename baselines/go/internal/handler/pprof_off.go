@@ -0,0 +1,10 @@
+//go:build !pprof
+
+package handler
+
+import "context"
+
+// profile does nothing without the pprof build tag; see pprof.go.
+func profile(context.Context) (finish func() (upload func())) {
+	return func() (upload func()) { return func() {} }
+}
@@ -0,0 +1,246 @@
+//go:build tinygo
+
+package handler
+
+/*
+#include <netdb.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/socket.h>
+#include <unistd.h>
+*/
+import "C"
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+)
+
+const apiVersion = "2018-06-01"
+
+// runtimeAPI is a Lambda Runtime API client for TinyGo builds: HTTP/1.1
+// over one kept-alive connection, opened again after a failed request.
+type runtimeAPI struct {
+	host, port string
+	c          conn
+	r          *bufio.Reader
+}
+
+// invocation is an event from the Runtime API.
+type invocation struct {
+	id, arn  string
+	deadline time.Time
+	payload  []byte
+}
+
+// next waits for the next event.
+func (a *runtimeAPI) next() (invocation, error) {
+	status, header, body, err := a.do("GET", "/invocation/next", nil)
+	if err != nil {
+		return invocation{}, err
+	}
+	if status != 200 {
+		return invocation{}, fmt.Errorf("status %d: %s", status, body)
+	}
+	inv := invocation{
+		id:      header["lambda-runtime-aws-request-id"],
+		arn:     header["lambda-runtime-invoked-function-arn"],
+		payload: body,
+	}
+	if inv.id == "" {
+		return invocation{}, errors.New("no Lambda-Runtime-Aws-Request-Id header")
+	}
+	if ms, err := strconv.ParseInt(header["lambda-runtime-deadline-ms"], 10, 64); err == nil {
+		inv.deadline = time.UnixMilli(ms)
+	}
+	return inv, nil
+}
+
+// respond posts the response to invocation id.
+func (a *runtimeAPI) respond(id string, body []byte) error {
+	return a.post("/invocation/"+id+"/response", body)
+}
+
+// fail reports err as invocation id's function error, in the shape
+// aws-lambda-go reports one.
+func (a *runtimeAPI) fail(id string, err error) error {
+	body, _ := json.Marshal(map[string]string{"errorMessage": err.Error(), "errorType": fmt.Sprintf("%T", err)})
+	return a.post("/invocation/"+id+"/error", body)
+}
+
+func (a *runtimeAPI) post(path string, body []byte) error {
+	status, _, resp, err := a.do("POST", path, body)
+	if err != nil {
+		return err
+	}
+	if status != 202 {
+		return fmt.Errorf("POST %s: status %d: %s", path, status, resp)
+	}
+	return nil
+}
+
+// do makes a request of the Runtime API, connecting first if need be.
+func (a *runtimeAPI) do(method, path string, body []byte) (int, map[string]string, []byte, error) {
+	if a.r == nil {
+		c, err := dial(a.host, a.port)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		a.c, a.r = c, bufio.NewReader(c)
+	}
+	status, header, resp, err := a.roundTrip(method, path, body)
+	if err != nil {
+		a.c.Close()
+		a.r = nil
+	}
+	return status, header, resp, err
+}
+
+// roundTrip writes a request and reads its response, whose header names
+// it lowercases.
+func (a *runtimeAPI) roundTrip(method, path string, body []byte) (int, map[string]string, []byte, error) {
+	req := fmt.Sprintf("%s /%s/runtime%s HTTP/1.1\r\nHost: %s:%s\r\nContent-Length: %d\r\n\r\n",
+		method, apiVersion, path, a.host, a.port, len(body))
+	if _, err := a.c.Write(append([]byte(req), body...)); err != nil {
+		return 0, nil, nil, err
+	}
+	line, err := a.r.ReadString('\n')
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return 0, nil, nil, fmt.Errorf("malformed status line %q", line)
+	}
+	status, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("malformed status line %q", line)
+	}
+	header := map[string]string{}
+	for {
+		line, err := a.r.ReadString('\n')
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			header[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+	var resp []byte
+	switch {
+	case strings.EqualFold(header["transfer-encoding"], "chunked"):
+		resp, err = readChunked(a.r)
+	case header["content-length"] != "":
+		var n int
+		if n, err = strconv.Atoi(header["content-length"]); err == nil {
+			resp = make([]byte, n)
+			_, err = io.ReadFull(a.r, resp)
+		}
+	}
+	return status, header, resp, err
+}
+
+// readChunked reads a chunked response body, and its trailers.
+func readChunked(r *bufio.Reader) ([]byte, error) {
+	var body []byte
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed chunk size %q", line)
+		}
+		if n == 0 {
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return nil, err
+				}
+				if strings.TrimSpace(line) == "" {
+					return body, nil
+				}
+			}
+		}
+		// The chunk, then its CRLF.
+		chunk := make([]byte, n+2)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, err
+		}
+		body = append(body, chunk[:n]...)
+	}
+}
+
+// conn is a TCP connection opened through libc, which TinyGo links:
+// TinyGo's net package has no sockets on Linux.
+type conn C.int
+
+func dial(host, port string) (conn, error) {
+	chost, cport := C.CString(host), C.CString(port)
+	defer C.free(unsafe.Pointer(chost))
+	defer C.free(unsafe.Pointer(cport))
+	var hints C.struct_addrinfo
+	C.memset(unsafe.Pointer(&hints), 0, C.sizeof_struct_addrinfo)
+	hints.ai_family = C.AF_UNSPEC
+	hints.ai_socktype = C.SOCK_STREAM
+	var res *C.struct_addrinfo
+	if rc := C.getaddrinfo(chost, cport, &hints, &res); rc != 0 {
+		return -1, fmt.Errorf("resolve %s: %s", host, C.GoString(C.gai_strerror(rc)))
+	}
+	defer C.freeaddrinfo(res)
+	for ai := res; ai != nil; ai = ai.ai_next {
+		fd := C.socket(ai.ai_family, ai.ai_socktype, ai.ai_protocol)
+		if fd < 0 {
+			continue
+		}
+		if C.connect(fd, ai.ai_addr, ai.ai_addrlen) == 0 {
+			return conn(fd), nil
+		}
+		C.close(fd)
+	}
+	return -1, fmt.Errorf("connect to %s:%s failed", host, port)
+}
+
+func (c conn) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := C.read(C.int(c), unsafe.Pointer(&p[0]), C.size_t(len(p)))
+	switch {
+	case n < 0:
+		return 0, errors.New("read from the Runtime API failed")
+	case n == 0:
+		return 0, io.EOF
+	}
+	return int(n), nil
+}
+
+func (c conn) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		n := C.write(C.int(c), unsafe.Pointer(&p[written]), C.size_t(len(p)-written))
+		if n <= 0 {
+			return written, errors.New("write to the Runtime API failed")
+		}
+		written += int(n)
+	}
+	return written, nil
+}
+
+func (c conn) Close() error {
+	C.close(C.int(c))
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !tinygo
+
+package handler
+
+import "github.com/aws/aws-lambda-go/lambda"
+
+// runtimeName is the runtime the handler reports in its responses.
+const runtimeName = "go"
+
+// Start runs w as the function's handler; it does not return.
+func Start[E any](w Workload[E]) {
+	lambda.Start(w.handler())
+}
@@ -0,0 +1,58 @@
+//go:build tinygo
+
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// runtimeName is the runtime the handler reports in its responses.
+const runtimeName = "tinygo"
+
+// Start runs w as the function's handler; it does not return. TinyGo
+// cannot build aws-lambda-go's runtime loop, whose net/http client has no
+// sockets under TinyGo on Linux, so Start answers the Runtime API itself,
+// as main-runtimeapi.go does: it hands each event to the same handler as
+// a Go build and posts what it returns, or the error, back. The Lambda
+// context it passes carries the request ID and the deadline.
+func Start[E any](w Workload[E]) {
+	h := w.handler()
+	host, port, _ := strings.Cut(os.Getenv("AWS_LAMBDA_RUNTIME_API"), ":")
+	api := &runtimeAPI{host: host, port: port}
+	for {
+		inv, err := api.next()
+		if err != nil {
+			// The Runtime API is gone; the environment is shutting down.
+			fmt.Fprintln(os.Stderr, "next invocation:", err)
+			os.Exit(1)
+		}
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{
+			AwsRequestID:       inv.id,
+			InvokedFunctionArn: inv.arn,
+		})
+		cancel := func() {}
+		if !inv.deadline.IsZero() {
+			ctx, cancel = context.WithDeadline(ctx, inv.deadline)
+		}
+		resp, err := h(ctx, inv.payload)
+		cancel()
+		var body []byte
+		if err == nil {
+			body, err = json.Marshal(resp)
+		}
+		if err != nil {
+			err = api.fail(inv.id, err)
+		} else {
+			err = api.respond(inv.id, body)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "post result:", err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+//go:build !tinygo
+
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"time"
+)
+
+// Trace returns a trace that adds the request it is attached to, with
+// httptrace.WithClientTrace, to the invocation's HTTP. Use one per
+// request. ctx must be the one Run was given.
+//
+// The transport may dial in the background for a request that is then
+// given an idle connection, so a handshake is charged only to the request
+// whose GotConn reports the connection it finished for as new.
+func Trace(ctx context.Context) *httptrace.ClientTrace {
+	stats, _ := ctx.Value(httpKey{}).(*httpStats)
+	if stats == nil {
+		return &httptrace.ClientTrace{}
+	}
+	var (
+		// started holds the start of each handshake in progress, oldest
+		// first; concurrent dials each run one.
+		started []time.Time
+		// done is the last handshake finished and not yet charged.
+		done time.Duration
+	)
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			stats.mu.Lock()
+			defer stats.mu.Unlock()
+			stats.sum.Requests++
+			if !info.Reused {
+				stats.sum.NewConns++
+				stats.tls += done
+			}
+			done = 0
+		},
+		TLSHandshakeStart: func() {
+			stats.mu.Lock()
+			defer stats.mu.Unlock()
+			started = append(started, time.Now())
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			stats.mu.Lock()
+			defer stats.mu.Unlock()
+			if len(started) == 0 {
+				return
+			}
+			done, started = time.Since(started[0]), started[1:]
+		},
+	}
+}
@@ -0,0 +1,53 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"lambdaperf/internal/codec"
+	"lambdaperf/internal/handler"
+)
+
+// API Gateway REST proxy benchmark: decode an events.APIGatewayProxyRequest
+// and echo the parsed request back. Invoke with baselines/events/apigw.json;
+// most real handlers sit behind a proxy event, and decoding it is part of
+// their cost. The handler decodes the event through internal/codec, as
+// this encodes the echo; easyjson has no code generated for either type
+// and so is encoding/json here.
+type echo struct {
+	Method    string              `json:"method"`
+	Path      string              `json:"path"`
+	Headers   map[string]string   `json:"headers"`
+	Query     map[string][]string `json:"query"`
+	BodyBytes int                 `json:"body_bytes"`
+}
+
+func echoRequest(ctx context.Context, req events.APIGatewayProxyRequest) (string, error) {
+	query := req.MultiValueQueryStringParameters
+	if query == nil {
+		query = make(map[string][]string, len(req.QueryStringParameters))
+		for k, v := range req.QueryStringParameters {
+			query[k] = []string{v}
+		}
+	}
+	body, err := codec.Marshal(echo{
+		Method:    req.HTTPMethod,
+		Path:      req.Path,
+		Headers:   req.Headers,
+		Query:     query,
+		BodyBytes: len(req.Body),
+	})
+	return string(body), err
+}
+
+func main() {
+	handler.Start(handler.Workload[events.APIGatewayProxyRequest]{
+		Name:        "apigw",
+		Params:      handler.Params{"serializer": codec.Name},
+		ContentType: "application/json",
+		Run:         echoRequest,
+	})
+}
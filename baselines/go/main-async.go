@@ -0,0 +1,84 @@
+//go:build baseline
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"lambdaperf/internal/handler"
+	"lambdaperf/pkg/async"
+)
+
+// Asynchronous invocation: record each attempt at an event as a
+// completion marker in S3, failing the attempt when the event asks to,
+// so Lambda retries it and finally sends it to the dead-letter queue.
+// ruchy-bench async invokes it with InvocationType=Event and times each
+// event from being submitted to the marker of the attempt completing it.
+// Input: {"run":...,"seq":...,"fail":bool,"bucket":...}; without a bucket
+// no marker is written.
+// Expected result: async(seq=0)=ok
+var client *s3.Client
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	client = s3.NewFromConfig(cfg)
+}
+
+// mark writes the marker of the attempt at e that started at started.
+func mark(ctx context.Context, e async.Event, started time.Time, failed bool) error {
+	var requestID string
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		requestID = lc.AwsRequestID
+	}
+	body, err := json.Marshal(async.Marker{
+		Run:        e.Run,
+		Seq:        e.Seq,
+		RequestID:  requestID,
+		StartedMS:  started.UnixMilli(),
+		FinishedMS: time.Now().UnixMilli(),
+		Failed:     failed,
+	})
+	if err != nil {
+		return err
+	}
+	key := async.MarkerKey(e, lambdacontext.FunctionName, requestID, started)
+	return handler.Time(ctx, func() error {
+		_, err := client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(e.Bucket),
+			Key:         aws.String(key),
+			Body:        bytes.NewReader(body),
+			ContentType: aws.String("application/json"),
+		})
+		return err
+	})
+}
+
+func attempt(ctx context.Context, e async.Event) (string, error) {
+	started := time.Now()
+	if e.Bucket != "" {
+		if err := mark(ctx, e, started, e.Fail); err != nil {
+			return "", fmt.Errorf("write marker: %w", err)
+		}
+	}
+	if e.Fail {
+		// A function error, not a status: only those are retried.
+		return "", fmt.Errorf("async(seq=%d) failed as asked", e.Seq)
+	}
+	return fmt.Sprintf("async(seq=%d)=ok", e.Seq), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[async.Event]{Name: async.Workload, Run: attempt})
+}
@@ -0,0 +1,88 @@
+//go:build baseline
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"lambdaperf/internal/handler"
+)
+
+// Compression round trip: gzip a deterministic 5 MB JSON-lines payload at
+// level 6 and base64-encode it, as a handler returning a binary body
+// through API Gateway does, then decode and gunzip it back. Reports
+// throughput: the payload's MB per second of duration, as throughput_mb_s.
+// Compressed sizes differ between zlib implementations, so the result
+// checks the round trip, not the compressed bytes.
+// Source: benchmarks/local-compress/compress.go
+// Input: {"mb": 1..16}, default 5.
+// Expected result: compress(5)=bytes:5242880,sha256:7fba765722313d5a
+
+var regions = []string{"us-east-1", "eu-west-1", "ap-south-1", "sa-east-1"}
+
+var statuses = []string{"pending", "shipped", "delivered"}
+
+// payload returns n bytes of order records, one JSON object per line.
+func payload(n int) []byte {
+	var b bytes.Buffer
+	b.Grow(n + 128)
+	for i := 0; b.Len() < n; i++ {
+		fmt.Fprintf(&b, `{"id":%d,"sku":"SKU-%06d","qty":%d,"price_cents":%d,"region":"%s","status":"%s"}`+"\n",
+			i, i*7919%1000000, i%17+1, i*104729%100000, regions[i%4], statuses[i%3])
+	}
+	return b.Bytes()[:n]
+}
+
+// data is kept across invocations so only the first at a size builds it.
+var data []byte
+
+func roundTrip(ctx context.Context, mb int) (string, error) {
+	if n := mb << 20; len(data) != n {
+		data = payload(n)
+	}
+	var gz bytes.Buffer
+	w, err := gzip.NewWriterLevel(&gz, 6)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	encoded := base64.StdEncoding.EncodeToString(gz.Bytes())
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(decoded))
+	if err != nil {
+		return "", err
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	handler.Processed(ctx, len(data))
+	sum := sha256.Sum256(out)
+	return handler.Result("compress", mb, fmt.Sprintf("bytes:%d,sha256:%x", len(out), sum[:8])), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.Args]{
+		Name:   "compress",
+		Inputs: map[string]handler.Input{"mb": {Default: 5, Min: 1, Max: 16}},
+		Params: handler.Params{"level": 6},
+		Run: func(ctx context.Context, args handler.Args) (string, error) {
+			return roundTrip(ctx, args["mb"])
+		},
+	})
+}
@@ -0,0 +1,37 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"lambdaperf/internal/handler"
+	"lambdaperf/pkg/configload"
+)
+
+// Config loading at init through the AWS Parameters and Secrets Lambda
+// Extension: main-configload.go's 5 secrets and 20 parameters, each asked
+// of the extension's localhost endpoint, which fetches and caches them.
+// The handler signs no requests, but the extension starts with every
+// environment and its first reads still go to the services, so Init
+// Duration carries both; compare init_ms with main-configload.go. Deploy
+// with ruchy-bench deploy -secrets-layer.
+// Expected result: configload-extension(secrets=5,parameters=20)=0814e3a8f6580d33
+
+// digest is the loaded configuration's; a failure to load it is reported
+// by every invocation, which says more than a failed init.
+var digest, loadErr = configload.Load(context.Background(), configload.NewExtension())
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:   configload.ExtensionWorkload,
+		Params: handler.Params{"source": "extension", "secrets": configload.Secrets, "parameters": configload.Parameters},
+		Run: func(context.Context, handler.NoEvent) (string, error) {
+			if loadErr != nil {
+				return "", fmt.Errorf("load configuration (run ruchy-bench seed -workload configload): %w", loadErr)
+			}
+			return handler.Result(configload.ExtensionWorkload, fmt.Sprintf("secrets=%d,parameters=%d", configload.Secrets, configload.Parameters), digest), nil
+		},
+	})
+}
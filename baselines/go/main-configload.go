@@ -0,0 +1,49 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+
+	"lambdaperf/internal/handler"
+	"lambdaperf/pkg/configload"
+)
+
+// Config loading at init: read the 5 Secrets Manager secrets and 20 SSM
+// parameters ruchy-bench seed writes, one GetSecretValue or GetParameter
+// call each, before the handler starts, as a service loading its
+// configuration on a cold start does. The time is part of the REPORT
+// line's Init Duration; compare init_ms with main-configload-extension.go,
+// which reads the same values through the AWS Parameters and Secrets
+// Lambda Extension, and with main.go's. Warm invocations only return the
+// result.
+// Expected result: configload(secrets=5,parameters=20)=0814e3a8f6580d33
+
+// digest is the loaded configuration's; a failure to load it is reported
+// by every invocation, which says more than a failed init.
+var digest, loadErr = load()
+
+func load() (string, error) {
+	ctx := context.Background()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	return configload.Load(ctx, &configload.Client{Config: cfg})
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:   configload.Workload,
+		Params: handler.Params{"source": "api", "secrets": configload.Secrets, "parameters": configload.Parameters},
+		Run: func(context.Context, handler.NoEvent) (string, error) {
+			if loadErr != nil {
+				return "", fmt.Errorf("load configuration (run ruchy-bench seed -workload configload): %w", loadErr)
+			}
+			return handler.Result(configload.Workload, fmt.Sprintf("secrets=%d,parameters=%d", configload.Secrets, configload.Parameters), digest), nil
+		},
+	})
+}
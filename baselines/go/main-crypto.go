@@ -0,0 +1,79 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+
+	"golang.org/x/sys/cpu"
+
+	"lambdaperf/internal/handler"
+)
+
+// Crypto throughput: SHA-256 a deterministic 10 MB buffer, then seal it
+// with AES-256-GCM under a fixed key and nonce. Go's crypto/sha256 and
+// crypto/aes switch to SHA-NI, AES-NI and PCLMULQDQ on x86_64 and to the
+// ARMv8 crypto extensions on arm64 when the CPU has them, so the same
+// handler can run several times faster on one architecture than another.
+// Whether AES-GCM had its instructions is logged with each invocation.
+// Source: benchmarks/local-crypto/crypto.go
+// Input: {"mb": 1..32}, default 10.
+// Expected result: crypto(10)=sha256:44f9296993796e20,gcm:f258271894e936fb8653169fc47dc5b5
+
+var (
+	key   = pattern(32)
+	nonce = pattern(12)
+	// buf and sealed are kept across invocations so only the first at a
+	// size pays for filling them.
+	buf, sealed []byte
+)
+
+// pattern returns n bytes cycling through 0..250, cheap to produce in any
+// runtime.
+func pattern(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+// aesGCMHardware reports whether this CPU has the instructions Go's
+// AES-GCM uses: AES-NI and PCLMULQDQ, or ARMv8 AES and PMULL.
+func aesGCMHardware() bool {
+	return cpu.X86.HasAES && cpu.X86.HasPCLMULQDQ || cpu.ARM64.HasAES && cpu.ARM64.HasPMULL
+}
+
+func digest(mb int) (string, error) {
+	n := mb << 20
+	if len(buf) != n {
+		buf, sealed = pattern(n), make([]byte, 0, n+16)
+	}
+	sum := sha256.Sum256(buf)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	sealed = gcm.Seal(sealed[:0], nonce, buf, nil)
+	tag := sealed[len(sealed)-gcm.Overhead():]
+	return handler.Result("crypto", mb, fmt.Sprintf("sha256:%x,gcm:%x", sum[:8], tag)), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.Args]{
+		Name:   "crypto",
+		Inputs: map[string]handler.Input{"mb": {Default: 10, Min: 1, Max: 32}},
+		Params: handler.Params{"aes_gcm_hw": aesGCMHardware()},
+		Run: func(_ context.Context, args handler.Args) (string, error) {
+			return digest(args["mb"])
+		},
+	})
+}
@@ -0,0 +1,92 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"lambdaperf/internal/handler"
+)
+
+// DynamoDB benchmark: one BatchWriteItem of 25 items, then 100 GetItem
+// reads of the items seeded by ruchy-bench seed. The client is built at
+// init; connection setup lands in the first request's SDK time, which is
+// reported separately from the handler's total duration.
+// Expected result: dynamodb(writes=25,reads=100)=ok
+const (
+	writes = 25
+	reads  = 100
+)
+
+var (
+	client *dynamodb.Client
+	table  = "ruchy-bench-items"
+)
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	client = dynamodb.NewFromConfig(cfg)
+	if name := os.Getenv("TABLE_NAME"); name != "" {
+		table = name
+	}
+}
+
+func key(id string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"pk": &types.AttributeValueMemberS{Value: id}}
+}
+
+func readWrite(ctx context.Context, _ handler.NoEvent) (string, error) {
+	requests := make([]types.WriteRequest, writes)
+	for i := range requests {
+		item := key(fmt.Sprintf("write-%02d", i))
+		item["n"] = &types.AttributeValueMemberN{Value: fmt.Sprint(i)}
+		requests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: item}}
+	}
+	var out *dynamodb.BatchWriteItemOutput
+	if err := handler.Time(ctx, func() (err error) {
+		out, err = client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{table: requests},
+		})
+		return err
+	}); err != nil {
+		return "", err
+	}
+	if n := len(out.UnprocessedItems[table]); n > 0 {
+		return "", fmt.Errorf("%d writes unprocessed", n)
+	}
+
+	for i := 0; i < reads; i++ {
+		var got *dynamodb.GetItemOutput
+		if err := handler.Time(ctx, func() (err error) {
+			got, err = client.GetItem(ctx, &dynamodb.GetItemInput{
+				TableName: aws.String(table),
+				Key:       key(fmt.Sprintf("item-%03d", i)),
+			})
+			return err
+		}); err != nil {
+			return "", err
+		}
+		if got.Item == nil {
+			return "", fmt.Errorf("item-%03d missing: run ruchy-bench seed", i)
+		}
+	}
+	return handler.Result("dynamodb", fmt.Sprintf("writes=%d,reads=%d", writes, reads), "ok"), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:   "dynamodb",
+		Params: handler.Params{"table": table, "writes": writes, "reads": reads},
+		Run:    readWrite,
+	})
+}
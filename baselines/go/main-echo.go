@@ -0,0 +1,44 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+
+	"lambdaperf/internal/codec"
+	"lambdaperf/internal/handler"
+)
+
+// Payload round-trip benchmark: decode the invocation payload into generic
+// values and encode them back, compact with sorted keys. The work is set by
+// the payload alone, so `ruchy-bench payloads` invoking it with 1 KB to
+// 6 MB documents (pkg/fixture.Payload) gives the cost of JSON marshaling
+// against payload size. The response is the re-encoding's length and
+// CRC-32 rather than the document, which keeps a maximum-size request's
+// response within Lambda's limit too. The expected result is the one for
+// the payload {}, which is what the handler is invoked with otherwise.
+// Any document can arrive, so easyjson, which needs generated types,
+// decodes it with encoding/json.
+// Expected result: echo(2)=a3a6bf43
+func echo(ctx context.Context, event handler.NoEvent) (string, error) {
+	var doc any
+	if err := codec.Unmarshal(event, &doc); err != nil {
+		return "", handler.Status(400, "payload is not JSON: %v", err)
+	}
+	out, err := codec.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	handler.Processed(ctx, len(event))
+	return handler.Result("echo", len(out), fmt.Sprintf("%08x", crc32.ChecksumIEEE(out))), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:   "echo",
+		Params: handler.Params{"serializer": codec.Name},
+		Run:    echo,
+	})
+}
@@ -0,0 +1,25 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"errors"
+
+	"lambdaperf/internal/handler"
+)
+
+// Error-path benchmark: return an error from every invocation. aws-lambda-go
+// reports it to the Runtime API as the function error, logs it, and goes on
+// to the next event in the same execution environment. `ruchy-bench errors`
+// measures what failing costs and how the error reaches the caller.
+func fail(context.Context, handler.NoEvent) (string, error) {
+	return "", errors.New("deliberate error")
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name: "error",
+		Run:  fail,
+	})
+}
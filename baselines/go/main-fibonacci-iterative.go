@@ -0,0 +1,37 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+
+	"lambdaperf/internal/handler"
+)
+
+// Fibonacci iterative: sum fibonacci(80..90) over 100000 repetitions,
+// wrapping at 2^64. Loop and integer arithmetic without call overhead.
+// Source: benchmarks/local-fibonacci/fibonacci-iterative.go
+// Input: {"repetitions": 1..10000000}, default 100000.
+// Expected result: fibonacci-iterative(100000)=2232225216200996121
+func fibonacci(n int) uint64 {
+	var a, b uint64 = 0, 1
+	for i := 0; i < n; i++ {
+		a, b = b, a+b
+	}
+	return a
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.Args]{
+		Name:   "fibonacci-iterative",
+		Inputs: map[string]handler.Input{"repetitions": {Default: 100000, Min: 1, Max: 10000000}},
+		Run: func(_ context.Context, args handler.Args) (string, error) {
+			repetitions := args["repetitions"]
+			var sum uint64
+			for i := 0; i < repetitions; i++ {
+				sum += fibonacci(80 + i%11)
+			}
+			return handler.Result("fibonacci-iterative", repetitions, sum), nil
+		},
+	})
+}
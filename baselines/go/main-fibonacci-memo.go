@@ -0,0 +1,42 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+
+	"lambdaperf/internal/handler"
+)
+
+// Fibonacci memoized: sum fibonacci(80..90) over 10000 repetitions, each
+// with a fresh memo table, wrapping at 2^64. Hash map traffic plus shallow
+// recursion.
+// Source: benchmarks/local-fibonacci/fibonacci-memo.go
+// Input: {"repetitions": 1..1000000}, default 10000.
+// Expected result: fibonacci-memo(10000)=12697144346765014788
+func fibonacci(n int, memo map[int]uint64) uint64 {
+	if n <= 1 {
+		return uint64(n)
+	}
+	if v, ok := memo[n]; ok {
+		return v
+	}
+	v := fibonacci(n-1, memo) + fibonacci(n-2, memo)
+	memo[n] = v
+	return v
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.Args]{
+		Name:   "fibonacci-memo",
+		Inputs: map[string]handler.Input{"repetitions": {Default: 10000, Min: 1, Max: 1000000}},
+		Run: func(_ context.Context, args handler.Args) (string, error) {
+			repetitions := args["repetitions"]
+			var sum uint64
+			for i := 0; i < repetitions; i++ {
+				sum += fibonacci(80+i%11, map[int]uint64{})
+			}
+			return handler.Result("fibonacci-memo", repetitions, sum), nil
+		},
+	})
+}
@@ -1,14 +1,17 @@
+//go:build baseline
+
 package main
 
 import (
 	"context"
-	"fmt"
 
-	"github.com/aws/aws-lambda-go/lambda"
+	"lambdaperf/internal/handler"
 )
 
 // Fibonacci recursive implementation
 // Source: ruchy-book bench-007-fibonacci.go
+// Input: {"n": 0..40}, default 35.
+// Expected result: fibonacci(35)=9227465
 func fibonacci(n int) int {
 	if n <= 1 {
 		return n
@@ -16,21 +19,13 @@ func fibonacci(n int) int {
 	return fibonacci(n-1) + fibonacci(n-2)
 }
 
-type testResponse struct {
-	StatusCode int    `json:"statusCode"`
-	Body       string `json:"body"`
-}
-
-func handleRequest(ctx context.Context) (testResponse, error) {
-	// Calculate fibonacci(35) - standard Lambda benchmark
-	result := fibonacci(35)
-
-	return testResponse{
-		StatusCode: 200,
-		Body:       fmt.Sprintf("fibonacci(35)=%d", result),
-	}, nil
-}
-
 func main() {
-	lambda.Start(handleRequest)
+	// Calculate fibonacci(35) - standard Lambda benchmark
+	handler.Start(handler.Workload[handler.Args]{
+		Name:   "fibonacci",
+		Inputs: map[string]handler.Input{"n": {Default: 35, Min: 0, Max: 40}},
+		Run: func(_ context.Context, args handler.Args) (string, error) {
+			return handler.Result("fibonacci", args["n"], fibonacci(args["n"])), nil
+		},
+	})
 }
@@ -0,0 +1,102 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"lambdaperf/pkg/lambdalog"
+)
+
+// Firehose record transformation: base64-decode each of a batch of JSON
+// access-log records, normalize it and re-encode it, answering in the
+// shape Kinesis Data Firehose expects of a transform function. Debug
+// records are dropped and undecodable ones returned as ProcessingFailed.
+// The work is mostly base64 and JSON, which stresses a runtime's codecs
+// rather than its arithmetic. Invoke with baselines/events/firehose.json,
+// a batch of 100 records.
+
+// accessLog is an input record.
+type accessLog struct {
+	Timestamp string  `json:"timestamp"`
+	Level     string  `json:"level"`
+	Service   string  `json:"service"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	User      string  `json:"user"`
+	Message   string  `json:"message"`
+}
+
+// transformed is an output record: the level upper-cased, latency in
+// seconds and the user pseudonymized.
+type transformed struct {
+	Timestamp string  `json:"timestamp"`
+	Level     string  `json:"level"`
+	Service   string  `json:"service"`
+	Method    string  `json:"method"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	LatencyS  float64 `json:"latency_s"`
+	UserHash  string  `json:"user_hash"`
+	Message   string  `json:"message"`
+}
+
+func transform(r events.KinesisFirehoseEventRecord) events.KinesisFirehoseResponseRecord {
+	out := events.KinesisFirehoseResponseRecord{RecordID: r.RecordID, Data: r.Data}
+	var in accessLog
+	if err := json.Unmarshal(r.Data, &in); err != nil || in.Timestamp == "" {
+		out.Result = events.KinesisFirehoseTransformedStateProcessingFailed
+		return out
+	}
+	if strings.EqualFold(in.Level, "debug") {
+		out.Result, out.Data = events.KinesisFirehoseTransformedStateDropped, nil
+		return out
+	}
+	user := sha256.Sum256([]byte(in.User))
+	data, err := json.Marshal(transformed{
+		Timestamp: in.Timestamp,
+		Level:     strings.ToUpper(in.Level),
+		Service:   in.Service,
+		Method:    in.Method,
+		Path:      in.Path,
+		Status:    in.Status,
+		LatencyS:  in.LatencyMS / 1000,
+		UserHash:  hex.EncodeToString(user[:8]),
+		Message:   in.Message,
+	})
+	if err != nil {
+		out.Result = events.KinesisFirehoseTransformedStateProcessingFailed
+		return out
+	}
+	// Firehose concatenates records as they are; the newline keeps the
+	// delivered objects one record per line.
+	out.Result, out.Data = events.KinesisFirehoseTransformedStateOk, append(data, '\n')
+	return out
+}
+
+func process(ctx context.Context, event events.KinesisFirehoseEvent) (events.KinesisFirehoseResponse, error) {
+	start := time.Now()
+	resp := events.KinesisFirehoseResponse{Records: make([]events.KinesisFirehoseResponseRecord, 0, len(event.Records))}
+	for _, r := range event.Records {
+		resp.Records = append(resp.Records, transform(r))
+	}
+	lambdalog.Log(ctx, lambdalog.Entry{
+		Workload: "firehose",
+		Params:   lambdalog.Params{"records": len(event.Records)},
+	}, start, nil)
+	return resp, nil
+}
+
+func main() {
+	lambda.Start(process)
+}
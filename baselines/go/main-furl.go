@@ -0,0 +1,51 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"lambdaperf/internal/handler"
+)
+
+// Function URL benchmark: decode an events.LambdaFunctionURLRequest (the
+// payload format 2.0 shared with HTTP APIs) and echo the parsed request
+// back. Invoke with baselines/events/furl.json.
+type echo struct {
+	Method    string              `json:"method"`
+	Path      string              `json:"path"`
+	Headers   map[string]string   `json:"headers"`
+	Query     map[string][]string `json:"query"`
+	Cookies   []string            `json:"cookies"`
+	BodyBytes int                 `json:"body_bytes"`
+}
+
+func echoRequest(ctx context.Context, req events.LambdaFunctionURLRequest) (string, error) {
+	// queryStringParameters joins repeated keys with commas; the raw query
+	// string keeps them apart.
+	query, err := url.ParseQuery(req.RawQueryString)
+	if err != nil {
+		return "", handler.Status(400, "%v", err)
+	}
+	body, err := json.Marshal(echo{
+		Method:    req.RequestContext.HTTP.Method,
+		Path:      req.RawPath,
+		Headers:   req.Headers,
+		Query:     query,
+		Cookies:   req.Cookies,
+		BodyBytes: len(req.Body),
+	})
+	return string(body), err
+}
+
+func main() {
+	handler.Start(handler.Workload[events.LambdaFunctionURLRequest]{
+		Name:        "furl",
+		ContentType: "application/json",
+		Run:         echoRequest,
+	})
+}
@@ -0,0 +1,106 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+
+	"lambdaperf/internal/handler"
+)
+
+// HTTP client benchmark: 20 sequential, then 20 parallel HTTPS GETs of the
+// mock API ruchy-bench seed stands up, an API Gateway MOCK integration
+// that answers without invoking anything, so the time is the client's.
+// The client is built at init and keeps up to 20 idle connections, so a
+// warm invocation reuses the ones the invocation before left unless API
+// Gateway has closed them; the response reports the requests, the new
+// connections they opened and the TLS handshake time as the http_*
+// metrics, and the time waiting on them as sdk_ms.
+// Input: {"url": ...}, written to .bench/events/httpclient.json by ruchy-bench seed.
+// Expected result: httpclient(sequential=20,parallel=20)=ok
+const (
+	sequential = 20
+	parallel   = 20
+	// want is mockapi.Body.
+	want = `{"ok":true}`
+)
+
+var client *http.Client
+
+func init() {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	// The default of 2 would close most of the parallel connections.
+	t.MaxIdleConnsPerHost = parallel
+	client = &http.Client{Transport: t}
+}
+
+type event struct {
+	URL string `json:"url"`
+}
+
+func get(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, handler.Trace(ctx)), http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Reading to the end returns the connection to the idle pool.
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != want {
+		return fmt.Errorf("GET %s: %s %q", url, resp.Status, body)
+	}
+	return nil
+}
+
+func fetch(ctx context.Context, ev event) (string, error) {
+	if ev.URL == "" {
+		return "", handler.Status(400, "no url in event: run ruchy-bench seed -workload httpclient")
+	}
+	if err := handler.Time(ctx, func() error {
+		for range sequential {
+			if err := get(ctx, ev.URL); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return "", err
+	}
+	if err := handler.Time(ctx, func() error {
+		var wg sync.WaitGroup
+		errs := make([]error, parallel)
+		for i := range errs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = get(ctx, ev.URL)
+			}()
+		}
+		wg.Wait()
+		return errors.Join(errs...)
+	}); err != nil {
+		return "", err
+	}
+	return handler.Result("httpclient", fmt.Sprintf("sequential=%d,parallel=%d", sequential, parallel), "ok"), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[event]{
+		Name:   "httpclient",
+		Params: handler.Params{"sequential": sequential, "parallel": parallel},
+		Run:    fetch,
+	})
+}
@@ -0,0 +1,67 @@
+//go:build baseline
+
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"strings"
+
+	"golang.org/x/image/draw"
+
+	"lambdaperf/internal/handler"
+)
+
+// Image resize: the thumbnailer behind nearly every upload bucket. Decode
+// the bundled 4000x3000 JPEG, scale it to three thumbnail sizes with a
+// Catmull-Rom filter and encode each as a JPEG again. Unlike fibonacci it
+// streams 12 megapixels through memory for every size, in the loops that
+// image libraries elsewhere vectorize: image/jpeg and x/image/draw are
+// plain Go.
+// Expected result: imgresize(4000x3000)=1280x960,640x480,160x120
+//
+//go:embed corpus/photo.jpg
+var photo []byte
+
+// quality is the thumbnails' JPEG quality, a typical web one.
+const quality = 80
+
+// sizes are the thumbnails, each scaled from the full photo.
+var sizes = []image.Point{{1280, 960}, {640, 480}, {160, 120}}
+
+func imgresize(ctx context.Context, _ handler.NoEvent) (string, error) {
+	src, err := jpeg.Decode(bytes.NewReader(photo))
+	if err != nil {
+		return "", err
+	}
+	handler.Processed(ctx, len(photo))
+	dims := make([]string, len(sizes))
+	for i, size := range sizes {
+		dst := image.NewRGBA(image.Rectangle{Max: size})
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+		var out bytes.Buffer
+		if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: quality}); err != nil {
+			return "", err
+		}
+		// Report what the encoded thumbnail says it is.
+		cfg, err := jpeg.DecodeConfig(&out)
+		if err != nil {
+			return "", fmt.Errorf("thumbnail %v: %w", size, err)
+		}
+		dims[i] = fmt.Sprintf("%dx%d", cfg.Width, cfg.Height)
+	}
+	b := src.Bounds()
+	return fmt.Sprintf("imgresize(%dx%d)=%s", b.Dx(), b.Dy(), strings.Join(dims, ",")), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:   "imgresize",
+		Params: handler.Params{"jpeg_bytes": len(photo), "thumbnails": len(sizes), "quality": quality, "filter": "catmull-rom"},
+		Run:    imgresize,
+	})
+}
@@ -0,0 +1,119 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"lambdaperf/internal/handler"
+)
+
+// Init-heavy service: the startup work of a real service rather than a
+// hello world, done before the handler starts so it lands in the REPORT
+// line's Init Duration. It registers 400 record types, warming the JSON
+// encoder of each as a codec registry would, compiles a router of 200
+// regular expressions, and builds a 200,000-entry lookup table. Each
+// invocation then serves 1000 requests from that state: routing a path
+// through the router, looking a key up in the table and instantiating a
+// registered type. Compare its init_ms with main.go's for what startup
+// work costs each runtime.
+// Expected result: initheavy(types=400,routes=200,entries=200000)=matched:1000,fields:9950,sum:c31fb729fd7a4d3d
+const (
+	types    = 400
+	routes   = 200
+	entries  = 200000
+	requests = 1000
+)
+
+// fieldTypes are the kinds a record field cycles through.
+var fieldTypes = []reflect.Type{
+	reflect.TypeFor[int64](),
+	reflect.TypeFor[float64](),
+	reflect.TypeFor[string](),
+	reflect.TypeFor[bool](),
+	reflect.TypeFor[[]string](),
+	reflect.TypeFor[map[string]int64](),
+}
+
+var (
+	registry = map[string]reflect.Type{}
+	router   []*regexp.Regexp
+	table    = make(map[string]uint64, entries)
+)
+
+func init() {
+	// Type i, named T<i>, has 4 + i%13 fields F<j> of the kind
+	// fieldTypes[(i+j)%6].
+	for i := range types {
+		fields := make([]reflect.StructField, 4+i%13)
+		for j := range fields {
+			name := fmt.Sprintf("F%02d", j)
+			fields[j] = reflect.StructField{
+				Name: name,
+				Type: fieldTypes[(i+j)%len(fieldTypes)],
+				Tag:  reflect.StructTag(fmt.Sprintf(`json:"f%02d"`, j)),
+			}
+		}
+		t := reflect.StructOf(fields)
+		if _, err := json.Marshal(reflect.New(t).Interface()); err != nil {
+			panic(err)
+		}
+		registry[fmt.Sprintf("T%03d", i)] = t
+	}
+	for i := range routes {
+		router = append(router, regexp.MustCompile(fmt.Sprintf(`^/v1/svc%d/items/([0-9]+)(?:/([a-z]+))?$`, i)))
+	}
+	for i := range entries {
+		key := fmt.Sprintf("key-%06d", i)
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		table[key] = h.Sum64()
+	}
+}
+
+// serve handles request k: the first route matching its path, which must
+// capture k as the item ID, a table lookup and a registered type's
+// instance. It reports whether the path was routed, and the fields of the
+// instance.
+func serve(k int) (routed bool, value uint64, fields int) {
+	path := fmt.Sprintf("/v1/svc%d/items/%d/detail", k*7%routes, k)
+	for _, re := range router {
+		if m := re.FindStringSubmatch(path); m != nil {
+			id, err := strconv.Atoi(m[1])
+			routed = err == nil && id == k
+			break
+		}
+	}
+	value = table[fmt.Sprintf("key-%06d", k*7919%entries)]
+	fields = reflect.New(registry[fmt.Sprintf("T%03d", k%types)]).Elem().NumField()
+	return routed, value, fields
+}
+
+func handle(context.Context, handler.NoEvent) (string, error) {
+	var matched, fields int
+	var sum uint64
+	for k := range requests {
+		routed, v, n := serve(k)
+		if routed {
+			matched++
+		}
+		sum += v
+		fields += n
+	}
+	return handler.Result("initheavy", fmt.Sprintf("types=%d,routes=%d,entries=%d", types, routes, entries),
+		fmt.Sprintf("matched:%d,fields:%d,sum:%016x", matched, fields, sum)), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:   "initheavy",
+		Params: handler.Params{"types": types, "routes": routes, "entries": entries, "requests": requests},
+		Run:    handle,
+	})
+}
@@ -0,0 +1,79 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+
+	"lambdaperf/internal/codec"
+	"lambdaperf/internal/handler"
+)
+
+// JSON round-trip benchmark: parse a ~1.1 MB nested document and
+// re-serialize it. Matches benchmarks/local-json/json.go. The round trip
+// goes through internal/codec, so the -serializer variants time
+// json-iterator and easyjson on it too; the document itself is always
+// built with encoding/json.
+// Expected result: json(1115300)=31fa7abb
+const records = 4000
+
+// document builds the deterministic benchmark payload. Keys are emitted
+// sorted and compact so every runtime produces identical bytes.
+func document(n int) map[string]any {
+	recs := make([]any, n)
+	for i := 0; i < n; i++ {
+		children := make([]any, 3)
+		for k := 0; k < 3; k++ {
+			children[k] = map[string]any{
+				"id":    i*10 + k,
+				"label": fmt.Sprintf("child-%d-%d", i, k),
+				"depth": k,
+			}
+		}
+		recs[i] = map[string]any{
+			"id":     i,
+			"name":   fmt.Sprintf("record-%d", i),
+			"active": i%3 == 0,
+			"tags":   []any{fmt.Sprintf("t%d", i%7), fmt.Sprintf("t%d", i%11), fmt.Sprintf("t%d", i%13)},
+			"metrics": map[string]any{
+				"count":   i * 3,
+				"score":   (i * 7919) % 1000,
+				"buckets": []any{i % 5, i % 9, i % 17, i % 31},
+			},
+			"children": children,
+		}
+	}
+	return map[string]any{"version": 1, "records": recs}
+}
+
+var payload []byte
+
+func init() {
+	var err error
+	if payload, err = json.Marshal(document(records)); err != nil {
+		panic(err)
+	}
+}
+
+func roundTrip(context.Context, handler.NoEvent) (string, error) {
+	doc := codec.NewDocument()
+	if err := codec.Unmarshal(payload, doc); err != nil {
+		return "", err
+	}
+	out, err := codec.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	return handler.Result("json", len(out), fmt.Sprintf("%08x", crc32.ChecksumIEEE(out))), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:   "json",
+		Params: handler.Params{"records": records, "serializer": codec.Name},
+		Run:    roundTrip,
+	})
+}
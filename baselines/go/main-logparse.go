@@ -0,0 +1,59 @@
+//go:build baseline
+
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"lambdaperf/internal/handler"
+)
+
+// Log parsing: count the matches of seven regular expressions of the kind
+// log pipelines run (addresses, request lines with error statuses,
+// timestamps, UUIDs, e-mail addresses, slow warnings and bot user agents)
+// over the bundled ~1 MB log. Regex engines differ more between runtimes
+// than almost anything else: Go's RE2-style engine runs in linear time
+// without backtracking, Python's backtracks.
+// Source: benchmarks/local-logparse/logparse.go
+// Expected result: logparse(lines=6768)=ipv4:3354,http_error:1587,timestamp:3414,uuid:3414,email:3414,slow:413,bot:1277
+//
+//go:embed corpus/app.log
+var logs []byte
+
+// patterns are matched in this order and reported under these names. They
+// use only syntax that means the same to RE2 and Python's re on ASCII.
+var patterns = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"ipv4", regexp.MustCompile(`\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b`)},
+	{"http_error", regexp.MustCompile(`"(?:GET|POST|PUT|DELETE|PATCH|HEAD) [^" ]+ HTTP/1\.[01]" [45][0-9]{2} `)},
+	{"timestamp", regexp.MustCompile(`[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}\.[0-9]{3}Z`)},
+	{"uuid", regexp.MustCompile(`\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)},
+	{"email", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{"slow", regexp.MustCompile(`level=(?:ERROR|WARN) [^\n]*duration_ms=[0-9]{3,}`)},
+	{"bot", regexp.MustCompile(`(?i)"[^"\n]*(?:bot|crawler|spider)[^"\n]*"`)},
+}
+
+func logparse(text []byte) string {
+	counts := make([]string, len(patterns))
+	for i, p := range patterns {
+		counts[i] = fmt.Sprintf("%s:%d", p.name, len(p.re.FindAllIndex(text, -1)))
+	}
+	return fmt.Sprintf("logparse(lines=%d)=%s", bytes.Count(text, []byte("\n")), strings.Join(counts, ","))
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:   "logparse",
+		Params: handler.Params{"log_bytes": len(logs), "patterns": len(patterns)},
+		Run: func(context.Context, handler.NoEvent) (string, error) {
+			return logparse(logs), nil
+		},
+	})
+}
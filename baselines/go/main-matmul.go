@@ -0,0 +1,85 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"lambdaperf/internal/handler"
+)
+
+// Matrix multiplication benchmark: multiply two 512x512 float64 matrices
+// filled from a fixed-seed LCG and sum the product. Matches
+// benchmarks/local-matmul/matmul.go.
+// Input: {"size": 1..1024}, default 512.
+// Expected result: matmul(512)=33519225.201954
+const defaultSize = 512
+
+// lcg is a 64-bit linear congruential generator (Knuth's MMIX constants)
+// yielding floats in [0, 1) from the top 53 bits.
+type lcg uint64
+
+func (x *lcg) next() float64 {
+	*x = *x*6364136223846793005 + 1442695040888963407
+	return float64(uint64(*x)>>11) / (1 << 53)
+}
+
+func fill(rng *lcg, size int) []float64 {
+	m := make([]float64, size*size)
+	for i := range m {
+		m[i] = rng.next()
+	}
+	return m
+}
+
+// a and b are the inputs at the last size multiplied, generated outside
+// the measured work: at start-up for the default size, otherwise by the
+// first invocation at a new size.
+var (
+	a, b  []float64
+	aSize int
+)
+
+func init() { generate(defaultSize) }
+
+func generate(size int) {
+	rng := lcg(42)
+	a = fill(&rng, size)
+	b = fill(&rng, size)
+	aSize = size
+}
+
+// multiply uses i-k-j order. The explicit float64 conversion forbids fused
+// multiply-add, which Go emits on arm64 and which would make the Graviton
+// checksum differ.
+func multiply(a, b []float64, size int) []float64 {
+	c := make([]float64, size*size)
+	for i := 0; i < size; i++ {
+		for k := 0; k < size; k++ {
+			aik := a[i*size+k]
+			for j := 0; j < size; j++ {
+				c[i*size+j] += float64(aik * b[k*size+j])
+			}
+		}
+	}
+	return c
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.Args]{
+		Name:   "matmul",
+		Inputs: map[string]handler.Input{"size": {Default: defaultSize, Min: 1, Max: 1024}},
+		Run: func(_ context.Context, args handler.Args) (string, error) {
+			size := args["size"]
+			if size != aSize {
+				generate(size)
+			}
+			var sum float64
+			for _, v := range multiply(a, b, size) {
+				sum += v
+			}
+			return handler.Result("matmul", size, fmt.Sprintf("%.6f", sum)), nil
+		},
+	})
+}
@@ -0,0 +1,25 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+
+	"lambdaperf/internal/handler"
+)
+
+// Panic-path benchmark: panic on every invocation. aws-lambda-go recovers
+// the panic, reports it with its stack trace as the function error and then
+// exits, so the execution environment is replaced and every invocation after
+// the first starts cold. `ruchy-bench errors` measures it against the error
+// workload, which fails without crashing.
+func crash(context.Context, handler.NoEvent) (string, error) {
+	panic("deliberate panic")
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name: "panic",
+		Run:  crash,
+	})
+}
@@ -0,0 +1,76 @@
+//go:build baseline
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// Minimal handler without aws-lambda-go: the bootstrap speaks the Lambda
+// Runtime API (provided.al2023) over plain net/http. It returns the same
+// response as main.go, so comparing the two separates what the managed
+// runtime library costs at cold start from what the Go binary itself
+// costs. The response is a constant, so no JSON encoder is linked either;
+// for the same reason it logs no pkg/lambdalog invocation line.
+// Expected result: {"statusCode":200}
+
+const apiVersion = "2018-06-01"
+
+var response = []byte(`{"statusCode":200}`)
+
+// client has no timeout: the next-invocation request blocks for as long
+// as the execution environment stays idle.
+var client = &http.Client{}
+
+func main() {
+	api := "http://" + os.Getenv("AWS_LAMBDA_RUNTIME_API") + "/" + apiVersion + "/runtime"
+	for {
+		id, err := next(api)
+		if err != nil {
+			// The Runtime API is gone; the environment is shutting down.
+			fmt.Fprintln(os.Stderr, "next invocation:", err)
+			os.Exit(1)
+		}
+		if err := post(api+"/invocation/"+id+"/response", response); err != nil {
+			fmt.Fprintln(os.Stderr, "post response:", err)
+		}
+	}
+}
+
+// next waits for the next event and returns its request ID. The event
+// itself is read and discarded.
+func next(api string) (string, error) {
+	resp, err := client.Get(api + "/invocation/next")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %s", resp.Status)
+	}
+	id := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+	if id == "" {
+		return "", fmt.Errorf("no Lambda-Runtime-Aws-Request-Id header")
+	}
+	return id, nil
+}
+
+func post(url string, body []byte) error {
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
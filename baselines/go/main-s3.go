@@ -0,0 +1,66 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"lambdaperf/internal/handler"
+)
+
+// S3-triggered benchmark: download the object named by an events.S3Event
+// and SHA-256 it. I/O-bound, unlike fibonacci. The harness seeds the object
+// (a synthetic 5 MB file) and writes the event: ruchy-bench seed.
+// Expected result: sha256(5242880)=8a54de1b509d976d896796fb95039ac200196f0a5fd21bbcf2c1e32f6d1007e6
+var client *s3.Client
+
+func init() {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		panic(err)
+	}
+	client = s3.NewFromConfig(cfg)
+}
+
+func digest(ctx context.Context, bucket, key string) (string, error) {
+	obj, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return "", err
+	}
+	defer obj.Body.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, obj.Body)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256(%d)=%s", n, hex.EncodeToString(h.Sum(nil))), nil
+}
+
+func hashObjects(ctx context.Context, event events.S3Event) (string, error) {
+	if len(event.Records) == 0 {
+		return "", handler.Status(400, "no S3 records in event")
+	}
+	sums := make([]string, 0, len(event.Records))
+	for _, r := range event.Records {
+		sum, err := digest(ctx, r.S3.Bucket.Name, r.S3.Object.URLDecodedKey)
+		if err != nil {
+			return "", err
+		}
+		sums = append(sums, sum)
+	}
+	return strings.Join(sums, ","), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[events.S3Event]{Name: "s3", Run: hashObjects})
+}
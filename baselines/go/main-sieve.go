@@ -0,0 +1,40 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+
+	"lambdaperf/internal/handler"
+)
+
+// Prime sieve: count the primes up to 10 million with a Sieve of
+// Eratosthenes over a fresh []bool. A large allocation plus strided writes.
+// Source: benchmarks/local-sieve/sieve.go
+// Input: {"limit": 2..50000000}, default 10000000.
+// Expected result: sieve(10000000)=664579
+
+func sieve(n int) int {
+	composite := make([]bool, n+1)
+	count := 0
+	for i := 2; i <= n; i++ {
+		if composite[i] {
+			continue
+		}
+		count++
+		for j := i * i; j <= n; j += i {
+			composite[j] = true
+		}
+	}
+	return count
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.Args]{
+		Name:   "sieve",
+		Inputs: map[string]handler.Input{"limit": {Default: 10_000_000, Min: 2, Max: 50_000_000}},
+		Run: func(_ context.Context, args handler.Args) (string, error) {
+			return handler.Result("sieve", args["limit"], sieve(args["limit"])), nil
+		},
+	})
+}
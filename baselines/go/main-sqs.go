@@ -0,0 +1,80 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"lambdaperf/pkg/lambdalog"
+)
+
+// SQS batch processing: SHA-256 the data of every message in a batch of up
+// to ten delivered by an event source mapping, and report the messages
+// asked to fail as batch item failures, so SQS redelivers only those.
+// ruchy-bench sqs sends the messages to the queue ruchy-bench seed creates
+// and measures each from being sent to being processed, from the records
+// this handler logs.
+// Input: message bodies {"run":...,"seq":...,"fail":bool,"data":...}.
+
+// message is a queue.Message body.
+type message struct {
+	Run  string `json:"run"`
+	Seq  int    `json:"seq"`
+	Fail bool   `json:"fail"`
+	Data string `json:"data"`
+}
+
+// digest is the last message's hash, kept so it is computed.
+var digest [sha256.Size]byte
+
+// handle processes one message, reporting whether it failed. A message
+// asked to fail does so on its first delivery only, so the redelivery
+// succeeds. A body that is not a benchmark message is consumed as is:
+// failing it would only have it redelivered forever.
+func handle(m events.SQSMessage) bool {
+	var msg message
+	if json.Unmarshal([]byte(m.Body), &msg) != nil {
+		return false
+	}
+	digest = sha256.Sum256([]byte(msg.Data))
+	return msg.Fail && m.Attributes["ApproximateReceiveCount"] == "1"
+}
+
+// age is the time since SQS accepted m.
+func age(m events.SQSMessage, now time.Time) float64 {
+	sent, err := strconv.ParseInt(m.Attributes["SentTimestamp"], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return float64(now.Sub(time.UnixMilli(sent))) / float64(time.Millisecond)
+}
+
+func process(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	start := time.Now()
+	var resp events.SQSEventResponse
+	records := make([]lambdalog.Record, 0, len(event.Records))
+	for _, m := range event.Records {
+		failed := handle(m)
+		if failed {
+			resp.BatchItemFailures = append(resp.BatchItemFailures, events.SQSBatchItemFailure{ItemIdentifier: m.MessageId})
+		}
+		records = append(records, lambdalog.Record{ID: m.MessageId, AgeMS: age(m, time.Now()), Failed: failed})
+	}
+	lambdalog.Log(ctx, lambdalog.Entry{
+		Workload: "sqs",
+		Params:   lambdalog.Params{"records": len(event.Records)},
+		Records:  records,
+	}, start, nil)
+	return resp, nil
+}
+
+func main() {
+	lambda.Start(process)
+}
@@ -0,0 +1,99 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"lambdaperf/pkg/lambdalog"
+)
+
+// Response streaming: answer a function URL request in RESPONSE_STREAM
+// mode with a 10 MB body, written in 64 KB chunks as it is produced
+// rather than buffered whole. ruchy-bench stream measures the time to the
+// first body byte and the total transfer time. Needs -tags lambda.norpc,
+// which build.sh and ruchy-bench always pass.
+// Input: query parameters bytes=1..20971520 (default 10485760) and
+// chunk=1..1048576 (default 65536).
+
+const (
+	defaultBytes = 10 << 20
+	// maxBytes is Lambda's default limit on a streamed response.
+	maxBytes     = 20 << 20
+	defaultChunk = 64 << 10
+	maxChunk     = 1 << 20
+)
+
+// pattern fills the body, so a truncated transfer is at least readable.
+const pattern = "ruchy-lambda response streaming benchmark 0123456789abcdefghij\n"
+
+// query reads an integer query parameter within 1..max, def when absent.
+func query(req events.LambdaFunctionURLRequest, name string, def, max int) (int, error) {
+	s, ok := req.QueryStringParameters[name]
+	if !ok {
+		return def, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 1 || v > max {
+		return 0, fmt.Errorf("%s must be an integer between 1 and %d", name, max)
+	}
+	return v, nil
+}
+
+// write sends size bytes in chunk-sized writes. Each write on the pipe
+// blocks until the runtime client has taken it, so the body leaves the
+// function as it is written.
+func write(w io.Writer, size, chunk int) error {
+	buf := []byte(strings.Repeat(pattern, chunk/len(pattern)+1))[:chunk]
+	for size > 0 {
+		n := min(chunk, size)
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+		size -= n
+	}
+	return nil
+}
+
+func stream(ctx context.Context, req events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+	start := time.Now()
+	size, err := query(req, "bytes", defaultBytes, maxBytes)
+	chunk := defaultChunk
+	if err == nil {
+		chunk, err = query(req, "chunk", defaultChunk, maxChunk)
+	}
+	entry := lambdalog.Entry{Workload: "stream", Params: lambdalog.Params{"bytes": size, "chunk": chunk}}
+	if err != nil {
+		lambdalog.Log(ctx, entry, start, err)
+		return &events.LambdaFunctionURLStreamingResponse{
+			StatusCode: 400,
+			Headers:    map[string]string{"Content-Type": "text/plain"},
+			Body:       strings.NewReader(err.Error()),
+		}, nil
+	}
+	r, w := io.Pipe()
+	go func() {
+		err := write(w, size, chunk)
+		// Logged before the pipe closes: once the runtime client reads
+		// EOF the invocation ends and the environment may be frozen.
+		lambdalog.Log(ctx, entry, start, err)
+		w.CloseWithError(err)
+	}()
+	return &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: 200,
+		Headers:    map[string]string{"Content-Type": "application/octet-stream"},
+		Body:       r,
+	}, nil
+}
+
+func main() {
+	lambda.Start(stream)
+}
@@ -0,0 +1,32 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"lambdaperf/internal/handler"
+)
+
+// Timeout-path benchmark: run a second past the function's deadline, like
+// a handler stuck in a call that ignores its context. ruchy-bench deploy
+// gives the function a 1-second timeout (pkg/deploy.TimeoutSec); the
+// platform ends the invocation when it runs out and replaces the execution
+// environment. `ruchy-bench errors` measures how long the caller waits and
+// how the timeout reaches it.
+func stall(ctx context.Context, _ handler.NoEvent) (string, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now()
+	}
+	time.Sleep(time.Until(deadline) + time.Second)
+	return "", nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name: "timeout",
+		Run:  stall,
+	})
+}
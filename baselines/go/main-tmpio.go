@@ -0,0 +1,99 @@
+//go:build baseline
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"lambdaperf/internal/handler"
+)
+
+// Ephemeral storage benchmark: write a 512 MB file to /tmp in 4 MB
+// chunks, fsync it, read it back in 4 MB chunks checking every byte, and
+// delete it. Each chunk is a repeating 0..255 pattern led by its index,
+// so a chunk read back out of place fails the check. Write and read
+// throughput are reported apart; the read may be served from the page
+// cache, as it would be for any function reading back what it wrote.
+// Deployed with 1 GB of /tmp; `ruchy-bench storage` sweeps the size.
+// Input: {"mb": 1..10240}, default 512.
+// Expected result: tmpio(512)=128 chunks
+const (
+	defaultMB = 512
+	chunkSize = 4 << 20
+)
+
+// pattern is a chunk without its index.
+var pattern = func() []byte {
+	b := make([]byte, chunkSize)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}()
+
+func tmpio(ctx context.Context, args handler.Args) (string, error) {
+	mb := args["mb"]
+	size := int64(mb) << 20
+	path := filepath.Join(os.TempDir(), "tmpio.dat")
+	defer os.Remove(path)
+
+	chunks := 0
+	buf := bytes.Clone(pattern)
+	err := handler.TimeWrite(ctx, size, func() error {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		for off := int64(0); off < size; off += chunkSize {
+			binary.BigEndian.PutUint64(buf, uint64(chunks))
+			if _, err := f.Write(buf[:min(chunkSize, size-off)]); err != nil {
+				return err
+			}
+			chunks++
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
+		return f.Close()
+	})
+	if err != nil {
+		return "", fmt.Errorf("write %d MB to %s: %w", mb, path, err)
+	}
+
+	err = handler.TimeRead(ctx, size, func() error {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		for i := 0; i < chunks; i++ {
+			n := min(chunkSize, size-int64(i)*chunkSize)
+			if _, err := io.ReadFull(f, buf[:n]); err != nil {
+				return err
+			}
+			if binary.BigEndian.Uint64(buf) != uint64(i) || !bytes.Equal(buf[8:n], pattern[8:n]) {
+				return fmt.Errorf("chunk %d read back corrupted", i)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("read %d MB from %s: %w", mb, path, err)
+	}
+	return handler.Result("tmpio", mb, fmt.Sprintf("%d chunks", chunks)), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.Args]{
+		Name:   "tmpio",
+		Inputs: map[string]handler.Input{"mb": {Default: defaultMB, Min: 1, Max: 10240}},
+		Run:    tmpio,
+	})
+}
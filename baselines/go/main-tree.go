@@ -0,0 +1,52 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+
+	"lambdaperf/internal/handler"
+)
+
+// Binary tree: build a complete tree of 2^19-1 heap-allocated nodes, then
+// walk it summing the node items. Nearly all of the time goes to
+// allocation, and the collector runs while the tree is live, so it
+// measures the allocator and GC rather than arithmetic. Max memory used in
+// the REPORT line shows each runtime's per-node overhead.
+// Source: benchmarks/local-tree/tree.go
+// Input: {"depth": 1..22}, default 19.
+// Expected result: tree(19)=137438691328
+
+type node struct {
+	left, right *node
+	item        int
+}
+
+// build returns a tree of the given depth whose root is item; the children
+// of item are 2*item and 2*item+1, so a tree rooted at 1 numbers its nodes
+// 1..2^depth-1.
+func build(item, depth int) *node {
+	n := &node{item: item}
+	if depth > 1 {
+		n.left = build(2*item, depth-1)
+		n.right = build(2*item+1, depth-1)
+	}
+	return n
+}
+
+func (n *node) sum() int {
+	if n == nil {
+		return 0
+	}
+	return n.item + n.left.sum() + n.right.sum()
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.Args]{
+		Name:   "tree",
+		Inputs: map[string]handler.Input{"depth": {Default: 19, Min: 1, Max: 22}},
+		Run: func(_ context.Context, args handler.Args) (string, error) {
+			return handler.Result("tree", args["depth"], build(1, args["depth"]).sum()), nil
+		},
+	})
+}
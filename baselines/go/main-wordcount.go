@@ -0,0 +1,62 @@
+//go:build baseline
+
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"lambdaperf/internal/handler"
+)
+
+// Word count: lowercase and tokenize the bundled ~2 MB corpus into runs of
+// ASCII letters, count each word and report the most frequent one. Branchy
+// byte scanning plus hash map updates.
+// Source: benchmarks/local-wordcount/wordcount.go
+// Expected result: wordcount(words=376128,unique=1124,top=the:37764)
+//
+//go:embed corpus/corpus.txt
+var corpus []byte
+
+func wordcount(text []byte) string {
+	counts := make(map[string]int)
+	words := 0
+	word := make([]byte, 0, 32)
+	for i := 0; i <= len(text); i++ {
+		var c byte
+		if i < len(text) {
+			c = text[i]
+		}
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if 'a' <= c && c <= 'z' {
+			word = append(word, c)
+			continue
+		}
+		if len(word) > 0 {
+			counts[string(word)]++
+			words++
+			word = word[:0]
+		}
+	}
+
+	top, topCount := "", 0
+	for w, n := range counts {
+		if n > topCount || n == topCount && w < top {
+			top, topCount = w, n
+		}
+	}
+	return fmt.Sprintf("wordcount(words=%d,unique=%d,top=%s:%d)", words, len(counts), top, topCount)
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:   "wordcount",
+		Params: handler.Params{"corpus_bytes": len(corpus)},
+		Run: func(context.Context, handler.NoEvent) (string, error) {
+			return wordcount(corpus), nil
+		},
+	})
+}
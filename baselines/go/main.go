@@ -1,9 +1,13 @@
+//go:build baseline
+
 package main
 
 import (
 	"context"
 
 	"github.com/aws/aws-lambda-go/lambda"
+
+	"lambdaperf/pkg/lambdalog"
 )
 
 type testResponse struct {
@@ -17,5 +21,5 @@ func handleRequest(ctx context.Context) (testResponse, error) {
 }
 
 func main() {
-	lambda.Start(handleRequest)
+	lambda.Start(lambdalog.Wrap("minimal", nil, handleRequest))
 }
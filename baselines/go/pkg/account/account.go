@@ -0,0 +1,130 @@
+// Package account is the AWS account the harness benchmarks in, as the
+// aws section of bench.yaml declares it: a named profile from the shared
+// config files, a role to assume with an external ID, and overrides of
+// either per region. A fleet run from a laptop or CI then reaches a
+// dedicated sandbox account through the credentials it already has,
+// without static keys for that account exported anywhere.
+package account
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// DefaultSessionName names the assumed role's sessions unless
+// Credentials.SessionName does, so CloudTrail attributes the calls.
+const DefaultSessionName = "ruchy-bench"
+
+// Account is where the harness's AWS calls go. Its zero value is the
+// default credential chain.
+type Account struct {
+	Credentials `yaml:",inline"`
+	// Regions replace Credentials for calls to one region: an entry
+	// stands alone, inheriting nothing.
+	Regions map[string]Credentials `yaml:"regions,omitempty"`
+}
+
+// Credentials are how to sign calls.
+type Credentials struct {
+	// Profile is a profile of the shared config and credentials files;
+	// none is the default chain, AWS_PROFILE included.
+	Profile string `yaml:"profile,omitempty"`
+	// RoleARN is a role assumed with the profile's credentials, and
+	// ExternalID the external ID its trust policy asks for.
+	RoleARN    string `yaml:"role_arn,omitempty"`
+	ExternalID string `yaml:"external_id,omitempty"`
+	// SessionName defaults to DefaultSessionName, and Duration, the
+	// assumed credentials' lifetime, to STS's hour. They are refreshed
+	// before they expire.
+	SessionName string        `yaml:"session_name,omitempty"`
+	Duration    time.Duration `yaml:"duration,omitempty"`
+}
+
+// For returns the credentials of calls to region.
+func (a Account) For(region string) Credentials {
+	if c, ok := a.Regions[region]; ok {
+		return c
+	}
+	return a.Credentials
+}
+
+// Validate reports credentials that cannot work, naming the region of
+// an override.
+func (a Account) Validate() error {
+	if err := a.Credentials.Validate(); err != nil {
+		return err
+	}
+	for region, c := range a.Regions {
+		if region == "" {
+			return errors.New("credentials for an empty region")
+		}
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("region %s: %w", region, err)
+		}
+	}
+	return nil
+}
+
+// Validate reports credentials that cannot work.
+func (c Credentials) Validate() error {
+	switch {
+	case c.RoleARN != "" && !strings.HasPrefix(c.RoleARN, "arn:"):
+		return fmt.Errorf("role_arn %q is not an ARN", c.RoleARN)
+	case c.RoleARN == "" && (c.ExternalID != "" || c.SessionName != "" || c.Duration != 0):
+		return errors.New("external_id, session_name and duration need a role_arn")
+	case c.Duration != 0 && (c.Duration < 15*time.Minute || c.Duration > 12*time.Hour):
+		return fmt.Errorf("duration %v is outside STS's 15m to 12h", c.Duration)
+	}
+	return nil
+}
+
+// LoadOptions are the options config.LoadDefaultConfig loads c's base
+// credentials with.
+func (c Credentials) LoadOptions() []func(*config.LoadOptions) error {
+	if c.Profile == "" {
+		return nil
+	}
+	return []func(*config.LoadOptions) error{config.WithSharedConfigProfile(c.Profile)}
+}
+
+// Provider returns the credentials of cfg, loaded with c's LoadOptions,
+// with c's role assumed if it has one. Share the result between clients:
+// it caches the assumed credentials.
+func (c Credentials) Provider(cfg aws.Config) aws.CredentialsProvider {
+	if c.RoleARN == "" {
+		return cfg.Credentials
+	}
+	return aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), c.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = c.SessionName
+		if o.RoleSessionName == "" {
+			o.RoleSessionName = DefaultSessionName
+		}
+		if c.ExternalID != "" {
+			o.ExternalID = aws.String(c.ExternalID)
+		}
+		if c.Duration != 0 {
+			o.Duration = c.Duration
+		}
+	}))
+}
+
+func (c Credentials) String() string {
+	var parts []string
+	if c.Profile != "" {
+		parts = append(parts, "profile "+c.Profile)
+	}
+	if c.RoleARN != "" {
+		parts = append(parts, "role "+c.RoleARN)
+	}
+	if len(parts) == 0 {
+		return "default credentials"
+	}
+	return strings.Join(parts, ", ")
+}
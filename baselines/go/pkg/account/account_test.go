@@ -0,0 +1,76 @@
+package account
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestFor(t *testing.T) {
+	a := Account{
+		Credentials: Credentials{Profile: "bench", RoleARN: "arn:aws:iam::123456789012:role/bench"},
+		Regions:     map[string]Credentials{"eu-west-1": {Profile: "bench-eu"}},
+	}
+	if c := a.For("us-east-1"); c != a.Credentials {
+		t.Errorf("us-east-1 = %+v", c)
+	}
+	if c := a.For("eu-west-1"); c.Profile != "bench-eu" || c.RoleARN != "" {
+		t.Errorf("eu-west-1 = %+v, want the override alone", c)
+	}
+	if err := a.Validate(); err != nil {
+		t.Error(err)
+	}
+	for name, bad := range map[string]Account{
+		"not an ARN":     {Credentials: Credentials{RoleARN: "bench"}},
+		"no role":        {Credentials: Credentials{ExternalID: "x"}},
+		"short duration": {Credentials: Credentials{RoleARN: "arn:aws:iam::1:role/r", Duration: time.Minute}},
+		"bad override":   {Regions: map[string]Credentials{"eu-west-1": {SessionName: "s"}}},
+		"empty region":   {Regions: map[string]Credentials{"": {}}},
+	} {
+		if bad.Validate() == nil {
+			t.Errorf("%s: validates", name)
+		}
+	}
+	if n := len((Credentials{}).LoadOptions()); n != 0 {
+		t.Errorf("%d load options without a profile", n)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write([]byte(`<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><AssumeRoleResult>
+<Credentials><AccessKeyId>ASSUMED</AccessKeyId><SecretAccessKey>s</SecretAccessKey><SessionToken>t</SessionToken>
+<Expiration>2099-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+	}))
+	defer srv.Close()
+	cfg := aws.Config{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "secret", ""),
+	}
+	if creds, err := (Credentials{}).Provider(cfg).Retrieve(context.Background()); err != nil || creds.AccessKeyID != "AKID" {
+		t.Errorf("credentials without a role = %v, %v", creds.AccessKeyID, err)
+	}
+	c := Credentials{RoleARN: "arn:aws:iam::123456789012:role/bench", ExternalID: "ext-42", Duration: time.Hour}
+	creds, err := c.Provider(cfg).Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != "ASSUMED" {
+		t.Errorf("access key = %s", creds.AccessKeyID)
+	}
+	if form.Get("Action") != "AssumeRole" || form.Get("RoleArn") != c.RoleARN || form.Get("ExternalId") != "ext-42" ||
+		form.Get("RoleSessionName") != DefaultSessionName || form.Get("DurationSeconds") != "3600" {
+		t.Errorf("AssumeRole request = %v", form)
+	}
+}
@@ -0,0 +1,215 @@
+// Package async drives the async workload, whose baseline
+// (main-async.go) is invoked with InvocationType=Event: Lambda accepts
+// the event, queues it internally and runs the function later, retrying
+// it up to twice when it fails. Nothing comes back to the caller, so
+// every attempt leaves a completion marker, a small JSON object under
+// Prefix in the S3 fixture bucket; the harness lists them to time each
+// event from being submitted to being completed. Events asked to fail do
+// so on every attempt, and once Lambda gives up on one it sends a record
+// of it to the function's on-failure destination, the dead-letter queue
+// ruchy-bench seed creates, where the harness picks it up.
+//
+// Submit times are the harness's clock and completion times the
+// function's, so end-to-end latency carries their skew; run the harness
+// from an instance kept in sync to trust small differences.
+package async
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// Workload is the workload name of main-async.go.
+	Workload = "async"
+	// DefaultDLQ is the dead-letter queue ruchy-bench seed creates for it.
+	DefaultDLQ = "ruchy-bench-async-dlq"
+	// MarkerPrefix is where in the bucket the handler writes markers.
+	MarkerPrefix = "async/"
+	// MaxRetries is the most retries Lambda makes of a failed event.
+	MaxRetries = 2
+)
+
+// Event is the payload of a benchmark invocation, as main-async.go reads
+// it.
+type Event struct {
+	Run string `json:"run"`
+	Seq int    `json:"seq"`
+	// Fail asks the handler to fail every attempt, so that Lambda retries
+	// the event and finally dead-letters it.
+	Fail bool `json:"fail,omitempty"`
+	// Bucket is where the handler writes its markers; without one it
+	// writes none.
+	Bucket string `json:"bucket,omitempty"`
+}
+
+// Payload encodes e as an invocation payload.
+func (e Event) Payload() []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// Events returns the n events of run, writing markers to bucket, with
+// the fraction fail of them, spread evenly, marked to fail.
+func Events(run string, n int, fail float64, bucket string) []Event {
+	events := make([]Event, n)
+	for i := range events {
+		events[i] = Event{
+			Run:    run,
+			Seq:    i,
+			Fail:   int(float64(i+1)*fail) > int(float64(i)*fail),
+			Bucket: bucket,
+		}
+	}
+	return events
+}
+
+// Prefix is the key prefix of the markers fn writes for run.
+func Prefix(run, fn string) string {
+	return MarkerPrefix + run + "/" + fn + "/"
+}
+
+// MarkerKey is the key of the marker of one attempt at an event. Lambda
+// retries an event under the same request ID, so the attempt's start
+// time tells its markers apart.
+func MarkerKey(e Event, fn, requestID string, started time.Time) string {
+	return fmt.Sprintf("%s%d/%s-%d.json", Prefix(e.Run, fn), e.Seq, requestID, started.UnixNano())
+}
+
+// Marker is the body of a completion marker: one attempt at an event.
+type Marker struct {
+	Run        string `json:"run"`
+	Seq        int    `json:"seq"`
+	RequestID  string `json:"request_id"`
+	StartedMS  int64  `json:"started_ms"`
+	FinishedMS int64  `json:"finished_ms"`
+	Failed     bool   `json:"failed,omitempty"`
+}
+
+// DeadLetter is Lambda's record of an event it gave up on, as delivered
+// to an on-failure destination.
+type DeadLetter struct {
+	Run string `json:"-"`
+	Seq int    `json:"-"`
+	// Function is the unqualified name of the function that failed.
+	Function  string `json:"-"`
+	RequestID string `json:"request_id"`
+	// Condition is why Lambda gave up: "RetriesExhausted", or
+	// "EventAgeExceeded" when the event waited too long to run.
+	Condition string `json:"condition"`
+	// Attempts is how many times Lambda ran the event.
+	Attempts int `json:"attempts"`
+	// At is when Lambda gave up, by its clock.
+	At time.Time `json:"at"`
+}
+
+// ParseDeadLetter decodes an on-failure destination record. Records of
+// events that are not benchmark events are an error.
+func ParseDeadLetter(body string) (DeadLetter, error) {
+	var rec struct {
+		Timestamp      time.Time `json:"timestamp"`
+		RequestContext struct {
+			RequestID              string `json:"requestId"`
+			FunctionArn            string `json:"functionArn"`
+			Condition              string `json:"condition"`
+			ApproximateInvokeCount int    `json:"approximateInvokeCount"`
+		} `json:"requestContext"`
+		RequestPayload *Event `json:"requestPayload"`
+	}
+	if err := json.Unmarshal([]byte(body), &rec); err != nil {
+		return DeadLetter{}, fmt.Errorf("decode failure record: %w", err)
+	}
+	if rec.RequestPayload == nil || rec.RequestPayload.Run == "" {
+		return DeadLetter{}, fmt.Errorf("failure record %s is not of a benchmark event", rec.RequestContext.RequestID)
+	}
+	return DeadLetter{
+		Run:       rec.RequestPayload.Run,
+		Seq:       rec.RequestPayload.Seq,
+		Function:  function(rec.RequestContext.FunctionArn),
+		RequestID: rec.RequestContext.RequestID,
+		Condition: rec.RequestContext.Condition,
+		Attempts:  rec.RequestContext.ApproximateInvokeCount,
+		At:        rec.Timestamp,
+	}, nil
+}
+
+// function returns the name in arn:aws:lambda:<region>:<account>:function:<name>[:<qualifier>].
+func function(arn string) string {
+	_, name, ok := strings.Cut(arn, ":function:")
+	if !ok {
+		return arn
+	}
+	name, _, _ = strings.Cut(name, ":")
+	return name
+}
+
+// Tracker follows a run's events through the markers and dead-letter
+// records of one function.
+type Tracker struct {
+	Run, Function string
+	// Attempts holds the markers of each event, by sequence number, and
+	// DeadLetters the record of each event Lambda gave up on.
+	Attempts    [][]Marker
+	DeadLetters []*DeadLetter
+
+	seen map[Marker]bool
+}
+
+// NewTracker tracks the n events of run invoking fn.
+func NewTracker(run, fn string, n int) *Tracker {
+	return &Tracker{
+		Run:         run,
+		Function:    fn,
+		Attempts:    make([][]Marker, n),
+		DeadLetters: make([]*DeadLetter, n),
+		seen:        map[Marker]bool{},
+	}
+}
+
+// AddMarker records m, reporting whether it is one of the tracker's
+// events. Adding a marker again, as listing the bucket does, changes
+// nothing.
+func (t *Tracker) AddMarker(m Marker) bool {
+	if m.Run != t.Run || m.Seq < 0 || m.Seq >= len(t.Attempts) {
+		return false
+	}
+	if !t.seen[m] {
+		t.seen[m] = true
+		t.Attempts[m.Seq] = append(t.Attempts[m.Seq], m)
+	}
+	return true
+}
+
+// AddDeadLetter records d, reporting whether it is of one of the
+// tracker's events.
+func (t *Tracker) AddDeadLetter(d DeadLetter) bool {
+	if d.Run != t.Run || d.Function != t.Function || d.Seq < 0 || d.Seq >= len(t.DeadLetters) {
+		return false
+	}
+	t.DeadLetters[d.Seq] = &d
+	return true
+}
+
+// Completed returns the attempt that completed event i successfully, if
+// one has.
+func (t *Tracker) Completed(i int) (Marker, bool) {
+	for _, m := range t.Attempts[i] {
+		if !m.Failed {
+			return m, true
+		}
+	}
+	return Marker{}, false
+}
+
+// Pending is the number of events neither completed nor dead-lettered.
+func (t *Tracker) Pending() int {
+	n := 0
+	for i := range t.Attempts {
+		if _, ok := t.Completed(i); !ok && t.DeadLetters[i] == nil {
+			n++
+		}
+	}
+	return n
+}
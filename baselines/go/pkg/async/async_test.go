@@ -0,0 +1,81 @@
+package async
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	events := Events("run-1", 20, 0.25, "b")
+	failing := 0
+	for i, e := range events {
+		if e.Run != "run-1" || e.Seq != i || e.Bucket != "b" {
+			t.Errorf("event %d = %+v", i, e)
+		}
+		if e.Fail {
+			failing++
+		}
+	}
+	if failing != 5 || !events[3].Fail || events[0].Fail {
+		t.Errorf("%d of 20 events fail, want every fourth", failing)
+	}
+	var got Event
+	if err := json.Unmarshal(events[3].Payload(), &got); err != nil || got != events[3] {
+		t.Errorf("payload round trip = %+v, %v", got, err)
+	}
+	key := MarkerKey(events[3], "fn", "req", time.Unix(0, 42))
+	if want := "async/run-1/fn/3/req-42.json"; key != want {
+		t.Errorf("MarkerKey = %q, want %q", key, want)
+	}
+}
+
+func TestParseDeadLetter(t *testing.T) {
+	body := `{"version":"1.0","timestamp":"2026-10-14T12:00:05.5Z",` +
+		`"requestContext":{"requestId":"req","functionArn":"arn:aws:lambda:us-east-1:1:function:baseline-go-async:$LATEST","condition":"RetriesExhausted","approximateInvokeCount":3},` +
+		`"requestPayload":{"run":"run-1","seq":7,"fail":true,"bucket":"b"},` +
+		`"responseContext":{"statusCode":200,"executedVersion":"$LATEST","functionError":"Unhandled"}}`
+	d, err := ParseDeadLetter(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DeadLetter{Run: "run-1", Seq: 7, Function: "baseline-go-async", RequestID: "req", Condition: "RetriesExhausted",
+		Attempts: 3, At: time.Date(2026, 10, 14, 12, 0, 5, 5e8, time.UTC)}
+	if d != want {
+		t.Errorf("ParseDeadLetter = %+v, want %+v", d, want)
+	}
+	if _, err := ParseDeadLetter(`{"requestPayload":{"ORDER_IDS":[1]}}`); err == nil {
+		t.Error("record of a foreign event parsed")
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker("run-1", "fn", 3)
+	failed := Marker{Run: "run-1", Seq: 0, RequestID: "a", StartedMS: 1, FinishedMS: 2, Failed: true}
+	for _, m := range []Marker{
+		failed, failed,
+		{Run: "run-1", Seq: 0, RequestID: "a", StartedMS: 60000, FinishedMS: 60001},
+		{Run: "run-1", Seq: 1, RequestID: "b", StartedMS: 3, FinishedMS: 4, Failed: true},
+	} {
+		tr.AddMarker(m)
+	}
+	if tr.AddMarker(Marker{Run: "run-0", Seq: 2}) || tr.AddMarker(Marker{Run: "run-1", Seq: 3}) {
+		t.Error("marker of another run or out of range tracked")
+	}
+	if len(tr.Attempts[0]) != 2 {
+		t.Errorf("event 0 has %d attempts, want 2: a marker listed twice counts once", len(tr.Attempts[0]))
+	}
+	if m, ok := tr.Completed(0); !ok || m.StartedMS != 60000 {
+		t.Errorf("Completed(0) = %+v, %v, want the retry", m, ok)
+	}
+	if tr.Pending() != 2 {
+		t.Errorf("Pending = %d, want 2", tr.Pending())
+	}
+	if tr.AddDeadLetter(DeadLetter{Run: "run-1", Function: "other", Seq: 1}) {
+		t.Error("dead letter of another function tracked")
+	}
+	tr.AddDeadLetter(DeadLetter{Run: "run-1", Function: "fn", Seq: 1, Attempts: 3})
+	if tr.Pending() != 1 || tr.DeadLetters[1].Attempts != 3 {
+		t.Errorf("Pending = %d after a dead letter, want 1", tr.Pending())
+	}
+}
@@ -0,0 +1,227 @@
+// Package budget stops a benchmark once its estimated spend reaches a
+// cap. Sweeps across memory sizes and concurrency levels get expensive
+// fast, and nothing else about a command bounds what it costs. A Budget
+// adds up what the invocations and provisioned concurrency a command uses
+// are estimated to cost. Every invocation is priced from its REPORT line.
+// Once the total reaches the cap, the Budget cancels the command's
+// context. The command then stops as it does when interrupted, keeping
+// the results collected so far.
+package budget
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/smithy-go/middleware"
+
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/reportparser"
+)
+
+// Exceeded is the cause of the context a Budget cancels, and the error
+// of invocations it refuses.
+type Exceeded struct {
+	MaxUSD, SpentUSD float64
+}
+
+func (e *Exceeded) Error() string {
+	return fmt.Sprintf("estimated spend $%.4f reached the budget of $%.4f", e.SpentUSD, e.MaxUSD)
+}
+
+// Budget caps estimated spend. It is safe for concurrent use; a nil
+// Budget charges nothing and never runs out.
+type Budget struct {
+	MaxUSD float64
+	// Pricing prices invocations and provisioned concurrency. Spend is
+	// estimated without the free tier.
+	Pricing cost.Pricing
+
+	// parent is the Budget of the context New was given, charged along
+	// with this one: a matrix's cap holds across its groups' own.
+	parent *Budget
+	cancel context.CancelCauseFunc
+	mu     sync.Mutex
+	spent  float64
+	// held is the Allocation of each function holding one, by the name
+	// and qualifier it is invoked with.
+	held map[string]*Allocation
+}
+
+type contextKey struct{}
+
+// New returns a Budget capping spend at maxUSD, and a child of ctx that
+// carries it and is canceled once it is spent.
+func New(ctx context.Context, maxUSD float64, p cost.Pricing) (context.Context, *Budget) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	b := &Budget{MaxUSD: maxUSD, Pricing: p, parent: From(ctx), cancel: cancel}
+	return context.WithValue(ctx, contextKey{}, b), b
+}
+
+// From returns the Budget ctx carries, or nil.
+func From(ctx context.Context) *Budget {
+	b, _ := ctx.Value(contextKey{}).(*Budget)
+	return b
+}
+
+// Spent returns the estimated spend so far, in USD.
+func (b *Budget) Spent() float64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
+
+// Err returns an *Exceeded once b or a parent of it is spent, nil before.
+func (b *Budget) Err() error {
+	for ; b != nil; b = b.parent {
+		if spent := b.Spent(); spent >= b.MaxUSD {
+			return &Exceeded{MaxUSD: b.MaxUSD, SpentUSD: spent}
+		}
+	}
+	return nil
+}
+
+// Charge adds usd to the spend of b and its parents, canceling the
+// context of each one it leaves spent.
+func (b *Budget) Charge(usd float64) {
+	for ; b != nil; b = b.parent {
+		b.mu.Lock()
+		b.spent += usd
+		spent := b.spent
+		b.mu.Unlock()
+		if spent >= b.MaxUSD {
+			b.cancel(&Exceeded{MaxUSD: b.MaxUSD, SpentUSD: spent})
+		}
+	}
+}
+
+// Invocation charges one invocation of function, qualified as it was
+// invoked, priced by its REPORT line rep. While function holds an
+// Allocation the invocation is priced on the allocation's architecture,
+// at the provisioned duration rate unless it was a cold start: those
+// spilled over the allocation into an on-demand environment, and are
+// billed as one. Otherwise the REPORT line does not name the
+// architecture, so the invocation is priced on demand as x86_64, the
+// dearer one.
+func (b *Budget) Invocation(function string, rep reportparser.Report) {
+	if b == nil {
+		return
+	}
+	u := cost.Usage{MemoryMB: int32(rep.MemorySizeMB), BilledMS: rep.BilledDurationMS + rep.BilledRestoreDurationMS, Invocations: 1}
+	b.mu.Lock()
+	if a := b.held[function]; a != nil {
+		u.Arch, u.Provisioned = a.arch, rep.InitDurationMS == 0
+	}
+	b.mu.Unlock()
+	usd, err := b.Pricing.Estimate(u, false)
+	if err != nil {
+		// Pricing without the architecture charges the request alone.
+		usd.Total = b.Pricing.PerRequest
+	}
+	b.Charge(usd.Total)
+}
+
+// Allocation charges provisioned concurrency to a Budget while it is
+// held. A nil Allocation charges nothing.
+type Allocation struct {
+	b                     *Budget
+	function, arch        string
+	memoryMB, concurrency int32
+	start                 time.Time
+	mu                    sync.Mutex
+	charged               float64
+}
+
+// Allocate starts charging concurrency environments of memoryMB on arch
+// for function, qualified as it is invoked, beginning with the minimum
+// Lambda bills for. Until Release, the function's warm invocations are
+// priced as provisioned ones.
+func (b *Budget) Allocate(function, arch string, memoryMB, concurrency int32) *Allocation {
+	if b == nil {
+		return nil
+	}
+	a := &Allocation{b: b, function: function, arch: arch, memoryMB: memoryMB, concurrency: concurrency, start: time.Now()}
+	b.mu.Lock()
+	if b.held == nil {
+		b.held = map[string]*Allocation{}
+	}
+	b.held[function] = a
+	b.mu.Unlock()
+	a.Update()
+	return a
+}
+
+// Release charges the rest of the allocation once it is released, and
+// prices the function's later invocations on demand again.
+func (a *Allocation) Release() {
+	if a == nil {
+		return
+	}
+	a.Update()
+	a.b.mu.Lock()
+	if a.b.held[a.function] == a {
+		delete(a.b.held, a.function)
+	}
+	a.b.mu.Unlock()
+}
+
+// Update charges the time the allocation has been held since the last
+// Update. Call it as the allocation is used.
+func (a *Allocation) Update() {
+	if a == nil {
+		return
+	}
+	usd, err := a.b.Pricing.Provisioned(a.arch, a.memoryMB, a.concurrency, time.Since(a.start))
+	if err != nil {
+		return
+	}
+	a.mu.Lock()
+	usd, a.charged = usd-a.charged, usd
+	a.mu.Unlock()
+	a.b.Charge(usd)
+}
+
+// Qualified names function as Invocation and Allocate take it: with
+// ":qualifier" appended unless qualifier is empty.
+func Qualified(function, qualifier string) string {
+	if qualifier == "" {
+		return function
+	}
+	return function + ":" + qualifier
+}
+
+// Middleware adds a step to an AWS client's middleware stack that charges
+// every Lambda invocation to the Budget its context carries, priced by
+// the REPORT line in its log tail (the request alone without one), and
+// refuses invocations once that Budget is spent. Other requests, and
+// requests without a Budget, pass straight through. Add it to every
+// client through aws.Config.APIOptions.
+func Middleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("Budget",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			b := From(ctx)
+			params, ok := in.Parameters.(*lambda.InvokeInput)
+			if !ok || b == nil {
+				return next.HandleInitialize(ctx, in)
+			}
+			if err := b.Err(); err != nil {
+				return middleware.InitializeOutput{}, middleware.Metadata{}, err
+			}
+			out, md, err := next.HandleInitialize(ctx, in)
+			if res, ok := out.Result.(*lambda.InvokeOutput); ok && err == nil {
+				var rep reportparser.Report
+				if logs, derr := base64.StdEncoding.DecodeString(aws.ToString(res.LogResult)); derr == nil {
+					rep, _ = reportparser.Last(string(logs))
+				}
+				b.Invocation(Qualified(aws.ToString(params.FunctionName), aws.ToString(params.Qualifier)), rep)
+			}
+			return out, md, err
+		}), middleware.After)
+}
@@ -0,0 +1,130 @@
+package budget
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/smithy-go/middleware"
+
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/reportparser"
+)
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-12 }
+
+func TestCharge(t *testing.T) {
+	outer, matrix := New(context.Background(), 1, cost.Default)
+	ctx, run := New(outer, 0.5, cost.Default)
+	run.Charge(0.3)
+	if ctx.Err() != nil || run.Err() != nil {
+		t.Fatalf("spent after $0.30 of $0.50: %v", run.Err())
+	}
+	run.Charge(0.3)
+	var exceeded *Exceeded
+	if !errors.As(context.Cause(ctx), &exceeded) || exceeded.MaxUSD != 0.5 || !approx(exceeded.SpentUSD, 0.6) {
+		t.Errorf("cause = %v, want $0.60 of $0.50", context.Cause(ctx))
+	}
+	// The matrix's budget is charged too, but not yet spent.
+	if !approx(matrix.Spent(), 0.6) || outer.Err() != nil {
+		t.Errorf("matrix spent %v, err %v", matrix.Spent(), outer.Err())
+	}
+	run.Charge(0.5)
+	if outer.Err() == nil || matrix.Err() == nil {
+		t.Error("matrix budget not spent after $1.10 of $1")
+	}
+
+	var none *Budget
+	none.Charge(1)
+	none.Invocation("f", reportparser.Report{MemorySizeMB: 128, BilledDurationMS: 100})
+	none.Allocate("f", cost.ArchX86, 128, 1).Release()
+	if none.Spent() != 0 || none.Err() != nil {
+		t.Error("nil Budget charged")
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	_, b := New(context.Background(), 100, cost.Default)
+	a := b.Allocate("f:provisioned", cost.ArchARM64, 1024, 2)
+	// The five-minute minimum is charged up front, and not again.
+	want, _ := cost.Default.Provisioned(cost.ArchARM64, 1024, 2, 0)
+	a.Update()
+	if !approx(b.Spent(), want) {
+		t.Errorf("spent %v, want the minimum %v", b.Spent(), want)
+	}
+
+	// Invocations of the allocated alias pay the provisioned duration
+	// rate on its architecture, and the function's others the on-demand
+	// one.
+	warm := reportparser.Report{MemorySizeMB: 1024, BilledDurationMS: 100}
+	served, _ := cost.Default.Estimate(cost.Usage{Arch: cost.ArchARM64, MemoryMB: 1024, BilledMS: 100, Invocations: 1, Provisioned: true}, false)
+	onDemand, _ := cost.Default.Estimate(cost.Usage{MemoryMB: 1024, BilledMS: 100, Invocations: 1}, false)
+	b.Invocation(Qualified("f", "provisioned"), warm)
+	if want += served.Total; !approx(b.Spent(), want) {
+		t.Errorf("spent %v after a provisioned invocation, want %v", b.Spent(), want)
+	}
+	// A cold start on the alias spilled over the allocation, and pays the
+	// on-demand rate on its architecture.
+	spilled, _ := cost.Default.Estimate(cost.Usage{Arch: cost.ArchARM64, MemoryMB: 1024, BilledMS: 100, Invocations: 1}, false)
+	cold := warm
+	cold.InitDurationMS = 180
+	b.Invocation(Qualified("f", "provisioned"), cold)
+	if want += spilled.Total; !approx(b.Spent(), want) {
+		t.Errorf("spent %v after a spillover invocation, want %v", b.Spent(), want)
+	}
+	b.Invocation("f", warm)
+	a.Release()
+	b.Invocation("f:provisioned", warm)
+	if want += 2 * onDemand.Total; !approx(b.Spent(), want) {
+		t.Errorf("spent %v after on-demand invocations, want %v", b.Spent(), want)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	report := "REPORT RequestId: a\tDuration: 99.10 ms\tBilled Duration: 100 ms\tMemory Size: 1024 MB\tMax Memory Used: 20 MB\t\n"
+	var invoked int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		invoked++
+		w.Header().Set("X-Amz-Log-Result", base64.StdEncoding.EncodeToString([]byte(report)))
+		io.WriteString(w, `{}`)
+	}))
+	defer srv.Close()
+	client := lambda.New(lambda.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		Credentials:  credentials.NewStaticCredentialsProvider("AKID", "secret", ""),
+		APIOptions:   []func(*middleware.Stack) error{Middleware},
+	})
+	in := &lambda.InvokeInput{FunctionName: aws.String("f")}
+
+	// Without a Budget, invocations pass straight through.
+	if _, err := client.Invoke(context.Background(), in); err != nil {
+		t.Fatal(err)
+	}
+
+	one, _ := cost.Default.Estimate(cost.Usage{MemoryMB: 1024, BilledMS: 100, Invocations: 1}, false)
+	ctx, b := New(context.Background(), 1.5*one.Total, cost.Default)
+	if _, err := client.Invoke(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+	if !approx(b.Spent(), one.Total) {
+		t.Errorf("spent %v after one invocation, want %v", b.Spent(), one.Total)
+	}
+	client.Invoke(ctx, in)
+	// The second invocation spent the budget; a third is refused.
+	var exceeded *Exceeded
+	if _, err := client.Invoke(context.WithoutCancel(ctx), in); !errors.As(err, &exceeded) {
+		t.Errorf("invocation after the budget = %v, want *Exceeded", err)
+	}
+	if invoked != 3 {
+		t.Errorf("%d invocations reached Lambda, want 3", invoked)
+	}
+}
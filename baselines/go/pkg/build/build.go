@@ -0,0 +1,253 @@
+// Package build turns discovered targets into runnable artifacts: local
+// binaries (or interpreter command lines), Lambda deployment zips and
+// Lambda container images.
+//
+// The compiler invocations mirror benchmarks/local-fibonacci/benchmark-framework.sh
+// so numbers stay comparable with the existing bashrs results.
+package build
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/pkgzip"
+)
+
+// Artifact is the output of building a target.
+type Artifact struct {
+	Target discover.Target
+	// Command is the argv that runs a local target.
+	Command []string
+	// Package is the deployment zip of a Lambda target.
+	Package string
+	// SHA256 is the base64 SHA-256 of Package, comparable with the
+	// CodeSha256 Lambda reports for the function deployed from it.
+	SHA256 string
+	// Image is the local tag of an image target's container image, which
+	// is built from Package.
+	Image string
+	// BinaryBytes is the stripped size of the compiled binary; zero for
+	// interpreted targets. PackageBytes is the size of Package, or the
+	// uncompressed size of Image.
+	BinaryBytes  int64
+	PackageBytes int64
+	// Reused reports that Package was not rebuilt: see Builder.Reuse.
+	Reused bool
+}
+
+// Builder compiles targets into OutDir.
+type Builder struct {
+	// Root is the repository root.
+	Root string
+	// OutDir receives build artifacts, one directory per target.
+	OutDir string
+	// Log receives compiler and build script output. Nil discards it.
+	Log io.Writer
+	// Pprof builds Go Lambda targets with the pprof tag, which links in
+	// per-invocation profiling; see pkg/profiles.
+	Pprof bool
+	// Reuse skips building a Lambda target whose sources, toolchain and
+	// options hash as they did when its package was last built into
+	// OutDir, and returns that package instead.
+	Reuse bool
+
+	mu sync.Mutex
+	// trees holds the digest of every source tree hashed so far.
+	trees map[string]string
+}
+
+// Build compiles t and returns its artifact.
+func (b *Builder) Build(ctx context.Context, t discover.Target) (Artifact, error) {
+	dir := filepath.Join(b.OutDir, string(t.Kind), t.Runtime, t.Workload, t.Arch)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Artifact{}, err
+	}
+	var (
+		a   Artifact
+		err error
+	)
+	switch t.Kind {
+	case discover.KindLocal:
+		a, err = b.buildLocal(ctx, t, dir)
+	case discover.KindLambda:
+		a, err = b.buildLambdaOnce(ctx, t, dir)
+	default:
+		err = fmt.Errorf("unknown target kind %q", t.Kind)
+	}
+	if err == nil {
+		err = measure(&a)
+	}
+	if err == nil && t.Kind == discover.KindLambda && t.Package == discover.PackageImage {
+		err = b.buildImage(ctx, t, dir, &a)
+	}
+	if err != nil {
+		return Artifact{}, fmt.Errorf("build %s: %w", t.ID(), err)
+	}
+	a.Target = t
+	return a, nil
+}
+
+func (b *Builder) buildLocal(ctx context.Context, t discover.Target, dir string) (Artifact, error) {
+	bin := filepath.Join(dir, t.Workload)
+	var compile []string
+	switch t.Runtime {
+	case "go":
+		compile = []string{"go", "build", "-o", bin, t.Source}
+	case discover.TinyGo:
+		compile = append(append([]string{"tinygo", "build"}, TinyGoFlags...), "-o", bin, t.Source)
+	case discover.Wasm:
+		return b.buildWasmLocal(ctx, t, dir)
+	case "rust":
+		compile = []string{"rustc", "-C", "opt-level=3", t.Source, "-o", bin}
+	case "c":
+		compile = []string{"gcc", "-O3", t.Source, "-o", bin, "-lm"}
+	case "ruchy":
+		compile = []string{"ruchy", "compile", t.Source, "-o", bin}
+	case "python":
+		return Artifact{Command: []string{"python3", t.Source}}, nil
+	case "julia":
+		return Artifact{Command: []string{"julia", t.Source}}, nil
+	default:
+		return Artifact{}, fmt.Errorf("no local toolchain for runtime %q", t.Runtime)
+	}
+	if err := b.run(ctx, t.Dir, nil, compile...); err != nil {
+		return Artifact{}, err
+	}
+	return Artifact{Command: []string{bin}}, nil
+}
+
+// buildLambdaOnce builds the Lambda package of t unless b.Reuse finds it
+// built from the same inputs, and records the inputs it was built from.
+func (b *Builder) buildLambdaOnce(ctx context.Context, t discover.Target, dir string) (Artifact, error) {
+	inputs, err := b.inputs(ctx, t)
+	if err != nil {
+		return Artifact{}, err
+	}
+	if b.Reuse {
+		if a, ok := reuse(dir, inputs); ok {
+			return a, nil
+		}
+	}
+	a, err := b.buildLambda(ctx, t, dir)
+	if err == nil && inputs != "" {
+		err = writeRecord(dir, inputs, a)
+	}
+	return a, err
+}
+
+func (b *Builder) buildLambda(ctx context.Context, t discover.Target, dir string) (Artifact, error) {
+	pkg := filepath.Join(dir, "function.zip")
+	if t.Arch == discover.ArchARM64 && t.Runtime != "go" && t.Runtime != discover.TinyGo && t.Runtime != discover.Wasm && t.Runtime != "python" {
+		return Artifact{}, fmt.Errorf("no arm64 build for runtime %q", t.Runtime)
+	}
+	switch t.Runtime {
+	case "go":
+		bin := filepath.Join(dir, "bootstrap")
+		env := []string{"GOOS=linux", "GOARCH=" + GoArch(t.Arch), "CGO_ENABLED=0"}
+		tags := "lambda.norpc"
+		if b.Pprof {
+			tags += ",pprof"
+		}
+		if t.Serializer != "" {
+			// The serializer's name is its internal/codec build tag.
+			tags += "," + t.Serializer
+		}
+		argv := append([]string{"go", "build", "-tags", tags}, Reproducible...)
+		if err := b.run(ctx, t.Dir, env, append(argv, "-o", bin, t.Source)...); err != nil {
+			return Artifact{}, err
+		}
+		if err := pkgzip.Write(pkg, pkgzip.Entry{Name: bootstrap, Src: bin, Mode: 0o755}); err != nil {
+			return Artifact{}, err
+		}
+	case discover.TinyGo:
+		bin := filepath.Join(dir, "bootstrap")
+		env := []string{"GOOS=linux", "GOARCH=" + GoArch(t.Arch)}
+		argv := append([]string{"tinygo", "build"}, TinyGoFlags...)
+		if err := b.run(ctx, t.Dir, env, append(argv, "-o", bin, t.Source)...); err != nil {
+			return Artifact{}, err
+		}
+		if err := pkgzip.Write(pkg, pkgzip.Entry{Name: bootstrap, Src: bin, Mode: 0o755}); err != nil {
+			return Artifact{}, err
+		}
+	case discover.Wasm:
+		if err := b.buildWasm(ctx, t, dir, pkg); err != nil {
+			return Artifact{}, err
+		}
+	case "python":
+		if err := pkgzip.Write(pkg, pkgzip.Entry{Name: "index.py", Src: t.Source, Mode: 0o644}); err != nil {
+			return Artifact{}, err
+		}
+	case "ruchy":
+		script := filepath.Join(b.Root, "scripts", "build-lambda-package.sh")
+		if err := b.run(ctx, b.Root, nil, script, t.Workload); err != nil {
+			return Artifact{}, err
+		}
+		pkg = filepath.Join(b.Root, "target", "lambda-packages", t.FunctionName()+".zip")
+	default:
+		// Rust and C++ baselines are built by their lambda-perf build.sh.
+		if err := b.run(ctx, t.Dir, nil, "./build.sh"); err != nil {
+			return Artifact{}, err
+		}
+		pkg = filepath.Join(t.Dir, "function.zip")
+	}
+	if _, err := os.Stat(pkg); err != nil {
+		return Artifact{}, fmt.Errorf("package not produced: %w", err)
+	}
+	// Scripts zip with whatever timestamps and order the machine gives.
+	if t.Runtime != "go" && t.Runtime != discover.TinyGo && t.Runtime != discover.Wasm && t.Runtime != "python" {
+		if err := pkgzip.Normalize(pkg); err != nil {
+			return Artifact{}, err
+		}
+	}
+	sum, err := pkgzip.Hash(pkg)
+	if err != nil {
+		return Artifact{}, err
+	}
+	return Artifact{Package: pkg, SHA256: sum}, nil
+}
+
+// Reproducible are the go build flags of every binary deployed to Lambda.
+// -trimpath, -buildvcs=false and an empty build ID keep the binary
+// identical across checkouts, commits and machines, and -s -w strip its
+// symbol table and DWARF as strip(1) would, so its package hashes alike
+// wherever the same code is built.
+var Reproducible = []string{"-trimpath", "-buildvcs=false", "-ldflags=-s -w -buildid="}
+
+// TinyGoFlags are the tinygo build flags of every TinyGo binary, local
+// or deployed: -opt=2 optimizes for speed rather than TinyGo's default of
+// size, as the Go toolchain does, and -no-debug strips DWARF as
+// Reproducible strips Go binaries. The garbage collector and scheduler
+// stay TinyGo's defaults for the target, which is what a TinyGo user would
+// ship. Lambda builds cross-compile with GOOS and GOARCH, linking TinyGo's
+// bundled musl statically.
+var TinyGoFlags = []string{"-opt=2", "-no-debug"}
+
+// GoArch maps a Lambda architecture name to its GOARCH value.
+func GoArch(arch string) string {
+	if arch == discover.ArchARM64 {
+		return "arm64"
+	}
+	return "amd64"
+}
+
+func (b *Builder) run(ctx context.Context, dir string, env []string, argv ...string) error {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	out := b.Log
+	if out == nil {
+		out = io.Discard
+	}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", argv[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package build
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/pkgzip"
+)
+
+// recordFile, in a target's build directory, records the inputs its
+// package was last built from.
+const recordFile = "build.json"
+
+type record struct {
+	// Inputs is the digest inputs returned for the build.
+	Inputs  string `json:"inputs"`
+	Package string `json:"package"`
+	SHA256  string `json:"sha256"`
+}
+
+// digestToolchains are the toolchains whose versions a Lambda package
+// depends on, by runtime. C++ builds in Docker and Python ships its
+// source, so neither depends on a local one.
+var digestToolchains = map[string][]string{
+	"go":            {"go"},
+	discover.TinyGo: {"tinygo"},
+	discover.Wasm:   {"go"},
+	"rust":          {"rust"},
+	"ruchy":         {"ruchy", "rust"},
+}
+
+// inputs returns a digest of everything the Lambda package of t is built
+// from: the files of its source tree, the toolchain versions and the
+// build options. Two builds with the same digest give the same package.
+// The digest is empty when a toolchain's version cannot be read, and
+// then matches no record.
+func (b *Builder) inputs(ctx context.Context, t discover.Target) (string, error) {
+	h := sha256.New()
+	// Only what changes the package: the +ext, +vpc and +chaos variants
+	// of a target deploy its package as it is.
+	fmt.Fprintf(h, "%s %s %s %s %s %s pprof=%t wasmtime=%s\n", t.Runtime, t.Workload, t.Arch, t.Package, t.Serializer, t.Source, b.Pprof, WasmtimeVersion)
+	for _, runtime := range digestToolchains[t.Runtime] {
+		v, err := ToolchainVersion(ctx, runtime)
+		if err != nil {
+			return "", nil
+		}
+		fmt.Fprintf(h, "%s %s\n", runtime, v)
+	}
+	var trees []string
+	switch t.Runtime {
+	case "go", discover.TinyGo:
+		trees = []string{b.goModule(), t.Source}
+	case discover.Wasm:
+		trees = []string{t.Source, b.goModule()}
+	case "python":
+		trees = []string{t.Source}
+	case "ruchy":
+		trees = []string{
+			filepath.Join(b.Root, "crates"),
+			filepath.Join(b.Root, "scripts", "build-lambda-package.sh"),
+			filepath.Join(b.Root, "Cargo.toml"),
+			filepath.Join(b.Root, "Cargo.lock"),
+		}
+	default:
+		trees = []string{t.Dir}
+	}
+	for _, tree := range trees {
+		sum, err := b.treeDigest(tree)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %s\n", tree, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// skipInput reports whether a file or directory under a source tree
+// cannot change a package: build output, tests, VCS metadata and, in
+// the Go module, the harness itself.
+func skipInput(name string, dir bool) bool {
+	switch {
+	case strings.HasPrefix(name, "."):
+		return true
+	case dir:
+		return name == "target" || name == "testdata" || name == "cmd"
+	}
+	return strings.HasSuffix(name, "_test.go") || strings.HasSuffix(name, ".zip")
+}
+
+// treeDigest hashes the names and contents of the files under path, or
+// of the file at path, once per Builder: the Go module is hashed for
+// every Go target.
+func (b *Builder) treeDigest(path string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if sum, ok := b.trees[path]; ok {
+		return sum, nil
+	}
+	// Each main*.go at the root of the Go module is a handler built on
+	// its own, hashed as its target's source, so that changing one
+	// rebuilds only its functions.
+	handlers := path == b.goModule()
+	h := sha256.New()
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != path && skipInput(d.Name(), d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || handlers && filepath.Dir(p) == path && filepath.Ext(p) == ".go" {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		rel, _ := filepath.Rel(path, p)
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		// Cargo.lock, say, is optional: its absence is an input too.
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if b.trees == nil {
+		b.trees = map[string]string{}
+	}
+	b.trees[path] = sum
+	return sum, nil
+}
+
+func (b *Builder) goModule() string {
+	return filepath.Join(b.Root, "baselines", "go")
+}
+
+// reuse returns the package recorded in dir if it was built from inputs
+// and is still there, as it was built.
+func reuse(dir, inputs string) (Artifact, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, recordFile))
+	if err != nil || inputs == "" {
+		return Artifact{}, false
+	}
+	var r record
+	if json.Unmarshal(data, &r) != nil || r.Inputs != inputs {
+		return Artifact{}, false
+	}
+	sum, err := pkgzip.Hash(r.Package)
+	if err != nil || sum != r.SHA256 {
+		return Artifact{}, false
+	}
+	return Artifact{Package: r.Package, SHA256: sum, Reused: true}, true
+}
+
+// writeRecord records that the package of a was built from inputs.
+func writeRecord(dir, inputs string, a Artifact) error {
+	data, err := json.Marshal(record{Inputs: inputs, Package: a.Package, SHA256: a.SHA256})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, recordFile), data, 0o644)
+}
@@ -0,0 +1,58 @@
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lambdaperf/pkg/discover"
+)
+
+func TestReuse(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "baselines", "python", "index.py")
+	if err := os.MkdirAll(filepath.Dir(src), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("def handler(e, c): return 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target := discover.Target{Runtime: "python", Workload: "minimal", Kind: discover.KindLambda, Arch: discover.ArchX86, Dir: filepath.Dir(src), Source: src}
+	build := func(b *Builder) Artifact {
+		t.Helper()
+		a, err := b.Build(context.Background(), target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	out := filepath.Join(root, ".bench", "build")
+	first := build(&Builder{Root: root, OutDir: out, Reuse: true})
+	if first.Reused {
+		t.Error("first build reused a package")
+	}
+	again := build(&Builder{Root: root, OutDir: out, Reuse: true})
+	if !again.Reused || again.SHA256 != first.SHA256 || again.PackageBytes != first.PackageBytes {
+		t.Errorf("unchanged rebuild = %+v, want %+v reused", again, first)
+	}
+	if a := build(&Builder{Root: root, OutDir: out}); a.Reused {
+		t.Error("reused without Reuse")
+	}
+
+	if err := os.WriteFile(src, []byte("def handler(e, c): return 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changed := build(&Builder{Root: root, OutDir: out, Reuse: true})
+	if changed.Reused || changed.SHA256 == first.SHA256 {
+		t.Errorf("changed source reused %+v", changed)
+	}
+	// A package changed since it was built is rebuilt.
+	if err := os.WriteFile(changed.Package, []byte("zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if a := build(&Builder{Root: root, OutDir: out, Reuse: true}); a.Reused || a.SHA256 != changed.SHA256 {
+		t.Errorf("tampered package reused: %+v", a)
+	}
+}
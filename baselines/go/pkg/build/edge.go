@@ -0,0 +1,51 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/pkgzip"
+)
+
+// BuildEdge packages a Python Lambda target as a Lambda@Edge function: its
+// baseline as index.py beside the viewer-request adapter edge.py, which
+// calls it. It returns the zip's path.
+func (b *Builder) BuildEdge(ctx context.Context, t discover.Target) (string, error) {
+	if t.Kind != discover.KindLambda || t.Runtime != "python" {
+		return "", fmt.Errorf("build %s: only Python Lambda targets run at the edge", t.ID())
+	}
+	dir := filepath.Join(b.OutDir, "edge", t.Workload)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	pkg := filepath.Join(dir, "function.zip")
+	adapter := filepath.Join(b.Root, "baselines", "python", "edge.py")
+	if err := pkgzip.Write(pkg, pkgzip.Entry{Name: "index.py", Src: t.Source, Mode: 0o644}, pkgzip.Entry{Name: "edge.py", Src: adapter, Mode: 0o644}); err != nil {
+		return "", fmt.Errorf("build %s for the edge: %w", t.ID(), err)
+	}
+	return pkg, nil
+}
+
+// BuildProbe compiles baselines/go/probe for x86_64 and packages it like a
+// Go baseline, returning the zip's path.
+func (b *Builder) BuildProbe(ctx context.Context) (string, error) {
+	dir := filepath.Join(b.OutDir, "probe")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	bin := filepath.Join(dir, "bootstrap")
+	src := filepath.Join(b.Root, "baselines", "go")
+	env := []string{"GOOS=linux", "GOARCH=" + GoArch(discover.ArchX86), "CGO_ENABLED=0"}
+	argv := append([]string{"go", "build", "-tags", "lambda.norpc"}, Reproducible...)
+	if err := b.run(ctx, src, env, append(argv, "-o", bin, "./probe")...); err != nil {
+		return "", fmt.Errorf("build probe: %w", err)
+	}
+	pkg := filepath.Join(dir, "function.zip")
+	if err := pkgzip.Write(pkg, pkgzip.Entry{Name: bootstrap, Src: bin, Mode: 0o755}); err != nil {
+		return "", err
+	}
+	return pkg, nil
+}
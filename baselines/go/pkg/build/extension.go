@@ -0,0 +1,46 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"lambdaperf/pkg/pkgzip"
+)
+
+// The extensions under baselines/go/extensions. Lambda starts every
+// executable in /opt/extensions, and each registers under its file name.
+const (
+	// NoopExtension is attached to extension variants of targets.
+	NoopExtension = "noop-telemetry"
+	// TelemetryExtension records Telemetry API phase timings; see
+	// pkg/telemetryext.
+	TelemetryExtension = "telemetry"
+	// ChaosExtension contends for the CPU and memory of chaos variants of
+	// targets; see pkg/chaos.
+	ChaosExtension = "chaos"
+)
+
+// BuildExtension compiles the named extension for arch and packages it as
+// a layer zip, returning the zip's path.
+func (b *Builder) BuildExtension(ctx context.Context, name, arch string) (string, error) {
+	dir := filepath.Join(b.OutDir, "extension", name, arch)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	bin := filepath.Join(dir, name)
+	src := filepath.Join(b.Root, "baselines", "go")
+	env := []string{"GOOS=linux", "GOARCH=" + GoArch(arch), "CGO_ENABLED=0"}
+	// A reproducible binary, and so layer, lets deploy.PublishLayer reuse
+	// a published version.
+	argv := append([]string{"go", "build"}, Reproducible...)
+	if err := b.run(ctx, src, env, append(argv, "-o", bin, "./extensions/"+name)...); err != nil {
+		return "", fmt.Errorf("build extension %s: %w", name, err)
+	}
+	pkg := filepath.Join(dir, "layer.zip")
+	if err := pkgzip.Write(pkg, pkgzip.Entry{Name: "extensions/" + name, Src: bin, Mode: 0o755}); err != nil {
+		return "", err
+	}
+	return pkg, nil
+}
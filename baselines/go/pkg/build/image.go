@@ -0,0 +1,124 @@
+package build
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"lambdaperf/pkg/discover"
+)
+
+// ImageRepository is the name image targets are tagged under, locally and
+// as the ECR repository they are pushed to. The tag is the target's
+// function name.
+const ImageRepository = "ruchy-bench"
+
+// ImageTag returns the local reference of t's image.
+func ImageTag(t discover.Target) string {
+	return ImageRepository + ":" + t.FunctionName()
+}
+
+// dockerfile layers the contents of t's deployment zip on an AWS Lambda
+// base image, so the image and zip variants run the same bytes and differ
+// only in packaging.
+func dockerfile(t discover.Target) string {
+	if t.Runtime == "python" {
+		return "FROM public.ecr.aws/lambda/python:3.12\n" +
+			"COPY . ${LAMBDA_TASK_ROOT}/\n" +
+			`CMD ["index.handler"]` + "\n"
+	}
+	// The custom runtime image's entrypoint starts
+	// ${LAMBDA_RUNTIME_DIR}/bootstrap. The rest of the package goes where a
+	// zip deployment would put it, since packaged bootstraps (C++) refer to
+	// ${LAMBDA_TASK_ROOT}. COPY rather than RUN keeps arm64 builds free of
+	// emulation.
+	return "FROM public.ecr.aws/lambda/provided:al2023\n" +
+		"COPY . ${LAMBDA_TASK_ROOT}/\n" +
+		"COPY " + bootstrap + " ${LAMBDA_RUNTIME_DIR}/\n" +
+		`CMD ["` + bootstrap + `"]` + "\n"
+}
+
+// buildImage builds the container image of t from the deployment zip in
+// a with docker. The image's size replaces the zip's as the package size.
+func (b *Builder) buildImage(ctx context.Context, t discover.Target, dir string, a *Artifact) error {
+	files := filepath.Join(dir, "image")
+	if err := os.RemoveAll(files); err != nil {
+		return err
+	}
+	if err := unzip(a.Package, files); err != nil {
+		return fmt.Errorf("unpack %s: %w", a.Package, err)
+	}
+	file := filepath.Join(dir, "Dockerfile")
+	if err := os.WriteFile(file, []byte(dockerfile(t)), 0o644); err != nil {
+		return err
+	}
+	tag := ImageTag(t)
+	// Lambda rejects image indexes, which buildx produces when it attaches
+	// provenance attestations.
+	if err := b.run(ctx, dir, nil, "docker", "build", "--platform", "linux/"+GoArch(t.Arch),
+		"--provenance=false", "-f", file, "-t", tag, files); err != nil {
+		return err
+	}
+	out, err := exec.CommandContext(ctx, "docker", "image", "inspect", "--format", "{{.Size}}", tag).Output()
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", tag, err)
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("inspect %s: size %q: %w", tag, out, err)
+	}
+	a.Image, a.PackageBytes = tag, size
+	return nil
+}
+
+// unzip extracts the archive at src into dir, keeping file modes so the
+// bootstrap stays executable.
+func unzip(src, dir string) error {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if !filepath.IsLocal(f.Name) {
+			return fmt.Errorf("entry %q escapes the archive", f.Name)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := extract(f, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extract(f *zip.File, path string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, f.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
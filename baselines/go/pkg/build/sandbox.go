@@ -0,0 +1,27 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// BuildTimer compiles baselines/go/timer for the host's architecture,
+// which sandboxes run local workloads under, and returns the binary's
+// path; see pkg/sandbox.
+func (b *Builder) BuildTimer(ctx context.Context) (string, error) {
+	dir := filepath.Join(b.OutDir, "timer")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	bin := filepath.Join(dir, "timer")
+	src := filepath.Join(b.Root, "baselines", "go")
+	// Static, so it runs on whatever image the sandbox has.
+	env := []string{"GOOS=linux", "GOARCH=" + runtime.GOARCH, "CGO_ENABLED=0"}
+	if err := b.run(ctx, src, env, "go", "build", "-trimpath", "-o", bin, "./timer"); err != nil {
+		return "", fmt.Errorf("build timer: %w", err)
+	}
+	return bin, nil
+}
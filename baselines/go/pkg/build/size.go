@@ -0,0 +1,101 @@
+package build
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/elf"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// bootstrap is the executable a custom-runtime deployment zip must contain.
+const bootstrap = "bootstrap"
+
+// measure fills in the artifact's sizes: the stripped size of the compiled
+// binary, taken from the package when there is one, and the package size.
+func measure(a *Artifact) error {
+	if a.Package == "" {
+		if len(a.Command) != 1 {
+			return nil // interpreted: no binary of its own
+		}
+		f, err := os.Open(a.Command[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		a.BinaryBytes, err = strippedSize(f, info.Size())
+		return err
+	}
+
+	info, err := os.Stat(a.Package)
+	if err != nil {
+		return err
+	}
+	a.PackageBytes = info.Size()
+	zr, err := zip.OpenReader(a.Package)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name != bootstrap {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		a.BinaryBytes, err = strippedSize(bytes.NewReader(data), int64(len(data)))
+		return err
+	}
+	return nil // e.g. a Python package: the runtime supplies the binary
+}
+
+// strippedSize is the size the size-byte ELF binary in r would have after
+// strip(1): the file less its symbol tables and debug sections. Go and Rust
+// binaries carry both by default, so their raw size overstates what a
+// release build ships. Non-ELF files (local builds on macOS) report zero.
+func strippedSize(r io.ReaderAt, size int64) (int64, error) {
+	f, err := elf.NewFile(r)
+	var notELF *elf.FormatError
+	if errors.As(err, &notELF) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var removed int64
+	for _, s := range f.Sections {
+		if s.Type == elf.SHT_NOBITS || !strippable(s) {
+			continue
+		}
+		removed += int64(s.FileSize)
+	}
+	return size - removed, nil
+}
+
+// strippable reports whether strip(1) removes s by default.
+func strippable(s *elf.Section) bool {
+	switch {
+	case s.Type == elf.SHT_SYMTAB:
+		return true
+	case s.Name == ".strtab":
+		return true
+	case strings.HasPrefix(s.Name, ".debug_"), strings.HasPrefix(s.Name, ".zdebug_"):
+		return true
+	}
+	return false
+}
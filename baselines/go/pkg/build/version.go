@@ -0,0 +1,50 @@
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// versionCommands print the version of the toolchain Build uses for each
+// runtime. Lambda Python targets run on the managed python3.12 runtime
+// whatever the local interpreter.
+var versionCommands = map[string][]string{
+	"go":     {"go", "env", "GOVERSION"},
+	"tinygo": {"tinygo", "version"},
+	"wasm":   {"wasmtime", "--version"},
+	"rust":   {"rustc", "--version"},
+	"c":      {"gcc", "--version"},
+	"ruchy":  {"ruchy", "--version"},
+	"python": {"python3", "--version"},
+	"julia":  {"julia", "--version"},
+}
+
+// ToolchainVersion returns the first line the toolchain of runtime prints
+// as its version, such as "go1.24.2" or "rustc 1.86.0 (05f9846f8 2025-03-31)".
+func ToolchainVersion(ctx context.Context, runtime string) (string, error) {
+	argv, ok := versionCommands[runtime]
+	if !ok {
+		return "", fmt.Errorf("no local toolchain for runtime %q", runtime)
+	}
+	out, err := exec.CommandContext(ctx, argv[0], argv[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", argv[0], err)
+	}
+	line, _, _ := bytes.Cut(bytes.TrimSpace(out), []byte("\n"))
+	return string(bytes.TrimSpace(line)), nil
+}
+
+// LambdaGoVersion returns the aws-lambda-go version the Go baselines
+// under root build with, such as "v1.50.0".
+func LambdaGoVersion(ctx context.Context, root string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "list", "-m", "-f", "{{.Version}}", "github.com/aws/aws-lambda-go")
+	cmd.Dir = filepath.Join(root, "baselines", "go")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("go list aws-lambda-go: %w", err)
+	}
+	return string(bytes.TrimSpace(out)), nil
+}
@@ -0,0 +1,116 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/pkgzip"
+)
+
+// WasmtimeVersion is the Wasmtime release Lambda wasm targets ship and
+// precompile their modules with. A precompiled module only loads in the
+// Wasmtime that compiled it, so both come from the same release.
+const WasmtimeVersion = "29.0.1"
+
+// wasmEnv compiles Go for WASI preview 1, which Wasmtime runs as a
+// command.
+var wasmEnv = []string{"GOOS=wasip1", "GOARCH=wasm"}
+
+// buildWasm packages t's local Go program for Lambda: the program
+// compiled to a WASI module and precompiled for t's architecture, the
+// wasmtime binary that runs it, and baselines/go/wasmrt as the bootstrap
+// that runs it per invocation.
+func (b *Builder) buildWasm(ctx context.Context, t discover.Target, dir, pkg string) error {
+	arch := wasmtimeArch(t.Arch)
+	host, err := b.fetchWasmtime(ctx, wasmtimeArch(runtime.GOARCH)+"-"+wasmtimeOS(runtime.GOOS))
+	if err != nil {
+		return err
+	}
+	shipped, err := b.fetchWasmtime(ctx, arch+"-linux")
+	if err != nil {
+		return err
+	}
+	module := filepath.Join(dir, "module.wasm")
+	argv := append([]string{"go", "build"}, Reproducible...)
+	if err := b.run(ctx, t.Dir, wasmEnv, append(argv, "-o", module, t.Source)...); err != nil {
+		return err
+	}
+	compiled := filepath.Join(dir, "module.cwasm")
+	if err := b.run(ctx, dir, nil, host, "compile", "--target", arch+"-unknown-linux-gnu", "-o", compiled, module); err != nil {
+		return err
+	}
+	bin := filepath.Join(dir, "bootstrap")
+	env := []string{"GOOS=linux", "GOARCH=" + GoArch(t.Arch), "CGO_ENABLED=0"}
+	ldflags := fmt.Sprintf("-ldflags=-s -w -buildid= -X main.workload=%s -X main.wasmtimeVersion=%s", t.Workload, WasmtimeVersion)
+	src := filepath.Join(b.Root, "baselines", "go")
+	if err := b.run(ctx, src, env, "go", "build", "-trimpath", "-buildvcs=false", ldflags, "-o", bin, "./wasmrt"); err != nil {
+		return err
+	}
+	return pkgzip.Write(pkg,
+		pkgzip.Entry{Name: bootstrap, Src: bin, Mode: 0o755},
+		pkgzip.Entry{Name: "wasmtime", Src: shipped, Mode: 0o755},
+		pkgzip.Entry{Name: "module.cwasm", Src: compiled, Mode: 0o644},
+	)
+}
+
+// buildWasmLocal compiles t's Go program to a WASI module and returns the
+// command running it under the wasmtime on the PATH.
+func (b *Builder) buildWasmLocal(ctx context.Context, t discover.Target, dir string) (Artifact, error) {
+	module := filepath.Join(dir, t.Workload+".wasm")
+	if err := b.run(ctx, t.Dir, wasmEnv, "go", "build", "-o", module, t.Source); err != nil {
+		return Artifact{}, err
+	}
+	return Artifact{Command: []string{"wasmtime", "run", module}}, nil
+}
+
+// fetchWasmtime returns the wasmtime binary of WasmtimeVersion for
+// platform, such as "x86_64-linux", downloading its release into OutDir
+// the first time. The release is unpacked beside its directory and moved
+// into place, so builds running at once never see half of it.
+func (b *Builder) fetchWasmtime(ctx context.Context, platform string) (string, error) {
+	dir := filepath.Join(b.OutDir, "wasmtime", WasmtimeVersion, platform)
+	bin := filepath.Join(dir, "wasmtime")
+	if _, err := os.Stat(bin); err == nil {
+		return bin, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), platform+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+	url := fmt.Sprintf("https://github.com/bytecodealliance/wasmtime/releases/download/v%[1]s/wasmtime-v%[1]s-%[2]s.tar.xz", WasmtimeVersion, platform)
+	if err := b.run(ctx, tmp, nil, "sh", "-c", `curl -fsSL "$1" | tar -xJ --strip-components=1`, "sh", url); err != nil {
+		return "", fmt.Errorf("fetch wasmtime %s for %s: %w", WasmtimeVersion, platform, err)
+	}
+	// Another build may have got there first.
+	if err := os.Rename(tmp, dir); err != nil {
+		if _, statErr := os.Stat(bin); statErr != nil {
+			return "", err
+		}
+	}
+	return bin, nil
+}
+
+// wasmtimeArch maps a Lambda architecture or GOARCH, which both call ARM
+// arm64, to the architecture Wasmtime names its releases and targets by.
+func wasmtimeArch(arch string) string {
+	if arch == discover.ArchARM64 {
+		return "aarch64"
+	}
+	return "x86_64"
+}
+
+// wasmtimeOS maps a GOOS to the OS Wasmtime names its releases by.
+func wasmtimeOS(goos string) string {
+	if goos == "darwin" {
+		return "macos"
+	}
+	return goos
+}
@@ -0,0 +1,186 @@
+// Package bundle packs one run into a gzipped tarball anyone can attach
+// to an issue: its raw samples and metadata as a results file, the
+// rendered report, and excerpts of the measured functions' logs. Whoever
+// disputes a published number can then show exactly what they measured,
+// and anyone can re-render or re-analyze it with ruchy-bench.
+//
+// Everything in the bundle is scrubbed of what identifies the AWS account
+// it was measured in: account IDs, ARNs, access key IDs and the IDs in
+// API Gateway, function URL and CloudFront hostnames. Function names are
+// the harness's own, and are kept.
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"lambdaperf/pkg/results"
+)
+
+// Bundle is what is packed.
+type Bundle struct {
+	Run *results.Run
+	// Report is the run rendered as Markdown.
+	Report []byte
+	// Logs holds the log excerpt of each function, by a name unique in
+	// the run, such as the function's name and region.
+	Logs map[string][]string
+}
+
+var scrubbers = []struct {
+	re   *regexp.Regexp
+	with string
+}{
+	// The resource of an ARN can name the account, as buckets and roles
+	// often do, so it goes with the account ID.
+	{regexp.MustCompile(`(arn:aws[\w-]*:[\w-]+:[\w-]*:)\d*:[\w\-/:.+=@$*]+`), "${1}[account]:[resource]"},
+	{regexp.MustCompile(`\b(AKIA|ASIA)[A-Z0-9]{16}\b`), "[access key]"},
+	{regexp.MustCompile(`\b[a-z0-9]{8,}\.(execute-api\.[\w-]+\.amazonaws\.com|lambda-url\.[\w-]+\.on\.aws|cloudfront\.net)\b`), "[id].$1"},
+	// Twelve digits alone are an account ID; within a request ID, a
+	// base64 package hash or another token they are not.
+	{regexp.MustCompile(`(^|[^\w\-/+=])\d{12}($|[^\w\-/+=])`), "${1}[account]${2}"},
+}
+
+// Scrub replaces what identifies an AWS account in s.
+func Scrub(s string) string {
+	for _, sc := range scrubbers {
+		s = sc.re.ReplaceAllString(s, sc.with)
+	}
+	return s
+}
+
+// scrubJSON scrubs every string of a JSON document, object keys included,
+// and leaves its numbers exactly as they were.
+func scrubJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var walk func(any) any
+	walk = func(v any) any {
+		switch v := v.(type) {
+		case string:
+			return Scrub(v)
+		case []any:
+			for i := range v {
+				v[i] = walk(v[i])
+			}
+		case map[string]any:
+			out := make(map[string]any, len(v))
+			for k, e := range v {
+				out[Scrub(k)] = walk(e)
+			}
+			return out
+		}
+		return v
+	}
+	out, err := json.MarshalIndent(walk(v), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+type file struct {
+	name string
+	data []byte
+}
+
+// Write writes the bundle to w as a gzipped tarball whose files are
+// under a directory named after the run.
+func (b Bundle) Write(w io.Writer) error {
+	data, err := results.Encode(b.Run)
+	if err != nil {
+		return err
+	}
+	if data, err = scrubJSON(data); err != nil {
+		return err
+	}
+	files := []file{
+		{"README.md", []byte(Scrub(b.readme()))},
+		{"results.json", data},
+		{"report.md", []byte(Scrub(string(b.Report)))},
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.Logs)) {
+		var log strings.Builder
+		for _, line := range b.Logs[name] {
+			log.WriteString(Scrub(line) + "\n")
+		}
+		files = append(files, file{"logs/" + logName(name), []byte(log.String())})
+	}
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	// Every file carries the run's start as its time, so bundling a run
+	// twice gives identical bytes.
+	mtime := b.Run.StartedAt
+	if mtime.IsZero() {
+		mtime = time.Unix(0, 0)
+	}
+	for _, f := range files {
+		hdr := &tar.Header{Name: path.Join(b.Run.ID, f.name), Mode: 0o644, Size: int64(len(f.data)), ModTime: mtime, Format: tar.FormatPAX}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// logName is the file name of a log excerpt: name with anything but
+// letters, digits, dots and hyphens replaced.
+func logName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, name) + ".log"
+}
+
+// readme describes the bundle to whoever unpacks it.
+func (b Bundle) readme() string {
+	run := b.Run
+	var s strings.Builder
+	fmt.Fprintf(&s, "# ruchy-bench run %s\n\n", run.ID)
+	fmt.Fprintf(&s, "Mode `%s`, %d results, started %s and finished %s.\n", run.Mode, len(run.Results),
+		run.StartedAt.UTC().Format(time.RFC3339), run.FinishedAt.UTC().Format(time.RFC3339))
+	if m := run.Metadata; m != nil && m.Commit != "" {
+		dirty := ""
+		if m.Dirty {
+			dirty = ", with uncommitted changes"
+		}
+		fmt.Fprintf(&s, "Measured from commit %s%s.\n", m.Commit, dirty)
+	}
+	s.WriteString(`
+- results.json: every raw sample and the run's metadata, as ruchy-bench
+  wrote them (check it with "ruchy-bench validate results.json").
+- report.md: the run rendered by "ruchy-bench report results.json".
+`)
+	if len(b.Logs) > 0 {
+		s.WriteString("- logs/: the measured functions' CloudWatch log lines during the run.\n")
+	}
+	s.WriteString(`
+Account IDs, ARNs, access key IDs and endpoint IDs are replaced by
+placeholders such as [account]. Re-analyze the samples from a checkout of
+the commit with "ruchy-bench analyze results.json".
+`)
+	return s.String()
+}
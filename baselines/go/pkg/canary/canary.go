@@ -0,0 +1,67 @@
+// Package canary makes the verification invoke a function gets after it
+// is deployed and before it is benchmarked. A function that answers with
+// the wrong result, a function error or another runtime's identity (a
+// stale package, a mixed-up function name, a handler built from the
+// wrong source) would otherwise produce samples that measure nothing;
+// the canary stops the benchmark before they are collected.
+//
+// Handlers report their identity in the Field of their response:
+// "runtime":"go" from internal/handler, and likewise in the Python, Rust
+// and Ruchy baselines. Handlers without it, such as the lambda-perf
+// minimal handlers, have only their result checked.
+package canary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/results"
+)
+
+// Field is the response field handlers report their runtime in.
+const Field = "runtime"
+
+// Result is what the canary invoke found.
+type Result struct {
+	Response invoke.Response
+	// Runtime is the runtime the handler reported in Field; empty when
+	// its response has none, so its identity went unchecked.
+	Runtime string
+}
+
+// Check invokes inv once with payload. It fails when the invocation
+// does, when the response does not report expected (empty accepts any
+// result) and when the handler reports a runtime other than runtime.
+func Check(ctx context.Context, inv invoke.Invoker, payload []byte, runtime, expected string) (Result, error) {
+	resp, err := inv.Invoke(ctx, payload)
+	res := Result{Response: resp, Runtime: Identity(resp.Payload)}
+	switch {
+	case err != nil:
+		return res, fmt.Errorf("canary: %w", err)
+	case resp.FunctionError != "":
+		return res, fmt.Errorf("canary: function error %s: %s", resp.FunctionError, results.Body(resp.Payload))
+	}
+	if s := (results.Sample{}).WithResponse(resp.Payload).Verify(expected); s.Error != "" {
+		return res, fmt.Errorf("canary: %s", s.Error)
+	}
+	if res.Runtime != "" && res.Runtime != runtime {
+		return res, fmt.Errorf("canary: the handler reports runtime %q, want %q", res.Runtime, runtime)
+	}
+	return res, nil
+}
+
+// Identity returns the runtime a handler response reports in Field, or
+// "" when it is not a JSON object with one.
+func Identity(payload []byte) string {
+	var resp map[string]json.RawMessage
+	if json.Unmarshal(payload, &resp) != nil {
+		return ""
+	}
+	var runtime string
+	if json.Unmarshal(resp[Field], &runtime) != nil {
+		return ""
+	}
+	return runtime
+}
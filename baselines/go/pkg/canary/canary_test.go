@@ -0,0 +1,48 @@
+package canary
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"lambdaperf/pkg/invoke"
+)
+
+// answer is an invoker that always responds the same way.
+type answer struct {
+	resp invoke.Response
+	err  error
+}
+
+func (a answer) Invoke(context.Context, []byte) (invoke.Response, error) { return a.resp, a.err }
+
+func TestCheck(t *testing.T) {
+	const want = "fibonacci(35)=9227465"
+	respond := func(payload string) answer { return answer{resp: invoke.Response{Payload: []byte(payload)}} }
+	for _, tc := range []struct {
+		name    string
+		inv     answer
+		runtime string
+		err     string
+	}{
+		{"verified", respond(`{"statusCode":200,"body":"fibonacci(35)=9227465","runtime":"go"}`), "go", ""},
+		{"no identity", respond(`{"statusCode":200,"body":"fibonacci(35)=9227465"}`), "", ""},
+		{"text response", respond("fibonacci(35)=9227465\n"), "", ""},
+		{"other runtime", respond(`{"statusCode":200,"body":"fibonacci(35)=9227465","runtime":"python"}`), "python", `reports runtime "python", want "go"`},
+		{"wrong result", respond(`{"statusCode":200,"body":"fibonacci(35)=0","runtime":"go"}`), "go", `wrong result "fibonacci(35)=0"`},
+		{"function error", answer{resp: invoke.Response{FunctionError: "Unhandled", Payload: []byte(`{"errorMessage":"boom"}`)}}, "", "function error Unhandled"},
+		{"invoke error", answer{err: errors.New("throttled")}, "", "throttled"},
+	} {
+		res, err := Check(context.Background(), tc.inv, []byte("{}"), "go", want)
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+			t.Errorf("%s: error %v, want %q", tc.name, err, tc.err)
+		}
+		if res.Runtime != tc.runtime {
+			t.Errorf("%s: runtime %q, want %q", tc.name, res.Runtime, tc.runtime)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Package chaos is the contention the chaos extension
+// (baselines/go/extensions/chaos) puts an execution environment under:
+// goroutines spinning on a share of every vCPU and a block of memory held
+// resident, both competing with the runtime for what the function's
+// memory size buys. Benchmarking -chaos variants beside the bare
+// functions shows how each runtime degrades when it does not have the
+// environment to itself, as it rarely does beside observability agents
+// and other extensions.
+//
+// Lambda freezes an environment between invocations, so the spinning
+// only happens while it is thawed: during init and invocations. Like
+// lambdalog it links nothing beyond the standard library: it runs inside
+// every environment it disturbs.
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The environment variables that configure the extension, each a
+// fraction between 0 and 1; ruchy-bench deploy sets them on -chaos
+// variants from -chaos-cpu and -chaos-memory.
+const (
+	CPUEnv    = "BENCH_CHAOS_CPU"
+	MemoryEnv = "BENCH_CHAOS_MEMORY"
+)
+
+// Default is the contention of -chaos variants unless deploy is told
+// otherwise: half of every vCPU and a quarter of the memory.
+var Default = Config{CPU: 0.5, Memory: 0.25}
+
+// Period is the time over which a burner spins for its share: short
+// enough that every invocation of a few milliseconds meets it.
+const Period = 10 * time.Millisecond
+
+// Config is how much of the environment the extension takes.
+type Config struct {
+	// CPU is the share of each vCPU's time a burner goroutine spins
+	// for. Where the memory size's CPU quota is less than the burners
+	// ask, they and the runtime split what there is.
+	CPU float64
+	// Memory is the share of the function's memory held resident.
+	Memory float64
+}
+
+// Validate reports a share out of range. Memory stops short of 1,
+// which would leave the runtime nothing.
+func (c Config) Validate() error {
+	switch {
+	case c.CPU < 0 || c.CPU > 1:
+		return fmt.Errorf("chaos CPU share %g is not between 0 and 1", c.CPU)
+	case c.Memory < 0 || c.Memory >= 1:
+		return fmt.Errorf("chaos memory share %g is not at least 0 and below 1", c.Memory)
+	}
+	return nil
+}
+
+// Env is c as the environment variables the extension reads.
+func (c Config) Env() map[string]string {
+	return map[string]string{
+		CPUEnv:    strconv.FormatFloat(c.CPU, 'f', -1, 64),
+		MemoryEnv: strconv.FormatFloat(c.Memory, 'f', -1, 64),
+	}
+}
+
+// FromEnv reads the Config from the environment; an unset share is 0.
+func FromEnv() (Config, error) {
+	var c Config
+	for name, v := range map[string]*float64{CPUEnv: &c.CPU, MemoryEnv: &c.Memory} {
+		s := os.Getenv(name)
+		if s == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s: %w", name, err)
+		}
+		*v = f
+	}
+	return c, c.Validate()
+}
+
+// pageSize is what Hold touches a byte of: any page size Lambda runs
+// on divides it.
+const pageSize = 4096
+
+// Hold allocates share of memoryMB and writes to every page of it, so
+// the kernel backs all of it. Keep the result reachable for as long as
+// it should be held.
+func Hold(share float64, memoryMB int) []byte {
+	n := int(share * float64(memoryMB) * (1 << 20))
+	if n <= 0 {
+		return nil
+	}
+	b := make([]byte, n)
+	for i := 0; i < n; i += pageSize {
+		b[i] = 1
+	}
+	return b
+}
+
+// Burn runs cpus goroutines, each spinning for share of every Period
+// and sleeping the rest, until ctx is done.
+func Burn(ctx context.Context, share float64, cpus int) {
+	if share <= 0 || cpus <= 0 {
+		return
+	}
+	spin := time.Duration(share * float64(Period))
+	var wg sync.WaitGroup
+	for range cpus {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				start := time.Now()
+				for time.Since(start) < spin {
+				}
+				time.Sleep(Period - time.Since(start))
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,49 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFromEnv(t *testing.T) {
+	for name, v := range (Config{CPU: 0.75, Memory: 0.1}).Env() {
+		t.Setenv(name, v)
+	}
+	if c, err := FromEnv(); err != nil || c != (Config{CPU: 0.75, Memory: 0.1}) {
+		t.Errorf("FromEnv = %+v, %v", c, err)
+	}
+	t.Setenv(CPUEnv, "")
+	if c, err := FromEnv(); err != nil || c.CPU != 0 {
+		t.Errorf("FromEnv without a CPU share = %+v, %v", c, err)
+	}
+	for _, bad := range []Config{{CPU: 1.5}, {CPU: -0.1}, {Memory: 1}} {
+		if bad.Validate() == nil {
+			t.Errorf("%+v validates", bad)
+		}
+	}
+	t.Setenv(MemoryEnv, "lots")
+	if _, err := FromEnv(); err == nil {
+		t.Error("a share that is not a number read")
+	}
+}
+
+func TestHold(t *testing.T) {
+	if b := Hold(0.25, 8); len(b) != 2<<20 || b[0] != 1 || b[pageSize] != 1 {
+		t.Errorf("held %d bytes", len(b))
+	}
+	if Hold(0, 128) != nil {
+		t.Error("held memory at a share of 0")
+	}
+}
+
+func TestBurn(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*Period)
+	defer cancel()
+	start := time.Now()
+	Burn(ctx, 0.5, 2)
+	if took := time.Since(start); took < 3*Period || took > 10*Period {
+		t.Errorf("Burn returned after %v, want about %v", took, 3*Period)
+	}
+	Burn(context.Background(), 0, 2)
+}
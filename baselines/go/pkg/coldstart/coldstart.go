@@ -0,0 +1,159 @@
+// Package coldstart forces Lambda cold starts and records the init
+// duration the platform reports for the first invocation afterwards.
+//
+// A cold start is forced the same way scripts/measure-aws-performance.sh
+// does it: changing a no-op environment variable (FORCE_COLD_START)
+// publishes a new configuration, so the next invocation lands on a fresh
+// execution environment. SnapStart functions additionally need a new
+// version published from that configuration, whose first invocation is a
+// snapshot restore rather than an init.
+package coldstart
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/canary"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/reportparser"
+)
+
+// EnvVar is the no-op environment variable rewritten to force a cold start.
+const EnvVar = "FORCE_COLD_START"
+
+// LambdaAPI is the subset of the Lambda client needed to force and
+// measure cold starts.
+type LambdaAPI interface {
+	invoke.LambdaAPI
+	lambda.GetFunctionAPIClient
+	GetFunctionConfiguration(ctx context.Context, in *lambda.GetFunctionConfigurationInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error)
+	UpdateFunctionConfiguration(ctx context.Context, in *lambda.UpdateFunctionConfigurationInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error)
+}
+
+// Measurement is one forced-cold invocation.
+type Measurement struct {
+	ClientMS float64
+	// Report is the invocation's REPORT line; Found is false when the log
+	// tail did not contain one.
+	Report   reportparser.Report
+	Found    bool
+	Response []byte
+	Error    string
+	// Excluded is the class of transient failure the measurement still
+	// failed with after its retries, if any; Retries counts the forced
+	// updates and invocations retried on the way.
+	Excluded invoke.Class
+	Retries  int
+}
+
+// Cold reports whether the platform recorded an init or restore phase.
+func (m Measurement) Cold() bool { return m.Found && m.Report.Cold() }
+
+// Runner forces cold starts on one function and invokes it.
+type Runner struct {
+	Client       LambdaAPI
+	FunctionName string
+	// Qualifier is the version or alias invoked; empty means $LATEST.
+	Qualifier string
+	// Publish, when set, runs after each configuration update. SnapStart
+	// targets use it to publish a version and move Qualifier to it.
+	Publish func(ctx context.Context) error
+	// UpdateTimeout bounds the wait for a configuration update to finish.
+	// Zero means two minutes.
+	UpdateTimeout time.Duration
+	// Backoff retries the update and invocation of a measurement on
+	// transient failures; the zero value tries each once.
+	Backoff invoke.Backoff
+	// Runtime, if set, is the runtime the function was deployed from.
+	// Each measured invocation is then a canary.Check against it and
+	// Expected, and Measure fails if the check does. Without Runtime,
+	// Expected is unused.
+	Runtime, Expected string
+}
+
+// Force rewrites FORCE_COLD_START, keeping every other environment
+// variable, and waits until the update has been applied.
+func (r *Runner) Force(ctx context.Context) error {
+	cfg, err := r.Client.GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(r.FunctionName),
+	})
+	if err != nil {
+		return fmt.Errorf("get configuration of %s: %w", r.FunctionName, err)
+	}
+	vars := map[string]string{}
+	if cfg.Environment != nil {
+		for k, v := range cfg.Environment.Variables {
+			vars[k] = v
+		}
+	}
+	vars[EnvVar] = strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	_, err = r.Client.UpdateFunctionConfiguration(ctx, &lambda.UpdateFunctionConfigurationInput{
+		FunctionName: aws.String(r.FunctionName),
+		Environment:  &types.Environment{Variables: vars},
+	})
+	if err != nil {
+		return fmt.Errorf("update configuration of %s: %w", r.FunctionName, err)
+	}
+
+	timeout := r.UpdateTimeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+	waiter := lambda.NewFunctionUpdatedV2Waiter(r.Client)
+	if err := waiter.Wait(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(r.FunctionName)}, timeout); err != nil {
+		return fmt.Errorf("wait for %s update: %w", r.FunctionName, err)
+	}
+	if r.Publish != nil {
+		return r.Publish(ctx)
+	}
+	return nil
+}
+
+// Measure forces a cold start and performs one invocation, reading the
+// init (or restore) duration from the REPORT line in the tailed logs. A
+// cold start that cannot be forced or invoked for a transient reason,
+// even after retries, is an excluded measurement rather than an error; a
+// failed canary check is an error.
+func (r *Runner) Measure(ctx context.Context, payload []byte) (Measurement, error) {
+	retries, err := r.Backoff.Do(ctx, func() error { return r.Force(ctx) })
+	if err != nil {
+		if class := invoke.Classify(err); class != "" && ctx.Err() == nil {
+			return Measurement{Error: err.Error(), Excluded: class, Retries: retries}, nil
+		}
+		return Measurement{}, err
+	}
+	inv := &invoke.Retry{
+		Invoker: &invoke.Lambda{Client: r.Client, FunctionName: r.FunctionName, Qualifier: r.Qualifier},
+		Backoff: r.Backoff,
+	}
+	var resp invoke.Response
+	if r.Runtime != "" {
+		// The forced invocation is the canary, keeping it cold.
+		var res canary.Result
+		res, err = canary.Check(ctx, inv, payload, r.Runtime, r.Expected)
+		if resp = res.Response; err != nil && invoke.Classify(err) == "" {
+			return Measurement{}, fmt.Errorf("%s: %w", r.FunctionName, err)
+		}
+	} else {
+		resp, err = inv.Invoke(ctx, payload)
+	}
+	m := Measurement{
+		ClientMS: float64(resp.Elapsed) / float64(time.Millisecond),
+		Response: resp.Payload,
+		Error:    resp.FunctionError,
+		Retries:  retries + resp.Retries,
+	}
+	if err != nil {
+		m.Error, m.Excluded = err.Error(), invoke.Classify(err)
+		return m, nil
+	}
+	m.Report, m.Found = reportparser.Last(resp.LogTail)
+	return m, nil
+}
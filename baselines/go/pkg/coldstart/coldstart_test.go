@@ -0,0 +1,160 @@
+package coldstart
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/invoke"
+)
+
+const coldTail = "START RequestId: 8f5c Version: $LATEST\n" +
+	"END RequestId: 8f5c\n" +
+	"REPORT RequestId: 8f5c\tDuration: 1.52 ms\tBilled Duration: 11 ms\tMemory Size: 128 MB\tMax Memory Used: 14 MB\tInit Duration: 8.91 ms\t\n"
+
+type fakeLambda struct {
+	env     map[string]string
+	updated map[string]string
+	tail    string
+	// payload is what invocations return; empty means {"statusCode":200}.
+	payload string
+	// The next throttleUpdates updates and throttleInvokes invocations
+	// fail with 429s.
+	throttleUpdates, throttleInvokes int
+}
+
+func (f *fakeLambda) Invoke(_ context.Context, _ *lambda.InvokeInput, _ ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	if f.throttleInvokes > 0 {
+		f.throttleInvokes--
+		return nil, &types.TooManyRequestsException{}
+	}
+	payload := f.payload
+	if payload == "" {
+		payload = `{"statusCode":200}`
+	}
+	return &lambda.InvokeOutput{
+		StatusCode: 200,
+		Payload:    []byte(payload),
+		LogResult:  aws.String(base64.StdEncoding.EncodeToString([]byte(f.tail))),
+	}, nil
+}
+
+func (f *fakeLambda) GetFunction(_ context.Context, _ *lambda.GetFunctionInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	return &lambda.GetFunctionOutput{Configuration: &types.FunctionConfiguration{
+		State:            types.StateActive,
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	}}, nil
+}
+
+func (f *fakeLambda) GetFunctionConfiguration(_ context.Context, _ *lambda.GetFunctionConfigurationInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error) {
+	return &lambda.GetFunctionConfigurationOutput{
+		Environment: &types.EnvironmentResponse{Variables: f.env},
+	}, nil
+}
+
+func (f *fakeLambda) UpdateFunctionConfiguration(_ context.Context, in *lambda.UpdateFunctionConfigurationInput, _ ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error) {
+	if f.throttleUpdates > 0 {
+		f.throttleUpdates--
+		return nil, &types.TooManyRequestsException{}
+	}
+	f.updated = in.Environment.Variables
+	return &lambda.UpdateFunctionConfigurationOutput{}, nil
+}
+
+func TestForcePreservesEnvironment(t *testing.T) {
+	fake := &fakeLambda{env: map[string]string{"LOG_LEVEL": "info"}}
+	r := &Runner{Client: fake, FunctionName: "baseline-go"}
+	if err := r.Force(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fake.updated["LOG_LEVEL"] != "info" {
+		t.Errorf("existing variable dropped: %v", fake.updated)
+	}
+	if fake.updated[EnvVar] == "" {
+		t.Errorf("%s not set: %v", EnvVar, fake.updated)
+	}
+}
+
+func TestForcePublishesAfterUpdate(t *testing.T) {
+	fake := &fakeLambda{}
+	published := false
+	r := &Runner{Client: fake, FunctionName: "baseline-python-snapstart", Qualifier: "snapstart",
+		Publish: func(context.Context) error {
+			if fake.updated[EnvVar] == "" {
+				t.Error("published before the configuration update")
+			}
+			published = true
+			return nil
+		}}
+	if err := r.Force(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !published {
+		t.Error("Publish hook not called")
+	}
+}
+
+func TestMeasureReadsInitDuration(t *testing.T) {
+	r := &Runner{Client: &fakeLambda{tail: coldTail}, FunctionName: "baseline-go"}
+	m, err := r.Measure(context.Background(), []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Cold() || m.Report.InitDurationMS != 8.91 || m.Report.DurationMS != 1.52 {
+		t.Errorf("Measure = %+v, want cold with init 8.91 ms and duration 1.52 ms", m)
+	}
+}
+
+func TestMeasureWarmInvocation(t *testing.T) {
+	warm := "REPORT RequestId: 8f5c\tDuration: 1.52 ms\tBilled Duration: 2 ms\tMemory Size: 128 MB\tMax Memory Used: 14 MB\t\n"
+	r := &Runner{Client: &fakeLambda{tail: warm}, FunctionName: "baseline-go"}
+	m, err := r.Measure(context.Background(), []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Cold() {
+		t.Errorf("warm invocation reported as cold: %+v", m)
+	}
+}
+
+func TestMeasureRetriesThrottles(t *testing.T) {
+	fake := &fakeLambda{tail: coldTail, throttleUpdates: 1, throttleInvokes: 2}
+	r := &Runner{Client: fake, FunctionName: "baseline-go", Backoff: invoke.Backoff{Attempts: 3, Base: time.Millisecond}}
+	m, err := r.Measure(context.Background(), []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Cold() || m.Retries != 3 || m.Excluded != "" {
+		t.Errorf("Measure = %+v, want cold after 3 retries", m)
+	}
+
+	// Out of retries, the measurement is excluded rather than the target failed.
+	fake.throttleUpdates = 3
+	if m, err = r.Measure(context.Background(), []byte("{}")); err != nil || m.Excluded != invoke.Throttle || m.Retries != 2 {
+		t.Errorf("Measure = %+v, %v, want excluded as throttled after 2 retries", m, err)
+	}
+}
+
+func TestMeasureCanary(t *testing.T) {
+	fake := &fakeLambda{tail: coldTail, payload: `{"statusCode":200,"body":"fibonacci(35)=9227465","runtime":"go"}`}
+	r := &Runner{Client: fake, FunctionName: "baseline-go-fibonacci", Runtime: "go", Expected: "fibonacci(35)=9227465"}
+	// The checked invocation is the measured one, still cold.
+	if m, err := r.Measure(context.Background(), []byte("{}")); err != nil || !m.Cold() || m.Error != "" {
+		t.Errorf("Measure = %+v, %v, want a cold measurement", m, err)
+	}
+	r.Expected = "fibonacci(35)=0"
+	if _, err := r.Measure(context.Background(), []byte("{}")); err == nil || !strings.Contains(err.Error(), "canary") {
+		t.Errorf("wrong result: err %v, want the canary's", err)
+	}
+	// Throttled still after retries, it is excluded as before.
+	r.Expected, fake.throttleInvokes = "fibonacci(35)=9227465", 1
+	if m, err := r.Measure(context.Background(), []byte("{}")); err != nil || m.Excluded != invoke.Throttle {
+		t.Errorf("Measure = %+v, %v, want excluded as throttled", m, err)
+	}
+}
@@ -0,0 +1,199 @@
+// Package compare is the regression gate behind ruchy-bench compare: it
+// matches a run's results with a baseline run's, target by target, and
+// flags those whose p95 grew by more than a threshold. A p95 moves with
+// noise alone, so a slowdown only counts when a Mann-Whitney U test finds
+// the current samples significantly slower than the baseline's.
+package compare
+
+import (
+	"fmt"
+	"strings"
+
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// DefaultAlpha is the significance level used when Options.Alpha is zero.
+const DefaultAlpha = 0.05
+
+// Options configure a comparison.
+type Options struct {
+	// Threshold is the relative p95 increase that is a regression, such as
+	// 0.05 for 5%.
+	Threshold float64
+	// Alpha is the significance level the slowdown must reach.
+	Alpha float64
+	// Metric is the metric compared. Empty means warm duration where both
+	// results have one, otherwise client time.
+	Metric string
+	// Stats summarizes each result's samples.
+	Stats stats.Options
+}
+
+// Verdict classifies one target.
+type Verdict string
+
+const (
+	Unchanged Verdict = "ok"
+	Regressed Verdict = "regressed"
+	Improved  Verdict = "improved"
+	// Noise is a p95 change beyond the threshold that the test does not
+	// find significant.
+	Noise Verdict = "noise"
+	// Failed is a target with no successful samples in the current run.
+	Failed Verdict = "failed"
+	// Missing is a baseline target the current run did not measure, and
+	// Added one the baseline did not.
+	Missing Verdict = "missing"
+	Added   Verdict = "new"
+)
+
+// Comparison is the outcome for one target.
+type Comparison struct {
+	Target string
+	Metric string
+	// Baseline and Current summarize the metric's successful samples.
+	Baseline, Current stats.Summary
+	// Change is the relative p95 change, positive when slower.
+	Change float64
+	// P is the one-sided Mann-Whitney p-value in the direction of the
+	// change.
+	P       float64
+	Verdict Verdict
+}
+
+// Failing reports whether c fails the gate.
+func (c Comparison) Failing() bool { return c.Verdict == Regressed || c.Verdict == Failed }
+
+// Runs compares current with baseline, in current's result order followed
+// by the baseline targets current lacks.
+func Runs(baseline, current *results.Run, o Options) []Comparison {
+	if o.Alpha == 0 {
+		o.Alpha = DefaultAlpha
+	}
+	base := map[string]results.Result{}
+	for _, r := range baseline.Results {
+		base[Target(r)] = r
+	}
+	var out []Comparison
+	seen := map[string]bool{}
+	for _, cur := range current.Results {
+		target := Target(cur)
+		seen[target] = true
+		b, ok := base[target]
+		if !ok {
+			out = append(out, Comparison{Target: target, Metric: metric(cur, cur, o), Verdict: Added})
+			continue
+		}
+		out = append(out, compare(target, b, cur, o))
+	}
+	for _, r := range baseline.Results {
+		if target := Target(r); !seen[target] {
+			seen[target] = true
+			out = append(out, Comparison{Target: target, Metric: metric(r, r, o), Verdict: Missing})
+		}
+	}
+	return out
+}
+
+func compare(target string, base, cur results.Result, o Options) Comparison {
+	m := metric(base, cur, o)
+	bx, cx := base.Values(m), cur.Values(m)
+	c := Comparison{
+		Target:   target,
+		Metric:   m,
+		Baseline: stats.Summarize(bx, o.Stats),
+		Current:  stats.Summarize(cx, o.Stats),
+		P:        1,
+	}
+	switch {
+	case c.Current.N == 0:
+		c.Verdict = Failed
+		return c
+	case c.Baseline.N == 0:
+		c.Verdict = Added
+		return c
+	}
+	if c.Baseline.P95 != 0 {
+		c.Change = (c.Current.P95 - c.Baseline.P95) / c.Baseline.P95
+	}
+	c.Verdict = Unchanged
+	// The test runs on the values the p95s came from, without the
+	// outliers the summaries dropped.
+	bx, _, _ = stats.Filter(bx, o.Stats)
+	cx, _, _ = stats.Filter(cx, o.Stats)
+	switch {
+	case c.Change > o.Threshold:
+		_, c.P = stats.MannWhitney(cx, bx)
+		c.Verdict = Regressed
+	case c.Change < -o.Threshold:
+		_, c.P = stats.MannWhitney(bx, cx)
+		c.Verdict = Improved
+	default:
+		return c
+	}
+	if c.P >= o.Alpha {
+		c.Verdict = Noise
+	}
+	return c
+}
+
+// metric picks the compared metric: Options.Metric, else warm duration when
+// both results recorded it, else client time.
+func metric(base, cur results.Result, o Options) string {
+	if o.Metric != "" {
+		return o.Metric
+	}
+	if len(base.Values(results.MetricWarm)) > 0 && len(cur.Values(results.MetricWarm)) > 0 {
+		return results.MetricWarm
+	}
+	return results.MetricClient
+}
+
+// Target identifies what a result measured, so that results of two runs
+// can be matched: runtime, workload and every configuration dimension.
+func Target(r results.Result) string {
+	parts := []string{r.Runtime + "/" + r.Workload, r.Kind}
+	if r.Arch != "" {
+		parts = append(parts, r.Arch)
+	}
+	// Only a memory size the harness set, as sweep does: a result that
+	// failed before any invocation has no reported size to match on.
+	if r.MemoryMB != 0 {
+		parts = append(parts, fmt.Sprintf("%dMB", r.MemoryMB))
+	}
+	if r.Region != "" {
+		parts = append(parts, r.Region)
+	}
+	if r.Package != "" {
+		parts = append(parts, r.Package)
+	}
+	if r.SnapStart {
+		parts = append(parts, "snapstart")
+	}
+	if r.Extension {
+		parts = append(parts, "ext")
+	}
+	if r.VPC {
+		parts = append(parts, "vpc")
+	}
+	if r.Chaos {
+		parts = append(parts, "chaos")
+	}
+	if r.Serializer != "" {
+		parts = append(parts, r.Serializer)
+	}
+	if r.Edge {
+		parts = append(parts, "edge")
+	}
+	if r.Sandbox != "" {
+		parts = append(parts, "sandbox="+r.Sandbox)
+	}
+	if r.ProvisionedConcurrency != 0 {
+		parts = append(parts, fmt.Sprintf("pc=%d", r.ProvisionedConcurrency))
+	}
+	if in := r.InputLabel(); in != "" {
+		parts = append(parts, "["+in+"]")
+	}
+	return strings.Join(parts, " ")
+}
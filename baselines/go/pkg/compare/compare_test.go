@@ -0,0 +1,145 @@
+package compare
+
+import (
+	"testing"
+
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// result builds a Lambda result whose warm durations are ms, after one
+// cold start.
+func result(runtime string, ms ...float64) results.Result {
+	r := results.Result{Runtime: runtime, Workload: "fibonacci", Kind: "lambda", Arch: "x86_64"}
+	r.Samples = append(r.Samples, results.Sample{RequestID: "cold", DurationMS: 900, Cold: true, InitMS: 80, MemorySizeMB: 128})
+	for _, v := range ms {
+		r.Samples = append(r.Samples, results.Sample{RequestID: "req", ClientMS: v + 20, DurationMS: v, MemorySizeMB: 128})
+	}
+	return r
+}
+
+func TestRuns(t *testing.T) {
+	steady := []float64{100, 101, 99, 102, 100, 98, 101, 100, 99, 103}
+	slower := []float64{110, 112, 109, 113, 111, 108, 112, 110, 111, 114}
+	// One slow outlier lifts the p95 but the bulk is unchanged.
+	spiky := []float64{100, 99, 101, 100, 98, 102, 100, 99, 101, 125}
+	faster := []float64{80, 81, 79, 82, 80, 78, 81, 80, 79, 83}
+
+	baseline := &results.Run{Results: []results.Result{
+		result("go", steady...), result("rust", steady...), result("python", steady...),
+		result("cpp", steady...), result("ruchy", steady...), result("c", steady...),
+	}}
+	failed := results.Result{Runtime: "ruchy", Workload: "fibonacci", Kind: "lambda", Arch: "x86_64", Error: "not deployed"}
+	current := &results.Run{Results: []results.Result{
+		result("go", slower...), result("rust", spiky...), result("python", faster...),
+		result("cpp", steady...), failed, result("julia", steady...),
+	}}
+
+	got := map[string]Comparison{}
+	var order []string
+	for _, c := range Runs(baseline, current, Options{Threshold: 0.05}) {
+		got[c.Target] = c
+		order = append(order, c.Target)
+	}
+	want := map[string]Verdict{
+		"go/fibonacci lambda x86_64":     Regressed,
+		"rust/fibonacci lambda x86_64":   Noise,
+		"python/fibonacci lambda x86_64": Improved,
+		"cpp/fibonacci lambda x86_64":    Unchanged,
+		"ruchy/fibonacci lambda x86_64":  Failed,
+		"julia/fibonacci lambda x86_64":  Added,
+		"c/fibonacci lambda x86_64":      Missing,
+	}
+	for target, v := range want {
+		if c := got[target]; c.Verdict != v {
+			t.Errorf("%s: %+v, want %s", target, c, v)
+		}
+	}
+	if len(order) != len(want) || order[len(order)-1] != "c/fibonacci lambda x86_64" {
+		t.Errorf("order = %v", order)
+	}
+
+	g := got["go/fibonacci lambda x86_64"]
+	if g.Metric != results.MetricWarm || g.Change < 0.09 || g.P > 0.001 || g.Baseline.N != 10 || !g.Failing() {
+		t.Errorf("go = %+v", g)
+	}
+	if got["rust/fibonacci lambda x86_64"].Failing() {
+		t.Error("noise fails the gate")
+	}
+}
+
+func TestRunsOutliers(t *testing.T) {
+	// Slow outliers in the baseline rank above the whole current sample;
+	// left in, they would weaken the test below Alpha.
+	base := &results.Run{Results: []results.Result{result("go", 100, 101, 99, 102, 100, 98, 101, 100, 99, 103, 500, 520, 510, 505)}}
+	cur := &results.Run{Results: []results.Result{result("go", 110, 112, 109, 113, 111, 108, 112, 110, 111, 114)}}
+	cs := Runs(base, cur, Options{Threshold: 0.05, Alpha: 0.01, Stats: stats.Options{RejectOutliers: true}})
+	if len(cs) != 1 || cs[0].Verdict != Regressed || cs[0].Baseline.Rejected != 4 || cs[0].P > 0.001 {
+		t.Errorf("Runs = %+v, want a regression tested without the outliers", cs)
+	}
+}
+
+func TestRuntimes(t *testing.T) {
+	steady := []float64{100, 101, 99, 102, 100, 98, 101, 100, 99, 103}
+	faster := []float64{80, 81, 79, 82, 80, 78, 81, 80, 79, 83}
+	noisy := []float64{101, 99, 100, 102, 98, 100, 101, 99, 103, 100}
+	sieve := result("go", steady...)
+	sieve.Workload = "sieve"
+	run := &results.Run{Results: []results.Result{
+		result("ruchy", faster...), result("go", steady...), sieve,
+		result("rust", noisy...),
+		{Runtime: "python", Workload: "fibonacci", Kind: "lambda", Arch: "x86_64", Error: "not deployed"},
+	}}
+	pairs := Runtimes(run, Options{})
+	// sieve has one runtime, python failed.
+	if len(pairs) != 3 {
+		t.Fatalf("%d pairs: %+v", len(pairs), pairs)
+	}
+	p := pairs[0]
+	if p.Target != "fibonacci lambda x86_64" || p.A != "ruchy" || p.B != "go" || p.Metric != results.MetricWarm {
+		t.Errorf("first pair = %+v", p)
+	}
+	if p.Delta != -1 || p.Magnitude != stats.Large || !p.Significant(0) || p.Faster() != "ruchy" || p.MedianA != 80 {
+		t.Errorf("ruchy vs go = %+v", p)
+	}
+	if q := pairs[2]; q.A != "go" || q.B != "rust" || q.Magnitude != stats.Negligible || q.Significant(0) {
+		t.Errorf("go vs rust = %+v", q)
+	}
+}
+
+func TestWinners(t *testing.T) {
+	steady := []float64{100, 101, 99, 102, 100, 98, 101, 100, 99, 103}
+	faster := []float64{80, 81, 79, 82, 80, 78, 81, 80, 79, 83}
+	sieve := result("go", steady...)
+	sieve.Workload = "sieve"
+	// A result without REPORT lines makes its whole target compare
+	// client time.
+	client := results.Result{Runtime: "rust", Workload: "tree", Kind: "lambda", Arch: "x86_64"}
+	for i, ms := range faster {
+		client.Samples = append(client.Samples, results.Sample{Iteration: i, ClientMS: ms})
+	}
+	tree := result("go", steady...)
+	tree.Workload = "tree"
+	for i := range steady {
+		tree.Samples[i+1].ClientMS = 105
+	}
+	run := &results.Run{Results: []results.Result{
+		result("go", steady...), result("ruchy", faster...), sieve, tree, client,
+		{Runtime: "python", Workload: "sieve", Kind: "lambda", Arch: "x86_64", Error: "not deployed"},
+	}}
+	ws := Winners(run, Options{})
+	// sieve has one runtime that succeeded.
+	if len(ws) != 2 {
+		t.Fatalf("%d winners: %+v", len(ws), ws)
+	}
+	w := ws[0]
+	if w.Target != "fibonacci lambda x86_64" || w.Runtime != "ruchy" || w.RunnerUp != "go" || w.Median != 80 || w.RunnerUpMedian != 100 {
+		t.Errorf("fibonacci winner = %+v", w)
+	}
+	if w.Metric != results.MetricWarm || !w.Pair.Significant(0) || w.Pair.Faster() != "ruchy" {
+		t.Errorf("fibonacci winner = %+v", w)
+	}
+	if w := ws[1]; w.Runtime != "rust" || w.Metric != results.MetricClient || w.RunnerUpMedian != 105 {
+		t.Errorf("tree winner = %+v", w)
+	}
+}
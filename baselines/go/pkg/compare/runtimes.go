@@ -0,0 +1,186 @@
+package compare
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// Pair compares two runtimes on one target of a run: a Mann-Whitney U test
+// of whether their samples differ, and Cliff's delta of how much.
+type Pair struct {
+	// Target is what both measured, without the runtime: the workload and
+	// every configuration dimension.
+	Target string
+	// A and B are the runtimes compared, A measured first in the run.
+	A, B   string
+	Metric string
+	// NA and NB count the samples compared, after outlier rejection.
+	NA, NB           int
+	MedianA, MedianB float64
+	// Delta is Cliff's delta of A's samples against B's: negative when A
+	// tends to be faster.
+	Delta     float64
+	Magnitude stats.Magnitude
+	// P is the two-sided Mann-Whitney p-value.
+	P float64
+}
+
+// Significant reports whether the runtimes differ at significance level
+// alpha, DefaultAlpha when zero.
+func (p Pair) Significant(alpha float64) bool {
+	if alpha == 0 {
+		alpha = DefaultAlpha
+	}
+	return p.NA > 0 && p.NB > 0 && p.P < alpha
+}
+
+// Faster is the runtime that tends to be faster, "" when neither does.
+func (p Pair) Faster() string {
+	switch {
+	case p.Delta < 0:
+		return p.A
+	case p.Delta > 0:
+		return p.B
+	}
+	return ""
+}
+
+// Runtimes pairs every two runtimes run measured the same target with,
+// comparing their samples of Options.Metric (warm duration where both
+// have it, otherwise client time) after Options.Stats' outlier policy.
+// Pairs come in the order the run measured their targets and runtimes;
+// failed results are left out. Threshold and Alpha are unused.
+func Runtimes(run *results.Run, o Options) []Pair {
+	order, groups := byTarget(run)
+	var pairs []Pair
+	for _, key := range order {
+		rs := groups[key]
+		for i, a := range rs {
+			for _, b := range rs[i+1:] {
+				if a.Runtime != b.Runtime {
+					pairs = append(pairs, pair(key, a, b, o))
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// Winner is the fastest runtime on one target of a run.
+type Winner struct {
+	// Target is what the runtimes measured, without the runtime.
+	Target  string
+	Runtime string
+	Metric  string
+	Median  float64
+	// RunnerUp is the next fastest runtime, "" when no other has
+	// successful samples. Pair compares the two, so Pair.Significant
+	// says whether the win is more than noise.
+	RunnerUp       string
+	RunnerUpMedian float64
+	Pair           Pair
+}
+
+// Winners returns the runtime with the lowest median on each target run
+// measured in more than one runtime, in the order the run measured them.
+// Medians are of Options.Metric, or warm duration where every result of
+// the target has it and client time otherwise, after Options.Stats'
+// outlier policy; failed results are left out.
+func Winners(run *results.Run, o Options) []Winner {
+	order, groups := byTarget(run)
+	var winners []Winner
+	for _, key := range order {
+		rs := groups[key]
+		// One metric for the whole group: warm duration only if every
+		// result has it, as metric picks it for a pair.
+		po := o
+		if po.Metric == "" {
+			po.Metric = results.MetricWarm
+		}
+		runtimes := map[string]bool{}
+		for _, r := range rs {
+			runtimes[r.Runtime] = true
+			if o.Metric == "" && len(r.Values(results.MetricWarm)) == 0 {
+				po.Metric = results.MetricClient
+			}
+		}
+		m := po.Metric
+		medians := make([]float64, len(rs))
+		for i, r := range rs {
+			xs, _ := stats.Reject(r.Values(m), o.Stats)
+			medians[i] = math.Inf(1)
+			if len(xs) > 0 {
+				medians[i] = stats.Median(xs)
+			}
+		}
+		if len(runtimes) < 2 {
+			continue
+		}
+		idx := make([]int, len(rs))
+		for i := range idx {
+			idx[i] = i
+		}
+		slices.SortStableFunc(idx, func(a, b int) int { return cmp.Compare(medians[a], medians[b]) })
+		best := rs[idx[0]]
+		if math.IsInf(medians[idx[0]], 1) {
+			continue
+		}
+		w := Winner{Target: key, Runtime: best.Runtime, Metric: m, Median: medians[idx[0]]}
+		for _, i := range idx[1:] {
+			if r := rs[i]; r.Runtime != best.Runtime && !math.IsInf(medians[i], 1) {
+				w.RunnerUp, w.RunnerUpMedian = r.Runtime, medians[i]
+				w.Pair = pair(key, best, r, po)
+				break
+			}
+		}
+		winners = append(winners, w)
+	}
+	return winners
+}
+
+// byTarget groups run's successful results by target without the
+// runtime, keys in the order the run measured them.
+func byTarget(run *results.Run) (order []string, groups map[string][]results.Result) {
+	groups = map[string][]results.Result{}
+	for _, r := range run.Results {
+		if r.Error != "" {
+			continue
+		}
+		key := withoutRuntime(r)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], r)
+	}
+	return order, groups
+}
+
+func pair(target string, a, b results.Result, o Options) Pair {
+	m := metric(a, b, o)
+	ax, _ := stats.Reject(a.Values(m), o.Stats)
+	bx, _ := stats.Reject(b.Values(m), o.Stats)
+	p := Pair{
+		Target: target, A: a.Runtime, B: b.Runtime, Metric: m,
+		NA: len(ax), NB: len(bx),
+		MedianA: stats.Median(ax), MedianB: stats.Median(bx),
+		Delta: stats.CliffsDelta(ax, bx),
+		P:     1,
+	}
+	p.Magnitude = stats.DeltaMagnitude(p.Delta)
+	if p.NA > 0 && p.NB > 0 {
+		p.P = stats.MannWhitneyTwoSided(ax, bx)
+	}
+	return p
+}
+
+// withoutRuntime is r's Target with the runtime left out, which results
+// of one target in different runtimes share.
+func withoutRuntime(r results.Result) string {
+	r.Runtime = ""
+	return strings.TrimPrefix(Target(r), "/")
+}
@@ -0,0 +1,173 @@
+package configload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+// Client calls the Secrets Manager and SSM JSON APIs. Like queue.Client
+// it implements only the calls the workloads make, which keeps two SDK
+// service modules out of the handlers as well as the harness.
+type Client struct {
+	Config aws.Config
+	// Endpoint overrides both https://secretsmanager.<region>.amazonaws.com
+	// and https://ssm.<region>.amazonaws.com; requests are told apart by
+	// their X-Amz-Target.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// APIError is an error response from Secrets Manager or SSM, such as
+// "ResourceNotFoundException".
+type APIError = awsapi.Error
+
+func isCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// Secret calls GetSecretValue for the secret's current string value.
+func (c *Client) Secret(ctx context.Context, id string) (string, error) {
+	var out struct{ SecretString string }
+	if err := c.call(ctx, "secretsmanager", "secretsmanager.GetSecretValue", map[string]any{"SecretId": id}, &out); err != nil {
+		return "", err
+	}
+	return out.SecretString, nil
+}
+
+// Parameter calls GetParameter, decrypting SecureString values.
+func (c *Client) Parameter(ctx context.Context, name string) (string, error) {
+	var out struct{ Parameter struct{ Value string } }
+	if err := c.call(ctx, "ssm", "AmazonSSM.GetParameter", map[string]any{"Name": name, "WithDecryption": true}, &out); err != nil {
+		return "", err
+	}
+	return out.Parameter.Value, nil
+}
+
+// PutSecret creates the secret with value, or makes value the current
+// version of an existing one.
+func (c *Client) PutSecret(ctx context.Context, id, value string) error {
+	in := map[string]any{"Name": id, "SecretString": value, "Description": "ruchy-bench configload fixture"}
+	err := c.call(ctx, "secretsmanager", "secretsmanager.CreateSecret", in, &struct{}{})
+	if isCode(err, "ResourceExistsException") {
+		in = map[string]any{"SecretId": id, "SecretString": value}
+		err = c.call(ctx, "secretsmanager", "secretsmanager.PutSecretValue", in, &struct{}{})
+	}
+	if err != nil {
+		return fmt.Errorf("put secret %s: %w", id, err)
+	}
+	return nil
+}
+
+// PutParameter writes a String parameter, overwriting any value it had.
+func (c *Client) PutParameter(ctx context.Context, name, value string) error {
+	in := map[string]any{"Name": name, "Value": value, "Type": "String", "Overwrite": true}
+	if err := c.call(ctx, "ssm", "AmazonSSM.PutParameter", in, &struct{}{}); err != nil {
+		return fmt.Errorf("put parameter %s: %w", name, err)
+	}
+	return nil
+}
+
+// Seed writes every secret and parameter the workloads load.
+func Seed(ctx context.Context, c *Client) error {
+	for i := range Secrets {
+		if err := c.PutSecret(ctx, SecretID(i), SecretValue(i)); err != nil {
+			return err
+		}
+	}
+	for i := range Parameters {
+		if err := c.PutParameter(ctx, ParameterName(i), ParameterValue(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// call calls the operation target of service with input in and decodes
+// its output into out.
+func (c *Client) call(ctx context.Context, service, target string, in, out any) error {
+	api := &awsapi.Client{Config: c.Config, Service: service, Endpoint: c.Endpoint, HTTP: c.HTTP}
+	return api.JSON(ctx, "1.1", target, in, out)
+}
+
+// ExtensionPortEnv is the variable the AWS Parameters and Secrets Lambda
+// Extension reads its port from, 2773 when unset.
+const ExtensionPortEnv = "PARAMETERS_SECRETS_EXTENSION_HTTP_PORT"
+
+// Extension reads configuration through the AWS Parameters and Secrets
+// Lambda Extension's local HTTP endpoint. It answers with the services'
+// own response documents, served from its cache after the first read.
+type Extension struct {
+	// Port is the extension's; empty means its default, 2773.
+	Port string
+	// Token authenticates requests to the extension: the function's
+	// AWS_SESSION_TOKEN.
+	Token string
+	// HTTP reaches the extension on localhost; nil means
+	// http.DefaultClient.
+	HTTP *http.Client
+}
+
+// NewExtension returns an Extension configured from the function's
+// environment.
+func NewExtension() *Extension {
+	return &Extension{Port: os.Getenv(ExtensionPortEnv), Token: os.Getenv("AWS_SESSION_TOKEN")}
+}
+
+// Secret asks the extension for the secret's current string value.
+func (e *Extension) Secret(ctx context.Context, id string) (string, error) {
+	var out struct{ SecretString string }
+	if err := e.get(ctx, "/secretsmanager/get?secretId="+url.QueryEscape(id), &out); err != nil {
+		return "", err
+	}
+	return out.SecretString, nil
+}
+
+// Parameter asks the extension for the parameter's value, decrypted.
+func (e *Extension) Parameter(ctx context.Context, name string) (string, error) {
+	var out struct{ Parameter struct{ Value string } }
+	if err := e.get(ctx, "/systemsmanager/parameters/get?withDecryption=true&name="+url.QueryEscape(name), &out); err != nil {
+		return "", err
+	}
+	return out.Parameter.Value, nil
+}
+
+func (e *Extension) get(ctx context.Context, path string, out any) error {
+	port := e.Port
+	if port == "" {
+		port = "2773"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost:"+port+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Aws-Parameters-Secrets-Token", e.Token)
+	client := e.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w (is the AWS Parameters and Secrets Lambda Extension attached?)", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("extension: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,97 @@
+// Package configload drives the config-loading workloads, whose baselines
+// (main-configload.go and main-configload-extension.go) read Secrets
+// secrets from Secrets Manager and Parameters parameters from SSM
+// Parameter Store while the function initializes, the way services load
+// their configuration on a cold start. The first calls the services'
+// APIs; the second asks the AWS Parameters and Secrets Lambda Extension
+// on localhost, which calls them on its behalf and caches the values.
+// Either way the loading lands in the REPORT line's Init Duration.
+//
+// ruchy-bench seed writes the values, which are deterministic, so the
+// handlers' results are too: Digest of the values in order.
+package configload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Workload and ExtensionWorkload are the workload names of
+	// main-configload.go and main-configload-extension.go.
+	Workload          = "configload"
+	ExtensionWorkload = "configload-extension"
+	// Secrets and Parameters are how many of each a handler loads.
+	Secrets    = 5
+	Parameters = 20
+	// Prefix starts every secret name and, after a slash, every
+	// parameter name.
+	Prefix = "ruchy-bench/config/"
+)
+
+// SecretID returns the name of secret i.
+func SecretID(i int) string { return fmt.Sprintf("%ssecret-%d", Prefix, i) }
+
+// ParameterName returns the name of parameter i.
+func ParameterName(i int) string { return fmt.Sprintf("/%sparam-%02d", Prefix, i) }
+
+// SecretValue returns the value of secret i: a JSON credential, as
+// secrets usually hold.
+func SecretValue(i int) string {
+	sum := sha256.Sum256([]byte(SecretID(i)))
+	return fmt.Sprintf(`{"username":"svc-%d","password":"%x"}`, i, sum[:16])
+}
+
+// ParameterValue returns the value of parameter i.
+func ParameterValue(i int) string {
+	return fmt.Sprintf("https://service-%02d.internal.example.com:8443/v1?timeout_ms=%d", i, 250*(i%4+1))
+}
+
+// Digest is the workloads' result for values, in load order: the first
+// eight bytes of the SHA-256 of them joined by newlines.
+func Digest(values []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Source is where a handler reads configuration from.
+type Source interface {
+	Secret(ctx context.Context, id string) (string, error)
+	Parameter(ctx context.Context, name string) (string, error)
+}
+
+// Load reads every secret, then every parameter, one call each, and
+// returns their Digest.
+func Load(ctx context.Context, src Source) (string, error) {
+	values := make([]string, 0, Secrets+Parameters)
+	for i := range Secrets {
+		v, err := src.Secret(ctx, SecretID(i))
+		if err != nil {
+			return "", fmt.Errorf("secret %s: %w", SecretID(i), err)
+		}
+		values = append(values, v)
+	}
+	for i := range Parameters {
+		v, err := src.Parameter(ctx, ParameterName(i))
+		if err != nil {
+			return "", fmt.Errorf("parameter %s: %w", ParameterName(i), err)
+		}
+		values = append(values, v)
+	}
+	return Digest(values), nil
+}
+
+// Expected returns the Digest of the values ruchy-bench seed writes.
+func Expected() string {
+	values := make([]string, 0, Secrets+Parameters)
+	for i := range Secrets {
+		values = append(values, SecretValue(i))
+	}
+	for i := range Parameters {
+		values = append(values, ParameterValue(i))
+	}
+	return Digest(values)
+}
@@ -0,0 +1,123 @@
+package configload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// store answers the Secrets Manager, SSM and extension calls from the
+// values it holds.
+type store struct {
+	secrets, params map[string]string
+}
+
+func (s *store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reply := func(v any) { json.NewEncoder(w).Encode(v) }
+	fail := func(typ, msg string) {
+		w.WriteHeader(http.StatusBadRequest)
+		reply(map[string]string{"__type": typ, "message": msg})
+	}
+	if r.Method == http.MethodGet {
+		if r.Header.Get("X-Aws-Parameters-Secrets-Token") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/secretsmanager/get":
+			reply(map[string]string{"SecretString": s.secrets[r.URL.Query().Get("secretId")]})
+		case "/systemsmanager/parameters/get":
+			reply(map[string]any{"Parameter": map[string]string{"Value": s.params[r.URL.Query().Get("name")]}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+	var in map[string]any
+	json.NewDecoder(r.Body).Decode(&in)
+	str := func(k string) string { v, _ := in[k].(string); return v }
+	switch r.Header.Get("X-Amz-Target") {
+	case "secretsmanager.CreateSecret":
+		if _, ok := s.secrets[str("Name")]; ok {
+			fail("ResourceExistsException", "the secret already exists")
+			return
+		}
+		s.secrets[str("Name")] = str("SecretString")
+		reply(map[string]string{})
+	case "secretsmanager.PutSecretValue":
+		s.secrets[str("SecretId")] = str("SecretString")
+		reply(map[string]string{})
+	case "secretsmanager.GetSecretValue":
+		v, ok := s.secrets[str("SecretId")]
+		if !ok {
+			fail("ResourceNotFoundException", "Secrets Manager can't find the specified secret.")
+			return
+		}
+		reply(map[string]string{"SecretString": v})
+	case "AmazonSSM.PutParameter":
+		if in["Overwrite"] != true {
+			fail("ParameterAlreadyExists", "")
+			return
+		}
+		s.params[str("Name")] = str("Value")
+		reply(map[string]int{"Version": 1})
+	case "AmazonSSM.GetParameter":
+		v, ok := s.params[str("Name")]
+		if !ok || in["WithDecryption"] != true {
+			fail("ParameterNotFound", "")
+			return
+		}
+		reply(map[string]any{"Parameter": map[string]string{"Name": str("Name"), "Value": v}})
+	default:
+		fail("UnknownOperationException", r.Header.Get("X-Amz-Target"))
+	}
+}
+
+func TestExpected(t *testing.T) {
+	// Pinned: main-configload.go and main-configload-extension.go
+	// document this digest as their expected result.
+	if got, want := Expected(), "0814e3a8f6580d33"; got != want {
+		t.Errorf("Expected() = %s, want %s", got, want)
+	}
+	if !strings.HasPrefix(ParameterName(7), "/"+Prefix) || !strings.HasPrefix(SecretID(4), Prefix) {
+		t.Errorf("names %s and %s are outside %s", ParameterName(7), SecretID(4), Prefix)
+	}
+}
+
+func TestSeedThenLoad(t *testing.T) {
+	s := &store{secrets: map[string]string{SecretID(2): "stale"}, params: map[string]string{}}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	c := &Client{Endpoint: srv.URL}
+	ctx := context.Background()
+
+	var apiErr *APIError
+	if _, err := c.Secret(ctx, SecretID(0)); !errors.As(err, &apiErr) || apiErr.Code != "ResourceNotFoundException" {
+		t.Errorf("Secret before seeding: %v", err)
+	}
+	// An existing secret gets a new version rather than failing.
+	if err := Seed(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if s.secrets[SecretID(2)] != SecretValue(2) || len(s.params) != Parameters {
+		t.Errorf("seeded %d secrets, %d parameters; secret 2 = %q", len(s.secrets), len(s.params), s.secrets[SecretID(2)])
+	}
+	if got, err := Load(ctx, c); err != nil || got != Expected() {
+		t.Errorf("Load through the APIs = %s, %v; want %s", got, err, Expected())
+	}
+
+	u, _ := url.Parse(srv.URL)
+	ext := &Extension{Port: u.Port(), Token: "token"}
+	if got, err := Load(ctx, ext); err != nil || got != Expected() {
+		t.Errorf("Load through the extension = %s, %v; want %s", got, err, Expected())
+	}
+	ext.Token = ""
+	if _, err := Load(ctx, ext); err == nil {
+		t.Error("extension request without the session token succeeded")
+	}
+}
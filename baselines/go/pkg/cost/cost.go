@@ -0,0 +1,181 @@
+// Package cost converts measured Lambda usage into USD. Performance numbers
+// alone hide the trade-off that matters in production: a runtime that is
+// 10% faster at twice the memory is not cheaper.
+package cost
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Architecture names, matching Lambda's and discover's spelling.
+const (
+	ArchX86   = "x86_64"
+	ArchARM64 = "arm64"
+)
+
+// Tier prices compute up to a monthly GB-second volume. The last tier of a
+// schedule has UpTo set to +Inf.
+type Tier struct {
+	UpTo     float64 // cumulative GB-seconds per month
+	PerGBSec float64 // USD
+}
+
+// Pricing is a Lambda price list for one region.
+type Pricing struct {
+	PerRequest float64 // USD per invocation
+	Compute    map[string][]Tier
+	// Ephemeral storage above IncludedEphemeralMB is billed per GB-second
+	// of configured storage.
+	PerEphemeralGBSec   float64
+	IncludedEphemeralMB int32
+	// Provisioned concurrency is billed per GB-second of allocated
+	// environments, by architecture, for at least ProvisionedMinimum.
+	PerProvisionedGBSec map[string]float64
+	ProvisionedMinimum  time.Duration
+	// Invocations served by provisioned concurrency are billed per
+	// GB-second of duration at this rate, by architecture, rather than
+	// Compute's.
+	PerProvisionedDurationGBSec map[string]float64
+	// Monthly free tier, shared across architectures.
+	FreeRequests  float64
+	FreeGBSeconds float64
+}
+
+// Default is on-demand pricing in us-east-1.
+var Default = Pricing{
+	PerRequest: 0.20 / 1e6,
+	Compute: map[string][]Tier{
+		ArchX86: {
+			{UpTo: 6e9, PerGBSec: 0.0000166667},
+			{UpTo: 15e9, PerGBSec: 0.000015},
+			{UpTo: math.Inf(1), PerGBSec: 0.0000133334},
+		},
+		ArchARM64: {
+			{UpTo: 7.5e9, PerGBSec: 0.0000133334},
+			{UpTo: 18.75e9, PerGBSec: 0.0000120001},
+			{UpTo: math.Inf(1), PerGBSec: 0.0000106667},
+		},
+	},
+	PerEphemeralGBSec:   0.0000000309,
+	IncludedEphemeralMB: 512,
+	PerProvisionedGBSec: map[string]float64{
+		ArchX86:   0.0000041667,
+		ArchARM64: 0.0000033334,
+	},
+	ProvisionedMinimum: 5 * time.Minute,
+	PerProvisionedDurationGBSec: map[string]float64{
+		ArchX86:   0.0000097222,
+		ArchARM64: 0.0000077778,
+	},
+	FreeRequests:  1e6,
+	FreeGBSeconds: 400000,
+}
+
+// Usage describes a month of invocations of one function.
+type Usage struct {
+	Arch        string // "" means x86_64
+	MemoryMB    int32
+	EphemeralMB int32 // 0 means the included 512 MB
+	BilledMS    float64
+	Invocations float64
+	// Provisioned is set when the invocations are served by provisioned
+	// concurrency. Their duration is then priced at the provisioned rate,
+	// untiered and outside the free tier.
+	Provisioned bool
+}
+
+// Breakdown is the USD cost of a Usage.
+type Breakdown struct {
+	Requests  float64 `json:"requests"`
+	Compute   float64 `json:"compute"`
+	Ephemeral float64 `json:"ephemeral"`
+	Total     float64 `json:"total"`
+}
+
+// GBSeconds is the compute volume of u.
+func (u Usage) GBSeconds() float64 {
+	return u.Invocations * u.BilledMS / 1000 * float64(u.MemoryMB) / 1024
+}
+
+// Estimate prices u. With freeTier the monthly free allowance is deducted
+// first, as it would be for the only function in an account.
+func (p Pricing) Estimate(u Usage, freeTier bool) (Breakdown, error) {
+	arch := u.Arch
+	if arch == "" {
+		arch = ArchX86
+	}
+	tiers, ok := p.Compute[arch]
+	if !ok {
+		return Breakdown{}, fmt.Errorf("no pricing for architecture %q", arch)
+	}
+	provisioned, ok := p.PerProvisionedDurationGBSec[arch]
+	if u.Provisioned && !ok {
+		return Breakdown{}, fmt.Errorf("no provisioned concurrency pricing for architecture %q", arch)
+	}
+
+	requests, gbs := u.Invocations, u.GBSeconds()
+	if freeTier {
+		requests = math.Max(0, requests-p.FreeRequests)
+		if !u.Provisioned {
+			gbs = math.Max(0, gbs-p.FreeGBSeconds)
+		}
+	}
+	var b Breakdown
+	b.Requests = requests * p.PerRequest
+	if u.Provisioned {
+		b.Compute = gbs * provisioned
+	} else {
+		b.Compute = tiered(tiers, gbs)
+	}
+	if u.EphemeralMB > p.IncludedEphemeralMB {
+		extraGB := float64(u.EphemeralMB-p.IncludedEphemeralMB) / 1024
+		b.Ephemeral = u.Invocations * u.BilledMS / 1000 * extraGB * p.PerEphemeralGBSec
+	}
+	b.Total = b.Requests + b.Compute + b.Ephemeral
+	return b, nil
+}
+
+// PerMillion is the cost of one million invocations when the function runs
+// monthly invocations a month. Tiers and the free tier depend on volume, so
+// the same workload is cheaper per call at scale.
+func (p Pricing) PerMillion(u Usage, monthly float64, freeTier bool) (float64, error) {
+	if monthly <= 0 {
+		return 0, fmt.Errorf("monthly invocations must be positive, got %v", monthly)
+	}
+	u.Invocations = monthly
+	b, err := p.Estimate(u, freeTier)
+	if err != nil {
+		return 0, err
+	}
+	return b.Total / monthly * 1e6, nil
+}
+
+// Provisioned prices keeping concurrency environments of memoryMB on arch
+// ("" means x86_64) allocated for d. The invocations they serve are
+// priced separately, by Estimate with Usage.Provisioned set.
+func (p Pricing) Provisioned(arch string, memoryMB, concurrency int32, d time.Duration) (float64, error) {
+	if arch == "" {
+		arch = ArchX86
+	}
+	rate, ok := p.PerProvisionedGBSec[arch]
+	if !ok {
+		return 0, fmt.Errorf("no provisioned concurrency pricing for architecture %q", arch)
+	}
+	gbs := float64(concurrency) * float64(memoryMB) / 1024 * max(d, p.ProvisionedMinimum).Seconds()
+	return gbs * rate, nil
+}
+
+// tiered prices gbs GB-seconds against a cumulative tier schedule.
+func tiered(tiers []Tier, gbs float64) float64 {
+	var usd, floor float64
+	for _, t := range tiers {
+		if gbs <= floor {
+			break
+		}
+		usd += (math.Min(gbs, t.UpTo) - floor) * t.PerGBSec
+		floor = t.UpTo
+	}
+	return usd
+}
@@ -0,0 +1,118 @@
+package cost
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestEstimate(t *testing.T) {
+	// 1M invocations of 100 ms at 1024 MB is 100,000 GB-s.
+	u := Usage{MemoryMB: 1024, BilledMS: 100, Invocations: 1e6}
+	b, err := Default.Estimate(u, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approx(b.Requests, 0.20) || !approx(b.Compute, 1e5*0.0000166667) || b.Ephemeral != 0 {
+		t.Errorf("Estimate = %+v", b)
+	}
+	if !approx(b.Total, b.Requests+b.Compute) {
+		t.Errorf("Total = %v, want sum of parts", b.Total)
+	}
+
+	u.Arch = ArchARM64
+	arm, _ := Default.Estimate(u, false)
+	if !approx(arm.Compute, 1e5*0.0000133334) {
+		t.Errorf("arm64 compute = %v", arm.Compute)
+	}
+
+	if _, err := Default.Estimate(Usage{Arch: "mips"}, false); err == nil {
+		t.Error("unknown architecture accepted")
+	}
+}
+
+func TestEstimateFreeTier(t *testing.T) {
+	// Entirely inside the free tier.
+	b, _ := Default.Estimate(Usage{MemoryMB: 1024, BilledMS: 100, Invocations: 1e6}, true)
+	if b.Total != 0 {
+		t.Errorf("free tier total = %v, want 0", b.Total)
+	}
+	// 2M requests and 500,000 GB-s: 1M requests and 100,000 GB-s billed.
+	b, _ = Default.Estimate(Usage{MemoryMB: 1024, BilledMS: 250, Invocations: 2e6}, true)
+	if !approx(b.Requests, 0.20) || !approx(b.Compute, 1e5*0.0000166667) {
+		t.Errorf("partial free tier = %+v", b)
+	}
+}
+
+func TestEstimateTiers(t *testing.T) {
+	// 10B GB-s on x86: 6B at tier 1, 4B at tier 2.
+	u := Usage{MemoryMB: 1024, BilledMS: 1000, Invocations: 1e10}
+	b, _ := Default.Estimate(u, false)
+	want := 6e9*0.0000166667 + 4e9*0.000015
+	if math.Abs(b.Compute-want) > 1e-3 {
+		t.Errorf("tiered compute = %v, want %v", b.Compute, want)
+	}
+}
+
+func TestEstimateEphemeral(t *testing.T) {
+	// 1536 MB configured is 1 GB above the included 512 MB.
+	u := Usage{MemoryMB: 128, EphemeralMB: 1536, BilledMS: 1000, Invocations: 1000}
+	b, _ := Default.Estimate(u, false)
+	if !approx(b.Ephemeral, 1000*0.0000000309) {
+		t.Errorf("ephemeral = %v", b.Ephemeral)
+	}
+	u.EphemeralMB = 512
+	if b, _ := Default.Estimate(u, false); b.Ephemeral != 0 {
+		t.Errorf("included storage billed: %v", b.Ephemeral)
+	}
+}
+
+func TestPerMillion(t *testing.T) {
+	u := Usage{MemoryMB: 1024, BilledMS: 100}
+	got, err := Default.PerMillion(u, 1e6, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 1e5*0.0000166667 + 0.20; !approx(got, want) {
+		t.Errorf("PerMillion = %v, want %v", got, want)
+	}
+	// Scaling volume without crossing a tier leaves the unit cost alone.
+	if at10M, _ := Default.PerMillion(u, 1e7, false); !approx(at10M, got) {
+		t.Errorf("PerMillion at 10M = %v, want %v", at10M, got)
+	}
+	if _, err := Default.PerMillion(u, 0, false); err == nil {
+		t.Error("zero monthly volume accepted")
+	}
+}
+
+func TestProvisioned(t *testing.T) {
+	// 10 environments of 2048 MB for an hour is 72,000 GB-s.
+	got, err := Default.Provisioned("", 2048, 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 72000 * 0.0000041667; !approx(got, want) {
+		t.Errorf("Provisioned = %v, want %v", got, want)
+	}
+	// A minute is billed as the five-minute minimum.
+	short, _ := Default.Provisioned(ArchARM64, 1024, 1, time.Minute)
+	if want := 300 * 0.0000033334; !approx(short, want) {
+		t.Errorf("Provisioned for a minute = %v, want %v", short, want)
+	}
+	if _, err := Default.Provisioned("mips", 128, 1, time.Hour); err == nil {
+		t.Error("unknown architecture accepted")
+	}
+
+	// The invocations they serve pay the provisioned duration rate, with
+	// no free GB-seconds: 1M invocations of 100 ms at 1024 MB is 100,000
+	// GB-s.
+	served, err := Default.Estimate(Usage{MemoryMB: 1024, BilledMS: 100, Invocations: 1e6, Provisioned: true}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := 100000 * 0.0000097222; !approx(served.Compute, want) || served.Requests != 0 {
+		t.Errorf("provisioned invocations = %+v, want compute %v and free requests", served, want)
+	}
+}
@@ -0,0 +1,192 @@
+// Package cwmetrics reads the metrics Lambda publishes to CloudWatch for a
+// function: invocations, throttles and concurrent executions. Throttles
+// in particular are only partly visible to the caller, whose SDK may
+// retry or whose requests may never get a response; CloudWatch counts
+// them on the service side. It also publishes custom metrics, for
+// pkg/sink. Like queue.Client, Client implements only the calls the
+// harness makes, over the CloudWatch JSON protocol.
+package cwmetrics
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+// Period is the resolution Lambda publishes its metrics at.
+const Period = time.Minute
+
+// Client reads and writes metrics over the CloudWatch JSON protocol.
+type Client struct {
+	Config aws.Config
+	// Endpoint overrides https://monitoring.<region>.amazonaws.com.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// Function is what CloudWatch recorded for a function over a window.
+type Function struct {
+	Invocations int
+	Throttles   int
+	// Errors counts invocations that failed with a function error.
+	Errors int
+	// PeakConcurrency is the highest per-minute maximum of concurrent
+	// executions.
+	PeakConcurrency int
+}
+
+// query is one metric of a function.
+type query struct {
+	id, metric, stat string
+}
+
+var queries = []query{
+	{"invocations", "Invocations", "Sum"},
+	{"throttles", "Throttles", "Sum"},
+	{"errors", "Errors", "Sum"},
+	{"concurrency", "ConcurrentExecutions", "Maximum"},
+}
+
+// Function calls GetMetricData for function's metrics between start and
+// end, widened to whole periods, following pagination. Minutes without
+// data count as zero: Lambda publishes nothing for an idle function.
+func (c *Client) Function(ctx context.Context, function string, start, end time.Time) (Function, error) {
+	type stat struct {
+		Metric struct {
+			Namespace  string
+			MetricName string
+			Dimensions []map[string]string
+		}
+		Period int
+		Stat   string
+	}
+	type metricQuery struct {
+		ID         string `json:"Id"`
+		MetricStat stat
+		ReturnData bool
+	}
+	var mqs []metricQuery
+	for _, q := range queries {
+		mq := metricQuery{ID: q.id, ReturnData: true}
+		mq.MetricStat.Metric.Namespace = "AWS/Lambda"
+		mq.MetricStat.Metric.MetricName = q.metric
+		mq.MetricStat.Metric.Dimensions = []map[string]string{{"Name": "FunctionName", "Value": function}}
+		mq.MetricStat.Period = int(Period / time.Second)
+		mq.MetricStat.Stat = q.stat
+		mqs = append(mqs, mq)
+	}
+
+	var (
+		f     Function
+		token string
+	)
+	for {
+		in := map[string]any{
+			"MetricDataQueries": mqs,
+			"StartTime":         start.Truncate(Period).Unix(),
+			"EndTime":           end.Truncate(Period).Add(Period).Unix(),
+		}
+		if token != "" {
+			in["NextToken"] = token
+		}
+		var out struct {
+			MetricDataResults []struct {
+				ID     string `json:"Id"`
+				Values []float64
+			}
+			NextToken string
+		}
+		if err := c.call(ctx, "GetMetricData", in, &out); err != nil {
+			return Function{}, fmt.Errorf("get metrics of %s: %w", function, err)
+		}
+		for _, r := range out.MetricDataResults {
+			for _, v := range r.Values {
+				n := int(math.Round(v))
+				switch r.ID {
+				case "invocations":
+					f.Invocations += n
+				case "throttles":
+					f.Throttles += n
+				case "errors":
+					f.Errors += n
+				case "concurrency":
+					f.PeakConcurrency = max(f.PeakConcurrency, n)
+				}
+			}
+		}
+		if token = out.NextToken; token == "" {
+			return f, nil
+		}
+	}
+}
+
+// Datum is one custom metric value set: Values, each seen Counts times
+// (nil counts each once), with the given dimensions and unit.
+type Datum struct {
+	MetricName string
+	Dimensions map[string]string
+	Unit       string
+	Timestamp  time.Time
+	Values     []float64
+	Counts     []float64
+}
+
+// Limits of a single PutMetricData call.
+const (
+	MaxValues = 150  // per datum
+	MaxData   = 1000 // per call
+)
+
+// Put publishes data to namespace, in as many calls as MaxData requires.
+// Each datum may hold at most MaxValues values.
+func (c *Client) Put(ctx context.Context, namespace string, data []Datum) error {
+	type dimension struct{ Name, Value string }
+	type datum struct {
+		MetricName string
+		Dimensions []dimension
+		Unit       string    `json:",omitempty"`
+		Timestamp  int64     `json:",omitempty"`
+		Values     []float64 `json:",omitempty"`
+		Counts     []float64 `json:",omitempty"`
+	}
+	for len(data) > 0 {
+		batch := data[:min(len(data), MaxData)]
+		data = data[len(batch):]
+		in := struct {
+			Namespace  string
+			MetricData []datum
+		}{Namespace: namespace}
+		for _, d := range batch {
+			if len(d.Values) > MaxValues {
+				return fmt.Errorf("put %s: %d values, want at most %d", d.MetricName, len(d.Values), MaxValues)
+			}
+			md := datum{MetricName: d.MetricName, Unit: d.Unit, Values: d.Values, Counts: d.Counts}
+			if !d.Timestamp.IsZero() {
+				md.Timestamp = d.Timestamp.Unix()
+			}
+			for _, name := range slices.Sorted(maps.Keys(d.Dimensions)) {
+				md.Dimensions = append(md.Dimensions, dimension{name, d.Dimensions[name]})
+			}
+			in.MetricData = append(in.MetricData, md)
+		}
+		if err := c.call(ctx, "PutMetricData", in, &struct{}{}); err != nil {
+			return fmt.Errorf("put metrics to %s: %w", namespace, err)
+		}
+	}
+	return nil
+}
+
+// call calls action with input in and decodes its output into out.
+// PutMetricData answers with no body, which decodes nothing.
+func (c *Client) call(ctx context.Context, action string, in, out any) error {
+	api := &awsapi.Client{Config: c.Config, Service: "monitoring", Endpoint: c.Endpoint, HTTP: c.HTTP}
+	return api.JSON(ctx, "1.0", "GraniteServiceVersion20100801."+action, in, out)
+}
@@ -0,0 +1,114 @@
+package cwmetrics
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFunction(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "GraniteServiceVersion20100801.GetMetricData" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.cloudwatch#InvalidAction","message":"bad target"}`))
+			return
+		}
+		var in struct {
+			MetricDataQueries []struct {
+				MetricStat struct {
+					Metric struct{ Dimensions []map[string]string }
+					Period int
+				}
+			}
+			StartTime, EndTime int64
+			NextToken          string
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		// 10:00:30 to 10:02:10 widens to the three minutes 10:00 to 10:03.
+		if in.StartTime != 1791972000 || in.EndTime != 1791972180 || in.MetricDataQueries[0].MetricStat.Period != 60 ||
+			in.MetricDataQueries[0].MetricStat.Metric.Dimensions[0]["Value"] != "fn" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.cloudwatch#InvalidParameterValueException","message":"bad query"}`))
+			return
+		}
+		calls++
+		if in.NextToken == "" {
+			w.Write([]byte(`{"MetricDataResults":[{"Id":"invocations","Values":[400,600]},{"Id":"throttles","Values":[0,37]},
+				{"Id":"concurrency","Values":[100,512]}],"NextToken":"more"}`))
+			return
+		}
+		w.Write([]byte(`{"MetricDataResults":[{"Id":"invocations","Values":[5]},{"Id":"errors","Values":[2]},{"Id":"concurrency","Values":[3]}]}`))
+	}))
+	defer srv.Close()
+	c := &Client{Endpoint: srv.URL}
+
+	start := time.Date(2026, 10, 14, 10, 0, 30, 0, time.UTC)
+	f, err := c.Function(context.Background(), "fn", start, start.Add(100*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Function{Invocations: 1005, Throttles: 37, Errors: 2, PeakConcurrency: 512}); f != want || calls != 2 {
+		t.Errorf("Function = %+v after %d calls, want %+v after 2", f, calls, want)
+	}
+	if _, err := c.Function(context.Background(), "other", start, start); err == nil || !strings.Contains(err.Error(), "InvalidParameterValueException: bad query") {
+		t.Errorf("rejected query: %v", err)
+	}
+}
+
+func TestPut(t *testing.T) {
+	var calls []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Namespace  string
+			MetricData []struct {
+				MetricName string
+				Dimensions []struct{ Name, Value string }
+				Unit       string
+				Timestamp  int64
+				Values     []float64
+			}
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		if r.Header.Get("X-Amz-Target") != "GraniteServiceVersion20100801.PutMetricData" || in.Namespace != "RuchyBench" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.cloudwatch#InvalidParameterValueException","message":"bad request"}`))
+			return
+		}
+		d := in.MetricData[0]
+		if d.MetricName != "duration_ms" || d.Unit != "Milliseconds" || d.Timestamp != 1791972030 ||
+			len(d.Dimensions) != 2 || d.Dimensions[0].Name != "Runtime" || d.Dimensions[1].Value != "fibonacci" {
+			t.Errorf("datum = %+v", d)
+		}
+		calls = append(calls, len(in.MetricData))
+	}))
+	defer srv.Close()
+	c := &Client{Endpoint: srv.URL}
+
+	data := make([]Datum, MaxData+1)
+	for i := range data {
+		data[i] = Datum{
+			MetricName: "duration_ms",
+			Dimensions: map[string]string{"Workload": "fibonacci", "Runtime": "go"},
+			Unit:       "Milliseconds",
+			Timestamp:  time.Date(2026, 10, 14, 10, 0, 30, 0, time.UTC),
+			Values:     []float64{1.5, 2.5},
+		}
+	}
+	if err := c.Put(context.Background(), "RuchyBench", data); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 || calls[0] != MaxData || calls[1] != 1 {
+		t.Errorf("batches = %v", calls)
+	}
+	if err := c.Put(context.Background(), "RuchyBench", []Datum{{MetricName: "x", Values: make([]float64, MaxValues+1)}}); err == nil {
+		t.Error("oversized datum sent")
+	}
+	if err := c.Put(context.Background(), "Other", data[:1]); err == nil || !strings.Contains(err.Error(), "bad request") {
+		t.Errorf("rejected put: %v", err)
+	}
+}
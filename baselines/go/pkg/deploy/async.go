@@ -0,0 +1,33 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// EventInvokeConfigAPI is the subset of the Lambda client used to
+// configure asynchronous invocation.
+type EventInvokeConfigAPI interface {
+	PutFunctionEventInvokeConfig(ctx context.Context, in *lambda.PutFunctionEventInvokeConfigInput, opts ...func(*lambda.Options)) (*lambda.PutFunctionEventInvokeConfigOutput, error)
+}
+
+// ConfigureAsync sets how Lambda handles fn's failed asynchronous
+// invocations: it retries each up to retries times, 0 to 2, then sends a
+// record of it to the SQS queue with ARN onFailureARN. The configuration
+// replaces any fn had, keeping the default six-hour event age.
+func ConfigureAsync(ctx context.Context, client EventInvokeConfigAPI, fn string, retries int32, onFailureARN string) error {
+	if _, err := client.PutFunctionEventInvokeConfig(ctx, &lambda.PutFunctionEventInvokeConfigInput{
+		FunctionName:         aws.String(fn),
+		MaximumRetryAttempts: aws.Int32(retries),
+		DestinationConfig: &types.DestinationConfig{
+			OnFailure: &types.OnFailure{Destination: aws.String(onFailureARN)},
+		},
+	}); err != nil {
+		return fmt.Errorf("configure asynchronous invocation of %s: %w", fn, err)
+	}
+	return nil
+}
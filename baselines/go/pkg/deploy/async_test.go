@@ -0,0 +1,31 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+type fakeEventInvokeConfig struct {
+	in *lambda.PutFunctionEventInvokeConfigInput
+}
+
+func (f *fakeEventInvokeConfig) PutFunctionEventInvokeConfig(_ context.Context, in *lambda.PutFunctionEventInvokeConfigInput, _ ...func(*lambda.Options)) (*lambda.PutFunctionEventInvokeConfigOutput, error) {
+	f.in = in
+	return &lambda.PutFunctionEventInvokeConfigOutput{}, nil
+}
+
+func TestConfigureAsync(t *testing.T) {
+	f := &fakeEventInvokeConfig{}
+	if err := ConfigureAsync(context.Background(), f, "fn", 1, "arn:aws:sqs:us-east-1:1:dlq"); err != nil {
+		t.Fatal(err)
+	}
+	if aws.ToString(f.in.FunctionName) != "fn" || aws.ToInt32(f.in.MaximumRetryAttempts) != 1 || f.in.MaximumEventAgeInSeconds != nil {
+		t.Errorf("input = %+v", f.in)
+	}
+	if d := f.in.DestinationConfig; d == nil || d.OnFailure == nil || aws.ToString(d.OnFailure.Destination) != "arn:aws:sqs:us-east-1:1:dlq" {
+		t.Errorf("destination = %+v", d)
+	}
+}
@@ -0,0 +1,456 @@
+// Package deploy creates, updates and deletes the benchmark functions
+// directly through the Lambda API, so the full comparison matrix can be
+// stood up (and torn down) without Terraform, SAM or the AWS CLI. Image
+// targets' container images are pushed to ECR first; see Registry.
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/errorpath"
+)
+
+// Defaults shared with scripts/deploy-baselines.sh.
+const (
+	DefaultMemoryMB   = 128
+	DefaultTimeoutSec = 30
+	// DefaultEphemeralMB is Lambda's default, and smallest, /tmp size.
+	DefaultEphemeralMB = 512
+)
+
+// TagKey marks the functions, roles and repositories created by the
+// harness, which ruchy-bench gc deletes.
+const TagKey = "ruchy-bench"
+
+// CreateFunction is retried while a new role propagates.
+const roleAttempts = 6
+
+var roleRetryDelay = 2 * time.Second // a variable so tests need not wait
+
+// Config is the function configuration a target is deployed with.
+type Config struct {
+	Runtime    types.Runtime
+	Handler    string
+	Arch       types.Architecture
+	MemoryMB   int32
+	TimeoutSec int32
+	// EphemeralMB is the size of /tmp, 512 to 10240 MB.
+	EphemeralMB int32
+	Env         map[string]string
+	// SnapStart snapshots published versions; Deploy then publishes one
+	// and points discover.SnapStartAlias at it.
+	SnapStart bool
+	// PackageType is Image for functions deployed from a container image,
+	// whose runtime and handler come from the image instead of Runtime and
+	// Handler. Empty means Zip.
+	PackageType types.PackageType
+	// Tracing enables active X-Ray tracing. The execution role needs
+	// write access to X-Ray; see GrantTracing.
+	Tracing bool
+	// URLInvokeMode, when set, gives the function an AWS_IAM-authenticated
+	// function URL in that invoke mode.
+	URLInvokeMode types.InvokeMode
+	// Layers are the layer version ARNs attached to a zip function, such
+	// as the extension layer PublishLayer returns.
+	Layers []string
+	// SubnetIDs and SecurityGroupIDs attach the function to a VPC when
+	// set, such as the harness VPC of package vpc. The execution role
+	// needs to manage network interfaces; see GrantVPCAccess.
+	SubnetIDs        []string
+	SecurityGroupIDs []string
+}
+
+// StreamWorkload is the workload answered through a RESPONSE_STREAM
+// function URL; ConfigFor gives its functions one.
+const StreamWorkload = "stream"
+
+// TimeoutSec is the function timeout of the timeout workload, which
+// runs past it on every invocation: the shortest Lambda allows, so each
+// costs one second.
+const TimeoutSec = 1
+
+// TmpIOWorkload is the workload writing and reading back a file in /tmp,
+// 512 MB by default, and TmpIOEphemeralMB the /tmp size ConfigFor gives
+// its functions: the default 512 MB cannot hold the file alongside what
+// the runtime keeps there.
+const (
+	TmpIOWorkload    = "tmpio"
+	TmpIOEphemeralMB = 1024
+)
+
+// ConfigFor returns the configuration for t at the default memory size.
+// Python baselines use the managed runtime; everything else ships a
+// bootstrap binary on provided.al2023.
+func ConfigFor(t discover.Target) Config {
+	c := Config{
+		Runtime:     types.RuntimeProvidedal2023,
+		Handler:     "bootstrap",
+		Arch:        types.ArchitectureX8664,
+		MemoryMB:    DefaultMemoryMB,
+		TimeoutSec:  DefaultTimeoutSec,
+		EphemeralMB: DefaultEphemeralMB,
+	}
+	if t.Runtime == "python" {
+		c.Runtime, c.Handler = types.RuntimePython312, "index.handler"
+	}
+	if t.Arch == discover.ArchARM64 {
+		c.Arch = types.ArchitectureArm64
+	}
+	if t.Package == discover.PackageImage {
+		c.PackageType = types.PackageTypeImage
+	}
+	c.SnapStart = t.SnapStart
+	if t.Workload == StreamWorkload {
+		c.URLInvokeMode = types.InvokeModeResponseStream
+	}
+	if t.Workload == errorpath.Timeout {
+		c.TimeoutSec = TimeoutSec
+	}
+	if t.Workload == TmpIOWorkload {
+		c.EphemeralMB = TmpIOEphemeralMB
+	}
+	return c
+}
+
+// LambdaAPI is the subset of the Lambda client used to manage functions.
+type LambdaAPI interface {
+	lambda.GetFunctionAPIClient
+	lambda.GetFunctionConfigurationAPIClient
+	CreateFunction(ctx context.Context, in *lambda.CreateFunctionInput, opts ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error)
+	UpdateFunctionCode(ctx context.Context, in *lambda.UpdateFunctionCodeInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error)
+	UpdateFunctionConfiguration(ctx context.Context, in *lambda.UpdateFunctionConfigurationInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error)
+	DeleteFunction(ctx context.Context, in *lambda.DeleteFunctionInput, opts ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error)
+	PublishVersion(ctx context.Context, in *lambda.PublishVersionInput, opts ...func(*lambda.Options)) (*lambda.PublishVersionOutput, error)
+	CreateAlias(ctx context.Context, in *lambda.CreateAliasInput, opts ...func(*lambda.Options)) (*lambda.CreateAliasOutput, error)
+	UpdateAlias(ctx context.Context, in *lambda.UpdateAliasInput, opts ...func(*lambda.Options)) (*lambda.UpdateAliasOutput, error)
+	URLConfigAPI
+	CreateFunctionUrlConfig(ctx context.Context, in *lambda.CreateFunctionUrlConfigInput, opts ...func(*lambda.Options)) (*lambda.CreateFunctionUrlConfigOutput, error)
+	UpdateFunctionUrlConfig(ctx context.Context, in *lambda.UpdateFunctionUrlConfigInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionUrlConfigOutput, error)
+}
+
+// Deployer manages functions in one account and region.
+type Deployer struct {
+	Client LambdaAPI
+	// RoleARN is the execution role attached to created functions.
+	RoleARN string
+	// WaitTimeout bounds each wait for the function to settle. Zero means
+	// five minutes.
+	WaitTimeout time.Duration
+	// Force updates functions whose code and configuration already match
+	// what Deploy would update them to.
+	Force bool
+}
+
+// Action reports what Deploy did.
+type Action string
+
+const (
+	Created Action = "created"
+	Updated Action = "updated"
+	// Unchanged is a function whose code and configuration were already
+	// as deployed, which Deploy left alone.
+	Unchanged Action = "unchanged"
+)
+
+// Deploy uploads the zip at pkg as functionName, creating the function or
+// updating its code and configuration, and waits until it can be invoked.
+// A zip function already running the package with configuration c is not
+// updated: see Unchanged.
+// For image configurations pkg is instead the URI of an image in ECR, as
+// returned by Registry.Push. SnapStart functions also get a published
+// version behind discover.SnapStartAlias, and configurations with a
+// URLInvokeMode a function URL.
+func (d *Deployer) Deploy(ctx context.Context, functionName, pkg string, c Config) (Action, error) {
+	code, err := loadCode(pkg, c)
+	if err != nil {
+		return "", err
+	}
+	current, err := d.get(ctx, functionName)
+	if err != nil {
+		return "", err
+	}
+	action := Updated
+	switch {
+	case current == nil:
+		action, err = Created, d.create(ctx, functionName, code, c)
+	case !d.Force && unchanged(current, code, c):
+		action = Unchanged
+	default:
+		err = d.update(ctx, functionName, code, c)
+	}
+	if err == nil && c.SnapStart {
+		_, err = d.Publish(ctx, functionName, discover.SnapStartAlias)
+	}
+	if err == nil && c.URLInvokeMode != "" {
+		err = d.functionURL(ctx, functionName, c.URLInvokeMode)
+	}
+	return action, err
+}
+
+// functionURL creates or updates the function URL of fn. It requires IAM
+// authentication, so only principals allowed lambda:InvokeFunctionUrl can
+// call it; see URL.
+func (d *Deployer) functionURL(ctx context.Context, fn string, mode types.InvokeMode) error {
+	_, err := d.Client.UpdateFunctionUrlConfig(ctx, &lambda.UpdateFunctionUrlConfigInput{
+		FunctionName: aws.String(fn),
+		AuthType:     types.FunctionUrlAuthTypeAwsIam,
+		InvokeMode:   mode,
+	})
+	var missing *types.ResourceNotFoundException
+	if errors.As(err, &missing) {
+		_, err = d.Client.CreateFunctionUrlConfig(ctx, &lambda.CreateFunctionUrlConfigInput{
+			FunctionName: aws.String(fn),
+			AuthType:     types.FunctionUrlAuthTypeAwsIam,
+			InvokeMode:   mode,
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("configure %s function URL: %w", fn, err)
+	}
+	return nil
+}
+
+// URLConfigAPI reads a function URL's configuration.
+type URLConfigAPI interface {
+	GetFunctionUrlConfig(ctx context.Context, in *lambda.GetFunctionUrlConfigInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionUrlConfigOutput, error)
+}
+
+// URL returns the function URL of fn.
+func URL(ctx context.Context, client URLConfigAPI, fn string) (string, error) {
+	out, err := client.GetFunctionUrlConfig(ctx, &lambda.GetFunctionUrlConfigInput{FunctionName: aws.String(fn)})
+	var missing *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &missing):
+		return "", fmt.Errorf("%s has no function URL; redeploy it", fn)
+	case err != nil:
+		return "", fmt.Errorf("get %s function URL: %w", fn, err)
+	}
+	return aws.ToString(out.FunctionUrl), nil
+}
+
+// Publish publishes $LATEST as a new version, waits until it is active
+// (for SnapStart, until its snapshot is ready), and points alias at it,
+// creating the alias if needed.
+func (d *Deployer) Publish(ctx context.Context, functionName, alias string) (version string, err error) {
+	out, err := d.Client.PublishVersion(ctx, &lambda.PublishVersionInput{FunctionName: aws.String(functionName)})
+	if err != nil {
+		return "", fmt.Errorf("publish %s: %w", functionName, err)
+	}
+	version = aws.ToString(out.Version)
+	waiter := lambda.NewPublishedVersionActiveWaiter(d.Client)
+	if err := waiter.Wait(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(functionName),
+		Qualifier:    aws.String(version),
+	}, d.waitTimeout()); err != nil {
+		return "", fmt.Errorf("wait for %s version %s: %w", functionName, version, err)
+	}
+
+	_, err = d.Client.UpdateAlias(ctx, &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(functionName),
+		Name:            aws.String(alias),
+		FunctionVersion: aws.String(version),
+	})
+	var missing *types.ResourceNotFoundException
+	if errors.As(err, &missing) {
+		_, err = d.Client.CreateAlias(ctx, &lambda.CreateAliasInput{
+			FunctionName:    aws.String(functionName),
+			Name:            aws.String(alias),
+			FunctionVersion: aws.String(version),
+		})
+	}
+	if err != nil {
+		return "", fmt.Errorf("point %s:%s at version %s: %w", functionName, alias, version, err)
+	}
+	return version, nil
+}
+
+// code is a function's deployment package: a zip's bytes or an image URI.
+type code struct {
+	zip      []byte
+	imageURI string
+}
+
+func loadCode(pkg string, c Config) (code, error) {
+	if c.PackageType == types.PackageTypeImage {
+		return code{imageURI: pkg}, nil
+	}
+	zip, err := os.ReadFile(pkg)
+	return code{zip: zip}, err
+}
+
+func (d *Deployer) create(ctx context.Context, fn string, code code, c Config) error {
+	if d.RoleARN == "" {
+		return errors.New("no execution role configured")
+	}
+	in := &lambda.CreateFunctionInput{
+		FunctionName:  aws.String(fn),
+		Role:          aws.String(d.RoleARN),
+		Architectures: []types.Architecture{c.Arch},
+		MemorySize:    aws.Int32(c.MemoryMB),
+		Timeout:       aws.Int32(c.TimeoutSec),
+		Tags:          map[string]string{TagKey: "true"},
+	}
+	if c.EphemeralMB > 0 {
+		in.EphemeralStorage = &types.EphemeralStorage{Size: aws.Int32(c.EphemeralMB)}
+	}
+	if code.imageURI != "" {
+		in.PackageType = types.PackageTypeImage
+		in.Code = &types.FunctionCode{ImageUri: aws.String(code.imageURI)}
+	} else {
+		in.Runtime, in.Handler = c.Runtime, aws.String(c.Handler)
+		in.Code = &types.FunctionCode{ZipFile: code.zip}
+		in.Layers = c.Layers
+	}
+	if len(c.Env) > 0 {
+		in.Environment = &types.Environment{Variables: c.Env}
+	}
+	if c.SnapStart {
+		in.SnapStart = &types.SnapStart{ApplyOn: types.SnapStartApplyOnPublishedVersions}
+	}
+	if c.Tracing {
+		in.TracingConfig = &types.TracingConfig{Mode: types.TracingModeActive}
+	}
+	if len(c.SubnetIDs) > 0 {
+		in.VpcConfig = &types.VpcConfig{SubnetIds: c.SubnetIDs, SecurityGroupIds: c.SecurityGroupIDs}
+	}
+	// A just-created role takes a few seconds to become assumable by
+	// Lambda, which reports it as an invalid parameter; retry until it is.
+	for attempt := 1; ; attempt++ {
+		_, err := d.Client.CreateFunction(ctx, in)
+		if err == nil {
+			break
+		}
+		var invalid *types.InvalidParameterValueException
+		if !errors.As(err, &invalid) || attempt == roleAttempts {
+			return fmt.Errorf("create %s: %w", fn, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(roleRetryDelay):
+		}
+	}
+	waiter := lambda.NewFunctionActiveV2Waiter(d.Client)
+	if err := waiter.Wait(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(fn)}, d.waitTimeout()); err != nil {
+		return fmt.Errorf("wait for %s to become active: %w", fn, err)
+	}
+	return nil
+}
+
+func (d *Deployer) update(ctx context.Context, fn string, code code, c Config) error {
+	uc := &lambda.UpdateFunctionCodeInput{
+		FunctionName:  aws.String(fn),
+		ZipFile:       code.zip,
+		Architectures: []types.Architecture{c.Arch},
+	}
+	if code.imageURI != "" {
+		uc.ImageUri = aws.String(code.imageURI)
+	}
+	if _, err := d.Client.UpdateFunctionCode(ctx, uc); err != nil {
+		return fmt.Errorf("update %s code: %w", fn, err)
+	}
+	if err := d.waitUpdated(ctx, fn); err != nil {
+		return err
+	}
+	in := &lambda.UpdateFunctionConfigurationInput{
+		FunctionName:  aws.String(fn),
+		MemorySize:    aws.Int32(c.MemoryMB),
+		Timeout:       aws.Int32(c.TimeoutSec),
+		TracingConfig: &types.TracingConfig{Mode: tracingMode(c)},
+	}
+	if c.EphemeralMB > 0 {
+		in.EphemeralStorage = &types.EphemeralStorage{Size: aws.Int32(c.EphemeralMB)}
+	}
+	if code.imageURI == "" {
+		in.Runtime, in.Handler = c.Runtime, aws.String(c.Handler)
+		// Like the environment below, layers are replaced, so a function
+		// redeployed without its extension loses it.
+		in.Layers = c.Layers
+		if in.Layers == nil {
+			in.Layers = []string{}
+		}
+	}
+	// The environment is replaced on every update, like tracing, so a
+	// variable dropped from the config is removed from the function.
+	env := c.Env
+	if env == nil {
+		env = map[string]string{}
+	}
+	in.Environment = &types.Environment{Variables: env}
+	// So is the VPC configuration: empty lists detach the function.
+	vpc := &types.VpcConfig{SubnetIds: c.SubnetIDs, SecurityGroupIds: c.SecurityGroupIDs}
+	if vpc.SubnetIds == nil {
+		vpc.SubnetIds, vpc.SecurityGroupIds = []string{}, []string{}
+	}
+	in.VpcConfig = vpc
+	if c.SnapStart {
+		in.SnapStart = &types.SnapStart{ApplyOn: types.SnapStartApplyOnPublishedVersions}
+	}
+	if _, err := d.Client.UpdateFunctionConfiguration(ctx, in); err != nil {
+		return fmt.Errorf("update %s configuration: %w", fn, err)
+	}
+	return d.waitUpdated(ctx, fn)
+}
+
+// tracingMode is set on every update so that redeploying without tracing
+// turns it back off.
+func tracingMode(c Config) types.TracingMode {
+	if c.Tracing {
+		return types.TracingModeActive
+	}
+	return types.TracingModePassThrough
+}
+
+// Delete removes functionName. A function that does not exist is not an
+// error, so teardown can be re-run after a partial failure.
+func (d *Deployer) Delete(ctx context.Context, functionName string) (deleted bool, err error) {
+	_, err = d.Client.DeleteFunction(ctx, &lambda.DeleteFunctionInput{FunctionName: aws.String(functionName)})
+	var missing *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &missing):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("delete %s: %w", functionName, err)
+	}
+	return true, nil
+}
+
+// get returns the configuration of fn, or nil if it does not exist.
+func (d *Deployer) get(ctx context.Context, fn string) (*types.FunctionConfiguration, error) {
+	out, err := d.Client.GetFunction(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(fn)})
+	var missing *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &missing):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("get %s: %w", fn, err)
+	}
+	if out.Configuration == nil {
+		return &types.FunctionConfiguration{}, nil
+	}
+	return out.Configuration, nil
+}
+
+func (d *Deployer) waitUpdated(ctx context.Context, fn string) error {
+	waiter := lambda.NewFunctionUpdatedV2Waiter(d.Client)
+	if err := waiter.Wait(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(fn)}, d.waitTimeout()); err != nil {
+		return fmt.Errorf("wait for %s update: %w", fn, err)
+	}
+	return nil
+}
+
+func (d *Deployer) waitTimeout() time.Duration {
+	if d.WaitTimeout == 0 {
+		return 5 * time.Minute
+	}
+	return d.WaitTimeout
+}
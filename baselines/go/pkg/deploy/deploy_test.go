@@ -0,0 +1,393 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/discover"
+)
+
+type fakeLambda struct {
+	functions map[string]*lambda.CreateFunctionInput
+	// roleErrors is how many CreateFunction calls fail as if the role had
+	// not propagated yet.
+	roleErrors int
+	calls      []string
+	versions   int
+	aliases    map[string]string
+	// code and config are the last update requests.
+	code   *lambda.UpdateFunctionCodeInput
+	config *lambda.UpdateFunctionConfigurationInput
+	// urls holds the invoke mode of every function URL.
+	urls map[string]types.InvokeMode
+	// live holds what GetFunction reports of the functions in it, in
+	// place of a configuration without code.
+	live map[string]*types.FunctionConfiguration
+}
+
+func (f *fakeLambda) GetFunction(_ context.Context, in *lambda.GetFunctionInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	if _, ok := f.functions[aws.ToString(in.FunctionName)]; !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	if live, ok := f.live[aws.ToString(in.FunctionName)]; ok {
+		return &lambda.GetFunctionOutput{Configuration: live}, nil
+	}
+	return &lambda.GetFunctionOutput{Configuration: &types.FunctionConfiguration{
+		State:            types.StateActive,
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	}}, nil
+}
+
+func (f *fakeLambda) GetFunctionConfiguration(_ context.Context, _ *lambda.GetFunctionConfigurationInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error) {
+	return &lambda.GetFunctionConfigurationOutput{State: types.StateActive}, nil
+}
+
+func (f *fakeLambda) PublishVersion(_ context.Context, _ *lambda.PublishVersionInput, _ ...func(*lambda.Options)) (*lambda.PublishVersionOutput, error) {
+	f.versions++
+	return &lambda.PublishVersionOutput{Version: aws.String(fmt.Sprint(f.versions))}, nil
+}
+
+func (f *fakeLambda) UpdateAlias(_ context.Context, in *lambda.UpdateAliasInput, _ ...func(*lambda.Options)) (*lambda.UpdateAliasOutput, error) {
+	name := aws.ToString(in.Name)
+	if _, ok := f.aliases[name]; !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("alias not found")}
+	}
+	f.aliases[name] = aws.ToString(in.FunctionVersion)
+	return &lambda.UpdateAliasOutput{}, nil
+}
+
+func (f *fakeLambda) CreateAlias(_ context.Context, in *lambda.CreateAliasInput, _ ...func(*lambda.Options)) (*lambda.CreateAliasOutput, error) {
+	f.aliases[aws.ToString(in.Name)] = aws.ToString(in.FunctionVersion)
+	return &lambda.CreateAliasOutput{}, nil
+}
+
+func (f *fakeLambda) CreateFunction(_ context.Context, in *lambda.CreateFunctionInput, _ ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error) {
+	f.calls = append(f.calls, "create")
+	if f.roleErrors > 0 {
+		f.roleErrors--
+		return nil, &types.InvalidParameterValueException{Message: aws.String("The role defined for the function cannot be assumed by Lambda.")}
+	}
+	f.functions[aws.ToString(in.FunctionName)] = in
+	return &lambda.CreateFunctionOutput{}, nil
+}
+
+func (f *fakeLambda) UpdateFunctionCode(_ context.Context, in *lambda.UpdateFunctionCodeInput, _ ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error) {
+	f.calls = append(f.calls, "code")
+	f.code = in
+	return &lambda.UpdateFunctionCodeOutput{}, nil
+}
+
+func (f *fakeLambda) UpdateFunctionConfiguration(_ context.Context, in *lambda.UpdateFunctionConfigurationInput, _ ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error) {
+	f.calls = append(f.calls, "config")
+	f.config = in
+	f.functions[aws.ToString(in.FunctionName)].MemorySize = in.MemorySize
+	return &lambda.UpdateFunctionConfigurationOutput{}, nil
+}
+
+func (f *fakeLambda) DeleteFunction(_ context.Context, in *lambda.DeleteFunctionInput, _ ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error) {
+	fn := aws.ToString(in.FunctionName)
+	if _, ok := f.functions[fn]; !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	delete(f.functions, fn)
+	return &lambda.DeleteFunctionOutput{}, nil
+}
+
+func (f *fakeLambda) GetFunctionUrlConfig(_ context.Context, in *lambda.GetFunctionUrlConfigInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionUrlConfigOutput, error) {
+	fn := aws.ToString(in.FunctionName)
+	mode, ok := f.urls[fn]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("url not found")}
+	}
+	return &lambda.GetFunctionUrlConfigOutput{FunctionUrl: aws.String("https://" + fn + ".lambda-url.us-east-1.on.aws/"), InvokeMode: mode}, nil
+}
+
+func (f *fakeLambda) CreateFunctionUrlConfig(_ context.Context, in *lambda.CreateFunctionUrlConfigInput, _ ...func(*lambda.Options)) (*lambda.CreateFunctionUrlConfigOutput, error) {
+	f.calls = append(f.calls, "create url")
+	f.urls[aws.ToString(in.FunctionName)] = in.InvokeMode
+	return &lambda.CreateFunctionUrlConfigOutput{}, nil
+}
+
+func (f *fakeLambda) UpdateFunctionUrlConfig(_ context.Context, in *lambda.UpdateFunctionUrlConfigInput, _ ...func(*lambda.Options)) (*lambda.UpdateFunctionUrlConfigOutput, error) {
+	fn := aws.ToString(in.FunctionName)
+	if _, ok := f.urls[fn]; !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("url not found")}
+	}
+	f.calls = append(f.calls, "update url")
+	f.urls[fn] = in.InvokeMode
+	return &lambda.UpdateFunctionUrlConfigOutput{}, nil
+}
+
+func writePackage(t *testing.T) string {
+	t.Helper()
+	pkg := filepath.Join(t.TempDir(), "function.zip")
+	if err := os.WriteFile(pkg, []byte("zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func TestDeployCreatesThenUpdates(t *testing.T) {
+	roleRetryDelay = 0
+	fake := &fakeLambda{functions: map[string]*lambda.CreateFunctionInput{}, roleErrors: 2}
+	d := &Deployer{Client: fake, RoleARN: "arn:aws:iam::123456789012:role/test"}
+	pkg := writePackage(t)
+	c := ConfigFor(discover.Target{Runtime: "go", Arch: discover.ArchARM64})
+	c.Tracing = true
+	c.Env = map[string]string{"BENCH_RUNTIME_METRICS": "1"}
+	c.Layers = []string{"arn:aws:lambda:us-east-1:123456789012:layer:ruchy-bench-noop-telemetry-arm64:1"}
+	c.SubnetIDs, c.SecurityGroupIDs = []string{"subnet-1", "subnet-2"}, []string{"sg-1"}
+
+	action, err := d.Deploy(context.Background(), "baseline-go-arm64", pkg, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := fake.functions["baseline-go-arm64"]
+	if action != Created || in == nil {
+		t.Fatalf("action = %s, function = %+v", action, in)
+	}
+	if in.Architectures[0] != types.ArchitectureArm64 || aws.ToString(in.Handler) != "bootstrap" ||
+		aws.ToInt32(in.MemorySize) != DefaultMemoryMB || in.Tags[TagKey] == "" || string(in.Code.ZipFile) != "zip" {
+		t.Errorf("created with %+v", in)
+	}
+	if len(in.Layers) != 1 {
+		t.Errorf("created with layers %v", in.Layers)
+	}
+	if in.TracingConfig == nil || in.TracingConfig.Mode != types.TracingModeActive {
+		t.Errorf("created with tracing %+v, want active", in.TracingConfig)
+	}
+	if in.VpcConfig == nil || len(in.VpcConfig.SubnetIds) != 2 || len(in.VpcConfig.SecurityGroupIds) != 1 {
+		t.Errorf("created with VPC config %+v", in.VpcConfig)
+	}
+	if len(fake.calls) != 3 {
+		t.Errorf("calls = %v, want three create attempts", fake.calls)
+	}
+
+	c.MemoryMB, c.Tracing, c.Env, c.Layers = 512, false, nil, nil
+	c.SubnetIDs, c.SecurityGroupIDs = nil, nil
+	if action, err = d.Deploy(context.Background(), "baseline-go-arm64", pkg, c); err != nil || action != Updated {
+		t.Fatalf("second deploy: %s, %v", action, err)
+	}
+	if got := aws.ToInt32(fake.functions["baseline-go-arm64"].MemorySize); got != 512 {
+		t.Errorf("memory after update = %d, want 512", got)
+	}
+	if fake.config.TracingConfig.Mode != types.TracingModePassThrough {
+		t.Errorf("tracing after update = %s, want it turned off", fake.config.TracingConfig.Mode)
+	}
+	if env := fake.config.Environment; env == nil || len(env.Variables) != 0 {
+		t.Errorf("environment after update = %+v, want it cleared", env)
+	}
+	if layers := fake.config.Layers; layers == nil || len(layers) != 0 {
+		t.Errorf("layers after update = %v, want them detached", layers)
+	}
+	if vpc := fake.config.VpcConfig; vpc == nil || vpc.SubnetIds == nil || len(vpc.SubnetIds) != 0 {
+		t.Errorf("VPC config after update = %+v, want it detached", vpc)
+	}
+}
+
+func TestDeploySnapStartPublishesAlias(t *testing.T) {
+	fake := &fakeLambda{functions: map[string]*lambda.CreateFunctionInput{}, aliases: map[string]string{}}
+	d := &Deployer{Client: fake, RoleARN: "arn:aws:iam::123456789012:role/test"}
+	tgt := discover.Target{Runtime: "python", Workload: discover.MinimalWorkload, Kind: discover.KindLambda, Arch: discover.ArchX86, SnapStart: true}
+	c := ConfigFor(tgt)
+	if !c.SnapStart {
+		t.Fatal("ConfigFor dropped SnapStart")
+	}
+	pkg := writePackage(t)
+	if _, err := d.Deploy(context.Background(), tgt.FunctionName(), pkg, c); err != nil {
+		t.Fatal(err)
+	}
+	in := fake.functions["baseline-python-snapstart"]
+	if in == nil || in.SnapStart == nil || in.SnapStart.ApplyOn != types.SnapStartApplyOnPublishedVersions {
+		t.Fatalf("created without SnapStart: %+v", in)
+	}
+	if fake.aliases[discover.SnapStartAlias] != "1" {
+		t.Errorf("aliases = %v, want %s -> 1", fake.aliases, discover.SnapStartAlias)
+	}
+	// Redeploying publishes a new version and moves the alias.
+	if _, err := d.Deploy(context.Background(), tgt.FunctionName(), pkg, c); err != nil {
+		t.Fatal(err)
+	}
+	if fake.aliases[discover.SnapStartAlias] != "2" {
+		t.Errorf("aliases after redeploy = %v", fake.aliases)
+	}
+}
+
+func TestDeployImage(t *testing.T) {
+	fake := &fakeLambda{functions: map[string]*lambda.CreateFunctionInput{}}
+	d := &Deployer{Client: fake, RoleARN: "arn:aws:iam::123456789012:role/test"}
+	tgt := discover.Target{Runtime: "go", Workload: discover.MinimalWorkload, Kind: discover.KindLambda, Arch: discover.ArchX86, Package: discover.PackageImage}
+	c := ConfigFor(tgt)
+	const uri = "123456789012.dkr.ecr.us-east-1.amazonaws.com/ruchy-bench:baseline-go-image"
+	if _, err := d.Deploy(context.Background(), tgt.FunctionName(), uri, c); err != nil {
+		t.Fatal(err)
+	}
+	in := fake.functions["baseline-go-image"]
+	if in == nil || in.PackageType != types.PackageTypeImage || aws.ToString(in.Code.ImageUri) != uri ||
+		in.Code.ZipFile != nil || in.Runtime != "" || in.Handler != nil {
+		t.Fatalf("created with %+v", in)
+	}
+	if _, err := d.Deploy(context.Background(), tgt.FunctionName(), uri, c); err != nil {
+		t.Fatal(err)
+	}
+	if aws.ToString(fake.code.ImageUri) != uri || fake.code.ZipFile != nil {
+		t.Errorf("code updated with %+v", fake.code)
+	}
+	if fake.config.Runtime != "" || fake.config.Handler != nil {
+		t.Errorf("image configuration updated with runtime %q, handler %v", fake.config.Runtime, fake.config.Handler)
+	}
+}
+
+func TestDeployRequiresRoleToCreate(t *testing.T) {
+	d := &Deployer{Client: &fakeLambda{functions: map[string]*lambda.CreateFunctionInput{}}}
+	if _, err := d.Deploy(context.Background(), "baseline-go", writePackage(t), Config{}); err == nil {
+		t.Error("created a function without a role")
+	}
+}
+
+func TestDeleteIgnoresMissing(t *testing.T) {
+	fake := &fakeLambda{functions: map[string]*lambda.CreateFunctionInput{"baseline-go": {}}}
+	d := &Deployer{Client: fake}
+	if deleted, err := d.Delete(context.Background(), "baseline-go"); !deleted || err != nil {
+		t.Errorf("Delete existing = %v, %v", deleted, err)
+	}
+	if deleted, err := d.Delete(context.Background(), "baseline-go"); deleted || err != nil {
+		t.Errorf("Delete missing = %v, %v", deleted, err)
+	}
+}
+
+func TestConfigForPython(t *testing.T) {
+	c := ConfigFor(discover.Target{Runtime: "python", Arch: discover.ArchX86})
+	if c.Runtime != types.RuntimePython312 || c.Handler != "index.handler" || c.Arch != types.ArchitectureX8664 {
+		t.Errorf("python config = %+v", c)
+	}
+}
+
+func TestConfigForTimeout(t *testing.T) {
+	if c := ConfigFor(discover.Target{Runtime: "python", Workload: "timeout"}); c.TimeoutSec != TimeoutSec {
+		t.Errorf("timeout workload times out after %d s", c.TimeoutSec)
+	}
+	if c := ConfigFor(discover.Target{Runtime: "go", Workload: "error"}); c.TimeoutSec != DefaultTimeoutSec {
+		t.Errorf("error workload times out after %d s", c.TimeoutSec)
+	}
+}
+
+func TestDeployTmpIOGetsEphemeralStorage(t *testing.T) {
+	fake := &fakeLambda{functions: map[string]*lambda.CreateFunctionInput{}}
+	d := &Deployer{Client: fake, RoleARN: "arn:aws:iam::123456789012:role/test"}
+	pkg := writePackage(t)
+	c := ConfigFor(discover.Target{Runtime: "go", Workload: TmpIOWorkload})
+	if _, err := d.Deploy(context.Background(), "baseline-go-tmpio", pkg, c); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.functions["baseline-go-tmpio"].EphemeralStorage; got == nil || aws.ToInt32(got.Size) != TmpIOEphemeralMB {
+		t.Errorf("created with ephemeral storage %+v, want %d MB", got, TmpIOEphemeralMB)
+	}
+	c.EphemeralMB = 2048
+	if _, err := d.Deploy(context.Background(), "baseline-go-tmpio", pkg, c); err != nil {
+		t.Fatal(err)
+	}
+	if got := fake.config.EphemeralStorage; got == nil || aws.ToInt32(got.Size) != 2048 {
+		t.Errorf("updated with ephemeral storage %+v, want 2048 MB", got)
+	}
+}
+
+func TestDeployStreamGetsFunctionURL(t *testing.T) {
+	fake := &fakeLambda{functions: map[string]*lambda.CreateFunctionInput{}, urls: map[string]types.InvokeMode{}}
+	d := &Deployer{Client: fake, RoleARN: "arn:aws:iam::123456789012:role/test"}
+	pkg := writePackage(t)
+	c := ConfigFor(discover.Target{Runtime: "go", Workload: StreamWorkload})
+	if c.URLInvokeMode != types.InvokeModeResponseStream {
+		t.Fatalf("stream config invoke mode = %q", c.URLInvokeMode)
+	}
+	for range 2 {
+		if _, err := d.Deploy(context.Background(), "baseline-go-stream", pkg, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fake.urls["baseline-go-stream"] != types.InvokeModeResponseStream {
+		t.Errorf("urls = %v", fake.urls)
+	}
+	if got := fmt.Sprint(fake.calls); got != "[create create url code config update url]" {
+		t.Errorf("calls = %s", got)
+	}
+	u, err := URL(context.Background(), fake, "baseline-go-stream")
+	if err != nil || u != "https://baseline-go-stream.lambda-url.us-east-1.on.aws/" {
+		t.Errorf("URL = %q, %v", u, err)
+	}
+	if _, err := URL(context.Background(), fake, "baseline-go-fibonacci"); err == nil {
+		t.Error("URL of a function without one succeeded")
+	}
+
+	if c := ConfigFor(discover.Target{Runtime: "go", Workload: "fibonacci"}); c.URLInvokeMode != "" {
+		t.Errorf("fibonacci config invoke mode = %q", c.URLInvokeMode)
+	}
+}
+
+func TestDeploySkipsUnchanged(t *testing.T) {
+	fake := &fakeLambda{functions: map[string]*lambda.CreateFunctionInput{}, live: map[string]*types.FunctionConfiguration{}}
+	d := &Deployer{Client: fake, RoleARN: "arn:aws:iam::123456789012:role/test"}
+	pkg := writePackage(t)
+	c := ConfigFor(discover.Target{Runtime: "go", Workload: "fibonacci", Arch: discover.ArchARM64})
+	c.Env = map[string]string{"BENCH_RUNTIME_METRICS": "1"}
+	c.SubnetIDs, c.SecurityGroupIDs = []string{"subnet-1", "subnet-2"}, []string{"sg-1"}
+	if action, err := d.Deploy(context.Background(), "baseline-go-fibonacci", pkg, c); err != nil || action != Created {
+		t.Fatalf("first deploy: %s, %v", action, err)
+	}
+	// What Lambda reports of the function just created, "zip" hashed.
+	fake.live["baseline-go-fibonacci"] = &types.FunctionConfiguration{
+		CodeSha256:       aws.String("SnD+mqZDbgLC3qNA+9HjUuTvLYzmylKtJdS5VHH8i/I="),
+		Runtime:          c.Runtime,
+		Handler:          aws.String(c.Handler),
+		Architectures:    []types.Architecture{types.ArchitectureArm64},
+		MemorySize:       aws.Int32(c.MemoryMB),
+		Timeout:          aws.Int32(c.TimeoutSec),
+		EphemeralStorage: &types.EphemeralStorage{Size: aws.Int32(DefaultEphemeralMB)},
+		Environment:      &types.EnvironmentResponse{Variables: map[string]string{"BENCH_RUNTIME_METRICS": "1"}},
+		VpcConfig:        &types.VpcConfigResponse{SubnetIds: []string{"subnet-2", "subnet-1"}, SecurityGroupIds: []string{"sg-1"}},
+		TracingConfig:    &types.TracingConfigResponse{Mode: types.TracingModePassThrough},
+		State:            types.StateActive,
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	}
+	fake.calls = nil
+	if action, err := d.Deploy(context.Background(), "baseline-go-fibonacci", pkg, c); err != nil || action != Unchanged || len(fake.calls) != 0 {
+		t.Errorf("redeploy unchanged: %s, %v, calls %v", action, err, fake.calls)
+	}
+
+	for name, change := range map[string]func(*Config){
+		"memory":  func(c *Config) { c.MemoryMB = 1024 },
+		"env":     func(c *Config) { c.Env = nil },
+		"tracing": func(c *Config) { c.Tracing = true },
+		"vpc":     func(c *Config) { c.SubnetIDs = c.SubnetIDs[:1] },
+		"layers":  func(c *Config) { c.Layers = []string{"arn:aws:lambda:us-east-1:123456789012:layer:noop:1"} },
+	} {
+		changed := c
+		change(&changed)
+		if action, err := d.Deploy(context.Background(), "baseline-go-fibonacci", pkg, changed); err != nil || action != Updated {
+			t.Errorf("%s changed: %s, %v", name, action, err)
+		}
+	}
+	other := filepath.Join(t.TempDir(), "function.zip")
+	if err := os.WriteFile(other, []byte("other zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if action, err := d.Deploy(context.Background(), "baseline-go-fibonacci", other, c); err != nil || action != Updated {
+		t.Errorf("new code: %s, %v", action, err)
+	}
+	fake.live["baseline-go-fibonacci"].LastUpdateStatus = types.LastUpdateStatusFailed
+	if action, _ := d.Deploy(context.Background(), "baseline-go-fibonacci", pkg, c); action != Updated {
+		t.Errorf("after a failed update: %s", action)
+	}
+	fake.live["baseline-go-fibonacci"].LastUpdateStatus = types.LastUpdateStatusSuccessful
+	d.Force = true
+	if action, _ := d.Deploy(context.Background(), "baseline-go-fibonacci", pkg, c); action != Updated {
+		t.Errorf("forced: %s", action)
+	}
+}
@@ -0,0 +1,162 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// ECRAPI is the subset of the ECR client used to host image targets.
+type ECRAPI interface {
+	CreateRepository(ctx context.Context, in *ecr.CreateRepositoryInput, opts ...func(*ecr.Options)) (*ecr.CreateRepositoryOutput, error)
+	DescribeRepositories(ctx context.Context, in *ecr.DescribeRepositoriesInput, opts ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error)
+	GetAuthorizationToken(ctx context.Context, in *ecr.GetAuthorizationTokenInput, opts ...func(*ecr.Options)) (*ecr.GetAuthorizationTokenOutput, error)
+	BatchDeleteImage(ctx context.Context, in *ecr.BatchDeleteImageInput, opts ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error)
+}
+
+// Registry pushes image targets to one ECR repository, tagged by function
+// name, so image functions can be created from them. Lambda grants itself
+// pull access to repositories in the function's own account. A Registry
+// is safe for concurrent use.
+type Registry struct {
+	Client ECRAPI
+	// Repository is the repository name; it is created on first push.
+	Repository string
+	// Docker runs the docker CLI with args and stdin. Nil runs docker,
+	// discarding its output except for error messages.
+	Docker func(ctx context.Context, stdin io.Reader, args ...string) error
+
+	mu       sync.Mutex // guards uri and loggedIn
+	uri      string     // repository URI, once ensured
+	loggedIn bool
+}
+
+// Push tags the local image as Repository:tag, pushes it and returns the
+// URI to deploy it from.
+func (r *Registry) Push(ctx context.Context, local, tag string) (string, error) {
+	r.mu.Lock()
+	err := r.ensure(ctx)
+	if err == nil {
+		err = r.login(ctx)
+	}
+	uri := r.uri
+	r.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+	remote := uri + ":" + tag
+	if err := r.docker(ctx, nil, "tag", local, remote); err != nil {
+		return "", err
+	}
+	if err := r.docker(ctx, nil, "push", remote); err != nil {
+		return "", fmt.Errorf("push %s: %w", remote, err)
+	}
+	return remote, nil
+}
+
+// Delete removes the image tagged tag. Like Deployer.Delete it reports
+// false rather than failing when there is nothing to delete.
+func (r *Registry) Delete(ctx context.Context, tag string) (deleted bool, err error) {
+	out, err := r.Client.BatchDeleteImage(ctx, &ecr.BatchDeleteImageInput{
+		RepositoryName: aws.String(r.Repository),
+		ImageIds:       []ecrtypes.ImageIdentifier{{ImageTag: aws.String(tag)}},
+	})
+	var missing *ecrtypes.RepositoryNotFoundException
+	switch {
+	case errors.As(err, &missing):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("delete image %s:%s: %w", r.Repository, tag, err)
+	}
+	for _, f := range out.Failures {
+		if f.FailureCode == ecrtypes.ImageFailureCodeImageNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("delete image %s:%s: %s", r.Repository, tag, aws.ToString(f.FailureReason))
+	}
+	return len(out.ImageIds) > 0, nil
+}
+
+// ensure finds or creates the repository and records its URI.
+func (r *Registry) ensure(ctx context.Context) error {
+	if r.uri != "" {
+		return nil
+	}
+	got, err := r.Client.DescribeRepositories(ctx, &ecr.DescribeRepositoriesInput{
+		RepositoryNames: []string{r.Repository},
+	})
+	var missing *ecrtypes.RepositoryNotFoundException
+	switch {
+	case err == nil && len(got.Repositories) > 0:
+		r.uri = aws.ToString(got.Repositories[0].RepositoryUri)
+		return nil
+	case err != nil && !errors.As(err, &missing):
+		return fmt.Errorf("describe repository %s: %w", r.Repository, err)
+	}
+	created, err := r.Client.CreateRepository(ctx, &ecr.CreateRepositoryInput{
+		RepositoryName: aws.String(r.Repository),
+		Tags:           []ecrtypes.Tag{{Key: aws.String(TagKey), Value: aws.String("true")}},
+	})
+	if err != nil {
+		return fmt.Errorf("create repository %s: %w", r.Repository, err)
+	}
+	r.uri = aws.ToString(created.Repository.RepositoryUri)
+	return nil
+}
+
+// login authenticates docker against the registry with a temporary ECR
+// token, once per Registry.
+func (r *Registry) login(ctx context.Context) error {
+	if r.loggedIn {
+		return nil
+	}
+	out, err := r.Client.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return fmt.Errorf("get ECR authorization token: %w", err)
+	}
+	if len(out.AuthorizationData) == 0 {
+		return errors.New("get ECR authorization token: no authorization data")
+	}
+	auth := out.AuthorizationData[0]
+	token, err := base64.StdEncoding.DecodeString(aws.ToString(auth.AuthorizationToken))
+	if err != nil {
+		return fmt.Errorf("decode ECR authorization token: %w", err)
+	}
+	user, password, ok := strings.Cut(string(token), ":")
+	if !ok {
+		return errors.New("decode ECR authorization token: no user:password pair")
+	}
+	endpoint := aws.ToString(auth.ProxyEndpoint)
+	if err := r.docker(ctx, strings.NewReader(password), "login", "--username", user, "--password-stdin", endpoint); err != nil {
+		return fmt.Errorf("docker login %s: %w", endpoint, err)
+	}
+	r.loggedIn = true
+	return nil
+}
+
+func (r *Registry) docker(ctx context.Context, stdin io.Reader, args ...string) error {
+	if r.Docker != nil {
+		return r.Docker(ctx, stdin, args...)
+	}
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("docker %s: %w: %s", args[0], err, msg)
+		}
+		return fmt.Errorf("docker %s: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package deploy
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+type fakeECR struct {
+	repos  map[string]string // name -> URI
+	tokens int
+	images map[string]bool // tags
+}
+
+func (f *fakeECR) DescribeRepositories(_ context.Context, in *ecr.DescribeRepositoriesInput, _ ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+	uri, ok := f.repos[in.RepositoryNames[0]]
+	if !ok {
+		return nil, &ecrtypes.RepositoryNotFoundException{Message: aws.String("not found")}
+	}
+	return &ecr.DescribeRepositoriesOutput{Repositories: []ecrtypes.Repository{{RepositoryUri: aws.String(uri)}}}, nil
+}
+
+func (f *fakeECR) CreateRepository(_ context.Context, in *ecr.CreateRepositoryInput, _ ...func(*ecr.Options)) (*ecr.CreateRepositoryOutput, error) {
+	uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + aws.ToString(in.RepositoryName)
+	f.repos[aws.ToString(in.RepositoryName)] = uri
+	return &ecr.CreateRepositoryOutput{Repository: &ecrtypes.Repository{RepositoryUri: aws.String(uri)}}, nil
+}
+
+func (f *fakeECR) GetAuthorizationToken(_ context.Context, _ *ecr.GetAuthorizationTokenInput, _ ...func(*ecr.Options)) (*ecr.GetAuthorizationTokenOutput, error) {
+	f.tokens++
+	return &ecr.GetAuthorizationTokenOutput{AuthorizationData: []ecrtypes.AuthorizationData{{
+		AuthorizationToken: aws.String(base64.StdEncoding.EncodeToString([]byte("AWS:secret"))),
+		ProxyEndpoint:      aws.String("https://123456789012.dkr.ecr.us-east-1.amazonaws.com"),
+	}}}, nil
+}
+
+func (f *fakeECR) BatchDeleteImage(_ context.Context, in *ecr.BatchDeleteImageInput, _ ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error) {
+	tag := aws.ToString(in.ImageIds[0].ImageTag)
+	if !f.images[tag] {
+		return &ecr.BatchDeleteImageOutput{Failures: []ecrtypes.ImageFailure{{FailureCode: ecrtypes.ImageFailureCodeImageNotFound}}}, nil
+	}
+	delete(f.images, tag)
+	return &ecr.BatchDeleteImageOutput{ImageIds: in.ImageIds}, nil
+}
+
+func TestRegistryPush(t *testing.T) {
+	fake := &fakeECR{repos: map[string]string{}, images: map[string]bool{}}
+	var commands []string
+	r := &Registry{Client: fake, Repository: "ruchy-bench", Docker: func(_ context.Context, stdin io.Reader, args ...string) error {
+		cmd := strings.Join(args, " ")
+		if stdin != nil {
+			data, _ := io.ReadAll(stdin)
+			cmd += " < " + string(data)
+		}
+		commands = append(commands, cmd)
+		if args[0] == "push" {
+			fake.images[args[1][strings.LastIndex(args[1], ":")+1:]] = true
+		}
+		return nil
+	}}
+	ctx := context.Background()
+	for _, fn := range []string{"baseline-go-image", "baseline-python-image"} {
+		uri, err := r.Push(ctx, "ruchy-bench:"+fn, fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "123456789012.dkr.ecr.us-east-1.amazonaws.com/ruchy-bench:" + fn; uri != want {
+			t.Errorf("Push = %s, want %s", uri, want)
+		}
+	}
+	want := []string{
+		"login --username AWS --password-stdin https://123456789012.dkr.ecr.us-east-1.amazonaws.com < secret",
+		"tag ruchy-bench:baseline-go-image 123456789012.dkr.ecr.us-east-1.amazonaws.com/ruchy-bench:baseline-go-image",
+		"push 123456789012.dkr.ecr.us-east-1.amazonaws.com/ruchy-bench:baseline-go-image",
+		"tag ruchy-bench:baseline-python-image 123456789012.dkr.ecr.us-east-1.amazonaws.com/ruchy-bench:baseline-python-image",
+		"push 123456789012.dkr.ecr.us-east-1.amazonaws.com/ruchy-bench:baseline-python-image",
+	}
+	if strings.Join(commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("docker commands:\n%s\nwant\n%s", strings.Join(commands, "\n"), strings.Join(want, "\n"))
+	}
+	if fake.tokens != 1 {
+		t.Errorf("fetched %d authorization tokens, want 1", fake.tokens)
+	}
+
+	if deleted, err := r.Delete(ctx, "baseline-go-image"); !deleted || err != nil {
+		t.Errorf("Delete pushed = %v, %v", deleted, err)
+	}
+	if deleted, err := r.Delete(ctx, "baseline-go-image"); deleted || err != nil {
+		t.Errorf("Delete missing = %v, %v", deleted, err)
+	}
+}
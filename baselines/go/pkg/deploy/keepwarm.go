@@ -0,0 +1,53 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// scheduleStatement is the ID of the resource policy statement letting
+// an EventBridge rule invoke a function.
+const scheduleStatement = "ruchy-bench-keepwarm"
+
+// PermissionAPI is the subset of the Lambda client used to let
+// EventBridge rules invoke functions.
+type PermissionAPI interface {
+	AddPermission(ctx context.Context, in *lambda.AddPermissionInput, opts ...func(*lambda.Options)) (*lambda.AddPermissionOutput, error)
+	RemovePermission(ctx context.Context, in *lambda.RemovePermissionInput, opts ...func(*lambda.Options)) (*lambda.RemovePermissionOutput, error)
+}
+
+// AllowSchedule lets the EventBridge rule with ARN ruleARN invoke fn. A
+// rule's ARN follows from its name, so a statement left from an earlier
+// run already allows it.
+func AllowSchedule(ctx context.Context, client PermissionAPI, fn, ruleARN string) error {
+	_, err := client.AddPermission(ctx, &lambda.AddPermissionInput{
+		FunctionName: aws.String(fn),
+		StatementId:  aws.String(scheduleStatement),
+		Action:       aws.String("lambda:InvokeFunction"),
+		Principal:    aws.String("events.amazonaws.com"),
+		SourceArn:    aws.String(ruleARN),
+	})
+	var exists *types.ResourceConflictException
+	if err != nil && !errors.As(err, &exists) {
+		return fmt.Errorf("allow %s to invoke %s: %w", ruleARN, fn, err)
+	}
+	return nil
+}
+
+// RevokeSchedule removes the statement AllowSchedule added to fn, if any.
+func RevokeSchedule(ctx context.Context, client PermissionAPI, fn string) error {
+	_, err := client.RemovePermission(ctx, &lambda.RemovePermissionInput{
+		FunctionName: aws.String(fn),
+		StatementId:  aws.String(scheduleStatement),
+	})
+	var missing *types.ResourceNotFoundException
+	if err != nil && !errors.As(err, &missing) {
+		return fmt.Errorf("revoke keep-warm permission of %s: %w", fn, err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package deploy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// fakePermissions holds one function's statements by ID.
+type fakePermissions struct {
+	statements map[string]string
+}
+
+func (f *fakePermissions) AddPermission(_ context.Context, in *lambda.AddPermissionInput, _ ...func(*lambda.Options)) (*lambda.AddPermissionOutput, error) {
+	id := aws.ToString(in.StatementId)
+	if _, ok := f.statements[id]; ok {
+		return nil, &types.ResourceConflictException{Message: aws.String("The statement id (" + id + ") provided already exists.")}
+	}
+	f.statements[id] = aws.ToString(in.Principal) + " " + aws.ToString(in.SourceArn)
+	return &lambda.AddPermissionOutput{}, nil
+}
+
+func (f *fakePermissions) RemovePermission(_ context.Context, in *lambda.RemovePermissionInput, _ ...func(*lambda.Options)) (*lambda.RemovePermissionOutput, error) {
+	id := aws.ToString(in.StatementId)
+	if _, ok := f.statements[id]; !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("No policy is associated with the given resource.")}
+	}
+	delete(f.statements, id)
+	return &lambda.RemovePermissionOutput{}, nil
+}
+
+func TestAllowSchedule(t *testing.T) {
+	f := &fakePermissions{statements: map[string]string{}}
+	ctx := context.Background()
+	// The second call finds the statement there already.
+	for range 2 {
+		if err := AllowSchedule(ctx, f, "fn", "arn:aws:events:us-east-1:1:rule/r"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := f.statements[scheduleStatement]; got != "events.amazonaws.com arn:aws:events:us-east-1:1:rule/r" {
+		t.Errorf("statement = %q", got)
+	}
+	for range 2 {
+		if err := RevokeSchedule(ctx, f, "fn"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(f.statements) != 0 {
+		t.Errorf("statements left: %v", f.statements)
+	}
+}
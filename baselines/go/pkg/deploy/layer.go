@@ -0,0 +1,85 @@
+package deploy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// LayerAPI is the subset of the Lambda client used to publish extension
+// layers.
+type LayerAPI interface {
+	lambda.ListLayerVersionsAPIClient
+	PublishLayerVersion(ctx context.Context, in *lambda.PublishLayerVersionInput, opts ...func(*lambda.Options)) (*lambda.PublishLayerVersionOutput, error)
+}
+
+// LayerPrefix begins every LayerName. Layers cannot be tagged, so
+// ruchy-bench gc finds them by it.
+const LayerPrefix = "ruchy-bench-"
+
+// LayerName is the name of the layer carrying extension for arch. Layer
+// versions are per architecture, so arm64 gets its own layer, suffixed
+// like discover.Target.FunctionName.
+func LayerName(extension string, arch types.Architecture) string {
+	name := LayerPrefix + extension
+	if arch == types.ArchitectureArm64 {
+		name += "-arm64"
+	}
+	return name
+}
+
+// LayerInRegion returns the ARN among arns of a layer version in region.
+// Layers are regional, so a layer published by AWS, such as the
+// Parameters and Secrets Lambda Extension, has an ARN per region; the
+// region is the ARN's fourth field.
+func LayerInRegion(arns []string, region string) (string, bool) {
+	for _, arn := range arns {
+		if parts := strings.Split(arn, ":"); len(parts) == 8 && parts[2] == "lambda" && parts[3] == region && parts[5] == "layer" {
+			return arn, true
+		}
+	}
+	return "", false
+}
+
+// PublishLayer publishes the zip at pkg as a version of the named layer
+// for arch and returns the version's ARN. The zip's SHA-256 is kept in
+// the version's description, so an unchanged zip reuses the latest
+// version instead: redeploying does not pile up versions, nor change the
+// configuration of functions already attached to it.
+func PublishLayer(ctx context.Context, client LayerAPI, name, pkg string, arch types.Architecture) (arn string, published bool, err error) {
+	zip, err := os.ReadFile(pkg)
+	if err != nil {
+		return "", false, err
+	}
+	sum := sha256.Sum256(zip)
+	desc := "sha256:" + hex.EncodeToString(sum[:])
+
+	// Versions are listed newest first.
+	latest, err := client.ListLayerVersions(ctx, &lambda.ListLayerVersionsInput{
+		LayerName: aws.String(name),
+		MaxItems:  aws.Int32(1),
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("list versions of layer %s: %w", name, err)
+	}
+	if len(latest.LayerVersions) > 0 && aws.ToString(latest.LayerVersions[0].Description) == desc {
+		return aws.ToString(latest.LayerVersions[0].LayerVersionArn), false, nil
+	}
+	out, err := client.PublishLayerVersion(ctx, &lambda.PublishLayerVersionInput{
+		LayerName:               aws.String(name),
+		Description:             aws.String(desc),
+		Content:                 &types.LayerVersionContentInput{ZipFile: zip},
+		CompatibleArchitectures: []types.Architecture{arch},
+	})
+	if err != nil {
+		return "", false, fmt.Errorf("publish layer %s: %w", name, err)
+	}
+	return aws.ToString(out.LayerVersionArn), true, nil
+}
@@ -0,0 +1,74 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// fakeLayers keeps one layer's versions, newest first.
+type fakeLayers struct {
+	versions []types.LayerVersionsListItem
+}
+
+func (f *fakeLayers) ListLayerVersions(_ context.Context, in *lambda.ListLayerVersionsInput, _ ...func(*lambda.Options)) (*lambda.ListLayerVersionsOutput, error) {
+	return &lambda.ListLayerVersionsOutput{LayerVersions: f.versions[:min(len(f.versions), int(aws.ToInt32(in.MaxItems)))]}, nil
+}
+
+func (f *fakeLayers) PublishLayerVersion(_ context.Context, in *lambda.PublishLayerVersionInput, _ ...func(*lambda.Options)) (*lambda.PublishLayerVersionOutput, error) {
+	arn := fmt.Sprintf("arn:aws:lambda:us-east-1:123456789012:layer:%s:%d", aws.ToString(in.LayerName), len(f.versions)+1)
+	v := types.LayerVersionsListItem{LayerVersionArn: aws.String(arn), Description: in.Description}
+	f.versions = append([]types.LayerVersionsListItem{v}, f.versions...)
+	return &lambda.PublishLayerVersionOutput{LayerVersionArn: aws.String(arn)}, nil
+}
+
+func TestPublishLayer(t *testing.T) {
+	f := &fakeLayers{}
+	name := LayerName("noop-telemetry", types.ArchitectureArm64)
+	if name != "ruchy-bench-noop-telemetry-arm64" {
+		t.Errorf("LayerName = %q", name)
+	}
+	pkg := filepath.Join(t.TempDir(), "layer.zip")
+	publish := func(content string) (string, bool) {
+		t.Helper()
+		if err := os.WriteFile(pkg, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		arn, published, err := PublishLayer(context.Background(), f, name, pkg, types.ArchitectureArm64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return arn, published
+	}
+
+	first, published := publish("v1")
+	if !published || first != "arn:aws:lambda:us-east-1:123456789012:layer:ruchy-bench-noop-telemetry-arm64:1" {
+		t.Errorf("first publish = %s, %v", first, published)
+	}
+	if again, published := publish("v1"); published || again != first {
+		t.Errorf("unchanged zip = %s, published %v; want version 1 reused", again, published)
+	}
+	if next, published := publish("v2"); !published || next == first {
+		t.Errorf("changed zip = %s, published %v; want a new version", next, published)
+	}
+}
+
+func TestLayerInRegion(t *testing.T) {
+	arns := []string{
+		"arn:aws:lambda:us-east-1:123456789012:layer:AWS-Parameters-and-Secrets-Lambda-Extension:12",
+		"arn:aws:lambda:eu-west-1:123456789012:layer:AWS-Parameters-and-Secrets-Lambda-Extension:12",
+	}
+	if arn, ok := LayerInRegion(arns, "eu-west-1"); !ok || arn != arns[1] {
+		t.Errorf("LayerInRegion(eu-west-1) = %q, %v", arn, ok)
+	}
+	// A function ARN is not a layer version, whatever its region.
+	if arn, ok := LayerInRegion(append(arns, "arn:aws:lambda:ap-south-1:123456789012:function:f"), "ap-south-1"); ok {
+		t.Errorf("LayerInRegion(ap-south-1) = %q", arn)
+	}
+}
@@ -0,0 +1,108 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// mappingPoll is the pause between event source mapping state checks; a
+// variable so tests need not wait.
+var mappingPoll = 2 * time.Second
+
+// mappingTimeout bounds the wait for mappings to settle. Enabling one
+// takes up to a minute or two.
+const mappingTimeout = 5 * time.Minute
+
+// EventSourceAPI is the subset of the Lambda client used to subscribe
+// functions to queues.
+type EventSourceAPI interface {
+	lambda.ListEventSourceMappingsAPIClient
+	GetEventSourceMapping(ctx context.Context, in *lambda.GetEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.GetEventSourceMappingOutput, error)
+	CreateEventSourceMapping(ctx context.Context, in *lambda.CreateEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.CreateEventSourceMappingOutput, error)
+	UpdateEventSourceMapping(ctx context.Context, in *lambda.UpdateEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.UpdateEventSourceMappingOutput, error)
+}
+
+// Subscribe makes fn the only consumer of the SQS queue with ARN queueARN,
+// receiving batches of up to batch messages and reporting failed ones
+// individually (ReportBatchItemFailures). The mappings of other functions
+// on the queue are disabled rather than deleted, so that benchmarking
+// each runtime in turn keeps reusing them. It returns once every mapping
+// has settled.
+func Subscribe(ctx context.Context, client EventSourceAPI, fn, queueARN string, batch int32) error {
+	want := map[string]string{} // mapping UUID to the state to wait for
+	var own bool
+	pages := lambda.NewListEventSourceMappingsPaginator(client, &lambda.ListEventSourceMappingsInput{EventSourceArn: aws.String(queueARN)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list event source mappings of %s: %w", queueARN, err)
+		}
+		for _, m := range page.EventSourceMappings {
+			id := aws.ToString(m.UUID)
+			if !strings.HasSuffix(aws.ToString(m.FunctionArn), ":function:"+fn) {
+				state := aws.ToString(m.State)
+				if state == "Disabled" || state == "Disabling" {
+					continue
+				}
+				if _, err := client.UpdateEventSourceMapping(ctx, &lambda.UpdateEventSourceMappingInput{
+					UUID:    m.UUID,
+					Enabled: aws.Bool(false),
+				}); err != nil {
+					return fmt.Errorf("disable event source mapping %s of %s: %w", id, aws.ToString(m.FunctionArn), err)
+				}
+				want[id] = "Disabled"
+				continue
+			}
+			own = true
+			if _, err := client.UpdateEventSourceMapping(ctx, &lambda.UpdateEventSourceMappingInput{
+				UUID:                           m.UUID,
+				Enabled:                        aws.Bool(true),
+				BatchSize:                      aws.Int32(batch),
+				MaximumBatchingWindowInSeconds: aws.Int32(0),
+				FunctionResponseTypes:          []types.FunctionResponseType{types.FunctionResponseTypeReportBatchItemFailures},
+			}); err != nil {
+				return fmt.Errorf("update event source mapping %s of %s: %w", id, fn, err)
+			}
+			want[id] = "Enabled"
+		}
+	}
+	if !own {
+		out, err := client.CreateEventSourceMapping(ctx, &lambda.CreateEventSourceMappingInput{
+			FunctionName:          aws.String(fn),
+			EventSourceArn:        aws.String(queueARN),
+			Enabled:               aws.Bool(true),
+			BatchSize:             aws.Int32(batch),
+			FunctionResponseTypes: []types.FunctionResponseType{types.FunctionResponseTypeReportBatchItemFailures},
+		})
+		if err != nil {
+			return fmt.Errorf("subscribe %s to %s: %w", fn, queueARN, err)
+		}
+		want[aws.ToString(out.UUID)] = "Enabled"
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mappingTimeout)
+	defer cancel()
+	for id, state := range want {
+		for {
+			m, err := client.GetEventSourceMapping(ctx, &lambda.GetEventSourceMappingInput{UUID: aws.String(id)})
+			if err != nil {
+				return fmt.Errorf("get event source mapping %s: %w", id, err)
+			}
+			if aws.ToString(m.State) == state {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("wait for event source mapping %s to become %s: %w", id, strings.ToLower(state), ctx.Err())
+			case <-time.After(mappingPoll):
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// fakeMappings settles every mapping on its second read.
+type fakeMappings struct {
+	mappings map[string]*types.EventSourceMappingConfiguration
+	// target is the state each mapping settles in.
+	target map[string]string
+	reads  map[string]int
+}
+
+func (f *fakeMappings) ListEventSourceMappings(_ context.Context, in *lambda.ListEventSourceMappingsInput, _ ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error) {
+	out := &lambda.ListEventSourceMappingsOutput{}
+	for _, m := range f.mappings {
+		if aws.ToString(m.EventSourceArn) == aws.ToString(in.EventSourceArn) {
+			out.EventSourceMappings = append(out.EventSourceMappings, *m)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeMappings) GetEventSourceMapping(_ context.Context, in *lambda.GetEventSourceMappingInput, _ ...func(*lambda.Options)) (*lambda.GetEventSourceMappingOutput, error) {
+	id := aws.ToString(in.UUID)
+	if f.reads[id]++; f.reads[id] > 1 {
+		f.mappings[id].State = aws.String(f.target[id])
+	}
+	return &lambda.GetEventSourceMappingOutput{UUID: in.UUID, State: f.mappings[id].State}, nil
+}
+
+func (f *fakeMappings) CreateEventSourceMapping(_ context.Context, in *lambda.CreateEventSourceMappingInput, _ ...func(*lambda.Options)) (*lambda.CreateEventSourceMappingOutput, error) {
+	id := fmt.Sprintf("uuid-%d", len(f.mappings))
+	f.mappings[id] = &types.EventSourceMappingConfiguration{
+		UUID:                  aws.String(id),
+		EventSourceArn:        in.EventSourceArn,
+		FunctionArn:           aws.String("arn:aws:lambda:us-east-1:1:function:" + aws.ToString(in.FunctionName)),
+		BatchSize:             in.BatchSize,
+		FunctionResponseTypes: in.FunctionResponseTypes,
+		State:                 aws.String("Creating"),
+	}
+	f.target[id] = "Enabled"
+	return &lambda.CreateEventSourceMappingOutput{UUID: aws.String(id)}, nil
+}
+
+func (f *fakeMappings) UpdateEventSourceMapping(_ context.Context, in *lambda.UpdateEventSourceMappingInput, _ ...func(*lambda.Options)) (*lambda.UpdateEventSourceMappingOutput, error) {
+	id := aws.ToString(in.UUID)
+	m := f.mappings[id]
+	if in.BatchSize != nil {
+		m.BatchSize = in.BatchSize
+	}
+	if in.FunctionResponseTypes != nil {
+		m.FunctionResponseTypes = in.FunctionResponseTypes
+	}
+	f.target[id] = "Disabled"
+	if aws.ToBool(in.Enabled) {
+		f.target[id] = "Enabled"
+	}
+	m.State = aws.String("Updating")
+	return &lambda.UpdateEventSourceMappingOutput{UUID: in.UUID}, nil
+}
+
+func TestSubscribe(t *testing.T) {
+	mappingPoll = 0
+	const queue = "arn:aws:sqs:us-east-1:1:ruchy-bench-sqs"
+	f := &fakeMappings{mappings: map[string]*types.EventSourceMappingConfiguration{}, target: map[string]string{}, reads: map[string]int{}}
+	ctx := context.Background()
+
+	if err := Subscribe(ctx, f, "ruchy-bench-python-sqs", queue, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := Subscribe(ctx, f, "ruchy-bench-go-sqs", queue, 5); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.mappings) != 2 || aws.ToString(f.mappings["uuid-0"].State) != "Disabled" || aws.ToString(f.mappings["uuid-1"].State) != "Enabled" {
+		t.Fatalf("after subscribing go: %+v", f.mappings)
+	}
+
+	// Subscribing again reuses the mapping with the new batch size.
+	if err := Subscribe(ctx, f, "ruchy-bench-go-sqs", queue, 10); err != nil {
+		t.Fatal(err)
+	}
+	m := f.mappings["uuid-1"]
+	if len(f.mappings) != 2 || aws.ToInt32(m.BatchSize) != 10 || aws.ToString(m.State) != "Enabled" ||
+		len(m.FunctionResponseTypes) != 1 || m.FunctionResponseTypes[0] != types.FunctionResponseTypeReportBatchItemFailures {
+		t.Errorf("after resubscribing: %+v", m)
+	}
+}
@@ -0,0 +1,307 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+)
+
+// DefaultRoleName is the execution role scripts/deploy-to-aws.sh creates.
+const DefaultRoleName = "ruchy-lambda-execution-role"
+
+const (
+	basicExecutionPolicy = "arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"
+	trustPolicy          = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"lambda.amazonaws.com"},"Action":"sts:AssumeRole"}]}`
+	// edgeTrustPolicy adds the principal that runs Lambda@Edge replicas.
+	edgeTrustPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":["lambda.amazonaws.com","edgelambda.amazonaws.com"]},"Action":"sts:AssumeRole"}]}`
+	// statesTrustPolicy is the trust policy of the role state machines
+	// run as.
+	statesTrustPolicy = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Principal":{"Service":"states.amazonaws.com"},"Action":"sts:AssumeRole"}]}`
+)
+
+// IAMAPI is the subset of the IAM client used to manage the execution role.
+type IAMAPI interface {
+	GetRole(ctx context.Context, in *iam.GetRoleInput, opts ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+	CreateRole(ctx context.Context, in *iam.CreateRoleInput, opts ...func(*iam.Options)) (*iam.CreateRoleOutput, error)
+	AttachRolePolicy(ctx context.Context, in *iam.AttachRolePolicyInput, opts ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error)
+}
+
+// EnsureRole returns the ARN of the named execution role, creating it with
+// CloudWatch Logs access if it does not exist. The REPORT lines every
+// benchmark reads come from those logs. A role it creates is tagged
+// TagKey, so ruchy-bench gc removes it; an existing one is left untagged.
+func EnsureRole(ctx context.Context, client IAMAPI, name string) (string, error) {
+	got, err := client.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(name)})
+	if err == nil {
+		return aws.ToString(got.Role.Arn), nil
+	}
+	var missing *iamtypes.NoSuchEntityException
+	if !errors.As(err, &missing) {
+		return "", fmt.Errorf("get role %s: %w", name, err)
+	}
+
+	created, err := client.CreateRole(ctx, &iam.CreateRoleInput{
+		RoleName:                 aws.String(name),
+		AssumeRolePolicyDocument: aws.String(trustPolicy),
+		Description:              aws.String("Execution role for Ruchy Lambda benchmarks"),
+		Tags:                     []iamtypes.Tag{{Key: aws.String(TagKey), Value: aws.String("true")}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("create role %s: %w", name, err)
+	}
+	if _, err := client.AttachRolePolicy(ctx, &iam.AttachRolePolicyInput{
+		RoleName:  aws.String(name),
+		PolicyArn: aws.String(basicExecutionPolicy),
+	}); err != nil {
+		return "", fmt.Errorf("attach policy to role %s: %w", name, err)
+	}
+	return aws.ToString(created.Role.Arn), nil
+}
+
+// StatesRoleAPI is the subset of the IAM client used to manage the role
+// state machines run as.
+type StatesRoleAPI interface {
+	IAMAPI
+	RolePolicyAPI
+}
+
+// EnsureStatesRole returns the ARN of the named role for Step Functions
+// state machines, creating it, tagged TagKey, if it does not exist. It
+// lets the role invoke every function whose name starts with one of
+// prefixes, in any region, replacing that inline policy on every call.
+func EnsureStatesRole(ctx context.Context, client StatesRoleAPI, name string, prefixes []string) (string, error) {
+	var arn string
+	got, err := client.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(name)})
+	var missing *iamtypes.NoSuchEntityException
+	switch {
+	case err == nil:
+		arn = aws.ToString(got.Role.Arn)
+	case errors.As(err, &missing):
+		created, err := client.CreateRole(ctx, &iam.CreateRoleInput{
+			RoleName:                 aws.String(name),
+			AssumeRolePolicyDocument: aws.String(statesTrustPolicy),
+			Description:              aws.String("Step Functions role for Ruchy Lambda benchmarks"),
+			Tags:                     []iamtypes.Tag{{Key: aws.String(TagKey), Value: aws.String("true")}},
+		})
+		if err != nil {
+			return "", fmt.Errorf("create role %s: %w", name, err)
+		}
+		arn = aws.ToString(created.Role.Arn)
+	default:
+		return "", fmt.Errorf("get role %s: %w", name, err)
+	}
+	functions := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		functions[i] = "arn:aws:lambda:*:*:function:" + p + "*"
+	}
+	resources, err := json.Marshal(functions)
+	if err != nil {
+		return "", err
+	}
+	doc := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"lambda:InvokeFunction","Resource":%s}]}`, resources)
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(name),
+		PolicyName:     aws.String(invokePolicy),
+		PolicyDocument: aws.String(doc),
+	}); err != nil {
+		return "", fmt.Errorf("grant role %s invoke access: %w", name, err)
+	}
+	return arn, nil
+}
+
+// TrustPolicyAPI is the subset of the IAM client used to change who may
+// assume the execution role.
+type TrustPolicyAPI interface {
+	UpdateAssumeRolePolicy(ctx context.Context, in *iam.UpdateAssumeRolePolicyInput, opts ...func(*iam.Options)) (*iam.UpdateAssumeRolePolicyOutput, error)
+}
+
+// TrustEdge lets Lambda@Edge assume the named role as well as Lambda,
+// which CloudFront requires of an edge function's role before it
+// replicates the function. Lambda functions are unaffected.
+func TrustEdge(ctx context.Context, client TrustPolicyAPI, role string) error {
+	if _, err := client.UpdateAssumeRolePolicy(ctx, &iam.UpdateAssumeRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyDocument: aws.String(edgeTrustPolicy),
+	}); err != nil {
+		return fmt.Errorf("trust Lambda@Edge with role %s: %w", role, err)
+	}
+	return nil
+}
+
+// Inline policy names written by GrantBucketRead, GrantTableAccess,
+// GrantQueueConsume, GrantAsync, GrantTracing, GrantVPCAccess,
+// GrantInvoke and GrantProfileUpload; EnsureStatesRole writes
+// invokePolicy to its own role.
+const (
+	fixtureReadPolicy   = "ruchy-bench-fixture-read"
+	tableAccessPolicy   = "ruchy-bench-table-access"
+	queueConsumePolicy  = "ruchy-bench-queue-consume"
+	asyncPolicy         = "ruchy-bench-async"
+	configReadPolicy    = "ruchy-bench-config-read"
+	tracingPolicy       = "ruchy-bench-tracing"
+	vpcAccessPolicy     = "ruchy-bench-vpc-access"
+	invokePolicy        = "ruchy-bench-invoke"
+	profileUploadPolicy = "ruchy-bench-pprof-upload"
+)
+
+const tracingPolicyDoc = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["xray:PutTraceSegments","xray:PutTelemetryRecords"],"Resource":"*"}]}`
+
+// vpcAccessPolicyDoc holds the actions of the AWSLambdaVPCAccessExecutionRole
+// managed policy Lambda needs to attach functions to their subnets.
+const vpcAccessPolicyDoc = `{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["ec2:CreateNetworkInterface","ec2:DescribeNetworkInterfaces","ec2:DescribeSubnets","ec2:DeleteNetworkInterface","ec2:AssignPrivateIpAddresses","ec2:UnassignPrivateIpAddresses"],"Resource":"*"}]}`
+
+// RolePolicyAPI is the subset of the IAM client used to grant the
+// execution role access to benchmark fixtures.
+type RolePolicyAPI interface {
+	PutRolePolicy(ctx context.Context, in *iam.PutRolePolicyInput, opts ...func(*iam.Options)) (*iam.PutRolePolicyOutput, error)
+}
+
+// GrantBucketRead lets the named role read every object in bucket, which
+// the I/O-bound baselines download their input from. The inline policy is
+// replaced on every call, so the grant follows the current bucket.
+func GrantBucketRead(ctx context.Context, client RolePolicyAPI, role, bucket string) error {
+	doc := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:GetObject","Resource":"arn:aws:s3:::%s/*"}]}`, bucket)
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyName:     aws.String(fixtureReadPolicy),
+		PolicyDocument: aws.String(doc),
+	}); err != nil {
+		return fmt.Errorf("grant role %s read access to %s: %w", role, bucket, err)
+	}
+	return nil
+}
+
+// GrantTableAccess lets the named role read and write items in the table
+// with ARN tableARN, for the dynamodb workload. Like GrantBucketRead it
+// replaces its inline policy on every call.
+func GrantTableAccess(ctx context.Context, client RolePolicyAPI, role, tableARN string) error {
+	doc := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["dynamodb:GetItem","dynamodb:BatchWriteItem"],"Resource":%q}]}`, tableARN)
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyName:     aws.String(tableAccessPolicy),
+		PolicyDocument: aws.String(doc),
+	}); err != nil {
+		return fmt.Errorf("grant role %s access to %s: %w", role, tableARN, err)
+	}
+	return nil
+}
+
+// GrantQueueConsume lets the named role receive and delete messages of
+// the SQS queue with ARN queueARN, which an event source mapping polls on
+// the function's behalf for the sqs workload.
+func GrantQueueConsume(ctx context.Context, client RolePolicyAPI, role, queueARN string) error {
+	doc := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":["sqs:ReceiveMessage","sqs:DeleteMessage","sqs:GetQueueAttributes"],"Resource":%q}]}`, queueARN)
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyName:     aws.String(queueConsumePolicy),
+		PolicyDocument: aws.String(doc),
+	}); err != nil {
+		return fmt.Errorf("grant role %s access to %s: %w", role, queueARN, err)
+	}
+	return nil
+}
+
+// GrantAsync lets the named role write objects under prefix in bucket,
+// where the async workload leaves its completion markers, and send
+// messages to the SQS queue with ARN dlqARN, which Lambda does with the
+// function's role when it dead-letters an event. Like GrantBucketRead it
+// replaces its inline policy on every call.
+func GrantAsync(ctx context.Context, client RolePolicyAPI, role, bucket, prefix, dlqARN string) error {
+	doc := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[`+
+		`{"Effect":"Allow","Action":"s3:PutObject","Resource":"arn:aws:s3:::%s/%s*"},`+
+		`{"Effect":"Allow","Action":"sqs:SendMessage","Resource":%q}]}`, bucket, prefix, dlqARN)
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyName:     aws.String(asyncPolicy),
+		PolicyDocument: aws.String(doc),
+	}); err != nil {
+		return fmt.Errorf("grant role %s access to s3://%s/%s and %s: %w", role, bucket, prefix, dlqARN, err)
+	}
+	return nil
+}
+
+// GrantConfigRead lets the named role read the secrets and parameters
+// whose names start with prefix, in any region, for the configload
+// workloads. Secret ARNs end in a random suffix, hence the wildcard after
+// the name too. Like GrantBucketRead it replaces its inline policy on
+// every call.
+func GrantConfigRead(ctx context.Context, client RolePolicyAPI, role, prefix string) error {
+	doc := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[`+
+		`{"Effect":"Allow","Action":"secretsmanager:GetSecretValue","Resource":"arn:aws:secretsmanager:*:*:secret:%s*"},`+
+		`{"Effect":"Allow","Action":"ssm:GetParameter","Resource":"arn:aws:ssm:*:*:parameter/%s*"}]}`, prefix, prefix)
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyName:     aws.String(configReadPolicy),
+		PolicyDocument: aws.String(doc),
+	}); err != nil {
+		return fmt.Errorf("grant role %s read access to %s: %w", role, prefix, err)
+	}
+	return nil
+}
+
+// GrantTracing lets the named role send traces to X-Ray, which functions
+// deployed with Config.Tracing need.
+func GrantTracing(ctx context.Context, client RolePolicyAPI, role string) error {
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyName:     aws.String(tracingPolicy),
+		PolicyDocument: aws.String(tracingPolicyDoc),
+	}); err != nil {
+		return fmt.Errorf("grant role %s tracing access: %w", role, err)
+	}
+	return nil
+}
+
+// GrantVPCAccess lets the named role manage the network interfaces
+// through which Lambda reaches the subnets of functions deployed with
+// Config.SubnetIDs.
+func GrantVPCAccess(ctx context.Context, client RolePolicyAPI, role string) error {
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyName:     aws.String(vpcAccessPolicy),
+		PolicyDocument: aws.String(vpcAccessPolicyDoc),
+	}); err != nil {
+		return fmt.Errorf("grant role %s VPC access: %w", role, err)
+	}
+	return nil
+}
+
+// GrantInvoke lets the named role invoke the functions with ARNs
+// functionARNs, as the edge probes invoke the regional baselines they
+// compare. Like GrantBucketRead it replaces its inline policy on every
+// call.
+func GrantInvoke(ctx context.Context, client RolePolicyAPI, role string, functionARNs []string) error {
+	arns, err := json.Marshal(functionARNs)
+	if err != nil {
+		return err
+	}
+	doc := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"lambda:InvokeFunction","Resource":%s}]}`, arns)
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyName:     aws.String(invokePolicy),
+		PolicyDocument: aws.String(doc),
+	}); err != nil {
+		return fmt.Errorf("grant role %s invoke access: %w", role, err)
+	}
+	return nil
+}
+
+// GrantProfileUpload lets the named role write objects under prefix in
+// bucket, where Go baselines built for profiling upload their profiles.
+// Like GrantBucketRead it replaces its inline policy on every call.
+func GrantProfileUpload(ctx context.Context, client RolePolicyAPI, role, bucket, prefix string) error {
+	doc := fmt.Sprintf(`{"Version":"2012-10-17","Statement":[{"Effect":"Allow","Action":"s3:PutObject","Resource":"arn:aws:s3:::%s/%s*"}]}`, bucket, prefix)
+	if _, err := client.PutRolePolicy(ctx, &iam.PutRolePolicyInput{
+		RoleName:       aws.String(role),
+		PolicyName:     aws.String(profileUploadPolicy),
+		PolicyDocument: aws.String(doc),
+	}); err != nil {
+		return fmt.Errorf("grant role %s write access to s3://%s/%s: %w", role, bucket, prefix, err)
+	}
+	return nil
+}
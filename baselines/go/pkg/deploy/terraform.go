@@ -0,0 +1,265 @@
+package deploy
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/discover"
+)
+
+// Fleet is a set of functions to declare as Terraform instead of deploying
+// them through the API, for accounts where resources may only be created
+// through infrastructure code.
+type Fleet struct {
+	Functions []FleetFunction
+	Layers    []FleetLayer
+	// RoleARN is an existing execution role to attach. Empty declares
+	// DefaultRoleName, as EnsureRole would create it, with tracing access
+	// if any function is traced.
+	RoleARN string
+	// ImageRepository names the ECR repository declared for image
+	// functions.
+	ImageRepository string
+}
+
+// FleetFunction is one function of a Fleet.
+type FleetFunction struct {
+	Name   string
+	Config Config
+	// Package is the path of the function's zip, relative to the
+	// directory the Terraform is written to; empty for image functions.
+	Package string
+	// ImageTag is the tag of an image function's image in the fleet's
+	// ImageRepository.
+	ImageTag string
+	// Layers names the fleet layers attached to the function. Config's
+	// Layers, which are ARNs of published versions, are ignored.
+	Layers []string
+}
+
+// FleetLayer is an extension layer of a Fleet.
+type FleetLayer struct {
+	// Name is the layer name, as returned by LayerName.
+	Name    string
+	Package string
+	Arch    types.Architecture
+}
+
+// Terraform renders f as a Terraform configuration for the hashicorp/aws
+// provider, declaring the same resources Deploy creates. The provider
+// itself, and so the region, is left to the caller's configuration.
+func Terraform(f Fleet) []byte {
+	var w hclWriter
+	w.open("terraform")
+	w.open("required_providers")
+	w.attrs(attr{"aws", `{ source = "hashicorp/aws", version = ">= 5.0" }`})
+	w.close()
+	w.close()
+
+	role := hclString(f.RoleARN)
+	// deps are the grants functions wait for, so that none is invoked
+	// before its role can write logs and traces.
+	var deps []string
+	if f.RoleARN == "" {
+		role = "aws_iam_role.execution.arn"
+		deps = append(deps, "aws_iam_role_policy_attachment.execution_logs")
+		w.open(`resource "aws_iam_role" "execution"`)
+		w.attrs(
+			attr{"name", hclString(DefaultRoleName)},
+			attr{"description", hclString("Execution role for Ruchy Lambda benchmarks")},
+			attr{"assume_role_policy", hclString(trustPolicy)},
+		)
+		w.close()
+		w.open(`resource "aws_iam_role_policy_attachment" "execution_logs"`)
+		w.attrs(
+			attr{"role", "aws_iam_role.execution.name"},
+			attr{"policy_arn", hclString(basicExecutionPolicy)},
+		)
+		w.close()
+		if slices.ContainsFunc(f.Functions, func(fn FleetFunction) bool { return fn.Config.Tracing }) {
+			w.open(`resource "aws_iam_role_policy" "execution_tracing"`)
+			w.attrs(
+				attr{"name", hclString(tracingPolicy)},
+				attr{"role", "aws_iam_role.execution.id"},
+				attr{"policy", hclString(tracingPolicyDoc)},
+			)
+			w.close()
+			deps = append(deps, "aws_iam_role_policy.execution_tracing")
+		}
+	}
+
+	if slices.ContainsFunc(f.Functions, func(fn FleetFunction) bool { return fn.Config.PackageType == types.PackageTypeImage }) {
+		w.open(`resource "aws_ecr_repository" "images"`)
+		w.attrs(
+			attr{"name", hclString(f.ImageRepository)},
+			attr{"force_delete", "true"},
+		)
+		w.close()
+	}
+
+	for _, l := range f.Layers {
+		w.open(fmt.Sprintf(`resource "aws_lambda_layer_version" %q`, hclName(l.Name)))
+		w.attrs(
+			attr{"layer_name", hclString(l.Name)},
+			attr{"filename", modulePath(l.Package)},
+			attr{"source_code_hash", fmt.Sprintf("filebase64sha256(%s)", modulePath(l.Package))},
+			attr{"compatible_architectures", hclList(hclString(string(l.Arch)))},
+		)
+		w.close()
+	}
+
+	for _, fn := range f.Functions {
+		name, c := hclName(fn.Name), fn.Config
+		w.open(fmt.Sprintf(`resource "aws_lambda_function" %q`, name))
+		w.attrs(
+			attr{"function_name", hclString(fn.Name)},
+			attr{"role", role},
+			attr{"architectures", hclList(hclString(string(c.Arch)))},
+			attr{"memory_size", strconv.Itoa(int(c.MemoryMB))},
+			attr{"timeout", strconv.Itoa(int(c.TimeoutSec))},
+		)
+		if c.PackageType == types.PackageTypeImage {
+			w.attrs(
+				attr{"package_type", hclString(string(types.PackageTypeImage))},
+				attr{"image_uri", fmt.Sprintf(`"${aws_ecr_repository.images.repository_url}:%s"`, fn.ImageTag)},
+			)
+		} else {
+			w.attrs(
+				attr{"runtime", hclString(string(c.Runtime))},
+				attr{"handler", hclString(c.Handler)},
+				attr{"filename", modulePath(fn.Package)},
+				attr{"source_code_hash", fmt.Sprintf("filebase64sha256(%s)", modulePath(fn.Package))},
+			)
+			if len(fn.Layers) > 0 {
+				refs := make([]string, len(fn.Layers))
+				for i, l := range fn.Layers {
+					refs[i] = "aws_lambda_layer_version." + hclName(l) + ".arn"
+				}
+				w.attrs(attr{"layers", hclList(refs...)})
+			}
+		}
+		if c.SnapStart {
+			// SnapStart only applies to published versions; the alias
+			// below follows the latest.
+			w.attrs(attr{"publish", "true"})
+		}
+		w.attrs(attr{"tags", fmt.Sprintf("{ %s = %s }", hclString(TagKey), hclString("true"))})
+		if len(c.Env) > 0 {
+			w.open("environment")
+			var vars []string
+			for _, k := range slices.Sorted(maps.Keys(c.Env)) {
+				vars = append(vars, fmt.Sprintf("%s = %s", hclString(k), hclString(c.Env[k])))
+			}
+			w.attrs(attr{"variables", "{ " + strings.Join(vars, ", ") + " }"})
+			w.close()
+		}
+		w.open("tracing_config")
+		w.attrs(attr{"mode", hclString(string(tracingMode(c)))})
+		w.close()
+		if c.SnapStart {
+			w.open("snap_start")
+			w.attrs(attr{"apply_on", hclString(string(types.SnapStartApplyOnPublishedVersions))})
+			w.close()
+		}
+		if len(deps) > 0 {
+			w.attrs(attr{"depends_on", hclList(deps...)})
+		}
+		w.close()
+
+		if c.SnapStart {
+			w.open(fmt.Sprintf(`resource "aws_lambda_alias" %q`, name+"_"+discover.SnapStartAlias))
+			w.attrs(
+				attr{"name", hclString(discover.SnapStartAlias)},
+				attr{"function_name", "aws_lambda_function." + name + ".function_name"},
+				attr{"function_version", "aws_lambda_function." + name + ".version"},
+			)
+			w.close()
+		}
+		if c.URLInvokeMode != "" {
+			w.open(fmt.Sprintf(`resource "aws_lambda_function_url" %q`, name))
+			w.attrs(
+				attr{"function_name", "aws_lambda_function." + name + ".function_name"},
+				attr{"authorization_type", hclString(string(types.FunctionUrlAuthTypeAwsIam))},
+				attr{"invoke_mode", hclString(string(c.URLInvokeMode))},
+			)
+			w.close()
+		}
+	}
+	return w.buf.Bytes()
+}
+
+// hclWriter writes blocks in terraform fmt's layout: two-space indents,
+// with the equals signs of consecutive attributes aligned.
+type hclWriter struct {
+	buf   bytes.Buffer
+	depth int
+	// pending holds the attributes written since the last block opened
+	// or closed, which are aligned together.
+	pending []attr
+}
+
+type attr struct{ key, value string }
+
+func (w *hclWriter) open(header string) {
+	w.flush()
+	if w.depth == 0 && w.buf.Len() > 0 {
+		w.buf.WriteString("\n")
+	}
+	w.line(header + " {")
+	w.depth++
+}
+
+func (w *hclWriter) close() {
+	w.flush()
+	w.depth--
+	w.line("}")
+}
+
+func (w *hclWriter) attrs(attrs ...attr) {
+	w.pending = append(w.pending, attrs...)
+}
+
+func (w *hclWriter) flush() {
+	var width int
+	for _, a := range w.pending {
+		width = max(width, len(a.key))
+	}
+	for _, a := range w.pending {
+		w.line(fmt.Sprintf("%-*s = %s", width, a.key, a.value))
+	}
+	w.pending = nil
+}
+
+func (w *hclWriter) line(s string) {
+	w.buf.WriteString(strings.Repeat("  ", w.depth))
+	w.buf.WriteString(s)
+	w.buf.WriteString("\n")
+}
+
+// hclString quotes s as an HCL string literal, escaping the template
+// sequences HCL would otherwise interpolate.
+func hclString(s string) string {
+	q := strconv.Quote(s)
+	q = strings.ReplaceAll(q, "${", "$${")
+	return strings.ReplaceAll(q, "%{", "%%{")
+}
+
+func hclList(values ...string) string {
+	return "[" + strings.Join(values, ", ") + "]"
+}
+
+// hclName turns a function or layer name into a resource name.
+func hclName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// modulePath refers to a path relative to the Terraform module.
+func modulePath(rel string) string {
+	return `"${path.module}/` + strings.TrimPrefix(hclString(rel), `"`)
+}
@@ -0,0 +1,65 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/discover"
+)
+
+func TestTerraform(t *testing.T) {
+	zip := ConfigFor(discover.Target{Runtime: "go", Workload: "fibonacci"})
+	zip.Tracing = true
+	snap := ConfigFor(discover.Target{Runtime: "python", Workload: "minimal", SnapStart: true})
+	snap.Env = map[string]string{"GREETING": "${not interpolated}"}
+	image := ConfigFor(discover.Target{Runtime: "rust", Workload: StreamWorkload, Package: discover.PackageImage, Arch: discover.ArchARM64})
+	layer := LayerName("telemetry", types.ArchitectureX8664)
+
+	hcl := string(Terraform(Fleet{
+		Functions: []FleetFunction{
+			{Name: "baseline-go-fibonacci", Config: zip, Package: "go/fibonacci/bootstrap.zip", Layers: []string{layer}},
+			{Name: "baseline-python-snapstart", Config: snap, Package: "python/minimal/function.zip"},
+			{Name: "baseline-rust-stream-arm64-image", Config: image, ImageTag: "baseline-rust-stream-arm64-image"},
+		},
+		Layers:          []FleetLayer{{Name: layer, Package: "extension/telemetry/x86_64/layer.zip", Arch: types.ArchitectureX8664}},
+		ImageRepository: "ruchy-bench",
+	}))
+	for _, want := range []string{
+		`resource "aws_iam_role" "execution" {`,
+		`resource "aws_iam_role_policy" "execution_tracing" {`,
+		`resource "aws_ecr_repository" "images" {`,
+		`resource "aws_lambda_layer_version" "ruchy_bench_telemetry" {`,
+		`  filename         = "${path.module}/go/fibonacci/bootstrap.zip"`,
+		`  source_code_hash = filebase64sha256("${path.module}/go/fibonacci/bootstrap.zip")`,
+		`  layers           = [aws_lambda_layer_version.ruchy_bench_telemetry.arn]`,
+		`    mode = "Active"`,
+		`    variables = { "GREETING" = "$${not interpolated}" }`,
+		`resource "aws_lambda_alias" "baseline_python_snapstart_snapstart" {`,
+		`  image_uri     = "${aws_ecr_repository.images.repository_url}:baseline-rust-stream-arm64-image"`,
+		`  architectures = ["arm64"]`,
+		`resource "aws_lambda_function_url" "baseline_rust_stream_arm64_image" {`,
+		`  depends_on = [aws_iam_role_policy_attachment.execution_logs, aws_iam_role_policy.execution_tracing]`,
+	} {
+		if !strings.Contains(hcl, want) {
+			t.Errorf("missing %s in:\n%s", want, hcl)
+		}
+	}
+	if strings.Count(hcl, "publish ") != 1 || strings.Count(hcl, "snap_start {") != 1 {
+		t.Error("want exactly the SnapStart function published")
+	}
+	if strings.Count(hcl, "{") != strings.Count(hcl, "}") {
+		t.Error("unbalanced braces")
+	}
+
+	// An existing role is referenced, not declared, and granted nothing.
+	hcl = string(Terraform(Fleet{
+		Functions: []FleetFunction{{Name: "baseline-go", Config: zip, Package: "go/minimal/bootstrap.zip"}},
+		RoleARN:   "arn:aws:iam::123456789012:role/bench",
+	}))
+	if strings.Contains(hcl, "aws_iam_role") || strings.Contains(hcl, "depends_on") || strings.Contains(hcl, "aws_ecr_repository") ||
+		!strings.Contains(hcl, `role             = "arn:aws:iam::123456789012:role/bench"`) {
+		t.Errorf("with an existing role:\n%s", hcl)
+	}
+}
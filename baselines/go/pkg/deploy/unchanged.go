@@ -0,0 +1,73 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"maps"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// unchanged reports whether the function configured as live already runs
+// code with configuration c, so that updating it would change nothing.
+// Settings another command changed since, such as the memory size a
+// sweep left, count as changes. Only zip packages compare: a tag pushed
+// again may hold other bytes than the digest the function resolved it
+// to. A function whose last update failed is always updated.
+func unchanged(live *types.FunctionConfiguration, code code, c Config) bool {
+	if code.imageURI != "" || live.LastUpdateStatus == types.LastUpdateStatusFailed {
+		return false
+	}
+	sum := sha256.Sum256(code.zip)
+	if aws.ToString(live.CodeSha256) != base64.StdEncoding.EncodeToString(sum[:]) {
+		return false
+	}
+	arch := types.ArchitectureX8664
+	if len(live.Architectures) > 0 {
+		arch = live.Architectures[0]
+	}
+	if arch != c.Arch || live.Runtime != c.Runtime || aws.ToString(live.Handler) != c.Handler ||
+		aws.ToInt32(live.MemorySize) != c.MemoryMB || aws.ToInt32(live.Timeout) != c.TimeoutSec {
+		return false
+	}
+	if c.EphemeralMB > 0 && (live.EphemeralStorage == nil || aws.ToInt32(live.EphemeralStorage.Size) != c.EphemeralMB) {
+		return false
+	}
+	tracing := types.TracingModePassThrough
+	if live.TracingConfig != nil && live.TracingConfig.Mode != "" {
+		tracing = live.TracingConfig.Mode
+	}
+	if tracing != tracingMode(c) {
+		return false
+	}
+	// update turns SnapStart on but never off.
+	if c.SnapStart && (live.SnapStart == nil || live.SnapStart.ApplyOn != types.SnapStartApplyOnPublishedVersions) {
+		return false
+	}
+	var env map[string]string
+	if live.Environment != nil {
+		env = live.Environment.Variables
+	}
+	if !maps.Equal(env, c.Env) {
+		return false
+	}
+	var layers []string
+	for _, l := range live.Layers {
+		layers = append(layers, aws.ToString(l.Arn))
+	}
+	if !slices.Equal(layers, c.Layers) {
+		return false
+	}
+	var subnets, groups []string
+	if live.VpcConfig != nil {
+		subnets, groups = live.VpcConfig.SubnetIds, live.VpcConfig.SecurityGroupIds
+	}
+	return sameSet(subnets, c.SubnetIDs) && sameSet(groups, c.SecurityGroupIDs)
+}
+
+// sameSet reports whether a and b hold the same strings in any order.
+func sameSet(a, b []string) bool {
+	return slices.Equal(slices.Sorted(slices.Values(a)), slices.Sorted(slices.Values(b)))
+}
@@ -0,0 +1,605 @@
+// Package discover locates benchmark targets in the repository: Lambda
+// baselines under baselines/, the Ruchy Lambda handlers under
+// crates/bootstrap, and local workloads under benchmarks/local-*.
+package discover
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Kind says where a target runs.
+type Kind string
+
+const (
+	// KindLocal targets are standalone programs run as subprocesses.
+	KindLocal Kind = "local"
+	// KindLambda targets are handlers deployed as Lambda functions.
+	KindLambda Kind = "lambda"
+	// KindRIE is never discovered. It marks results of Lambda targets run
+	// locally from their container image under the Runtime Interface
+	// Emulator (ruchy-bench run -rie), which must not be mistaken for
+	// measurements on Lambda.
+	KindRIE Kind = "rie"
+)
+
+// Lambda instruction set architectures, spelled as the Lambda API does.
+const (
+	ArchX86   = "x86_64"
+	ArchARM64 = "arm64"
+)
+
+// Lambda deployment package types, spelled as the -package flag does.
+// Image targets are built into a container image on an AWS base image and
+// pushed to ECR; zip is the default.
+const (
+	PackageZip   = "zip"
+	PackageImage = "image"
+)
+
+// JSON serializers of Go baselines, spelled as the -serializer flag does.
+// SerializerStd is encoding/json, which baselines use without a build tag;
+// the others are the internal/codec build tags that select them.
+const (
+	SerializerStd      = "std"
+	SerializerJSONIter = "jsoniter"
+	SerializerEasyJSON = "easyjson"
+)
+
+// serializerWorkloads are the JSON-heavy Go baselines that serialize
+// through internal/codec, and so have -serializer variants.
+var serializerWorkloads = map[string]bool{"json": true, "echo": true, "apigw": true}
+
+// TinyGo is the runtime of Go sources compiled with TinyGo rather than
+// the Go toolchain: the same programs and Lambda handlers as runtime go,
+// with TinyGo's smaller binaries and its own garbage collector.
+const TinyGo = "tinygo"
+
+// tinyGoWorkloads are the CPU workloads whose Go sources TinyGo builds:
+// the local programs and Lambda handlers that import nothing it lacks,
+// such as golang.org/x/sys/cpu's assembly.
+var tinyGoWorkloads = map[string]bool{
+	"fibonacci": true, "fibonacci-iterative": true, "fibonacci-memo": true,
+	"matmul": true, "sieve": true, "tree": true,
+}
+
+// Wasm is the runtime of WASM modules run under Wasmtime: the local Go
+// programs of wasmWorkloads compiled for wasip1, run by wasmtime locally
+// and by the baselines/go/wasmrt custom runtime on Lambda.
+const Wasm = "wasm"
+
+// wasmWorkloads are the CPU workloads whose local Go programs are WASI
+// commands as they stand: single-threaded, printing their result and
+// touching nothing WASI does not give them.
+var wasmWorkloads = map[string]bool{
+	"fibonacci": true, "fibonacci-iterative": true, "fibonacci-memo": true,
+	"matmul": true, "sieve": true, "tree": true,
+}
+
+// MinimalWorkload is the workload name of the lambda-perf "hello world"
+// handlers (main.go, index.py, ...).
+const MinimalWorkload = "minimal"
+
+// SnapStartAlias is the alias SnapStart targets are invoked through. It
+// points at the latest published version, since SnapStart only applies to
+// published versions.
+const SnapStartAlias = "snapstart"
+
+// GeneratedEventsDir, relative to the repository root, holds events the
+// harness writes for fixtures it seeded in the account (such as the S3
+// workload's bucket and key). Discover prefers them over the committed
+// baselines/events/<workload>.json.
+const GeneratedEventsDir = ".bench/events"
+
+// snapStartRuntimes are the baseline runtimes Lambda supports SnapStart
+// for. Custom runtimes (provided.al2023) are not eligible.
+var snapStartRuntimes = map[string]bool{"python": true}
+
+// Target is one runtime/workload pair the harness can build and invoke.
+type Target struct {
+	Runtime  string `json:"runtime"`
+	Workload string `json:"workload"`
+	Kind     Kind   `json:"kind"`
+	// Arch is the Lambda architecture; empty for local targets, which run
+	// on the host.
+	Arch string `json:"arch,omitempty"`
+	// SnapStart selects the SnapStart-enabled variant of a Lambda target.
+	SnapStart bool `json:"snapstart,omitempty"`
+	// Package is the Lambda deployment package type. Empty means
+	// PackageZip.
+	Package string `json:"package,omitempty"`
+	// Extension selects the variant of a Lambda target deployed with the
+	// noop-telemetry extension layer attached; see SupportsExtension.
+	Extension bool `json:"extension,omitempty"`
+	// VPC selects the variant of a Lambda target attached to the harness
+	// VPC; see package vpc.
+	VPC bool `json:"vpc,omitempty"`
+	// Chaos selects the variant of a Lambda target deployed with the
+	// chaos extension contending for its CPU and memory; see package
+	// chaos and SupportsExtension.
+	Chaos bool `json:"chaos,omitempty"`
+	// Serializer selects the variant of a Go target built with another
+	// JSON serializer than encoding/json; see SupportsSerializer. Empty
+	// means SerializerStd.
+	Serializer string `json:"serializer,omitempty"`
+	Dir        string `json:"dir"`
+	Source     string `json:"source"`
+	// Event is the invocation payload fixture for Lambda workloads that
+	// expect a trigger event, if any: see GeneratedEventsDir.
+	Event string `json:"event,omitempty"`
+}
+
+// ID returns a stable identifier such as "lambda/go/fibonacci". Targets on
+// a non-default architecture get an "@arch" suffix and SnapStart variants
+// a "+snapstart" suffix, image-packaged ones an "+image" suffix,
+// extension variants an "+ext" suffix, VPC variants a "+vpc" suffix,
+// chaos variants a "+chaos" suffix and serializer variants the
+// serializer's name, such as "+jsoniter".
+func (t Target) ID() string {
+	id := fmt.Sprintf("%s/%s/%s", t.Kind, t.Runtime, t.Workload)
+	if t.Arch != "" && t.Arch != ArchX86 {
+		id += "@" + t.Arch
+	}
+	if t.Package == PackageImage {
+		id += "+image"
+	}
+	if t.SnapStart {
+		id += "+snapstart"
+	}
+	if t.Extension {
+		id += "+ext"
+	}
+	if t.VPC {
+		id += "+vpc"
+	}
+	if t.Chaos {
+		id += "+chaos"
+	}
+	if t.Serializer != "" {
+		id += "+" + t.Serializer
+	}
+	return id
+}
+
+// FunctionPrefixes begin every FunctionName: Ruchy's, then the baselines'.
+var FunctionPrefixes = []string{"ruchy-lambda-", "baseline-"}
+
+// FunctionName returns the deployed Lambda function name, following the
+// naming used by scripts/deploy-to-aws.sh and scripts/deploy-baselines.sh.
+// arm64 variants get an "-arm64" suffix, image-packaged variants an
+// "-image" suffix, SnapStart variants a "-snapstart" suffix, extension
+// variants an "-ext" suffix, VPC variants a "-vpc" suffix, chaos variants
+// a "-chaos" suffix and serializer variants the serializer's name, such as "-jsoniter", so they never share
+// configuration or code with $LATEST zip benchmarks. (Lambda cannot
+// change an existing function's package type either.)
+func (t Target) FunctionName() string {
+	var name string
+	switch {
+	case t.Runtime == "ruchy":
+		name = FunctionPrefixes[0] + t.Workload
+	case t.Workload == MinimalWorkload:
+		name = FunctionPrefixes[1] + t.Runtime
+	default:
+		name = FunctionPrefixes[1] + t.Runtime + "-" + t.Workload
+	}
+	if t.Arch == ArchARM64 {
+		name += "-arm64"
+	}
+	if t.Package == PackageImage {
+		name += "-image"
+	}
+	if t.SnapStart {
+		name += "-snapstart"
+	}
+	if t.Extension {
+		name += "-ext"
+	}
+	if t.VPC {
+		name += "-vpc"
+	}
+	if t.Chaos {
+		name += "-chaos"
+	}
+	if t.Serializer != "" {
+		name += "-" + t.Serializer
+	}
+	return name
+}
+
+// Qualifier is the version or alias to invoke: SnapStartAlias for
+// SnapStart variants, empty ($LATEST) otherwise.
+func (t Target) Qualifier() string {
+	if t.SnapStart {
+		return SnapStartAlias
+	}
+	return ""
+}
+
+// SupportsSnapStart reports whether t can be deployed with SnapStart.
+// Container images are not eligible.
+func (t Target) SupportsSnapStart() bool {
+	return t.Kind == KindLambda && snapStartRuntimes[t.Runtime] && t.Package != PackageImage
+}
+
+// localRuntimes maps local workload source extensions to runtime names.
+var localRuntimes = map[string]string{
+	".c":     "c",
+	".rs":    "rust",
+	".go":    "go",
+	".py":    "python",
+	".jl":    "julia",
+	".ruchy": "ruchy",
+}
+
+// lambdaLayout describes where a baseline language keeps its handler
+// entry points: the glob relative to the baseline directory and the file
+// name prefix shared by every variant (main.go, main-fibonacci.go, ...).
+type lambdaLayout struct {
+	glob   string
+	prefix string
+}
+
+var lambdaLayouts = map[string]lambdaLayout{
+	"go":     {glob: "main*.go", prefix: "main"},
+	"rust":   {glob: "src/main*.rs", prefix: "main"},
+	"cpp":    {glob: "lambda/main*.cpp", prefix: "main"},
+	"python": {glob: "index*.py", prefix: "index"},
+}
+
+// Discover returns every target found under root, sorted by ID.
+func Discover(root string) ([]Target, error) {
+	var targets []Target
+
+	lambda, err := discoverBaselines(filepath.Join(root, "baselines"))
+	if err != nil {
+		return nil, err
+	}
+	targets = append(targets, lambda...)
+
+	ruchy, err := discoverRuchyHandlers(filepath.Join(root, "crates", "bootstrap", "src"))
+	if err != nil {
+		return nil, err
+	}
+	targets = append(targets, ruchy...)
+
+	for i, t := range targets {
+		targets[i].Event = findEvent(root, t.Workload)
+	}
+
+	local, err := discoverLocal(filepath.Join(root, "benchmarks"))
+	if err != nil {
+		return nil, err
+	}
+	targets = append(targets, local...)
+	targets = append(targets, withTinyGo(targets)...)
+	targets = append(targets, withWasm(root, targets)...)
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID() < targets[j].ID() })
+	return targets, nil
+}
+
+func discoverBaselines(dir string) ([]Target, error) {
+	var targets []Target
+	for runtime, layout := range lambdaLayouts {
+		base := filepath.Join(dir, runtime)
+		matches, err := filepath.Glob(filepath.Join(base, layout.glob))
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range matches {
+			name := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+			workload := strings.TrimPrefix(strings.TrimPrefix(name, layout.prefix), "-")
+			if workload == "" {
+				workload = MinimalWorkload
+			}
+			targets = append(targets, Target{
+				Runtime:  runtime,
+				Workload: workload,
+				Kind:     KindLambda,
+				Arch:     ArchX86,
+				Dir:      base,
+				Source:   src,
+			})
+		}
+	}
+	return targets, nil
+}
+
+// withTinyGo returns a TinyGo target for every Go target of a workload
+// TinyGo builds, from the same source.
+func withTinyGo(targets []Target) []Target {
+	var out []Target
+	for _, t := range targets {
+		if t.Runtime == "go" && tinyGoWorkloads[t.Workload] {
+			t.Runtime = TinyGo
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// withWasm returns, for every local Go program of a workload in
+// wasmWorkloads, a local and a Lambda Wasm target compiled from it. The
+// Lambda target's function is invoked with the workload's event, which
+// the module ignores as the program does its arguments.
+func withWasm(root string, targets []Target) []Target {
+	var out []Target
+	for _, t := range targets {
+		if t.Runtime == "go" && t.Kind == KindLocal && wasmWorkloads[t.Workload] {
+			t.Runtime = Wasm
+			out = append(out, t)
+			t.Kind, t.Arch, t.Event = KindLambda, ArchX86, findEvent(root, t.Workload)
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// discoverRuchyHandlers maps crates/bootstrap/src/handler_<workload>.ruchy
+// to the handler types accepted by scripts/build-lambda-package.sh.
+func discoverRuchyHandlers(dir string) ([]Target, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "handler_*.ruchy"))
+	if err != nil {
+		return nil, err
+	}
+	var targets []Target
+	for _, src := range matches {
+		workload := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(src), "handler_"), ".ruchy")
+		targets = append(targets, Target{
+			Runtime:  "ruchy",
+			Workload: workload,
+			Kind:     KindLambda,
+			Arch:     ArchX86,
+			Dir:      dir,
+			Source:   src,
+		})
+	}
+	return targets, nil
+}
+
+// discoverLocal treats every benchmarks/local-*/<workload>.<ext> file with
+// a known extension as a local target.
+func discoverLocal(dir string) ([]Target, error) {
+	dirs, err := filepath.Glob(filepath.Join(dir, "local-*"))
+	if err != nil {
+		return nil, err
+	}
+	var targets []Target
+	for _, d := range dirs {
+		entries, err := os.ReadDir(d)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			ext := filepath.Ext(e.Name())
+			runtime, ok := localRuntimes[ext]
+			if !ok {
+				continue
+			}
+			targets = append(targets, Target{
+				Runtime:  runtime,
+				Workload: strings.TrimSuffix(e.Name(), ext),
+				Kind:     KindLocal,
+				Dir:      d,
+				Source:   filepath.Join(d, e.Name()),
+			})
+		}
+	}
+	return targets, nil
+}
+
+// Filter keeps targets whose kind, runtime and workload are in the given
+// sets. An empty set matches everything.
+func Filter(targets []Target, kind Kind, runtimes, workloads []string) []Target {
+	var out []Target
+	for _, t := range targets {
+		if kind != "" && t.Kind != kind {
+			continue
+		}
+		if !matches(runtimes, t.Runtime) || !matches(workloads, t.Workload) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// WithArchs returns one copy of every Lambda target per architecture in
+// archs. Local targets are returned unchanged, and an empty archs keeps
+// the discovered (x86_64) variants.
+func WithArchs(targets []Target, archs []string) []Target {
+	if len(archs) == 0 {
+		return targets
+	}
+	var out []Target
+	for _, t := range targets {
+		if t.Kind != KindLambda {
+			out = append(out, t)
+			continue
+		}
+		for _, a := range archs {
+			t.Arch = a
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// WithPackages returns one copy of every Lambda target per package type
+// in pkgs, so image and zip cold starts can be measured side by side.
+// Local targets are returned unchanged, and an empty pkgs keeps the zip
+// variants.
+func WithPackages(targets []Target, pkgs []string) []Target {
+	if len(pkgs) == 0 {
+		return targets
+	}
+	var out []Target
+	for _, t := range targets {
+		if t.Kind != KindLambda {
+			out = append(out, t)
+			continue
+		}
+		for _, p := range pkgs {
+			t.Package = ""
+			if p == PackageImage {
+				t.Package = PackageImage
+			}
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// SupportsExtension reports whether t can be deployed with an extension
+// layer. Layers attach to zip functions only; an image would have to
+// carry the extension itself.
+func (t Target) SupportsExtension() bool {
+	return t.Kind == KindLambda && t.Package != PackageImage
+}
+
+// WithExtension returns every target that supports it both without and
+// with the extension attached, so an extension's overhead is measured
+// side by side with the bare function. Other targets are returned
+// unchanged.
+func WithExtension(targets []Target) []Target {
+	var out []Target
+	for _, t := range targets {
+		out = append(out, t)
+		if t.SupportsExtension() {
+			t.Extension = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// WithChaos returns every target that supports an extension both without
+// and with the chaos extension attached, so a runtime under contention is
+// measured side by side with the same function alone. Other targets are
+// returned unchanged.
+func WithChaos(targets []Target) []Target {
+	var out []Target
+	for _, t := range targets {
+		out = append(out, t)
+		if t.SupportsExtension() {
+			t.Chaos = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// SupportsSerializer reports whether t has variants built with other JSON
+// serializers: Go Lambda targets of the workloads that serialize through
+// internal/codec.
+func (t Target) SupportsSerializer() bool {
+	return t.Kind == KindLambda && t.Runtime == "go" && serializerWorkloads[t.Workload]
+}
+
+// WithSerializers returns one copy of every target that supports it per
+// serializer in names, so Go's best-case serialization is measured side
+// by side with encoding/json's. Other targets are returned unchanged, and
+// an empty names keeps the encoding/json variants.
+func WithSerializers(targets []Target, names []string) []Target {
+	if len(names) == 0 {
+		return targets
+	}
+	var out []Target
+	for _, t := range targets {
+		if !t.SupportsSerializer() {
+			out = append(out, t)
+			continue
+		}
+		for _, s := range names {
+			t.Serializer = ""
+			if s != SerializerStd {
+				t.Serializer = s
+			}
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// WithVPC returns every Lambda target both outside and attached to the
+// harness VPC, so VPC attachment's cost is measured side by side with the
+// same function without it. Local targets are returned unchanged.
+func WithVPC(targets []Target) []Target {
+	var out []Target
+	for _, t := range targets {
+		out = append(out, t)
+		if t.Kind == KindLambda {
+			t.VPC = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// WithSnapStart switches every target that supports SnapStart to its
+// SnapStart variant and leaves the rest unchanged, so snap-restored cold
+// starts are measured side by side with native ones.
+func WithSnapStart(targets []Target) []Target {
+	out := make([]Target, len(targets))
+	for i, t := range targets {
+		t.SnapStart = t.SupportsSnapStart()
+		out[i] = t
+	}
+	return out
+}
+
+func findEvent(root, workload string) string {
+	for _, dir := range []string{filepath.FromSlash(GeneratedEventsDir), filepath.Join("baselines", "events")} {
+		if ev := filepath.Join(root, dir, workload+".json"); isFile(ev) {
+			return ev
+		}
+	}
+	return ""
+}
+
+func matches(set []string, v string) bool {
+	if len(set) == 0 {
+		return true
+	}
+	for _, s := range set {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// FindRoot walks up from dir until it finds the repository root, which is
+// the first directory containing both baselines/ and benchmarks/.
+func FindRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if isDir(filepath.Join(dir, "baselines")) && isDir(filepath.Join(dir, "benchmarks")) {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no repository root (baselines/ and benchmarks/) above %s", dir)
+		}
+		dir = parent
+	}
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,266 @@
+package discover
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root,
+		"baselines/go/main.go",
+		"baselines/go/main-fibonacci.go",
+		"baselines/go/build.sh",
+		"baselines/python/index-fibonacci.py",
+		"crates/bootstrap/src/handler_fibonacci.ruchy",
+		"crates/bootstrap/src/handler.ruchy",
+		"benchmarks/local-fibonacci/fibonacci.rs",
+		"benchmarks/local-fibonacci/fibonacci.go",
+		"benchmarks/local-fibonacci/results.json",
+		"benchmarks/reports/cold-start.json",
+	)
+
+	targets, err := Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"lambda/go/fibonacci",
+		"lambda/go/minimal",
+		"lambda/python/fibonacci",
+		"lambda/ruchy/fibonacci",
+		"lambda/tinygo/fibonacci",
+		"lambda/wasm/fibonacci",
+		"local/go/fibonacci",
+		"local/rust/fibonacci",
+		"local/tinygo/fibonacci",
+		"local/wasm/fibonacci",
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("got %d targets %v, want %v", len(targets), targets, want)
+	}
+	for i, id := range want {
+		if got := targets[i].ID(); got != id {
+			t.Errorf("target %d = %s, want %s", i, got, id)
+		}
+	}
+	// The Lambda Wasm target is compiled from the local program.
+	if w := targets[5]; w.Arch != ArchX86 || w.Source != filepath.Join(root, "benchmarks", "local-fibonacci", "fibonacci.go") {
+		t.Errorf("wasm target %+v", w)
+	}
+}
+
+func TestDiscoverEventFixtures(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root,
+		"baselines/go/main.go",
+		"baselines/go/main-apigw.go",
+		"baselines/go/main-s3.go",
+		"baselines/events/apigw.json",
+		"baselines/events/s3.json",
+		".bench/events/s3.json",
+		"benchmarks/local-apigw/apigw.go",
+	)
+	targets, err := Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"lambda/go/apigw":   filepath.Join(root, "baselines", "events", "apigw.json"),
+		"lambda/go/minimal": "",
+		"lambda/go/s3":      filepath.Join(root, ".bench", "events", "s3.json"),
+		"local/go/apigw":    "",
+	}
+	for _, tg := range targets {
+		if tg.Event != want[tg.ID()] {
+			t.Errorf("%s event = %q, want %q", tg.ID(), tg.Event, want[tg.ID()])
+		}
+	}
+}
+
+func TestFunctionName(t *testing.T) {
+	tests := []struct {
+		target Target
+		want   string
+	}{
+		{Target{Runtime: "go", Workload: MinimalWorkload}, "baseline-go"},
+		{Target{Runtime: "go", Workload: "fibonacci"}, "baseline-go-fibonacci"},
+		{Target{Runtime: "ruchy", Workload: "fibonacci"}, "ruchy-lambda-fibonacci"},
+		{Target{Runtime: "go", Workload: "fibonacci", Arch: ArchARM64}, "baseline-go-fibonacci-arm64"},
+		{Target{Runtime: "go", Workload: MinimalWorkload, Arch: ArchX86}, "baseline-go"},
+		{Target{Runtime: "python", Workload: "fibonacci", Arch: ArchARM64, SnapStart: true}, "baseline-python-fibonacci-arm64-snapstart"},
+		{Target{Runtime: "go", Workload: MinimalWorkload, Arch: ArchARM64, Package: PackageImage}, "baseline-go-arm64-image"},
+		{Target{Runtime: "go", Workload: "fibonacci", Extension: true}, "baseline-go-fibonacci-ext"},
+		{Target{Runtime: "go", Workload: "fibonacci", Extension: true, VPC: true}, "baseline-go-fibonacci-ext-vpc"},
+		{Target{Runtime: "ruchy", Workload: "fibonacci", Arch: ArchARM64, Chaos: true}, "ruchy-lambda-fibonacci-arm64-chaos"},
+		{Target{Runtime: "go", Workload: "json", Arch: ArchARM64, Serializer: SerializerEasyJSON}, "baseline-go-json-arm64-easyjson"},
+	}
+	for _, tt := range tests {
+		if got := tt.target.FunctionName(); got != tt.want {
+			t.Errorf("FunctionName(%+v) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	targets := []Target{
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLocal},
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLambda},
+		{Runtime: "rust", Workload: "fibonacci", Kind: KindLocal},
+	}
+	got := Filter(targets, KindLocal, []string{"go"}, nil)
+	if len(got) != 1 || got[0].Kind != KindLocal || got[0].Runtime != "go" {
+		t.Errorf("Filter = %+v", got)
+	}
+	if got := Filter(targets, "", nil, nil); len(got) != len(targets) {
+		t.Errorf("empty filter kept %d of %d targets", len(got), len(targets))
+	}
+}
+
+func TestFindRoot(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, "baselines/go/main.go", "benchmarks/README.md")
+	got, err := FindRoot(filepath.Join(root, "baselines", "go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != root {
+		t.Errorf("FindRoot = %s, want %s", got, root)
+	}
+}
+
+func TestWithArchs(t *testing.T) {
+	targets := []Target{
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86},
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLocal},
+	}
+	got := WithArchs(targets, []string{ArchX86, ArchARM64})
+	if len(got) != 3 {
+		t.Fatalf("WithArchs returned %d targets, want 3: %+v", len(got), got)
+	}
+	if got[1].ID() != "lambda/go/fibonacci@arm64" || got[2].Arch != "" {
+		t.Errorf("WithArchs = %+v", got)
+	}
+}
+
+func TestWithPackages(t *testing.T) {
+	targets := []Target{
+		{Runtime: "python", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86},
+		{Runtime: "python", Workload: "fibonacci", Kind: KindLocal},
+	}
+	got := WithPackages(targets, []string{PackageZip, PackageImage})
+	if len(got) != 3 {
+		t.Fatalf("WithPackages returned %d targets, want 3: %+v", len(got), got)
+	}
+	if got[0].Package != "" || got[1].ID() != "lambda/python/fibonacci+image" || got[2].Package != "" {
+		t.Errorf("WithPackages = %+v", got)
+	}
+	if WithSnapStart(got)[1].SnapStart {
+		t.Error("image target switched to SnapStart")
+	}
+}
+
+func TestWithSnapStart(t *testing.T) {
+	targets := []Target{
+		{Runtime: "python", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86},
+		{Runtime: "ruchy", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86},
+		{Runtime: "python", Workload: "fibonacci", Kind: KindLocal},
+	}
+	got := WithSnapStart(targets)
+	if !got[0].SnapStart || got[1].SnapStart || got[2].SnapStart {
+		t.Errorf("WithSnapStart = %+v", got)
+	}
+	if got[0].ID() != "lambda/python/fibonacci+snapstart" || got[0].Qualifier() != SnapStartAlias {
+		t.Errorf("SnapStart target ID %q, qualifier %q", got[0].ID(), got[0].Qualifier())
+	}
+	if got[1].Qualifier() != "" {
+		t.Errorf("native target qualifier = %q", got[1].Qualifier())
+	}
+	if targets[0].SnapStart {
+		t.Error("WithSnapStart modified its input")
+	}
+}
+
+func TestWithExtension(t *testing.T) {
+	targets := []Target{
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86},
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86, Package: PackageImage},
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLocal},
+	}
+	got := WithExtension(targets)
+	if len(got) != 4 {
+		t.Fatalf("WithExtension returned %d targets, want 4: %+v", len(got), got)
+	}
+	if got[0].Extension || !got[1].Extension || got[2].Extension || got[3].Extension {
+		t.Errorf("WithExtension = %+v", got)
+	}
+	if got[1].ID() != "lambda/go/fibonacci+ext" {
+		t.Errorf("extension target ID %q", got[1].ID())
+	}
+}
+
+func TestWithVPC(t *testing.T) {
+	targets := []Target{
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86, Package: PackageImage},
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLocal},
+	}
+	got := WithVPC(targets)
+	if len(got) != 3 {
+		t.Fatalf("WithVPC returned %d targets, want 3: %+v", len(got), got)
+	}
+	if got[0].VPC || !got[1].VPC || got[2].VPC {
+		t.Errorf("WithVPC = %+v", got)
+	}
+	if got[1].ID() != "lambda/go/fibonacci+image+vpc" {
+		t.Errorf("VPC target ID %q", got[1].ID())
+	}
+}
+
+func TestWithChaos(t *testing.T) {
+	targets := []Target{
+		{Runtime: "ruchy", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86},
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86, Package: PackageImage},
+	}
+	got := WithChaos(targets)
+	if len(got) != 3 || got[0].Chaos || !got[1].Chaos || got[2].Chaos {
+		t.Fatalf("WithChaos = %+v", got)
+	}
+	if got[1].ID() != "lambda/ruchy/fibonacci+chaos" {
+		t.Errorf("chaos target ID %q", got[1].ID())
+	}
+}
+
+func TestWithSerializers(t *testing.T) {
+	targets := []Target{
+		{Runtime: "go", Workload: "json", Kind: KindLambda, Arch: ArchX86},
+		{Runtime: "go", Workload: "fibonacci", Kind: KindLambda, Arch: ArchX86},
+		{Runtime: "ruchy", Workload: "json", Kind: KindLambda, Arch: ArchX86},
+	}
+	got := WithSerializers(targets, []string{SerializerStd, SerializerJSONIter})
+	if len(got) != 4 {
+		t.Fatalf("WithSerializers returned %d targets, want 4: %+v", len(got), got)
+	}
+	if got[0].Serializer != "" || got[1].Serializer != SerializerJSONIter || got[2].Serializer != "" || got[3].Serializer != "" {
+		t.Errorf("WithSerializers = %+v", got)
+	}
+	if got[1].ID() != "lambda/go/json+jsoniter" {
+		t.Errorf("serializer target ID %q", got[1].ID())
+	}
+	if got := WithSerializers(targets, nil); len(got) != len(targets) {
+		t.Errorf("WithSerializers without names returned %d targets", len(got))
+	}
+}
@@ -0,0 +1,330 @@
+package edge
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+const (
+	// apiVersion is the CloudFront API version requests are made against,
+	// the first segment of every path.
+	apiVersion = "2020-05-31"
+	xmlns      = "http://cloudfront.amazonaws.com/doc/" + apiVersion + "/"
+	// cachingDisabled is the managed cache policy that caches nothing,
+	// so every request reaches its trigger.
+	cachingDisabled = "4135ea2d-6df8-44a3-9df3-4b5a84be39ad"
+	// origin is the distribution's origin, which CloudFront requires but
+	// viewer triggers answer for; example.com is reserved for examples.
+	origin   = "example.com"
+	originID = "unused"
+)
+
+// ErrNotFound is returned by Find when there is no harness distribution.
+var ErrNotFound = errors.New("no harness CloudFront distribution")
+
+// Variables so tests need not wait.
+var (
+	pollInterval  = 15 * time.Second
+	deployTimeout = 30 * time.Minute
+)
+
+// Client makes the CloudFront calls the harness needs, as queue.Client
+// does for SQS. CloudFront is global: requests are signed for Region
+// whatever the config's.
+type Client struct {
+	Config aws.Config
+	// Endpoint overrides https://cloudfront.amazonaws.com.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// APIError is an error response from CloudFront, such as
+// "NoSuchDistribution".
+type APIError = awsapi.Error
+
+// Distribution is the harness distribution.
+type Distribution struct {
+	ID         string
+	DomainName string
+	// Status is "Deployed" once every edge location has the current
+	// configuration, "InProgress" until then.
+	Status  string
+	Enabled bool
+}
+
+// Deployed reports whether d's configuration has reached every edge
+// location.
+func (d Distribution) Deployed() bool { return d.Status == "Deployed" }
+
+// distributionConfig is the part of a DistributionConfig the harness
+// sets. CloudFront's schema orders elements, as do the fields.
+type distributionConfig struct {
+	XMLName              xml.Name  `xml:"DistributionConfig"`
+	Namespace            string    `xml:"xmlns,attr,omitempty"`
+	CallerReference      string    `xml:"CallerReference"`
+	Origins              origins   `xml:"Origins"`
+	DefaultCacheBehavior behavior  `xml:"DefaultCacheBehavior"`
+	CacheBehaviors       behaviors `xml:"CacheBehaviors"`
+	Comment              string    `xml:"Comment"`
+	PriceClass           string    `xml:"PriceClass"`
+	Enabled              bool      `xml:"Enabled"`
+}
+
+type origins struct {
+	Quantity int          `xml:"Quantity"`
+	Items    []originItem `xml:"Items>Origin"`
+}
+
+type originItem struct {
+	ID                 string `xml:"Id"`
+	DomainName         string `xml:"DomainName"`
+	CustomOriginConfig struct {
+		HTTPPort             int    `xml:"HTTPPort"`
+		HTTPSPort            int    `xml:"HTTPSPort"`
+		OriginProtocolPolicy string `xml:"OriginProtocolPolicy"`
+	} `xml:"CustomOriginConfig"`
+}
+
+type behaviors struct {
+	Quantity int        `xml:"Quantity"`
+	Items    []behavior `xml:"Items>CacheBehavior"`
+}
+
+type behavior struct {
+	PathPattern                string       `xml:"PathPattern,omitempty"`
+	TargetOriginID             string       `xml:"TargetOriginId"`
+	ViewerProtocolPolicy       string       `xml:"ViewerProtocolPolicy"`
+	LambdaFunctionAssociations associations `xml:"LambdaFunctionAssociations"`
+	CachePolicyID              string       `xml:"CachePolicyId"`
+}
+
+type associations struct {
+	Quantity int           `xml:"Quantity"`
+	Items    []association `xml:"Items>LambdaFunctionAssociation"`
+}
+
+type association struct {
+	LambdaFunctionARN string `xml:"LambdaFunctionARN"`
+	EventType         string `xml:"EventType"`
+}
+
+type distribution struct {
+	ID         string `xml:"Id"`
+	Status     string `xml:"Status"`
+	DomainName string `xml:"DomainName"`
+	Enabled    bool   `xml:"DistributionConfig>Enabled"`
+}
+
+// config returns the configuration routing the path of each workload to
+// the function version ARN functions holds for it.
+func config(ref string, functions map[string]string, enabled bool) distributionConfig {
+	c := distributionConfig{
+		Namespace:       xmlns,
+		CallerReference: ref,
+		Comment:         Name,
+		PriceClass:      "PriceClass_All",
+		Enabled:         enabled,
+	}
+	o := originItem{ID: originID, DomainName: origin}
+	o.CustomOriginConfig.HTTPPort, o.CustomOriginConfig.HTTPSPort = 80, 443
+	o.CustomOriginConfig.OriginProtocolPolicy = "https-only"
+	c.Origins = origins{Quantity: 1, Items: []originItem{o}}
+	newBehavior := func(path string) behavior {
+		return behavior{PathPattern: path, TargetOriginID: originID, ViewerProtocolPolicy: "https-only", CachePolicyID: cachingDisabled}
+	}
+	// Other paths go to the origin, which nothing requests.
+	c.DefaultCacheBehavior = newBehavior("")
+	for _, w := range slices.Sorted(maps.Keys(functions)) {
+		b := newBehavior(Path(w))
+		b.LambdaFunctionAssociations = associations{Quantity: 1, Items: []association{{LambdaFunctionARN: functions[w], EventType: "viewer-request"}}}
+		c.CacheBehaviors.Items = append(c.CacheBehaviors.Items, b)
+	}
+	c.CacheBehaviors.Quantity = len(c.CacheBehaviors.Items)
+	return c
+}
+
+// Ensure creates the harness distribution, or updates it, so that the
+// path of each workload in functions runs the published function version
+// ARN it maps to as a viewer-request trigger. It does not wait for the
+// change to deploy; see Wait.
+func (c *Client) Ensure(ctx context.Context, functions map[string]string) (Distribution, error) {
+	d, err := c.Find(ctx)
+	if errors.Is(err, ErrNotFound) {
+		var out distribution
+		cfg := config(fmt.Sprintf("%s-%d", Name, time.Now().UnixNano()), functions, true)
+		if _, err := c.call(ctx, http.MethodPost, "/distribution", "", cfg, &out); err != nil {
+			return Distribution{}, fmt.Errorf("create distribution: %w", err)
+		}
+		return Distribution{ID: out.ID, DomainName: out.DomainName, Status: out.Status, Enabled: true}, nil
+	}
+	if err != nil {
+		return Distribution{}, err
+	}
+	return c.update(ctx, d, functions, true)
+}
+
+// update replaces d's configuration, keeping its caller reference, which
+// CloudFront requires.
+func (c *Client) update(ctx context.Context, d Distribution, functions map[string]string, enabled bool) (Distribution, error) {
+	var current distributionConfig
+	etag, err := c.call(ctx, http.MethodGet, "/distribution/"+d.ID+"/config", "", nil, &current)
+	if err != nil {
+		return d, fmt.Errorf("get distribution %s: %w", d.ID, err)
+	}
+	if functions == nil {
+		// Keep the current associations.
+		functions = map[string]string{}
+		for _, b := range current.CacheBehaviors.Items {
+			if len(b.LambdaFunctionAssociations.Items) > 0 {
+				functions[b.PathPattern[1:]] = b.LambdaFunctionAssociations.Items[0].LambdaFunctionARN
+			}
+		}
+	}
+	var out distribution
+	if _, err := c.call(ctx, http.MethodPut, "/distribution/"+d.ID+"/config", etag, config(current.CallerReference, functions, enabled), &out); err != nil {
+		return d, fmt.Errorf("update distribution %s: %w", d.ID, err)
+	}
+	return Distribution{ID: out.ID, DomainName: out.DomainName, Status: out.Status, Enabled: enabled}, nil
+}
+
+// Find returns the harness distribution, or ErrNotFound.
+func (c *Client) Find(ctx context.Context) (Distribution, error) {
+	marker := ""
+	for {
+		path := "/distribution"
+		if marker != "" {
+			path += "?Marker=" + url.QueryEscape(marker)
+		}
+		var out struct {
+			IsTruncated bool   `xml:"IsTruncated"`
+			NextMarker  string `xml:"NextMarker"`
+			Items       []struct {
+				ID         string `xml:"Id"`
+				Status     string `xml:"Status"`
+				DomainName string `xml:"DomainName"`
+				Comment    string `xml:"Comment"`
+				Enabled    bool   `xml:"Enabled"`
+			} `xml:"Items>DistributionSummary"`
+		}
+		if _, err := c.call(ctx, http.MethodGet, path, "", nil, &out); err != nil {
+			return Distribution{}, fmt.Errorf("list distributions: %w", err)
+		}
+		for _, d := range out.Items {
+			if d.Comment == Name {
+				return Distribution{ID: d.ID, DomainName: d.DomainName, Status: d.Status, Enabled: d.Enabled}, nil
+			}
+		}
+		if !out.IsTruncated || out.NextMarker == "" {
+			return Distribution{}, fmt.Errorf("%w; create it with ruchy-bench edge -deploy", ErrNotFound)
+		}
+		marker = out.NextMarker
+	}
+}
+
+// Wait waits for distribution id to finish deploying, which takes
+// minutes, and returns it with the ETag its deletion needs.
+func (c *Client) Wait(ctx context.Context, id string) (Distribution, error) {
+	d, _, err := c.wait(ctx, id)
+	return d, err
+}
+
+func (c *Client) wait(ctx context.Context, id string) (Distribution, string, error) {
+	deadline := time.Now().Add(deployTimeout)
+	for {
+		var out distribution
+		etag, err := c.call(ctx, http.MethodGet, "/distribution/"+id, "", nil, &out)
+		if err != nil {
+			return Distribution{}, "", fmt.Errorf("get distribution %s: %w", id, err)
+		}
+		d := Distribution{ID: out.ID, DomainName: out.DomainName, Status: out.Status, Enabled: out.Enabled}
+		if d.Deployed() {
+			return d, etag, nil
+		}
+		if time.Now().After(deadline) {
+			return d, "", fmt.Errorf("distribution %s not deployed after %s", id, deployTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return d, "", ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Delete removes the harness distribution, reporting whether there was
+// one. CloudFront only deletes a disabled distribution, so Delete first
+// disables it and waits for that to deploy, which takes minutes. The
+// edge functions' replicas outlive it by hours, and Lambda refuses to
+// delete the functions until CloudFront has removed them.
+func (c *Client) Delete(ctx context.Context) (bool, error) {
+	d, err := c.Find(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if d.Enabled {
+		if d, err = c.update(ctx, d, nil, false); err != nil {
+			return false, err
+		}
+	}
+	_, etag, err := c.wait(ctx, d.ID)
+	if err != nil {
+		return false, err
+	}
+	if _, err := c.call(ctx, http.MethodDelete, "/distribution/"+d.ID, etag, nil, nil); err != nil {
+		return false, fmt.Errorf("delete distribution %s: %w", d.ID, err)
+	}
+	return true, nil
+}
+
+// call sends in, if not nil, as the XML body of a request with If-Match
+// ifMatch, decodes the response into out, which may be nil, and returns
+// its ETag.
+func (c *Client) call(ctx context.Context, method, path, ifMatch string, in, out any) (string, error) {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = xml.Marshal(in); err != nil {
+			return "", err
+		}
+		body = append([]byte(xml.Header), body...)
+	}
+	api := &awsapi.Client{Config: c.Config, Service: "cloudfront", Region: Region, Endpoint: c.Endpoint, HTTP: c.HTTP}
+	if api.Endpoint == "" {
+		api.Endpoint = "https://cloudfront.amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, api.URL("/"+apiVersion+path), bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "text/xml")
+	}
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	resp, data, err := api.Do(req, body)
+	if err != nil {
+		return "", err
+	}
+	if out != nil {
+		if err := xml.Unmarshal(data, out); err != nil {
+			return "", fmt.Errorf("decode %s %s response: %w", method, path, err)
+		}
+	}
+	return resp.Header.Get("ETag"), nil
+}
@@ -0,0 +1,130 @@
+// Package edge runs baselines as Lambda@Edge functions behind a CloudFront
+// distribution and measures them from synthetic probes, so a workload
+// served from the edge can be compared with the same workload in one
+// region, from several geographies.
+//
+// Lambda@Edge only runs Node.js and Python, so the edge functions are the
+// Python baselines, wrapped by baselines/python/edge.py as viewer-request
+// triggers: the query string is the workload's input and its result the
+// response, so no request reaches the origin. Viewer triggers are limited
+// to 128 MB and five seconds, so the workloads are the ones that fit,
+// trimmed to smaller inputs; see Inputs. (CloudFront Functions, the other
+// edge compute, run JavaScript within a millisecond and cannot run any
+// workload.) Edge functions are created in us-east-1, from which
+// CloudFront replicates a published version to every regional edge cache.
+//
+// A probe is a Go function (baselines/go/probe) deployed in each probe
+// region. Each invocation alternates HTTPS requests to an edge function
+// with invocations of its regional twin, the workload's ordinary baseline
+// in us-east-1, and reports both round trips as its region sees them.
+package edge
+
+import (
+	"encoding/json"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+)
+
+const (
+	// Region is where edge functions are created, as Lambda@Edge
+	// requires, and where probes find their regional twins.
+	Region = "us-east-1"
+	// Runtime is the only baseline runtime Lambda@Edge can run.
+	Runtime = "python"
+	// Handler is the adapter's handler.
+	Handler = "edge.handler"
+	// MemoryMB and TimeoutSec are the most a viewer-request trigger may
+	// have.
+	MemoryMB   = 128
+	TimeoutSec = 5
+	// Name is the comment that marks the harness distribution,
+	// which is how Client finds it.
+	Name = "ruchy-bench-edge"
+	// Alias points at the published version of an edge function the
+	// distribution runs, as triggers must name a version.
+	Alias = "edge"
+	// ProbeFunction names the probe function in each probe region.
+	ProbeFunction = "ruchy-bench-probe"
+)
+
+// Inputs are the workloads that run at the edge, each with the input it
+// is trimmed to so that it finishes well within a viewer trigger's limits
+// at 128 MB; nil runs the workload's default.
+var Inputs = map[string]map[string]int{
+	"minimal":   nil,
+	"fibonacci": {"n": 22},
+	"tree":      {"depth": 14},
+}
+
+// Workloads lists Inputs' workloads in order.
+func Workloads() []string {
+	return slices.Sorted(maps.Keys(Inputs))
+}
+
+// FunctionName names the edge function of a target's regional function.
+func FunctionName(regional string) string {
+	return regional + "-edge"
+}
+
+// Path is the path the distribution routes to workload's edge function.
+func Path(workload string) string {
+	return "/" + workload
+}
+
+// URL is the URL of workload's edge function behind the distribution at
+// domain, with its trimmed input as the query string.
+func URL(domain, workload string) string {
+	u := url.URL{Scheme: "https", Host: domain, Path: Path(workload)}
+	q := url.Values{}
+	for name, v := range Inputs[workload] {
+		q.Set(name, strconv.Itoa(v))
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// Payload is the invocation payload of workload's regional twin: the
+// input URL puts in the query string.
+func Payload(workload string) json.RawMessage {
+	if len(Inputs[workload]) == 0 {
+		return json.RawMessage("{}")
+	}
+	b, _ := json.Marshal(Inputs[workload])
+	return b
+}
+
+// ProbeEvent is the invocation payload of the probe.
+type ProbeEvent struct {
+	// URL is the edge function's URL.
+	URL string `json:"url"`
+	// Function is the regional twin, invoked in FunctionRegion with
+	// Payload: the same input as URL's query string.
+	Function       string          `json:"function"`
+	FunctionRegion string          `json:"function_region"`
+	Payload        json.RawMessage `json:"payload"`
+	// N is how many round trips of each to time after the first, which
+	// opens the connections.
+	N int `json:"n"`
+}
+
+// ProbeResult is the probe's response: N+1 timings of each, the first
+// with connection setup.
+type ProbeResult struct {
+	// POP is the CloudFront edge location that answered, as its
+	// x-amz-cf-pop header names it.
+	POP      string   `json:"pop"`
+	Edge     []Timing `json:"edge"`
+	Regional []Timing `json:"regional"`
+	// EdgeBody and RegionalBody are the last bodies each returned, which
+	// must match.
+	EdgeBody     string `json:"edge_body"`
+	RegionalBody string `json:"regional_body"`
+}
+
+// Timing is one round trip.
+type Timing struct {
+	MS    float64 `json:"ms"`
+	Error string  `json:"error,omitempty"`
+}
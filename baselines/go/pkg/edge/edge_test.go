@@ -0,0 +1,178 @@
+package edge
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestURL(t *testing.T) {
+	if got := URL("d1.cloudfront.net", "fibonacci"); got != "https://d1.cloudfront.net/fibonacci?n=22" {
+		t.Errorf("URL = %s", got)
+	}
+	if got := URL("d1.cloudfront.net", "minimal"); got != "https://d1.cloudfront.net/minimal" {
+		t.Errorf("URL = %s", got)
+	}
+}
+
+// fakeCloudFront keeps distributions in memory. A changed distribution
+// is InProgress until the next GET of it.
+type fakeCloudFront struct {
+	t     *testing.T
+	dists map[string]*fakeDistribution
+	// bodies are the configurations posted and put, in order.
+	bodies []string
+}
+
+type fakeDistribution struct {
+	config  distributionConfig
+	status  string
+	version int
+}
+
+func (f *fakeCloudFront) etag(d *fakeDistribution) string { return fmt.Sprintf("E%d", d.version) }
+
+func (f *fakeCloudFront) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fail := func(status int, code string) {
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `<ErrorResponse xmlns="%s"><Error><Type>Sender</Type><Code>%s</Code><Message>%s %s</Message></Error></ErrorResponse>`, xmlns, code, r.Method, r.URL.Path)
+	}
+	reply := func(d *fakeDistribution, id string, status int) {
+		w.Header().Set("ETag", f.etag(d))
+		w.WriteHeader(status)
+		body, _ := xml.Marshal(d.config)
+		fmt.Fprintf(w, `<Distribution xmlns="%s"><Id>%s</Id><Status>%s</Status><DomainName>%s.cloudfront.net</DomainName>%s</Distribution>`, xmlns, id, d.status, strings.ToLower(id), body)
+	}
+	decode := func() (distributionConfig, bool) {
+		body, _ := io.ReadAll(r.Body)
+		f.bodies = append(f.bodies, string(body))
+		var c distributionConfig
+		if err := xml.Unmarshal(body, &c); err != nil || c.Namespace != xmlns {
+			f.t.Errorf("bad configuration %s: %v", body, err)
+			fail(http.StatusBadRequest, "MalformedXML")
+			return c, false
+		}
+		return c, true
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/"+apiVersion)
+	id := strings.Split(strings.TrimPrefix(path, "/distribution/"), "/")[0]
+	d := f.dists[id]
+	switch {
+	case r.Method == http.MethodPost && path == "/distribution":
+		c, ok := decode()
+		if !ok {
+			return
+		}
+		id := fmt.Sprintf("E%dXAMPLE", len(f.dists)+1)
+		d := &fakeDistribution{config: c, status: "InProgress", version: 1}
+		f.dists[id] = d
+		reply(d, id, http.StatusCreated)
+	case r.Method == http.MethodGet && path == "/distribution":
+		var items strings.Builder
+		for id, d := range f.dists {
+			fmt.Fprintf(&items, "<DistributionSummary><Id>%s</Id><Status>%s</Status><DomainName>%s.cloudfront.net</DomainName><Comment>%s</Comment><Enabled>%t</Enabled></DistributionSummary>",
+				id, d.status, strings.ToLower(id), d.config.Comment, d.config.Enabled)
+		}
+		fmt.Fprintf(w, `<DistributionList xmlns="%s"><IsTruncated>false</IsTruncated><Quantity>%d</Quantity><Items>%s</Items></DistributionList>`, xmlns, len(f.dists), items.String())
+	case d == nil:
+		fail(http.StatusNotFound, "NoSuchDistribution")
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/config"):
+		w.Header().Set("ETag", f.etag(d))
+		xml.NewEncoder(w).Encode(d.config)
+	case r.Method == http.MethodGet:
+		reply(d, id, http.StatusOK)
+		d.status = "Deployed"
+	case r.Header.Get("If-Match") != f.etag(d):
+		fail(http.StatusPreconditionFailed, "PreconditionFailed")
+	case r.Method == http.MethodPut:
+		c, ok := decode()
+		if !ok {
+			return
+		}
+		if c.CallerReference != d.config.CallerReference {
+			fail(http.StatusBadRequest, "IllegalUpdate")
+			return
+		}
+		d.config, d.status = c, "InProgress"
+		d.version++
+		reply(d, id, http.StatusOK)
+	case r.Method == http.MethodDelete:
+		if d.config.Enabled || d.status != "Deployed" {
+			fail(http.StatusConflict, "DistributionNotDisabled")
+			return
+		}
+		delete(f.dists, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		f.t.Errorf("unexpected %s %s", r.Method, r.URL)
+		fail(http.StatusBadRequest, "InvalidAction")
+	}
+}
+
+func newFake(t *testing.T) (*fakeCloudFront, *Client) {
+	pollInterval = time.Millisecond
+	fake := &fakeCloudFront{t: t, dists: map[string]*fakeDistribution{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, &Client{Config: aws.Config{Region: "eu-west-1"}, Endpoint: srv.URL}
+}
+
+func TestEnsureThenDelete(t *testing.T) {
+	fake, c := newFake(t)
+	ctx := context.Background()
+	fns := map[string]string{
+		"minimal":   "arn:aws:lambda:us-east-1:123456789012:function:baseline-python-edge:1",
+		"fibonacci": "arn:aws:lambda:us-east-1:123456789012:function:baseline-python-fibonacci-edge:1",
+	}
+	d, err := c.Ensure(ctx, fns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID == "" || d.DomainName == "" || d.Deployed() {
+		t.Fatalf("created %+v", d)
+	}
+	// Behaviors come sorted by path, each with its trigger, and the
+	// schema's element order is kept.
+	body := fake.bodies[0]
+	if !strings.Contains(body, "<CacheBehaviors><Quantity>2</Quantity><Items><CacheBehavior><PathPattern>/fibonacci</PathPattern>") ||
+		!strings.Contains(body, "<LambdaFunctionARN>"+fns["fibonacci"]+"</LambdaFunctionARN><EventType>viewer-request</EventType>") ||
+		strings.Index(body, "<Origins>") > strings.Index(body, "<DefaultCacheBehavior>") ||
+		strings.Index(body, "<Comment>") > strings.Index(body, "<Enabled>") {
+		t.Errorf("configuration %s", body)
+	}
+	if d, err = c.Wait(ctx, d.ID); err != nil || !d.Deployed() {
+		t.Fatalf("Wait = %+v, %v", d, err)
+	}
+
+	fns["fibonacci"] = strings.Replace(fns["fibonacci"], ":1", ":2", 1)
+	again, err := c.Ensure(ctx, fns)
+	if err != nil || again.ID != d.ID || len(fake.dists) != 1 {
+		t.Fatalf("second Ensure = %+v, %v with %d distributions", again, err, len(fake.dists))
+	}
+	if got := fake.dists[d.ID].config.CacheBehaviors.Items[0].LambdaFunctionAssociations.Items[0].LambdaFunctionARN; got != fns["fibonacci"] {
+		t.Errorf("fibonacci runs %s after the update", got)
+	}
+
+	deleted, err := c.Delete(ctx)
+	if err != nil || !deleted || len(fake.dists) != 0 {
+		t.Fatalf("Delete = %v, %v with %d distributions left", deleted, err, len(fake.dists))
+	}
+	// Disabling kept the functions associated.
+	if last := fake.bodies[len(fake.bodies)-1]; !strings.Contains(last, "<Enabled>false</Enabled>") || !strings.Contains(last, fns["minimal"]) {
+		t.Errorf("disabled with %s", last)
+	}
+	if deleted, err := c.Delete(ctx); deleted || err != nil {
+		t.Errorf("second Delete = %v, %v; want nothing to delete", deleted, err)
+	}
+	if _, err := c.Find(ctx); err == nil {
+		t.Error("Find after Delete succeeded")
+	}
+}
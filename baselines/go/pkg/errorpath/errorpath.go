@@ -0,0 +1,180 @@
+// Package errorpath reads how a failed Lambda invocation reported its
+// failure, for the workloads that fail by design: panic, error and
+// timeout. A function error reaches the caller three ways: the
+// X-Amz-Function-Error header, the error object Lambda returns in place of
+// a response (errorMessage and errorType, and from some runtimes
+// stackTrace and requestId), and the function's logs, where the runtime or
+// the platform records it, along with the REPORT line's status. Runtimes
+// fill these in differently, so alerting or retry logic written against
+// one may not recognize another's failures. A Surface captures all of it,
+// and Differences names where two runtimes disagree.
+package errorpath
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"lambdaperf/pkg/lambdalog"
+	"lambdaperf/pkg/reportparser"
+)
+
+// The workloads that fail on every invocation.
+const (
+	// Panic crashes the handler: a Go panic.
+	Panic = "panic"
+	// Error returns an error, or raises an exception in runtimes without
+	// error returns.
+	Error = "error"
+	// Timeout runs past the function timeout.
+	Timeout = "timeout"
+)
+
+// Workloads are the failing workloads, in the order ruchy-bench errors
+// measures them.
+var Workloads = []string{Panic, Error, Timeout}
+
+// Fails reports whether workload is one of Workloads.
+func Fails(workload string) bool { return slices.Contains(Workloads, workload) }
+
+// Kinds of error record in a function's logs.
+const (
+	// LogJSON is a JSON error object, as aws-lambda-go logs the error it
+	// reports and the Python runtime does under the JSON log format.
+	LogJSON = "json"
+	// LogText is an "[ERROR]" line, as the Python runtime logs an
+	// exception under the text log format.
+	LogText = "text"
+	// LogInvocation is a pkg/lambdalog invocation line with an error.
+	LogInvocation = "invocation"
+	// LogTimeout is the platform's "Task timed out" line.
+	LogTimeout = "timeout"
+	// LogExit is the platform's "Runtime exited" line, for a runtime
+	// process that died during the invocation.
+	LogExit = "exit"
+)
+
+// Surface is everything a failed invocation told its caller about the
+// failure.
+type Surface struct {
+	// FunctionError is the X-Amz-Function-Error header, Unhandled for
+	// every error the runtime or platform reports.
+	FunctionError string `json:"function_error"`
+	// ErrorType and Message are the error object's errorType and
+	// errorMessage.
+	ErrorType string `json:"error_type,omitempty"`
+	Message   string `json:"message,omitempty"`
+	// Fields are the error object's fields, sorted; nil when the response
+	// is not a JSON object.
+	Fields []string `json:"fields,omitempty"`
+	// Status and StatusErrorType are the REPORT line's Status and Error
+	// Type.
+	Status          string `json:"status,omitempty"`
+	StatusErrorType string `json:"status_error_type,omitempty"`
+	// Logs are the kinds of error record in the invocation's log tail,
+	// sorted.
+	Logs []string `json:"logs,omitempty"`
+}
+
+// Read returns the surface of an invocation from its X-Amz-Function-Error
+// header, response payload and log tail.
+func Read(functionError string, payload []byte, logTail string) Surface {
+	s := Surface{FunctionError: functionError}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(payload, &fields) == nil && fields != nil {
+		s.Fields = slices.Sorted(maps.Keys(fields))
+		json.Unmarshal(fields["errorType"], &s.ErrorType)
+		json.Unmarshal(fields["errorMessage"], &s.Message)
+	}
+	if r, ok := reportparser.Last(logTail); ok {
+		s.Status, s.StatusErrorType = r.Status, r.ErrorType
+	}
+	sc := bufio.NewScanner(strings.NewReader(logTail))
+	for sc.Scan() {
+		if kind := logKind(sc.Text()); kind != "" && !slices.Contains(s.Logs, kind) {
+			s.Logs = append(s.Logs, kind)
+		}
+	}
+	slices.Sort(s.Logs)
+	return s
+}
+
+// logKind is the kind of error record line is, "" for other lines.
+func logKind(line string) string {
+	if e, ok := lambdalog.Parse(line); ok {
+		if e.Error != "" {
+			return LogInvocation
+		}
+		return ""
+	}
+	switch line = strings.TrimSpace(line); {
+	case strings.Contains(line, "Task timed out after"):
+		return LogTimeout
+	case strings.Contains(line, "Runtime exited"):
+		return LogExit
+	case strings.HasPrefix(line, "[ERROR]"):
+		return LogText
+	case strings.Contains(line, `"errorType"`) || strings.Contains(line, `"errorMessage"`):
+		return LogJSON
+	}
+	return ""
+}
+
+// String renders the surface on one line, without the message, which
+// carries request IDs and timestamps: header, error type, fields, REPORT
+// status and log records, a dash for each that is missing.
+func (s Surface) String() string {
+	status := s.Status
+	if s.StatusErrorType != "" {
+		status += "/" + s.StatusErrorType
+	}
+	return fmt.Sprintf("%s %s {%s} status=%s logs=%s", dash(s.FunctionError), dash(s.ErrorType),
+		strings.Join(s.Fields, ","), dash(status), dash(strings.Join(s.Logs, "+")))
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Expect returns an error unless s is the failure workload must produce:
+// a function error, and for Timeout one the platform ended for running
+// out of time.
+func Expect(workload string, s Surface) error {
+	if s.FunctionError == "" {
+		return errors.New("invocation succeeded; want a function error")
+	}
+	timedOut := s.Status == "timeout" || slices.Contains(s.Logs, LogTimeout) || strings.Contains(s.Message, "timed out")
+	switch {
+	case workload == Timeout && !timedOut:
+		return fmt.Errorf("failed with %s; want a timeout", s)
+	case workload != Timeout && timedOut:
+		return fmt.Errorf("timed out; want the %s workload's own failure", workload)
+	}
+	return nil
+}
+
+// Differences lists how b reports a failure differently from a, each as
+// "what: a's against b's". Error types and messages are left out: they
+// name the runtime's own exception types and always differ. Whether there
+// is an error type is compared, through the fields.
+func Differences(a, b Surface) []string {
+	var out []string
+	diff := func(what, x, y string) {
+		if x != y {
+			out = append(out, fmt.Sprintf("%s: %s against %s", what, dash(x), dash(y)))
+		}
+	}
+	diff("header", a.FunctionError, b.FunctionError)
+	diff("fields", strings.Join(a.Fields, ","), strings.Join(b.Fields, ","))
+	diff("status", a.Status, b.Status)
+	diff("status error type", a.StatusErrorType, b.StatusErrorType)
+	diff("logs", strings.Join(a.Logs, "+"), strings.Join(b.Logs, "+"))
+	return out
+}
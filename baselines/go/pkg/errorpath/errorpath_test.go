@@ -0,0 +1,75 @@
+package errorpath
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	goError = `START RequestId: 1a Version: $LATEST
+{"type":"invocation","request_id":"1a","workload":"error","duration_ms":0.01,"error":"deliberate error"}
+2026/10/14 10:00:00 {"errorMessage":"deliberate error","errorType":"errorString"}
+END RequestId: 1a
+REPORT RequestId: 1a	Duration: 1.20 ms	Billed Duration: 2 ms	Memory Size: 128 MB	Max Memory Used: 18 MB
+`
+	pythonError = `START RequestId: 2b Version: $LATEST
+[ERROR] RuntimeError: deliberate error
+Traceback (most recent call last):
+  File "/var/task/index.py", line 9, in handler
+    raise RuntimeError('deliberate error')
+END RequestId: 2b
+REPORT RequestId: 2b	Duration: 1.91 ms	Billed Duration: 2 ms	Memory Size: 128 MB	Max Memory Used: 38 MB
+`
+	timedOut = `START RequestId: 3c Version: $LATEST
+2026-10-14T10:00:01.000Z 3c Task timed out after 1.00 seconds
+
+END RequestId: 3c
+REPORT RequestId: 3c	Duration: 1000.00 ms	Billed Duration: 1000 ms	Memory Size: 128 MB	Max Memory Used: 18 MB	Status: timeout
+`
+)
+
+func TestRead(t *testing.T) {
+	g := Read("Unhandled", []byte(`{"errorMessage":"deliberate error","errorType":"errorString"}`), goError)
+	if got := g.String(); got != "Unhandled errorString {errorMessage,errorType} status=- logs=invocation+json" {
+		t.Errorf("go error = %s", got)
+	}
+	p := Read("Unhandled", []byte(`{"errorMessage": "deliberate error", "errorType": "RuntimeError", "requestId": "2b", "stackTrace": ["  File ..."]}`), pythonError)
+	if got := p.String(); got != "Unhandled RuntimeError {errorMessage,errorType,requestId,stackTrace} status=- logs=text" {
+		t.Errorf("python error = %s", got)
+	}
+	to := Read("Unhandled", []byte(`{"errorMessage":"2026-10-14T10:00:01.000Z 3c Task timed out after 1.00 seconds"}`), timedOut)
+	if to.Status != "timeout" || strings.Join(to.Logs, "+") != LogTimeout || to.ErrorType != "" {
+		t.Errorf("timeout = %s", to)
+	}
+	if ok := Read("", []byte(`{"statusCode":200}`), ""); ok.FunctionError != "" || ok.Logs != nil {
+		t.Errorf("success = %s", ok)
+	}
+
+	if d := Differences(g, p); len(d) != 2 || d[0] != "fields: errorMessage,errorType against errorMessage,errorType,requestId,stackTrace" || d[1] != "logs: invocation+json against text" {
+		t.Errorf("differences = %q", d)
+	}
+	if d := Differences(g, g); d != nil {
+		t.Errorf("a surface differs from itself: %q", d)
+	}
+}
+
+func TestExpect(t *testing.T) {
+	failed := Read("Unhandled", []byte(`{"errorMessage":"deliberate error","errorType":"errorString"}`), goError)
+	timeout := Read("Unhandled", []byte(`{"errorMessage":"Task timed out after 1.00 seconds"}`), timedOut)
+	for _, c := range []struct {
+		workload string
+		s        Surface
+		ok       bool
+	}{
+		{Error, failed, true},
+		{Panic, failed, true},
+		{Timeout, timeout, true},
+		{Timeout, failed, false},
+		{Error, timeout, false},
+		{Error, Surface{}, false},
+	} {
+		if err := Expect(c.workload, c.s); (err == nil) != c.ok {
+			t.Errorf("Expect(%s, %s) = %v", c.workload, c.s, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package fixture
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strings"
+)
+
+// CorpusSize is the approximate size of the word-count corpus: 2 MB.
+const CorpusSize = 2 << 20
+
+// CorpusPath, relative to baselines/go, is the bundled corpus the wordcount
+// workloads read. It is checked in so that every runtime tokenizes the very
+// same bytes; Corpus regenerates it.
+const CorpusPath = "corpus/corpus.txt"
+
+// stopwords take the most frequent ranks, as they do in English prose.
+var stopwords = strings.Fields(`the of and to a in is that it was for on as with he be at by
+	this had not are but from or have an they which one you were her all she there would their`)
+
+var stems = strings.Fields(`abandon account answer arrange attempt balance bark borrow brush
+	burn call carry change charge check claim clean climb collect color comfort count cover crack
+	cross crowd damage dance delay deliver depend desert doubt drift dress drop earn end enjoy
+	escape experience explain face fail farm fear fill finish fish fix flood flow fold follow
+	force form frame gather glow govern grant guard guess hand harm heat help hunt hurry inform
+	jump kick kill kiss land last laugh launch lead learn lift light limit list load look love
+	mark match measure melt mind miss move name need note offer open order paint pass pick plan
+	plant play point pour present press print pull push question rain reach record rest return
+	ride ring roll rule rush sail scratch search serve settle shape share shift shock show sign
+	signal smell smile sound spark spell spray start stay step stop store storm strike stretch
+	suit supply support surprise talk taste test thank touch trace track trade train travel
+	treat trust turn twist visit voice walk want warm wash watch wave wish wonder work worry yield`)
+
+var suffixes = []string{"", "s", "ed", "ing", "er", "ers"}
+
+// vocabulary returns the corpus words in frequency-rank order: stopwords
+// first, then every stem and suffix combination in a fixed shuffle.
+func vocabulary(rng *rand.Rand) []string {
+	var words []string
+	for _, stem := range stems {
+		for _, suffix := range suffixes {
+			words = append(words, inflect(stem, suffix))
+		}
+	}
+	rng.Shuffle(len(words), func(i, j int) { words[i], words[j] = words[j], words[i] })
+	return append(append([]string(nil), stopwords...), words...)
+}
+
+// inflect applies the common English spelling rules for adding suffix.
+func inflect(stem, suffix string) string {
+	switch {
+	case suffix == "":
+		return stem
+	case strings.HasSuffix(stem, "e") && suffix != "s":
+		return stem[:len(stem)-1] + suffix
+	case strings.HasSuffix(stem, "y") && !strings.ContainsRune("aeiou", rune(stem[len(stem)-2])) && suffix != "ing":
+		if suffix == "s" {
+			suffix = "es"
+		}
+		return stem[:len(stem)-1] + "i" + suffix
+	case suffix == "s" && (strings.HasSuffix(stem, "sh") || strings.HasSuffix(stem, "ch") ||
+		strings.HasSuffix(stem, "ss") || strings.HasSuffix(stem, "x")):
+		return stem + "es"
+	}
+	return stem + suffix
+}
+
+// Corpus returns deterministic English-like prose of at least size bytes:
+// Zipf-distributed words in capitalized sentences with some punctuation,
+// wrapped at 72 columns into paragraphs. The bytes depend only on size.
+func Corpus(size int) []byte {
+	rng := rand.New(rand.NewChaCha8(seed))
+	words := vocabulary(rng)
+	zipf := rand.NewZipf(rng, 1.1, 2, uint64(len(words)-1))
+
+	var out bytes.Buffer
+	out.Grow(size + 1024)
+	col := 0
+	emit := func(token string) {
+		if col > 0 && col+1+len(token) > 72 {
+			out.WriteByte('\n')
+			col = 0
+		} else if col > 0 {
+			out.WriteByte(' ')
+			col++
+		}
+		out.WriteString(token)
+		col += len(token)
+	}
+	for out.Len() < size {
+		for s := 3 + rng.IntN(6); s > 0; s-- {
+			n := 4 + rng.IntN(16)
+			for i := 0; i < n; i++ {
+				w := words[zipf.Uint64()]
+				if i == 0 {
+					w = strings.ToUpper(w[:1]) + w[1:]
+				}
+				switch {
+				case i == n-1 && rng.IntN(8) == 0:
+					w += "?"
+				case i == n-1:
+					w += "."
+				case rng.IntN(12) == 0:
+					w += ","
+				}
+				emit(w)
+			}
+		}
+		out.WriteString("\n\n")
+		col = 0
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,101 @@
+package fixture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDB workload parameters, matching main-dynamodb.go.
+const (
+	DynamoDBWorkload = "dynamodb"
+	// DefaultTable is the table the handler uses unless TABLE_NAME is set.
+	DefaultTable = "ruchy-bench-items"
+	// TableItems is the number of items seeded for the handler to read.
+	TableItems = 100
+)
+
+// batchSize is BatchWriteItem's per-call limit.
+const batchSize = 25
+
+// DynamoDBAPI is the subset of the DynamoDB client used to provision the
+// benchmark table.
+type DynamoDBAPI interface {
+	dynamodb.DescribeTableAPIClient
+	CreateTable(ctx context.Context, in *dynamodb.CreateTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error)
+	BatchWriteItem(ctx context.Context, in *dynamodb.BatchWriteItemInput, opts ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+}
+
+// SeedTable creates the on-demand table if needed, waits until it is
+// active, and writes the TableItems items the handler reads. It returns the
+// table ARN for the execution role's policy.
+func SeedTable(ctx context.Context, client DynamoDBAPI, table string) (arn string, created bool, err error) {
+	desc, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	var missing *ddbtypes.ResourceNotFoundException
+	switch {
+	case errors.As(err, &missing):
+		if _, err := client.CreateTable(ctx, &dynamodb.CreateTableInput{
+			TableName:            aws.String(table),
+			BillingMode:          ddbtypes.BillingModePayPerRequest,
+			AttributeDefinitions: []ddbtypes.AttributeDefinition{{AttributeName: aws.String("pk"), AttributeType: ddbtypes.ScalarAttributeTypeS}},
+			KeySchema:            []ddbtypes.KeySchemaElement{{AttributeName: aws.String("pk"), KeyType: ddbtypes.KeyTypeHash}},
+			Tags:                 []ddbtypes.Tag{{Key: aws.String(TagKey), Value: aws.String("true")}},
+		}); err != nil {
+			return "", false, fmt.Errorf("create table %s: %w", table, err)
+		}
+		created = true
+	case err != nil:
+		return "", false, fmt.Errorf("describe table %s: %w", table, err)
+	}
+	if created || desc.Table.TableStatus != ddbtypes.TableStatusActive {
+		waiter := dynamodb.NewTableExistsWaiter(client)
+		if desc, err = waiter.WaitForOutput(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)}, 5*time.Minute); err != nil {
+			return "", created, fmt.Errorf("wait for table %s: %w", table, err)
+		}
+	}
+
+	for start := 0; start < TableItems; start += batchSize {
+		var requests []ddbtypes.WriteRequest
+		for i := start; i < min(start+batchSize, TableItems); i++ {
+			requests = append(requests, ddbtypes.WriteRequest{PutRequest: &ddbtypes.PutRequest{Item: item(i)}})
+		}
+		if err := writeBatch(ctx, client, table, requests); err != nil {
+			return "", created, err
+		}
+	}
+	return aws.ToString(desc.Table.TableArn), created, nil
+}
+
+// item is the i-th seeded item: a key plus a ~200 byte payload, about the
+// size of a typical application record.
+func item(i int) map[string]ddbtypes.AttributeValue {
+	return map[string]ddbtypes.AttributeValue{
+		"pk":      &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf("item-%03d", i)},
+		"n":       &ddbtypes.AttributeValueMemberN{Value: fmt.Sprint(i)},
+		"payload": &ddbtypes.AttributeValueMemberS{Value: strings.Repeat(fmt.Sprintf("%03d", i), 64)},
+	}
+}
+
+// writeBatch writes requests, resubmitting whatever DynamoDB leaves
+// unprocessed.
+func writeBatch(ctx context.Context, client DynamoDBAPI, table string, requests []ddbtypes.WriteRequest) error {
+	for attempt := 0; len(requests) > 0; attempt++ {
+		if attempt == 5 {
+			return fmt.Errorf("seed table %s: %d items still unprocessed", table, len(requests))
+		}
+		out, err := client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]ddbtypes.WriteRequest{table: requests},
+		})
+		if err != nil {
+			return fmt.Errorf("seed table %s: %w", table, err)
+		}
+		requests = out.UnprocessedItems[table]
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Package fixture provisions the AWS resources the I/O-bound baselines
+// read: the synthetic S3 object the S3 workload downloads (with the event
+// that points a handler at it) and the DynamoDB table the dynamodb workload
+// reads and writes, plus the text corpus bundled with the wordcount workload,
+// the log bundled with the logparse workload, the order export bundled
+// with the etl workload, the photo bundled with the imgresize workload and
+// the documents the echo workload is invoked with.
+// The object's bytes are a pure function of its size, so every runtime
+// hashes the same input and must report the same digest.
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// DefaultSize is the size of the S3 workload's object: 5 MB.
+const DefaultSize = 5 << 20
+
+// S3Workload is the name of the S3-triggered workload (main-s3.go).
+const S3Workload = "s3"
+
+// TagKey marks the buckets and tables created by the harness, as
+// deploy.TagKey does functions.
+const TagKey = "ruchy-bench"
+
+// digestKey is the object metadata entry holding the SHA-256 of the body,
+// so an existing upload can be checked without downloading it.
+const digestKey = "sha256"
+
+// seed fixes the generator; changing it changes every expected digest.
+var seed = [32]byte{'r', 'u', 'c', 'h', 'y', '-', 'b', 'e', 'n', 'c', 'h'}
+
+// Data returns size deterministic pseudo-random bytes. Random rather than
+// repeated content keeps transfer compression from flattering anyone.
+func Data(size int) []byte {
+	b := make([]byte, size)
+	rand.NewChaCha8(seed).Read(b)
+	return b
+}
+
+// Key is the object key of the fixture of the given size.
+func Key(size int) string {
+	return fmt.Sprintf("fixtures/synthetic-%d.bin", size)
+}
+
+// Digest is the hex SHA-256 of Data(size).
+func Digest(size int) string {
+	sum := sha256.Sum256(Data(size))
+	return hex.EncodeToString(sum[:])
+}
+
+// S3API is the subset of the S3 client used to seed fixtures.
+type S3API interface {
+	HeadBucket(ctx context.Context, in *s3.HeadBucketInput, opts ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	CreateBucket(ctx context.Context, in *s3.CreateBucketInput, opts ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
+	PutBucketTagging(ctx context.Context, in *s3.PutBucketTaggingInput, opts ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error)
+	HeadObject(ctx context.Context, in *s3.HeadObjectInput, opts ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	PutObject(ctx context.Context, in *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// Object is an uploaded fixture.
+type Object struct {
+	Bucket string
+	Key    string
+	Size   int64
+	ETag   string
+	Digest string
+}
+
+// Seed makes sure bucket exists in region and holds the fixture of the
+// given size, uploading it only when missing or different.
+func Seed(ctx context.Context, client S3API, bucket, region string, size int) (obj Object, uploaded bool, err error) {
+	if err := EnsureBucket(ctx, client, bucket, region); err != nil {
+		return Object{}, false, err
+	}
+	obj = Object{Bucket: bucket, Key: Key(size), Size: int64(size), Digest: Digest(size)}
+
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(obj.Key)})
+	var missing *types.NotFound
+	switch {
+	case err == nil && aws.ToInt64(head.ContentLength) == obj.Size && head.Metadata[digestKey] == obj.Digest:
+		obj.ETag = aws.ToString(head.ETag)
+		return obj, false, nil
+	case err != nil && !errors.As(err, &missing):
+		return Object{}, false, fmt.Errorf("head s3://%s/%s: %w", bucket, obj.Key, err)
+	}
+
+	out, err := client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(obj.Key),
+		Body:        bytes.NewReader(Data(size)),
+		ContentType: aws.String("application/octet-stream"),
+		Metadata:    map[string]string{digestKey: obj.Digest},
+	})
+	if err != nil {
+		return Object{}, false, fmt.Errorf("upload s3://%s/%s: %w", bucket, obj.Key, err)
+	}
+	obj.ETag = aws.ToString(out.ETag)
+	return obj, true, nil
+}
+
+// EnsureBucket creates bucket in region unless it exists, tagging it
+// TagKey when it does.
+func EnsureBucket(ctx context.Context, client S3API, bucket, region string) error {
+	_, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	var missing *types.NotFound
+	if err == nil {
+		return nil
+	}
+	if !errors.As(err, &missing) {
+		return fmt.Errorf("head bucket %s: %w", bucket, err)
+	}
+	in := &s3.CreateBucketInput{Bucket: aws.String(bucket)}
+	// us-east-1 is the one region that rejects an explicit constraint.
+	if region != "us-east-1" {
+		in.CreateBucketConfiguration = &types.CreateBucketConfiguration{LocationConstraint: types.BucketLocationConstraint(region)}
+	}
+	_, err = client.CreateBucket(ctx, in)
+	var owned *types.BucketAlreadyOwnedByYou
+	switch {
+	case errors.As(err, &owned):
+		return nil
+	case err != nil:
+		return fmt.Errorf("create bucket %s: %w", bucket, err)
+	}
+	// Only a bucket created here is tagged: ruchy-bench gc deletes it.
+	if _, err := client.PutBucketTagging(ctx, &s3.PutBucketTaggingInput{
+		Bucket:  aws.String(bucket),
+		Tagging: &types.Tagging{TagSet: []types.Tag{{Key: aws.String(TagKey), Value: aws.String("true")}}},
+	}); err != nil {
+		return fmt.Errorf("tag bucket %s: %w", bucket, err)
+	}
+	return nil
+}
+
+// Event returns the ObjectCreated:Put notification S3 would deliver for
+// obj, ready to pass to a handler as its invocation payload.
+func Event(obj Object, region string, at time.Time) events.S3Event {
+	return events.S3Event{Records: []events.S3EventRecord{{
+		EventVersion: "2.1",
+		EventSource:  "aws:s3",
+		AWSRegion:    region,
+		EventTime:    at.UTC(),
+		EventName:    "ObjectCreated:Put",
+		S3: events.S3Entity{
+			SchemaVersion:   "1.0",
+			ConfigurationID: "ruchy-bench",
+			Bucket: events.S3Bucket{
+				Name: obj.Bucket,
+				Arn:  "arn:aws:s3:::" + obj.Bucket,
+			},
+			Object: events.S3Object{
+				Key:  obj.Key,
+				Size: obj.Size,
+				ETag: obj.ETag,
+			},
+		},
+	}}}
+}
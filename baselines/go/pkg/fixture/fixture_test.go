@@ -0,0 +1,292 @@
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var update = flag.Bool("update", false, "rewrite the bundled corpus, log, orders and photo")
+
+type object struct {
+	body     []byte
+	metadata map[string]string
+}
+
+type fakeS3 struct {
+	buckets map[string]map[string]object
+	puts    int
+	region  types.BucketLocationConstraint
+	tagged  []string
+}
+
+func (f *fakeS3) HeadBucket(_ context.Context, in *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	if _, ok := f.buckets[aws.ToString(in.Bucket)]; !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *fakeS3) CreateBucket(_ context.Context, in *s3.CreateBucketInput, _ ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	if in.CreateBucketConfiguration != nil {
+		f.region = in.CreateBucketConfiguration.LocationConstraint
+	}
+	f.buckets[aws.ToString(in.Bucket)] = map[string]object{}
+	return &s3.CreateBucketOutput{}, nil
+}
+
+func (f *fakeS3) PutBucketTagging(_ context.Context, in *s3.PutBucketTaggingInput, _ ...func(*s3.Options)) (*s3.PutBucketTaggingOutput, error) {
+	f.tagged = append(f.tagged, aws.ToString(in.Bucket))
+	return &s3.PutBucketTaggingOutput{}, nil
+}
+
+func (f *fakeS3) HeadObject(_ context.Context, in *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	o, ok := f.buckets[aws.ToString(in.Bucket)][aws.ToString(in.Key)]
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+	return &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(o.body))), Metadata: o.metadata, ETag: aws.String(`"etag"`)}, nil
+}
+
+func (f *fakeS3) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.puts++
+	f.buckets[aws.ToString(in.Bucket)][aws.ToString(in.Key)] = object{body: body, metadata: in.Metadata}
+	return &s3.PutObjectOutput{ETag: aws.String(`"etag"`)}, nil
+}
+
+func TestDataIsDeterministic(t *testing.T) {
+	if !bytes.Equal(Data(4096), Data(4096)) {
+		t.Fatal("Data differs between calls")
+	}
+	if !bytes.Equal(Data(1024), Data(4096)[:1024]) {
+		t.Error("smaller fixture is not a prefix of a larger one")
+	}
+	// Pinned: main-s3.go documents this digest as its expected result.
+	if got, want := Digest(DefaultSize), "8a54de1b509d976d896796fb95039ac200196f0a5fd21bbcf2c1e32f6d1007e6"; got != want {
+		t.Errorf("Digest(DefaultSize) = %s, want %s", got, want)
+	}
+}
+
+func TestSeedUploadsOnce(t *testing.T) {
+	f := &fakeS3{buckets: map[string]map[string]object{}}
+	ctx := context.Background()
+	obj, uploaded, err := Seed(ctx, f, "bench", "eu-west-1", 1024)
+	if err != nil || !uploaded {
+		t.Fatalf("first Seed: uploaded=%v err=%v", uploaded, err)
+	}
+	if f.region != "eu-west-1" || len(f.tagged) != 1 {
+		t.Errorf("bucket location = %q, tagged %v", f.region, f.tagged)
+	}
+	if got := f.buckets["bench"][obj.Key].body; !bytes.Equal(got, Data(1024)) {
+		t.Error("uploaded body is not Data(1024)")
+	}
+	if _, uploaded, err = Seed(ctx, f, "bench", "eu-west-1", 1024); err != nil || uploaded {
+		t.Fatalf("second Seed: uploaded=%v err=%v", uploaded, err)
+	}
+
+	// A tampered object is replaced.
+	f.buckets["bench"][obj.Key] = object{body: make([]byte, 1024)}
+	if _, uploaded, err = Seed(ctx, f, "bench", "eu-west-1", 1024); err != nil || !uploaded {
+		t.Fatalf("Seed after tampering: uploaded=%v err=%v", uploaded, err)
+	}
+	if f.puts != 2 {
+		t.Errorf("%d uploads, want 2", f.puts)
+	}
+}
+
+func TestSeedUSEast1OmitsLocation(t *testing.T) {
+	f := &fakeS3{buckets: map[string]map[string]object{}}
+	if _, _, err := Seed(context.Background(), f, "bench", "us-east-1", 16); err != nil {
+		t.Fatal(err)
+	}
+	if f.region != "" {
+		t.Errorf("us-east-1 bucket created with location %q", f.region)
+	}
+}
+
+func TestEventRoundTrips(t *testing.T) {
+	obj := Object{Bucket: "bench", Key: Key(DefaultSize), Size: DefaultSize, ETag: `"etag"`}
+	data, err := json.Marshal(Event(obj, "us-east-1", time.Unix(0, 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got events.S3Event
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	r := got.Records[0]
+	if r.S3.Bucket.Name != "bench" || r.S3.Object.URLDecodedKey != obj.Key || r.S3.Object.Size != DefaultSize {
+		t.Errorf("decoded record = %+v", r.S3)
+	}
+}
+
+type fakeDynamoDB struct {
+	exists  bool
+	creates int
+	items   map[string]bool
+	// throttleOnce leaves the first write of the first batch unprocessed.
+	throttleOnce bool
+}
+
+func (f *fakeDynamoDB) DescribeTable(_ context.Context, in *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if !f.exists {
+		return nil, &ddbtypes.ResourceNotFoundException{}
+	}
+	return &dynamodb.DescribeTableOutput{Table: &ddbtypes.TableDescription{
+		TableStatus: ddbtypes.TableStatusActive,
+		TableArn:    aws.String("arn:aws:dynamodb:us-east-1:123456789012:table/" + aws.ToString(in.TableName)),
+	}}, nil
+}
+
+func (f *fakeDynamoDB) CreateTable(_ context.Context, _ *dynamodb.CreateTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	f.exists = true
+	f.creates++
+	return &dynamodb.CreateTableOutput{}, nil
+}
+
+func (f *fakeDynamoDB) BatchWriteItem(_ context.Context, in *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	out := &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]ddbtypes.WriteRequest{}}
+	for table, reqs := range in.RequestItems {
+		if len(reqs) > batchSize {
+			return nil, fmt.Errorf("%d writes in one batch", len(reqs))
+		}
+		for i, r := range reqs {
+			if f.throttleOnce && i == 0 {
+				out.UnprocessedItems[table] = append(out.UnprocessedItems[table], r)
+				continue
+			}
+			f.items[r.PutRequest.Item["pk"].(*ddbtypes.AttributeValueMemberS).Value] = true
+		}
+	}
+	f.throttleOnce = false
+	return out, nil
+}
+
+func TestSeedTable(t *testing.T) {
+	f := &fakeDynamoDB{items: map[string]bool{}, throttleOnce: true}
+	arn, created, err := SeedTable(context.Background(), f, DefaultTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created || f.creates != 1 || arn == "" {
+		t.Errorf("created=%v creates=%d arn=%q", created, f.creates, arn)
+	}
+	if len(f.items) != TableItems || !f.items["item-000"] || !f.items["item-099"] {
+		t.Errorf("seeded %d items", len(f.items))
+	}
+	if _, created, err = SeedTable(context.Background(), f, DefaultTable); err != nil || created {
+		t.Errorf("reseeding: created=%v err=%v", created, err)
+	}
+}
+
+func TestCorpusIsBundled(t *testing.T) {
+	path := filepath.Join("..", "..", filepath.FromSlash(CorpusPath))
+	want := Corpus(CorpusSize)
+	if *update {
+		if err := os.WriteFile(path, want, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is stale; regenerate with go test ./pkg/fixture -run Corpus -update", CorpusPath)
+	}
+}
+
+func TestLogsAreBundled(t *testing.T) {
+	path := filepath.Join("..", "..", filepath.FromSlash(LogPath))
+	want := Logs(LogSize)
+	if *update {
+		if err := os.WriteFile(path, want, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is stale; regenerate with go test ./pkg/fixture -run Logs -update", LogPath)
+	}
+}
+
+func TestPayloadRoundTrips(t *testing.T) {
+	for _, size := range []int{MinPayload, MinPayload + 1, 1 << 10, 64 << 10, MaxPayload} {
+		p := Payload(size)
+		if len(p) != size {
+			t.Errorf("Payload(%d) is %d bytes", size, len(p))
+			continue
+		}
+		var doc any
+		if err := json.Unmarshal(p, &doc); err != nil {
+			t.Fatalf("Payload(%d): %v", size, err)
+		}
+		// What the Go echo handler does: the result must be the payload's.
+		again, err := json.Marshal(doc)
+		if err != nil || !bytes.Equal(again, p) {
+			t.Errorf("Payload(%d) does not re-encode to itself", size)
+		}
+	}
+	if !bytes.Equal(Payload(1<<10), Payload(1<<10)) {
+		t.Error("Payload differs between calls")
+	}
+	// Pinned: the manifest's expected result, for the default {} payload.
+	if got := EchoResult([]byte("{}")); got != "echo(2)=a3a6bf43" {
+		t.Errorf("EchoResult({}) = %s", got)
+	}
+}
+
+func TestOrdersAreBundled(t *testing.T) {
+	path := filepath.Join("..", "..", filepath.FromSlash(OrdersPath))
+	want := Orders(OrderRows)
+	if *update {
+		if err := os.WriteFile(path, want, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is stale; regenerate with go test ./pkg/fixture -run Orders -update", OrdersPath)
+	}
+}
+
+func TestPhotoIsBundled(t *testing.T) {
+	path := filepath.Join("..", "..", filepath.FromSlash(PhotoPath))
+	want := Photo(PhotoWidth, PhotoHeight)
+	if *update {
+		if err := os.WriteFile(path, want, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is stale; regenerate with go test ./pkg/fixture -run Photo -update", PhotoPath)
+	}
+}
@@ -0,0 +1,75 @@
+package fixture
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// LogSize is the approximate size of the log-parsing workload's log: 1 MB.
+const LogSize = 1 << 20
+
+// LogPath, relative to baselines/go, is the bundled log the logparse
+// workloads scan. Like the corpus it is checked in so every runtime matches
+// the same bytes; Logs regenerates it.
+const LogPath = "corpus/app.log"
+
+var (
+	methods  = []string{"GET", "GET", "GET", "GET", "POST", "POST", "PUT", "DELETE", "PATCH", "HEAD"}
+	paths    = []string{"/", "/health", "/api/orders", "/api/orders/%d", "/api/users/%d", "/api/cart", "/static/app.%x.js", "/login", "/search?q=item%d"}
+	statuses = []int{200, 200, 200, 200, 200, 200, 201, 204, 301, 304, 400, 401, 403, 404, 404, 429, 500, 502, 503}
+	agents   = []string{
+		"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36",
+		"curl/8.8.0",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+		"Site-Crawler/1.4 (+https://example.com/crawler)",
+		"python-requests/2.32.3",
+	}
+	levels   = []string{"DEBUG", "INFO", "INFO", "INFO", "INFO", "WARN", "ERROR"}
+	messages = []string{"order placed", "payment authorized", "cache miss", "retrying upstream call",
+		"session expired", "inventory reserved", "upstream timeout", "request validated"}
+	domains = []string{"example.com", "mail.example.org", "corp.example.net", "test.example.co.uk"}
+)
+
+// Logs returns a deterministic application log of at least size bytes:
+// combined-format access lines interleaved with key=value application
+// lines carrying ISO timestamps, levels, UUIDs, e-mail addresses and
+// durations, the things log pipelines match regular expressions against.
+// The bytes depend only on size.
+func Logs(size int) []byte {
+	rng := rand.New(rand.NewChaCha8(seed))
+	pick := func(xs []string) string { return xs[rng.IntN(len(xs))] }
+	uuid := func() string {
+		b := make([]byte, 16)
+		for i := range b {
+			b[i] = byte(rng.UintN(256))
+		}
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	}
+	at := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	var out bytes.Buffer
+	out.Grow(size + 512)
+	for out.Len() < size {
+		at = at.Add(time.Duration(rng.IntN(250)) * time.Millisecond)
+		if rng.IntN(2) == 0 {
+			path := pick(paths)
+			if strings.Contains(path, "%") {
+				path = fmt.Sprintf(path, rng.IntN(100000))
+			}
+			fmt.Fprintf(&out, "%d.%d.%d.%d - - [%s] \"%s %s HTTP/1.%d\" %d %d \"-\" \"%s\"\n",
+				rng.IntN(224)+1, rng.IntN(256), rng.IntN(256), rng.IntN(256), at.Format("02/Jan/2006:15:04:05 -0700"),
+				pick(methods), path, rng.IntN(2), statuses[rng.IntN(len(statuses))], rng.IntN(50000), pick(agents))
+			continue
+		}
+		fmt.Fprintf(&out, "%s level=%s request_id=%s user=user%d@%s msg=%q duration_ms=%d\n",
+			at.Format("2006-01-02T15:04:05.000Z"), pick(levels), uuid(), rng.IntN(5000), pick(domains),
+			pick(messages), int(rng.ExpFloat64()*120))
+	}
+	return out.Bytes()
+}
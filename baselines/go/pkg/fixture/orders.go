@@ -0,0 +1,48 @@
+package fixture
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// OrderRows is the number of rows of the ETL workload's order export.
+const OrderRows = 50000
+
+// OrdersPath, relative to baselines/go, is the bundled order export the
+// etl workload reads. Like the corpus it is checked in so every runtime
+// matches the same bytes; Orders regenerates it.
+const OrdersPath = "corpus/orders.csv"
+
+var (
+	regions    = []string{"us-east", "us-west", "eu-west", "eu-central", "ap-south", "ap-northeast"}
+	categories = []string{"books", "electronics", "garden", "grocery", "home", "outdoor", "toys", "apparel"}
+	// Most orders ship; the rest are spread over the statuses an export
+	// would filter out.
+	orderStatuses = []string{"shipped", "shipped", "shipped", "shipped", "shipped", "shipped", "pending", "cancelled", "returned"}
+	discounts     = []int{0, 0, 0, 0, 5, 10, 15, 20, 25}
+)
+
+// Orders returns a deterministic CSV export of rows orders, with a header:
+// an order ID, an RFC 3339 timestamp, a region, a category, a status, a
+// quantity, a unit price in dollars and cents and a discount percentage,
+// the mix of keys, strings and numbers an ETL job parses. The bytes depend
+// only on rows.
+func Orders(rows int) []byte {
+	rng := rand.New(rand.NewChaCha8(seed))
+	at := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	var out bytes.Buffer
+	out.Grow(rows * 72)
+	out.WriteString("order_id,ordered_at,region,category,status,quantity,unit_price,discount_pct\n")
+	for i := range rows {
+		at = at.Add(time.Duration(rng.IntN(90)) * time.Second)
+		cents := 199 + rng.IntN(49800)
+		fmt.Fprintf(&out, "%d,%s,%s,%s,%s,%d,%d.%02d,%d\n", 1000000+i, at.Format(time.RFC3339),
+			regions[rng.IntN(len(regions))], categories[rng.IntN(len(categories))],
+			orderStatuses[rng.IntN(len(orderStatuses))], 1+rng.IntN(12), cents/100, cents%100,
+			discounts[rng.IntN(len(discounts))])
+	}
+	return out.Bytes()
+}
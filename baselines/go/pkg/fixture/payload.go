@@ -0,0 +1,65 @@
+package fixture
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"math/rand/v2"
+	"strconv"
+)
+
+// EchoWorkload is the name of the payload round-trip workload
+// (main-echo.go, index-echo.py).
+const EchoWorkload = "echo"
+
+// Payload sizes, in bytes. A synchronous invocation's request must be
+// smaller than 6 MiB, so MaxPayload is the largest one Lambda accepts.
+const (
+	MaxPayload = 6<<20 - 1
+	// MinPayload is the smallest document Payload can size: an empty pad
+	// and no records.
+	MinPayload = len(`{"pad":"","records":[]}`)
+)
+
+// PayloadSizes are the sizes ruchy-bench payloads sweeps by default:
+// 1 KB to the Lambda maximum.
+var PayloadSizes = []int{1 << 10, 64 << 10, 256 << 10, 1 << 20, MaxPayload}
+
+// Payload returns a deterministic JSON document of exactly size bytes: an
+// array of small records, objects of integers, strings, a boolean and a
+// nested array, the shape of a typical API or queue payload, topped up to
+// size with a pad string. It is encoded canonically, compact with sorted
+// keys, ASCII strings that need no escaping and integers only, so any
+// runtime decoding it into generic values and encoding them back the same
+// way reproduces it byte for byte, and EchoResult is what its echo handler
+// reports. size must be at least MinPayload.
+func Payload(size int) []byte {
+	rng := rand.New(rand.NewChaCha8(seed))
+	var recs bytes.Buffer
+	for i := 0; ; i++ {
+		rec := fmt.Appendf(nil, `{"active":%t,"id":%d,"name":"record-%06d","tags":["t%d","t%d"],"value":%d}`,
+			rng.IntN(2) == 0, i, i, rng.IntN(16), rng.IntN(64), rng.IntN(1_000_000))
+		if i > 0 {
+			rec = append([]byte{','}, rec...)
+		}
+		if MinPayload+recs.Len()+len(rec) > size {
+			break
+		}
+		recs.Write(rec)
+	}
+	pad := max(size-MinPayload-recs.Len(), 0)
+	out := make([]byte, 0, size)
+	out = append(out, `{"pad":"`...)
+	out = append(out, bytes.Repeat([]byte{'x'}, pad)...)
+	out = append(out, `","records":[`...)
+	out = append(out, recs.Bytes()...)
+	return append(out, "]}"...)
+}
+
+// EchoResult is the result the echo workload reports for payload, which
+// it decodes and re-encodes compact with sorted keys: the length and
+// CRC-32 of the re-encoded document. For canonical documents, such as
+// Payload's and {}, that is the payload's own.
+func EchoResult(payload []byte) string {
+	return "echo(" + strconv.Itoa(len(payload)) + ")=" + fmt.Sprintf("%08x", crc32.ChecksumIEEE(payload))
+}
@@ -0,0 +1,69 @@
+package fixture
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"math/rand/v2"
+)
+
+// PhotoWidth and PhotoHeight are the dimensions of the image-resize
+// workload's photo: 12 megapixels, as a phone camera takes.
+const (
+	PhotoWidth  = 4000
+	PhotoHeight = 3000
+)
+
+// PhotoPath, relative to baselines/go, is the bundled JPEG the imgresize
+// workload decodes. Like the corpus it is checked in so every runtime
+// decodes the same bytes; Photo regenerates it.
+const PhotoPath = "corpus/photo.jpg"
+
+// PhotoQuality is the JPEG quality the photo is saved at, a camera's.
+const PhotoQuality = 90
+
+// Photo returns a deterministic width x height JPEG that compresses like
+// a photograph rather than a flat test card: a sky gradient over rolling
+// hills, shaded and textured with per-pixel sensor noise, so every 8x8
+// block carries detail. The bytes depend only on the dimensions and on
+// image/jpeg's encoder.
+func Photo(width, height int) []byte {
+	rng := rand.New(rand.NewChaCha8(seed))
+	img := image.NewYCbCr(image.Rect(0, 0, width, height), image.YCbCrSubsampleRatio420)
+	fw, fh := float64(width), float64(height)
+	for y := range height {
+		for x := range width {
+			u, v := float64(x)/fw, float64(y)/fh
+			ridge := 0.55 + 0.08*math.Sin(u*11+1) + 0.04*math.Sin(u*37+2) + 0.015*math.Sin(u*131)
+			var r, g, b float64
+			if v < ridge {
+				// Sky: lighter toward the horizon.
+				t := v / ridge
+				r, g, b = 90+110*t, 140+80*t, 220+25*t
+			} else {
+				// Hills: darker with depth, striped by field rows.
+				t := (v - ridge) / (1 - ridge)
+				rows := 0.5 + 0.5*math.Sin((v-ridge)*fh/9+math.Sin(u*23)*4)
+				r, g, b = 70-30*t+25*rows, 120-50*t+30*rows, 40-20*t+10*rows
+			}
+			n := rng.NormFloat64() * 6
+			yy, cb, cr := color.RGBToYCbCr(clamp(r+n), clamp(g+n), clamp(b+n))
+			img.Y[img.YOffset(x, y)] = yy
+			if x%2 == 0 && y%2 == 0 {
+				off := img.COffset(x, y)
+				img.Cb[off], img.Cr[off] = cb, cr
+			}
+		}
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: PhotoQuality}); err != nil {
+		panic(err) // writing to a bytes.Buffer does not fail
+	}
+	return out.Bytes()
+}
+
+func clamp(v float64) uint8 {
+	return uint8(math.Round(math.Min(255, math.Max(0, v))))
+}
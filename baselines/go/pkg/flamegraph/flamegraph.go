@@ -0,0 +1,222 @@
+// Package flamegraph renders profiles from every runtime as flame graphs
+// drawn alike, so that where two runtimes spend a workload's time can be
+// compared by eye. Profiles are read from the formats the runtimes'
+// profilers write: pprof for Go (see pkg/profiles), and for native
+// runtimes such as Rust and Ruchy the text output of perf script, DTrace
+// ustack() aggregations, or stacks already folded by Brendan Gregg's
+// stackcollapse scripts.
+//
+// Every format becomes a Profile of folded stacks. Profiles of the same
+// runtime merge by adding their stacks, so many short runs add up to one
+// graph. Graphs of different profilers have different units (pprof CPU
+// time against perf's sample counts), so widths are shares of each
+// graph's own total and only shapes compare across runtimes.
+package flamegraph
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Profile is a set of stacks with how much of the profiled resource each
+// accounts for.
+type Profile struct {
+	// Stacks holds values by folded stack: frame names from the root to
+	// the leaf, joined by semicolons.
+	Stacks map[string]float64
+	// Unit is what the values count, such as "nanoseconds" or "samples".
+	Unit string
+}
+
+// Total is the sum of the profile's values.
+func (p Profile) Total() float64 {
+	var total float64
+	for _, v := range p.Stacks {
+		total += v
+	}
+	return total
+}
+
+// add records value for the stack of frames, given leaf first as
+// profilers list them.
+func (p *Profile) add(leafFirst []string, value float64) {
+	if len(leafFirst) == 0 || value == 0 {
+		return
+	}
+	frames := make([]string, len(leafFirst))
+	for i, f := range leafFirst {
+		// Semicolons separate frames; C++ and Rust symbols may hold one.
+		frames[len(frames)-1-i] = strings.ReplaceAll(f, ";", ":")
+	}
+	if p.Stacks == nil {
+		p.Stacks = map[string]float64{}
+	}
+	p.Stacks[strings.Join(frames, ";")] += value
+}
+
+// Merge adds the stacks of ps into one profile. The unit is the first
+// profile's.
+func Merge(ps ...Profile) Profile {
+	var m Profile
+	for _, p := range ps {
+		if m.Unit == "" {
+			m.Unit = p.Unit
+		}
+		for stack, v := range p.Stacks {
+			if m.Stacks == nil {
+				m.Stacks = map[string]float64{}
+			}
+			m.Stacks[stack] += v
+		}
+	}
+	return m
+}
+
+// Parse reads a profile in any of the supported formats, telling them
+// apart by their content: pprof profiles are gzipped protocol buffers,
+// DTrace indents every frame, folded stacks end each line with a count,
+// and perf script starts each sample with an unindented header.
+func Parse(data []byte) (Profile, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		return ParsePprof(data)
+	}
+	first := ""
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		if strings.TrimSpace(sc.Text()) != "" {
+			first = sc.Text()
+			break
+		}
+	}
+	switch {
+	case first == "":
+		return Profile{}, fmt.Errorf("empty profile")
+	case first[0] == ' ' || first[0] == '\t':
+		return ParseDTrace(bytes.NewReader(data))
+	case foldedLine.MatchString(first):
+		return ParseFolded(bytes.NewReader(data))
+	}
+	return ParsePerf(bytes.NewReader(data))
+}
+
+var foldedLine = regexp.MustCompile(`^\S.* [0-9.]+$`)
+
+// ParseFolded reads folded stacks, one "root;...;leaf count" per line.
+func ParseFolded(r io.Reader) (Profile, error) {
+	p := Profile{Unit: "samples"}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			return Profile{}, fmt.Errorf("folded stacks line %d: no count", n)
+		}
+		v, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			return Profile{}, fmt.Errorf("folded stacks line %d: no count", n)
+		}
+		frames := strings.Split(line[:i], ";")
+		for l, r := 0, len(frames)-1; l < r; l, r = l+1, r-1 {
+			frames[l], frames[r] = frames[r], frames[l]
+		}
+		p.add(frames, v)
+	}
+	return p, sc.Err()
+}
+
+// ParsePerf reads the output of perf script for a profile recorded with
+// call graphs (perf record -g): samples separated by blank lines, each a
+// header line followed by one indented "address symbol+offset (object)"
+// line per frame, leaf first. Every sample counts one.
+func ParsePerf(r io.Reader) (Profile, error) {
+	p := Profile{Unit: "samples"}
+	var frames []string
+	inSample := false
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.TrimSpace(line) == "":
+			p.add(frames, 1)
+			frames, inSample = frames[:0], false
+		case line[0] != ' ' && line[0] != '\t':
+			if inSample {
+				// A sample without call graph: only its header.
+				p.add(frames, 1)
+				frames = frames[:0]
+			}
+			inSample = true
+		case inSample:
+			frames = append(frames, perfSymbol(line))
+		}
+	}
+	p.add(frames, 1)
+	if len(p.Stacks) == 0 {
+		return Profile{}, fmt.Errorf("no call graphs in perf script output; record with perf record -g")
+	}
+	return p, sc.Err()
+}
+
+// perfSymbol is the symbol of a perf script frame line, without its
+// offset, or the object's name for an unresolved address.
+func perfSymbol(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return "[unknown]"
+	}
+	sym := strings.Join(fields[1:], " ")
+	object := ""
+	if i := strings.LastIndex(sym, " ("); i >= 0 && strings.HasSuffix(sym, ")") {
+		sym, object = sym[:i], sym[i+2:len(sym)-1]
+	}
+	sym = stripOffset(sym)
+	if sym == "[unknown]" && object != "" {
+		return "[" + object[strings.LastIndexByte(object, '/')+1:] + "]"
+	}
+	return sym
+}
+
+// stripOffset removes the +0x1f of symbol+0x1f.
+func stripOffset(sym string) string {
+	if i := strings.LastIndex(sym, "+0x"); i > 0 {
+		return sym[:i]
+	}
+	return sym
+}
+
+// ParseDTrace reads the output of a DTrace aggregation of ustack() or
+// stack(), such as dtrace -n 'profile-997 { @[ustack()] = count(); }':
+// stacks of indented "module`function+offset" frames, leaf first, each
+// followed by its count.
+func ParseDTrace(r io.Reader) (Profile, error) {
+	p := Profile{Unit: "samples"}
+	var frames []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if v, err := strconv.ParseFloat(line, 64); err == nil {
+			p.add(frames, v)
+			frames = frames[:0]
+			continue
+		}
+		frames = append(frames, stripOffset(line))
+	}
+	if len(p.Stacks) == 0 {
+		return Profile{}, fmt.Errorf("no stacks in DTrace output")
+	}
+	return p, sc.Err()
+}
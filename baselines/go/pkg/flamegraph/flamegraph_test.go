@@ -0,0 +1,131 @@
+package flamegraph
+
+import (
+	"bytes"
+	"maps"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"testing"
+)
+
+func TestParseFolded(t *testing.T) {
+	p, err := Parse([]byte("main;fib;fib 30\nmain;fib 10\nmain;print 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Stacks["main;fib;fib"]; got != 30 {
+		t.Errorf("main;fib;fib = %v, want 30", got)
+	}
+	if got := p.Total(); got != 42 {
+		t.Errorf("total %v, want 42", got)
+	}
+}
+
+const perfScript = `fibonacci 4242 1000.000100:     250000 cpu-clock:u: 
+	    55d0c0a41139 fibonacci::fib+0x19 (/b/fibonacci)
+	    55d0c0a41150 fibonacci::fib+0x30 (/b/fibonacci)
+	    55d0c0a41200 main+0x10 (/b/fibonacci)
+
+fibonacci 4242 1000.000350:     250000 cpu-clock:u: 
+	    55d0c0a41150 fibonacci::fib+0x30 (/b/fibonacci)
+	    55d0c0a41200 main+0x10 (/b/fibonacci)
+
+fibonacci 4242 1000.000600:     250000 cpu-clock:u: 
+	    7f0000001000 [unknown] (/usr/lib64/libc.so.6)
+	    55d0c0a41200 main+0x10 (/b/fibonacci)
+`
+
+func TestParsePerf(t *testing.T) {
+	p, err := Parse([]byte(perfScript))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"main;fibonacci::fib;fibonacci::fib": 1,
+		"main;fibonacci::fib":                1,
+		"main;[libc.so.6]":                   1,
+	}
+	if !maps.Equal(p.Stacks, want) {
+		t.Errorf("stacks %v, want %v", p.Stacks, want)
+	}
+}
+
+const dtraceOutput = `
+
+              fibonacci` + "`" + `fib+0x19
+              fibonacci` + "`" + `main+0x2c
+               37
+
+              libc.so.1` + "`" + `_write+0x15
+              fibonacci` + "`" + `main+0x40
+                5
+`
+
+func TestParseDTrace(t *testing.T) {
+	p, err := Parse([]byte(dtraceOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"fibonacci`main;fibonacci`fib":    37,
+		"fibonacci`main;libc.so.1`_write": 5,
+	}
+	if !maps.Equal(p.Stacks, want) {
+		t.Errorf("stacks %v, want %v", p.Stacks, want)
+	}
+}
+
+//go:noinline
+func allocateForHeapProfile() []byte {
+	return make([]byte, 1<<20)
+}
+
+var kept []byte
+
+func TestParsePprof(t *testing.T) {
+	// A heap profile is deterministic where a CPU profile is not: with
+	// every allocation sampled, the megabyte below is in it.
+	defer func(rate int) { runtime.MemProfileRate = rate }(runtime.MemProfileRate)
+	runtime.MemProfileRate = 1
+	kept = allocateForHeapProfile()
+	runtime.GC()
+	var buf bytes.Buffer
+	if err := pprof.WriteHeapProfile(&buf); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Unit != "bytes" {
+		t.Errorf("unit %q, want bytes (inuse_space)", p.Unit)
+	}
+	var found float64
+	for stack, v := range p.Stacks {
+		if strings.HasSuffix(stack, ".allocateForHeapProfile") && strings.Contains(stack, ".TestParsePprof;") {
+			found += v
+		}
+	}
+	if found < 1<<20 {
+		t.Errorf("allocateForHeapProfile holds %v bytes, want at least 1 MB; stacks %v", found, p.Stacks)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	p, _ := ParseFolded(strings.NewReader("main;fib;fib 30\nmain;fib 10\nmain;print<1> 2\n"))
+	var buf bytes.Buffer
+	if err := SVG(&buf, "rust fibonacci", p); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	// all, main, fib, fib and print.
+	if n := strings.Count(out, "<rect"); n != 5 {
+		t.Errorf("%d frames, want 5", n)
+	}
+	for _, want := range []string{"rust fibonacci", "<title>fib (95.24%)</title>", "print&lt;1&gt; (4.76%)", `height="92"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("SVG lacks %s", want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package flamegraph
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ParsePprof reads a pprof profile, gzipped as Go writes them or not: the
+// profile.proto messages of github.com/google/pprof, decoded by hand for
+// the few fields a flame graph needs. Of several sample types it takes
+// the CPU time of a CPU profile and the in-use bytes of a heap profile,
+// otherwise the last.
+func ParsePprof(data []byte) (Profile, error) {
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return Profile{}, fmt.Errorf("pprof: %w", err)
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return Profile{}, fmt.Errorf("pprof: %w", err)
+		}
+	}
+	var (
+		strs      []string
+		types     [][2]int64 // type and unit string indexes
+		samples   []pbSample
+		locations = map[uint64][]uint64{} // location ID: function IDs, inlined first
+		functions = map[uint64]int64{}    // function ID: name string index
+	)
+	err := eachField(data, func(f field) error {
+		switch f.num {
+		case 1: // sample_type
+			var vt [2]int64
+			err := eachField(f.data, func(f field) error {
+				if f.num == 1 || f.num == 2 {
+					vt[f.num-1] = int64(f.v)
+				}
+				return nil
+			})
+			types = append(types, vt)
+			return err
+		case 2: // sample
+			var s pbSample
+			err := eachField(f.data, func(f field) error {
+				switch f.num {
+				case 1:
+					s.locations = f.appendVarints(s.locations)
+				case 2:
+					s.values = f.appendVarints(s.values)
+				}
+				return nil
+			})
+			samples = append(samples, s)
+			return err
+		case 4: // location
+			var (
+				id    uint64
+				funcs []uint64
+			)
+			err := eachField(f.data, func(f field) error {
+				switch f.num {
+				case 1:
+					id = f.v
+				case 4: // line
+					return eachField(f.data, func(f field) error {
+						if f.num == 1 {
+							funcs = append(funcs, f.v)
+						}
+						return nil
+					})
+				}
+				return nil
+			})
+			locations[id] = funcs
+			return err
+		case 5: // function
+			var id uint64
+			var name int64
+			err := eachField(f.data, func(f field) error {
+				switch f.num {
+				case 1:
+					id = f.v
+				case 2:
+					name = int64(f.v)
+				}
+				return nil
+			})
+			functions[id] = name
+			return err
+		case 6: // string_table
+			strs = append(strs, string(f.data))
+		}
+		return nil
+	})
+	if err != nil {
+		return Profile{}, fmt.Errorf("pprof: %w", err)
+	}
+	str := func(i int64) string {
+		if i < 0 || i >= int64(len(strs)) {
+			return ""
+		}
+		return strs[i]
+	}
+	if len(types) == 0 {
+		return Profile{}, errors.New("pprof: no sample types")
+	}
+	vi := len(types) - 1
+	for i, t := range types {
+		if name := str(t[0]); name == "cpu" || name == "inuse_space" {
+			vi = i
+		}
+	}
+	p := Profile{Unit: str(types[vi][1])}
+	var frames []string
+	for _, s := range samples {
+		if vi >= len(s.values) {
+			continue
+		}
+		frames = frames[:0]
+		for _, loc := range s.locations {
+			funcs := locations[loc]
+			if len(funcs) == 0 {
+				frames = append(frames, "[unknown]")
+			}
+			for _, fn := range funcs {
+				frames = append(frames, str(functions[fn]))
+			}
+		}
+		p.add(frames, float64(int64(s.values[vi])))
+	}
+	return p, nil
+}
+
+type pbSample struct {
+	locations, values []uint64
+}
+
+// field is one protocol buffer field: a varint in v, or length-delimited
+// bytes in data.
+type field struct {
+	num  int
+	wire int
+	v    uint64
+	data []byte
+}
+
+// appendVarints appends f's values to vs: one varint, or many packed ones.
+func (f field) appendVarints(vs []uint64) []uint64 {
+	if f.wire == 0 {
+		return append(vs, f.v)
+	}
+	for b := f.data; len(b) > 0; {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			break
+		}
+		vs, b = append(vs, v), b[n:]
+	}
+	return vs
+}
+
+// eachField calls fn with every field of the message in b, skipping fixed
+// 32- and 64-bit fields, which no field read here uses.
+func eachField(b []byte, fn func(field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errors.New("malformed field key")
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case 0:
+			if f.v, n = binary.Uvarint(b); n <= 0 {
+				return errors.New("malformed varint")
+			}
+			b = b[n:]
+		case 1:
+			if len(b) < 8 {
+				return io.ErrUnexpectedEOF
+			}
+			b = b[8:]
+			continue
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return io.ErrUnexpectedEOF
+			}
+			f.data, b = b[n:n+int(l)], b[n+int(l):]
+		case 5:
+			if len(b) < 4 {
+				return io.ErrUnexpectedEOF
+			}
+			b = b[4:]
+			continue
+		default:
+			return fmt.Errorf("unsupported wire type %d", f.wire)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package flamegraph
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"io"
+	"slices"
+	"strings"
+)
+
+// SVG drawing parameters. Width suits two graphs side by side on a wide
+// screen.
+const (
+	Width       = 800 // px
+	frameHeight = 16
+	titleHeight = 28
+	// minWidth is the narrowest frame drawn; narrower ones, and their
+	// callees, would only be noise.
+	minWidth = 0.3 // px
+	// labelChars is roughly how many characters of the 11px labels fit
+	// in a pixel of frame.
+	labelChars = 1.0 / 6.5
+)
+
+// node is a frame in the call tree, with the value of every stack through
+// it.
+type node struct {
+	name     string
+	value    float64
+	children map[string]*node
+}
+
+func tree(p Profile) *node {
+	root := &node{name: "all", children: map[string]*node{}}
+	for stack, v := range p.Stacks {
+		n := root
+		n.value += v
+		for _, frame := range strings.Split(stack, ";") {
+			c, ok := n.children[frame]
+			if !ok {
+				c = &node{name: frame, children: map[string]*node{}}
+				n.children[frame] = c
+			}
+			c.value += v
+			n = c
+		}
+	}
+	return root
+}
+
+// rect is a frame as drawn.
+type rect struct {
+	X, Y, W float64
+	Fill    string
+	Label   string
+	Title   string
+}
+
+// SVG writes p as a standalone flame graph: the root at the bottom and
+// each frame's callees above it, widths in proportion to their values and
+// callees sorted by name, as in Brendan Gregg's flamegraph.pl. Hovering a
+// frame shows its name and share of the total.
+func SVG(w io.Writer, title string, p Profile) error {
+	root := tree(p)
+	if root.value <= 0 {
+		return fmt.Errorf("%s: empty profile", title)
+	}
+	scale := Width / root.value
+	var rects []rect
+	depth := 0
+	var walk func(n *node, x float64, d int)
+	walk = func(n *node, x float64, d int) {
+		width := n.value * scale
+		if width < minWidth {
+			return
+		}
+		depth = max(depth, d)
+		label := ""
+		if chars := int(width * labelChars); chars >= 3 {
+			label = n.name
+			if len(label) > chars {
+				label = label[:chars-2] + ".."
+			}
+		}
+		rects = append(rects, rect{
+			X: x, Y: float64(d), W: width,
+			Fill:  color(n.name),
+			Label: label,
+			Title: fmt.Sprintf("%s (%.2f%%)", n.name, 100*n.value/root.value),
+		})
+		names := make([]string, 0, len(n.children))
+		for name := range n.children {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			c := n.children[name]
+			walk(c, x, d+1)
+			x += c.value * scale
+		}
+	}
+	walk(root, 0, 0)
+	height := titleHeight + (depth+1)*frameHeight
+	// Depth 0 is drawn at the bottom.
+	for i := range rects {
+		rects[i].Y = float64(height - (int(rects[i].Y)+1)*frameHeight)
+	}
+	return svg.Execute(w, struct {
+		Title         string
+		Width, Height int
+		Total         string
+		Rects         []rect
+	}{title, Width, height, total(p), rects})
+}
+
+// total describes the profile's total for the graph's subtitle.
+func total(p Profile) string {
+	t := p.Total()
+	switch p.Unit {
+	case "nanoseconds":
+		return fmt.Sprintf("%.2f s of CPU", t/1e9)
+	case "bytes":
+		return fmt.Sprintf("%.1f MB in use", t/(1<<20))
+	}
+	return fmt.Sprintf("%.0f %s", t, p.Unit)
+}
+
+// color gives each function a warm color of its own, the same in every
+// graph, so a function shared by two graphs is recognizable in both.
+func color(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+v%50, 80+(v>>8)%150, 30+(v>>16)%50)
+}
+
+var svg = template.Must(template.New("flamegraph").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" font-family="Verdana, sans-serif">
+<text x="{{.Width}}" dx="-4" y="18" text-anchor="end" font-size="11" fill="#868e96">{{.Total}}</text>
+<text x="4" y="18" font-size="14">{{.Title}}</text>
+{{- range .Rects}}
+<g><title>{{.Title}}</title><rect x="{{printf "%.2f" .X}}" y="{{printf "%.0f" .Y}}" width="{{printf "%.2f" .W}}" height="15" fill="{{.Fill}}"></rect>
+{{- if .Label}}<text x="{{printf "%.2f" .X}}" dx="3" y="{{printf "%.0f" .Y}}" dy="11.5" font-size="11">{{.Label}}</text>{{end}}</g>
+{{- end}}
+</svg>
+`))
+
+// Graph is one runtime's flame graph on a Page.
+type Graph struct {
+	Runtime string
+	// File is the SVG's path relative to the page, and Source says what
+	// the profile was made from.
+	File, Source string
+}
+
+// Page writes an HTML page showing graphs of workload side by side.
+func Page(w io.Writer, workload string, graphs []Graph) error {
+	return page.Execute(w, struct {
+		Workload string
+		Graphs   []Graph
+		Width    int
+	}{workload, graphs, Width})
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Flame graphs: {{.Workload}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem; color: #212529; }
+.graphs { display: flex; flex-wrap: wrap; gap: 1.5rem; align-items: flex-start; }
+figure { margin: 0; }
+figcaption { color: #868e96; font-size: 0.85rem; }
+object { width: {{.Width}}px; }
+</style>
+</head>
+<body>
+<h1>Flame graphs: {{.Workload}}</h1>
+<p>Frame widths are shares of each graph's own total; the profilers differ, so compare shapes, not sizes.</p>
+<div class="graphs">
+{{- range .Graphs}}
+<figure><object data="{{.File}}" type="image/svg+xml">{{.Runtime}}</object><figcaption>{{.Runtime}}: {{.Source}}</figcaption></figure>
+{{- end}}
+</div>
+</body>
+</html>
+`))
@@ -0,0 +1,623 @@
+// Package gc finds and deletes what the harness created in an AWS
+// account, including what crashed or interrupted runs left behind:
+// state machines, keep-warm rules, functions, their log groups, extension
+// layers, image repositories, fixture queues, tables and buckets, and
+// execution roles. Resources are
+// found by the deploy.TagKey tag the harness gives everything it creates,
+// so a resource of the same name created by hand is left alone. The two
+// kinds that cannot be tagged are matched by name instead: layers by
+// deploy.LayerPrefix, and the log groups Lambda creates by the name of
+// their function.
+package gc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/keepwarm"
+	"lambdaperf/pkg/stepfn"
+)
+
+// Kind is the kind of a harness resource.
+type Kind string
+
+// The kinds of resource collected, in the order Find lists them.
+const (
+	StateMachine Kind = "state-machine"
+	Rule         Kind = "rule"
+	Function     Kind = "function"
+	LogGroup     Kind = "log-group"
+	Layer        Kind = "layer"
+	Repository   Kind = "repository"
+	Queue        Kind = "queue"
+	Table        Kind = "table"
+	Bucket       Kind = "bucket"
+	Role         Kind = "role"
+)
+
+// logPrefix begins the name of every log group Lambda creates.
+const logPrefix = "/aws/lambda/"
+
+// Resource is one harness resource.
+type Resource struct {
+	Kind Kind
+	// Region is empty for roles, which IAM keeps globally.
+	Region string
+	Name   string
+	// URL is a queue's URL, which SQS identifies it by.
+	URL string
+	// ARN is a state machine's ARN, which Step Functions identifies it
+	// by.
+	ARN string
+}
+
+// StateMachineAPI is the subset of stepfn.Client used to collect state
+// machines.
+type StateMachineAPI interface {
+	List(ctx context.Context) ([]stepfn.StateMachine, error)
+	Tags(ctx context.Context, arn string) (map[string]string, error)
+	Delete(ctx context.Context, arn string) error
+}
+
+// RuleAPI is the subset of keepwarm.Client used to collect EventBridge
+// rules.
+type RuleAPI interface {
+	List(ctx context.Context, prefix string) ([]keepwarm.Rule, error)
+	Tags(ctx context.Context, arn string) (map[string]string, error)
+	Remove(ctx context.Context, name string) error
+}
+
+// LambdaAPI is the subset of the Lambda client used to collect functions
+// and layers.
+type LambdaAPI interface {
+	lambda.ListFunctionsAPIClient
+	lambda.ListLayersAPIClient
+	lambda.ListLayerVersionsAPIClient
+	lambda.ListEventSourceMappingsAPIClient
+	ListTags(ctx context.Context, in *lambda.ListTagsInput, opts ...func(*lambda.Options)) (*lambda.ListTagsOutput, error)
+	DeleteFunction(ctx context.Context, in *lambda.DeleteFunctionInput, opts ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error)
+	DeleteLayerVersion(ctx context.Context, in *lambda.DeleteLayerVersionInput, opts ...func(*lambda.Options)) (*lambda.DeleteLayerVersionOutput, error)
+	DeleteEventSourceMapping(ctx context.Context, in *lambda.DeleteEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.DeleteEventSourceMappingOutput, error)
+}
+
+// LogsAPI is the subset of the CloudWatch Logs client used to collect log
+// groups.
+type LogsAPI interface {
+	cloudwatchlogs.DescribeLogGroupsAPIClient
+	DeleteLogGroup(ctx context.Context, in *cloudwatchlogs.DeleteLogGroupInput, opts ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error)
+}
+
+// ECRAPI is the subset of the ECR client used to collect repositories.
+type ECRAPI interface {
+	ecr.DescribeRepositoriesAPIClient
+	ListTagsForResource(ctx context.Context, in *ecr.ListTagsForResourceInput, opts ...func(*ecr.Options)) (*ecr.ListTagsForResourceOutput, error)
+	DeleteRepository(ctx context.Context, in *ecr.DeleteRepositoryInput, opts ...func(*ecr.Options)) (*ecr.DeleteRepositoryOutput, error)
+}
+
+// QueueAPI is the subset of queue.Client used to collect queues.
+type QueueAPI interface {
+	List(ctx context.Context, prefix string) ([]string, error)
+	Tags(ctx context.Context, queueURL string) (map[string]string, error)
+	Delete(ctx context.Context, queueURL string) error
+}
+
+// DynamoDBAPI is the subset of the DynamoDB client used to collect tables.
+type DynamoDBAPI interface {
+	dynamodb.ListTablesAPIClient
+	DescribeTable(ctx context.Context, in *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+	ListTagsOfResource(ctx context.Context, in *dynamodb.ListTagsOfResourceInput, opts ...func(*dynamodb.Options)) (*dynamodb.ListTagsOfResourceOutput, error)
+	DeleteTable(ctx context.Context, in *dynamodb.DeleteTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error)
+}
+
+// S3API is the subset of the S3 client used to collect buckets.
+type S3API interface {
+	s3.ListBucketsAPIClient
+	s3.ListObjectsV2APIClient
+	GetBucketTagging(ctx context.Context, in *s3.GetBucketTaggingInput, opts ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error)
+	DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, opts ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	DeleteBucket(ctx context.Context, in *s3.DeleteBucketInput, opts ...func(*s3.Options)) (*s3.DeleteBucketOutput, error)
+}
+
+// IAMAPI is the subset of the IAM client used to collect roles.
+type IAMAPI interface {
+	iam.ListRolesAPIClient
+	iam.ListAttachedRolePoliciesAPIClient
+	iam.ListRolePoliciesAPIClient
+	ListRoleTags(ctx context.Context, in *iam.ListRoleTagsInput, opts ...func(*iam.Options)) (*iam.ListRoleTagsOutput, error)
+	DetachRolePolicy(ctx context.Context, in *iam.DetachRolePolicyInput, opts ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error)
+	DeleteRolePolicy(ctx context.Context, in *iam.DeleteRolePolicyInput, opts ...func(*iam.Options)) (*iam.DeleteRolePolicyOutput, error)
+	DeleteRole(ctx context.Context, in *iam.DeleteRoleInput, opts ...func(*iam.Options)) (*iam.DeleteRoleOutput, error)
+}
+
+// Collector finds and deletes the harness resources of one region, or
+// the global roles with only IAM set. A nil client skips its kinds.
+type Collector struct {
+	Region        string
+	StateMachines StateMachineAPI
+	Rules         RuleAPI
+	Lambda        LambdaAPI
+	Logs          LogsAPI
+	ECR           ECRAPI
+	Queues        QueueAPI
+	DynamoDB      DynamoDBAPI
+	S3            S3API
+	IAM           IAMAPI
+	// Prefixes begin the names of the functions the harness deploys,
+	// such as discover.FunctionPrefixes. A log group is collected with
+	// its tagged function, or, once the function is gone, if its name
+	// has one of them. Lambda@Edge replicas log to groups named after
+	// the function's region too, "/aws/lambda/us-east-1.<function>".
+	Prefixes []string
+}
+
+// Find lists the harness resources in the order Delete should remove
+// them: the state machines and rules invoking functions, functions before
+// the log groups they write to, the layers, repositories and fixtures
+// they use, and the roles they and the state machines run as.
+func (c *Collector) Find(ctx context.Context) ([]Resource, error) {
+	var found []Resource
+	add := func(kind Kind, names ...string) {
+		for _, name := range names {
+			found = append(found, Resource{Kind: kind, Region: c.Region, Name: name})
+		}
+	}
+	if c.StateMachines != nil {
+		machines, err := c.stateMachines(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range machines {
+			found = append(found, Resource{Kind: StateMachine, Region: c.Region, Name: m.Name, ARN: m.ARN})
+		}
+	}
+	if c.Rules != nil {
+		rules, err := c.rules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		add(Rule, rules...)
+	}
+	if c.Lambda != nil {
+		functions, harness, err := c.functions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		add(Function, harness...)
+		if c.Logs != nil {
+			groups, err := c.logGroups(ctx, functions, harness)
+			if err != nil {
+				return nil, err
+			}
+			add(LogGroup, groups...)
+		}
+		layers, err := c.layers(ctx)
+		if err != nil {
+			return nil, err
+		}
+		add(Layer, layers...)
+	}
+	if c.ECR != nil {
+		repos, err := c.repositories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		add(Repository, repos...)
+	}
+	if c.Queues != nil {
+		urls, err := c.queues(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range urls {
+			found = append(found, Resource{Kind: Queue, Region: c.Region, Name: u[strings.LastIndex(u, "/")+1:], URL: u})
+		}
+	}
+	if c.DynamoDB != nil {
+		tables, err := c.tables(ctx)
+		if err != nil {
+			return nil, err
+		}
+		add(Table, tables...)
+	}
+	if c.S3 != nil {
+		buckets, err := c.buckets(ctx)
+		if err != nil {
+			return nil, err
+		}
+		add(Bucket, buckets...)
+	}
+	if c.IAM != nil {
+		roles, err := c.roles(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range roles {
+			found = append(found, Resource{Kind: Role, Name: name})
+		}
+	}
+	return found, nil
+}
+
+// Delete removes r, which Find returned, along with what would keep it
+// from being deleted: a function's event source mappings, every version
+// of a layer, a bucket's objects and a role's policies.
+func (c *Collector) Delete(ctx context.Context, r Resource) error {
+	var err error
+	switch r.Kind {
+	case StateMachine:
+		err = c.StateMachines.Delete(ctx, r.ARN)
+	case Rule:
+		err = c.Rules.Remove(ctx, r.Name)
+	case Function:
+		err = c.deleteFunction(ctx, r.Name)
+	case LogGroup:
+		_, err = c.Logs.DeleteLogGroup(ctx, &cloudwatchlogs.DeleteLogGroupInput{LogGroupName: aws.String(r.Name)})
+	case Layer:
+		err = c.deleteLayer(ctx, r.Name)
+	case Repository:
+		// Force deletes the images with it.
+		_, err = c.ECR.DeleteRepository(ctx, &ecr.DeleteRepositoryInput{RepositoryName: aws.String(r.Name), Force: true})
+	case Queue:
+		err = c.Queues.Delete(ctx, r.URL)
+	case Table:
+		_, err = c.DynamoDB.DeleteTable(ctx, &dynamodb.DeleteTableInput{TableName: aws.String(r.Name)})
+	case Bucket:
+		err = c.deleteBucket(ctx, r.Name)
+	case Role:
+		err = c.deleteRole(ctx, r.Name)
+	default:
+		err = errors.New("unknown kind")
+	}
+	if err != nil {
+		return fmt.Errorf("delete %s %s: %w", r.Kind, r.Name, err)
+	}
+	return nil
+}
+
+// tagged reports whether tags hold deploy.TagKey.
+func tagged(tags map[string]string) bool {
+	_, ok := tags[deploy.TagKey]
+	return ok
+}
+
+func (c *Collector) stateMachines(ctx context.Context) ([]stepfn.StateMachine, error) {
+	all, err := c.StateMachines.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var machines []stepfn.StateMachine
+	for _, m := range all {
+		tags, err := c.StateMachines.Tags(ctx, m.ARN)
+		if err != nil {
+			return nil, err
+		}
+		if tagged(tags) {
+			machines = append(machines, m)
+		}
+	}
+	return machines, nil
+}
+
+func (c *Collector) rules(ctx context.Context) ([]string, error) {
+	all, err := c.Rules.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	var rules []string
+	for _, r := range all {
+		tags, err := c.Rules.Tags(ctx, r.ARN)
+		if err != nil {
+			return nil, err
+		}
+		if tagged(tags) {
+			rules = append(rules, r.Name)
+		}
+	}
+	return rules, nil
+}
+
+// functions returns every function in the region and the tagged ones.
+func (c *Collector) functions(ctx context.Context) (all, harness []string, err error) {
+	pages := lambda.NewListFunctionsPaginator(c.Lambda, &lambda.ListFunctionsInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("list functions: %w", err)
+		}
+		for _, f := range page.Functions {
+			name := aws.ToString(f.FunctionName)
+			all = append(all, name)
+			tags, err := c.Lambda.ListTags(ctx, &lambda.ListTagsInput{Resource: f.FunctionArn})
+			if err != nil {
+				return nil, nil, fmt.Errorf("list tags of %s: %w", name, err)
+			}
+			if tagged(tags.Tags) {
+				harness = append(harness, name)
+			}
+		}
+	}
+	return all, harness, nil
+}
+
+// logGroups returns the log groups of the harness functions among
+// functions, and of harness functions already gone.
+func (c *Collector) logGroups(ctx context.Context, functions, harness []string) ([]string, error) {
+	var groups []string
+	pages := cloudwatchlogs.NewDescribeLogGroupsPaginator(c.Logs, &cloudwatchlogs.DescribeLogGroupsInput{LogGroupNamePrefix: aws.String(logPrefix)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describe log groups: %w", err)
+		}
+		for _, g := range page.LogGroups {
+			name := aws.ToString(g.LogGroupName)
+			fn := strings.TrimPrefix(name, logPrefix)
+			// Function names hold no dots; a replica's group is prefixed
+			// with the function's region and one.
+			if i := strings.LastIndexByte(fn, '.'); i >= 0 {
+				fn = fn[i+1:]
+			}
+			gone := !slices.Contains(functions, fn) && slices.ContainsFunc(c.Prefixes, func(p string) bool { return strings.HasPrefix(fn, p) })
+			if slices.Contains(harness, fn) || gone {
+				groups = append(groups, name)
+			}
+		}
+	}
+	return groups, nil
+}
+
+func (c *Collector) layers(ctx context.Context) ([]string, error) {
+	var layers []string
+	pages := lambda.NewListLayersPaginator(c.Lambda, &lambda.ListLayersInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list layers: %w", err)
+		}
+		for _, l := range page.Layers {
+			if name := aws.ToString(l.LayerName); strings.HasPrefix(name, deploy.LayerPrefix) {
+				layers = append(layers, name)
+			}
+		}
+	}
+	return layers, nil
+}
+
+func (c *Collector) repositories(ctx context.Context) ([]string, error) {
+	var repos []string
+	pages := ecr.NewDescribeRepositoriesPaginator(c.ECR, &ecr.DescribeRepositoriesInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describe repositories: %w", err)
+		}
+		for _, r := range page.Repositories {
+			name := aws.ToString(r.RepositoryName)
+			out, err := c.ECR.ListTagsForResource(ctx, &ecr.ListTagsForResourceInput{ResourceArn: r.RepositoryArn})
+			if err != nil {
+				return nil, fmt.Errorf("list tags of %s: %w", name, err)
+			}
+			if slices.ContainsFunc(out.Tags, func(t ecrtypes.Tag) bool { return aws.ToString(t.Key) == deploy.TagKey }) {
+				repos = append(repos, name)
+			}
+		}
+	}
+	return repos, nil
+}
+
+func (c *Collector) queues(ctx context.Context) ([]string, error) {
+	all, err := c.Queues.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("list queues: %w", err)
+	}
+	var urls []string
+	for _, u := range all {
+		tags, err := c.Queues.Tags(ctx, u)
+		if err != nil {
+			return nil, fmt.Errorf("list tags of %s: %w", u, err)
+		}
+		if tagged(tags) {
+			urls = append(urls, u)
+		}
+	}
+	return urls, nil
+}
+
+func (c *Collector) tables(ctx context.Context) ([]string, error) {
+	var tables []string
+	pages := dynamodb.NewListTablesPaginator(c.DynamoDB, &dynamodb.ListTablesInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list tables: %w", err)
+		}
+		for _, name := range page.TableNames {
+			desc, err := c.DynamoDB.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+			if err != nil {
+				return nil, fmt.Errorf("describe table %s: %w", name, err)
+			}
+			in := &dynamodb.ListTagsOfResourceInput{ResourceArn: desc.Table.TableArn}
+			for {
+				out, err := c.DynamoDB.ListTagsOfResource(ctx, in)
+				if err != nil {
+					return nil, fmt.Errorf("list tags of %s: %w", name, err)
+				}
+				if slices.ContainsFunc(out.Tags, func(t ddbtypes.Tag) bool { return aws.ToString(t.Key) == deploy.TagKey }) {
+					tables = append(tables, name)
+					break
+				}
+				if out.NextToken == nil {
+					break
+				}
+				in.NextToken = out.NextToken
+			}
+		}
+	}
+	return tables, nil
+}
+
+func (c *Collector) buckets(ctx context.Context) ([]string, error) {
+	var buckets []string
+	// Buckets are listed account-wide; only the region's are collected.
+	pages := s3.NewListBucketsPaginator(c.S3, &s3.ListBucketsInput{BucketRegion: aws.String(c.Region)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list buckets: %w", err)
+		}
+		for _, b := range page.Buckets {
+			name := aws.ToString(b.Name)
+			out, err := c.S3.GetBucketTagging(ctx, &s3.GetBucketTaggingInput{Bucket: b.Name})
+			var api interface{ ErrorCode() string }
+			switch {
+			case errors.As(err, &api) && api.ErrorCode() == "NoSuchTagSet":
+				continue
+			case err != nil:
+				return nil, fmt.Errorf("get tags of %s: %w", name, err)
+			}
+			if slices.ContainsFunc(out.TagSet, func(t s3types.Tag) bool { return aws.ToString(t.Key) == deploy.TagKey }) {
+				buckets = append(buckets, name)
+			}
+		}
+	}
+	return buckets, nil
+}
+
+func (c *Collector) roles(ctx context.Context) ([]string, error) {
+	var roles []string
+	pages := iam.NewListRolesPaginator(c.IAM, &iam.ListRolesInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list roles: %w", err)
+		}
+		for _, r := range page.Roles {
+			name := aws.ToString(r.RoleName)
+			// A role holds at most 50 tags, fewer than one page.
+			out, err := c.IAM.ListRoleTags(ctx, &iam.ListRoleTagsInput{RoleName: r.RoleName})
+			if err != nil {
+				return nil, fmt.Errorf("list tags of %s: %w", name, err)
+			}
+			if slices.ContainsFunc(out.Tags, func(t iamtypes.Tag) bool { return aws.ToString(t.Key) == deploy.TagKey }) {
+				roles = append(roles, name)
+			}
+		}
+	}
+	return roles, nil
+}
+
+// deleteFunction deletes the function's event source mappings, which
+// outlive it, then the function with all its versions and aliases.
+func (c *Collector) deleteFunction(ctx context.Context, name string) error {
+	pages := lambda.NewListEventSourceMappingsPaginator(c.Lambda, &lambda.ListEventSourceMappingsInput{FunctionName: aws.String(name)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list event source mappings: %w", err)
+		}
+		for _, m := range page.EventSourceMappings {
+			if _, err := c.Lambda.DeleteEventSourceMapping(ctx, &lambda.DeleteEventSourceMappingInput{UUID: m.UUID}); err != nil {
+				return fmt.Errorf("delete event source mapping %s: %w", aws.ToString(m.UUID), err)
+			}
+		}
+	}
+	_, err := c.Lambda.DeleteFunction(ctx, &lambda.DeleteFunctionInput{FunctionName: aws.String(name)})
+	return err
+}
+
+// deleteLayer deletes every version of the layer; the layer goes with
+// its last.
+func (c *Collector) deleteLayer(ctx context.Context, name string) error {
+	var versions []int64
+	pages := lambda.NewListLayerVersionsPaginator(c.Lambda, &lambda.ListLayerVersionsInput{LayerName: aws.String(name)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list versions: %w", err)
+		}
+		for _, v := range page.LayerVersions {
+			versions = append(versions, v.Version)
+		}
+	}
+	for _, v := range versions {
+		if _, err := c.Lambda.DeleteLayerVersion(ctx, &lambda.DeleteLayerVersionInput{LayerName: aws.String(name), VersionNumber: aws.Int64(v)}); err != nil {
+			return fmt.Errorf("delete version %d: %w", v, err)
+		}
+	}
+	return nil
+}
+
+// deleteBucket empties the bucket, which S3 requires, and deletes it.
+func (c *Collector) deleteBucket(ctx context.Context, name string) error {
+	pages := s3.NewListObjectsV2Paginator(c.S3, &s3.ListObjectsV2Input{Bucket: aws.String(name)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list objects: %w", err)
+		}
+		if len(page.Contents) == 0 {
+			continue
+		}
+		objects := make([]s3types.ObjectIdentifier, len(page.Contents))
+		for i, o := range page.Contents {
+			objects[i] = s3types.ObjectIdentifier{Key: o.Key}
+		}
+		out, err := c.S3.DeleteObjects(ctx, &s3.DeleteObjectsInput{Bucket: aws.String(name), Delete: &s3types.Delete{Objects: objects, Quiet: aws.Bool(true)}})
+		if err != nil {
+			return fmt.Errorf("delete objects: %w", err)
+		}
+		if len(out.Errors) > 0 {
+			e := out.Errors[0]
+			return fmt.Errorf("delete object %s: %s", aws.ToString(e.Key), aws.ToString(e.Message))
+		}
+	}
+	_, err := c.S3.DeleteBucket(ctx, &s3.DeleteBucketInput{Bucket: aws.String(name)})
+	return err
+}
+
+// deleteRole detaches the role's managed policies and deletes its inline
+// ones, which IAM requires, then the role.
+func (c *Collector) deleteRole(ctx context.Context, name string) error {
+	attached := iam.NewListAttachedRolePoliciesPaginator(c.IAM, &iam.ListAttachedRolePoliciesInput{RoleName: aws.String(name)})
+	for attached.HasMorePages() {
+		page, err := attached.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list attached policies: %w", err)
+		}
+		for _, p := range page.AttachedPolicies {
+			if _, err := c.IAM.DetachRolePolicy(ctx, &iam.DetachRolePolicyInput{RoleName: aws.String(name), PolicyArn: p.PolicyArn}); err != nil {
+				return fmt.Errorf("detach %s: %w", aws.ToString(p.PolicyName), err)
+			}
+		}
+	}
+	inline := iam.NewListRolePoliciesPaginator(c.IAM, &iam.ListRolePoliciesInput{RoleName: aws.String(name)})
+	for inline.HasMorePages() {
+		page, err := inline.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("list inline policies: %w", err)
+		}
+		for _, p := range page.PolicyNames {
+			if _, err := c.IAM.DeleteRolePolicy(ctx, &iam.DeleteRolePolicyInput{RoleName: aws.String(name), PolicyName: aws.String(p)}); err != nil {
+				return fmt.Errorf("delete policy %s: %w", p, err)
+			}
+		}
+	}
+	_, err := c.IAM.DeleteRole(ctx, &iam.DeleteRoleInput{RoleName: aws.String(name)})
+	return err
+}
@@ -0,0 +1,314 @@
+package gc
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/keepwarm"
+	"lambdaperf/pkg/stepfn"
+)
+
+type fakeLambda struct {
+	// functions maps every function name to its tags.
+	functions map[string]map[string]string
+	layers    map[string][]int64
+	mappings  map[string][]string
+	deleted   []string
+}
+
+func (f *fakeLambda) ListFunctions(_ context.Context, _ *lambda.ListFunctionsInput, _ ...func(*lambda.Options)) (*lambda.ListFunctionsOutput, error) {
+	out := &lambda.ListFunctionsOutput{}
+	for name := range f.functions {
+		out.Functions = append(out.Functions, types.FunctionConfiguration{FunctionName: aws.String(name), FunctionArn: aws.String("arn:" + name)})
+	}
+	return out, nil
+}
+
+func (f *fakeLambda) ListTags(_ context.Context, in *lambda.ListTagsInput, _ ...func(*lambda.Options)) (*lambda.ListTagsOutput, error) {
+	return &lambda.ListTagsOutput{Tags: f.functions[aws.ToString(in.Resource)[len("arn:"):]]}, nil
+}
+
+func (f *fakeLambda) DeleteFunction(_ context.Context, in *lambda.DeleteFunctionInput, _ ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error) {
+	f.deleted = append(f.deleted, "function "+aws.ToString(in.FunctionName))
+	return &lambda.DeleteFunctionOutput{}, nil
+}
+
+func (f *fakeLambda) ListLayers(_ context.Context, _ *lambda.ListLayersInput, _ ...func(*lambda.Options)) (*lambda.ListLayersOutput, error) {
+	out := &lambda.ListLayersOutput{}
+	for name := range f.layers {
+		out.Layers = append(out.Layers, types.LayersListItem{LayerName: aws.String(name)})
+	}
+	return out, nil
+}
+
+func (f *fakeLambda) ListLayerVersions(_ context.Context, in *lambda.ListLayerVersionsInput, _ ...func(*lambda.Options)) (*lambda.ListLayerVersionsOutput, error) {
+	out := &lambda.ListLayerVersionsOutput{}
+	for _, v := range f.layers[aws.ToString(in.LayerName)] {
+		out.LayerVersions = append(out.LayerVersions, types.LayerVersionsListItem{Version: v})
+	}
+	return out, nil
+}
+
+func (f *fakeLambda) DeleteLayerVersion(_ context.Context, in *lambda.DeleteLayerVersionInput, _ ...func(*lambda.Options)) (*lambda.DeleteLayerVersionOutput, error) {
+	f.deleted = append(f.deleted, "layer version "+aws.ToString(in.LayerName))
+	return &lambda.DeleteLayerVersionOutput{}, nil
+}
+
+func (f *fakeLambda) ListEventSourceMappings(_ context.Context, in *lambda.ListEventSourceMappingsInput, _ ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error) {
+	out := &lambda.ListEventSourceMappingsOutput{}
+	for _, id := range f.mappings[aws.ToString(in.FunctionName)] {
+		out.EventSourceMappings = append(out.EventSourceMappings, types.EventSourceMappingConfiguration{UUID: aws.String(id)})
+	}
+	return out, nil
+}
+
+func (f *fakeLambda) DeleteEventSourceMapping(_ context.Context, in *lambda.DeleteEventSourceMappingInput, _ ...func(*lambda.Options)) (*lambda.DeleteEventSourceMappingOutput, error) {
+	f.deleted = append(f.deleted, "mapping "+aws.ToString(in.UUID))
+	return &lambda.DeleteEventSourceMappingOutput{}, nil
+}
+
+type fakeLogs struct{ groups []string }
+
+func (f *fakeLogs) DescribeLogGroups(_ context.Context, _ *cloudwatchlogs.DescribeLogGroupsInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	out := &cloudwatchlogs.DescribeLogGroupsOutput{}
+	for _, g := range f.groups {
+		out.LogGroups = append(out.LogGroups, logtypes.LogGroup{LogGroupName: aws.String(g)})
+	}
+	return out, nil
+}
+
+func (f *fakeLogs) DeleteLogGroup(_ context.Context, _ *cloudwatchlogs.DeleteLogGroupInput, _ ...func(*cloudwatchlogs.Options)) (*cloudwatchlogs.DeleteLogGroupOutput, error) {
+	return &cloudwatchlogs.DeleteLogGroupOutput{}, nil
+}
+
+type fakeQueues struct {
+	tags    map[string]map[string]string
+	deleted []string
+}
+
+func (f *fakeQueues) List(_ context.Context, _ string) ([]string, error) {
+	var urls []string
+	for u := range f.tags {
+		urls = append(urls, u)
+	}
+	slices.Sort(urls)
+	return urls, nil
+}
+
+func (f *fakeQueues) Tags(_ context.Context, queueURL string) (map[string]string, error) {
+	return f.tags[queueURL], nil
+}
+
+func (f *fakeQueues) Delete(_ context.Context, queueURL string) error {
+	f.deleted = append(f.deleted, queueURL)
+	return nil
+}
+
+type fakeStateMachines struct {
+	tags    map[string]map[string]string
+	deleted []string
+}
+
+func (f *fakeStateMachines) List(_ context.Context) ([]stepfn.StateMachine, error) {
+	var machines []stepfn.StateMachine
+	for arn := range f.tags {
+		machines = append(machines, stepfn.StateMachine{Name: arn[strings.LastIndex(arn, ":")+1:], ARN: arn})
+	}
+	slices.SortFunc(machines, func(a, b stepfn.StateMachine) int { return strings.Compare(a.ARN, b.ARN) })
+	return machines, nil
+}
+
+func (f *fakeStateMachines) Tags(_ context.Context, arn string) (map[string]string, error) {
+	return f.tags[arn], nil
+}
+
+func (f *fakeStateMachines) Delete(_ context.Context, arn string) error {
+	f.deleted = append(f.deleted, arn)
+	return nil
+}
+
+type fakeRules struct {
+	tags    map[string]map[string]string
+	removed []string
+}
+
+func (f *fakeRules) List(_ context.Context, _ string) ([]keepwarm.Rule, error) {
+	var rules []keepwarm.Rule
+	for arn := range f.tags {
+		rules = append(rules, keepwarm.Rule{Name: arn[strings.LastIndex(arn, "/")+1:], ARN: arn})
+	}
+	slices.SortFunc(rules, func(a, b keepwarm.Rule) int { return strings.Compare(a.ARN, b.ARN) })
+	return rules, nil
+}
+
+func (f *fakeRules) Tags(_ context.Context, arn string) (map[string]string, error) {
+	return f.tags[arn], nil
+}
+
+func (f *fakeRules) Remove(_ context.Context, name string) error {
+	f.removed = append(f.removed, name)
+	return nil
+}
+
+type fakeIAM struct {
+	// roles maps every role name to its tags.
+	roles   map[string][]iamtypes.Tag
+	calls   []string
+	managed []string
+	inline  []string
+}
+
+func (f *fakeIAM) ListRoles(_ context.Context, _ *iam.ListRolesInput, _ ...func(*iam.Options)) (*iam.ListRolesOutput, error) {
+	out := &iam.ListRolesOutput{}
+	for name := range f.roles {
+		out.Roles = append(out.Roles, iamtypes.Role{RoleName: aws.String(name)})
+	}
+	return out, nil
+}
+
+func (f *fakeIAM) ListRoleTags(_ context.Context, in *iam.ListRoleTagsInput, _ ...func(*iam.Options)) (*iam.ListRoleTagsOutput, error) {
+	return &iam.ListRoleTagsOutput{Tags: f.roles[aws.ToString(in.RoleName)]}, nil
+}
+
+func (f *fakeIAM) ListAttachedRolePolicies(_ context.Context, _ *iam.ListAttachedRolePoliciesInput, _ ...func(*iam.Options)) (*iam.ListAttachedRolePoliciesOutput, error) {
+	out := &iam.ListAttachedRolePoliciesOutput{}
+	for _, arn := range f.managed {
+		out.AttachedPolicies = append(out.AttachedPolicies, iamtypes.AttachedPolicy{PolicyArn: aws.String(arn)})
+	}
+	return out, nil
+}
+
+func (f *fakeIAM) ListRolePolicies(_ context.Context, _ *iam.ListRolePoliciesInput, _ ...func(*iam.Options)) (*iam.ListRolePoliciesOutput, error) {
+	return &iam.ListRolePoliciesOutput{PolicyNames: f.inline}, nil
+}
+
+func (f *fakeIAM) DetachRolePolicy(_ context.Context, in *iam.DetachRolePolicyInput, _ ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error) {
+	f.calls = append(f.calls, "detach "+aws.ToString(in.PolicyArn))
+	return &iam.DetachRolePolicyOutput{}, nil
+}
+
+func (f *fakeIAM) DeleteRolePolicy(_ context.Context, in *iam.DeleteRolePolicyInput, _ ...func(*iam.Options)) (*iam.DeleteRolePolicyOutput, error) {
+	f.calls = append(f.calls, "delete policy "+aws.ToString(in.PolicyName))
+	return &iam.DeleteRolePolicyOutput{}, nil
+}
+
+func (f *fakeIAM) DeleteRole(_ context.Context, in *iam.DeleteRoleInput, _ ...func(*iam.Options)) (*iam.DeleteRoleOutput, error) {
+	f.calls = append(f.calls, "delete role "+aws.ToString(in.RoleName))
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+func TestFind(t *testing.T) {
+	tag := map[string]string{deploy.TagKey: "true"}
+	c := &Collector{
+		Region: "eu-west-1",
+		StateMachines: &fakeStateMachines{tags: map[string]map[string]string{
+			"arn:sm:ruchy-bench-baseline-go-fibonacci": tag,
+			"arn:sm:orders": nil,
+		}},
+		Rules: &fakeRules{tags: map[string]map[string]string{
+			"arn:rule/ruchy-bench-keepwarm-baseline-go-fibonacci": tag,
+			"arn:rule/nightly": nil,
+		}},
+		Lambda: &fakeLambda{
+			functions: map[string]map[string]string{
+				"baseline-go-fibonacci": tag,
+				// Created by hand under a harness name: kept.
+				"baseline-go": nil,
+				"unrelated":   nil,
+			},
+			layers: map[string][]int64{deploy.LayerPrefix + "telemetry": {1, 2}, "other": {1}},
+		},
+		Logs: &fakeLogs{groups: []string{
+			"/aws/lambda/baseline-go-fibonacci",
+			"/aws/lambda/baseline-go",
+			// Its function was torn down earlier.
+			"/aws/lambda/ruchy-lambda-tree",
+			"/aws/lambda/us-east-1.baseline-python-edge",
+			"/aws/lambda/unrelated",
+			"/aws/lambda/gone",
+		}},
+		Queues: &fakeQueues{tags: map[string]map[string]string{
+			"https://sqs/1/ruchy-bench-sqs": tag,
+			"https://sqs/1/mine":            {"team": "x"},
+		}},
+		IAM: &fakeIAM{roles: map[string][]iamtypes.Tag{
+			deploy.DefaultRoleName: {{Key: aws.String(deploy.TagKey), Value: aws.String("true")}},
+			"admin":                nil,
+		}},
+		Prefixes: []string{"ruchy-lambda-", "baseline-"},
+	}
+	got, err := c.Find(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Resource{
+		{Kind: StateMachine, Region: "eu-west-1", Name: "ruchy-bench-baseline-go-fibonacci", ARN: "arn:sm:ruchy-bench-baseline-go-fibonacci"},
+		{Kind: Rule, Region: "eu-west-1", Name: "ruchy-bench-keepwarm-baseline-go-fibonacci"},
+		{Kind: Function, Region: "eu-west-1", Name: "baseline-go-fibonacci"},
+		{Kind: LogGroup, Region: "eu-west-1", Name: "/aws/lambda/baseline-go-fibonacci"},
+		{Kind: LogGroup, Region: "eu-west-1", Name: "/aws/lambda/ruchy-lambda-tree"},
+		{Kind: LogGroup, Region: "eu-west-1", Name: "/aws/lambda/us-east-1.baseline-python-edge"},
+		{Kind: Layer, Region: "eu-west-1", Name: deploy.LayerPrefix + "telemetry"},
+		{Kind: Queue, Region: "eu-west-1", Name: "ruchy-bench-sqs", URL: "https://sqs/1/ruchy-bench-sqs"},
+		{Kind: Role, Name: deploy.DefaultRoleName},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Find =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	l := &fakeLambda{
+		layers:   map[string][]int64{"ruchy-bench-telemetry": {1, 2}},
+		mappings: map[string][]string{"baseline-go-sqs": {"m-1"}},
+	}
+	q := &fakeQueues{}
+	sm := &fakeStateMachines{}
+	rules := &fakeRules{}
+	i := &fakeIAM{managed: []string{"arn:logs"}, inline: []string{"fixtures"}}
+	c := &Collector{StateMachines: sm, Rules: rules, Lambda: l, Queues: q, IAM: i}
+	ctx := context.Background()
+	for _, r := range []Resource{
+		{Kind: StateMachine, Name: "ruchy-bench-baseline-go-sqs", ARN: "arn:sm:ruchy-bench-baseline-go-sqs"},
+		{Kind: Rule, Name: "ruchy-bench-keepwarm-baseline-go-sqs"},
+		{Kind: Function, Name: "baseline-go-sqs"},
+		{Kind: Layer, Name: "ruchy-bench-telemetry"},
+		{Kind: Queue, Name: "ruchy-bench-sqs", URL: "https://sqs/1/ruchy-bench-sqs"},
+		{Kind: Role, Name: deploy.DefaultRoleName},
+	} {
+		if err := c.Delete(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Mappings outlive their function, so they go first.
+	if want := []string{"mapping m-1", "function baseline-go-sqs", "layer version ruchy-bench-telemetry", "layer version ruchy-bench-telemetry"}; !slices.Equal(l.deleted, want) {
+		t.Errorf("Lambda deleted %q, want %q", l.deleted, want)
+	}
+	if !slices.Equal(sm.deleted, []string{"arn:sm:ruchy-bench-baseline-go-sqs"}) {
+		t.Errorf("state machines deleted %q", sm.deleted)
+	}
+	if !slices.Equal(rules.removed, []string{"ruchy-bench-keepwarm-baseline-go-sqs"}) {
+		t.Errorf("rules removed %q", rules.removed)
+	}
+	if !slices.Equal(q.deleted, []string{"https://sqs/1/ruchy-bench-sqs"}) {
+		t.Errorf("queues deleted %q", q.deleted)
+	}
+	// IAM deletes a role only once it has no policies.
+	if want := []string{"detach arn:logs", "delete policy fixtures", "delete role " + deploy.DefaultRoleName}; !slices.Equal(i.calls, want) {
+		t.Errorf("IAM calls %q, want %q", i.calls, want)
+	}
+	if err := c.Delete(ctx, Resource{Kind: "vpc", Name: "x"}); err == nil {
+		t.Error("Delete of an unknown kind succeeded")
+	}
+}
@@ -0,0 +1,114 @@
+// Package goldencheck keeps the Ruchy side of the comparison honest: it
+// runs the Ruchy-compiled binary and the Go baseline of every local
+// workload both implement on the same input and requires them to print
+// the same thing. verify-parity checks each implementation against the
+// manifest's expected result; this checks them against each other, all
+// of their output rather than the result line, so a change to either side
+// that alters what it computes or prints fails go test.
+//
+// Only local targets are paired. The Lambda handlers need the Runtime
+// API, which ruchy-bench run -rie provides against the Go side alone.
+package goldencheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/localbench"
+)
+
+// ErrUnavailable is wrapped by Check's error when a side cannot be built
+// or started here because its toolchain is not installed.
+var ErrUnavailable = errors.New("toolchain not installed")
+
+// Pair is the Ruchy and Go implementation of one local workload.
+type Pair struct {
+	Workload  string
+	Ruchy, Go discover.Target
+}
+
+// Pairs returns a Pair for every local workload with both a Ruchy and a
+// Go implementation among targets, by workload name.
+func Pairs(targets []discover.Target) []Pair {
+	ruchy := map[string]discover.Target{}
+	for _, t := range targets {
+		if t.Kind == discover.KindLocal && t.Runtime == "ruchy" {
+			ruchy[t.Workload] = t
+		}
+	}
+	var pairs []Pair
+	for _, t := range targets {
+		if r, ok := ruchy[t.Workload]; ok && t.Kind == discover.KindLocal && t.Runtime == "go" {
+			pairs = append(pairs, Pair{Workload: t.Workload, Ruchy: r, Go: t})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Workload < pairs[j].Workload })
+	return pairs
+}
+
+// Logical returns output as the comparison sees it: line endings
+// normalized to \n, trailing whitespace cut from every line, and leading
+// and trailing blank lines dropped. Everything else must match byte for
+// byte.
+func Logical(output []byte) []byte {
+	lines := bytes.Split(bytes.ReplaceAll(output, []byte("\r\n"), []byte("\n")), []byte("\n"))
+	for i, line := range lines {
+		lines[i] = bytes.TrimRight(line, " \t\r")
+	}
+	for len(lines) > 0 && len(lines[0]) == 0 {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// Check builds both sides of p with b, runs each once on the same input
+// and compares their Logical output. The input is the workload's event
+// fixture, if it has one, else an empty object, as ruchy-bench run gives
+// local targets.
+func Check(ctx context.Context, b *build.Builder, p Pair) error {
+	stdin := []byte("{}")
+	if p.Go.Event != "" {
+		var err error
+		if stdin, err = os.ReadFile(p.Go.Event); err != nil {
+			return err
+		}
+	}
+	golden, err := output(ctx, b, p.Go, stdin)
+	if err != nil {
+		return err
+	}
+	ruchy, err := output(ctx, b, p.Ruchy, stdin)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(ruchy, golden) {
+		return fmt.Errorf("%s: ruchy printed %q, go printed %q", p.Workload, ruchy, golden)
+	}
+	return nil
+}
+
+func output(ctx context.Context, b *build.Builder, t discover.Target, stdin []byte) ([]byte, error) {
+	a, err := b.Build(ctx, t)
+	if err == nil {
+		r := &localbench.Runner{Command: a.Command, Dir: t.Dir, Stdin: stdin}
+		var m localbench.Measurement
+		if m, err = r.Run(ctx); err == nil {
+			return Logical(m.Output), nil
+		}
+		err = fmt.Errorf("run %s: %w", t.ID(), err)
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return nil, err
+}
@@ -0,0 +1,123 @@
+package goldencheck
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/discover"
+)
+
+func TestPairs(t *testing.T) {
+	targets := []discover.Target{
+		{Kind: discover.KindLocal, Runtime: "go", Workload: "sieve"},
+		{Kind: discover.KindLocal, Runtime: "go", Workload: "fibonacci"},
+		{Kind: discover.KindLocal, Runtime: "rust", Workload: "fibonacci"},
+		{Kind: discover.KindLocal, Runtime: "ruchy", Workload: "fibonacci"},
+		{Kind: discover.KindLambda, Runtime: "ruchy", Workload: "sieve"},
+		{Kind: discover.KindLambda, Runtime: "go", Workload: "sieve"},
+	}
+	pairs := Pairs(targets)
+	if len(pairs) != 1 || pairs[0].Workload != "fibonacci" || pairs[0].Ruchy.Runtime != "ruchy" || pairs[0].Go.Runtime != "go" {
+		t.Errorf("Pairs = %+v, want the local fibonacci pair only", pairs)
+	}
+}
+
+func TestLogical(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"fibonacci(35)=9227465\n", "fibonacci(35)=9227465"},
+		{"\r\na  \r\n\tb\t\r\n\n", "a\n\tb"},
+		{"a\n\nb", "a\n\nb"},
+		{"", ""},
+	} {
+		if got := string(Logical([]byte(tc.in))); got != tc.want {
+			t.Errorf("Logical(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+// program writes a Go local target printing out.
+func program(t *testing.T, dir, name, out string) discover.Target {
+	t.Helper()
+	src := filepath.Join(dir, name+".go")
+	code := "package main\n\nimport \"os\"\n\nfunc main() { os.Stdout.WriteString(" + `"` + out + `"` + ") }\n"
+	if err := os.WriteFile(src, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return discover.Target{Kind: discover.KindLocal, Runtime: "go", Workload: name, Dir: dir, Source: src}
+}
+
+func TestCheck(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds programs")
+	}
+	dir := t.TempDir()
+	b := &build.Builder{Root: dir, OutDir: filepath.Join(dir, "out")}
+	ctx := context.Background()
+
+	golden := program(t, dir, "golden", `sum=6\n`)
+	same := program(t, dir, "same", `sum=6\r\n\n`)
+	if err := Check(ctx, b, Pair{Workload: "sum", Ruchy: same, Go: golden}); err != nil {
+		t.Errorf("outputs differing only in line endings: %v", err)
+	}
+	differs := program(t, dir, "differs", `sum= 6\n`)
+	if err := Check(ctx, b, Pair{Workload: "sum", Ruchy: differs, Go: golden}); err == nil || !strings.Contains(err.Error(), `"sum= 6"`) {
+		t.Errorf("differing outputs: %v", err)
+	}
+
+	missing := discover.Target{Kind: discover.KindLocal, Runtime: "ruchy", Workload: "sum", Dir: dir, Source: filepath.Join(dir, "sum.ruchy")}
+	t.Setenv("PATH", filepath.Dir(goBinary(t)))
+	if err := Check(ctx, b, Pair{Workload: "sum", Ruchy: missing, Go: golden}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("without the ruchy toolchain: %v, want ErrUnavailable", err)
+	}
+}
+
+func goBinary(t *testing.T) string {
+	t.Helper()
+	path, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not on PATH")
+	}
+	return path
+}
+
+// TestGolden is the check itself, over the repository's own workloads.
+// Pairs whose Ruchy side cannot be built here are skipped.
+func TestGolden(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds and runs every paired workload")
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := discover.FindRoot(wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets, err := discover.Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pairs := Pairs(targets)
+	if len(pairs) == 0 {
+		t.Fatal("no local workload has both a ruchy and a go implementation")
+	}
+	b := &build.Builder{Root: root, OutDir: t.TempDir()}
+	for _, p := range pairs {
+		t.Run(p.Workload, func(t *testing.T) {
+			err := Check(context.Background(), b, p)
+			if errors.Is(err, ErrUnavailable) {
+				t.Skip(err)
+			}
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Package hyperfine converts between results and the JSON hyperfine writes
+// with --export-json, so that plots and scripts written against hyperfine
+// keep working on ruchy-bench runs, and measurements taken with hyperfine
+// can be imported into the history database.
+package hyperfine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// Parameter names identifying the target of a benchmark. Exports set them;
+// imports read them, so a hyperfine parameter scan such as
+// "hyperfine -L runtime go,rust './fibonacci-{runtime}'" imports as one
+// result per runtime.
+const (
+	ParamRuntime  = "runtime"
+	ParamWorkload = "workload"
+	ParamArch     = "arch"
+)
+
+// Export is a hyperfine --export-json document.
+type Export struct {
+	Results []Benchmark `json:"results"`
+}
+
+// Benchmark is the entry of one command. Times are in seconds.
+type Benchmark struct {
+	Command string  `json:"command"`
+	Mean    float64 `json:"mean"`
+	// Stddev is null for a single run, as hyperfine writes it.
+	Stddev *float64 `json:"stddev"`
+	Median float64  `json:"median"`
+	// User and System are mean CPU times.
+	User   float64   `json:"user"`
+	System float64   `json:"system"`
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	Times  []float64 `json:"times"`
+	// MemoryUsageBytes is the peak RSS of each run; hyperfine 1.19 and
+	// later record it.
+	MemoryUsageBytes []int64 `json:"memory_usage_byte,omitempty"`
+	// ExitCodes has a null entry for a run killed by a signal.
+	ExitCodes  []*int            `json:"exit_codes"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// FromRun converts the local results of run. Only successful samples are
+// exported, as hyperfine itself stops at a failing command; results
+// without any are left out, as are Lambda results, which have no process
+// whose CPU time and memory could be reported.
+func FromRun(run *results.Run) Export {
+	e := Export{Results: []Benchmark{}}
+	for _, r := range run.Results {
+		if r.Kind != "local" {
+			continue
+		}
+		var (
+			times       []float64
+			user, sys   float64
+			memory      []int64
+			exitCodes   []*int
+			zero        = 0
+			everyMemory = true
+		)
+		for _, s := range r.Samples {
+			// hyperfine leaves its warm-up runs out of the export too.
+			if s.Error != "" || s.Warmup {
+				continue
+			}
+			times = append(times, s.ClientMS/1000)
+			user += s.UserMS / 1000
+			sys += s.SystemMS / 1000
+			memory = append(memory, s.MaxRSSKB*1024)
+			everyMemory = everyMemory && s.MaxRSSKB > 0
+			exitCodes = append(exitCodes, &zero)
+		}
+		if len(times) == 0 {
+			continue
+		}
+		sum := stats.Summarize(times, stats.Options{})
+		b := Benchmark{
+			Command:   r.Runtime + "/" + r.Workload,
+			Mean:      sum.Mean,
+			Median:    sum.Median,
+			User:      user / float64(len(times)),
+			System:    sys / float64(len(times)),
+			Min:       sum.Min,
+			Max:       sum.Max,
+			Times:     times,
+			ExitCodes: exitCodes,
+			Parameters: map[string]string{
+				ParamRuntime:  r.Runtime,
+				ParamWorkload: r.Workload,
+			},
+		}
+		if len(times) > 1 {
+			b.Stddev = &sum.StdDev
+		}
+		if everyMemory {
+			b.MemoryUsageBytes = memory
+		}
+		if r.Arch != "" {
+			b.Parameters[ParamArch] = r.Arch
+		}
+		e.Results = append(e.Results, b)
+	}
+	return e
+}
+
+// ToRun converts an export to a local run started at startedAt, one result
+// per benchmark. The runtime and workload come from the benchmark's
+// parameters; without a runtime parameter the runtime is the command's
+// name (set it with "hyperfine -n"), and without a workload parameter it
+// is workload, which may then not be empty. Only per-run wall times, peak
+// memory and exit codes are imported: hyperfine keeps CPU times as means.
+func ToRun(e Export, workload string, startedAt time.Time) (*results.Run, error) {
+	if len(e.Results) == 0 {
+		return nil, errors.New("no benchmarks in export")
+	}
+	run := results.NewRun("local", startedAt)
+	for _, b := range e.Results {
+		r := results.Result{
+			Runtime:  b.Parameters[ParamRuntime],
+			Workload: b.Parameters[ParamWorkload],
+			Kind:     "local",
+			Arch:     b.Parameters[ParamArch],
+		}
+		if r.Runtime == "" {
+			r.Runtime = commandName(b.Command)
+		}
+		if r.Workload == "" {
+			r.Workload = workload
+		}
+		if r.Runtime == "" || r.Workload == "" {
+			return nil, fmt.Errorf("benchmark %q: no runtime or workload; set a %q or %q parameter or pass a workload",
+				b.Command, ParamRuntime, ParamWorkload)
+		}
+		for i, t := range b.Times {
+			s := results.Sample{Iteration: i, ClientMS: t * 1000}
+			if i < len(b.MemoryUsageBytes) {
+				s.MaxRSSKB = b.MemoryUsageBytes[i] / 1024
+			}
+			if i < len(b.ExitCodes) {
+				switch code := b.ExitCodes[i]; {
+				case code == nil:
+					s.Error = "killed by a signal"
+				case *code != 0:
+					s.Error = fmt.Sprintf("exit status %d", *code)
+				}
+			}
+			r.Samples = append(r.Samples, s)
+		}
+		run.Results = append(run.Results, r)
+	}
+	run.FinishedAt = run.StartedAt
+	return run, nil
+}
+
+// commandName is the base name of the program a command line runs.
+func commandName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return filepath.Base(fields[0])
+}
+
+// Read loads an export written by hyperfine or Write.
+func Read(path string) (Export, error) {
+	var e Export
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return e, err
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return e, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return e, nil
+}
+
+// Write stores e at path, formatted as hyperfine formats it.
+func Write(path string, e Export) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
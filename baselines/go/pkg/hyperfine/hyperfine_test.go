@@ -0,0 +1,134 @@
+package hyperfine
+
+import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lambdaperf/pkg/results"
+)
+
+// export is hyperfine 1.19 output for
+// hyperfine -N -i -L runtime c,python --export-json out.json './fib-{runtime}'
+// trimmed to three runs each.
+const export = `{
+  "results": [
+    {
+      "command": "./fib-c",
+      "mean": 0.0127,
+      "stddev": 0.0005,
+      "median": 0.0126,
+      "user": 0.0121,
+      "system": 0.0004,
+      "min": 0.0122,
+      "max": 0.0132,
+      "times": [0.0122, 0.0126, 0.0132],
+      "memory_usage_byte": [1527808, 1531904, 1527808],
+      "exit_codes": [0, 0, 0],
+      "parameters": {"runtime": "c"}
+    },
+    {
+      "command": "./fib-python",
+      "mean": 0.689,
+      "stddev": 0.004,
+      "median": 0.688,
+      "user": 0.671,
+      "system": 0.015,
+      "min": 0.685,
+      "max": 0.694,
+      "times": [0.685, 0.688, 0.694],
+      "memory_usage_byte": [9011200, 9015296, 9011200],
+      "exit_codes": [0, 1, null],
+      "parameters": {"runtime": "python"}
+    }
+  ]
+}`
+
+func TestToRun(t *testing.T) {
+	var e Export
+	if err := json.Unmarshal([]byte(export), &e); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	run, err := ToRun(e, "fibonacci", at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ID != "20250301T120000Z" || run.Mode != "local" || len(run.Results) != 2 {
+		t.Fatalf("run = %+v", run)
+	}
+	c := run.Results[0]
+	if c.Runtime != "c" || c.Workload != "fibonacci" || c.Kind != "local" || len(c.Samples) != 3 {
+		t.Errorf("c = %+v", c)
+	}
+	if s := c.Samples[1]; math.Abs(s.ClientMS-12.6) > 1e-9 || s.MaxRSSKB != 1496 || s.Error != "" {
+		t.Errorf("c sample = %+v", s)
+	}
+	py := run.Results[1].Samples
+	if py[0].Error != "" || py[1].Error != "exit status 1" || py[2].Error != "killed by a signal" {
+		t.Errorf("python errors = %q, %q, %q", py[0].Error, py[1].Error, py[2].Error)
+	}
+
+	delete(e.Results[0].Parameters, ParamRuntime)
+	e.Results[0].Command = "/opt/bench/rust --n 35"
+	if run, err = ToRun(e, "fibonacci", at); err != nil || run.Results[0].Runtime != "rust" {
+		t.Errorf("runtime from command = %q, %v", run.Results[0].Runtime, err)
+	}
+	if _, err := ToRun(e, "", at); err == nil {
+		t.Error("no error without a workload")
+	}
+}
+
+func TestFromRunRoundTrips(t *testing.T) {
+	run := results.NewRun("mixed", time.Now())
+	run.Results = []results.Result{
+		{Runtime: "go", Workload: "sieve", Kind: "local", Samples: []results.Sample{
+			{ClientMS: 40, UserMS: 30, SystemMS: 6, MaxRSSKB: 12000},
+			{ClientMS: 60, UserMS: 50, SystemMS: 4, MaxRSSKB: 12004},
+			{ClientMS: 1, Error: "printed \"\", want \"sieve(10000000)=664579\""},
+		}},
+		{Runtime: "go", Workload: "sieve", Kind: "lambda", Samples: []results.Sample{{ClientMS: 20}}},
+		{Runtime: "python", Workload: "sieve", Kind: "local", Samples: []results.Sample{{ClientMS: 900}}},
+	}
+	e := FromRun(run)
+	if len(e.Results) != 2 {
+		t.Fatalf("%d benchmarks, want 2", len(e.Results))
+	}
+	b := e.Results[0]
+	if b.Command != "go/sieve" || b.Mean != 0.05 || b.Median != 0.05 || b.Min != 0.04 || b.Max != 0.06 ||
+		math.Abs(b.User-0.04) > 1e-9 || b.System != 0.005 || len(b.Times) != 2 {
+		t.Errorf("go = %+v", b)
+	}
+	if b.Stddev == nil || math.Abs(*b.Stddev-0.01414) > 1e-4 {
+		t.Errorf("stddev = %v", b.Stddev)
+	}
+	if len(b.MemoryUsageBytes) != 2 || b.MemoryUsageBytes[0] != 12000*1024 {
+		t.Errorf("memory = %v", b.MemoryUsageBytes)
+	}
+	if py := e.Results[1]; py.Stddev != nil || py.MemoryUsageBytes != nil {
+		t.Errorf("single run without RSS = %+v", py)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.json")
+	if err := Write(path, e); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	back, err := ToRun(read, "", run.StartedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := back.Results[0]; r.Runtime != "go" || r.Workload != "sieve" || len(r.Samples) != 2 || r.Samples[1].MaxRSSKB != 12004 {
+		t.Errorf("round trip = %+v", r)
+	}
+	data, _ := json.Marshal(read.Results[1])
+	if !strings.Contains(string(data), `"stddev":null`) {
+		t.Errorf("single-run stddev not null: %s", data)
+	}
+}
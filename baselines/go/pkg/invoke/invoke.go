@@ -0,0 +1,235 @@
+// Package invoke runs a single invocation of a target: as a local
+// subprocess, as a synchronous Lambda Invoke call, as a request to a
+// Runtime Interface Emulator running the target's container image, or as a
+// request to a function URL whose response is streamed.
+package invoke
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/internal/awsapi"
+)
+
+// Response is what one invocation produced.
+type Response struct {
+	// Payload is the handler response (Lambda) or stdout (local).
+	Payload []byte
+	// FunctionError is set when Lambda reports an unhandled or handled
+	// function error.
+	FunctionError string
+	// LogTail holds the last 4 KB of the invocation's logs (Lambda), or
+	// the emulator's output since the previous invocation (RIE).
+	LogTail string
+	// Elapsed is the client-observed round trip.
+	Elapsed time.Duration
+	// FirstByte is how long the first byte of a streamed body took to
+	// arrive, and Bytes the body's length; see FunctionURL.
+	FirstByte time.Duration
+	Bytes     int64
+	// Retries is how many transiently failed attempts came before this
+	// one; see Retry.
+	Retries int
+}
+
+// Invoker performs one invocation with the given payload.
+type Invoker interface {
+	Invoke(ctx context.Context, payload []byte) (Response, error)
+}
+
+// Local runs a command once per invocation, passing the payload on stdin.
+type Local struct {
+	Command []string
+	Dir     string
+}
+
+// Invoke runs the command and measures its wall time.
+func (l *Local) Invoke(ctx context.Context, payload []byte) (Response, error) {
+	cmd := exec.CommandContext(ctx, l.Command[0], l.Command[1:]...)
+	cmd.Dir = l.Dir
+	cmd.Stdin = bytes.NewReader(payload)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	elapsed := time.Since(start)
+	if err != nil {
+		return Response{Elapsed: elapsed}, fmt.Errorf("%s: %w: %s", l.Command[0], err, stderr.Bytes())
+	}
+	return Response{Payload: stdout.Bytes(), Elapsed: elapsed}, nil
+}
+
+// LambdaAPI is the subset of the Lambda client used by the invoker.
+type LambdaAPI interface {
+	Invoke(ctx context.Context, in *lambda.InvokeInput, opts ...func(*lambda.Options)) (*lambda.InvokeOutput, error)
+}
+
+// Lambda invokes a deployed function synchronously with log tailing on.
+type Lambda struct {
+	Client       LambdaAPI
+	FunctionName string
+	// Qualifier selects a version or alias; empty invokes $LATEST.
+	Qualifier string
+}
+
+// Invoke calls the function and decodes the tailed logs.
+func (l *Lambda) Invoke(ctx context.Context, payload []byte) (Response, error) {
+	in := &lambda.InvokeInput{
+		FunctionName:   aws.String(l.FunctionName),
+		InvocationType: types.InvocationTypeRequestResponse,
+		LogType:        types.LogTypeTail,
+		Payload:        payload,
+	}
+	if l.Qualifier != "" {
+		in.Qualifier = aws.String(l.Qualifier)
+	}
+	start := time.Now()
+	out, err := l.Client.Invoke(ctx, in)
+	elapsed := time.Since(start)
+	if err != nil {
+		return Response{Elapsed: elapsed}, fmt.Errorf("invoke %s: %w", l.FunctionName, err)
+	}
+	resp := Response{
+		Payload:       out.Payload,
+		FunctionError: aws.ToString(out.FunctionError),
+		Elapsed:       elapsed,
+	}
+	if out.LogResult != nil {
+		logs, err := base64.StdEncoding.DecodeString(*out.LogResult)
+		if err != nil {
+			return resp, fmt.Errorf("decode log tail: %w", err)
+		}
+		resp.LogTail = string(logs)
+	}
+	return resp, nil
+}
+
+// RIEPath is the emulator's invoke endpoint, the one Lambda's own Invoke
+// API uses.
+const RIEPath = "/2015-03-31/functions/function/invocations"
+
+// RIE invokes a handler running under the Lambda Runtime Interface
+// Emulator, which is what AWS base images start when run outside Lambda.
+type RIE struct {
+	// URL is the emulator's base URL, such as http://127.0.0.1:9000.
+	URL string
+	// Client sends the requests; nil uses http.DefaultClient.
+	Client *http.Client
+	// Logs, if set, returns the container output written since it was
+	// last called, which holds the emulator's REPORT line.
+	Logs func(ctx context.Context) (string, error)
+}
+
+// Invoke posts the payload to the emulator and waits for the response.
+func (r *RIE) Invoke(ctx context.Context, payload []byte) (Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL+RIEPath, bytes.NewReader(payload))
+	if err != nil {
+		return Response{}, err
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Response{Elapsed: time.Since(start)}, fmt.Errorf("invoke %s: %w", r.URL, err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	elapsed := time.Since(start)
+	if err != nil {
+		return Response{Elapsed: elapsed}, fmt.Errorf("invoke %s: %w", r.URL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Response{Elapsed: elapsed}, &StatusError{URL: r.URL, StatusCode: resp.StatusCode, Status: resp.Status, Body: bytes.TrimSpace(body)}
+	}
+	out := Response{
+		Payload:       body,
+		FunctionError: resp.Header.Get("X-Amz-Function-Error"),
+		Elapsed:       elapsed,
+	}
+	if r.Logs != nil {
+		if out.LogTail, err = r.Logs(ctx); err != nil {
+			return out, fmt.Errorf("read emulator logs: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// FunctionURL requests a function URL and reads the response as it
+// streams in, timing the first byte of the body. A successful body is
+// counted, not kept: a streamed response can be far larger than a sample
+// should hold.
+type FunctionURL struct {
+	// URL is the function URL, query string included.
+	URL string
+	// Client sends the requests; nil uses http.DefaultClient.
+	Client *http.Client
+	// Credentials, if set, sign every request with SigV4 for Region, as
+	// function URLs with AWS_IAM authentication require.
+	Credentials aws.CredentialsProvider
+	Region      string
+}
+
+// Invoke sends the payload as the request body, a GET when it is empty,
+// and reads the response to its end. Responses other than 200 are errors
+// carrying the start of their body.
+func (f *FunctionURL) Invoke(ctx context.Context, payload []byte) (Response, error) {
+	method := http.MethodGet
+	if len(payload) > 0 {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, f.URL, bytes.NewReader(payload))
+	if err != nil {
+		return Response{}, err
+	}
+	signer := &awsapi.Client{Config: aws.Config{Credentials: f.Credentials}, Service: "lambda", Region: f.Region}
+	if err := signer.Sign(req, payload); err != nil {
+		return Response{}, err
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return Response{Elapsed: time.Since(start)}, fmt.Errorf("invoke %s: %w", f.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return Response{Elapsed: time.Since(start)}, &StatusError{URL: f.URL, StatusCode: resp.StatusCode, Status: resp.Status, Body: bytes.TrimSpace(body)}
+	}
+	var out Response
+	buf := make([]byte, 64<<10)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 && out.Bytes == 0 {
+			out.FirstByte = time.Since(start)
+		}
+		out.Bytes += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			out.Elapsed = time.Since(start)
+			return out, fmt.Errorf("invoke %s: after %d bytes: %w", f.URL, out.Bytes, err)
+		}
+	}
+	out.Elapsed = time.Since(start)
+	return out, nil
+}
@@ -0,0 +1,162 @@
+package invoke
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func TestRIE(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != RIEPath {
+			http.NotFound(w, r)
+			return
+		}
+		event, _ := io.ReadAll(r.Body)
+		if string(event) == `{"fail":true}` {
+			w.Header().Set("X-Amz-Function-Error", "Unhandled")
+			io.WriteString(w, `{"errorType":"Runtime.ExitError"}`)
+			return
+		}
+		io.WriteString(w, `{"statusCode":200,"body":"ok"}`)
+	}))
+	defer srv.Close()
+
+	logs := []string{"REPORT RequestId: a\tInit Duration: 1.50 ms\tDuration: 2.00 ms\t\n", ""}
+	r := &RIE{URL: srv.URL, Logs: func(context.Context) (string, error) {
+		l := logs[0]
+		logs = logs[1:]
+		return l, nil
+	}}
+	resp, err := r.Invoke(context.Background(), []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Payload) != `{"statusCode":200,"body":"ok"}` || resp.FunctionError != "" || !strings.HasPrefix(resp.LogTail, "REPORT") {
+		t.Errorf("response = %+v", resp)
+	}
+	if resp, err = r.Invoke(context.Background(), []byte(`{"fail":true}`)); err != nil || resp.FunctionError != "Unhandled" {
+		t.Errorf("failing invocation = %+v, %v", resp, err)
+	}
+
+	r.URL += "/missing"
+	if _, err := r.Invoke(context.Background(), nil); err == nil {
+		t.Error("no error for a 404")
+	}
+}
+
+func TestFunctionURL(t *testing.T) {
+	const pause = 20 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			!strings.Contains(r.Header.Get("Authorization"), "/us-east-1/lambda/aws4_request") {
+			http.Error(w, "missing signature", http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("bytes") == "0" {
+			http.Error(w, "bytes must be an integer between 1 and 20971520", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		time.Sleep(pause)
+		w.Write(make([]byte, 100_000))
+	}))
+	defer srv.Close()
+
+	f := &FunctionURL{
+		URL:         srv.URL + "/?bytes=100005",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "secret", ""),
+		Region:      "us-east-1",
+	}
+	resp, err := f.Invoke(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Bytes != 100_005 || resp.Payload != nil {
+		t.Errorf("read %d bytes, payload %q", resp.Bytes, resp.Payload)
+	}
+	if resp.FirstByte <= 0 || resp.Elapsed-resp.FirstByte < pause {
+		t.Errorf("first byte after %s, done after %s; want the pause between them", resp.FirstByte, resp.Elapsed)
+	}
+
+	f.URL = srv.URL + "/?bytes=0"
+	if _, err := f.Invoke(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "between 1 and") {
+		t.Errorf("refused request: %v", err)
+	}
+	f.Credentials = nil
+	if _, err := f.Invoke(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("unsigned request: %v", err)
+	}
+}
+
+// flaky fails with errs in turn, then succeeds.
+type flaky struct {
+	errs  []error
+	calls int
+}
+
+func (f *flaky) Invoke(context.Context, []byte) (Response, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return Response{Elapsed: time.Second}, err
+	}
+	return Response{Payload: []byte("ok"), Elapsed: time.Millisecond}, nil
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want Class
+	}{
+		{&types.TooManyRequestsException{}, Throttle},
+		{fmt.Errorf("invoke f: %w", &types.ServiceException{}), Server},
+		{&StatusError{StatusCode: http.StatusTooManyRequests}, Throttle},
+		{&StatusError{StatusCode: http.StatusBadGateway}, Server},
+		{&StatusError{StatusCode: http.StatusForbidden}, ""},
+		{fmt.Errorf("invoke f: %w", context.DeadlineExceeded), Timeout},
+		{context.Canceled, ""},
+		{&types.ResourceNotFoundException{}, ""},
+		{nil, ""},
+	} {
+		if got := Classify(tc.err); got != tc.want {
+			t.Errorf("Classify(%v) = %q, want %q", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	b := Backoff{Attempts: 3, Base: time.Millisecond, Max: 4 * time.Millisecond}
+	inv := &flaky{errs: []error{&types.TooManyRequestsException{}, &StatusError{StatusCode: 503}}}
+	resp, err := (&Retry{Invoker: inv, Backoff: b}).Invoke(context.Background(), nil)
+	if err != nil || resp.Retries != 2 || resp.Elapsed != time.Millisecond || string(resp.Payload) != "ok" {
+		t.Errorf("after two transient failures: %+v, %v", resp, err)
+	}
+
+	inv = &flaky{errs: []error{&types.TooManyRequestsException{}, &types.TooManyRequestsException{}, &types.TooManyRequestsException{}}}
+	resp, err = (&Retry{Invoker: inv, Backoff: b}).Invoke(context.Background(), nil)
+	if Classify(err) != Throttle || resp.Retries != 2 || inv.calls != 3 {
+		t.Errorf("out of attempts: %+v, %v after %d calls", resp, err, inv.calls)
+	}
+
+	inv = &flaky{errs: []error{&types.ResourceNotFoundException{}}}
+	if resp, err = (&Retry{Invoker: inv, Backoff: b}).Invoke(context.Background(), nil); err == nil || resp.Retries != 0 || inv.calls != 1 {
+		t.Errorf("permanent failure retried: %+v, %v after %d calls", resp, err, inv.calls)
+	}
+
+	for i := range 10 {
+		if d := b.Delay(i); d < 0 || d >= b.Max {
+			t.Errorf("Delay(%d) = %s", i, d)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package invoke
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// Class says why an invocation failed transiently: for a reason that has
+// nothing to do with the target, so that trying again may succeed.
+type Class string
+
+const (
+	// Throttle is a throttled request: Lambda's 429 TooManyRequests, or
+	// another service's throttling error code.
+	Throttle Class = "throttle"
+	// Server is a 5xx response from the service rather than the function.
+	Server Class = "server"
+	// Timeout is a request that timed out on the client.
+	Timeout Class = "timeout"
+	// Connection is a connection that was refused or reset.
+	Connection Class = "connection"
+)
+
+var (
+	throttleCodes = retry.ThrottleErrorCode{Codes: retry.DefaultThrottleErrorCodes}
+	connection    = retry.RetryableConnectionError{}
+	// serverCodes are the error codes of AWS services' own failures, for
+	// errors that arrive without their HTTP status.
+	serverCodes = map[string]bool{
+		"ServiceException": true, "InternalFailure": true, "InternalServerError": true, "ServiceUnavailable": true,
+	}
+)
+
+// Classify returns the class of a transient failure, or "" when err is nil
+// or not transient: a function error, a bad request, missing permissions
+// or a canceled context.
+func Classify(err error) Class {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return ""
+	}
+	var status interface{ HTTPStatusCode() int }
+	code := 0
+	if errors.As(err, &status) {
+		code = status.HTTPStatusCode()
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		code = se.StatusCode
+	}
+	var api interface{ ErrorCode() string }
+	if errors.As(err, &api) && serverCodes[api.ErrorCode()] && code == 0 {
+		code = http.StatusInternalServerError
+	}
+	var ne net.Error
+	switch {
+	case code == http.StatusTooManyRequests || throttleCodes.IsErrorThrottle(err) == aws.TrueTernary:
+		return Throttle
+	case code >= 500:
+		return Server
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &ne) && ne.Timeout():
+		return Timeout
+	case connection.IsErrorRetryable(err) == aws.TrueTernary:
+		return Connection
+	}
+	return ""
+}
+
+// StatusError is the error of an HTTP invocation whose response was not
+// 200 OK.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return "invoke " + e.URL + ": " + e.Status + ": " + string(e.Body)
+}
+
+// Backoff retries transient failures with capped exponential backoff and
+// full jitter: the wait before retry i is uniform in [0, min(Max, Base*2^i)),
+// so throttled callers do not retry in lockstep.
+type Backoff struct {
+	// Attempts is the most tries an operation gets, the first included;
+	// one or fewer means no retries.
+	Attempts  int
+	Base, Max time.Duration
+}
+
+// DefaultBackoff tries four times, waiting up to 200 ms, 400 ms and 800 ms.
+var DefaultBackoff = Backoff{Attempts: 4, Base: 200 * time.Millisecond, Max: 5 * time.Second}
+
+// Delay returns how long to wait before retry i, counting from zero. A
+// zero Max leaves the growth uncapped.
+func (b Backoff) Delay(i int) time.Duration {
+	d := b.Base << min(i, 20)
+	if b.Max > 0 && d > b.Max {
+		d = b.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+// Do calls fn until it succeeds, fails for a reason Classify does not
+// consider transient, runs out of attempts or ctx is done, returning how
+// many retries it made and fn's last error.
+func (b Backoff) Do(ctx context.Context, fn func() error) (retries int, err error) {
+	for {
+		err = fn()
+		if err == nil || retries+1 >= b.Attempts || Classify(err) == "" || ctx.Err() != nil {
+			return retries, err
+		}
+		select {
+		case <-ctx.Done():
+			return retries, err
+		case <-time.After(b.Delay(retries)):
+		}
+		retries++
+	}
+}
+
+// Retry retries an Invoker's transiently failed invocations. The response
+// is the last attempt's, timed on its own, with Retries saying how many
+// attempts came before it.
+type Retry struct {
+	Invoker Invoker
+	Backoff Backoff
+}
+
+// Invoke invokes until an attempt succeeds or Backoff gives up.
+func (r *Retry) Invoke(ctx context.Context, payload []byte) (Response, error) {
+	var resp Response
+	retries, err := r.Backoff.Do(ctx, func() error {
+		var err error
+		resp, err = r.Invoker.Invoke(ctx, payload)
+		return err
+	})
+	resp.Retries = retries
+	return resp, err
+}
@@ -0,0 +1,133 @@
+package keepwarm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+// Client manages EventBridge rules over its JSON API. Like queue.Client
+// it implements only the calls the harness makes.
+type Client struct {
+	Config aws.Config
+	// Endpoint overrides https://events.<region>.amazonaws.com.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// APIError is an error response from EventBridge, such as
+// "ResourceNotFoundException".
+type APIError = awsapi.Error
+
+// TagKey marks rules created by the harness, as deploy.TagKey does
+// functions.
+const TagKey = "ruchy-bench"
+
+// targetID identifies the function among a rule's targets.
+const targetID = "function"
+
+// Rule is a rule ListRules returned.
+type Rule struct {
+	Name string `json:"Name"`
+	ARN  string `json:"Arn"`
+}
+
+// Schedule creates or updates the rule name, tagged TagKey, to invoke the
+// function with ARN functionARN on input every interval, returning the
+// rule's ARN. The function must allow EventBridge to invoke it; see
+// deploy.AllowSchedule.
+func (c *Client) Schedule(ctx context.Context, name string, interval time.Duration, functionARN string, input []byte) (string, error) {
+	in := map[string]any{
+		"Name":               name,
+		"ScheduleExpression": Schedule(interval),
+		"State":              "ENABLED",
+		"Description":        "ruchy-bench keep-warm pings",
+		"Tags":               []map[string]string{{"Key": TagKey, "Value": "true"}},
+	}
+	var rule struct{ RuleArn string }
+	if err := c.call(ctx, "PutRule", in, &rule); err != nil {
+		return "", fmt.Errorf("put rule %s: %w", name, err)
+	}
+	var out struct {
+		FailedEntryCount int
+		FailedEntries    []struct{ ErrorCode, ErrorMessage string }
+	}
+	targets := map[string]any{
+		"Rule":    name,
+		"Targets": []map[string]string{{"Id": targetID, "Arn": functionARN, "Input": string(input)}},
+	}
+	if err := c.call(ctx, "PutTargets", targets, &out); err != nil {
+		return "", fmt.Errorf("put target of rule %s: %w", name, err)
+	}
+	if out.FailedEntryCount > 0 && len(out.FailedEntries) > 0 {
+		e := out.FailedEntries[0]
+		return "", fmt.Errorf("put target of rule %s: %s: %s", name, e.ErrorCode, e.ErrorMessage)
+	}
+	return rule.RuleArn, nil
+}
+
+// Remove deletes the rule name and its target. A rule already gone is
+// not an error.
+func (c *Client) Remove(ctx context.Context, name string) error {
+	err := c.call(ctx, "RemoveTargets", map[string]any{"Rule": name, "Ids": []string{targetID}}, nil)
+	if err == nil {
+		err = c.call(ctx, "DeleteRule", map[string]any{"Name": name}, nil)
+	}
+	var api *APIError
+	if err != nil && !(errors.As(err, &api) && api.Code == "ResourceNotFoundException") {
+		return fmt.Errorf("delete rule %s: %w", name, err)
+	}
+	return nil
+}
+
+// List calls ListRules for the rules whose names start with prefix,
+// following every page.
+func (c *Client) List(ctx context.Context, prefix string) ([]Rule, error) {
+	var rules []Rule
+	in := map[string]any{}
+	if prefix != "" {
+		in["NamePrefix"] = prefix
+	}
+	for {
+		var out struct {
+			Rules     []Rule
+			NextToken string
+		}
+		if err := c.call(ctx, "ListRules", in, &out); err != nil {
+			return nil, fmt.Errorf("list rules: %w", err)
+		}
+		rules = append(rules, out.Rules...)
+		if out.NextToken == "" {
+			return rules, nil
+		}
+		in["NextToken"] = out.NextToken
+	}
+}
+
+// Tags calls ListTagsForResource.
+func (c *Client) Tags(ctx context.Context, arn string) (map[string]string, error) {
+	var out struct {
+		Tags []struct{ Key, Value string }
+	}
+	if err := c.call(ctx, "ListTagsForResource", map[string]any{"ResourceARN": arn}, &out); err != nil {
+		return nil, fmt.Errorf("list tags of %s: %w", arn, err)
+	}
+	tags := make(map[string]string, len(out.Tags))
+	for _, t := range out.Tags {
+		tags[t.Key] = t.Value
+	}
+	return tags, nil
+}
+
+// call calls action with input in and decodes its output into out,
+// unless out is nil.
+func (c *Client) call(ctx context.Context, action string, in, out any) error {
+	api := &awsapi.Client{Config: c.Config, Service: "events", Endpoint: c.Endpoint, HTTP: c.HTTP}
+	return api.JSON(ctx, "1.1", "AWSEvents."+action, in, out)
+}
@@ -0,0 +1,79 @@
+// Package keepwarm measures what keep-warm pings do to the cold starts
+// sporadic traffic sees. An EventBridge rule invokes a function on a
+// fixed schedule, as keep-warm hacks do, while the harness invokes it at
+// the random, Poisson-distributed times of Arrivals and records which of
+// its invocations started cold. Comparing the cold-start rate with pings
+// at several intervals against none shows, per runtime, whether pinging
+// is worth it: a runtime with cheap cold starts gains little, and a ping
+// running when a request arrives sends that request to a second, cold
+// environment.
+package keepwarm
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// DefaultIntervals are the ping intervals compared when none are given,
+// no pings first.
+const DefaultIntervals = "off,5m,15m"
+
+// rulePrefix begins the name of every rule the harness creates.
+const rulePrefix = "ruchy-bench-keepwarm-"
+
+// RuleName is the name of the rule pinging fn.
+func RuleName(fn string) string {
+	return rulePrefix + fn
+}
+
+// ParseIntervals parses a comma-separated list of ping intervals, "off"
+// for no pings, as zero. EventBridge schedules rules in whole minutes.
+func ParseIntervals(s string) ([]time.Duration, error) {
+	var intervals []time.Duration
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if v == "off" {
+			intervals = append(intervals, 0)
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil || d < time.Minute || d%time.Minute != 0 {
+			return nil, fmt.Errorf("invalid ping interval %q: want off or whole minutes, such as 5m", v)
+		}
+		intervals = append(intervals, d)
+	}
+	return intervals, nil
+}
+
+// Label names interval d as ParseIntervals accepts it.
+func Label(d time.Duration) string {
+	if d == 0 {
+		return "off"
+	}
+	return fmt.Sprintf("%dm", d/time.Minute)
+}
+
+// Schedule is the EventBridge schedule expression of a rule firing every
+// d, which must be whole minutes.
+func Schedule(d time.Duration) string {
+	m := int(d / time.Minute)
+	if m == 1 {
+		return "rate(1 minute)"
+	}
+	return fmt.Sprintf("rate(%d minutes)", m)
+}
+
+// Arrivals returns the times, as offsets from the start, of sporadic
+// requests over duration: a Poisson process whose gaps average mean. The
+// times depend only on seed, so every function and every interval is
+// compared under the same traffic.
+func Arrivals(seed uint64, mean, duration time.Duration) []time.Duration {
+	rng := rand.New(rand.NewPCG(seed, 0))
+	var at []time.Duration
+	for t := time.Duration(rng.ExpFloat64() * float64(mean)); t < duration; t += time.Duration(rng.ExpFloat64() * float64(mean)) {
+		at = append(at, t)
+	}
+	return at
+}
@@ -0,0 +1,98 @@
+package keepwarm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseIntervals(t *testing.T) {
+	got, err := ParseIntervals("off, 1m,15m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{0, time.Minute, 15 * time.Minute}; !slices.Equal(got, want) {
+		t.Errorf("ParseIntervals = %v, want %v", got, want)
+	}
+	for _, bad := range []string{"30s", "90s", "never", ""} {
+		if _, err := ParseIntervals(bad); err == nil {
+			t.Errorf("ParseIntervals(%q) succeeded", bad)
+		}
+	}
+	if Schedule(time.Minute) != "rate(1 minute)" || Schedule(15*time.Minute) != "rate(15 minutes)" {
+		t.Errorf("schedules %q, %q", Schedule(time.Minute), Schedule(15*time.Minute))
+	}
+	if Label(0) != "off" || Label(5*time.Minute) != "5m" {
+		t.Errorf("labels %q, %q", Label(0), Label(5*time.Minute))
+	}
+}
+
+func TestArrivals(t *testing.T) {
+	a := Arrivals(1, 10*time.Minute, 100*time.Hour)
+	if !slices.Equal(a, Arrivals(1, 10*time.Minute, 100*time.Hour)) {
+		t.Error("arrivals differ for one seed")
+	}
+	if !slices.IsSorted(a) || a[len(a)-1] >= 100*time.Hour {
+		t.Error("arrivals out of order or past the duration")
+	}
+	// 600 expected; a Poisson count is within 3 sigma of it.
+	if n := len(a); n < 527 || n > 673 {
+		t.Errorf("%d arrivals in 100h at one per 10m", n)
+	}
+}
+
+func TestClient(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]any
+		json.NewDecoder(r.Body).Decode(&in)
+		target := r.Header.Get("X-Amz-Target")
+		calls = append(calls, target)
+		switch target {
+		case "AWSEvents.PutRule":
+			if in["ScheduleExpression"] != "rate(5 minutes)" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"RuleArn":"arn:rule:r"}`))
+		case "AWSEvents.PutTargets":
+			w.Write([]byte(`{"FailedEntryCount":0,"FailedEntries":[]}`))
+		case "AWSEvents.ListRules":
+			if in["NextToken"] == nil {
+				w.Write([]byte(`{"Rules":[{"Name":"a","Arn":"arn:rule:a"}],"NextToken":"t"}`))
+				return
+			}
+			w.Write([]byte(`{"Rules":[{"Name":"b","Arn":"arn:rule:b"}]}`))
+		case "AWSEvents.ListTagsForResource":
+			w.Write([]byte(`{"Tags":[{"Key":"ruchy-bench","Value":"true"}]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Rule gone does not exist on EventBus default."}`))
+		}
+	}))
+	defer srv.Close()
+	c := &Client{Endpoint: srv.URL}
+	ctx := context.Background()
+
+	if arn, err := c.Schedule(ctx, "r", 5*time.Minute, "arn:fn", []byte(`{}`)); err != nil || arn != "arn:rule:r" {
+		t.Errorf("Schedule = %q, %v", arn, err)
+	}
+	rules, err := c.List(ctx, rulePrefix)
+	if err != nil || len(rules) != 2 || rules[1].ARN != "arn:rule:b" {
+		t.Errorf("List = %v, %v", rules, err)
+	}
+	if tags, err := c.Tags(ctx, "arn:rule:a"); err != nil || tags[TagKey] != "true" {
+		t.Errorf("Tags = %v, %v", tags, err)
+	}
+	// Removing a rule already gone succeeds.
+	if err := c.Remove(ctx, "gone"); err != nil {
+		t.Errorf("Remove: %v", err)
+	}
+	if want := []string{"AWSEvents.PutRule", "AWSEvents.PutTargets"}; !slices.Equal(calls[:2], want) {
+		t.Errorf("calls %q", calls)
+	}
+}
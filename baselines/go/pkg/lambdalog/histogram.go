@@ -0,0 +1,169 @@
+package lambdalog
+
+import (
+	"encoding/json"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// HistogramEnv is the environment variable that, set to a number of
+// milliseconds, makes the Go baselines rerun their workload for that
+// long after every invocation's run and respond with a Histogram of the
+// runs' times. ruchy-bench deploy -histogram sets it.
+const HistogramEnv = "BENCH_HISTOGRAM_MS"
+
+// subBits is log2 of the buckets per power of two: 128 of them hold
+// every time to within 1%, as an HDR histogram of two significant
+// digits does.
+const subBits = 7
+
+// Histogram is an HDR histogram of the times of a workload's runs within
+// one invocation, in nanoseconds. Times below 2^(subBits+1) ns are
+// counted exactly; above, each power of two splits into 2^subBits
+// buckets of equal width.
+type Histogram struct {
+	// BudgetMS is how long the handler kept rerunning the workload.
+	BudgetMS float64
+	// Count is the number of runs, Sum their total and Min and Max the
+	// extremes, in nanoseconds.
+	Count, Sum, Min, Max int64
+	counts               []int64
+}
+
+// Record adds one run of d.
+func (h *Histogram) Record(d time.Duration) {
+	v := max(int64(d), 0)
+	i := bucket(v)
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]int64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	if h.Count == 0 || v < h.Min {
+		h.Min = v
+	}
+	h.Max = max(h.Max, v)
+	h.Count++
+	h.Sum += v
+}
+
+// Merge adds o's runs to h's.
+func (h *Histogram) Merge(o *Histogram) {
+	if o == nil || o.Count == 0 {
+		return
+	}
+	if len(o.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]int64, len(o.counts)-len(h.counts))...)
+	}
+	for i, n := range o.counts {
+		h.counts[i] += n
+	}
+	if h.Count == 0 || o.Min < h.Min {
+		h.Min = o.Min
+	}
+	h.Max = max(h.Max, o.Max)
+	h.Count += o.Count
+	h.Sum += o.Sum
+	h.BudgetMS += o.BudgetMS
+}
+
+// QuantileMS returns the q-quantile of the runs' times in milliseconds,
+// as the midpoint of the bucket holding it, clamped to Min and Max. It
+// is NaN for an empty histogram.
+func (h *Histogram) QuantileMS(q float64) float64 {
+	if h == nil || h.Count == 0 {
+		return math.NaN()
+	}
+	rank := int64(math.Ceil(q * float64(h.Count)))
+	var seen int64
+	for i, n := range h.counts {
+		if seen += n; seen >= max(rank, 1) {
+			lo, width := bucketRange(i)
+			v := min(max(float64(lo)+float64(width-1)/2, float64(h.Min)), float64(h.Max))
+			return v / 1e6
+		}
+	}
+	return float64(h.Max) / 1e6
+}
+
+// MeanMS is the runs' mean time in milliseconds.
+func (h *Histogram) MeanMS() float64 {
+	if h == nil || h.Count == 0 {
+		return math.NaN()
+	}
+	return float64(h.Sum) / float64(h.Count) / 1e6
+}
+
+// StddevMS is the standard deviation of the runs' times in milliseconds,
+// each counted at its bucket's midpoint.
+func (h *Histogram) StddevMS() float64 {
+	if h == nil || h.Count == 0 {
+		return math.NaN()
+	}
+	mean := float64(h.Sum) / float64(h.Count)
+	var ss float64
+	for i, n := range h.counts {
+		if n > 0 {
+			lo, width := bucketRange(i)
+			d := float64(lo) + float64(width-1)/2 - mean
+			ss += float64(n) * d * d
+		}
+	}
+	return math.Sqrt(ss/float64(h.Count)) / 1e6
+}
+
+// bucket is the index of the bucket counting v.
+func bucket(v int64) int {
+	e := bits.Len64(uint64(v)) - (subBits + 1)
+	if e <= 0 {
+		return int(v)
+	}
+	return e<<subBits + int(v>>e)
+}
+
+// bucketRange is the lowest value bucket i counts and how many it does.
+func bucketRange(i int) (lo, width int64) {
+	if i < 2<<subBits {
+		return int64(i), 1
+	}
+	e := i>>subBits - 1
+	return int64(i-e<<subBits) << e, 1 << e
+}
+
+// histogramJSON is a Histogram on the wire. Counts lists the non-empty
+// buckets as [lowest value in ns, runs] pairs in ascending order.
+type histogramJSON struct {
+	BudgetMS float64    `json:"budget_ms"`
+	Count    int64      `json:"count"`
+	SumNS    int64      `json:"sum_ns"`
+	MinNS    int64      `json:"min_ns"`
+	MaxNS    int64      `json:"max_ns"`
+	Counts   [][2]int64 `json:"counts"`
+}
+
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	out := histogramJSON{BudgetMS: h.BudgetMS, Count: h.Count, SumNS: h.Sum, MinNS: h.Min, MaxNS: h.Max, Counts: [][2]int64{}}
+	for i, n := range h.counts {
+		if n > 0 {
+			lo, _ := bucketRange(i)
+			out.Counts = append(out.Counts, [2]int64{lo, n})
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var in histogramJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*h = Histogram{BudgetMS: in.BudgetMS, Count: in.Count, Sum: in.SumNS, Min: in.MinNS, Max: in.MaxNS}
+	for _, c := range in.Counts {
+		i := bucket(max(c[0], 0))
+		if i >= len(h.counts) {
+			h.counts = append(h.counts, make([]int64, i+1-len(h.counts))...)
+		}
+		h.counts[i] += c[1]
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package lambdalog
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Host is the hardware an invocation ran on, as the execution
+// environment sees it. Lambda places environments on hosts of several CPU
+// generations, and a fibonacci(35) on one is measurably slower than on
+// the next; Host lets the harness tell the two apart instead of charging
+// the difference to the runtime.
+type Host struct {
+	// CPUModel is /proc/cpuinfo's model name. Firecracker masks it to a
+	// generic "Intel(R) Xeon(R) Processor @ 2.90GHz", and arm64 kernels
+	// do not report one at all, so CPUGeneration is what to group by.
+	CPUModel string `json:"cpu_model,omitempty"`
+	// CPUGeneration names the microarchitecture from the CPU's family
+	// and model, or implementer and part on arm64: "skylake",
+	// "cascadelake", "icelake", "milan" or "neoverse-n1" (Graviton2),
+	// say. A CPU missing from the table is named by its raw identifiers,
+	// as in "intel-6-183", so it still groups. Empty when /proc/cpuinfo
+	// could not be read.
+	CPUGeneration string `json:"cpu_generation,omitempty"`
+	// Sandbox identifies the execution environment: a hash of the
+	// kernel's boot_id, which every Firecracker microVM draws afresh.
+	// Invocations with the same Sandbox ran in the same environment, on
+	// the same host.
+	Sandbox string `json:"sandbox,omitempty"`
+}
+
+// Paths ReadHost reads, variables so tests can point them elsewhere.
+var (
+	cpuinfoPath = "/proc/cpuinfo"
+	bootIDPath  = "/proc/sys/kernel/random/boot_id"
+)
+
+// ReadHost reads the host of the running process, nil where neither file
+// it reads exists, as off Linux. It reads a few kilobytes from procfs, so
+// handlers call it once per environment rather than per invocation.
+func ReadHost() *Host {
+	var h Host
+	if f, err := os.Open(cpuinfoPath); err == nil {
+		h.CPUModel, h.CPUGeneration = parseCPUInfo(bufio.NewScanner(f))
+		f.Close()
+	}
+	if id, err := os.ReadFile(bootIDPath); err == nil {
+		sum := sha256.Sum256([]byte(strings.TrimSpace(string(id))))
+		h.Sandbox = hex.EncodeToString(sum[:6])
+	}
+	if h == (Host{}) {
+		return nil
+	}
+	return &h
+}
+
+// parseCPUInfo returns the model name and generation of the first
+// processor /proc/cpuinfo lists: every vCPU of an environment is the
+// same.
+func parseCPUInfo(sc *bufio.Scanner) (model, generation string) {
+	fields := map[string]string{}
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" && len(fields) > 0 {
+			break
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			fields[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	num := func(k string) int {
+		n, err := strconv.ParseInt(fields[k], 0, 0)
+		if err != nil {
+			return -1
+		}
+		return int(n)
+	}
+	model = fields["model name"]
+	switch vendor := fields["vendor_id"]; {
+	case vendor == "GenuineIntel":
+		generation = intelGeneration(num("cpu family"), num("model"), num("stepping"))
+	case vendor == "AuthenticAMD":
+		generation = amdGeneration(num("cpu family"), num("model"))
+	case fields["CPU implementer"] != "":
+		generation = armGeneration(num("CPU implementer"), num("CPU part"))
+	}
+	return model, generation
+}
+
+// intelGeneration names the Xeon server generations Lambda and EC2 have
+// run on. Skylake-SP, Cascade Lake and Cooper Lake share model 85 and
+// differ in stepping.
+func intelGeneration(family, model, stepping int) string {
+	if family == 6 {
+		switch model {
+		case 63:
+			return "haswell"
+		case 79:
+			return "broadwell"
+		case 85:
+			switch {
+			case stepping <= 4:
+				return "skylake"
+			case stepping <= 7:
+				return "cascadelake"
+			default:
+				return "cooperlake"
+			}
+		case 106, 108:
+			return "icelake"
+		case 143:
+			return "sapphirerapids"
+		case 207:
+			return "emeraldrapids"
+		case 173:
+			return "graniterapids"
+		}
+	}
+	return fmt.Sprintf("intel-%d-%d", family, model)
+}
+
+// amdGeneration names the EPYC generations.
+func amdGeneration(family, model int) string {
+	switch {
+	case family == 23 && model < 48:
+		return "naples"
+	case family == 23 && model < 64:
+		return "rome"
+	case family == 25 && model < 16:
+		return "milan"
+	case family == 25 && model < 32:
+		return "genoa"
+	case family == 26 && model < 16:
+		return "turin"
+	}
+	return fmt.Sprintf("amd-%d-%d", family, model)
+}
+
+// armGeneration names Arm's Neoverse cores by their part numbers: the
+// N1, V1 and V2 are Graviton2, 3 and 4.
+func armGeneration(implementer, part int) string {
+	if implementer == 0x41 {
+		switch part {
+		case 0xd0c:
+			return "neoverse-n1"
+		case 0xd40:
+			return "neoverse-v1"
+		case 0xd4f:
+			return "neoverse-v2"
+		}
+	}
+	return fmt.Sprintf("arm-%#x-%#x", implementer, part)
+}
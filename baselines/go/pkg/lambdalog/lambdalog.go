@@ -0,0 +1,157 @@
+// Package lambdalog writes the structured log line every Go baseline
+// emits at the end of an invocation: one JSON object with the request ID,
+// workload, workload parameters and handler duration. The request ID is
+// the one in Lambda's REPORT line, so the harness joins the two on it
+// rather than on log timestamps (see reportparser.FetchInvocations).
+//
+// It is imported by the handlers themselves, so it depends on nothing the
+// aws-lambda-go runtime does not already link. main-runtimeapi.go does
+// not use it: that baseline exists to link nothing beyond net/http.
+package lambdalog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// Type is the "type" of invocation lines, which tells them apart from
+// whatever else a handler logs.
+const Type = "invocation"
+
+// RuntimeMetricsEnv is the environment variable that, set to 1, makes the
+// Go baselines sample runtime/metrics around every invocation; see
+// GoRuntime. ruchy-bench deploy -runtime-metrics sets it.
+const RuntimeMetricsEnv = "BENCH_RUNTIME_METRICS"
+
+// Params are the workload inputs an invocation ran with, such as the n of
+// fibonacci(n).
+type Params map[string]any
+
+// Entry is one invocation line.
+type Entry struct {
+	Type       string  `json:"type"`
+	RequestID  string  `json:"request_id"`
+	Workload   string  `json:"workload"`
+	Params     Params  `json:"params,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+	// DecodeMS is the part of the duration spent decoding the event.
+	DecodeMS float64 `json:"decode_ms,omitempty"`
+	// Go is the runtime activity during the invocation, when sampled.
+	Go *GoRuntime `json:"go_runtime,omitempty"`
+	// Init splits the Go side of a cold start, on an environment's first
+	// invocation only.
+	Init *GoInit `json:"go_init,omitempty"`
+	// Host is the hardware the execution environment runs on.
+	Host *Host `json:"host,omitempty"`
+	// Records are the outcomes of a batch event's records, in order.
+	Records []Record `json:"records,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Record is how an invocation handled one record of a batch, such as an
+// SQS message.
+type Record struct {
+	ID string `json:"id"`
+	// AgeMS is the time from the record entering its source to the
+	// handler finishing with it: end-to-end latency, as both clocks are
+	// AWS's.
+	AgeMS float64 `json:"age_ms"`
+	// Failed records were reported back for redelivery.
+	Failed bool `json:"failed,omitempty"`
+}
+
+// GoRuntime is what the Go runtime did during one invocation, from
+// runtime/metrics. The counts are deltas over the invocation; Goroutines
+// and HeapBytes are taken at its end. Its field names are
+// results.Metric* names, so the harness reads the response's copy into
+// per-sample metrics directly.
+type GoRuntime struct {
+	AllocBytes float64 `json:"go_alloc_bytes"`
+	Allocs     float64 `json:"go_allocs"`
+	GCCycles   float64 `json:"go_gc_cycles"`
+	// GCPauseMS is total stop-the-world GC time, estimated from the pause
+	// histogram's bucket midpoints.
+	GCPauseMS  float64 `json:"go_gc_pause_ms"`
+	Goroutines float64 `json:"go_goroutines"`
+	HeapBytes  float64 `json:"go_heap_bytes"`
+}
+
+// GoInit is how a Go baseline spent the time after its packages were
+// initialized, up to its first response. Lambda's Init Duration covers
+// the process start, the Go runtime's and every package's initialization
+// and lambda.Start reaching the Runtime API; ToHandlerMS is the part of
+// that after the handler package initialized, plus the wait for the
+// first event, and DecodeMS the first event's decoding, which Init
+// Duration does not include. Like GoRuntime its field names are
+// results.Metric* names.
+type GoInit struct {
+	ToHandlerMS float64 `json:"go_init_to_handler_ms"`
+	DecodeMS    float64 `json:"go_first_decode_ms"`
+}
+
+// out is where entries go; Lambda sends a function's standard output to
+// its log group.
+var out io.Writer = os.Stdout
+
+// Wrap returns h logging an entry for workload after every call.
+func Wrap[Out any](workload string, params Params, h func(context.Context) (Out, error)) func(context.Context) (Out, error) {
+	return func(ctx context.Context) (Out, error) {
+		start := time.Now()
+		resp, err := h(ctx)
+		Log(ctx, Entry{Workload: workload, Params: params}, start, err)
+		return resp, err
+	}
+}
+
+// WrapEvent is Wrap for handlers that take an event.
+func WrapEvent[In, Out any](workload string, params Params, h func(context.Context, In) (Out, error)) func(context.Context, In) (Out, error) {
+	return func(ctx context.Context, event In) (Out, error) {
+		start := time.Now()
+		resp, err := h(ctx, event)
+		Log(ctx, Entry{Workload: workload, Params: params}, start, err)
+		return resp, err
+	}
+}
+
+// Log writes e for an invocation that started at start and failed with
+// err, if not nil, filling in its type, request ID, duration and error.
+// Handlers whose params depend on the event, or that sample the runtime,
+// call it directly instead of being wrapped.
+func Log(ctx context.Context, e Entry, start time.Time, err error) {
+	e.Type = Type
+	e.DurationMS = float64(time.Since(start)) / float64(time.Millisecond)
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		e.RequestID = lc.AwsRequestID
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	line, merr := json.Marshal(e)
+	if merr != nil {
+		// Params that cannot be encoded are a bug in the handler; log
+		// the entry without them.
+		e.Params = nil
+		line, _ = json.Marshal(e)
+	}
+	out.Write(append(line, '\n'))
+}
+
+// Parse parses a single invocation line, reporting false for any other
+// line.
+func Parse(line string) (Entry, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return Entry{}, false
+	}
+	var e Entry
+	if json.Unmarshal([]byte(line), &e) != nil || e.Type != Type || e.RequestID == "" {
+		return Entry{}, false
+	}
+	return e, true
+}
@@ -0,0 +1,151 @@
+package lambdalog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+func TestWrap(t *testing.T) {
+	var buf bytes.Buffer
+	out = &buf
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "req-1"})
+
+	h := Wrap("fibonacci", Params{"n": 35}, func(context.Context) (int, error) { return 9227465, nil })
+	if got, err := h(ctx); got != 9227465 || err != nil {
+		t.Fatalf("wrapped handler = %d, %v", got, err)
+	}
+	e, ok := Parse(buf.String())
+	if !ok || e.RequestID != "req-1" || e.Workload != "fibonacci" || e.Params["n"] != 35.0 || e.Error != "" {
+		t.Errorf("entry = %+v, %v from %q", e, ok, buf.String())
+	}
+
+	buf.Reset()
+	failing := WrapEvent("s3", nil, func(context.Context, string) (string, error) { return "", errors.New("access denied") })
+	if _, err := failing(ctx, "event"); err == nil {
+		t.Fatal("error swallowed")
+	}
+	if e, ok := Parse(buf.String()); !ok || e.Error != "access denied" || e.Params != nil {
+		t.Errorf("failed entry = %+v, %v", e, ok)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, line := range []string{
+		"REPORT RequestId: req-1\tDuration: 1.00 ms",
+		`{"type":"other","request_id":"req-1"}`,
+		`{"type":"invocation"}`,
+		`{"type":`,
+	} {
+		if e, ok := Parse(line); ok {
+			t.Errorf("Parse(%q) = %+v", line, e)
+		}
+	}
+}
+
+// lambdaX86 and lambdaARM are the heads of /proc/cpuinfo in x86_64 and
+// arm64 Lambda environments.
+const (
+	lambdaX86 = `processor	: 0
+vendor_id	: GenuineIntel
+cpu family	: 6
+model		: 85
+model name	: Intel(R) Xeon(R) Processor @ 2.90GHz
+stepping	: 7
+
+processor	: 1
+vendor_id	: AuthenticAMD
+`
+	lambdaARM = `processor	: 0
+BogoMIPS	: 243.75
+Features	: fp asimd evtstrm aes pmull sha1 sha2 crc32 atomics fphp asimdhp cpuid
+CPU implementer	: 0x41
+CPU architecture: 8
+CPU variant	: 0x1
+CPU part	: 0xd40
+CPU revision	: 1
+`
+)
+
+func TestParseCPUInfo(t *testing.T) {
+	for _, tc := range []struct{ cpuinfo, model, generation string }{
+		{lambdaX86, "Intel(R) Xeon(R) Processor @ 2.90GHz", "cascadelake"},
+		{lambdaARM, "", "neoverse-v1"},
+		{"vendor_id : AuthenticAMD\ncpu family : 25\nmodel : 1\n", "", "milan"},
+		{"vendor_id : GenuineIntel\ncpu family : 6\nmodel : 183\n", "", "intel-6-183"},
+		{"", "", ""},
+	} {
+		model, generation := parseCPUInfo(bufio.NewScanner(strings.NewReader(tc.cpuinfo)))
+		if model != tc.model || generation != tc.generation {
+			t.Errorf("parseCPUInfo(%.40q) = %q, %q; want %q, %q", tc.cpuinfo, model, generation, tc.model, tc.generation)
+		}
+	}
+}
+
+func TestReadHost(t *testing.T) {
+	dir := t.TempDir()
+	cpuinfoPath, bootIDPath = filepath.Join(dir, "cpuinfo"), filepath.Join(dir, "boot_id")
+	if h := ReadHost(); h != nil {
+		t.Errorf("host without procfs = %+v", h)
+	}
+	os.WriteFile(cpuinfoPath, []byte(lambdaARM), 0o644)
+	os.WriteFile(bootIDPath, []byte("5c9d2b5e-3f4a-4b8e-9c1d-7e6f5a4b3c2d\n"), 0o644)
+	h := ReadHost()
+	if h == nil || h.CPUGeneration != "neoverse-v1" || len(h.Sandbox) != 12 {
+		t.Fatalf("host = %+v", h)
+	}
+	os.WriteFile(bootIDPath, []byte("0b3f7a1c-8d2e-4f6a-b5c9-1e2d3c4b5a69\n"), 0o644)
+	if other := ReadHost(); other.Sandbox == h.Sandbox {
+		t.Errorf("two boots share sandbox %s", h.Sandbox)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	for v := int64(0); v < 1<<20; v += 37 {
+		lo, width := bucketRange(bucket(v))
+		if v < lo || v >= lo+width || width > max(1, lo>>subBits) {
+			t.Fatalf("%d counted in [%d, %d)", v, lo, lo+width)
+		}
+	}
+
+	var h Histogram
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	for q, want := range map[float64]float64{0: 0.001, 0.5: 0.5, 0.99: 0.99, 1: 1} {
+		if got := h.QuantileMS(q); math.Abs(got-want)/want > 0.01 {
+			t.Errorf("q%g = %g, want %g", q, got, want)
+		}
+	}
+	if h.MeanMS() != 0.5005 || math.Abs(h.StddevMS()-0.2887)/0.2887 > 0.01 {
+		t.Errorf("mean %g, stddev %g", h.MeanMS(), h.StddevMS())
+	}
+
+	h.BudgetMS = 200
+	data, err := json.Marshal(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Histogram
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatal(err)
+	}
+	back.Merge(&h)
+	if back.Count != 2000 || back.BudgetMS != 400 || back.Min != h.Min || back.Max != h.Max || back.QuantileMS(0.5) != h.QuantileMS(0.5) {
+		t.Errorf("round trip and merge = %+v", back)
+	}
+	var empty Histogram
+	if !math.IsNaN(empty.QuantileMS(0.5)) {
+		t.Error("empty histogram has a median")
+	}
+}
@@ -0,0 +1,98 @@
+package loadgen
+
+import (
+	"math"
+
+	"lambdaperf/pkg/results"
+)
+
+// Histogram buckets latencies on a logarithmic scale: four buckets per
+// doubling from 0.1 ms, so any quantile is reported within ~19% without
+// keeping every value. Values past the last bound land in the overflow
+// bucket.
+type Histogram struct {
+	counts []int
+	n      int
+	min    float64
+	max    float64
+}
+
+const (
+	histBase    = 0.1 // ms, upper bound of the first bucket
+	histPerOct  = 4   // buckets per doubling
+	histBuckets = 4*20 + 1
+)
+
+// NewHistogram returns an empty histogram covering 0.1 ms to ~100 s.
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]int, histBuckets+1), min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// upper is the upper bound of bucket i.
+func upper(i int) float64 {
+	return histBase * math.Pow(2, float64(i)/histPerOct)
+}
+
+func bucket(ms float64) int {
+	if ms <= histBase {
+		return 0
+	}
+	i := int(math.Ceil(math.Log2(ms/histBase) * histPerOct))
+	// Guard against rounding just past a bound.
+	if i > 0 && ms <= upper(i-1) {
+		i--
+	}
+	if i > histBuckets {
+		return histBuckets
+	}
+	return i
+}
+
+// Record adds one latency in milliseconds.
+func (h *Histogram) Record(ms float64) {
+	h.counts[bucket(ms)]++
+	h.n++
+	h.min = math.Min(h.min, ms)
+	h.max = math.Max(h.max, ms)
+}
+
+// Count is the number of recorded values.
+func (h *Histogram) Count() int { return h.n }
+
+// Quantile returns the upper bound of the bucket holding the q-th
+// quantile (0 < q ≤ 1), clamped to the observed range. Zero when empty.
+func (h *Histogram) Quantile(q float64) float64 {
+	if h.n == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(q * float64(h.n)))
+	if rank < 1 {
+		rank = 1
+	}
+	seen := 0
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return math.Max(h.min, math.Min(upper(i), h.max))
+		}
+	}
+	return h.max
+}
+
+// Buckets returns the non-empty buckets in ascending order. The overflow
+// bucket is bounded by the largest recorded value, since JSON has no
+// infinity.
+func (h *Histogram) Buckets() []results.Bucket {
+	var out []results.Bucket
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		le := upper(i)
+		if i == histBuckets {
+			le = h.max
+		}
+		out = append(out, results.Bucket{UpperMS: le, Count: c})
+	}
+	return out
+}
@@ -0,0 +1,208 @@
+// Package loadgen drives a function with concurrent invocations at a
+// target request rate. Sequential benchmarks only ever see one warm
+// environment; under load, throttling, concurrency scaling and the cold
+// starts of new environments show up in the latency distribution.
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/results"
+)
+
+// Config describes one load run.
+type Config struct {
+	// Workers is the number of concurrent invokers, and so the most
+	// requests in flight at once.
+	Workers int
+	// RPS is the target request rate across all workers. Zero runs
+	// closed-loop: every worker invokes again as soon as it gets a reply.
+	RPS float64
+	// Duration is how long requests are started for; in-flight requests
+	// are allowed to finish.
+	Duration time.Duration
+	Payload  []byte
+	// Expected is the result every response must report; requests that
+	// return anything else fail. Empty accepts any response.
+	Expected string
+}
+
+// Result is the outcome of a load run.
+type Result struct {
+	// Samples holds every completed request in start order.
+	Samples []results.Sample
+	// Client is the histogram of client-observed latency of successful
+	// requests.
+	Client *Histogram
+	// Missed counts request slots skipped because every worker was busy,
+	// i.e. how far the achieved rate fell short of RPS.
+	Missed int
+	// Throttled counts requests Lambda rejected with 429.
+	Throttled int
+	Elapsed   time.Duration
+	// Late counts the events of a replay that started over LateAfter
+	// behind their time, all Workers requests being in flight, and
+	// MaxLag is the furthest behind one started.
+	Late   int
+	MaxLag time.Duration
+	// ScaleUp is the time from the start until every worker had had a
+	// successful reply: until the function served Workers requests at
+	// once, the cold starts of the environments it added included. It is
+	// zero, and ScaledUp false, if some worker never had one.
+	ScaleUp  time.Duration
+	ScaledUp bool
+}
+
+// AchievedRPS is the completed request rate over the run.
+func (r Result) AchievedRPS() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(len(r.Samples)) / r.Elapsed.Seconds()
+}
+
+// Generator runs load against one invoker.
+type Generator struct {
+	Invoker invoke.Invoker
+	Config  Config
+}
+
+// Run generates load until Duration elapses or ctx is cancelled.
+func (g *Generator) Run(ctx context.Context) (Result, error) {
+	c := g.Config
+	if c.Workers < 1 || c.Duration <= 0 || c.RPS < 0 {
+		return Result{}, errors.New("loadgen: need at least one worker, a positive duration and a non-negative rate")
+	}
+	runCtx, stop := context.WithTimeout(ctx, c.Duration)
+	defer stop()
+
+	var (
+		mu   sync.Mutex
+		res  = Result{Client: NewHistogram()}
+		seq  int
+		wg   sync.WaitGroup
+		work = make(chan struct{})
+	)
+	next := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		seq++
+		return seq - 1
+	}
+
+	start := time.Now()
+	firstOK := make([]time.Duration, c.Workers)
+	for w := 0; w < c.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if c.RPS > 0 {
+					if _, ok := <-work; !ok {
+						return
+					}
+				} else if runCtx.Err() != nil {
+					return
+				}
+				// In-flight requests use the parent context so the
+				// deadline stops new work without aborting replies.
+				s, throttled := invokeOnce(ctx, g.Invoker, c.Payload, c.Expected, next())
+				mu.Lock()
+				res.Samples = append(res.Samples, s)
+				if throttled {
+					res.Throttled++
+				}
+				if s.Error == "" {
+					res.Client.Record(s.ClientMS)
+					if firstOK[w] == 0 {
+						firstOK[w] = time.Since(start)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	if c.RPS > 0 {
+		res.Missed = pace(runCtx, work, c.RPS)
+		close(work)
+	}
+	wg.Wait()
+	res.Elapsed = time.Since(start)
+	res.ScaledUp = true
+	for _, d := range firstOK {
+		if d == 0 {
+			res.ScaleUp, res.ScaledUp = 0, false
+			break
+		}
+		res.ScaleUp = max(res.ScaleUp, d)
+	}
+	sortByIteration(res.Samples)
+	return res, ctx.Err()
+}
+
+// pace offers one request slot per 1/rps interval until ctx ends. A slot no
+// idle worker takes is counted as missed rather than queued, so a
+// saturated function cannot build an unbounded backlog.
+func pace(ctx context.Context, work chan<- struct{}, rps float64) (missed int) {
+	interval := time.Duration(float64(time.Second) / rps)
+	if interval <= 0 {
+		interval = time.Nanosecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case work <- struct{}{}:
+			// A worker took the slot; wait for the next tick.
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return missed
+			}
+		case <-ticker.C:
+			missed++
+		case <-ctx.Done():
+			return missed
+		}
+	}
+}
+
+// invokeOnce performs invocation iteration with payload, reporting
+// whether Lambda throttled it.
+func invokeOnce(ctx context.Context, inv invoke.Invoker, payload []byte, expected string, iteration int) (results.Sample, bool) {
+	resp, err := inv.Invoke(ctx, payload)
+	s := results.Sample{
+		Iteration: iteration,
+		ClientMS:  results.Milliseconds(resp.Elapsed),
+	}.WithResponse(resp.Payload)
+	if r, ok := reportparser.Last(resp.LogTail); ok {
+		s = s.WithReport(r)
+	}
+	var throttled *types.TooManyRequestsException
+	switch {
+	case err != nil:
+		s.Error = err.Error()
+	case resp.FunctionError != "":
+		s.Error = resp.FunctionError
+	default:
+		s = s.Verify(expected)
+	}
+	return s, errors.As(err, &throttled)
+}
+
+func sortByIteration(samples []results.Sample) {
+	// Iterations are a permutation of 0..n-1.
+	ordered := make([]results.Sample, len(samples))
+	for _, s := range samples {
+		ordered[s.Iteration] = s
+	}
+	copy(samples, ordered)
+}
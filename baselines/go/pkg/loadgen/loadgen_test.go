@@ -0,0 +1,298 @@
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/invoke"
+)
+
+// fakeInvoker sleeps for latency, tracks peak concurrency, throttles
+// every throttleEvery-th request and answers every wrongEvery-th with the
+// wrong result.
+type fakeInvoker struct {
+	latency       time.Duration
+	throttleEvery int64
+	wrongEvery    int64
+	calls         atomic.Int64
+	inFlight      atomic.Int64
+	mu            sync.Mutex
+	peak          int64
+}
+
+func (f *fakeInvoker) Invoke(ctx context.Context, _ []byte) (invoke.Response, error) {
+	n := f.calls.Add(1)
+	cur := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+	f.mu.Lock()
+	f.peak = max(f.peak, cur)
+	f.mu.Unlock()
+	if f.throttleEvery > 0 && n%f.throttleEvery == 0 {
+		return invoke.Response{Elapsed: time.Millisecond}, fmt.Errorf("invoke: %w", &types.TooManyRequestsException{Message: aws.String("Rate exceeded")})
+	}
+	time.Sleep(f.latency)
+	tail := fmt.Sprintf("REPORT RequestId: r%d\tDuration: 1.00 ms\tBilled Duration: 1 ms\tMemory Size: 128 MB\tMax Memory Used: 14 MB\t\n", n)
+	body := `{"statusCode":200,"body":"fibonacci(35)=9227465"}`
+	if f.wrongEvery > 0 && n%f.wrongEvery == 0 {
+		body = `{"statusCode":200,"body":"fibonacci(35)=0"}`
+	}
+	return invoke.Response{Elapsed: f.latency, LogTail: tail, Payload: []byte(body)}, nil
+}
+
+func TestClosedLoopUsesAllWorkers(t *testing.T) {
+	fake := &fakeInvoker{latency: 5 * time.Millisecond, throttleEvery: 10}
+	g := &Generator{Invoker: fake, Config: Config{Workers: 4, Duration: 100 * time.Millisecond}}
+	res, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.peak != 4 {
+		t.Errorf("peak concurrency = %d, want 4", fake.peak)
+	}
+	if len(res.Samples) != int(fake.calls.Load()) {
+		t.Errorf("%d samples for %d calls", len(res.Samples), fake.calls.Load())
+	}
+	for i, s := range res.Samples {
+		if s.Iteration != i {
+			t.Fatalf("sample %d has iteration %d", i, s.Iteration)
+		}
+	}
+	if res.Throttled == 0 || res.Throttled != len(res.Samples)/10 {
+		t.Errorf("throttled = %d of %d", res.Throttled, len(res.Samples))
+	}
+	if res.Client.Count() != len(res.Samples)-res.Throttled {
+		t.Errorf("histogram has %d values, want %d", res.Client.Count(), len(res.Samples)-res.Throttled)
+	}
+	if res.Samples[0].RequestID == "" {
+		t.Error("REPORT metrics not recorded")
+	}
+}
+
+func TestWrongResultsFail(t *testing.T) {
+	fake := &fakeInvoker{latency: time.Millisecond, wrongEvery: 3}
+	g := &Generator{Invoker: fake, Config: Config{Workers: 2, Duration: 50 * time.Millisecond, Expected: "fibonacci(35)=9227465"}}
+	res, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrong := 0
+	for _, s := range res.Samples {
+		if s.Wrong() {
+			wrong++
+		}
+	}
+	if wrong == 0 || wrong != len(res.Samples)/3 {
+		t.Errorf("%d of %d samples wrong", wrong, len(res.Samples))
+	}
+	if res.Client.Count() != len(res.Samples)-wrong {
+		t.Errorf("histogram has %d values, want %d", res.Client.Count(), len(res.Samples)-wrong)
+	}
+}
+
+func TestPacedRateCountsMissedSlots(t *testing.T) {
+	// One worker taking 20 ms cannot sustain 200 RPS: most slots are missed.
+	fake := &fakeInvoker{latency: 20 * time.Millisecond}
+	g := &Generator{Invoker: fake, Config: Config{Workers: 1, RPS: 200, Duration: 200 * time.Millisecond}}
+	res, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(res.Samples); n < 5 || n > 12 {
+		t.Errorf("%d requests, want about 10", n)
+	}
+	if res.Missed < 20 {
+		t.Errorf("missed = %d, want most of ~40 slots", res.Missed)
+	}
+	if got := res.AchievedRPS(); got > 60 {
+		t.Errorf("achieved %v RPS through one 20 ms worker", got)
+	}
+}
+
+func TestRunRejectsBadConfig(t *testing.T) {
+	g := &Generator{Invoker: &fakeInvoker{}, Config: Config{Workers: 0, Duration: time.Second}}
+	if _, err := g.Run(context.Background()); err == nil {
+		t.Error("accepted zero workers")
+	}
+}
+
+func TestHistogramQuantiles(t *testing.T) {
+	h := NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		h.Record(float64(i))
+	}
+	for _, tt := range []struct{ q, want float64 }{{0.5, 500}, {0.99, 990}, {1, 1000}} {
+		got := h.Quantile(tt.q)
+		if got < tt.want || got > tt.want*math.Pow(2, 0.25) {
+			t.Errorf("Quantile(%v) = %v, want within one bucket above %v", tt.q, got, tt.want)
+		}
+	}
+	total := 0
+	for _, b := range h.Buckets() {
+		total += b.Count
+	}
+	if total != 1000 {
+		t.Errorf("buckets hold %d values, want 1000", total)
+	}
+	h.Record(1e9)
+	if last := h.Buckets()[len(h.Buckets())-1]; last.UpperMS != 1e9 || last.Count != 1 {
+		t.Errorf("overflow bucket = %+v", last)
+	}
+	if NewHistogram().Quantile(0.5) != 0 {
+		t.Error("empty histogram quantile not zero")
+	}
+}
+
+func TestRamp(t *testing.T) {
+	fake := &fakeInvoker{latency: 2 * time.Millisecond}
+	levels, err := Ramp(context.Background(), fake, RampConfig{Levels: []int{1, 2, 4}, Step: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(levels) != 3 || fake.peak != 4 {
+		t.Fatalf("%d levels, peak concurrency %d", len(levels), fake.peak)
+	}
+	for _, l := range levels {
+		if !l.Result.ScaledUp || l.Result.ScaleUp <= 0 || l.Result.ScaleUp > 50*time.Millisecond {
+			t.Errorf("level %d: scaled up %v in %s", l.Concurrency, l.Result.ScaledUp, l.Result.ScaleUp)
+		}
+	}
+	samples := Samples(levels)
+	if len(samples) != int(fake.calls.Load()) {
+		t.Errorf("%d samples for %d calls", len(samples), fake.calls.Load())
+	}
+	for i, s := range samples {
+		if s.Iteration != i {
+			t.Fatalf("sample %d has iteration %d", i, s.Iteration)
+		}
+	}
+
+	// A level whose workers are all throttled never scales up.
+	throttled, _ := Ramp(context.Background(), &fakeInvoker{throttleEvery: 1}, RampConfig{Levels: []int{2}, Step: 10 * time.Millisecond})
+	if r := throttled[0].Result; r.ScaledUp || r.ScaleUp != 0 || r.Throttled == 0 {
+		t.Errorf("throttled level = scaled up %v in %s, %d throttled", r.ScaledUp, r.ScaleUp, r.Throttled)
+	}
+	if _, err := Ramp(context.Background(), fake, RampConfig{Step: time.Second}); err == nil {
+		t.Error("ramp without levels ran")
+	}
+}
+
+func TestReadTrace(t *testing.T) {
+	for name, trace := range map[string]string{
+		"offsets": `{"offset_ms": 2500, "payload": {"n": 30}}
+{"offset_ms": 1000, "payload": {"n": 35}}
+
+{"offset_ms": 1000.25, "payload": {"n": 25}}
+`,
+		"timestamps": `{"timestamp": "2025-11-02T10:00:01.5Z", "payload": {"n": 30}}
+{"timestamp": 1762077600000, "payload": {"n": 35}}
+{"timestamp": "2025-11-02T11:00:00.00025+01:00", "payload": {"n": 25}}
+`,
+		"insights download": `[
+{"@timestamp": "2025-11-02 10:00:01.500", "@message": "2025-11-02T10:00:01.500Z\treq-3\tINFO\t{\"n\": 30}\n"},
+{"@timestamp": "2025-11-02 10:00:00.000", "@message": "START RequestId: req-1 Version: $LATEST"},
+{"@timestamp": "2025-11-02 10:00:00.000", "@message": "event {\"n\": 35}"},
+{"@timestamp": "2025-11-02 10:00:00.00025", "@message": "{\"n\": 25}"}
+]`,
+		"get-query-results": `{"status": "Complete", "results": [
+[{"field": "@timestamp", "value": "2025-11-02 10:00:00.000"}, {"field": "@message", "value": "{\"n\": 35}"}],
+[{"field": "@timestamp", "value": "2025-11-02 10:00:00.00025"}, {"field": "@message", "value": "{\"n\": 25}"}],
+[{"field": "@timestamp", "value": "2025-11-02 10:00:01.500"}, {"field": "@message", "value": "{\"n\": 30}"}]
+]}`,
+	} {
+		events, err := ReadTrace(strings.NewReader(trace))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		var got []string
+		for _, e := range events {
+			var compact bytes.Buffer
+			json.Compact(&compact, e.Payload)
+			got = append(got, fmt.Sprintf("%v %s", e.At, compact.String()))
+		}
+		if want := `0s {"n":35}|250µs {"n":25}|1.5s {"n":30}`; strings.Join(got, "|") != want {
+			t.Errorf("%s: events %s, want %s", name, strings.Join(got, "|"), want)
+		}
+		if Span(events) != 1500*time.Millisecond {
+			t.Errorf("%s: span %s", name, Span(events))
+		}
+	}
+	for name, trace := range map[string]string{
+		"empty":      "",
+		"no payload": `[{"@timestamp": "2025-11-02 10:00:00.000", "@message": "END RequestId: req-1"}]`,
+		"untimed":    `{"payload": {}}`,
+		"mixed":      "{\"offset_ms\": 0, \"payload\": {}}\n{\"timestamp\": 0, \"payload\": {}}",
+		"bad time":   `{"timestamp": "yesterday", "payload": {}}`,
+		"unknown":    `{"event": {}}`,
+	} {
+		if events, err := ReadTrace(strings.NewReader(trace)); err == nil {
+			t.Errorf("%s: read %v", name, events)
+		}
+	}
+}
+
+func TestWriteTraceRoundTrips(t *testing.T) {
+	events := []Event{{0, json.RawMessage(`{"n": 35}`)}, {1500 * time.Microsecond, json.RawMessage(`"ping"`)}}
+	var buf bytes.Buffer
+	if err := WriteTrace(&buf, events); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"offset_ms\":0,\"payload\":{\"n\":35}}\n{\"offset_ms\":1.5,\"payload\":\"ping\"}\n"; buf.String() != want {
+		t.Errorf("wrote %q", buf.String())
+	}
+	back, err := ReadTrace(&buf)
+	if err != nil || len(back) != 2 || back[1].At != events[1].At || string(back[1].Payload) != `"ping"` {
+		t.Errorf("read back %v, %v", back, err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	// A burst of four, then two more 200 ms later, replayed four times as
+	// fast.
+	var events []Event
+	for _, ms := range []int{0, 0, 0, 0, 200, 200} {
+		events = append(events, Event{At: time.Duration(ms) * time.Millisecond, Payload: json.RawMessage(`{}`)})
+	}
+	fake := &fakeInvoker{latency: 20 * time.Millisecond}
+	start := time.Now()
+	res, err := Replay(context.Background(), fake, ReplayConfig{Events: events, Speed: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 70*time.Millisecond || elapsed > 150*time.Millisecond {
+		t.Errorf("replay took %s, want about 70 ms", elapsed)
+	}
+	if fake.peak != 4 || len(res.Samples) != 6 || res.Late != 0 {
+		t.Errorf("peak %d, %d samples, %d late", fake.peak, len(res.Samples), res.Late)
+	}
+	for i, s := range res.Samples {
+		if s.Iteration != i || s.Error != "" {
+			t.Fatalf("sample %d: %+v", i, s)
+		}
+	}
+
+	// Capped at two in flight, the burst's second pair starts a reply
+	// late.
+	fake = &fakeInvoker{latency: 20 * time.Millisecond}
+	res, err = Replay(context.Background(), fake, ReplayConfig{Events: events, Speed: 4, Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.peak != 2 || res.Late != 2 || res.MaxLag < 15*time.Millisecond {
+		t.Errorf("peak %d, %d late by up to %s", fake.peak, res.Late, res.MaxLag)
+	}
+	if _, err := Replay(context.Background(), fake, ReplayConfig{}); err == nil {
+		t.Error("replayed no events")
+	}
+}
@@ -0,0 +1,72 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/results"
+)
+
+// DefaultLevels is the concurrency ramp of ruchy-bench burst, up to the
+// default account concurrency limit of 1000.
+var DefaultLevels = []int{1, 10, 50, 100, 250, 500, 1000}
+
+// RampConfig describes a stepped concurrency ramp.
+type RampConfig struct {
+	// Levels are the concurrencies stepped through, in order.
+	Levels []int
+	// Step is how long each level runs closed-loop.
+	Step     time.Duration
+	Payload  []byte
+	Expected string
+}
+
+// Level is the outcome of one step of a ramp.
+type Level struct {
+	Concurrency int
+	Result      Result
+}
+
+// Ramp runs inv closed-loop at each level's concurrency for Step in turn,
+// without pausing in between: environments added for one level are warm
+// for the next, so each level only has to scale by its difference, as a
+// real burst would. It stops at the first cancelled level and returns the
+// levels run so far.
+func Ramp(ctx context.Context, inv invoke.Invoker, c RampConfig) ([]Level, error) {
+	if len(c.Levels) == 0 || c.Step <= 0 {
+		return nil, errors.New("loadgen: a ramp needs levels and a positive step")
+	}
+	var levels []Level
+	for _, n := range c.Levels {
+		g := &Generator{Invoker: inv, Config: Config{
+			Workers:  n,
+			Duration: c.Step,
+			Payload:  c.Payload,
+			Expected: c.Expected,
+		}}
+		res, err := g.Run(ctx)
+		if err != nil && ctx.Err() == nil {
+			return levels, err
+		}
+		levels = append(levels, Level{Concurrency: n, Result: res})
+		if ctx.Err() != nil {
+			return levels, ctx.Err()
+		}
+	}
+	return levels, nil
+}
+
+// Samples concatenates the levels' samples, numbering iterations across
+// the whole ramp.
+func Samples(levels []Level) []results.Sample {
+	var out []results.Sample
+	for _, l := range levels {
+		for _, s := range l.Result.Samples {
+			s.Iteration = len(out)
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,103 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"lambdaperf/pkg/invoke"
+)
+
+// LateAfter is how far behind its time a replayed event may start before
+// it counts as late: timer wake-ups alone run a millisecond or so behind.
+const LateAfter = 10 * time.Millisecond
+
+// ReplayConfig describes a replay of recorded traffic.
+type ReplayConfig struct {
+	Events []Event
+	// Speed divides the recorded gaps between events: 1 replays them at
+	// their original pace, 10 ten times as fast. Zero means 1.
+	Speed float64
+	// Workers caps the requests in flight. An event that comes due with
+	// Workers in flight waits for one to finish and may start late. Zero
+	// leaves concurrency to the trace, as Lambda would.
+	Workers int
+	// Expected is the result every response must report; empty accepts
+	// any. Recorded payloads rarely share one.
+	Expected string
+}
+
+// Replay invokes inv with each event's payload at its offset divided by
+// Speed, without waiting for earlier replies: bursts in the trace become
+// concurrent requests, and the quiet between them lets environments go
+// idle, as they did for the traffic recorded. Sample i is event i. It
+// stops starting events when ctx is cancelled, and lets those in flight
+// finish.
+func Replay(ctx context.Context, inv invoke.Invoker, c ReplayConfig) (Result, error) {
+	if len(c.Events) == 0 || c.Speed < 0 || c.Workers < 0 {
+		return Result{}, errors.New("loadgen: a replay needs events, a non-negative speed and a non-negative worker cap")
+	}
+	speed := c.Speed
+	if speed == 0 {
+		speed = 1
+	}
+	var slots chan struct{}
+	if c.Workers > 0 {
+		slots = make(chan struct{}, c.Workers)
+	}
+
+	var (
+		mu  sync.Mutex
+		res = Result{Client: NewHistogram()}
+		wg  sync.WaitGroup
+	)
+	// In-flight requests outlive ctx, as Run's outlive its deadline.
+	inFlight := context.WithoutCancel(ctx)
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+replay:
+	for i, e := range c.Events {
+		due := start.Add(time.Duration(float64(e.At) / speed))
+		timer.Reset(time.Until(due))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			break replay
+		}
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				break replay
+			}
+		}
+		if lag := time.Since(due); lag > LateAfter {
+			res.Late++
+			res.MaxLag = max(res.MaxLag, lag)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, throttled := invokeOnce(inFlight, inv, e.Payload, c.Expected, i)
+			if slots != nil {
+				<-slots
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			res.Samples = append(res.Samples, s)
+			if throttled {
+				res.Throttled++
+			}
+			if s.Error == "" {
+				res.Client.Record(s.ClientMS)
+			}
+		}()
+	}
+	wg.Wait()
+	res.Elapsed = time.Since(start)
+	sortByIteration(res.Samples)
+	return res, ctx.Err()
+}
@@ -0,0 +1,208 @@
+package loadgen
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is one recorded invocation: its payload, and when it arrived as
+// an offset from the first event of its trace.
+type Event struct {
+	At      time.Duration
+	Payload json.RawMessage
+}
+
+// Span is the time from a trace's first event to its last.
+func Span(events []Event) time.Duration {
+	if len(events) == 0 {
+		return 0
+	}
+	return events[len(events)-1].At - events[0].At
+}
+
+// ReadTrace reads recorded invocations in any of these forms:
+//
+//   - JSON lines of {"offset_ms": 12.5, "payload": {...}}, as WriteTrace
+//     writes them, or of {"timestamp": "2025-11-02T10:00:00.123Z",
+//     "payload": {...}} with RFC 3339 timestamps or epoch milliseconds.
+//   - A CloudWatch Logs Insights export: the console's JSON download, an
+//     array of rows, or the JSON lines of one row each, or the output of
+//     aws logs get-query-results. Each row needs @timestamp and an
+//     @message holding the event, as a handler that logs its event writes
+//     it; the JSON object is taken from the first "{" of the message, so
+//     the runtime's timestamp, request ID and level may precede it. Rows
+//     without one, such as START and REPORT lines, are skipped.
+//
+// The events are returned in order of arrival, offset from the first.
+func ReadTrace(r io.Reader) ([]Event, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	var rows []map[string]json.RawMessage
+	switch {
+	case len(data) == 0:
+	case data[0] == '[':
+		if err := json.Unmarshal(data, &rows); err != nil {
+			return nil, fmt.Errorf("trace: %w", err)
+		}
+	default:
+		var query struct {
+			Results [][]struct{ Field, Value string } `json:"results"`
+		}
+		if json.Unmarshal(data, &query) == nil && query.Results != nil {
+			for _, fields := range query.Results {
+				row := map[string]json.RawMessage{}
+				for _, f := range fields {
+					row[f.Field], _ = json.Marshal(f.Value)
+				}
+				rows = append(rows, row)
+			}
+			break
+		}
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(nil, 8<<20) // Lambda accepts payloads up to 6 MB
+		for line := 1; sc.Scan(); line++ {
+			if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+				continue
+			}
+			var row map[string]json.RawMessage
+			if err := json.Unmarshal(sc.Bytes(), &row); err != nil {
+				return nil, fmt.Errorf("trace line %d: %w", line, err)
+			}
+			rows = append(rows, row)
+		}
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("trace: %w", err)
+		}
+	}
+
+	var (
+		events   []Event
+		absolute []time.Time
+		offsets  int
+	)
+	for i, row := range rows {
+		if payload, ok := row["payload"]; ok {
+			if raw, ok := row["offset_ms"]; ok {
+				ms, err := strconv.ParseFloat(string(raw), 64)
+				if err != nil {
+					return nil, fmt.Errorf("trace record %d: offset_ms: %w", i+1, err)
+				}
+				events = append(events, Event{At: time.Duration(math.Round(ms * float64(time.Millisecond))), Payload: payload})
+				offsets++
+				continue
+			}
+			at, err := timestamp(row["timestamp"])
+			if err != nil {
+				return nil, fmt.Errorf("trace record %d: %w", i+1, err)
+			}
+			events, absolute = append(events, Event{Payload: payload}), append(absolute, at)
+			continue
+		}
+		var message string
+		if json.Unmarshal(row["@message"], &message) != nil {
+			return nil, fmt.Errorf("trace record %d: neither a payload nor an @message", i+1)
+		}
+		payload, ok := messageEvent(message)
+		if !ok {
+			continue
+		}
+		at, err := timestamp(row["@timestamp"])
+		if err != nil {
+			return nil, fmt.Errorf("trace record %d: %w", i+1, err)
+		}
+		events, absolute = append(events, Event{Payload: payload}), append(absolute, at)
+	}
+	if offsets > 0 && len(absolute) > 0 {
+		return nil, errors.New("trace mixes offsets and timestamps")
+	}
+	if len(events) == 0 {
+		return nil, errors.New("trace holds no payloads")
+	}
+	if len(absolute) > 0 {
+		first := slices.MinFunc(absolute, time.Time.Compare)
+		for i, at := range absolute {
+			events[i].At = at.Sub(first)
+		}
+	}
+	slices.SortStableFunc(events, func(a, b Event) int { return cmp.Compare(a.At, b.At) })
+	first := events[0].At
+	for i := range events {
+		events[i].At -= first
+	}
+	return events, nil
+}
+
+// timestampLayouts are the forms of timestamp ReadTrace accepts as
+// strings: RFC 3339, and Logs Insights' UTC "2025-11-02 10:00:00.123".
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999"}
+
+// timestamp parses raw as a timestamp string or as epoch milliseconds.
+func timestamp(raw json.RawMessage) (time.Time, error) {
+	if raw == nil {
+		return time.Time{}, errors.New("no offset_ms or timestamp")
+	}
+	var ms float64
+	if json.Unmarshal(raw, &ms) == nil {
+		return time.UnixMilli(0).Add(time.Duration(ms * float64(time.Millisecond))), nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return time.Time{}, fmt.Errorf("timestamp %s: %w", raw, err)
+	}
+	if ms, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.UnixMilli(0).Add(time.Duration(ms * float64(time.Millisecond))), nil
+	}
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("timestamp %q is neither RFC 3339 nor epoch milliseconds", s)
+}
+
+// messageEvent extracts the JSON object a log message ends with.
+func messageEvent(message string) (json.RawMessage, bool) {
+	i := strings.IndexByte(message, '{')
+	if i < 0 {
+		return nil, false
+	}
+	payload := bytes.TrimSpace([]byte(message[i:]))
+	if !json.Valid(payload) {
+		return nil, false
+	}
+	return payload, true
+}
+
+// WriteTrace writes events as the JSON lines ReadTrace reads back, one
+// {"offset_ms", "payload"} object per line.
+func WriteTrace(w io.Writer, events []Event) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range events {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, e.Payload); err != nil {
+			return fmt.Errorf("payload at %v: %w", e.At, err)
+		}
+		line, err := json.Marshal(struct {
+			OffsetMS float64         `json:"offset_ms"`
+			Payload  json.RawMessage `json:"payload"`
+		}{float64(e.At) / float64(time.Millisecond), compact.Bytes()})
+		if err != nil {
+			return err
+		}
+		bw.Write(append(line, '\n'))
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,61 @@
+package localbench
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// Cgroup is a cgroup v2 group with a CPU quota. A Runner with one starts
+// the workload in it, so the workload gets that much CPU time per period
+// however many cores the host has, as a Lambda function gets the share of
+// a vCPU its memory size buys. Only the CPU is capped: the workloads' peak
+// RSS is reported rather than enforced.
+type Cgroup struct {
+	dir string
+	fd  *os.File
+}
+
+// NewCgroup creates a group capped at vcpus vCPUs in parent, a cgroup v2
+// directory the harness can write to and holding no processes itself: the
+// kernel lets a group hand the cpu controller down to its children only
+// then. It enables that controller in parent.
+func NewCgroup(parent string, vcpus float64) (*Cgroup, error) {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(parent, &fs); err != nil {
+		return nil, fmt.Errorf("cgroup: %w", err)
+	}
+	if fs.Type != unix.CGROUP2_SUPER_MAGIC {
+		return nil, fmt.Errorf("cgroup: %s is not in a cgroup v2 hierarchy", parent)
+	}
+	if err := os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+cpu"), 0); err != nil {
+		return nil, fmt.Errorf("cgroup: enable the cpu controller: %w", err)
+	}
+	dir, err := os.MkdirTemp(parent, "run-")
+	if err != nil {
+		return nil, fmt.Errorf("cgroup: %w", err)
+	}
+	c := &Cgroup{dir: dir}
+	if err := os.WriteFile(filepath.Join(dir, "cpu.max"), []byte(cpuMax(vcpus)), 0); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("cgroup: %w", err)
+	}
+	if c.fd, err = os.Open(dir); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("cgroup: %w", err)
+	}
+	return c, nil
+}
+
+// Dir is the group's cgroupfs directory.
+func (c *Cgroup) Dir() string { return c.dir }
+
+// Close removes the group, which must have no processes left.
+func (c *Cgroup) Close() error {
+	if c.fd != nil {
+		c.fd.Close()
+	}
+	return os.Remove(c.dir)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package localbench
+
+import "errors"
+
+// Cgroup is Linux-only; elsewhere NewCgroup fails.
+type Cgroup struct{}
+
+func NewCgroup(string, float64) (*Cgroup, error) {
+	return nil, errors.New("cgroup: CPU quotas need Linux")
+}
+
+func (*Cgroup) Dir() string { return "" }
+
+func (*Cgroup) Close() error { return nil }
@@ -0,0 +1,81 @@
+package localbench
+
+import (
+	"encoding/binary"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+var hardwareEvents = map[string]uint64{
+	"instructions":     unix.PERF_COUNT_HW_INSTRUCTIONS,
+	"cycles":           unix.PERF_COUNT_HW_CPU_CYCLES,
+	"cache-references": unix.PERF_COUNT_HW_CACHE_REFERENCES,
+	"cache-misses":     unix.PERF_COUNT_HW_CACHE_MISSES,
+	"branch-misses":    unix.PERF_COUNT_HW_BRANCH_MISSES,
+}
+
+// counters are perf events opened on the calling thread, disabled, with
+// inherit and enable-on-exec set: they start counting when a process
+// forked from the thread execs, and that process's counts are folded into
+// them when it exits. The thread itself never execs, so it counts nothing.
+// Counting user space only keeps this legal at the default
+// perf_event_paranoid of 2.
+type counters struct {
+	names []string
+	fds   []int
+}
+
+func openCounters(events []string) *counters {
+	c := &counters{}
+	for _, name := range events {
+		config, ok := hardwareEvents[name]
+		if !ok {
+			continue
+		}
+		attr := unix.PerfEventAttr{
+			Type:   unix.PERF_TYPE_HARDWARE,
+			Size:   uint32(unsafe.Sizeof(unix.PerfEventAttr{})),
+			Config: config,
+			Bits: unix.PerfBitDisabled | unix.PerfBitInherit | unix.PerfBitEnableOnExec |
+				unix.PerfBitExcludeKernel | unix.PerfBitExcludeHv,
+			Read_format: unix.PERF_FORMAT_TOTAL_TIME_ENABLED | unix.PERF_FORMAT_TOTAL_TIME_RUNNING,
+		}
+		fd, err := unix.PerfEventOpen(&attr, 0, -1, -1, unix.PERF_FLAG_FD_CLOEXEC)
+		if err != nil {
+			continue // not supported here, or not permitted
+		}
+		c.names = append(c.names, name)
+		c.fds = append(c.fds, fd)
+	}
+	return c
+}
+
+// read returns the counts, scaled up when the kernel had to multiplex
+// more events than the PMU has counters.
+func (c *counters) read() map[string]float64 {
+	var out map[string]float64
+	buf := make([]byte, 24) // value, time enabled, time running
+	for i, fd := range c.fds {
+		if n, err := unix.Read(fd, buf); err != nil || n != len(buf) {
+			continue
+		}
+		value := binary.NativeEndian.Uint64(buf[0:])
+		enabled := binary.NativeEndian.Uint64(buf[8:])
+		running := binary.NativeEndian.Uint64(buf[16:])
+		if running == 0 {
+			continue // never scheduled onto the PMU
+		}
+		if out == nil {
+			out = map[string]float64{}
+		}
+		out[c.names[i]] = float64(value) * float64(enabled) / float64(running)
+	}
+	return out
+}
+
+func (c *counters) close() {
+	for _, fd := range c.fds {
+		unix.Close(fd)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package localbench
+
+// counters are Linux-only; elsewhere runs record no counters.
+type counters struct{}
+
+func openCounters([]string) *counters { return &counters{} }
+
+func (*counters) read() map[string]float64 { return nil }
+
+func (*counters) close() {}
@@ -0,0 +1,178 @@
+package localbench
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// exec runs the command traced, so that the kernel stops it on its way out
+// while its address space still exists, and reads the peak RSS (VmHWM)
+// from /proc then. The rusage wait4 returns is no use here: Go vforks, and
+// exec folds the peak RSS of the address space it replaces (the harness's)
+// into the child's, so every workload would report at least the harness's
+// own footprint.
+//
+// Tracing costs a stop per signal the workload receives (the Go runtime's
+// preemption signals, mostly): microseconds per run. Where ptrace is not
+// permitted the command runs untraced and reports no RSS.
+func (r *Runner) exec(ctx context.Context) (Measurement, error) {
+	path, err := exec.LookPath(r.Command[0])
+	if err != nil {
+		return Measurement{}, err
+	}
+	stdin, stdinW, err := os.Pipe()
+	if err != nil {
+		return Measurement{}, err
+	}
+	stdoutR, stdout, err := os.Pipe()
+	if err != nil {
+		return Measurement{}, err
+	}
+	stderrR, stderr, err := os.Pipe()
+	if err != nil {
+		return Measurement{}, err
+	}
+	attr := &os.ProcAttr{
+		Dir:   r.Dir,
+		Files: []*os.File{stdin, stdout, stderr},
+		Sys:   &syscall.SysProcAttr{Ptrace: true},
+	}
+	if r.Cgroup != nil {
+		attr.Sys.UseCgroupFD, attr.Sys.CgroupFD = true, int(r.Cgroup.fd.Fd())
+	}
+
+	start := time.Now()
+	p, err := os.StartProcess(path, r.Command, attr)
+	if errors.Is(err, syscall.EPERM) {
+		attr.Sys.Ptrace = false
+		start = time.Now()
+		p, err = os.StartProcess(path, r.Command, attr)
+	}
+	stdin.Close()
+	stdout.Close()
+	stderr.Close()
+	if err != nil {
+		stdinW.Close()
+		stdoutR.Close()
+		stderrR.Close()
+		return Measurement{}, err
+	}
+	defer p.Release()
+	stop := context.AfterFunc(ctx, func() { p.Kill() })
+	defer stop()
+
+	go func() {
+		stdinW.Write(r.Stdin)
+		stdinW.Close()
+	}()
+	var outBuf, errBuf bytes.Buffer
+	copied := make(chan struct{}, 2)
+	for _, c := range []struct {
+		dst *bytes.Buffer
+		src *os.File
+	}{{&outBuf, stdoutR}, {&errBuf, stderrR}} {
+		go func() {
+			io.Copy(c.dst, c.src)
+			c.src.Close()
+			copied <- struct{}{}
+		}()
+	}
+
+	m, ws, err := wait(p.Pid, attr.Sys.Ptrace, start)
+	<-copied
+	<-copied
+	m.Output = outBuf.Bytes()
+	switch {
+	case err != nil:
+	case ws.Signaled():
+		err = fmt.Errorf("signal: %v", ws.Signal())
+	case ws.ExitStatus() != 0:
+		err = fmt.Errorf("exit status %d", ws.ExitStatus())
+	}
+	if err != nil {
+		return m, fmt.Errorf("%s: %w: %s", r.Command[0], err, bytes.TrimSpace(errBuf.Bytes()))
+	}
+	return m, nil
+}
+
+// wait reaps pid, resuming it through its ptrace stops when traced and
+// sampling its peak RSS at the exit stop. Time spent reading /proc is left
+// out of the wall time. CPU times come from the final rusage, which exec
+// does not inherit.
+func wait(pid int, traced bool, start time.Time) (Measurement, syscall.WaitStatus, error) {
+	var (
+		m      Measurement
+		ws     syscall.WaitStatus
+		ru     syscall.Rusage
+		paused time.Duration
+	)
+	for first := true; ; {
+		if _, err := syscall.Wait4(pid, &ws, 0, &ru); err != nil {
+			if err == syscall.EINTR {
+				continue
+			}
+			return m, ws, err
+		}
+		if ws.Exited() || ws.Signaled() {
+			m.Wall = time.Since(start) - paused
+			m.User = time.Duration(ru.Utime.Nano())
+			m.System = time.Duration(ru.Stime.Nano())
+			return m, ws, nil
+		}
+		if !ws.Stopped() {
+			continue
+		}
+		sig := ws.StopSignal()
+		switch {
+		case !traced:
+			continue
+		case first && sig == syscall.SIGTRAP:
+			// Stopped after exec: ask to be told about the exit, turn
+			// later execs (launcher scripts) into events rather than
+			// SIGTRAPs, and take the workload down with us if the
+			// harness dies.
+			first = false
+			sig = 0
+			opts := syscall.PTRACE_O_TRACEEXIT | syscall.PTRACE_O_TRACEEXEC | unix.PTRACE_O_EXITKILL
+			if err := syscall.PtraceSetOptions(pid, opts); err != nil {
+				return m, ws, fmt.Errorf("ptrace: %w", err)
+			}
+		case sig == syscall.SIGTRAP && ws.TrapCause() == syscall.PTRACE_EVENT_EXEC:
+			sig = 0
+		case sig == syscall.SIGTRAP && ws.TrapCause() == syscall.PTRACE_EVENT_EXIT:
+			t := time.Now()
+			m.MaxRSSKB = peakRSSKB(pid)
+			paused = time.Since(t)
+			sig = 0
+		}
+		if err := syscall.PtraceCont(pid, int(sig)); err != nil && err != syscall.ESRCH {
+			return m, ws, fmt.Errorf("ptrace: %w", err)
+		}
+	}
+}
+
+// peakRSSKB reads VmHWM from /proc/<pid>/status, which is in kB.
+func peakRSSKB(pid int) int64 {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/status")
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if v, ok := strings.CutPrefix(line, "VmHWM:"); ok {
+			kb, _ := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(v), " kB"), 10, 64)
+			return kb
+		}
+	}
+	return 0
+}
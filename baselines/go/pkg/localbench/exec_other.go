@@ -0,0 +1,34 @@
+//go:build !linux
+
+package localbench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// exec runs the command, taking the peak RSS from its rusage where the
+// platform reports one and the CPU times from its process state.
+func (r *Runner) exec(ctx context.Context) (Measurement, error) {
+	cmd := exec.CommandContext(ctx, r.Command[0], r.Command[1:]...)
+	cmd.Dir = r.Dir
+	cmd.Stdin = bytes.NewReader(r.Stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	m := Measurement{Wall: time.Since(start), Output: stdout.Bytes()}
+	if cmd.ProcessState != nil {
+		m.MaxRSSKB = maxRSSKB(cmd.ProcessState)
+		m.User, m.System = cmd.ProcessState.UserTime(), cmd.ProcessState.SystemTime()
+	}
+	if err != nil {
+		return m, fmt.Errorf("%s: %w: %s", r.Command[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return m, nil
+}
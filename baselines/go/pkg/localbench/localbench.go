@@ -0,0 +1,165 @@
+// Package localbench runs local workloads as subprocesses and measures each
+// run the way perf stat would: wall time, peak resident set size and, on
+// Linux, hardware counters such as instructions and cache misses.
+//
+// Workloads print their result, and the runner checks it against the
+// "Expected result:" line of the source header. A run that printed nothing
+// or the wrong answer is a failed sample, so a compiler that optimizes the
+// computation away cannot post a fast time.
+package localbench
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// Events are the hardware counters recorded per run where the platform and
+// machine support them, named as perf stat names them.
+var Events = []string{
+	results.MetricInstructions,
+	results.MetricCycles,
+	results.MetricCacheRefs,
+	results.MetricCacheMisses,
+	results.MetricBranchMisses,
+}
+
+// Measurement is one run of a workload.
+type Measurement struct {
+	Wall time.Duration
+	// MaxRSSKB is the peak resident set size of the workload process, in
+	// KB; zero where the platform does not report it.
+	MaxRSSKB int64
+	// User and System are the CPU time the workload spent in user and
+	// kernel mode.
+	User, System time.Duration
+	// Counters holds the counted Events. Events the machine cannot count
+	// (common in VMs and containers) are missing.
+	Counters map[string]float64
+	Output   []byte
+}
+
+// Runner runs one workload.
+type Runner struct {
+	Command []string
+	Dir     string
+	// Stdin is passed to every run.
+	Stdin []byte
+	// Expected is what the workload must print, compared with surrounding
+	// whitespace trimmed. Empty skips the check.
+	Expected string
+	// Warmup runs before the recorded runs; see results.Collect.
+	Warmup stats.Warmup
+	// Precision extends the recorded runs; see results.Collect.
+	Precision stats.Precision
+	// Exec, if set, runs the command in place of a subprocess of the
+	// harness, as a sandbox does. Counters are not read then: they would
+	// count whatever Exec starts on the host, not the workload.
+	Exec func(ctx context.Context, command []string, dir string, stdin []byte) (Measurement, error)
+	// Cgroup, if set, is the group runs start in, which caps the CPU time
+	// they get; see NewCgroup.
+	Cgroup *Cgroup
+}
+
+// Run executes the workload once.
+func (r *Runner) Run(ctx context.Context) (Measurement, error) {
+	var (
+		m   Measurement
+		err error
+	)
+	if r.Exec != nil {
+		m, err = r.Exec(ctx, r.Command, r.Dir, r.Stdin)
+	} else {
+		m, err = r.counted(ctx)
+	}
+	if err != nil {
+		return m, err
+	}
+	if got := string(bytes.TrimSpace(m.Output)); r.Expected != "" && got != r.Expected {
+		return m, fmt.Errorf("printed %q, want %q", got, r.Expected)
+	}
+	return m, nil
+}
+
+// cpuPeriodUS is the CFS period cpu.max quotas are given over, the
+// kernel's default.
+const cpuPeriodUS = 100_000
+
+// cpuMax is the cpu.max setting for a quota of vcpus vCPUs: microseconds
+// of CPU time per period. The kernel rejects quotas under a millisecond.
+func cpuMax(vcpus float64) string {
+	return fmt.Sprintf("%d %d", max(int(vcpus*cpuPeriodUS), 1000), cpuPeriodUS)
+}
+
+// counted runs the command as a subprocess and reads its counters.
+func (r *Runner) counted(ctx context.Context) (Measurement, error) {
+	// Counters follow the process forked from this thread, so the thread
+	// must not change between opening them and starting the command.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	c := openCounters(Events)
+	defer c.close()
+
+	m, err := r.exec(ctx)
+	if err == nil {
+		m.Counters = c.read()
+	}
+	return m, err
+}
+
+// Samples warms up and then runs the workload n times in sequence,
+// recording failures as samples rather than stopping. steady is false when
+// warm-up gave up before run times settled.
+func (r *Runner) Samples(ctx context.Context, n int) (samples []results.Sample, steady bool) {
+	return results.Collect(ctx, n, r.Warmup, r.Precision, func(i int) results.Sample {
+		m, err := r.Run(ctx)
+		s := results.Sample{
+			Iteration: i,
+			ClientMS:  results.Milliseconds(m.Wall),
+			MaxRSSKB:  m.MaxRSSKB,
+			UserMS:    results.Milliseconds(m.User),
+			SystemMS:  results.Milliseconds(m.System),
+			Counters:  m.Counters,
+			Response:  string(bytes.TrimSpace(m.Output)),
+		}
+		if err != nil {
+			s.Error = err.Error()
+		}
+		return s
+	})
+}
+
+// Expected returns the value of the "Expected result:" line in the leading
+// comment block of the source file at path, or "" when there is none.
+func Expected(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#!") {
+			continue
+		}
+		text, ok := strings.CutPrefix(line, "//")
+		if !ok {
+			if text, ok = strings.CutPrefix(line, "#"); !ok {
+				break // end of the header
+			}
+		}
+		if v, ok := strings.CutPrefix(strings.TrimSpace(text), "Expected result:"); ok {
+			return strings.TrimSpace(v), nil
+		}
+	}
+	return "", sc.Err()
+}
@@ -0,0 +1,146 @@
+package localbench
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// The test binary doubles as a workload: with LOCALBENCH_HELPER_MB set it
+// touches that many MB, prints them and exits.
+func TestMain(m *testing.M) {
+	if v := os.Getenv("LOCALBENCH_HELPER_MB"); v != "" {
+		var mb int
+		fmt.Sscan(v, &mb)
+		buf := make([]byte, mb<<20)
+		for i := range buf {
+			buf[i] = 1
+		}
+		fmt.Println(len(buf) >> 20)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func helper(t *testing.T, mb int) *Runner {
+	t.Helper()
+	t.Setenv("LOCALBENCH_HELPER_MB", fmt.Sprint(mb))
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Runner{Command: []string{exe}, Expected: fmt.Sprint(mb)}
+}
+
+func TestExpected(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]struct{ src, want string }{
+		"go.go": {"// JSON round-trip - Go\n// Expected result: json(1115300)=31fa7abb\n\npackage main\n", "json(1115300)=31fa7abb"},
+		"py.py": {"#!/usr/bin/env python3\n# Fibonacci - Python\n# Expected result: 9227465\n", "9227465"},
+		// Only the header counts.
+		"body.c": {"// Fibonacci - C\n\nint main() {\n// Expected result: 1\n}\n", ""},
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(tc.src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := Expected(path); err != nil || got != tc.want {
+			t.Errorf("%s: Expected = %q, %v; want %q", name, got, err, tc.want)
+		}
+	}
+}
+
+func TestRunChecksOutput(t *testing.T) {
+	r := helper(t, 1)
+	m, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Wall <= 0 || strings.TrimSpace(string(m.Output)) != "1" {
+		t.Errorf("measurement = %+v", m)
+	}
+
+	r.Expected = "2"
+	if _, err := r.Run(context.Background()); err == nil || !strings.Contains(err.Error(), `want "2"`) {
+		t.Errorf("wrong output: err = %v", err)
+	}
+	samples, _ := r.Samples(context.Background(), 3)
+	if len(samples) != 3 || samples[2].Iteration != 2 || samples[0].Error == "" || samples[0].Response != "1" {
+		t.Errorf("samples = %+v", samples)
+	}
+	r.Warmup.Min = 2
+	if samples, _ = r.Samples(context.Background(), 1); len(samples) != 3 || !samples[1].Warmup || samples[2].Warmup {
+		t.Errorf("samples after 2 warm-up runs = %+v", samples)
+	}
+}
+
+func TestRunExec(t *testing.T) {
+	var got []string
+	r := &Runner{Command: []string{"fib"}, Dir: "/w", Stdin: []byte("{}"), Expected: "55",
+		Exec: func(_ context.Context, command []string, dir string, stdin []byte) (Measurement, error) {
+			got = append(append(got, command...), dir, string(stdin))
+			return Measurement{Wall: 5, Output: []byte("55\n")}, nil
+		}}
+	m, err := r.Run(context.Background())
+	if err != nil || m.Wall != 5 || m.Counters != nil || strings.Join(got, " ") != "fib /w {}" {
+		t.Errorf("Run = %+v, %v after Exec(%q)", m, err, got)
+	}
+	r.Expected = "89"
+	if _, err := r.Run(context.Background()); err == nil {
+		t.Error("wrong output from Exec passed")
+	}
+}
+
+func TestRunReportsFailure(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Test binaries reject unknown flags with exit status 2.
+	r := &Runner{Command: []string{exe, "-no-such-flag"}}
+	if _, err := r.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "exit status 2") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestRunMeasuresWorkloadRSS(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("rusage-based peak RSS on " + runtime.GOOS + " is not isolated from the harness")
+	}
+	// A harness much larger than the workload must not inflate the
+	// workload's peak.
+	ballast := make([]byte, 256<<20)
+	for i := range ballast {
+		ballast[i] = 1
+	}
+	m, err := helper(t, 48).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.MaxRSSKB == 0 {
+		t.Skip("peak RSS unavailable (ptrace not permitted?)")
+	}
+	if m.MaxRSSKB < 48<<10 || m.MaxRSSKB >= 256<<10 {
+		t.Errorf("MaxRSSKB = %d, want between 48 MB and the 256 MB harness", m.MaxRSSKB)
+	}
+	runtime.KeepAlive(ballast)
+}
+
+func TestCPUMax(t *testing.T) {
+	for vcpus, want := range map[float64]string{1: "100000 100000", 2.5: "250000 100000", 128.0 / 1769: "7235 100000", 0.001: "1000 100000"} {
+		if got := cpuMax(vcpus); got != want {
+			t.Errorf("cpuMax(%v) = %q, want %q", vcpus, got, want)
+		}
+	}
+}
+
+func TestNewCgroupNeedsCgroup2(t *testing.T) {
+	if c, err := NewCgroup(t.TempDir(), 1); err == nil {
+		c.Close()
+		t.Error("NewCgroup succeeded outside cgroupfs")
+	}
+}
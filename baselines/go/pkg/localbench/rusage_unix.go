@@ -0,0 +1,21 @@
+//go:build unix && !linux
+
+package localbench
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSSKB reads the peak RSS from rusage: bytes on macOS, KB on the BSDs.
+func maxRSSKB(ps *os.ProcessState) int64 {
+	ru, ok := ps.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(ru.Maxrss) / 1024
+	}
+	return int64(ru.Maxrss)
+}
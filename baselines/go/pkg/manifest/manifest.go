@@ -0,0 +1,236 @@
+// Package manifest loads benchmarks/manifest.yaml, which declares every
+// workload, its inputs and expected result, and the runtimes implementing
+// it, and checks the discovered targets against it. Comparing runtimes on a
+// workload is only meaningful when each implements it and computes the same
+// answer; the manifest is what makes that checkable.
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/results"
+)
+
+// Path is the manifest's location relative to the repository root.
+const Path = "benchmarks/manifest.yaml"
+
+// Manifest is the declared set of workloads.
+type Manifest struct {
+	Workloads []Workload `yaml:"workloads"`
+}
+
+// Workload is one benchmark every listed runtime must implement alike.
+type Workload struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Params documents the workload's fixed inputs: sizes, seeds, fixtures.
+	Params map[string]any `yaml:"params,omitempty"`
+	// Inputs are the integers a Lambda invocation payload may set, by
+	// payload field; Expected is the result at their defaults.
+	Inputs map[string]Input `yaml:"inputs,omitempty"`
+	// Expected is what local implementations print and Lambda handlers
+	// return as their response body. Empty for workloads whose result
+	// depends on the event or is not meant to match across runtimes.
+	Expected string `yaml:"expected,omitempty"`
+	// Runtimes lists the implementations by kind.
+	Runtimes map[discover.Kind][]string `yaml:"runtimes"`
+	// SLOs are what every implementation of the workload must meet to
+	// pass; see pkg/slo.
+	SLOs []SLO `yaml:"slos,omitempty"`
+}
+
+// SLO bounds a statistic of one metric of the workload's results, such
+// as mean init_ms under 100 at 256 MB.
+type SLO struct {
+	// Metric is a results metric, such as init_ms or warm_ms.
+	Metric string `yaml:"metric"`
+	// Stat is the statistic bounded: mean, p50, p95, p99 or max.
+	Stat string `yaml:"stat"`
+	// Max is the bound, in the metric's unit; the statistic must not
+	// exceed it.
+	Max float64 `yaml:"max"`
+	// MemoryMB, when set, limits the SLO to results at that memory size.
+	MemoryMB int32 `yaml:"memory_mb,omitempty"`
+}
+
+// Stats are the statistics an SLO can bound.
+var Stats = []string{"mean", "p50", "p95", "p99", "max"}
+
+func (s SLO) String() string {
+	str := fmt.Sprintf("%s %s ≤ %g", s.Metric, s.Stat, s.Max)
+	if s.MemoryMB != 0 {
+		str += fmt.Sprintf(" at %d MB", s.MemoryMB)
+	}
+	return str
+}
+
+// Input is one integer workload input and the bounds the handlers
+// enforce, refusing payloads outside them.
+type Input struct {
+	Default int `yaml:"default"`
+	Min     int `yaml:"min"`
+	Max     int `yaml:"max"`
+	// Fixed lists the Lambda runtimes whose handler ignores the input and
+	// always computes the default.
+	Fixed []string `yaml:"fixed,omitempty"`
+}
+
+// Accepts reports whether runtime's Lambda handler reads the input.
+func (in Input) Accepts(runtime string) bool {
+	return !slices.Contains(in.Fixed, runtime)
+}
+
+// Load reads and validates the manifest under root.
+func Load(root string) (*Manifest, error) {
+	path := filepath.Join(root, filepath.FromSlash(Path))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", Path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes and validates a manifest. Unknown fields are errors, so a
+// misspelt key cannot silently drop a declaration.
+func Parse(data []byte) (*Manifest, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var m Manifest
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	if len(m.Workloads) == 0 {
+		return nil, errors.New("no workloads")
+	}
+	seen := map[string]bool{}
+	for _, w := range m.Workloads {
+		switch {
+		case w.Name == "":
+			return nil, errors.New("workload without a name")
+		case seen[w.Name]:
+			return nil, fmt.Errorf("workload %q declared twice", w.Name)
+		case len(w.Runtimes) == 0:
+			return nil, fmt.Errorf("workload %q: no runtimes", w.Name)
+		}
+		seen[w.Name] = true
+		for kind, runtimes := range w.Runtimes {
+			if kind != discover.KindLocal && kind != discover.KindLambda {
+				return nil, fmt.Errorf("workload %q: unknown kind %q", w.Name, kind)
+			}
+			sorted := slices.Sorted(slices.Values(runtimes))
+			if len(slices.Compact(sorted)) != len(runtimes) {
+				return nil, fmt.Errorf("workload %q: %s runtime listed twice", w.Name, kind)
+			}
+		}
+		for name, in := range w.Inputs {
+			if in.Min > in.Default || in.Default > in.Max {
+				return nil, fmt.Errorf("workload %q: input %s default %d outside %d..%d", w.Name, name, in.Default, in.Min, in.Max)
+			}
+			for _, rt := range in.Fixed {
+				if !w.Implements(discover.KindLambda, rt) {
+					return nil, fmt.Errorf("workload %q: input %s fixed for %s, which implements no lambda handler", w.Name, name, rt)
+				}
+			}
+		}
+		for _, slo := range w.SLOs {
+			switch {
+			case !slices.Contains(results.Metrics, slo.Metric):
+				return nil, fmt.Errorf("workload %q: SLO on unknown metric %q", w.Name, slo.Metric)
+			case !slices.Contains(Stats, slo.Stat):
+				return nil, fmt.Errorf("workload %q: SLO on %s: unknown stat %q, want one of %v", w.Name, slo.Metric, slo.Stat, Stats)
+			case slo.Max <= 0:
+				return nil, fmt.Errorf("workload %q: SLO on %s %s: max %g is not positive", w.Name, slo.Metric, slo.Stat, slo.Max)
+			}
+		}
+	}
+	return &m, nil
+}
+
+// Workload returns the named workload.
+func (m *Manifest) Workload(name string) (Workload, bool) {
+	for _, w := range m.Workloads {
+		if w.Name == name {
+			return w, true
+		}
+	}
+	return Workload{}, false
+}
+
+// Implements reports whether the manifest declares runtime as
+// implementing w for kind.
+func (w Workload) Implements(kind discover.Kind, runtime string) bool {
+	return slices.Contains(w.Runtimes[kind], runtime)
+}
+
+// Gap is a difference between the declared and the discovered
+// implementations.
+type Gap struct {
+	Kind     discover.Kind
+	Runtime  string
+	Workload string
+	// Missing is set for a declared implementation that was not found;
+	// otherwise the implementation exists but is not declared.
+	Missing bool
+}
+
+func (g Gap) String() string {
+	if g.Missing {
+		return fmt.Sprintf("%s/%s/%s: declared in the manifest but not implemented", g.Kind, g.Runtime, g.Workload)
+	}
+	return fmt.Sprintf("%s/%s/%s: implemented but not declared in the manifest", g.Kind, g.Runtime, g.Workload)
+}
+
+// Coverage compares the manifest with the discovered targets and returns
+// every gap, sorted. Architecture, package and SnapStart variants share
+// their base target's entry.
+func (m *Manifest) Coverage(targets []discover.Target) []Gap {
+	type key struct {
+		kind              discover.Kind
+		runtime, workload string
+	}
+	found := map[key]bool{}
+	var gaps []Gap
+	for _, t := range targets {
+		k := key{t.Kind, t.Runtime, t.Workload}
+		if found[k] {
+			continue
+		}
+		found[k] = true
+		if w, ok := m.Workload(t.Workload); !ok || !w.Implements(t.Kind, t.Runtime) {
+			gaps = append(gaps, Gap{Kind: t.Kind, Runtime: t.Runtime, Workload: t.Workload})
+		}
+	}
+	for _, w := range m.Workloads {
+		for kind, runtimes := range w.Runtimes {
+			for _, rt := range runtimes {
+				if !found[key{kind, rt, w.Name}] {
+					gaps = append(gaps, Gap{Kind: kind, Runtime: rt, Workload: w.Name, Missing: true})
+				}
+			}
+		}
+	}
+	sort.Slice(gaps, func(i, j int) bool {
+		a, b := gaps[i], gaps[j]
+		if a.Workload != b.Workload {
+			return a.Workload < b.Workload
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Runtime < b.Runtime
+	})
+	return gaps
+}
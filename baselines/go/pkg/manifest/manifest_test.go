@@ -0,0 +1,111 @@
+package manifest
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"lambdaperf/pkg/discover"
+)
+
+const sample = `
+workloads:
+  - name: fibonacci
+    params: {n: 35}
+    inputs:
+      n: {default: 35, min: 0, max: 40, fixed: [rust]}
+    expected: fibonacci(35)=9227465
+    runtimes:
+      local: [go, python]
+      lambda: [go, rust]
+  - name: minimal
+    runtimes:
+      lambda: [go]
+`
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, ok := m.Workload("fibonacci")
+	if !ok || w.Expected != "fibonacci(35)=9227465" || w.Params["n"] != 35 {
+		t.Fatalf("fibonacci = %+v", w)
+	}
+	if !w.Implements(discover.KindLambda, "rust") || w.Implements(discover.KindLocal, "rust") {
+		t.Errorf("Implements: runtimes = %v", w.Runtimes)
+	}
+	if in := w.Inputs["n"]; in.Default != 35 || in.Max != 40 || !in.Accepts("go") || in.Accepts("rust") {
+		t.Errorf("input n = %+v", in)
+	}
+
+	for name, bad := range map[string]string{
+		"unknown field": "workloads:\n  - name: a\n    expect: x\n    runtimes: {local: [go]}\n",
+		"unknown kind":  "workloads:\n  - name: a\n    runtimes: {docker: [go]}\n",
+		"no runtimes":   "workloads:\n  - name: a\n",
+		"duplicate":     "workloads:\n  - name: a\n    runtimes: {local: [go]}\n  - name: a\n    runtimes: {local: [go]}\n",
+		"runtime twice": "workloads:\n  - name: a\n    runtimes: {local: [go, go]}\n",
+		"empty":         "workloads: []\n",
+		"bad default":   "workloads:\n  - name: a\n    inputs: {n: {default: 50, min: 0, max: 40}}\n    runtimes: {lambda: [go]}\n",
+		"fixed local":   "workloads:\n  - name: a\n    inputs: {n: {default: 1, max: 2, fixed: [go]}}\n    runtimes: {local: [go]}\n",
+		"slo metric":    "workloads:\n  - name: a\n    runtimes: {lambda: [go]}\n    slos: [{metric: cold_ms, stat: mean, max: 100}]\n",
+		"slo stat":      "workloads:\n  - name: a\n    runtimes: {lambda: [go]}\n    slos: [{metric: init_ms, stat: p90, max: 100}]\n",
+		"slo max":       "workloads:\n  - name: a\n    runtimes: {lambda: [go]}\n    slos: [{metric: init_ms, stat: mean}]\n",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	m, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := []discover.Target{
+		{Kind: discover.KindLocal, Runtime: "go", Workload: "fibonacci"},
+		{Kind: discover.KindLocal, Runtime: "python", Workload: "fibonacci"},
+		{Kind: discover.KindLambda, Runtime: "go", Workload: "fibonacci"},
+		{Kind: discover.KindLambda, Runtime: "go", Workload: "fibonacci", Arch: discover.ArchARM64},
+		{Kind: discover.KindLambda, Runtime: "go", Workload: "minimal"},
+		{Kind: discover.KindLambda, Runtime: "python", Workload: "minimal"},
+		{Kind: discover.KindLambda, Runtime: "go", Workload: "sieve"},
+	}
+	var got []string
+	for _, g := range m.Coverage(targets) {
+		got = append(got, g.String())
+	}
+	want := []string{
+		"lambda/rust/fibonacci: declared in the manifest but not implemented",
+		"lambda/python/minimal: implemented but not declared in the manifest",
+		"lambda/go/sieve: implemented but not declared in the manifest",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Coverage =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// TestRepositoryManifest keeps benchmarks/manifest.yaml in step with the
+// implementations in the tree.
+func TestRepositoryManifest(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := discover.FindRoot(wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets, err := discover.Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range m.Coverage(targets) {
+		t.Error(g)
+	}
+}
@@ -0,0 +1,229 @@
+// Package matrix loads bench.yaml, which declares the benchmark matrix:
+// groups of workloads and runtimes with the memory sizes, architectures,
+// regions and sample counts each is measured at. Groups carry tags, so a
+// run selects part of the matrix by what it measures (cpu, io) rather
+// than by listing targets; ruchy-bench matrix runs the selection.
+package matrix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"lambdaperf/pkg/account"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/manifest"
+)
+
+// Path is the matrix's location relative to the repository root.
+const Path = "bench.yaml"
+
+// Matrix is the declared set of groups.
+type Matrix struct {
+	// Defaults apply to every group that leaves a setting out.
+	Defaults Settings `yaml:"defaults"`
+	Groups   []Group  `yaml:"groups"`
+	// AWS is the account every command's AWS calls go to, matrix or
+	// not; see pkg/account.
+	AWS account.Account `yaml:"aws,omitempty"`
+}
+
+// Settings are how a group is measured.
+type Settings struct {
+	// Samples is the number of recorded invocations or runs per target.
+	Samples int `yaml:"samples,omitempty"`
+	// MemoryMB lists the memory sizes to measure Lambda targets at; none
+	// measures them at their deployed size.
+	MemoryMB []int32 `yaml:"memory,omitempty"`
+	// Archs lists the Lambda architectures; none means x86_64.
+	Archs []string `yaml:"archs,omitempty"`
+	// Regions lists the AWS regions to measure Lambda targets in; none
+	// means the AWS config's.
+	Regions []string `yaml:"regions,omitempty"`
+}
+
+// Group is a set of targets measured alike: every runtime's
+// implementation of every workload, of one kind.
+type Group struct {
+	Name      string        `yaml:"name"`
+	Tags      []string      `yaml:"tags,omitempty"`
+	Kind      discover.Kind `yaml:"kind"`
+	Workloads []string      `yaml:"workloads"`
+	// Runtimes picks among the workloads' implementations; none takes
+	// them all.
+	Runtimes []string `yaml:"runtimes,omitempty"`
+	Settings `yaml:",inline"`
+}
+
+// Matches reports whether the group is tagged tag. A group's name counts
+// as one of its tags.
+func (g Group) Matches(tag string) bool {
+	return g.Name == tag || slices.Contains(g.Tags, tag)
+}
+
+// Load reads and validates the matrix at path.
+func Load(path string) (*Matrix, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes and validates a matrix. Unknown fields are errors, as in
+// the manifest.
+func Parse(data []byte) (*Matrix, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var m Matrix
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (m *Matrix) validate() error {
+	if len(m.Groups) == 0 {
+		return errors.New("no groups")
+	}
+	if err := m.Defaults.validate(); err != nil {
+		return fmt.Errorf("defaults: %w", err)
+	}
+	if err := m.AWS.Validate(); err != nil {
+		return fmt.Errorf("aws: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, g := range m.Groups {
+		if g.Name == "" {
+			return errors.New("group without a name")
+		}
+		if seen[g.Name] {
+			return fmt.Errorf("group %s declared twice", g.Name)
+		}
+		seen[g.Name] = true
+		if g.Kind != discover.KindLocal && g.Kind != discover.KindLambda {
+			return fmt.Errorf("group %s: kind %q: want local or lambda", g.Name, g.Kind)
+		}
+		if len(g.Workloads) == 0 {
+			return fmt.Errorf("group %s: no workloads", g.Name)
+		}
+		if err := g.Settings.validate(); err != nil {
+			return fmt.Errorf("group %s: %w", g.Name, err)
+		}
+		if g.Kind == discover.KindLocal && (len(g.MemoryMB) > 0 || len(g.Archs) > 0 || len(g.Regions) > 0) {
+			return fmt.Errorf("group %s: local groups run on the host; memory, archs and regions are for lambda groups", g.Name)
+		}
+	}
+	return nil
+}
+
+func (s Settings) validate() error {
+	if s.Samples < 0 {
+		return fmt.Errorf("samples %d", s.Samples)
+	}
+	for _, mb := range s.MemoryMB {
+		if mb < 128 || mb > 10240 {
+			return fmt.Errorf("memory %d: want 128-10240 MB", mb)
+		}
+	}
+	for _, a := range s.Archs {
+		if a != discover.ArchX86 && a != discover.ArchARM64 {
+			return fmt.Errorf("unknown architecture %q", a)
+		}
+	}
+	return nil
+}
+
+// Check reports workloads the manifest does not declare, and runtimes
+// that implement none of their group's workloads, which would make the
+// group run less than it says.
+func (m *Matrix) Check(man *manifest.Manifest) error {
+	declared := map[string]manifest.Workload{}
+	for _, w := range man.Workloads {
+		declared[w.Name] = w
+	}
+	var errs []error
+	for _, g := range m.Groups {
+		implemented := map[string]bool{}
+		for _, name := range g.Workloads {
+			w, ok := declared[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("group %s: workload %s is not in %s", g.Name, name, manifest.Path))
+				continue
+			}
+			for _, rt := range w.Runtimes[g.Kind] {
+				implemented[rt] = true
+			}
+		}
+		for _, rt := range g.Runtimes {
+			if !implemented[rt] {
+				errs = append(errs, fmt.Errorf("group %s: runtime %s implements none of its %s workloads", g.Name, rt, g.Kind))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Tags lists every tag and group name, sorted.
+func (m *Matrix) Tags() []string {
+	var tags []string
+	for _, g := range m.Groups {
+		tags = append(tags, g.Name)
+		tags = append(tags, g.Tags...)
+	}
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// Select returns the groups matching any tag of only (every group when
+// only is empty) and none of skip, in declaration order, with the
+// defaults filled in. A tag no group has is an error: a misspelt -skip
+// would otherwise run what it meant to leave out.
+func (m *Matrix) Select(only, skip []string) ([]Group, error) {
+	tags := m.Tags()
+	for _, t := range append(slices.Clone(only), skip...) {
+		if !slices.Contains(tags, t) {
+			return nil, fmt.Errorf("no group is tagged %q; tags are %v", t, tags)
+		}
+	}
+	var groups []Group
+	for _, g := range m.Groups {
+		matches := func(tag string) bool { return g.Matches(tag) }
+		if len(only) > 0 && !slices.ContainsFunc(only, matches) || slices.ContainsFunc(skip, matches) {
+			continue
+		}
+		groups = append(groups, m.withDefaults(g))
+	}
+	return groups, nil
+}
+
+func (m *Matrix) withDefaults(g Group) Group {
+	d := m.Defaults
+	if g.Samples == 0 {
+		g.Samples = d.Samples
+	}
+	if g.Kind == discover.KindLocal {
+		return g
+	}
+	if g.MemoryMB == nil {
+		g.MemoryMB = d.MemoryMB
+	}
+	if g.Archs == nil {
+		g.Archs = d.Archs
+	}
+	if g.Regions == nil {
+		g.Regions = d.Regions
+	}
+	return g
+}
@@ -0,0 +1,135 @@
+package matrix
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/manifest"
+)
+
+const sample = `
+defaults:
+  samples: 10
+  memory: [128, 1024]
+  regions: [us-east-1]
+groups:
+  - name: cpu-lambda
+    tags: [cpu]
+    kind: lambda
+    workloads: [fibonacci]
+    runtimes: [go, rust]
+    archs: [x86_64, arm64]
+    samples: 20
+  - name: cpu-local
+    tags: [cpu, local]
+    kind: local
+    workloads: [fibonacci]
+  - name: io
+    tags: [io]
+    kind: lambda
+    workloads: [s3]
+    memory: []
+aws:
+  profile: bench
+  role_arn: arn:aws:iam::123456789012:role/ruchy-bench
+  external_id: ext-42
+  duration: 2h
+  regions:
+    eu-west-1: {profile: bench-eu}
+`
+
+func TestSelect(t *testing.T) {
+	m, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := m.AWS.For("us-east-1"); c.Profile != "bench" || c.ExternalID != "ext-42" || c.Duration != 2*time.Hour || m.AWS.For("eu-west-1").Profile != "bench-eu" {
+		t.Errorf("aws = %+v", m.AWS)
+	}
+	names := func(gs []Group) (out []string) {
+		for _, g := range gs {
+			out = append(out, g.Name)
+		}
+		return out
+	}
+	for _, c := range []struct {
+		only, skip, want []string
+	}{
+		{nil, nil, []string{"cpu-lambda", "cpu-local", "io"}},
+		{[]string{"cpu"}, nil, []string{"cpu-lambda", "cpu-local"}},
+		{nil, []string{"io"}, []string{"cpu-lambda", "cpu-local"}},
+		{[]string{"cpu"}, []string{"local"}, []string{"cpu-lambda"}},
+		{[]string{"io", "cpu-local"}, nil, []string{"cpu-local", "io"}},
+	} {
+		gs, err := m.Select(c.only, c.skip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(gs); !slices.Equal(got, c.want) {
+			t.Errorf("Select(%v, %v) = %v, want %v", c.only, c.skip, got, c.want)
+		}
+	}
+	if _, err := m.Select(nil, []string{"cpuu"}); err == nil {
+		t.Error("Select skipped an unknown tag")
+	}
+
+	gs, _ := m.Select(nil, nil)
+	if g := gs[0]; g.Samples != 20 || !slices.Equal(g.MemoryMB, []int32{128, 1024}) || !slices.Equal(g.Regions, []string{"us-east-1"}) {
+		t.Errorf("cpu-lambda = %+v", g)
+	}
+	// Local groups take no Lambda settings; an empty list overrides a
+	// default.
+	if g := gs[1]; g.Samples != 10 || g.MemoryMB != nil || g.Regions != nil {
+		t.Errorf("cpu-local = %+v", g)
+	}
+	if g := gs[2]; len(g.MemoryMB) != 0 {
+		t.Errorf("io memory = %v, want none", g.MemoryMB)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	group := "groups:\n  - name: a\n    kind: lambda\n    workloads: [fibonacci]\n"
+	for name, bad := range map[string]string{
+		"unknown field": group + "    sample: 3\n",
+		"no groups":     "defaults: {samples: 3}\n",
+		"unknown kind":  strings.Replace(group, "lambda", "docker", 1),
+		"no workloads":  "groups:\n  - name: a\n    kind: lambda\n",
+		"duplicate":     group + strings.TrimPrefix(group, "groups:\n"),
+		"bad memory":    group + "    memory: [64]\n",
+		"bad arch":      group + "    archs: [riscv]\n",
+		"local memory":  strings.Replace(group, "lambda", "local", 1) + "    memory: [512]\n",
+		"bad role":      group + "aws: {role_arn: bench}\n",
+	} {
+		if _, err := Parse([]byte(bad)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// TestRepositoryMatrix keeps bench.yaml in step with the manifest.
+func TestRepositoryMatrix(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := discover.FindRoot(wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := Load(filepath.Join(root, Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	man, err := manifest.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check(man); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,163 @@
+// Package mockapi provisions the endpoint the httpclient workload
+// (main-httpclient.go) sends its requests to: a regional API Gateway REST
+// API whose GET / is a MOCK integration, answered by API Gateway itself
+// with Body. Nothing runs behind it, so what the handler measures is its
+// own HTTP client: DNS, TCP and TLS setup when it has no connection to
+// reuse, and the round trips.
+//
+// The API is public, as the handler sends unsigned requests; it returns
+// nothing but Body, and API Gateway bills each request.
+package mockapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+const (
+	// Workload is the workload name of main-httpclient.go.
+	Workload = "httpclient"
+	// DefaultAPI is the name of the REST API ruchy-bench seed creates.
+	DefaultAPI = "ruchy-bench-mock"
+	// Stage is the stage the API is deployed to, the first path segment
+	// of its URL.
+	Stage = "bench"
+	// Body is what the mock integration answers GET / with.
+	Body = `{"ok":true}`
+)
+
+// Event is the invocation payload of the httpclient workload, as
+// main-httpclient.go reads it.
+type Event struct {
+	URL string `json:"url"`
+}
+
+// Client manages REST APIs through the API Gateway management endpoints,
+// with only the calls the harness makes, as queue.Client.
+type Client struct {
+	Config aws.Config
+	// Endpoint overrides https://apigateway.<region>.amazonaws.com.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// APIError is an error response from API Gateway, such as
+// "NotFoundException".
+type APIError = awsapi.Error
+
+func isCode(err error, code string) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+type restAPI struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	RootResourceID string `json:"rootResourceId"`
+}
+
+// Ensure makes sure the REST API called name exists, answers GET / and
+// is deployed to Stage, returning the URL to send requests to and
+// whether the API had to be created. An API left half configured by an
+// earlier failure is finished rather than duplicated.
+func (c *Client) Ensure(ctx context.Context, name string) (string, bool, error) {
+	api, err := c.find(ctx, name)
+	if err != nil {
+		return "", false, err
+	}
+	created := api == nil
+	if created {
+		api = &restAPI{}
+		in := map[string]any{
+			"name":                  name,
+			"description":           "ruchy-bench httpclient mock endpoint",
+			"endpointConfiguration": map[string]any{"types": []string{"REGIONAL"}},
+		}
+		if err := c.call(ctx, http.MethodPost, "/restapis", in, api); err != nil {
+			return "", false, fmt.Errorf("create rest api %s: %w", name, err)
+		}
+	} else {
+		err := c.call(ctx, http.MethodGet, "/restapis/"+api.ID+"/stages/"+Stage, nil, &struct{}{})
+		if err == nil {
+			return c.url(api.ID), false, nil
+		}
+		if !isCode(err, "NotFoundException") {
+			return "", false, fmt.Errorf("get stage %s of %s: %w", Stage, name, err)
+		}
+	}
+	if err := c.configure(ctx, api); err != nil {
+		return "", created, fmt.Errorf("configure rest api %s: %w", name, err)
+	}
+	return c.url(api.ID), created, nil
+}
+
+// find returns the REST API called name, or nil if there is none.
+func (c *Client) find(ctx context.Context, name string) (*restAPI, error) {
+	position := ""
+	for {
+		path := "/restapis?limit=500"
+		if position != "" {
+			path += "&position=" + url.QueryEscape(position)
+		}
+		var out struct {
+			Items    []restAPI `json:"item"`
+			Position string    `json:"position"`
+		}
+		if err := c.call(ctx, http.MethodGet, path, nil, &out); err != nil {
+			return nil, fmt.Errorf("get rest apis: %w", err)
+		}
+		for i := range out.Items {
+			if out.Items[i].Name == name {
+				return &out.Items[i], nil
+			}
+		}
+		if position = out.Position; position == "" {
+			return nil, nil
+		}
+	}
+}
+
+// configure sets up GET / as the mock integration and deploys the API to
+// Stage. Parts that already exist are left as they are.
+func (c *Client) configure(ctx context.Context, api *restAPI) error {
+	method := "/restapis/" + api.ID + "/resources/" + api.RootResourceID + "/methods/GET"
+	steps := []struct {
+		path string
+		in   map[string]any
+	}{
+		{method, map[string]any{"authorizationType": "NONE"}},
+		{method + "/responses/200", map[string]any{}},
+		{method + "/integration", map[string]any{
+			"type":             "MOCK",
+			"requestTemplates": map[string]string{"application/json": `{"statusCode": 200}`},
+		}},
+		{method + "/integration/responses/200", map[string]any{
+			"responseTemplates": map[string]string{"application/json": Body},
+		}},
+	}
+	for _, s := range steps {
+		if err := c.call(ctx, http.MethodPut, s.path, s.in, &struct{}{}); err != nil && !isCode(err, "ConflictException") {
+			return err
+		}
+	}
+	in := map[string]any{"stageName": Stage, "description": "ruchy-bench seed"}
+	return c.call(ctx, http.MethodPost, "/restapis/"+api.ID+"/deployments", in, &struct{}{})
+}
+
+func (c *Client) url(id string) string {
+	return fmt.Sprintf("https://%s.execute-api.%s.amazonaws.com/%s/", id, c.Config.Region, Stage)
+}
+
+// call sends in, if not nil, as the JSON body of a method request to
+// path and decodes the response into out.
+func (c *Client) call(ctx context.Context, method, path string, in, out any) error {
+	api := &awsapi.Client{Config: c.Config, Service: "apigateway", Endpoint: c.Endpoint, HTTP: c.HTTP}
+	return api.REST(ctx, method, path, in, out)
+}
@@ -0,0 +1,95 @@
+package mockapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// fakeGateway answers the management calls Ensure makes for a single
+// REST API, recording the configuration it was given.
+type fakeGateway struct {
+	apis     []restAPI
+	puts     map[string]map[string]any
+	deployed bool
+}
+
+func (f *fakeGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var in map[string]any
+	json.NewDecoder(r.Body).Decode(&in)
+	notFound := func() {
+		w.Header().Set("X-Amzn-ErrorType", "NotFoundException:http://internal.amazon.com/coral/com.amazon.backplane.restapi/")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Invalid stage identifier specified"}`))
+	}
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/restapis":
+		json.NewEncoder(w).Encode(map[string]any{"item": f.apis})
+	case r.Method == http.MethodPost && r.URL.Path == "/restapis":
+		api := restAPI{ID: "abc123", Name: in["name"].(string), RootResourceID: "root"}
+		f.apis = append(f.apis, api)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(api)
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/stages/"+Stage):
+		if !f.deployed {
+			notFound()
+			return
+		}
+		w.Write([]byte(`{"stageName":"bench"}`))
+	case r.Method == http.MethodPut:
+		if _, ok := f.puts[r.URL.Path]; ok {
+			w.Header().Set("X-Amzn-ErrorType", "ConflictException:")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"Method already exists for this resource"}`))
+			return
+		}
+		f.puts[r.URL.Path] = in
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/restapis/abc123/deployments":
+		f.deployed = in["stageName"] == Stage
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"d1"}`))
+	default:
+		notFound()
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	f := &fakeGateway{puts: map[string]map[string]any{}}
+	srv := httptest.NewServer(f)
+	defer srv.Close()
+	c := &Client{Config: aws.Config{Region: "eu-west-1"}, Endpoint: srv.URL}
+	ctx := context.Background()
+
+	u, created, err := c.Ensure(ctx, DefaultAPI)
+	if err != nil || !created || u != "https://abc123.execute-api.eu-west-1.amazonaws.com/bench/" {
+		t.Fatalf("Ensure = %q, %v, %v", u, created, err)
+	}
+	integration := f.puts["/restapis/abc123/resources/root/methods/GET/integration"]
+	if integration["type"] != "MOCK" {
+		t.Errorf("integration = %v", integration)
+	}
+	if got := f.puts["/restapis/abc123/resources/root/methods/GET/integration/responses/200"]["responseTemplates"]; got.(map[string]any)["application/json"] != Body {
+		t.Errorf("response templates = %v", got)
+	}
+
+	if u, created, err = c.Ensure(ctx, DefaultAPI); err != nil || created || !strings.Contains(u, "abc123") {
+		t.Errorf("second Ensure = %q, %v, %v", u, created, err)
+	}
+	if len(f.apis) != 1 {
+		t.Errorf("%d APIs created", len(f.apis))
+	}
+
+	// An API whose stage was never deployed is finished, not recreated;
+	// the methods it already has conflict and are kept.
+	f.deployed = false
+	if _, created, err = c.Ensure(ctx, DefaultAPI); err != nil || created || !f.deployed {
+		t.Errorf("Ensure of an undeployed API: created=%v deployed=%v err=%v", created, f.deployed, err)
+	}
+}
@@ -0,0 +1,148 @@
+// Package notify posts a message about a run where people will see it: an
+// SNS topic, whose subscribers may be email addresses, queues or chat
+// integrations, or a Slack or Discord incoming webhook. ruchy-bench
+// daemon posts every nightly run's comparison with the night before
+// through it, so a regression is reported rather than waiting to be
+// looked for.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+// Notifier posts a message.
+type Notifier interface {
+	Notify(ctx context.Context, subject, text string) error
+}
+
+// MaxSubject is the longest subject SNS accepts; longer ones are cut.
+const MaxSubject = 100
+
+// SNS publishes to a topic over the SNS query API. Like queue.Client it
+// implements only the call it makes, which keeps the SNS service module
+// out of the harness.
+type SNS struct {
+	Config aws.Config
+	// TopicARN is the topic published to, in the region the ARN names.
+	TopicARN string
+	// Endpoint overrides https://sns.<region>.amazonaws.com.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// APIError is an error response from SNS, such as "NotFound" or
+// "AuthorizationError".
+type APIError = awsapi.Error
+
+// Notify publishes text to the topic with subject, which email
+// subscriptions use as theirs.
+func (s SNS) Notify(ctx context.Context, subject, text string) error {
+	// arn:aws:sns:<region>:<account>:<topic>
+	parts := strings.Split(s.TopicARN, ":")
+	if len(parts) != 6 || parts[2] != "sns" {
+		return fmt.Errorf("%q is not an SNS topic ARN", s.TopicARN)
+	}
+	region := parts[3]
+	params := map[string]string{
+		"TopicArn": s.TopicARN,
+		"Subject":  cut(subject, MaxSubject),
+		"Message":  text,
+	}
+	api := &awsapi.Client{Config: s.Config, Service: "sns", Region: region, Endpoint: s.Endpoint, HTTP: s.HTTP}
+	if err := api.Query(ctx, "2010-03-31", "Publish", params, nil); err != nil {
+		return fmt.Errorf("publish to %s: %w", s.TopicARN, err)
+	}
+	return nil
+}
+
+// Slack posts to a Slack incoming webhook, with the subject as the
+// message's first line in bold and the text below it preformatted, so
+// tables line up.
+type Slack struct {
+	URL string
+	// HTTP posts to the webhook; nil means http.DefaultClient.
+	HTTP *http.Client
+}
+
+func (s Slack) Notify(ctx context.Context, subject, text string) error {
+	return postWebhook(ctx, s.HTTP, "Slack", s.URL, map[string]string{"text": "*" + subject + "*\n```\n" + text + "\n```"})
+}
+
+// MaxDiscord is the longest message a Discord webhook accepts; longer
+// texts are cut to fit.
+const MaxDiscord = 2000
+
+// Discord posts to a Discord webhook, formatted as Slack formats it: the
+// subject in bold, the text preformatted below it.
+type Discord struct {
+	// URL and HTTP are as in Slack.
+	URL  string
+	HTTP *http.Client
+}
+
+func (d Discord) Notify(ctx context.Context, subject, text string) error {
+	head, tail := "**"+subject+"**\n```\n", "\n```"
+	if n := len(head) + len(text) + len(tail); n > MaxDiscord {
+		text = cut(text, max(0, MaxDiscord-len(head)-len(tail)-len("\n…"))) + "\n…"
+	}
+	return postWebhook(ctx, d.HTTP, "Discord", d.URL, map[string]string{"content": head + text + tail})
+}
+
+// postWebhook posts msg as JSON to a chat service's incoming webhook.
+func postWebhook(ctx context.Context, c *http.Client, service, webhook string, msg any) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client(c).Do(req)
+	if err != nil {
+		// The URL is the webhook's credential; keep it out of the error.
+		if uerr := (*url.Error)(nil); errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return fmt.Errorf("post to %s webhook: %w", service, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("post to %s webhook: %s: %s", service, resp.Status, bytes.TrimSpace(data))
+	}
+	return nil
+}
+
+func client(c *http.Client) *http.Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
+
+// cut shortens s to at most n bytes without splitting a character.
+func cut(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
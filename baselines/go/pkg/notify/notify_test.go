@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const topic = "arn:aws:sns:eu-west-1:123456789012:ruchy-bench-nightly"
+
+func TestSNS(t *testing.T) {
+	var got url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r.PostForm
+		if got.Get("TopicArn") != topic {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>NotFound</Code><Message>Topic does not exist</Message></Error></ErrorResponse>`))
+			return
+		}
+		w.Write([]byte(`<PublishResponse><PublishResult><MessageId>m-1</MessageId></PublishResult></PublishResponse>`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	subject := strings.Repeat("é", MaxSubject)
+	if err := (SNS{TopicARN: topic, Endpoint: srv.URL}).Notify(ctx, subject, "2 regressed"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("Action") != "Publish" || got.Get("Message") != "2 regressed" || len(got.Get("Subject")) != MaxSubject {
+		t.Errorf("published %v", got)
+	}
+
+	var apiErr *APIError
+	err := (SNS{TopicARN: topic + "-gone", Endpoint: srv.URL}).Notify(ctx, "s", "t")
+	if !errors.As(err, &apiErr) || apiErr.Code != "NotFound" {
+		t.Errorf("publish to a missing topic: %v", err)
+	}
+	if err := (SNS{TopicARN: "ruchy-bench-nightly"}).Notify(ctx, "s", "t"); err == nil {
+		t.Error("published to a topic name rather than an ARN")
+	}
+}
+
+func TestSlack(t *testing.T) {
+	var text string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&msg)
+		if text = msg.Text; r.URL.Path != "/services/T0/B0/secret" {
+			http.Error(w, "invalid_token", http.StatusForbidden)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := (Slack{URL: srv.URL + "/services/T0/B0/secret"}).Notify(ctx, "nightly", "a\tb"); err != nil {
+		t.Fatal(err)
+	}
+	if text != "*nightly*\n```\na\tb\n```" {
+		t.Errorf("posted %q", text)
+	}
+	if err := (Slack{URL: srv.URL + "/revoked"}).Notify(ctx, "s", "t"); err == nil || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("rejected post: %v", err)
+	}
+	if err := (Slack{URL: "http://127.0.0.1:1/services/T0/B0/secret"}).Notify(ctx, "s", "t"); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("unreachable webhook: %v", err)
+	}
+}
+
+func TestDiscord(t *testing.T) {
+	var content string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct{ Content string }
+		json.NewDecoder(r.Body).Decode(&msg)
+		if content = msg.Content; len(content) > MaxDiscord {
+			http.Error(w, `{"message": "Invalid Form Body", "code": 50035}`, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := (Discord{URL: srv.URL}).Notify(ctx, "run", "a\tb"); err != nil {
+		t.Fatal(err)
+	}
+	if content != "**run**\n```\na\tb\n```" {
+		t.Errorf("posted %q", content)
+	}
+	// A text too long for one message is cut, keeping the code block
+	// closed.
+	if err := (Discord{URL: srv.URL}).Notify(ctx, "run", strings.Repeat("é", MaxDiscord)); err != nil {
+		t.Fatal(err)
+	}
+	if len(content) > MaxDiscord || !strings.HasSuffix(content, "…\n```") {
+		t.Errorf("posted %d bytes ending %q", len(content), content[len(content)-10:])
+	}
+}
@@ -0,0 +1,153 @@
+// Package pkgzip writes Lambda deployment zips that are byte for byte
+// reproducible: entries are sorted by name, every timestamp is Epoch and
+// modes are reduced to executable or not. Two builds of the same inputs
+// hash alike on any machine, so equal package hashes in two runs mean
+// they measured identical code.
+package pkgzip
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Epoch is the modification time of every entry: the earliest time a
+// zip's MS-DOS timestamp can hold.
+var Epoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Entry is a file to archive: the contents of Src stored as Name. Only
+// the executable bits of Mode are kept.
+type Entry struct {
+	Name string
+	Src  string
+	Mode os.FileMode
+}
+
+// file is an entry's name, normalized mode and contents.
+type file struct {
+	name string
+	mode os.FileMode
+	data []byte
+}
+
+// Write writes a reproducible archive of entries to dst.
+func Write(dst string, entries ...Entry) error {
+	files := make([]file, 0, len(entries))
+	for _, e := range entries {
+		data, err := os.ReadFile(e.Src)
+		if err != nil {
+			return err
+		}
+		files = append(files, file{e.Name, e.Mode, data})
+	}
+	return write(dst, files)
+}
+
+// Normalize rewrites the archive at path, built by a script or zip(1),
+// as Write would have: directory entries, timestamps, extra fields and
+// comments are dropped and the files are sorted by name.
+func Normalize(path string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	var files []file
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			zr.Close()
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			zr.Close()
+			return fmt.Errorf("%s: %s: %w", path, f.Name, err)
+		}
+		files = append(files, file{f.Name, f.Mode(), data})
+	}
+	if err := zr.Close(); err != nil {
+		return err
+	}
+	return write(path, files)
+}
+
+// write archives files to dst through a temporary file renamed over it,
+// so Normalize never leaves a truncated package behind.
+func write(dst string, files []file) error {
+	slices.SortFunc(files, func(a, b file) int { return strings.Compare(a.name, b.name) })
+	for i := 1; i < len(files); i++ {
+		if files[i].name == files[i-1].name {
+			return fmt.Errorf("%s: duplicate entry %s", dst, files[i].name)
+		}
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		hdr := &zip.FileHeader{Name: filepath.ToSlash(f.name), Method: zip.Deflate, Modified: Epoch}
+		hdr.SetMode(normalMode(f.mode))
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".pkgzip-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// normalMode is 0755 for a mode with any executable bit and 0644
+// otherwise, discarding the umask and owner of whoever built it.
+func normalMode(m os.FileMode) os.FileMode {
+	if m&0o111 != 0 {
+		return 0o755
+	}
+	return 0o644
+}
+
+// Hash returns the SHA-256 of the file at path, base64-encoded as Lambda
+// reports a function's CodeSha256, so a built package can be compared
+// with the one deployed.
+func Hash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,115 @@
+package pkgzip
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteIsReproducible(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, mode os.FileMode, mtime time.Time) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	now := time.Now()
+	bin := write("bootstrap", "\x7fELF binary", 0o700, now)
+	lib := write("lib.txt", "data", 0o600, now)
+	a := filepath.Join(dir, "a.zip")
+	if err := Write(a, Entry{"bootstrap", bin, 0o700}, Entry{"lib/data.txt", lib, 0o600}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same contents, touched later and listed in another order.
+	bin = write("bootstrap", "\x7fELF binary", 0o750, now.Add(time.Hour))
+	lib = write("lib.txt", "data", 0o664, now.Add(time.Hour))
+	b := filepath.Join(dir, "b.zip")
+	if err := Write(b, Entry{"lib/data.txt", lib, 0o664}, Entry{"bootstrap", bin, 0o750}); err != nil {
+		t.Fatal(err)
+	}
+	ha, err := Hash(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hb, _ := Hash(b); ha != hb {
+		t.Fatalf("hashes differ: %s, %s", ha, hb)
+	}
+
+	zr, err := zip.OpenReader(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 2 {
+		t.Fatalf("got %d entries", len(zr.File))
+	}
+	for i, want := range []struct {
+		name string
+		mode os.FileMode
+	}{{"bootstrap", 0o755}, {"lib/data.txt", 0o644}} {
+		f := zr.File[i]
+		if f.Name != want.name || f.Mode() != want.mode || !f.Modified.Equal(Epoch) {
+			t.Errorf("entry %d = %s %v %v, want %s %v %v", i, f.Name, f.Mode(), f.Modified, want.name, want.mode, Epoch)
+		}
+	}
+
+	if err := Write(filepath.Join(dir, "dup.zip"), Entry{"x", lib, 0}, Entry{"x", bin, 0}); err == nil {
+		t.Error("archived a duplicate entry")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	dir := t.TempDir()
+	// An archive as zip(1) writes one: a directory entry, today's
+	// timestamps, an extra field and a comment, in no particular order.
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name string
+		mode os.FileMode
+		data string
+	}{{"lib/", os.ModeDir | 0o755, ""}, {"lib/data.txt", 0o640, "data"}, {"bootstrap", 0o711, "\x7fELF binary"}} {
+		hdr := &zip.FileHeader{Name: f.name, Method: zip.Store, Modified: time.Now(), Extra: []byte{0xfe, 0xca, 0, 0}}
+		hdr.SetMode(f.mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(f.data))
+	}
+	zw.SetComment("built on a laptop")
+	zw.Close()
+	scripted := filepath.Join(dir, "scripted.zip")
+	if err := os.WriteFile(scripted, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Normalize(scripted); err != nil {
+		t.Fatal(err)
+	}
+
+	bin, lib := filepath.Join(dir, "bootstrap"), filepath.Join(dir, "data.txt")
+	os.WriteFile(bin, []byte("\x7fELF binary"), 0o755)
+	os.WriteFile(lib, []byte("data"), 0o644)
+	written := filepath.Join(dir, "written.zip")
+	if err := Write(written, Entry{"bootstrap", bin, 0o755}, Entry{"lib/data.txt", lib, 0o644}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(scripted)
+	want, _ := os.ReadFile(written)
+	if !bytes.Equal(got, want) {
+		t.Error("normalized archive differs from the one Write produces")
+	}
+}
@@ -0,0 +1,177 @@
+// Package plan estimates what a benchmark command would do before it
+// runs: the functions it invokes, how many invocations and configuration
+// updates it makes, how long that takes and what Lambda bills for it.
+package plan
+
+import (
+	"slices"
+	"time"
+
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// Per is what one invocation of a target is expected to take.
+type Per struct {
+	// ClientMS is an invocation's wall-clock time, BilledMS what Lambda
+	// bills for it when warm.
+	ClientMS, BilledMS float64
+	// InitMS is what a cold start adds, to both.
+	InitMS float64
+	// Source says where the figures come from: the ID of the stored run
+	// they were observed in, or "default".
+	Source string
+}
+
+// Default is assumed for targets without recorded history. It errs high
+// for the baselines, which warm-invoke in a few milliseconds.
+var Default = Per{ClientMS: 100, BilledMS: 20, InitMS: 300, Source: "default"}
+
+const (
+	// UpdateWait is how long a configuration update takes to apply.
+	UpdateWait = 5 * time.Second
+	// PublishWait is how long publishing a SnapStart version takes,
+	// snapshot included.
+	PublishWait = 90 * time.Second
+	// fullCPUMB is the memory size at which a function gets a full vCPU.
+	fullCPUMB = 1769
+)
+
+// Observe derives a Per from a recorded result: the median client time
+// and mean billed duration of its warm invocations and the mean init
+// duration of its cold ones. Missing figures are taken from Default. It
+// reports false when r has no successful invocation.
+func Observe(r results.Result, source string) (Per, bool) {
+	var client, billed, init []float64
+	for _, s := range r.Samples {
+		switch {
+		case s.Error != "":
+		case s.Cold:
+			if s.InitMS > 0 {
+				init = append(init, s.InitMS)
+			}
+		case !s.Warmup:
+			client = append(client, s.ClientMS)
+			if s.BilledMS > 0 {
+				billed = append(billed, s.BilledMS)
+			}
+		}
+	}
+	if len(client) == 0 && len(init) == 0 {
+		return Per{}, false
+	}
+	p := Default
+	p.Source = source
+	if len(client) > 0 {
+		p.ClientMS = stats.Median(client)
+	}
+	if len(billed) > 0 {
+		p.BilledMS = stats.Mean(billed)
+	}
+	if len(init) > 0 {
+		p.InitMS = stats.Mean(init)
+	}
+	return p, true
+}
+
+// Scale adjusts p, observed at from MB, to a function configured with to
+// MB. Lambda allocates CPU in proportion to memory up to a full vCPU, so
+// single-threaded work scales inversely with memory up to that point and
+// not beyond it. Init is left alone: it is mostly loading, not compute.
+func (p Per) Scale(from, to int32) Per {
+	if from <= 0 || to <= 0 || from == to {
+		return p
+	}
+	f := float64(min(from, fullCPUMB)) / float64(min(to, fullCPUMB))
+	p.ClientMS *= f
+	p.BilledMS *= f
+	return p
+}
+
+// Item is one target in one region, as a command would benchmark it.
+type Item struct {
+	// Label identifies the target, and the memory size or input value for
+	// commands that vary one.
+	Label string
+	// Function is the Lambda function invoked, "" for local targets.
+	Function string
+	Region   string
+	Arch     string
+	MemoryMB int32
+	// Invocations counts every invocation, warm-up and cold starts
+	// included; ColdStarts how many of them are cold.
+	Invocations, ColdStarts int
+	// Updates counts the configuration updates waited on, Publishes the
+	// SnapStart versions published.
+	Updates, Publishes int
+	Per                Per
+}
+
+// Duration is how long the item takes to run.
+func (it Item) Duration() time.Duration {
+	ms := float64(it.Invocations)*it.Per.ClientMS + float64(it.ColdStarts)*it.Per.InitMS
+	return time.Duration(ms*float64(time.Millisecond)) +
+		time.Duration(it.Updates)*UpdateWait + time.Duration(it.Publishes)*PublishWait
+}
+
+// Cost prices the item's invocations, cold starts' init included, without
+// the free tier: a benchmark should not count on it being unused.
+// Local items cost nothing.
+func (it Item) Cost(p cost.Pricing) (float64, error) {
+	if it.Function == "" || it.Invocations == 0 {
+		return 0, nil
+	}
+	billed := it.Per.BilledMS + float64(it.ColdStarts)*it.Per.InitMS/float64(it.Invocations)
+	b, err := p.Estimate(cost.Usage{
+		Arch:        it.Arch,
+		MemoryMB:    it.MemoryMB,
+		BilledMS:    billed,
+		Invocations: float64(it.Invocations),
+	}, false)
+	return b.Total, err
+}
+
+// Duration is how long items take as the harness runs them with workers
+// functions at a time (-parallel): the regions of a label in parallel,
+// the labels of a function, such as a sweep's memory sizes, one after
+// another, and local labels alone before any function.
+func Duration(items []Item, workers int) time.Duration {
+	var (
+		longest = map[string]time.Duration{}
+		labels  []string
+	)
+	for _, it := range items {
+		if _, ok := longest[it.Label]; !ok {
+			labels = append(labels, it.Label)
+		}
+		longest[it.Label] = max(longest[it.Label], it.Duration())
+	}
+	function := map[string]string{}
+	for _, it := range items {
+		function[it.Label] = it.Function
+	}
+	var (
+		local     time.Duration
+		functions []string
+		each      = map[string]time.Duration{}
+	)
+	for _, l := range labels {
+		fn := function[l]
+		if fn == "" {
+			local += longest[l]
+			continue
+		}
+		if _, ok := each[fn]; !ok {
+			functions = append(functions, fn)
+		}
+		each[fn] += longest[l]
+	}
+	// Functions start in order, each on the worker that frees up first.
+	busy := make([]time.Duration, max(1, workers))
+	for _, fn := range functions {
+		i := slices.Index(busy, slices.Min(busy))
+		busy[i] += each[fn]
+	}
+	return local + slices.Max(busy)
+}
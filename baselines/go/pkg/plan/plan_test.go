@@ -0,0 +1,90 @@
+package plan
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/results"
+)
+
+func TestObserve(t *testing.T) {
+	r := results.Result{Samples: []results.Sample{
+		{ClientMS: 900, BilledMS: 300, InitMS: 250, Cold: true},
+		{ClientMS: 50, Warmup: true},
+		{ClientMS: 12, BilledMS: 3},
+		{ClientMS: 10, BilledMS: 2},
+		{ClientMS: 11, BilledMS: 4},
+		{ClientMS: 5000, Error: "timeout"},
+	}}
+	p, ok := Observe(r, "20261014T100000Z")
+	if !ok || p.ClientMS != 11 || p.BilledMS != 3 || p.InitMS != 250 || p.Source != "20261014T100000Z" {
+		t.Errorf("Observe = %+v, %v", p, ok)
+	}
+
+	// Cold starts alone give the init time; the rest is assumed.
+	if p, ok = Observe(results.Result{Samples: r.Samples[:1]}, "cold"); !ok || p.InitMS != 250 || p.ClientMS != Default.ClientMS {
+		t.Errorf("Observe(cold only) = %+v, %v", p, ok)
+	}
+	if _, ok = Observe(results.Result{Samples: r.Samples[5:]}, "failed"); ok {
+		t.Error("Observe of only failed samples reported ok")
+	}
+}
+
+func TestScale(t *testing.T) {
+	p := Per{ClientMS: 80, BilledMS: 40, InitMS: 100}
+	if got := p.Scale(128, 512); got.ClientMS != 20 || got.BilledMS != 10 || got.InitMS != 100 {
+		t.Errorf("Scale(128, 512) = %+v", got)
+	}
+	// Past a full vCPU there is nothing to gain.
+	if got := p.Scale(1769, 3008); got != p {
+		t.Errorf("Scale(1769, 3008) = %+v", got)
+	}
+}
+
+func TestItems(t *testing.T) {
+	per := Per{ClientMS: 10, BilledMS: 2, InitMS: 100}
+	items := []Item{
+		{Label: "go", Function: "baseline-go", Region: "us-east-1", MemoryMB: 128, Invocations: 100, ColdStarts: 1, Per: per},
+		{Label: "go", Function: "baseline-go", Region: "eu-west-1", MemoryMB: 128, Invocations: 100, ColdStarts: 10, Updates: 10, Per: per},
+		{Label: "local", Invocations: 100, Per: per},
+	}
+	if d := items[1].Duration(); d != 52*time.Second {
+		t.Errorf("Duration = %v, want 52s", d)
+	}
+	// The regions of a target run in parallel.
+	if d := Duration(items, 1); d != 53*time.Second {
+		t.Errorf("Duration(items, 1) = %v, want 53s", d)
+	}
+
+	usd, err := items[0].Cost(cost.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := 100*cost.Default.PerRequest + 100*(2+1.0)/1000*128/1024*cost.Default.Compute[cost.ArchX86][0].PerGBSec
+	if math.Abs(usd-want) > 1e-12 {
+		t.Errorf("Cost = %g, want %g", usd, want)
+	}
+	if usd, _ := items[2].Cost(cost.Default); usd != 0 {
+		t.Errorf("local Cost = %g", usd)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	per := Per{ClientMS: 1000}
+	items := []Item{
+		{Label: "a/128", Function: "a", Invocations: 2, Per: per},
+		{Label: "a/512", Function: "a", Invocations: 2, Per: per},
+		{Label: "b/128", Function: "b", Invocations: 2, Per: per},
+		{Label: "c/128", Function: "c", Invocations: 2, Per: per},
+		{Label: "local", Invocations: 1, Per: per},
+	}
+	// A function's sizes run one after another, functions side by side
+	// and local targets alone.
+	for workers, want := range map[int]time.Duration{1: 9 * time.Second, 2: 5 * time.Second, 8: 5 * time.Second} {
+		if d := Duration(items, workers); d != want {
+			t.Errorf("Duration(items, %d) = %v, want %v", workers, d, want)
+		}
+	}
+}
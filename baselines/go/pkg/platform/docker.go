@@ -0,0 +1,72 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/rie"
+)
+
+// Docker is a function's container image running locally under the
+// Runtime Interface Emulator; see package rie. It needs no AWS account,
+// and its numbers measure the host rather than Lambda.
+type Docker struct {
+	// Arch is the image's GOARCH-style architecture, amd64 or arm64.
+	Arch string
+	// Env is the container's environment, such as the
+	// AWS_LAMBDA_FUNCTION_NAME handlers read.
+	Env map[string]string
+
+	c *rie.Container
+}
+
+// Deploy starts a new container from the image of a, stopping the one a
+// previous Deploy started. The container's first invocation is its cold
+// start.
+func (d *Docker) Deploy(ctx context.Context, a build.Artifact) error {
+	if a.Image == "" {
+		return fmt.Errorf("%s has no container image to run", a.Target.ID())
+	}
+	if err := d.Destroy(ctx); err != nil {
+		return err
+	}
+	c, err := rie.Start(ctx, a.Image, d.Arch, d.Env)
+	if err != nil {
+		return err
+	}
+	d.c = c
+	return nil
+}
+
+// Invoke posts the payload to the emulator, reading the container's
+// output since the previous invocation into the log tail.
+func (d *Docker) Invoke(ctx context.Context, payload []byte) (invoke.Response, error) {
+	if d.c == nil {
+		return invoke.Response{}, errors.New("no container running")
+	}
+	inv := &invoke.RIE{URL: d.c.URL, Logs: d.c.Logs}
+	return inv.Invoke(ctx, payload)
+}
+
+// FetchMetrics reads the emulator's REPORT line from the log tail of
+// resp. The emulator bills its duration rounded up and reports the
+// memory it was configured with as used, so neither is kept.
+func (d *Docker) FetchMetrics(_ context.Context, resp invoke.Response) (reportparser.Report, bool, error) {
+	r, ok := reportparser.Last(resp.LogTail)
+	r.BilledDurationMS, r.MemorySizeMB, r.MaxMemoryUsedMB = 0, 0, 0
+	return r, ok, nil
+}
+
+// Destroy stops the container, which removes it.
+func (d *Docker) Destroy(ctx context.Context) error {
+	if d.c == nil {
+		return nil
+	}
+	err := d.c.Stop(ctx)
+	d.c = nil
+	return err
+}
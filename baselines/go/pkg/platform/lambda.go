@@ -0,0 +1,82 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/reportparser"
+)
+
+// Lambda is a function on AWS Lambda, deployed through package deploy.
+type Lambda struct {
+	// Client invokes the function.
+	Client invoke.LambdaAPI
+	// Deployer deploys and deletes it; only Deploy and Destroy need one.
+	Deployer *deploy.Deployer
+	Function string
+	Config   deploy.Config
+	// Qualifier is the version or alias invoked; empty invokes $LATEST.
+	Qualifier string
+	// Backoff retries invocations that fail transiently. The zero Backoff
+	// tries each once.
+	Backoff invoke.Backoff
+	// Registry receives the container image of an image artifact, and
+	// loses it again on Destroy.
+	Registry *deploy.Registry
+
+	// Deployed is what the last Deploy did, for reporting it.
+	Deployed deploy.Action
+	// Deleted is whether the last Destroy found anything to delete.
+	Deleted bool
+}
+
+// Deploy deploys the package of a, or pushes its image first.
+func (l *Lambda) Deploy(ctx context.Context, a build.Artifact) error {
+	pkg := a.Package
+	if a.Image != "" {
+		if l.Registry == nil {
+			return fmt.Errorf("no registry to push %s to", a.Image)
+		}
+		var err error
+		if pkg, err = l.Registry.Push(ctx, a.Image, l.Function); err != nil {
+			return err
+		}
+	}
+	action, err := l.Deployer.Deploy(ctx, l.Function, pkg, l.Config)
+	l.Deployed = action
+	return err
+}
+
+// Invoke invokes the function synchronously, tailing its logs and
+// retrying under Backoff. It also makes l an invoke.Invoker.
+func (l *Lambda) Invoke(ctx context.Context, payload []byte) (invoke.Response, error) {
+	inv := &invoke.Retry{
+		Invoker: &invoke.Lambda{Client: l.Client, FunctionName: l.Function, Qualifier: l.Qualifier},
+		Backoff: l.Backoff,
+	}
+	return inv.Invoke(ctx, payload)
+}
+
+// FetchMetrics reads the REPORT line from the log tail of resp.
+func (l *Lambda) FetchMetrics(_ context.Context, resp invoke.Response) (reportparser.Report, bool, error) {
+	r, ok := reportparser.Last(resp.LogTail)
+	return r, ok, nil
+}
+
+// Destroy deletes the function, and its image if it is an image function.
+// The image is tagged by function name; see build.ImageTag.
+func (l *Lambda) Destroy(ctx context.Context) error {
+	deleted, err := l.Deployer.Delete(ctx, l.Function)
+	if err == nil && l.Config.PackageType == types.PackageTypeImage && l.Registry != nil {
+		var imageDeleted bool
+		imageDeleted, err = l.Registry.Delete(ctx, l.Function)
+		deleted = deleted || imageDeleted
+	}
+	l.Deleted = deleted
+	return err
+}
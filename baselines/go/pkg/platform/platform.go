@@ -0,0 +1,63 @@
+// Package platform is what the harness needs of a FaaS platform to
+// measure a function on it: put a built artifact there, invoke it, read
+// what the platform reported of each invocation and remove it again.
+// Lambda is the first platform and a local Docker container under the
+// Runtime Interface Emulator the second. Another, such as Cloudflare
+// Workers or Google Cloud Functions, joins the comparison by implementing
+// Target, and Sample records its invocations as the harness records the
+// others'.
+package platform
+
+import (
+	"context"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/results"
+)
+
+// Target is one function on a platform. Deploy comes first and Destroy
+// last; Invoke and FetchMetrics may be called any number of times between
+// them, but not concurrently.
+type Target interface {
+	// Deploy creates the function from a, or updates it, and returns once
+	// it can be invoked.
+	Deploy(ctx context.Context, a build.Artifact) error
+	// Invoke performs one invocation.
+	Invoke(ctx context.Context, payload []byte) (invoke.Response, error)
+	// FetchMetrics returns the platform's own record of the invocation
+	// that gave resp, in the form of a Lambda REPORT line, and false if
+	// the platform reported nothing of it. Metrics the platform does not
+	// measure, such as a billed duration where nothing is billed, are
+	// zero.
+	FetchMetrics(ctx context.Context, resp invoke.Response) (reportparser.Report, bool, error)
+	// Destroy removes the function. A function already gone is not an
+	// error.
+	Destroy(ctx context.Context) error
+}
+
+// Sample performs invocation i of t and records it with the platform's
+// metrics of it, checking its response against expected.
+func Sample(ctx context.Context, t Target, payload []byte, i int, expected string) results.Sample {
+	resp, err := t.Invoke(ctx, payload)
+	s := results.Sample{
+		Iteration: i,
+		ClientMS:  results.Milliseconds(resp.Elapsed),
+		Retries:   resp.Retries,
+	}.WithResponse(resp.Payload)
+	if r, ok, ferr := t.FetchMetrics(ctx, resp); ferr != nil && err == nil {
+		err = ferr
+	} else if ok {
+		s = s.WithReport(r)
+	}
+	switch {
+	case err != nil:
+		s.Error, s.Excluded = err.Error(), string(invoke.Classify(err))
+	case resp.FunctionError != "":
+		s.Error = resp.FunctionError
+	default:
+		s = s.Verify(expected)
+	}
+	return s
+}
@@ -0,0 +1,160 @@
+package platform
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/build"
+	"lambdaperf/pkg/deploy"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/reportparser"
+)
+
+const coldLine = "REPORT RequestId: r1\tDuration: 1.50 ms\tBilled Duration: 2 ms\tMemory Size: 128 MB\tMax Memory Used: 20 MB\tInit Duration: 9.00 ms\t"
+
+// fakeTarget answers every invocation alike.
+type fakeTarget struct {
+	resp     invoke.Response
+	err      error
+	fetchErr error
+}
+
+func (f *fakeTarget) Deploy(context.Context, build.Artifact) error { return nil }
+func (f *fakeTarget) Destroy(context.Context) error                { return nil }
+
+func (f *fakeTarget) Invoke(context.Context, []byte) (invoke.Response, error) { return f.resp, f.err }
+
+func (f *fakeTarget) FetchMetrics(_ context.Context, resp invoke.Response) (reportparser.Report, bool, error) {
+	r, ok := reportparser.Last(resp.LogTail)
+	return r, ok, f.fetchErr
+}
+
+func TestSample(t *testing.T) {
+	ctx := context.Background()
+	f := &fakeTarget{resp: invoke.Response{Payload: []byte(`"fibonacci(35)=9227465"`), LogTail: coldLine, Elapsed: 12 * time.Millisecond}}
+	s := Sample(ctx, f, nil, 3, "fibonacci(35)=9227465")
+	if s.Error != "" || s.Iteration != 3 || s.ClientMS != 12 || s.DurationMS != 1.5 || s.InitMS != 9 || !s.Cold || s.RequestID != "r1" {
+		t.Errorf("sample = %+v", s)
+	}
+	if s := Sample(ctx, f, nil, 0, "fibonacci(35)=0"); !s.Wrong() {
+		t.Errorf("wrong result passed: %+v", s)
+	}
+	f.fetchErr = errors.New("logs gone")
+	if s := Sample(ctx, f, nil, 0, ""); s.Error != "logs gone" {
+		t.Errorf("metrics error = %q", s.Error)
+	}
+	f.fetchErr, f.resp.FunctionError = nil, "Unhandled"
+	if s := Sample(ctx, f, nil, 0, ""); s.Error != "Unhandled" || s.DurationMS != 1.5 {
+		t.Errorf("function error sample = %+v", s)
+	}
+}
+
+// lambdaAPI is what a Lambda target's deployer and invocations call.
+type lambdaAPI interface {
+	deploy.LambdaAPI
+	invoke.LambdaAPI
+}
+
+// fakeLambda holds one function. The embedded interface panics on calls
+// a Lambda target does not make.
+type fakeLambda struct {
+	lambdaAPI
+	created   *lambda.CreateFunctionInput
+	throttles int
+}
+
+func (f *fakeLambda) GetFunction(_ context.Context, _ *lambda.GetFunctionInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	if f.created == nil {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	return &lambda.GetFunctionOutput{Configuration: &types.FunctionConfiguration{State: types.StateActive, LastUpdateStatus: types.LastUpdateStatusSuccessful}}, nil
+}
+
+func (f *fakeLambda) CreateFunction(_ context.Context, in *lambda.CreateFunctionInput, _ ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error) {
+	f.created = in
+	return &lambda.CreateFunctionOutput{}, nil
+}
+
+func (f *fakeLambda) DeleteFunction(_ context.Context, _ *lambda.DeleteFunctionInput, _ ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error) {
+	if f.created == nil {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	f.created = nil
+	return &lambda.DeleteFunctionOutput{}, nil
+}
+
+func (f *fakeLambda) Invoke(_ context.Context, in *lambda.InvokeInput, _ ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	if f.throttles > 0 {
+		f.throttles--
+		return nil, &types.TooManyRequestsException{Message: aws.String("rate exceeded")}
+	}
+	return &lambda.InvokeOutput{Payload: in.Payload, LogResult: aws.String(base64.StdEncoding.EncodeToString([]byte(coldLine)))}, nil
+}
+
+func TestLambda(t *testing.T) {
+	ctx := context.Background()
+	pkg := filepath.Join(t.TempDir(), "function.zip")
+	if err := os.WriteFile(pkg, []byte("zip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tgt := discover.Target{Runtime: "go", Workload: "echo", Kind: discover.KindLambda, Arch: discover.ArchX86}
+	fake := &fakeLambda{}
+	l := &Lambda{
+		Client:   fake,
+		Deployer: &deploy.Deployer{Client: fake, RoleARN: "arn:aws:iam::123456789012:role/test"},
+		Function: tgt.FunctionName(),
+		Config:   deploy.ConfigFor(tgt),
+		Backoff:  invoke.Backoff{Attempts: 2},
+	}
+	var _ Target = l
+
+	if err := l.Deploy(ctx, build.Artifact{Target: tgt, Package: pkg}); err != nil {
+		t.Fatal(err)
+	}
+	if fake.created == nil || aws.ToString(fake.created.FunctionName) != "baseline-go-echo" || string(fake.created.Code.ZipFile) != "zip" || l.Deployed != deploy.Created {
+		t.Fatalf("created %+v, %s", fake.created, l.Deployed)
+	}
+	fake.throttles = 1
+	s := Sample(ctx, l, []byte(`"hi"`), 0, "hi")
+	if s.Error != "" || s.DurationMS != 1.5 || s.MemorySizeMB != 128 || !s.Cold || s.Retries != 1 {
+		t.Errorf("sample = %+v", s)
+	}
+	if err := l.Deploy(ctx, build.Artifact{Target: tgt, Image: "ruchy-bench:baseline-go-echo"}); err == nil {
+		t.Error("deployed an image without a registry")
+	}
+	if err := l.Destroy(ctx); err != nil || !l.Deleted || fake.created != nil {
+		t.Fatalf("destroy = %v, deleted %v", err, l.Deleted)
+	}
+	if err := l.Destroy(ctx); err != nil || l.Deleted {
+		t.Errorf("destroying it again = %v, deleted %v", err, l.Deleted)
+	}
+}
+
+func TestDocker(t *testing.T) {
+	ctx := context.Background()
+	d := &Docker{Arch: "amd64"}
+	var _ Target = d
+	if err := d.Deploy(ctx, build.Artifact{Target: discover.Target{Runtime: "go", Workload: "echo", Kind: discover.KindLambda}}); err == nil {
+		t.Error("deployed an artifact without an image")
+	}
+	if _, err := d.Invoke(ctx, nil); err == nil {
+		t.Error("invoked before deploying")
+	}
+	r, ok, err := d.FetchMetrics(ctx, invoke.Response{LogTail: "START RequestId: r1\n" + coldLine + "\n"})
+	if err != nil || !ok || r.DurationMS != 1.5 || r.InitDurationMS != 9 || r.BilledDurationMS != 0 || r.MemorySizeMB != 0 || r.MaxMemoryUsedMB != 0 {
+		t.Errorf("metrics = %+v, %v, %v", r, ok, err)
+	}
+	if err := d.Destroy(ctx); err != nil {
+		t.Errorf("destroying nothing: %v", err)
+	}
+}
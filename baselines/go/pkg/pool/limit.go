@@ -0,0 +1,87 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// DefaultRate is the rate of Lambda control-plane requests, per second,
+// commands keep to by default: below the quota of 15, which other
+// clients of the account share.
+const DefaultRate = 10
+
+// Limiter spaces out requests to a steady rate, allowing a burst of up to
+// one second's worth after a lull. It is safe for concurrent use; a nil
+// Limiter does not limit.
+type Limiter struct {
+	interval time.Duration
+	burst    time.Duration
+	mu       sync.Mutex
+	// next is when the next request may be made.
+	next time.Time
+	// now and sleep are replaced in tests.
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+}
+
+// NewLimiter returns a limiter allowing perSecond requests a second.
+func NewLimiter(perSecond float64) *Limiter {
+	interval := time.Duration(float64(time.Second) / perSecond)
+	return &Limiter{interval: interval, burst: max(time.Second, interval) - interval, now: time.Now, sleep: sleep}
+}
+
+// Wait blocks until a request may be made, or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := l.now()
+	at := now.Add(-l.burst)
+	if l.next.After(at) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+	if d := at.Sub(now); d > 0 {
+		return l.sleep(ctx, d)
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// invocations are the Lambda operations the control-plane quota leaves
+// out. They are limited by concurrency instead, and are what commands
+// measure.
+var invocations = map[string]bool{"Invoke": true, "InvokeWithResponseStream": true, "InvokeAsync": true}
+
+// ControlPlane adds a step to an AWS client's middleware stack that waits
+// for l before every Lambda request but invocations, each retry included;
+// requests to other services pass straight through. Add it to every
+// client of a region through aws.Config.APIOptions.
+func (l *Limiter) ControlPlane(stack *middleware.Stack) error {
+	return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("ControlPlaneRateLimit",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if awsmiddleware.GetServiceID(ctx) == lambda.ServiceID && !invocations[awsmiddleware.GetOperationName(ctx)] {
+				if err := l.Wait(ctx); err != nil {
+					return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+				}
+			}
+			return next.HandleFinalize(ctx, in)
+		}), middleware.After)
+}
@@ -0,0 +1,44 @@
+// Package pool runs the independent steps of a benchmark, such as
+// deploying one function or measuring one target, on a bounded number of
+// workers, and paces the Lambda API calls they make with a limit shared
+// by every worker. A matrix of runtimes, workloads and memory sizes is
+// hundreds of functions, each spending most of its time waiting for
+// Lambda to finish an update, so running them one after another takes
+// hours; running them all at once trips Lambda's control-plane quota of
+// 15 requests per second per region.
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultWorkers is the number of steps commands run at once by default.
+const DefaultWorkers = 4
+
+// Each calls fn for every i in [0, n) on at most workers goroutines,
+// returning once all calls have. Steps are started in order, and none is
+// started once ctx is done. fn sees a step's index, so it can write its
+// outcome to a slice without locking.
+func Each(ctx context.Context, n, workers int, fn func(i int)) {
+	workers = max(1, min(workers, n))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(next)
+	wg.Wait()
+}
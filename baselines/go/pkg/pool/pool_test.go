@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/smithy-go/middleware"
+)
+
+func TestEach(t *testing.T) {
+	var running, peak atomic.Int32
+	done := make([]bool, 10)
+	Each(context.Background(), len(done), 3, func(i int) {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		done[i] = true
+		running.Add(-1)
+	})
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("step %d did not run", i)
+		}
+	}
+	if p := peak.Load(); p > 3 {
+		t.Errorf("%d steps ran at once, want at most 3", p)
+	}
+}
+
+func TestEachStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var ran []int
+	Each(ctx, 10, 1, func(i int) {
+		mu.Lock()
+		ran = append(ran, i)
+		mu.Unlock()
+		if i == 2 {
+			cancel()
+		}
+	})
+	if len(ran) > 4 {
+		t.Errorf("ran steps %v after cancellation at 2", ran)
+	}
+}
+
+// fakeClock pins a limiter's time and records its waits.
+func fakeClock(l *Limiter) *[]time.Duration {
+	start := time.Unix(1e9, 0)
+	var waits []time.Duration
+	l.now = func() time.Time { return start }
+	l.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return &waits
+}
+
+func TestLimiter(t *testing.T) {
+	l := NewLimiter(4)
+	waits := fakeClock(l)
+	for range 6 {
+		l.Wait(context.Background())
+	}
+	// A burst of a second's worth goes through at once, then requests
+	// are spaced a quarter-second apart.
+	want := []time.Duration{250 * time.Millisecond, 500 * time.Millisecond}
+	if len(*waits) != len(want) || (*waits)[0] != want[0] || (*waits)[1] != want[1] {
+		t.Errorf("waits = %v, want %v", *waits, want)
+	}
+	if err := (*Limiter)(nil).Wait(context.Background()); err != nil {
+		t.Errorf("nil limiter: %v", err)
+	}
+}
+
+type okClient struct{}
+
+func (okClient) Do(*http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestControlPlane(t *testing.T) {
+	l := NewLimiter(1)
+	waits := fakeClock(l)
+	client := lambda.NewFromConfig(aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		HTTPClient:  okClient{},
+		APIOptions:  []func(*middleware.Stack) error{l.ControlPlane},
+	})
+	ctx := context.Background()
+	for range 3 {
+		if _, err := client.Invoke(ctx, &lambda.InvokeInput{FunctionName: aws.String("f")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*waits) != 0 {
+		t.Errorf("invocations waited %v", *waits)
+	}
+	for range 2 {
+		if _, err := client.GetFunction(ctx, &lambda.GetFunctionInput{FunctionName: aws.String("f")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*waits) != 1 {
+		t.Errorf("two control-plane requests at 1/s waited %v, want once", *waits)
+	}
+}
@@ -0,0 +1,120 @@
+// Package powertune picks the memory size a function should run at, the
+// way aws-lambda-power-tuning does: from a sweep of memory sizes, reduce
+// each size's durations to a trimmed mean, price it, and choose the size
+// that is cheapest, fastest or the best blend of the two. Every runtime
+// comparison at a single memory size invites the question of whether that
+// size is fair to each; the tuned size answers it per runtime.
+package powertune
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// Strategy is what the optimum minimizes.
+type Strategy string
+
+const (
+	// Cost minimizes the price of an invocation, then its duration.
+	Cost Strategy = "cost"
+	// Speed minimizes the duration of an invocation, then its price.
+	Speed Strategy = "speed"
+	// Balanced minimizes a weighted sum of price and duration, each
+	// relative to its largest value across the sizes.
+	Balanced Strategy = "balanced"
+)
+
+// Strategies lists every strategy.
+var Strategies = []Strategy{Cost, Speed, Balanced}
+
+// Parse returns the strategy named s.
+func Parse(s string) (Strategy, error) {
+	if st := Strategy(s); slices.Contains(Strategies, st) {
+		return st, nil
+	}
+	return "", fmt.Errorf("unknown strategy %q: want cost, speed or balanced", s)
+}
+
+const (
+	// DefaultWeight is the power tuner's balancedWeight: price and
+	// duration count equally. 1 is Cost, 0 is Speed.
+	DefaultWeight = 0.5
+	// DiscardTopBottom is the fraction of durations the power tuner
+	// leaves out at each end before averaging.
+	DiscardTopBottom = 0.2
+)
+
+// Point is one memory size's measurements, reduced as the power tuner
+// reduces them.
+type Point struct {
+	MemoryMB int32
+	// DurationMS is the mean REPORT duration with the fastest and slowest
+	// DiscardTopBottom of invocations left out.
+	DurationMS float64
+	// USD is the cost of a million invocations of DurationMS, billed by
+	// the millisecond.
+	USD float64
+}
+
+// AverageDuration is the trimmed mean of durations the power tuner
+// compares sizes on, or 0 without durations.
+func AverageDuration(durations []float64) float64 {
+	kept, _ := stats.TrimEnds(durations, DiscardTopBottom)
+	return stats.Mean(kept)
+}
+
+// Price returns the USD cost of a million invocations of u, u.Invocations
+// aside.
+type Price func(u cost.Usage) (float64, error)
+
+// FromResult reduces a sweep result to its Point, pricing it with price.
+// ok is false for a result without durations.
+func FromResult(r results.Result, price Price) (p Point, ok bool, err error) {
+	durations := r.Values(results.MetricDuration)
+	if len(durations) == 0 {
+		return Point{}, false, nil
+	}
+	p = Point{MemoryMB: r.Memory(), DurationMS: AverageDuration(durations)}
+	p.USD, err = price(cost.Usage{Arch: r.Arch, MemoryMB: p.MemoryMB, BilledMS: math.Ceil(p.DurationMS)})
+	return p, err == nil, err
+}
+
+// Optimum returns the index of the point s chooses, weight being the
+// share of price in Balanced's blend, or -1 without points.
+func Optimum(points []Point, s Strategy, weight float64) int {
+	if len(points) == 0 {
+		return -1
+	}
+	var maxUSD, maxMS float64
+	for _, p := range points {
+		maxUSD, maxMS = max(maxUSD, p.USD), max(maxMS, p.DurationMS)
+	}
+	score := func(p Point) []float64 {
+		switch s {
+		case Cost:
+			return []float64{p.USD, p.DurationMS}
+		case Speed:
+			return []float64{p.DurationMS, p.USD}
+		}
+		return []float64{weight*ratio(p.USD, maxUSD) + (1-weight)*ratio(p.DurationMS, maxMS)}
+	}
+	best := 0
+	for i := 1; i < len(points); i++ {
+		if slices.Compare(score(points[i]), score(points[best])) < 0 {
+			best = i
+		}
+	}
+	return best
+}
+
+func ratio(v, of float64) float64 {
+	if of == 0 {
+		return 0
+	}
+	return v / of
+}
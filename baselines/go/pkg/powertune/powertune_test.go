@@ -0,0 +1,71 @@
+package powertune
+
+import (
+	"testing"
+
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/results"
+)
+
+func TestAverageDuration(t *testing.T) {
+	// Ten durations: the fastest and slowest two are left out.
+	xs := []float64{1, 100, 9, 10, 11, 9, 10, 11, 2, 200}
+	if got := AverageDuration(xs); got != 10 {
+		t.Errorf("average = %v, want the mean of the middle six, 10", got)
+	}
+	if got := AverageDuration([]float64{3, 5}); got != 4 {
+		t.Errorf("average of two = %v, want their mean", got)
+	}
+}
+
+func TestFromResult(t *testing.T) {
+	r := results.Result{MemoryMB: 1024, Samples: []results.Sample{
+		{RequestID: "a", DurationMS: 9.25}, {RequestID: "b", DurationMS: 9.75}, {Error: "boom"},
+	}}
+	var billed float64
+	p, ok, err := FromResult(r, func(u cost.Usage) (float64, error) {
+		billed = u.BilledMS
+		return cost.Default.PerMillion(u, 1e6, false)
+	})
+	if err != nil || !ok || p.MemoryMB != 1024 || p.DurationMS != 9.5 || billed != 10 {
+		t.Errorf("point = %+v (ok %v, err %v), billed %v ms", p, ok, err, billed)
+	}
+	if _, ok, _ := FromResult(results.Result{}, nil); ok {
+		t.Error("point from a result without durations")
+	}
+}
+
+func TestOptimum(t *testing.T) {
+	// CPU-bound: doubling memory near halves the duration until 1024 MB.
+	points := []Point{
+		{MemoryMB: 128, DurationMS: 800, USD: 1.87},
+		{MemoryMB: 256, DurationMS: 400, USD: 1.87},
+		{MemoryMB: 512, DurationMS: 210, USD: 1.95},
+		{MemoryMB: 1024, DurationMS: 120, USD: 2.20},
+		{MemoryMB: 2048, DurationMS: 110, USD: 3.87},
+	}
+	for _, tc := range []struct {
+		s      Strategy
+		weight float64
+		want   int32
+	}{
+		// 128 and 256 MB cost the same; the faster wins the tie.
+		{Cost, 0, 256},
+		{Speed, 0, 2048},
+		{Balanced, DefaultWeight, 1024},
+		// A blend that ties keeps the smaller size, as the power tuner's
+		// stable sort does.
+		{Balanced, 1, 128},
+		{Balanced, 0, 2048},
+	} {
+		if i := Optimum(points, tc.s, tc.weight); points[i].MemoryMB != tc.want {
+			t.Errorf("%s (weight %v) chose %d MB, want %d", tc.s, tc.weight, points[i].MemoryMB, tc.want)
+		}
+	}
+	if Optimum(nil, Cost, 0) != -1 {
+		t.Error("optimum without points")
+	}
+	if _, err := Parse("cheapest"); err == nil {
+		t.Error("unknown strategy parsed")
+	}
+}
@@ -0,0 +1,125 @@
+// Package profiles carries pprof profiles from the Go baselines to the
+// harness. Handlers built on internal/handler with the pprof build tag
+// (ruchy-bench deploy -pprof) profile every invocation while Env names a
+// bucket: they write a CPU profile of the invocation and a heap profile
+// at its end to /tmp and upload both under Key. Fetch downloads them
+// again. go tool pprof merges the profiles of one kind given together, so
+// a run of many short invocations adds up to one profile with enough
+// samples to read.
+package profiles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Env is the environment variable naming the bucket profiles are
+// uploaded to.
+const Env = "BENCH_PPROF_BUCKET"
+
+// Prefix is the key prefix of every profile.
+const Prefix = "pprof/"
+
+// The kinds of profile taken per invocation, as pprof names them.
+const (
+	CPU  = "cpu"
+	Heap = "heap"
+)
+
+// Kinds lists every kind.
+var Kinds = []string{CPU, Heap}
+
+// Key is where the kind profile of an invocation of function is stored.
+func Key(function, requestID, kind string) string {
+	return functionPrefix(function) + requestID + "/" + kind + ".pprof"
+}
+
+func functionPrefix(function string) string {
+	return Prefix + function + "/"
+}
+
+// API is the part of the S3 client Fetch uses.
+type API interface {
+	s3.ListObjectsV2APIClient
+	GetObject(ctx context.Context, in *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// Fetch downloads the kind profiles of function's invocations from bucket
+// into dir, at their keys' paths, and returns their paths, oldest first.
+// Profiles already downloaded are not fetched again. A positive last
+// keeps only the newest last.
+func Fetch(ctx context.Context, client API, bucket, function, kind, dir string, last int) ([]string, error) {
+	type object struct {
+		key      string
+		modified time.Time
+	}
+	var objects []object
+	p := s3.NewListObjectsV2Paginator(client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(functionPrefix(function)),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list s3://%s/%s: %w", bucket, functionPrefix(function), err)
+		}
+		for _, o := range page.Contents {
+			if key := aws.ToString(o.Key); strings.HasSuffix(key, "/"+kind+".pprof") {
+				objects = append(objects, object{key, aws.ToTime(o.LastModified)})
+			}
+		}
+	}
+	slices.SortStableFunc(objects, func(a, b object) int { return a.modified.Compare(b.modified) })
+	if last > 0 && len(objects) > last {
+		objects = objects[len(objects)-last:]
+	}
+	paths := make([]string, len(objects))
+	for i, o := range objects {
+		paths[i] = filepath.Join(dir, filepath.FromSlash(o.key))
+		if _, err := os.Stat(paths[i]); err == nil {
+			continue
+		}
+		if err := download(ctx, client, bucket, o.key, paths[i]); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// download writes the object at key to path, through a temporary file so
+// that an interrupted download is not mistaken for a complete one.
+func download(ctx context.Context, client API, bucket, key, path string) error {
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return fmt.Errorf("get s3://%s/%s: %w", bucket, key, err)
+	}
+	defer out.Body.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".download-")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, out.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("get s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package profiles
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type object struct {
+	body     string
+	modified time.Time
+}
+
+// fakeS3 serves one bucket's objects, listing them one per page.
+type fakeS3 struct {
+	objects map[string]object
+	gets    int
+}
+
+func (f *fakeS3) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, aws.ToString(in.Prefix)) && key > aws.ToString(in.ContinuationToken) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return &s3.ListObjectsV2Output{}, nil
+	}
+	key := keys[0]
+	for _, k := range keys {
+		key = min(key, k)
+	}
+	out := &s3.ListObjectsV2Output{Contents: []types.Object{{Key: aws.String(key), LastModified: aws.Time(f.objects[key].modified)}}}
+	if len(keys) > 1 {
+		out.IsTruncated, out.NextContinuationToken = aws.Bool(true), aws.String(key)
+	}
+	return out, nil
+}
+
+func (f *fakeS3) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.gets++
+	return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(f.objects[aws.ToString(in.Key)].body))}, nil
+}
+
+func TestFetch(t *testing.T) {
+	t0 := time.Date(2025, 11, 2, 10, 0, 0, 0, time.UTC)
+	f := &fakeS3{objects: map[string]object{
+		// Request IDs do not sort by time.
+		Key("go-fibonacci", "b", CPU):  {"cpu b", t0},
+		Key("go-fibonacci", "a", CPU):  {"cpu a", t0.Add(time.Second)},
+		Key("go-fibonacci", "c", CPU):  {"cpu c", t0.Add(2 * time.Second)},
+		Key("go-fibonacci", "a", Heap): {"heap a", t0},
+		Key("go-sieve", "d", CPU):      {"cpu d", t0},
+	}}
+	dir := t.TempDir()
+	ctx := context.Background()
+	paths, err := Fetch(ctx, f, "bench", "go-fibonacci", CPU, dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(data))
+	}
+	if want := []string{"cpu a", "cpu c"}; !slices.Equal(got, want) {
+		t.Errorf("fetched %q, want %q", got, want)
+	}
+	if want := filepath.Join(dir, "pprof", "go-fibonacci", "c", "cpu.pprof"); paths[1] != want {
+		t.Errorf("path %s, want %s", paths[1], want)
+	}
+
+	// Downloaded profiles are not fetched again.
+	if _, err := Fetch(ctx, f, "bench", "go-fibonacci", CPU, dir, 0); err != nil {
+		t.Fatal(err)
+	}
+	if f.gets != 3 {
+		t.Errorf("%d downloads, want 3", f.gets)
+	}
+}
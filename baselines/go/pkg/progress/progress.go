@@ -0,0 +1,402 @@
+// Package progress reports how a long benchmark is getting on while it
+// runs. A matrix can take hours and prints none of its measurements
+// until the end; a Board follows each target being measured, with its
+// samples so far, rolling p50 and p99, errors and expected finish, and
+// either redraws them at the foot of a terminal or, for CI logs, logs
+// them as plain lines every so often.
+//
+// A Board travels in a context, as a budget does: results.Collect
+// records every sample into the Task its context carries, so a command
+// only names what it measures with Track.
+package progress
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"lambdaperf/pkg/stats"
+)
+
+// Window is how many of a task's latest samples its rolling quantiles
+// are taken over.
+const Window = 200
+
+// PlainEvery is how often a plain Board logs the tasks still running.
+// Each task also logs once as it finishes.
+const PlainEvery = 30 * time.Second
+
+// redrawEvery is how often a live Board redraws.
+const redrawEvery = 250 * time.Millisecond
+
+// Board shows the progress of a command's tasks. It is safe for
+// concurrent use, and its Write method takes the command's own output
+// so a live Board can print it above the tasks rather than through
+// them. A nil Board shows nothing.
+type Board struct {
+	out   io.Writer
+	width int
+	now   func() time.Time
+
+	mu      sync.Mutex
+	started time.Time
+	tasks   []*Task
+	// step and steps count a matrix's steps, 1-based; stepsTook is the
+	// time the finished ones took.
+	step, steps int
+	stepName    string
+	stepStarted time.Time
+	stepsTook   time.Duration
+	partial     []byte // output not yet ended by a newline
+	drawn       int    // lines of the live block on screen
+	logged      time.Time
+	stop, done  chan struct{}
+}
+
+// New returns a Board writing to out. A positive width makes it live,
+// redrawn at the foot of out, a terminal that many columns wide; at zero
+// it logs plain lines instead.
+func New(out io.Writer, width int) *Board {
+	return &Board{out: out, width: width, now: time.Now}
+}
+
+// Live reports whether b redraws in place.
+func (b *Board) Live() bool {
+	return b != nil && b.width > 0
+}
+
+type contextKey struct{}
+
+// NewContext returns a child of ctx carrying b.
+func NewContext(ctx context.Context, b *Board) context.Context {
+	return context.WithValue(ctx, contextKey{}, b)
+}
+
+// From returns the Board ctx carries, or nil.
+func From(ctx context.Context) *Board {
+	b, _ := ctx.Value(contextKey{}).(*Board)
+	return b
+}
+
+// Start begins redrawing or logging, until Stop.
+func (b *Board) Start() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.started, b.logged = b.now(), b.now()
+	b.stop, b.done = make(chan struct{}), make(chan struct{})
+	go b.loop(b.stop, b.done)
+}
+
+// Stop stops b, and leaves the final state of a live Board's tasks on
+// the screen.
+func (b *Board) Stop() {
+	if b == nil || b.stop == nil {
+		return
+	}
+	close(b.stop)
+	<-b.done
+	if !b.Live() {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.partial) > 0 {
+		b.partial = append(b.partial, '\n')
+	}
+	b.flush()
+}
+
+func (b *Board) loop(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	every := redrawEvery
+	if !b.Live() {
+		every = time.Second
+	}
+	tick := time.NewTicker(every)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+			b.mu.Lock()
+			if b.Live() {
+				b.flush()
+			} else if b.now().Sub(b.logged) >= PlainEvery {
+				b.logged = b.now()
+				b.logRunning()
+			}
+			b.mu.Unlock()
+		}
+	}
+}
+
+// Write prints p, a command's output, above a live Board's tasks; a
+// plain Board passes it straight through.
+func (b *Board) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.Live() {
+		return b.out.Write(p)
+	}
+	b.partial = append(b.partial, p...)
+	if bytes.IndexByte(p, '\n') >= 0 {
+		b.flush()
+	}
+	return len(p), nil
+}
+
+// Step marks the start of step i of n of a matrix, named name, so the
+// Board can tell how long the rest should take.
+func (b *Board) Step(i, n int, name string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if b.step > 0 {
+		b.stepsTook += now.Sub(b.stepStarted)
+	}
+	b.step, b.steps, b.stepName, b.stepStarted = i, n, name, now
+	if !b.Live() {
+		eta := ""
+		if left, ok := b.remaining(now); ok {
+			eta = ", about " + formatDuration(left) + " left"
+		}
+		fmt.Fprintf(b.out, "progress: step %d/%d %s, %s elapsed%s\n", i, n, name, formatDuration(now.Sub(b.started)), eta)
+	}
+}
+
+// remaining estimates the time a matrix has left from its finished
+// steps' average.
+func (b *Board) remaining(now time.Time) (time.Duration, bool) {
+	finished := b.step - 1
+	if finished < 1 {
+		return 0, false
+	}
+	per := b.stepsTook / time.Duration(finished)
+	left := per*time.Duration(b.steps-finished) - now.Sub(b.stepStarted)
+	return max(left, 0), true
+}
+
+// flush prints pending output, then redraws the live block.
+func (b *Board) flush() {
+	var buf bytes.Buffer
+	if b.drawn > 0 {
+		// Back to the block's first line, and clear from there down.
+		fmt.Fprintf(&buf, "\x1b[%dF\x1b[J", b.drawn)
+	}
+	if i := bytes.LastIndexByte(b.partial, '\n'); i >= 0 {
+		buf.Write(b.partial[:i+1])
+		b.partial = slices.Delete(b.partial, 0, i+1)
+	}
+	lines := b.block()
+	for _, l := range lines {
+		buf.WriteString(truncate(l, b.width-1))
+		buf.WriteByte('\n')
+	}
+	b.drawn = len(lines)
+	b.out.Write(buf.Bytes())
+}
+
+// block is the live view: a heading, then the tasks still running.
+func (b *Board) block() []string {
+	now := b.now()
+	head := "elapsed " + formatDuration(now.Sub(b.started))
+	if b.steps > 0 {
+		head = fmt.Sprintf("step %d/%d %s, %s", b.step, b.steps, b.stepName, head)
+		if left, ok := b.remaining(now); ok {
+			head += ", about " + formatDuration(left) + " left"
+		}
+	}
+	finished := 0
+	var lines []string
+	for _, t := range b.tasks {
+		if !t.finished.IsZero() {
+			finished++
+			continue
+		}
+		lines = append(lines, "  "+t.line(now, true))
+	}
+	if finished > 0 {
+		head += fmt.Sprintf(", %d finished", finished)
+	}
+	return append([]string{head}, lines...)
+}
+
+// logRunning logs a line for each running task.
+func (b *Board) logRunning() {
+	now := b.now()
+	for _, t := range b.tasks {
+		if t.finished.IsZero() {
+			fmt.Fprintf(b.out, "progress: %s\n", t.line(now, false))
+		}
+	}
+}
+
+// Task is one target being measured. A nil Task records nothing, so
+// code measuring outside a Board need not check.
+type Task struct {
+	b    *Board
+	name string
+	n    int
+
+	started, recording, finished time.Time
+	warm, recorded, errors       int
+	// recent holds the latest Window successful values, next the slot
+	// the next one goes in.
+	recent []float64
+	next   int
+}
+
+// Track adds a task named name to the Board ctx carries, expecting n
+// recorded samples after warm-up, and returns a child of ctx carrying
+// the task for results.Collect to record into. Without a Board the task
+// is nil and ctx is returned unchanged.
+func Track(ctx context.Context, name string, n int) (context.Context, *Task) {
+	b := From(ctx)
+	if b == nil {
+		return ctx, nil
+	}
+	t := &Task{b: b, name: name, n: n}
+	b.mu.Lock()
+	t.started = b.now()
+	b.tasks = append(b.tasks, t)
+	b.mu.Unlock()
+	return context.WithValue(ctx, taskKey{}, t), t
+}
+
+type taskKey struct{}
+
+// TaskFrom returns the Task ctx carries, or nil.
+func TaskFrom(ctx context.Context) *Task {
+	t, _ := ctx.Value(taskKey{}).(*Task)
+	return t
+}
+
+// Record counts a sample measuring ms milliseconds, or one that failed;
+// warm-up samples count apart and stay out of the quantiles.
+func (t *Task) Record(ms float64, failed, warmup bool) {
+	if t == nil {
+		return
+	}
+	t.b.mu.Lock()
+	defer t.b.mu.Unlock()
+	if failed {
+		t.errors++
+	}
+	if warmup {
+		t.warm++
+		return
+	}
+	if t.recorded == 0 {
+		t.recording = t.b.now()
+	}
+	t.recorded++
+	if failed {
+		return
+	}
+	if len(t.recent) < Window {
+		t.recent = append(t.recent, ms)
+		return
+	}
+	t.recent[t.next] = ms
+	t.next = (t.next + 1) % Window
+}
+
+// Finish marks t done; a plain Board logs its final line.
+func (t *Task) Finish() {
+	if t == nil {
+		return
+	}
+	t.b.mu.Lock()
+	defer t.b.mu.Unlock()
+	t.finished = t.b.now()
+	if !t.b.Live() {
+		fmt.Fprintf(t.b.out, "progress: %s\n", t.line(t.finished, false))
+	}
+}
+
+// Quantiles returns the p50 and p99 of t's latest Window samples.
+func (t *Task) Quantiles() (p50, p99 float64) {
+	t.b.mu.Lock()
+	defer t.b.mu.Unlock()
+	return t.quantiles()
+}
+
+func (t *Task) quantiles() (p50, p99 float64) {
+	sorted := slices.Sorted(slices.Values(t.recent))
+	return stats.Percentile(sorted, 50), stats.Percentile(sorted, 99)
+}
+
+// line describes t: on a live Board with a bar of the samples recorded
+// so far.
+func (t *Task) line(now time.Time, bar bool) string {
+	var sb strings.Builder
+	sb.WriteString(t.name)
+	switch {
+	case t.recorded == 0 && t.finished.IsZero():
+		fmt.Fprintf(&sb, " warming up, %d runs", t.warm)
+	case bar:
+		fmt.Fprintf(&sb, " %s %d/%d", progressBar(t.recorded, t.n, 20), t.recorded, t.n)
+	default:
+		fmt.Fprintf(&sb, " %d/%d", t.recorded, t.n)
+	}
+	if len(t.recent) > 0 {
+		p50, p99 := t.quantiles()
+		fmt.Fprintf(&sb, ", p50 %.2f ms, p99 %.2f ms", p50, p99)
+	}
+	fmt.Fprintf(&sb, ", %d errors", t.errors)
+	switch {
+	case !t.finished.IsZero():
+		fmt.Fprintf(&sb, ", done in %s", formatDuration(t.finished.Sub(t.started)))
+	case t.recorded >= t.n:
+		// Past n, Collect extends recording until the median is precise.
+		sb.WriteString(", extending for precision")
+	case t.recorded > 0:
+		took := now.Sub(t.recording)
+		left := took / time.Duration(t.recorded) * time.Duration(t.n-t.recorded)
+		fmt.Fprintf(&sb, ", about %s left", formatDuration(left))
+	}
+	return sb.String()
+}
+
+// progressBar draws done of total as a bar width cells wide.
+func progressBar(done, total, width int) string {
+	filled := width
+	if total > 0 && done < total {
+		filled = width * done / total
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
+
+// truncate cuts s to width characters, so a redrawn line never wraps.
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	if r := []rune(s); len(r) > width {
+		return string(r[:width])
+	}
+	return s
+}
+
+// formatDuration rounds d to what a progress line needs: seconds under
+// an hour, minutes over.
+func formatDuration(d time.Duration) string {
+	if d >= time.Hour {
+		d = d.Round(time.Minute)
+	} else {
+		d = d.Round(time.Second)
+	}
+	return d.String()
+}
@@ -0,0 +1,126 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns a Board's clock, and a function moving it on.
+func fakeClock(b *Board) func(time.Duration) {
+	now := time.Date(2025, 11, 2, 10, 0, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+	return func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestPlain(t *testing.T) {
+	var out bytes.Buffer
+	b := New(&out, 0)
+	advance := fakeClock(b)
+	b.started = b.now()
+
+	ctx, task := Track(NewContext(context.Background(), b), "go/fibonacci", 4)
+	if TaskFrom(ctx) != task {
+		t.Fatal("context does not carry the task")
+	}
+	task.Record(9, false, true)
+	for _, ms := range []float64{10, 20, 30} {
+		advance(time.Second)
+		task.Record(ms, false, false)
+	}
+	advance(time.Second)
+	task.Record(0, true, false)
+	if line := task.line(b.now(), false); line != "go/fibonacci 4/4, p50 20.00 ms, p99 29.80 ms, 1 errors, extending for precision" {
+		t.Errorf("running line %q", line)
+	}
+	task.Finish()
+	if got, want := out.String(), "progress: go/fibonacci 4/4, p50 20.00 ms, p99 29.80 ms, 1 errors, done in 4s\n"; got != want {
+		t.Errorf("finish logged %q, want %q", got, want)
+	}
+
+	out.Reset()
+	_, other := Track(ctx, "ruchy/fibonacci", 10)
+	other.Record(1, false, false)
+	advance(2 * time.Second)
+	other.Record(1, false, false)
+	b.logRunning()
+	if got := out.String(); got != "progress: ruchy/fibonacci 2/10, p50 1.00 ms, p99 1.00 ms, 0 errors, about 8s left\n" {
+		t.Errorf("running tasks logged %q", got)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	b := New(&bytes.Buffer{}, 0)
+	_, task := Track(NewContext(context.Background(), b), "x", 1000)
+	for i := range 300 {
+		task.Record(float64(i), false, false)
+	}
+	// Only the latest Window values, 100 to 299, count.
+	if p50, p99 := task.Quantiles(); p50 != 199.5 || p99 < 297 || p99 > 298 {
+		t.Errorf("quantiles %v, %v", p50, p99)
+	}
+}
+
+func TestWithoutBoard(t *testing.T) {
+	ctx, task := Track(context.Background(), "x", 10)
+	if task != nil || TaskFrom(ctx) != nil {
+		t.Fatal("task without a board")
+	}
+	// A nil Task and Board do nothing.
+	task.Record(1, false, false)
+	task.Finish()
+	var b *Board
+	b.Step(1, 2, "x")
+	b.Start()
+	b.Stop()
+}
+
+func TestLive(t *testing.T) {
+	var out bytes.Buffer
+	b := New(&out, 60)
+	advance := fakeClock(b)
+	b.started = b.now()
+	ctx := NewContext(context.Background(), b)
+
+	b.Step(1, 3, "cpu")
+	_, task := Track(ctx, "go/fibonacci", 100)
+	task.Record(5, false, true)
+	b.Write([]byte("go/fibonacci: 100 "))
+	if out.Len() != 0 {
+		t.Fatalf("drew %q before a full line", out.String())
+	}
+	b.Write([]byte("invocations\n"))
+	if got, want := out.String(), "go/fibonacci: 100 invocations\nstep 1/3 cpu, elapsed 0s\n  go/fibonacci warming up, 1 runs, 0 errors\n"; got != want {
+		t.Errorf("first draw %q, want %q", got, want)
+	}
+
+	out.Reset()
+	advance(time.Minute)
+	b.Step(2, 3, "memory")
+	for range 50 {
+		task.Record(12, false, false)
+	}
+	b.flush()
+	got := out.String()
+	if !strings.HasPrefix(got, "\x1b[2F\x1b[J") {
+		t.Errorf("redraw %q does not replace the old block", got)
+	}
+	if !strings.Contains(got, "step 2/3 memory, elapsed 1m0s, about 2m0s left\n") {
+		t.Errorf("redraw %q lacks the matrix estimate", got)
+	}
+	// Lines are cut to the terminal's width, less one column.
+	for _, l := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if l = strings.TrimPrefix(l, "\x1b[2F\x1b[J"); len(l) > 59 {
+			t.Errorf("line %q is wider than the terminal", l)
+		}
+	}
+
+	out.Reset()
+	task.Finish()
+	b.flush()
+	if got := out.String(); !strings.HasSuffix(got, "step 2/3 memory, elapsed 1m0s, about 2m0s left, 1 finished\n") {
+		t.Errorf("finished task drawn as %q", got)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package progress
+
+import "os"
+
+// Width is 0 off Unix: a Board there always logs plain lines.
+func Width(*os.File) int { return 0 }
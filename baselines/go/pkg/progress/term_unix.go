@@ -0,0 +1,23 @@
+//go:build unix
+
+package progress
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Width returns the columns of the terminal f is, or 0 when it is not a
+// terminal.
+func Width(f *os.File) int {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0
+	}
+	if ws.Col == 0 {
+		// A terminal that does not know its size, as under some CI runners.
+		return 80
+	}
+	return int(ws.Col)
+}
@@ -0,0 +1,211 @@
+// Package provisioned benchmarks a function with provisioned concurrency:
+// it allocates pre-initialized execution environments on an alias, waits
+// until they are ready, and fires bursts of concurrent invocations.
+// Invocations served by a provisioned environment report no init
+// duration; those that spill over onto on-demand environments cold start,
+// so bursts wider than the allocation show how much of the gap between
+// runtimes provisioned concurrency actually closes.
+package provisioned
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/budget"
+	"lambdaperf/pkg/canary"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/results"
+)
+
+// Alias is the alias provisioned concurrency is allocated on. Provisioned
+// concurrency needs a published version, never $LATEST.
+const Alias = "provisioned"
+
+// LambdaAPI is the subset of the Lambda client used to manage provisioned
+// concurrency and invoke the alias.
+type LambdaAPI interface {
+	invoke.LambdaAPI
+	GetFunctionConfiguration(ctx context.Context, in *lambda.GetFunctionConfigurationInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error)
+	PutProvisionedConcurrencyConfig(ctx context.Context, in *lambda.PutProvisionedConcurrencyConfigInput, opts ...func(*lambda.Options)) (*lambda.PutProvisionedConcurrencyConfigOutput, error)
+	GetProvisionedConcurrencyConfig(ctx context.Context, in *lambda.GetProvisionedConcurrencyConfigInput, opts ...func(*lambda.Options)) (*lambda.GetProvisionedConcurrencyConfigOutput, error)
+	DeleteProvisionedConcurrencyConfig(ctx context.Context, in *lambda.DeleteProvisionedConcurrencyConfigInput, opts ...func(*lambda.Options)) (*lambda.DeleteProvisionedConcurrencyConfigOutput, error)
+}
+
+// Runner benchmarks one function's Alias.
+type Runner struct {
+	Client       LambdaAPI
+	FunctionName string
+	// Concurrency is the number of provisioned environments.
+	Concurrency int32
+	// Burst is the number of simultaneous invocations per round; more
+	// than Concurrency forces spillover.
+	Burst int
+	// Rounds of Burst invocations.
+	Rounds  int
+	Payload []byte
+	// Expected is the result every response must report; invocations
+	// that return anything else fail. Empty accepts any response.
+	Expected string
+	// Runtime, if set, is the runtime the function was deployed from:
+	// once the environments are ready, a canary.Check of the alias
+	// against it and Expected must pass before the first round.
+	Runtime string
+	// ReadyTimeout bounds the wait for allocation. Zero means fifteen
+	// minutes, which large allocations can need.
+	ReadyTimeout time.Duration
+	// PollInterval between readiness checks. Zero means five seconds.
+	PollInterval time.Duration
+}
+
+// Run allocates provisioned concurrency, runs every round and releases the
+// allocation afterwards, even on failure: idle provisioned concurrency is
+// billed by the hour. The allocation is charged to the budget ctx
+// carries, if any, for as long as it is held.
+func (r *Runner) Run(ctx context.Context) (samples []results.Sample, err error) {
+	if r.Concurrency < 1 || r.Burst < 1 || r.Rounds < 1 {
+		return nil, errors.New("concurrency, burst and rounds must be positive")
+	}
+	var memoryMB int32
+	var arch string
+	b := budget.From(ctx)
+	if b != nil {
+		cfg, err := r.Client.GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{FunctionName: aws.String(r.FunctionName)})
+		if err != nil {
+			return nil, fmt.Errorf("get configuration of %s: %w", r.FunctionName, err)
+		}
+		memoryMB = aws.ToInt32(cfg.MemorySize)
+		if len(cfg.Architectures) > 0 {
+			arch = string(cfg.Architectures[0])
+		}
+	}
+	if _, err := r.Client.PutProvisionedConcurrencyConfig(ctx, &lambda.PutProvisionedConcurrencyConfigInput{
+		FunctionName:                    aws.String(r.FunctionName),
+		Qualifier:                       aws.String(Alias),
+		ProvisionedConcurrentExecutions: aws.Int32(r.Concurrency),
+	}); err != nil {
+		return nil, fmt.Errorf("provision %d environments on %s:%s: %w", r.Concurrency, r.FunctionName, Alias, err)
+	}
+	held := b.Allocate(budget.Qualified(r.FunctionName, Alias), arch, memoryMB, r.Concurrency)
+	defer func() {
+		if derr := r.release(context.WithoutCancel(ctx)); derr != nil && err == nil {
+			err = derr
+		}
+		held.Release()
+	}()
+	if err := r.waitReady(ctx); err != nil {
+		return nil, err
+	}
+
+	inv := &invoke.Lambda{Client: r.Client, FunctionName: r.FunctionName, Qualifier: Alias}
+	if r.Runtime != "" {
+		if _, err := canary.Check(ctx, inv, r.Payload, r.Runtime, r.Expected); err != nil {
+			return nil, fmt.Errorf("%s:%s: %w", r.FunctionName, Alias, err)
+		}
+	}
+	for round := 0; round < r.Rounds && ctx.Err() == nil; round++ {
+		samples = append(samples, r.burst(ctx, inv, round*r.Burst)...)
+		held.Update()
+	}
+	return samples, ctx.Err()
+}
+
+// burst starts Burst invocations together and returns their samples in
+// iteration order.
+func (r *Runner) burst(ctx context.Context, inv invoke.Invoker, first int) []results.Sample {
+	out := make([]results.Sample, r.Burst)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range out {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			resp, err := inv.Invoke(ctx, r.Payload)
+			s := results.Sample{
+				Iteration: first + i,
+				ClientMS:  results.Milliseconds(resp.Elapsed),
+			}.WithResponse(resp.Payload)
+			if rep, ok := reportparser.Last(resp.LogTail); ok {
+				s = s.WithReport(rep)
+			}
+			switch {
+			case err != nil:
+				s.Error = err.Error()
+			case resp.FunctionError != "":
+				s.Error = resp.FunctionError
+			default:
+				s = s.Verify(r.Expected)
+			}
+			out[i] = s
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	return out
+}
+
+func (r *Runner) waitReady(ctx context.Context) error {
+	timeout, poll := r.ReadyTimeout, r.PollInterval
+	if timeout == 0 {
+		timeout = 15 * time.Minute
+	}
+	if poll == 0 {
+		poll = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		out, err := r.Client.GetProvisionedConcurrencyConfig(ctx, &lambda.GetProvisionedConcurrencyConfigInput{
+			FunctionName: aws.String(r.FunctionName),
+			Qualifier:    aws.String(Alias),
+		})
+		if err != nil {
+			return fmt.Errorf("get provisioned concurrency of %s:%s: %w", r.FunctionName, Alias, err)
+		}
+		switch out.Status {
+		case types.ProvisionedConcurrencyStatusEnumReady:
+			return nil
+		case types.ProvisionedConcurrencyStatusEnumFailed:
+			return fmt.Errorf("provisioned concurrency on %s:%s failed: %s", r.FunctionName, Alias, aws.ToString(out.StatusReason))
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for provisioned concurrency on %s:%s: %w", r.FunctionName, Alias, ctx.Err())
+		case <-time.After(poll):
+		}
+	}
+}
+
+func (r *Runner) release(ctx context.Context) error {
+	_, err := r.Client.DeleteProvisionedConcurrencyConfig(ctx, &lambda.DeleteProvisionedConcurrencyConfigInput{
+		FunctionName: aws.String(r.FunctionName),
+		Qualifier:    aws.String(Alias),
+	})
+	if err != nil {
+		return fmt.Errorf("release provisioned concurrency on %s:%s: %w", r.FunctionName, Alias, err)
+	}
+	return nil
+}
+
+// Spillover counts the successful samples that cold started, i.e. were
+// not served by a provisioned environment.
+func Spillover(samples []results.Sample) (spilled, total int) {
+	for _, s := range samples {
+		if s.Error != "" || s.RequestID == "" {
+			continue
+		}
+		total++
+		if s.Cold {
+			spilled++
+		}
+	}
+	return spilled, total
+}
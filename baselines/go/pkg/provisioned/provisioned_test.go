@@ -0,0 +1,133 @@
+package provisioned
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/budget"
+	"lambdaperf/pkg/cost"
+)
+
+// fakeLambda serves the first `provisioned` invocations of each round warm
+// and the rest as cold spillover.
+type fakeLambda struct {
+	mu          sync.Mutex
+	provisioned int32
+	polls       int
+	invocations int
+	released    bool
+	qualifiers  map[string]bool
+	// payload is what every invocation returns.
+	payload string
+}
+
+func (f *fakeLambda) Invoke(_ context.Context, in *lambda.InvokeInput, _ ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.qualifiers[aws.ToString(in.Qualifier)] = true
+	burst := int(f.provisioned) + 1
+	cold := f.invocations%burst >= int(f.provisioned)
+	f.invocations++
+	tail := fmt.Sprintf("REPORT RequestId: r%d\tDuration: 1.00 ms\tBilled Duration: 1 ms\tMemory Size: 128 MB\tMax Memory Used: 14 MB\t", f.invocations)
+	if cold {
+		tail += "Init Duration: 9.00 ms\t"
+	}
+	return &lambda.InvokeOutput{
+		StatusCode: 200,
+		Payload:    []byte(f.payload),
+		LogResult:  aws.String(base64.StdEncoding.EncodeToString([]byte(tail))),
+	}, nil
+}
+
+func (f *fakeLambda) GetFunctionConfiguration(_ context.Context, _ *lambda.GetFunctionConfigurationInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error) {
+	return &lambda.GetFunctionConfigurationOutput{MemorySize: aws.Int32(128), Architectures: []types.Architecture{types.ArchitectureArm64}}, nil
+}
+
+func (f *fakeLambda) PutProvisionedConcurrencyConfig(_ context.Context, in *lambda.PutProvisionedConcurrencyConfigInput, _ ...func(*lambda.Options)) (*lambda.PutProvisionedConcurrencyConfigOutput, error) {
+	f.provisioned = aws.ToInt32(in.ProvisionedConcurrentExecutions)
+	return &lambda.PutProvisionedConcurrencyConfigOutput{Status: types.ProvisionedConcurrencyStatusEnumInProgress}, nil
+}
+
+func (f *fakeLambda) GetProvisionedConcurrencyConfig(_ context.Context, _ *lambda.GetProvisionedConcurrencyConfigInput, _ ...func(*lambda.Options)) (*lambda.GetProvisionedConcurrencyConfigOutput, error) {
+	f.polls++
+	status := types.ProvisionedConcurrencyStatusEnumInProgress
+	if f.polls > 2 {
+		status = types.ProvisionedConcurrencyStatusEnumReady
+	}
+	return &lambda.GetProvisionedConcurrencyConfigOutput{Status: status}, nil
+}
+
+func (f *fakeLambda) DeleteProvisionedConcurrencyConfig(_ context.Context, _ *lambda.DeleteProvisionedConcurrencyConfigInput, _ ...func(*lambda.Options)) (*lambda.DeleteProvisionedConcurrencyConfigOutput, error) {
+	f.released = true
+	return &lambda.DeleteProvisionedConcurrencyConfigOutput{}, nil
+}
+
+func TestRunMeasuresSpillover(t *testing.T) {
+	fake := &fakeLambda{qualifiers: map[string]bool{}}
+	r := &Runner{Client: fake, FunctionName: "baseline-go", Concurrency: 2, Burst: 3, Rounds: 4, PollInterval: 1}
+	samples, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 12 {
+		t.Fatalf("%d samples, want 12", len(samples))
+	}
+	for i, s := range samples {
+		if s.Iteration != i {
+			t.Fatalf("sample %d has iteration %d", i, s.Iteration)
+		}
+	}
+	if spilled, total := Spillover(samples); spilled != 4 || total != 12 {
+		t.Errorf("Spillover = %d/%d, want 4/12", spilled, total)
+	}
+	if fake.polls < 3 {
+		t.Errorf("ran before provisioned concurrency was ready (%d polls)", fake.polls)
+	}
+	if !fake.released {
+		t.Error("provisioned concurrency not released")
+	}
+	if len(fake.qualifiers) != 1 || !fake.qualifiers[Alias] {
+		t.Errorf("invoked qualifiers %v, want only %q", fake.qualifiers, Alias)
+	}
+}
+
+func TestRunCanary(t *testing.T) {
+	fake := &fakeLambda{qualifiers: map[string]bool{}, payload: `{"statusCode":200,"body":"fibonacci(35)=9227465","runtime":"python"}`}
+	r := &Runner{Client: fake, FunctionName: "baseline-go", Concurrency: 2, Burst: 3, Rounds: 4, PollInterval: 1,
+		Runtime: "go", Expected: "fibonacci(35)=9227465"}
+	samples, err := r.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "canary") || len(samples) != 0 || fake.invocations != 1 {
+		t.Errorf("wrong runtime: %d samples after %d invocations, err %v", len(samples), fake.invocations, err)
+	}
+	if !fake.released {
+		t.Error("provisioned concurrency not released after the failed canary")
+	}
+}
+
+func TestRunChargesBudget(t *testing.T) {
+	ctx, b := budget.New(context.Background(), 1, cost.Default)
+	r := &Runner{Client: &fakeLambda{qualifiers: map[string]bool{}}, FunctionName: "baseline-go", Concurrency: 2, Burst: 2, Rounds: 1, PollInterval: 1}
+	if _, err := r.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// A short allocation is billed for the minimum.
+	if want, _ := cost.Default.Provisioned(cost.ArchARM64, 128, 2, 0); math.Abs(b.Spent()-want) > 1e-12 {
+		t.Errorf("charged %v, want %v", b.Spent(), want)
+	}
+}
+
+func TestRunRejectsEmptyScenario(t *testing.T) {
+	r := &Runner{Client: &fakeLambda{}, FunctionName: "baseline-go", Concurrency: 1}
+	if _, err := r.Run(context.Background()); err == nil {
+		t.Error("Run accepted zero burst and rounds")
+	}
+}
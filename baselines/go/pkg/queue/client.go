@@ -0,0 +1,189 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+// Client calls the SQS JSON API, implementing only the calls the harness
+// makes. Its fields become those of the awsapi.Client each call goes
+// through, where HTTP nil means http.DefaultClient.
+type Client struct {
+	Config aws.Config
+	// Endpoint overrides https://sqs.<region>.amazonaws.com.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// APIError is an error response from SQS, such as "QueueDoesNotExist".
+type APIError = awsapi.Error
+
+// TagKey marks queues created by the harness, as deploy.TagKey does
+// functions.
+const TagKey = "ruchy-bench"
+
+// Create calls CreateQueue for a standard queue whose visibility timeout
+// is visibility, tagged TagKey, returning its URL. SQS returns the
+// existing queue's URL when one of the name already has the same
+// attributes.
+func (c *Client) Create(ctx context.Context, name string, visibility time.Duration) (string, error) {
+	in := map[string]any{
+		"QueueName":  name,
+		"Attributes": map[string]string{"VisibilityTimeout": strconv.Itoa(int(visibility / time.Second))},
+		"tags":       map[string]string{TagKey: "true"},
+	}
+	var out struct{ QueueUrl string }
+	if err := c.call(ctx, "CreateQueue", in, &out); err != nil {
+		return "", fmt.Errorf("create queue %s: %w", name, err)
+	}
+	return out.QueueUrl, nil
+}
+
+// URL calls GetQueueUrl.
+func (c *Client) URL(ctx context.Context, name string) (string, error) {
+	var out struct{ QueueUrl string }
+	if err := c.call(ctx, "GetQueueUrl", map[string]any{"QueueName": name}, &out); err != nil {
+		return "", fmt.Errorf("get queue url of %s: %w", name, err)
+	}
+	return out.QueueUrl, nil
+}
+
+// ARN calls GetQueueAttributes for the queue's ARN, which event source
+// mappings and IAM policies name it by.
+func (c *Client) ARN(ctx context.Context, queueURL string) (string, error) {
+	in := map[string]any{"QueueUrl": queueURL, "AttributeNames": []string{"QueueArn"}}
+	var out struct{ Attributes map[string]string }
+	if err := c.call(ctx, "GetQueueAttributes", in, &out); err != nil {
+		return "", fmt.Errorf("get attributes of %s: %w", queueURL, err)
+	}
+	return out.Attributes["QueueArn"], nil
+}
+
+// SendBatch calls SendMessageBatch with up to MaxBatch bodies, returning
+// the message IDs SQS assigned them, in order. Any entry SQS rejects fails
+// the whole call.
+func (c *Client) SendBatch(ctx context.Context, queueURL string, bodies []string) ([]string, error) {
+	if len(bodies) == 0 || len(bodies) > MaxBatch {
+		return nil, fmt.Errorf("send batch of %d messages: want 1 to %d", len(bodies), MaxBatch)
+	}
+	type entry struct {
+		ID          string `json:"Id"`
+		MessageBody string
+	}
+	in := struct {
+		QueueUrl string
+		Entries  []entry
+	}{QueueUrl: queueURL}
+	for i, b := range bodies {
+		in.Entries = append(in.Entries, entry{ID: strconv.Itoa(i), MessageBody: b})
+	}
+	var out struct {
+		Successful []struct {
+			ID        string `json:"Id"`
+			MessageID string `json:"MessageId"`
+		}
+		Failed []struct {
+			ID      string `json:"Id"`
+			Code    string
+			Message string
+		}
+	}
+	if err := c.call(ctx, "SendMessageBatch", in, &out); err != nil {
+		return nil, fmt.Errorf("send message batch: %w", err)
+	}
+	if len(out.Failed) > 0 {
+		f := out.Failed[0]
+		return nil, fmt.Errorf("send message batch: %d of %d entries failed, first: %s: %s", len(out.Failed), len(bodies), f.Code, f.Message)
+	}
+	ids := make([]string, len(bodies))
+	for _, s := range out.Successful {
+		i, err := strconv.Atoi(s.ID)
+		if err != nil || i < 0 || i >= len(ids) {
+			return nil, fmt.Errorf("send message batch: unknown entry %q in response", s.ID)
+		}
+		ids[i] = s.MessageID
+	}
+	return ids, nil
+}
+
+// Received is a message Receive returned.
+type Received struct {
+	ID            string `json:"MessageId"`
+	ReceiptHandle string
+	Body          string
+}
+
+// Receive calls ReceiveMessage for up to max messages, 1 to MaxBatch,
+// long-polling for up to wait when none is available at once.
+func (c *Client) Receive(ctx context.Context, queueURL string, max int, wait time.Duration) ([]Received, error) {
+	if max < 1 || max > MaxBatch {
+		return nil, fmt.Errorf("receive %d messages: want 1 to %d", max, MaxBatch)
+	}
+	in := map[string]any{"QueueUrl": queueURL, "MaxNumberOfMessages": max, "WaitTimeSeconds": int(wait / time.Second)}
+	var out struct{ Messages []Received }
+	if err := c.call(ctx, "ReceiveMessage", in, &out); err != nil {
+		return nil, fmt.Errorf("receive from %s: %w", queueURL, err)
+	}
+	return out.Messages, nil
+}
+
+// DeleteMessage calls DeleteMessage for a message Receive returned.
+func (c *Client) DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error {
+	in := map[string]any{"QueueUrl": queueURL, "ReceiptHandle": receiptHandle}
+	if err := c.call(ctx, "DeleteMessage", in, nil); err != nil {
+		return fmt.Errorf("delete message from %s: %w", queueURL, err)
+	}
+	return nil
+}
+
+// List calls ListQueues for the URLs of the queues whose names start
+// with prefix, following every page.
+func (c *Client) List(ctx context.Context, prefix string) ([]string, error) {
+	var urls []string
+	in := map[string]any{"QueueNamePrefix": prefix, "MaxResults": 1000}
+	for {
+		var out struct {
+			QueueUrls []string
+			NextToken string
+		}
+		if err := c.call(ctx, "ListQueues", in, &out); err != nil {
+			return nil, fmt.Errorf("list queues: %w", err)
+		}
+		urls = append(urls, out.QueueUrls...)
+		if out.NextToken == "" {
+			return urls, nil
+		}
+		in["NextToken"] = out.NextToken
+	}
+}
+
+// Tags calls ListQueueTags.
+func (c *Client) Tags(ctx context.Context, queueURL string) (map[string]string, error) {
+	var out struct{ Tags map[string]string }
+	if err := c.call(ctx, "ListQueueTags", map[string]any{"QueueUrl": queueURL}, &out); err != nil {
+		return nil, fmt.Errorf("list tags of %s: %w", queueURL, err)
+	}
+	return out.Tags, nil
+}
+
+// Delete calls DeleteQueue.
+func (c *Client) Delete(ctx context.Context, queueURL string) error {
+	if err := c.call(ctx, "DeleteQueue", map[string]any{"QueueUrl": queueURL}, nil); err != nil {
+		return fmt.Errorf("delete queue %s: %w", queueURL, err)
+	}
+	return nil
+}
+
+// call calls action with input in and decodes its output into out,
+// unless out is nil.
+func (c *Client) call(ctx context.Context, action string, in, out any) error {
+	api := &awsapi.Client{Config: c.Config, Service: "sqs", Endpoint: c.Endpoint, HTTP: c.HTTP}
+	return api.JSON(ctx, "1.0", "AmazonSQS."+action, in, out)
+}
@@ -0,0 +1,141 @@
+// Package queue drives the sqs workload, whose baseline (main-sqs.go)
+// handles batches of up to ten SQS messages and reports the ones it was
+// asked to fail as batch item failures. The harness sends a run's
+// messages to the queue, then follows each through the records the
+// handler logs (lambdalog.Entry.Records) until it has been processed
+// successfully; the record's age when it was, measured from SQS's sent
+// timestamp, is the message's end-to-end latency.
+//
+// A failed message becomes visible again once the queue's visibility
+// timeout has passed, so a redelivered message's latency includes that
+// timeout on top of the extra invocation.
+package queue
+
+import (
+	"encoding/json"
+	"strings"
+
+	"lambdaperf/pkg/lambdalog"
+)
+
+const (
+	// Workload is the workload name of main-sqs.go.
+	Workload = "sqs"
+	// DefaultQueue is the queue ruchy-bench seed creates for it.
+	DefaultQueue = "ruchy-bench-sqs"
+	// MaxBatch is both SendMessageBatch's limit and the largest batch an
+	// SQS event source mapping delivers without a batching window.
+	MaxBatch = 10
+)
+
+// Message is the body of a benchmark message, as main-sqs.go reads it.
+type Message struct {
+	Run string `json:"run"`
+	Seq int    `json:"seq"`
+	// Fail asks the handler to report the message as failed on its
+	// first delivery, so that SQS redelivers it.
+	Fail bool `json:"fail,omitempty"`
+	// Data is hashed by the handler, so a message's size is work.
+	Data string `json:"data"`
+}
+
+// Body encodes m as a message body.
+func (m Message) Body() string {
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// Messages returns the n messages of run, each with size bytes of data,
+// with the fraction fail of them, spread evenly, marked to fail.
+func Messages(run string, n, size int, fail float64) []Message {
+	data := strings.Repeat("x", size)
+	msgs := make([]Message, n)
+	for i := range msgs {
+		msgs[i] = Message{
+			Run:  run,
+			Seq:  i,
+			Fail: int(float64(i+1)*fail) > int(float64(i)*fail),
+			Data: data,
+		}
+	}
+	return msgs
+}
+
+// Delivery is one time a handler received a message.
+type Delivery struct {
+	RequestID string
+	AgeMS     float64
+	Failed    bool
+}
+
+// Tracker follows sent messages through the invocation entries of the
+// function consuming their queue.
+type Tracker struct {
+	// Deliveries holds every delivery of each message, in the order of
+	// the IDs the tracker was made with.
+	Deliveries [][]Delivery
+	// Invocations counts the entries that delivered tracked messages,
+	// and Records the deliveries in them.
+	Invocations, Records int
+
+	index map[string]int
+	added map[string]bool
+}
+
+// NewTracker tracks the messages with the given SQS message IDs.
+func NewTracker(ids []string) *Tracker {
+	t := &Tracker{
+		Deliveries: make([][]Delivery, len(ids)),
+		index:      make(map[string]int, len(ids)),
+		added:      map[string]bool{},
+	}
+	for i, id := range ids {
+		t.index[id] = i
+	}
+	return t
+}
+
+// Add records the deliveries in e of tracked messages. Entries are
+// identified by request ID, so adding one again, as polling the log group
+// does, changes nothing.
+func (t *Tracker) Add(e lambdalog.Entry) {
+	if t.added[e.RequestID] {
+		return
+	}
+	t.added[e.RequestID] = true
+	n := 0
+	for _, r := range e.Records {
+		i, ok := t.index[r.ID]
+		if !ok {
+			continue
+		}
+		t.Deliveries[i] = append(t.Deliveries[i], Delivery{RequestID: e.RequestID, AgeMS: r.AgeMS, Failed: r.Failed})
+		n++
+	}
+	if n > 0 {
+		t.Invocations++
+		t.Records += n
+	}
+}
+
+// Processed returns the successful delivery of message i, if it has had
+// one yet.
+func (t *Tracker) Processed(i int) (Delivery, bool) {
+	for _, d := range t.Deliveries[i] {
+		if !d.Failed {
+			return d, true
+		}
+	}
+	return Delivery{}, false
+}
+
+// Pending is the number of messages not yet processed successfully.
+func (t *Tracker) Pending() int {
+	n := 0
+	for i := range t.Deliveries {
+		if _, ok := t.Processed(i); !ok {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,131 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"lambdaperf/pkg/lambdalog"
+)
+
+func TestMessages(t *testing.T) {
+	msgs := Messages("run-1", 20, 8, 0.25)
+	failing := 0
+	for i, m := range msgs {
+		if m.Run != "run-1" || m.Seq != i || len(m.Data) != 8 {
+			t.Errorf("message %d = %+v", i, m)
+		}
+		if m.Fail {
+			failing++
+		}
+	}
+	if failing != 5 || !msgs[3].Fail || msgs[0].Fail {
+		t.Errorf("%d of 20 messages fail, want every fourth", failing)
+	}
+	var got Message
+	if err := json.Unmarshal([]byte(msgs[3].Body()), &got); err != nil || got != msgs[3] {
+		t.Errorf("body round trip = %+v, %v", got, err)
+	}
+	for _, m := range Messages("run-1", 10, 0, 0) {
+		if m.Fail {
+			t.Errorf("message %d fails with -fail 0", m.Seq)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker([]string{"m1", "m2", "m3"})
+	first := lambdalog.Entry{RequestID: "r1", Records: []lambdalog.Record{
+		{ID: "m1", AgeMS: 20}, {ID: "m2", AgeMS: 21, Failed: true}, {ID: "other", AgeMS: 5},
+	}}
+	tr.Add(first)
+	tr.Add(first)
+	if tr.Pending() != 2 || tr.Invocations != 1 || tr.Records != 2 {
+		t.Errorf("after one batch: pending %d, %d invocations, %d records", tr.Pending(), tr.Invocations, tr.Records)
+	}
+	tr.Add(lambdalog.Entry{RequestID: "r0", Records: []lambdalog.Record{{ID: "unrelated"}}})
+	tr.Add(lambdalog.Entry{RequestID: "r2", Records: []lambdalog.Record{{ID: "m2", AgeMS: 30050}, {ID: "m3", AgeMS: 25}}})
+	if tr.Pending() != 0 || tr.Invocations != 2 {
+		t.Errorf("after redelivery: pending %d, %d invocations", tr.Pending(), tr.Invocations)
+	}
+	if d, ok := tr.Processed(1); !ok || d.RequestID != "r2" || d.AgeMS != 30050 || len(tr.Deliveries[1]) != 2 {
+		t.Errorf("redelivered message: %+v, %v, deliveries %+v", d, ok, tr.Deliveries[1])
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]any
+		json.NewDecoder(r.Body).Decode(&in)
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSQS.CreateQueue":
+			if in["Attributes"].(map[string]any)["VisibilityTimeout"] != "30" || in["tags"].(map[string]any)[TagKey] != "true" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"QueueUrl":"https://sqs/q"}`))
+		case "AmazonSQS.GetQueueAttributes":
+			w.Write([]byte(`{"Attributes":{"QueueArn":"arn:aws:sqs:us-east-1:1:q"}}`))
+		case "AmazonSQS.ListQueues":
+			if in["NextToken"] == nil {
+				w.Write([]byte(`{"QueueUrls":["https://sqs/ruchy-bench-a"],"NextToken":"t"}`))
+				return
+			}
+			w.Write([]byte(`{"QueueUrls":["https://sqs/ruchy-bench-b"]}`))
+		case "AmazonSQS.ListQueueTags":
+			w.Write([]byte(`{"Tags":{"ruchy-bench":"true"}}`))
+		case "AmazonSQS.DeleteQueue", "AmazonSQS.DeleteMessage":
+		case "AmazonSQS.ReceiveMessage":
+			w.Write([]byte(`{"Messages":[{"MessageId":"m-a","ReceiptHandle":"r-a","Body":"a"}]}`))
+		case "AmazonSQS.SendMessageBatch":
+			// Successful entries need not come back in order.
+			w.Write([]byte(`{"Successful":[{"Id":"1","MessageId":"m-b"},{"Id":"0","MessageId":"m-a"}]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.sqs#QueueDoesNotExist","message":"The specified queue does not exist."}`))
+		}
+	}))
+	defer srv.Close()
+	c := &Client{Endpoint: srv.URL}
+	ctx := context.Background()
+
+	u, err := c.Create(ctx, "q", 30*time.Second)
+	if err != nil || u != "https://sqs/q" {
+		t.Errorf("Create = %q, %v", u, err)
+	}
+	if arn, err := c.ARN(ctx, u); err != nil || arn != "arn:aws:sqs:us-east-1:1:q" {
+		t.Errorf("ARN = %q, %v", arn, err)
+	}
+	ids, err := c.SendBatch(ctx, u, []string{"a", "b"})
+	if err != nil || len(ids) != 2 || ids[0] != "m-a" || ids[1] != "m-b" {
+		t.Errorf("SendBatch = %v, %v", ids, err)
+	}
+	if _, err := c.SendBatch(ctx, u, make([]string, MaxBatch+1)); err == nil {
+		t.Error("oversized batch sent")
+	}
+	got, err := c.Receive(ctx, u, MaxBatch, 0)
+	if err != nil || len(got) != 1 || got[0].ReceiptHandle != "r-a" || got[0].Body != "a" {
+		t.Fatalf("Receive = %+v, %v", got, err)
+	}
+	if err := c.DeleteMessage(ctx, u, got[0].ReceiptHandle); err != nil {
+		t.Errorf("DeleteMessage: %v", err)
+	}
+	urls, err := c.List(ctx, "ruchy-bench-")
+	if err != nil || len(urls) != 2 || urls[1] != "https://sqs/ruchy-bench-b" {
+		t.Errorf("List = %v, %v", urls, err)
+	}
+	if tags, err := c.Tags(ctx, urls[0]); err != nil || tags[TagKey] != "true" {
+		t.Errorf("Tags = %v, %v", tags, err)
+	}
+	if err := c.Delete(ctx, urls[0]); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	var apiErr *APIError
+	if _, err := c.URL(ctx, "missing"); !errors.As(err, &apiErr) || apiErr.Code != "QueueDoesNotExist" {
+		t.Errorf("URL of a missing queue: %v", err)
+	}
+}
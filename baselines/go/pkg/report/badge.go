@@ -0,0 +1,146 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"lambdaperf/pkg/results"
+)
+
+// Badge is a shields.io endpoint badge: the JSON document
+// https://img.shields.io/endpoint?url=... renders, hosted anywhere the
+// badge URL can reach, such as GitHub Pages.
+type Badge struct {
+	// Name identifies the badge among a run's, usable as a file name
+	// without the .json extension.
+	Name          string `json:"-"`
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// BadgeOptions chooses what the badges compare.
+type BadgeOptions struct {
+	// Runtime is the runtime the badges are about, "ruchy" when empty.
+	Runtime string
+	// Versus is the runtime it is compared with. Empty means the fastest
+	// other runtime the target was measured in.
+	Versus string
+}
+
+// badgeMetrics are the columns of Row badges are made of, all lower is
+// better.
+var badgeMetrics = []struct {
+	name, label string
+	value       func(Row) float64
+	format      func(float64) string
+}{
+	{"cold-start", "cold start", func(r Row) float64 { return r.ColdStartMS }, milliseconds},
+	{"warm", "warm p50", func(r Row) float64 { return r.WarmP50MS }, milliseconds},
+	{"binary", "binary size", func(r Row) float64 { return r.BinaryKB }, sizeKB},
+}
+
+// Badges returns badges of run's cold start, warm median and binary size
+// of o.Runtime against another runtime, one of each per target both
+// measured, as "ruchy 12ms vs go 68ms". Targets o.Runtime alone measured
+// get a badge with its number only. The color is green where o.Runtime
+// is best, orange where it is not, and blue alone. Results must be
+// summarized first.
+func Badges(run *results.Run, o BadgeOptions) []Badge {
+	if o.Runtime == "" {
+		o.Runtime = "ruchy"
+	}
+	var order []string
+	groups := map[string][]Row{}
+	for _, row := range Rows(run, DefaultCost) {
+		if row.Error != "" {
+			continue
+		}
+		key := strings.TrimPrefix(row.Label, row.Runtime+"/")
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], row)
+	}
+
+	var badges []Badge
+	for _, key := range order {
+		rows := groups[key]
+		for _, m := range badgeMetrics {
+			own := math.NaN()
+			other, otherV := "", math.Inf(1)
+			for _, row := range rows {
+				v := m.value(row)
+				switch {
+				case math.IsNaN(v):
+				case row.Runtime == o.Runtime:
+					if math.IsNaN(own) {
+						own = v
+					}
+				case o.Versus != "" && row.Runtime != o.Versus:
+				case v < otherV:
+					other, otherV = row.Runtime, v
+				}
+			}
+			if math.IsNaN(own) {
+				continue
+			}
+			b := Badge{
+				Name:          m.name + "-" + slug(key),
+				SchemaVersion: 1,
+				Label:         m.label,
+				Message:       o.Runtime + " " + m.format(own),
+				Color:         "blue",
+			}
+			if other != "" {
+				b.Message += " vs " + other + " " + m.format(otherV)
+				b.Color = "orange"
+				if own <= otherV {
+					b.Color = "brightgreen"
+				}
+			}
+			badges = append(badges, b)
+		}
+	}
+	return badges
+}
+
+// milliseconds formats a duration to two or three significant figures.
+func milliseconds(ms float64) string {
+	switch {
+	case ms >= 1000:
+		return fmt.Sprintf("%.2fs", ms/1000)
+	case ms >= 100:
+		return fmt.Sprintf("%.0fms", ms)
+	case ms >= 10:
+		return fmt.Sprintf("%.1fms", ms)
+	}
+	return fmt.Sprintf("%.2fms", ms)
+}
+
+func sizeKB(kb float64) string {
+	if kb >= 1024 {
+		return fmt.Sprintf("%.1fMB", kb/1024)
+	}
+	return fmt.Sprintf("%.0fKB", kb)
+}
+
+// slug lowercases s and joins its words with dashes.
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(s) {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '.' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(c)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
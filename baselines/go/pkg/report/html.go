@@ -0,0 +1,314 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+
+	"lambdaperf/pkg/results"
+)
+
+// chart is one horizontal bar chart of a single column.
+type chart struct {
+	Title  string
+	Unit   string
+	Bars   []bar
+	Height int
+}
+
+type bar struct {
+	Label string
+	Value string
+	Color string
+	Width float64 // px
+	Y     int
+}
+
+const (
+	chartWidth = 420.0 // px available to the longest bar
+	barHeight  = 22
+	barGap     = 6
+)
+
+// runtimeColors keeps a runtime the same color on every chart.
+var runtimeColors = map[string]string{
+	"ruchy":  "#d9480f",
+	"go":     "#1c7ed6",
+	"tinygo": "#15aabf",
+	"wasm":   "#654ff0",
+	"rust":   "#7048e8",
+	"c":      "#495057",
+	"cpp":    "#495057",
+	"python": "#f59f00",
+	"julia":  "#2b8a3e",
+}
+
+func newChart(title, unit string, rows []Row, value func(Row) float64, prec int) chart {
+	c := chart{Title: title, Unit: unit}
+	max := 0.0
+	for _, r := range rows {
+		if v := value(r); !math.IsNaN(v) && v > max {
+			max = v
+		}
+	}
+	for _, r := range rows {
+		v := value(r)
+		if math.IsNaN(v) {
+			continue
+		}
+		width := 0.0
+		if max > 0 {
+			width = v / max * chartWidth
+		}
+		color, ok := runtimeColors[r.Runtime]
+		if !ok {
+			color = "#868e96"
+		}
+		c.Bars = append(c.Bars, bar{
+			Label: r.Label,
+			Value: fmt.Sprintf("%.*f", prec, v),
+			Color: color,
+			Width: width,
+			Y:     len(c.Bars) * (barHeight + barGap),
+		})
+	}
+	c.Height = len(c.Bars) * (barHeight + barGap)
+	return c
+}
+
+var page = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Benchmark results {{.Run.ID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem auto; max-width: 60rem; color: #212529; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; font-size: 0.9rem; }
+th, td { border-bottom: 1px solid #dee2e6; padding: 0.35rem 0.6rem; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+td.error { color: #c92a2a; text-align: left; }
+section { margin-bottom: 2rem; }
+svg text { font-size: 12px; fill: #212529; }
+.note { color: #868e96; font-size: 0.85rem; }
+</style>
+</head>
+<body>
+<h1>Benchmark results: {{.Run.ID}}</h1>
+<p>Mode <code>{{.Run.Mode}}</code>, started {{.Started}}.</p>
+<table>
+<tr><th>Target</th><th>Arch</th><th>Memory (MB)</th><th>Cold start (ms)</th><th>Warm p50 (ms)</th><th>Warm p99 (ms)</th><th>Max memory (MB)</th><th>Package (KB)</th><th>Binary (KB)</th><th>USD / 1M</th></tr>
+{{- range .Rows}}
+{{- if .Error}}
+<tr><td>{{.Label}}</td><td>{{.Arch}}</td><td class="error" colspan="8">error: {{.Error}}</td></tr>
+{{- else}}
+<tr><td>{{.Label}}</td><td>{{.Arch}}</td><td>{{.Memory}}</td><td>{{.ColdStart}}</td><td>{{.WarmP50}}</td><td>{{.WarmP99}}</td><td>{{.MaxMemory}}</td><td>{{.Package}}</td><td>{{.Binary}}</td><td>{{.Cost}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- if .SLOs}}
+<h2>SLOs</h2>
+<p class="note">{{.Compliance}}</p>
+<table>
+<tr><th>Target</th><th>SLO</th><th>Value</th><th>Verdict</th></tr>
+{{- range .SLOs}}
+<tr><td>{{.Label}}</td><td>{{.SLO}}</td><td>{{.Value}}</td><td{{if ne .Verdict "met"}} class="error"{{end}}>{{.Verdict}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Traces}}
+<h2>X-Ray segments <small>(ms, mean)</small></h2>
+<table>
+<tr><th>Target</th><th>Traced</th><th>Init</th><th>Invocation</th><th>Downstream</th><th>Overhead</th></tr>
+{{- range .Traces}}
+<tr><td>{{.Label}}</td><td>{{.Traced}}</td><td>{{.Init}}</td><td>{{.Invocation}}</td><td>{{.Downstream}}</td><td>{{.Overhead}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Tuned}}
+<h2>Power tuning</h2>
+<p class="note">The memory size each function's strategy chose, as aws-lambda-power-tuning chooses it.</p>
+<table>
+<tr><th>Target</th><th>Strategy</th><th>Memory (MB)</th><th>Warm p50 (ms)</th><th>USD / 1M</th></tr>
+{{- range .Tuned}}
+<tr><td>{{.Target}}</td><td>{{.Strategy}}</td><td>{{.MemoryMB}}</td><td>{{.WarmP50}}</td><td>{{.Cost}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Comparisons}}
+<h2>Runtime comparisons</h2>
+<p class="note">{{.SignificanceNote}}</p>
+<table>
+<tr><th>Target</th><th>Runtimes</th><th>Metric</th><th>Medians (ms)</th><th>Cliff's δ</th><th>p</th><th>Difference</th></tr>
+{{- range .Comparisons}}
+<tr><td>{{.Target}}</td><td>{{.Runtimes}}</td><td>{{.Metric}}</td><td>{{.Medians}}</td><td>{{printf "%.2f" .Delta}}</td><td>{{.P}}</td><td>{{.Difference}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Exclusions}}
+<h2>Retries and exclusions</h2>
+<p>Samples that still failed transiently after their retries are excluded from every statistic.</p>
+<table>
+<tr><th>Target</th><th>Samples</th><th>Retried</th><th>Retries</th><th>Excluded</th></tr>
+{{- range .Exclusions}}
+<tr><td>{{.Label}}</td><td>{{.Samples}}</td><td>{{.Retried}}</td><td>{{.Retries}}</td><td>{{.Excluded}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Regions}}
+<h2>Across regions</h2>
+<table>
+<tr><th>Target</th><th>Regions</th><th>Cold start (ms)</th><th>Region range (ms)</th><th>Warm p50 (ms)</th><th>Region range (ms)</th></tr>
+{{- range .Regions}}
+<tr><td>{{.Label}}</td><td>{{.Regions}}</td><td>{{.ColdStart}}</td><td>{{.ColdStartRange}}</td><td>{{.WarmP50}}</td><td>{{.WarmP50Range}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{range .Charts}}
+<section>
+<h2>{{.Title}} <small>({{.Unit}})</small></h2>
+<svg width="720" height="{{.Height}}" role="img" aria-label="{{.Title}}">
+{{- range .Bars}}
+<text x="0" y="{{.Y}}" dy="16">{{.Label}}</text>
+<rect x="220" y="{{.Y}}" width="{{printf "%.1f" .Width}}" height="22" fill="{{.Color}}"></rect>
+<text x="{{printf "%.1f" .Width}}" y="{{.Y}}" dx="226" dy="16">{{.Value}}</text>
+{{- end}}
+</svg>
+</section>
+{{end}}
+<p class="note">{{.CostNote}}</p>
+</body>
+</html>
+`))
+
+// htmlRow is a Row formatted for the table.
+type htmlRow struct {
+	Label, Arch, Error                                                    string
+	Memory, ColdStart, WarmP50, WarmP99, MaxMemory, Package, Binary, Cost string
+}
+
+// traceRow is a Row's X-Ray segments formatted for the segments table.
+type traceRow struct {
+	Label                                  string
+	Traced                                 int
+	Init, Invocation, Downstream, Overhead string
+}
+
+// tunedRow is a TunedRow with its numbers formatted.
+type tunedRow struct {
+	TunedRow
+	WarmP50, Cost string
+}
+
+// comparisonRow is a ComparisonRow with its p-value formatted.
+type comparisonRow struct {
+	ComparisonRow
+	P string
+}
+
+// exclusionRow is an ExclusionRow formatted for the exclusions table.
+type exclusionRow struct {
+	Label                     string
+	Samples, Retried, Retries int
+	Excluded                  string
+}
+
+// regionRow is a RegionRow formatted for the across-regions table.
+type regionRow struct {
+	Label, Regions                                   string
+	ColdStart, ColdStartRange, WarmP50, WarmP50Range string
+}
+
+// HTML writes run as a standalone page: the comparison table followed by
+// bar charts of cold start, warm p50/p99, memory, package size and cost,
+// with a table of SLO checks when there are any, one of X-Ray segments
+// for traced runs, one of power-tuned
+// memory sizes, one testing each pair of runtimes measured on a target,
+// one of retried and excluded samples and one pooling targets measured in
+// several regions. Charts are inline SVG, so the page needs no network
+// access to render.
+func HTML(w io.Writer, run *results.Run, o Options) error {
+	rows := Rows(run, o.CostOptions)
+	var ok []Row
+	table := make([]htmlRow, 0, len(rows))
+	for _, r := range rows {
+		mem := "-"
+		if r.MemoryMB != 0 {
+			mem = fmt.Sprint(r.MemoryMB)
+		}
+		table = append(table, htmlRow{
+			Label: r.Label, Arch: orDash(r.Arch), Error: r.Error, Memory: mem,
+			ColdStart: num(r.ColdStartMS, 2), WarmP50: num(r.WarmP50MS, 2), WarmP99: num(r.WarmP99MS, 2),
+			MaxMemory: num(r.MaxMemoryMB, 0), Package: num(r.PackageKB, 0), Binary: num(r.BinaryKB, 0),
+			Cost: num(r.CostPer1M, 4),
+		})
+		if r.Error == "" {
+			ok = append(ok, r)
+		}
+	}
+
+	var traces []traceRow
+	for _, r := range traced(rows) {
+		traces = append(traces, traceRow{
+			Label: r.Label, Traced: r.Traced, Init: num(r.TraceInitMS, 2), Invocation: num(r.TraceInvocationMS, 2),
+			Downstream: num(r.TraceDownstreamMS, 2), Overhead: num(r.TraceOverheadMS, 2),
+		})
+	}
+
+	var tuned []tunedRow
+	for _, r := range Tuned(run, o.CostOptions) {
+		tuned = append(tuned, tunedRow{TunedRow: r, WarmP50: num(r.WarmP50MS, 2), Cost: num(r.CostPer1M, 4)})
+	}
+
+	var comparisons []comparisonRow
+	for _, r := range Comparisons(run) {
+		comparisons = append(comparisons, comparisonRow{ComparisonRow: r, P: pValue(r.P)})
+	}
+
+	var exclusions []exclusionRow
+	for _, r := range Exclusions(run) {
+		exclusions = append(exclusions, exclusionRow{
+			Label: r.Label, Samples: r.Samples, Retried: r.Retried, Retries: r.Retries, Excluded: r.Breakdown(),
+		})
+	}
+
+	var regions []regionRow
+	for _, r := range Regional(run) {
+		regions = append(regions, regionRow{
+			Label: r.Label, Regions: strings.Join(r.Regions, ", "),
+			ColdStart: num(r.ColdStartMS, 2), ColdStartRange: numRange(r.ColdStartMinMS, r.ColdStartMaxMS, 2),
+			WarmP50: num(r.WarmP50MS, 2), WarmP50Range: numRange(r.WarmP50MinMS, r.WarmP50MaxMS, 2),
+		})
+	}
+
+	var charts []chart
+	for _, c := range []chart{
+		newChart("Cold start", "init or SnapStart restore ms, mean", ok, func(r Row) float64 { return r.ColdStartMS }, 2),
+		newChart("Warm p50", "ms", ok, func(r Row) float64 { return r.WarmP50MS }, 2),
+		newChart("Warm p99", "ms", ok, func(r Row) float64 { return r.WarmP99MS }, 2),
+		newChart("Memory", "max used MB, mean", ok, func(r Row) float64 { return r.MaxMemoryMB }, 0),
+		newChart("Package size", "deployment zip KB", ok, func(r Row) float64 { return r.PackageKB }, 0),
+		newChart("Cost", "USD per 1M invocations", ok, func(r Row) float64 { return r.CostPer1M }, 4),
+	} {
+		if len(c.Bars) > 0 {
+			charts = append(charts, c)
+		}
+	}
+
+	return page.Execute(w, map[string]any{
+		"Run":              run,
+		"Started":          run.StartedAt.UTC().Format("2006-01-02 15:04 MST"),
+		"Rows":             table,
+		"Traces":           traces,
+		"Tuned":            tuned,
+		"Comparisons":      comparisons,
+		"SignificanceNote": significanceNote,
+		"Exclusions":       exclusions,
+		"Regions":          regions,
+		"Charts":           charts,
+		"SLOs":             SLORows(o.SLOs),
+		"Compliance":       compliance(o.SLOs),
+		"CostNote":         costNote(o.CostOptions),
+	})
+}
@@ -0,0 +1,616 @@
+// Package report renders a summarized results.Run as a Markdown comparison
+// table for the repository and as a standalone HTML page with bar charts.
+package report
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"lambdaperf/pkg/compare"
+	"lambdaperf/pkg/cost"
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/slo"
+	"lambdaperf/pkg/stats"
+)
+
+// CostOptions are the pricing assumptions behind the cost column.
+type CostOptions struct {
+	Pricing cost.Pricing
+	// Monthly is the invocation volume tiers are evaluated at.
+	Monthly     float64
+	FreeTier    bool
+	EphemeralMB int32
+}
+
+// Options configure a report.
+type Options struct {
+	CostOptions
+	// SLOs are the run's checks against the manifest's SLOs, rendered
+	// with each runtime's compliance; see pkg/slo.
+	SLOs []slo.Check
+}
+
+// DefaultCost prices at us-east-1 on-demand rates for 1M invocations a
+// month, without the free tier.
+var DefaultCost = CostOptions{Pricing: cost.Default, Monthly: 1e6, EphemeralMB: 512}
+
+// CostPerMillion is the USD cost of one million invocations of r at its
+// mean billed duration. It reports false when r has nothing to price, as
+// for local results.
+func CostPerMillion(r results.Result, o CostOptions) (float64, bool) {
+	billed, ok := r.Stats[results.MetricBilled]
+	mem := r.Memory()
+	if !ok || billed.N == 0 || mem == 0 {
+		return 0, false
+	}
+	usd, err := o.Pricing.PerMillion(cost.Usage{
+		Arch:        r.Arch,
+		MemoryMB:    mem,
+		EphemeralMB: o.EphemeralMB,
+		BilledMS:    billed.Mean,
+	}, o.Monthly, o.FreeTier)
+	return usd, err == nil
+}
+
+// Row is one result reduced to the compared columns. Missing values are
+// NaN.
+type Row struct {
+	Label    string
+	Runtime  string
+	Workload string
+	Kind     string
+	Arch     string
+	MemoryMB int32
+	Region   string
+	Error    string
+
+	ColdStartMS float64 // mean init duration, or restore duration under SnapStart
+	WarmP50MS   float64 // warm duration, or client time for local results
+	WarmP99MS   float64
+	MaxMemoryMB float64 // mean max memory used, or peak RSS for local results
+	CostPer1M   float64 // USD
+	BinaryKB    float64 // stripped binary size
+	PackageKB   float64 // deployment zip size
+
+	// Mean X-Ray segment durations over the Traced invocations; see
+	// pkg/tracing. Invocation includes downstream calls.
+	Traced            int
+	TraceInitMS       float64
+	TraceInvocationMS float64
+	TraceDownstreamMS float64
+	TraceOverheadMS   float64
+}
+
+// Rows reduces run to one Row per result, in run order. Results must be
+// summarized first.
+func Rows(run *results.Run, o CostOptions) []Row {
+	rows := make([]Row, 0, len(run.Results))
+	for _, r := range run.Results {
+		row := Row{
+			Label:       label(r),
+			Runtime:     r.Runtime,
+			Workload:    r.Workload,
+			Kind:        r.Kind,
+			Arch:        r.Arch,
+			MemoryMB:    r.Memory(),
+			Region:      r.Region,
+			Error:       r.Error,
+			ColdStartMS: math.NaN(),
+			WarmP50MS:   math.NaN(),
+			WarmP99MS:   math.NaN(),
+			MaxMemoryMB: math.NaN(),
+			CostPer1M:   math.NaN(),
+			BinaryKB:    kilobytes(r.BinaryBytes),
+			PackageKB:   kilobytes(r.PackageBytes),
+			Traced:      r.Stats[results.MetricTraceInvocation].N,
+
+			TraceInitMS:       mean(r, results.MetricTraceInit),
+			TraceInvocationMS: mean(r, results.MetricTraceInvocation),
+			TraceDownstreamMS: mean(r, results.MetricTraceDownstream),
+			TraceOverheadMS:   mean(r, results.MetricTraceOverhead),
+		}
+		if s, ok := r.Stats[results.MetricInit]; ok {
+			row.ColdStartMS = s.Mean
+		} else if s, ok := r.Stats[results.MetricRestore]; ok {
+			row.ColdStartMS = s.Mean
+		}
+		warm, ok := r.Stats[results.MetricWarm]
+		if !ok {
+			warm, ok = r.Stats[results.MetricClient]
+		}
+		if ok {
+			row.WarmP50MS, row.WarmP99MS = warm.Median, warm.P99
+		}
+		if mem, n := maxMemory(r); n > 0 {
+			row.MaxMemoryMB = mem
+		}
+		if usd, ok := CostPerMillion(r, o); ok {
+			row.CostPer1M = usd
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func label(r results.Result) string {
+	l := target(r)
+	if r.Region != "" {
+		l += " (" + r.Region + ")"
+	}
+	return l
+}
+
+// target labels what r measured regardless of where: the label of every
+// region's result of the same target.
+func target(r results.Result) string {
+	l := r.Runtime + "/" + r.Workload
+	if r.Kind == "local" {
+		l += " (local)"
+	}
+	if r.Kind == string(discover.KindRIE) {
+		l += " (RIE)"
+	}
+	if r.Arch != "" && r.Arch != cost.ArchX86 {
+		l += " @" + r.Arch
+	}
+	if r.Package == discover.PackageImage {
+		l += " (image)"
+	}
+	if r.SnapStart {
+		l += " (SnapStart)"
+	}
+	if r.Extension {
+		l += " (extension)"
+	}
+	if r.VPC {
+		l += " (VPC)"
+	}
+	if r.Chaos {
+		l += " (chaos)"
+	}
+	if r.Serializer != "" {
+		l += " (" + r.Serializer + ")"
+	}
+	if r.Edge {
+		l += " (Lambda@Edge)"
+	}
+	if r.Sandbox != "" {
+		l += " (" + r.Sandbox + " sandbox)"
+	}
+	if r.MemoryMB != 0 {
+		l += fmt.Sprintf(" %dMB", r.MemoryMB)
+	}
+	if in := r.InputLabel(); in != "" {
+		l += " [" + in + "]"
+	}
+	return l
+}
+
+// ExclusionRow is a result some of whose invocations were retried or
+// excluded after failing transiently; see results.Sample.Excluded.
+type ExclusionRow struct {
+	Label   string
+	Samples int
+	// Retried counts the samples measured on a retry, and Retries the
+	// retries made over all samples.
+	Retried, Retries int
+	// Excluded counts the excluded samples, and Classes the same by class
+	// of failure, such as "throttle".
+	Excluded int
+	Classes  map[string]int
+}
+
+// Exclusions lists the results of run with retried or excluded samples, in
+// run order.
+func Exclusions(run *results.Run) []ExclusionRow {
+	var rows []ExclusionRow
+	for _, r := range run.Results {
+		row := ExclusionRow{Label: label(r), Samples: len(r.Samples)}
+		for _, s := range r.Samples {
+			if s.Retries > 0 {
+				row.Retries += s.Retries
+				if s.Excluded == "" {
+					row.Retried++
+				}
+			}
+			if s.Excluded != "" {
+				if row.Classes == nil {
+					row.Classes = map[string]int{}
+				}
+				row.Excluded++
+				row.Classes[s.Excluded]++
+			}
+		}
+		if row.Retries > 0 || row.Excluded > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// Breakdown formats the excluded samples as "3 (throttle 2, timeout 1)".
+func (r ExclusionRow) Breakdown() string {
+	if r.Excluded == 0 {
+		return "0"
+	}
+	var classes []string
+	for _, c := range slices.Sorted(maps.Keys(r.Classes)) {
+		classes = append(classes, fmt.Sprintf("%s %d", c, r.Classes[c]))
+	}
+	return fmt.Sprintf("%d (%s)", r.Excluded, strings.Join(classes, ", "))
+}
+
+// RegionRow is one target measured in several regions of a run.
+type RegionRow struct {
+	Label   string
+	Regions []string
+	// The cold start is the mean over every region's cold starts, and
+	// warm p50 the median over every region's warm samples. Min and Max
+	// are the lowest and highest of the regions' own values: the spread a
+	// single-region result could have landed anywhere in.
+	ColdStartMS, ColdStartMinMS, ColdStartMaxMS float64
+	WarmP50MS, WarmP50MinMS, WarmP50MaxMS       float64
+}
+
+// Regional pools the successful results of each target run in more than
+// one region, in run order. Pooled samples are summarized without outlier
+// rejection. It returns nil for runs of a single region.
+func Regional(run *results.Run) []RegionRow {
+	var (
+		order  []string
+		groups = map[string][]results.Result{}
+	)
+	for _, r := range run.Results {
+		if r.Region == "" || r.Error != "" {
+			continue
+		}
+		key := target(r)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], r)
+	}
+	var rows []RegionRow
+	for _, key := range order {
+		rs := groups[key]
+		if len(rs) < 2 {
+			continue
+		}
+		row := RegionRow{Label: key}
+		var cold, warm []float64
+		row.ColdStartMinMS, row.ColdStartMaxMS = math.NaN(), math.NaN()
+		row.WarmP50MinMS, row.WarmP50MaxMS = math.NaN(), math.NaN()
+		for _, r := range rs {
+			row.Regions = append(row.Regions, r.Region)
+			metric := results.MetricInit
+			if _, ok := r.Stats[metric]; !ok {
+				metric = results.MetricRestore
+			}
+			if xs := r.Values(metric); len(xs) > 0 {
+				cold = append(cold, xs...)
+				row.ColdStartMinMS, row.ColdStartMaxMS = spread(row.ColdStartMinMS, row.ColdStartMaxMS, r.Stats[metric].Mean)
+			}
+			if xs := r.Values(results.MetricWarm); len(xs) > 0 {
+				warm = append(warm, xs...)
+				row.WarmP50MinMS, row.WarmP50MaxMS = spread(row.WarmP50MinMS, row.WarmP50MaxMS, r.Stats[results.MetricWarm].Median)
+			}
+		}
+		row.ColdStartMS, row.WarmP50MS = math.NaN(), math.NaN()
+		if len(cold) > 0 {
+			row.ColdStartMS = stats.Summarize(cold, stats.Options{}).Mean
+		}
+		if len(warm) > 0 {
+			row.WarmP50MS = stats.Summarize(warm, stats.Options{}).Median
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// spread widens the range [lo, hi], NaN while empty, to include v.
+func spread(lo, hi, v float64) (float64, float64) {
+	if math.IsNaN(lo) || v < lo {
+		lo = v
+	}
+	if math.IsNaN(hi) || v > hi {
+		hi = v
+	}
+	return lo, hi
+}
+
+// mean is the summarized mean of metric, NaN when r has none.
+func mean(r results.Result, metric string) float64 {
+	if s, ok := r.Stats[metric]; ok {
+		return s.Mean
+	}
+	return math.NaN()
+}
+
+// traced selects the rows with X-Ray segment data.
+// TunedRow is the memory size a power-tuning sweep chose for one
+// function; see pkg/powertune.
+type TunedRow struct {
+	Target    string
+	Strategy  string
+	MemoryMB  int32
+	WarmP50MS float64
+	CostPer1M float64
+}
+
+// Tuned lists the optimum of every function run was power-tuned for, in
+// run order.
+func Tuned(run *results.Run, o CostOptions) []TunedRow {
+	var out []TunedRow
+	for i, row := range Rows(run, o) {
+		r := run.Results[i]
+		if r.Optimum == "" {
+			continue
+		}
+		r.MemoryMB = 0
+		out = append(out, TunedRow{Target: label(r), Strategy: r.Optimum, MemoryMB: row.MemoryMB, WarmP50MS: row.WarmP50MS, CostPer1M: row.CostPer1M})
+	}
+	return out
+}
+
+func traced(rows []Row) []Row {
+	var out []Row
+	for _, r := range rows {
+		if r.Traced > 0 && r.Error == "" {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// kilobytes converts a size in bytes to KB, NaN when unknown.
+func kilobytes(n int64) float64 {
+	if n == 0 {
+		return math.NaN()
+	}
+	return float64(n) / 1024
+}
+
+// maxMemory is the mean memory used: Lambda's max memory used, or the peak
+// RSS of local runs.
+func maxMemory(r results.Result) (float64, int) {
+	var sum float64
+	var n int
+	for _, s := range r.Samples {
+		if s.Error != "" || s.Warmup {
+			continue
+		}
+		switch {
+		case s.MaxMemoryMB > 0:
+			sum += float64(s.MaxMemoryMB)
+		case s.MaxRSSKB > 0:
+			sum += float64(s.MaxRSSKB) / 1024
+		default:
+			continue
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return sum / float64(n), n
+}
+
+// ComparisonRow is two runtimes compared on one target; see
+// compare.Runtimes.
+type ComparisonRow struct {
+	Target, Runtimes, Metric string
+	// Medians are the runtimes' medians, in Runtimes order.
+	Medians string
+	Delta   float64
+	P       float64
+	// Difference is the verdict: which runtime is faster, and by how
+	// large an effect, or that the difference is not significant.
+	Difference string
+}
+
+// significanceNote explains the runtime comparisons.
+const significanceNote = "Two-sided Mann-Whitney U tests at p < 0.05 on each pair of runtimes, with Cliff's delta as the effect size: " +
+	"the chance a sample of the first is larger than one of the second, less the reverse, labeled negligible below 0.147, " +
+	"small below 0.33, medium below 0.474 and large beyond. Both are rank-based, so outliers sway neither."
+
+// Comparisons pairs the runtimes of every target run measured in more
+// than one, comparing init durations for coldstart runs and warm
+// durations, or client time, otherwise.
+func Comparisons(run *results.Run) []ComparisonRow {
+	var o compare.Options
+	if run.Mode == "coldstart" {
+		o.Metric = results.MetricInit
+	}
+	var rows []ComparisonRow
+	for _, p := range compare.Runtimes(run, o) {
+		row := ComparisonRow{
+			Target:   p.Target,
+			Runtimes: p.A + " vs " + p.B,
+			Metric:   p.Metric,
+			Medians:  num(p.MedianA, 2) + " vs " + num(p.MedianB, 2),
+			Delta:    p.Delta,
+			P:        p.P,
+		}
+		switch {
+		case p.NA == 0 || p.NB == 0:
+			row.Medians, row.Difference = "-", "no samples to compare"
+		case !p.Significant(0):
+			row.Difference = fmt.Sprintf("not significant (%s)", p.Magnitude)
+		default:
+			row.Difference = fmt.Sprintf("%s faster, %s", p.Faster(), p.Magnitude)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// pValue formats p, flooring it at the precision the normal approximation
+// deserves.
+func pValue(p float64) string {
+	if p < 0.001 {
+		return "<0.001"
+	}
+	return fmt.Sprintf("%.3f", p)
+}
+
+// SLORow is one SLO check reduced to its table columns.
+type SLORow struct {
+	Label, SLO, Value string
+	// Verdict is met, missed or failed, for a result with no samples.
+	Verdict string
+}
+
+// SLORows reduces checks to one SLORow each, in order.
+func SLORows(checks []slo.Check) []SLORow {
+	rows := make([]SLORow, 0, len(checks))
+	for _, c := range checks {
+		row := SLORow{Label: label(c.Result), SLO: c.SLO.String(), Value: num(c.Value, 2), Verdict: "met"}
+		switch {
+		case c.Result.Error != "":
+			row.Value, row.Verdict = "-", "failed"
+		case !c.Met():
+			row.Verdict = "missed"
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// compliance sums up checks per runtime in one sentence.
+func compliance(checks []slo.Check) string {
+	var parts []string
+	for _, c := range slo.ByRuntime(checks) {
+		parts = append(parts, fmt.Sprintf("%s %d of %d", c.Runtime, c.Met, c.Checked))
+	}
+	return "SLOs met: " + strings.Join(parts, ", ") + "."
+}
+
+// Markdown writes run as a GitHub-flavored Markdown table.
+func Markdown(w io.Writer, run *results.Run, o Options) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Benchmark results: %s\n\n", run.ID)
+	fmt.Fprintf(&b, "Mode `%s`, started %s.\n\n", run.Mode, run.StartedAt.UTC().Format("2006-01-02 15:04 MST"))
+	b.WriteString("| Target | Arch | Memory (MB) | Cold start (ms) | Warm p50 (ms) | Warm p99 (ms) | Max memory (MB) | Package (KB) | Binary (KB) | USD / 1M |\n")
+	b.WriteString("|--------|------|------------:|----------------:|--------------:|--------------:|----------------:|-------------:|------------:|---------:|\n")
+	for _, r := range Rows(run, o.CostOptions) {
+		if r.Error != "" {
+			fmt.Fprintf(&b, "| %s | %s | - | error: %s | | | | | | |\n", r.Label, orDash(r.Arch), escapeCell(r.Error))
+			continue
+		}
+		mem := "-"
+		if r.MemoryMB != 0 {
+			mem = fmt.Sprint(r.MemoryMB)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s | %s | %s | %s | %s |\n",
+			r.Label, orDash(r.Arch), mem, num(r.ColdStartMS, 2), num(r.WarmP50MS, 2),
+			num(r.WarmP99MS, 2), num(r.MaxMemoryMB, 0), num(r.PackageKB, 0), num(r.BinaryKB, 0), num(r.CostPer1M, 4))
+	}
+	if len(o.SLOs) > 0 {
+		b.WriteString("\n### SLOs\n\n")
+		b.WriteString(compliance(o.SLOs) + "\n\n")
+		b.WriteString("| Target | SLO | Value | Verdict |\n")
+		b.WriteString("|--------|-----|------:|---------|\n")
+		for _, r := range SLORows(o.SLOs) {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", r.Label, r.SLO, r.Value, r.Verdict)
+		}
+	}
+	if rows := traced(Rows(run, o.CostOptions)); len(rows) > 0 {
+		b.WriteString("\n### X-Ray segments\n\n")
+		b.WriteString("| Target | Traced | Init (ms) | Invocation (ms) | Downstream (ms) | Overhead (ms) |\n")
+		b.WriteString("|--------|-------:|----------:|----------------:|----------------:|--------------:|\n")
+		for _, r := range rows {
+			fmt.Fprintf(&b, "| %s | %d | %s | %s | %s | %s |\n", r.Label, r.Traced, num(r.TraceInitMS, 2),
+				num(r.TraceInvocationMS, 2), num(r.TraceDownstreamMS, 2), num(r.TraceOverheadMS, 2))
+		}
+	}
+	if rows := Regional(run); len(rows) > 0 {
+		b.WriteString("\n### Across regions\n\n")
+		b.WriteString("| Target | Regions | Cold start (ms) | Region range (ms) | Warm p50 (ms) | Region range (ms) |\n")
+		b.WriteString("|--------|---------|----------------:|------------------:|--------------:|------------------:|\n")
+		for _, r := range rows {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", r.Label, strings.Join(r.Regions, ", "),
+				num(r.ColdStartMS, 2), numRange(r.ColdStartMinMS, r.ColdStartMaxMS, 2),
+				num(r.WarmP50MS, 2), numRange(r.WarmP50MinMS, r.WarmP50MaxMS, 2))
+		}
+	}
+	if rows := Tuned(run, o.CostOptions); len(rows) > 0 {
+		b.WriteString("\n### Power tuning\n\n")
+		b.WriteString("The memory size each function's strategy chose, as aws-lambda-power-tuning chooses it.\n\n")
+		b.WriteString("| Target | Strategy | Memory (MB) | Warm p50 (ms) | USD / 1M |\n")
+		b.WriteString("|--------|----------|------------:|--------------:|---------:|\n")
+		for _, r := range rows {
+			fmt.Fprintf(&b, "| %s | %s | %d | %s | %s |\n", r.Target, r.Strategy, r.MemoryMB, num(r.WarmP50MS, 2), num(r.CostPer1M, 4))
+		}
+	}
+	if rows := Comparisons(run); len(rows) > 0 {
+		b.WriteString("\n### Runtime comparisons\n\n")
+		b.WriteString(significanceNote + "\n\n")
+		b.WriteString("| Target | Runtimes | Metric | Medians (ms) | Cliff's δ | p | Difference |\n")
+		b.WriteString("|--------|----------|--------|-------------:|----------:|--:|------------|\n")
+		for _, r := range rows {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %.2f | %s | %s |\n", r.Target, r.Runtimes, r.Metric, r.Medians,
+				r.Delta, pValue(r.P), r.Difference)
+		}
+	}
+	if rows := Exclusions(run); len(rows) > 0 {
+		b.WriteString("\n### Retries and exclusions\n\n")
+		b.WriteString("Samples that still failed transiently after their retries are excluded from every statistic.\n\n")
+		b.WriteString("| Target | Samples | Retried | Retries | Excluded |\n")
+		b.WriteString("|--------|--------:|--------:|--------:|----------|\n")
+		for _, r := range rows {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %s |\n", r.Label, r.Samples, r.Retried, r.Retries, r.Breakdown())
+		}
+	}
+	fmt.Fprintf(&b, "\n%s\n", costNote(o.CostOptions))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func costNote(o CostOptions) string {
+	note := fmt.Sprintf("Costs assume %s invocations/month at us-east-1 on-demand rates", humanCount(o.Monthly))
+	if o.FreeTier {
+		note += ", after the free tier"
+	}
+	return note + "."
+}
+
+func num(v float64, prec int) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return fmt.Sprintf("%.*f", prec, v)
+}
+
+// numRange formats "lo to hi", or a dash when the range is empty.
+func numRange(lo, hi float64, prec int) string {
+	if math.IsNaN(lo) {
+		return "-"
+	}
+	return num(lo, prec) + " to " + num(hi, prec)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func escapeCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+func humanCount(n float64) string {
+	switch {
+	case n >= 1e9 && math.Mod(n, 1e9) == 0:
+		return fmt.Sprintf("%dB", int64(n/1e9))
+	case n >= 1e6 && math.Mod(n, 1e6) == 0:
+		return fmt.Sprintf("%dM", int64(n/1e6))
+	}
+	return fmt.Sprintf("%.0f", n)
+}
@@ -0,0 +1,307 @@
+package report
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/slo"
+	"lambdaperf/pkg/stats"
+	"lambdaperf/pkg/tracing"
+)
+
+func testRun() *results.Run {
+	lambda := results.Result{Runtime: "ruchy", Workload: "fibonacci", Kind: "lambda", Arch: "arm64",
+		BinaryBytes: 401 << 10, PackageBytes: 180 << 10}
+	for i, d := range []float64{200, 10, 12, 14} {
+		lambda.Samples = append(lambda.Samples, results.Sample{
+			Iteration: i, ClientMS: d + 5, RequestID: "req", DurationMS: d, BilledMS: d,
+			MemorySizeMB: 128, MaxMemoryMB: 15, Cold: i == 0, InitMS: 8,
+		})
+	}
+	// X-Ray sampled the cold start.
+	lambda.Samples[0] = lambda.Samples[0].WithTrace(tracing.Breakdown{InitMS: 7.5, InvocationMS: 190, OverheadMS: 0.5,
+		Downstream: map[string]float64{"S3": 120}})
+	local := results.Result{Runtime: "go", Workload: "fibonacci", Kind: "local",
+		Samples: []results.Sample{{ClientMS: 30, MaxRSSKB: 2048}, {ClientMS: 40, MaxRSSKB: 4096}}}
+	failed := results.Result{Runtime: "python", Workload: "fibonacci", Kind: "lambda", Arch: "x86_64",
+		Input: map[string]int{"n": 30}, Error: "not | deployed"}
+	run := &results.Run{ID: "20250101T000000Z", Mode: "mixed", StartedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Results: []results.Result{lambda, local, failed}}
+	run.Summarize(stats.Options{})
+	return run
+}
+
+func TestRows(t *testing.T) {
+	rows := Rows(testRun(), DefaultCost)
+	if len(rows) != 3 {
+		t.Fatalf("%d rows, want 3", len(rows))
+	}
+	r := rows[0]
+	if r.Label != "ruchy/fibonacci @arm64" || r.MemoryMB != 128 || r.ColdStartMS != 8 || r.WarmP50MS != 12 || r.MaxMemoryMB != 15 ||
+		r.PackageKB != 180 || r.BinaryKB != 401 || r.Traced != 1 || r.TraceInitMS != 7.5 || r.TraceDownstreamMS != 120 {
+		t.Errorf("lambda row = %+v", r)
+	}
+	// Mean billed 59 ms at 128 MB on arm64.
+	want := 59.0/1000*128/1024*0.0000133334*1e6 + 0.20
+	if math.Abs(r.CostPer1M-want) > 1e-9 {
+		t.Errorf("cost = %v, want %v", r.CostPer1M, want)
+	}
+	l := rows[1]
+	if l.Label != "go/fibonacci (local)" || l.WarmP50MS != 35 || l.MaxMemoryMB != 3 || !math.IsNaN(l.ColdStartMS) || l.Traced != 0 || !math.IsNaN(l.CostPer1M) || !math.IsNaN(l.PackageKB) {
+		t.Errorf("local row = %+v", l)
+	}
+	if rows[2].Error == "" || rows[2].Label != "python/fibonacci [n=30]" {
+		t.Errorf("failed row = %+v", rows[2])
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	if err := Markdown(&b, testRun(), Options{CostOptions: DefaultCost}); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"| ruchy/fibonacci @arm64 | arm64 | 128 | 8.00 | 12.00 |",
+		"| 15 | 180 | 401 |",
+		"| go/fibonacci (local) | - | - | - | 35.00 |",
+		`error: not \| deployed`,
+		"1M invocations/month",
+		"| ruchy/fibonacci @arm64 | 1 | 7.50 | 190.00 | 120.00 | 0.50 |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	var b bytes.Buffer
+	if err := HTML(&b, testRun(), Options{CostOptions: DefaultCost}); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	// Cold start, package size and cost have one bar each; warm p50/p99
+	// and memory have two.
+	if n := strings.Count(out, "<rect"); n != 9 {
+		t.Errorf("%d bars, want 9", n)
+	}
+	for _, want := range []string{"<h2>Cold start", "<h2>Package size", "<h2>Cost", "not | deployed", "#d9480f", "<td>190.00</td>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("html missing %q", want)
+		}
+	}
+}
+
+func TestRegional(t *testing.T) {
+	result := func(region string, init float64, warm ...float64) results.Result {
+		r := results.Result{Runtime: "ruchy", Workload: "fibonacci", Kind: "lambda", Region: region,
+			Samples: []results.Sample{{RequestID: "cold", DurationMS: 20, Cold: true, InitMS: init}}}
+		for _, d := range warm {
+			r.Samples = append(r.Samples, results.Sample{RequestID: "warm", DurationMS: d})
+		}
+		return r
+	}
+	run := &results.Run{ID: "multi", Results: []results.Result{
+		result("us-east-1", 10, 2, 2, 2),
+		result("eu-west-1", 20, 4, 4, 4),
+		result("ap-southeast-2", 0),
+		{Runtime: "go", Workload: "fibonacci", Kind: "lambda", Region: "us-east-1", Samples: []results.Sample{{RequestID: "w", DurationMS: 3}}},
+	}}
+	run.Results[2].Error = "not deployed"
+	run.Summarize(stats.Options{})
+
+	rows := Regional(run)
+	if len(rows) != 1 {
+		t.Fatalf("rows = %+v, want ruchy alone: go ran in one region", rows)
+	}
+	r := rows[0]
+	if r.Label != "ruchy/fibonacci" || strings.Join(r.Regions, ",") != "us-east-1,eu-west-1" ||
+		r.ColdStartMS != 15 || r.ColdStartMinMS != 10 || r.ColdStartMaxMS != 20 ||
+		r.WarmP50MS != 3 || r.WarmP50MinMS != 2 || r.WarmP50MaxMS != 4 {
+		t.Errorf("row = %+v", r)
+	}
+	if got := Rows(run, DefaultCost)[1].Label; got != "ruchy/fibonacci (eu-west-1)" {
+		t.Errorf("label = %q", got)
+	}
+	var b bytes.Buffer
+	if err := Markdown(&b, run, Options{CostOptions: DefaultCost}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "| ruchy/fibonacci | us-east-1, eu-west-1 | 15.00 | 10.00 to 20.00 | 3.00 | 2.00 to 4.00 |"; !strings.Contains(b.String(), want) {
+		t.Errorf("markdown missing %q:\n%s", want, b.String())
+	}
+	if Regional(testRun()) != nil {
+		t.Error("single-region run has an across-regions view")
+	}
+}
+
+func TestExclusions(t *testing.T) {
+	run := &results.Run{ID: "flaky", Results: []results.Result{
+		{Runtime: "go", Workload: "fibonacci", Kind: "lambda", Samples: []results.Sample{{RequestID: "a", DurationMS: 2}}},
+		{Runtime: "ruchy", Workload: "fibonacci", Kind: "lambda", Samples: []results.Sample{
+			{RequestID: "a", DurationMS: 2, Retries: 1},
+			{Error: "invoke: TooManyRequestsException", Retries: 3, Excluded: "throttle"},
+			{Error: "invoke: context deadline exceeded", Retries: 3, Excluded: "timeout"},
+			{Error: "invoke: TooManyRequestsException", Retries: 3, Excluded: "throttle"},
+		}},
+	}}
+	run.Summarize(stats.Options{})
+	rows := Exclusions(run)
+	if len(rows) != 1 {
+		t.Fatalf("rows = %+v, want ruchy alone", rows)
+	}
+	r := rows[0]
+	if r.Label != "ruchy/fibonacci" || r.Samples != 4 || r.Retried != 1 || r.Retries != 10 || r.Breakdown() != "3 (throttle 2, timeout 1)" {
+		t.Errorf("row = %+v", r)
+	}
+	var b bytes.Buffer
+	if err := Markdown(&b, run, Options{CostOptions: DefaultCost}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "| ruchy/fibonacci | 4 | 1 | 10 | 3 (throttle 2, timeout 1) |"; !strings.Contains(b.String(), want) {
+		t.Errorf("markdown missing %q:\n%s", want, b.String())
+	}
+	b.Reset()
+	if err := HTML(&b, run, Options{CostOptions: DefaultCost}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "<td>3 (throttle 2, timeout 1)</td>") {
+		t.Error("HTML has no exclusions table")
+	}
+}
+
+func TestComparisons(t *testing.T) {
+	samples := func(ms ...float64) []results.Sample {
+		var ss []results.Sample
+		for _, v := range ms {
+			ss = append(ss, results.Sample{RequestID: "r", DurationMS: v})
+		}
+		return ss
+	}
+	run := &results.Run{ID: "ab", Mode: "lambda", Results: []results.Result{
+		{Runtime: "ruchy", Workload: "fibonacci", Kind: "lambda", Samples: samples(1, 2, 3, 4, 5, 6, 7, 8)},
+		{Runtime: "go", Workload: "fibonacci", Kind: "lambda", Samples: samples(11, 12, 13, 14, 15, 16, 17, 18)},
+		{Runtime: "ruchy", Workload: "json", Kind: "lambda", Samples: samples(1, 3, 5, 7)},
+		{Runtime: "go", Workload: "json", Kind: "lambda", Samples: samples(2, 4, 6, 8)},
+	}}
+	run.Summarize(stats.Options{})
+	rows := Comparisons(run)
+	if len(rows) != 2 {
+		t.Fatalf("rows = %+v, want fibonacci and json", rows)
+	}
+	if r := rows[0]; r.Target != "fibonacci lambda" || r.Runtimes != "ruchy vs go" || r.Delta != -1 || r.Difference != "ruchy faster, large" {
+		t.Errorf("fibonacci = %+v", r)
+	}
+	if r := rows[1]; r.Difference != "not significant (small)" {
+		t.Errorf("json = %+v", r)
+	}
+	var b bytes.Buffer
+	if err := Markdown(&b, run, Options{CostOptions: DefaultCost}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "| fibonacci lambda | ruchy vs go | warm_ms | 4.50 vs 14.50 | -1.00 | <0.001 | ruchy faster, large |"; !strings.Contains(b.String(), want) {
+		t.Errorf("markdown missing %q:\n%s", want, b.String())
+	}
+	b.Reset()
+	if err := HTML(&b, run, Options{CostOptions: DefaultCost}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "<td>ruchy faster, large</td>") {
+		t.Error("HTML has no runtime comparisons")
+	}
+}
+
+func TestTuned(t *testing.T) {
+	sample := results.Sample{RequestID: "a", DurationMS: 12, BilledMS: 12}
+	run := &results.Run{ID: "sweep", Mode: "sweep", Results: []results.Result{
+		{Runtime: "go", Workload: "fibonacci", Kind: "lambda", MemoryMB: 512, Samples: []results.Sample{sample}},
+		{Runtime: "go", Workload: "fibonacci", Kind: "lambda", MemoryMB: 1024, Optimum: "balanced", Samples: []results.Sample{sample}},
+	}}
+	run.Summarize(stats.Options{})
+	rows := Tuned(run, DefaultCost)
+	if len(rows) != 1 || rows[0].Target != "go/fibonacci" || rows[0].MemoryMB != 1024 || rows[0].Strategy != "balanced" {
+		t.Fatalf("rows = %+v, want the 1024 MB optimum", rows)
+	}
+	var b bytes.Buffer
+	if err := Markdown(&b, run, Options{CostOptions: DefaultCost}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "| go/fibonacci | balanced | 1024 | 12.00 |"; !strings.Contains(b.String(), want) {
+		t.Errorf("markdown missing %q:\n%s", want, b.String())
+	}
+	b.Reset()
+	if err := HTML(&b, run, Options{CostOptions: DefaultCost}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "<td>go/fibonacci</td><td>balanced</td><td>1024</td>") {
+		t.Error("HTML has no power tuning table")
+	}
+}
+
+func TestSLOs(t *testing.T) {
+	run := testRun()
+	checks := []slo.Check{
+		{Result: run.Results[0], SLO: manifest.SLO{Metric: results.MetricInit, Stat: "mean", Max: 10, MemoryMB: 128}, Value: 8, N: 1},
+		{Result: run.Results[0], SLO: manifest.SLO{Metric: results.MetricWarm, Stat: "p99", Max: 5}, Value: 13.96, N: 3},
+		{Result: run.Results[2], SLO: manifest.SLO{Metric: results.MetricWarm, Stat: "p99", Max: 5}},
+	}
+	rows := SLORows(checks)
+	if len(rows) != 3 || rows[0].Verdict != "met" || rows[0].SLO != "init_ms mean ≤ 10 at 128 MB" || rows[1].Verdict != "missed" ||
+		rows[2].Verdict != "failed" || rows[2].Value != "-" {
+		t.Errorf("SLORows = %+v", rows)
+	}
+	var b bytes.Buffer
+	if err := Markdown(&b, run, Options{CostOptions: DefaultCost, SLOs: checks}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"SLOs met: python 0 of 1, ruchy 1 of 2.", "| ruchy/fibonacci @arm64 | warm_ms p99 ≤ 5 | 13.96 | missed |"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("markdown missing %q:\n%s", want, b.String())
+		}
+	}
+	b.Reset()
+	if err := HTML(&b, run, Options{CostOptions: DefaultCost, SLOs: checks}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `<td class="error">missed</td>`) {
+		t.Error("html does not mark the missed SLO")
+	}
+	b.Reset()
+	if err := Markdown(&b, run, Options{CostOptions: DefaultCost}); err != nil || strings.Contains(b.String(), "### SLOs") {
+		t.Errorf("SLO section without checks, %v", err)
+	}
+}
+
+func TestBadges(t *testing.T) {
+	run := testRun()
+	other := run.Results[0]
+	other.Runtime, other.BinaryBytes, other.Samples = "go", 6<<20, nil
+	for i, d := range []float64{300, 2, 2, 2} {
+		other.Samples = append(other.Samples, results.Sample{Iteration: i, ClientMS: d, DurationMS: d, BilledMS: d,
+			MemorySizeMB: 128, Cold: i == 0, InitMS: 68})
+	}
+	run.Results = append(run.Results, other)
+	run.Summarize(stats.Options{})
+
+	var got []string
+	for _, b := range Badges(run, BadgeOptions{}) {
+		got = append(got, b.Name+" "+b.Label+": "+b.Message+" ("+b.Color+")")
+	}
+	want := []string{
+		"cold-start-fibonacci-arm64 cold start: ruchy 8.00ms vs go 68.0ms (brightgreen)",
+		"warm-fibonacci-arm64 warm p50: ruchy 12.0ms vs go 2.00ms (orange)",
+		"binary-fibonacci-arm64 binary size: ruchy 401KB vs go 6.0MB (brightgreen)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("badges:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if bs := Badges(run, BadgeOptions{Runtime: "go", Versus: "python"}); len(bs) != 4 || bs[3].Name != "warm-fibonacci-local" || bs[0].Message != "go 68.0ms" || bs[0].Color != "blue" {
+		t.Errorf("go versus python: %+v", bs)
+	}
+}
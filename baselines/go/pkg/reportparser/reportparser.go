@@ -0,0 +1,240 @@
+// Package reportparser extracts the platform REPORT line Lambda writes
+// after every invocation:
+//
+//	REPORT RequestId: <id>	Duration: 1.52 ms	Billed Duration: 11 ms	Memory Size: 128 MB	Max Memory Used: 14 MB	Init Duration: 8.91 ms
+//
+// SnapStart functions report "Restore Duration" and "Billed Restore
+// Duration" in place of the init duration when resumed from a snapshot,
+// and invocations that did not complete end with "Status: timeout", or
+// "Status: error" and the "Error Type", such as Runtime.ExitError.
+//
+// It parses lines from an Invoke log tail or from the function's
+// CloudWatch log group. These are the only source of billed duration and
+// memory figures; the handler response cannot report them. The log group
+// also holds the Go baselines' invocation lines (pkg/lambdalog), which
+// carry the same request ID.
+package reportparser
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+
+	"lambdaperf/pkg/lambdalog"
+	"lambdaperf/pkg/telemetryext"
+)
+
+// Report is one parsed REPORT line.
+type Report struct {
+	RequestID        string
+	DurationMS       float64
+	BilledDurationMS float64
+	MemorySizeMB     int
+	MaxMemoryUsedMB  int
+	// InitDurationMS is only present on cold starts.
+	InitDurationMS float64
+	// RestoreDurationMS and BilledRestoreDurationMS are only present on
+	// SnapStart restores.
+	RestoreDurationMS       float64
+	BilledRestoreDurationMS float64
+	// Status is "timeout" or "error" for an invocation the platform ended
+	// early, empty for the rest; ErrorType says why an errored one did.
+	Status, ErrorType string
+	// Timestamp is the log event time when fetched from CloudWatch.
+	Timestamp time.Time
+}
+
+// Cold reports whether the invocation ran in a new execution environment,
+// either initialized from scratch or restored from a SnapStart snapshot.
+func (r Report) Cold() bool { return r.InitDurationMS > 0 || r.Restored() }
+
+// Restored reports whether the invocation was a SnapStart restore.
+func (r Report) Restored() bool { return r.RestoreDurationMS > 0 }
+
+// ErrNotReport is returned by Parse for lines that are not REPORT lines.
+var ErrNotReport = errors.New("not a REPORT line")
+
+// Parse parses a single REPORT line.
+func Parse(line string) (Report, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "REPORT ") {
+		return Report{}, ErrNotReport
+	}
+	var r Report
+	for _, field := range strings.Split(strings.TrimPrefix(line, "REPORT "), "\t") {
+		key, value, ok := strings.Cut(strings.TrimSpace(field), ": ")
+		if !ok {
+			continue
+		}
+		var err error
+		switch key {
+		case "RequestId":
+			r.RequestID = value
+		case "Duration":
+			r.DurationMS, err = parseUnit(value, "ms")
+		case "Billed Duration":
+			r.BilledDurationMS, err = parseUnit(value, "ms")
+		case "Init Duration":
+			r.InitDurationMS, err = parseUnit(value, "ms")
+		case "Restore Duration":
+			r.RestoreDurationMS, err = parseUnit(value, "ms")
+		case "Billed Restore Duration":
+			r.BilledRestoreDurationMS, err = parseUnit(value, "ms")
+		case "Memory Size":
+			r.MemorySizeMB, err = parseMB(value)
+		case "Max Memory Used":
+			r.MaxMemoryUsedMB, err = parseMB(value)
+		case "Status":
+			r.Status = value
+		case "Error Type":
+			r.ErrorType = value
+		}
+		if err != nil {
+			return Report{}, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if r.RequestID == "" {
+		return Report{}, fmt.Errorf("REPORT line without RequestId: %q", line)
+	}
+	return r, nil
+}
+
+// ParseAll returns every REPORT line found in logs, skipping other lines.
+func ParseAll(logs string) ([]Report, error) {
+	var reports []Report
+	sc := bufio.NewScanner(strings.NewReader(logs))
+	for sc.Scan() {
+		r, err := Parse(sc.Text())
+		if errors.Is(err, ErrNotReport) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, r)
+	}
+	return reports, sc.Err()
+}
+
+// Last returns the final REPORT line in logs, which for an Invoke log tail
+// belongs to that invocation.
+func Last(logs string) (Report, bool) {
+	reports, err := ParseAll(logs)
+	if err != nil || len(reports) == 0 {
+		return Report{}, false
+	}
+	return reports[len(reports)-1], true
+}
+
+func parseUnit(value, unit string) (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(value, unit)), 64)
+}
+
+func parseMB(value string) (int, error) {
+	return strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(value, "MB")))
+}
+
+// LogGroup returns the default CloudWatch log group of a function.
+func LogGroup(function string) string {
+	return "/aws/lambda/" + function
+}
+
+// Fetch pulls every REPORT line logged by function between start and end
+// from its CloudWatch log group.
+func Fetch(ctx context.Context, client cloudwatchlogs.FilterLogEventsAPIClient, function string, start, end time.Time) ([]Report, error) {
+	var reports []Report
+	err := filter(ctx, client, function, `"REPORT RequestId"`, start, end, func(ev types.FilteredLogEvent) error {
+		r, err := Parse(aws.ToString(ev.Message))
+		if errors.Is(err, ErrNotReport) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		r.Timestamp = time.UnixMilli(aws.ToInt64(ev.Timestamp)).UTC()
+		reports = append(reports, r)
+		return nil
+	})
+	return reports, err
+}
+
+// FetchInvocations pulls every invocation line logged by function between
+// start and end, keyed by request ID.
+func FetchInvocations(ctx context.Context, client cloudwatchlogs.FilterLogEventsAPIClient, function string, start, end time.Time) (map[string]lambdalog.Entry, error) {
+	entries := map[string]lambdalog.Entry{}
+	pattern := fmt.Sprintf(`{ $.type = %q }`, lambdalog.Type)
+	err := filter(ctx, client, function, pattern, start, end, func(ev types.FilteredLogEvent) error {
+		if e, ok := lambdalog.Parse(aws.ToString(ev.Message)); ok {
+			entries[e.RequestID] = e
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// FetchTelemetry pulls every line the telemetry extension logged for
+// function between start and end, keyed by request ID.
+func FetchTelemetry(ctx context.Context, client cloudwatchlogs.FilterLogEventsAPIClient, function string, start, end time.Time) (map[string]telemetryext.Line, error) {
+	lines := map[string]telemetryext.Line{}
+	pattern := fmt.Sprintf(`{ $.type = %q }`, telemetryext.Type)
+	err := filter(ctx, client, function, pattern, start, end, func(ev types.FilteredLogEvent) error {
+		if l, ok := telemetryext.Parse(aws.ToString(ev.Message)); ok {
+			lines[l.RequestID] = l
+		}
+		return nil
+	})
+	return lines, err
+}
+
+// Excerpt pulls the first max lines function logged between start and
+// end, platform lines and the handler's alike, each prefixed with its
+// UTC timestamp.
+func Excerpt(ctx context.Context, client cloudwatchlogs.FilterLogEventsAPIClient, function string, start, end time.Time, max int) ([]string, error) {
+	var lines []string
+	err := filter(ctx, client, function, "", start, end, func(ev types.FilteredLogEvent) error {
+		if len(lines) == max {
+			return errExcerpted
+		}
+		ts := time.UnixMilli(aws.ToInt64(ev.Timestamp)).UTC().Format(time.RFC3339Nano)
+		lines = append(lines, ts+" "+strings.TrimRight(aws.ToString(ev.Message), "\n"))
+		return nil
+	})
+	if errors.Is(err, errExcerpted) {
+		err = nil
+	}
+	return lines, err
+}
+
+// errExcerpted stops Excerpt's filter once it has its lines.
+var errExcerpted = errors.New("excerpt complete")
+
+// filter calls fn with every event of function's log group matching
+// pattern between start and end.
+func filter(ctx context.Context, client cloudwatchlogs.FilterLogEventsAPIClient, function, pattern string, start, end time.Time, fn func(types.FilteredLogEvent) error) error {
+	p := cloudwatchlogs.NewFilterLogEventsPaginator(client, &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:  aws.String(LogGroup(function)),
+		FilterPattern: aws.String(pattern),
+		StartTime:     aws.Int64(start.UnixMilli()),
+		EndTime:       aws.Int64(end.UnixMilli()),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("filter %s: %w", LogGroup(function), err)
+		}
+		for _, ev := range page.Events {
+			if err := fn(ev); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
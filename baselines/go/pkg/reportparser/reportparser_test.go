@@ -0,0 +1,94 @@
+package reportparser
+
+import (
+	"errors"
+	"testing"
+)
+
+const coldLine = "REPORT RequestId: 3f2a9c1e-5b2d-4e8a-9f0b-1c2d3e4f5a6b\tDuration: 637.42 ms\tBilled Duration: 646 ms\tMemory Size: 128 MB\tMax Memory Used: 14 MB\tInit Duration: 8.50 ms\t"
+
+func TestParseColdStart(t *testing.T) {
+	r, err := Parse(coldLine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Report{
+		RequestID:        "3f2a9c1e-5b2d-4e8a-9f0b-1c2d3e4f5a6b",
+		DurationMS:       637.42,
+		BilledDurationMS: 646,
+		MemorySizeMB:     128,
+		MaxMemoryUsedMB:  14,
+		InitDurationMS:   8.50,
+	}
+	if r != want {
+		t.Errorf("Parse =\n%+v\nwant\n%+v", r, want)
+	}
+	if !r.Cold() {
+		t.Error("Cold() = false for a line with Init Duration")
+	}
+}
+
+func TestParseWarm(t *testing.T) {
+	r, err := Parse("REPORT RequestId: abc\tDuration: 1.10 ms\tBilled Duration: 2 ms\tMemory Size: 128 MB\tMax Memory Used: 15 MB\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Cold() || r.DurationMS != 1.10 || r.BilledDurationMS != 2 {
+		t.Errorf("Parse = %+v", r)
+	}
+}
+
+func TestParseSnapStartRestore(t *testing.T) {
+	line := "REPORT RequestId: 9d1e\tDuration: 101.69 ms\tBilled Duration: 344 ms\tMemory Size: 128 MB\tMax Memory Used: 42 MB\tRestore Duration: 241.67 ms\tBilled Restore Duration: 242 ms\t"
+	r, err := Parse(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RestoreDurationMS != 241.67 || r.BilledRestoreDurationMS != 242 || r.InitDurationMS != 0 {
+		t.Errorf("Parse = %+v", r)
+	}
+	if !r.Restored() || !r.Cold() {
+		t.Errorf("Restored() = %v, Cold() = %v, want both true", r.Restored(), r.Cold())
+	}
+}
+
+func TestParseEndedEarly(t *testing.T) {
+	r, err := Parse("REPORT RequestId: 7c1b\tDuration: 1000.00 ms\tBilled Duration: 1000 ms\tMemory Size: 128 MB\tMax Memory Used: 19 MB\tStatus: timeout")
+	if err != nil || r.Status != "timeout" || r.ErrorType != "" || r.DurationMS != 1000 {
+		t.Errorf("timed out: %+v, %v", r, err)
+	}
+	r, err = Parse("REPORT RequestId: 7c1c\tDuration: 3.02 ms\tBilled Duration: 4 ms\tMemory Size: 128 MB\tMax Memory Used: 19 MB\tStatus: error\tError Type: Runtime.ExitError")
+	if err != nil || r.Status != "error" || r.ErrorType != "Runtime.ExitError" {
+		t.Errorf("exited: %+v, %v", r, err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	if _, err := Parse("START RequestId: abc Version: $LATEST"); !errors.Is(err, ErrNotReport) {
+		t.Errorf("START line: err = %v, want ErrNotReport", err)
+	}
+	if _, err := Parse("REPORT RequestId: abc\tDuration: fast ms\t"); err == nil {
+		t.Error("malformed duration accepted")
+	}
+	if _, err := Parse("REPORT Duration: 1.0 ms\t"); err == nil {
+		t.Error("REPORT line without RequestId accepted")
+	}
+}
+
+func TestLastPicksFinalReport(t *testing.T) {
+	logs := "START RequestId: a Version: $LATEST\n" +
+		"REPORT RequestId: a\tDuration: 1.00 ms\tBilled Duration: 1 ms\tMemory Size: 128 MB\tMax Memory Used: 14 MB\t\n" +
+		"START RequestId: b Version: $LATEST\n" +
+		"END RequestId: b\n" +
+		coldLine + "\n"
+	r, ok := Last(logs)
+	if !ok {
+		t.Fatal("no report found")
+	}
+	if r.RequestID != "3f2a9c1e-5b2d-4e8a-9f0b-1c2d3e4f5a6b" {
+		t.Errorf("Last = %s, want final REPORT line", r.RequestID)
+	}
+	if _, ok := Last("END RequestId: b\n"); ok {
+		t.Error("Last found a report in logs without one")
+	}
+}
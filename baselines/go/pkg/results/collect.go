@@ -0,0 +1,77 @@
+package results
+
+import (
+	"context"
+
+	"lambdaperf/pkg/progress"
+	"lambdaperf/pkg/stats"
+)
+
+// Collect runs a benchmark loop. measure performs invocation i and returns
+// its sample. Invocations run, flagged Warmup, until w considers the
+// target warmed up, and then n more are recorded, and more after those
+// until p considers the median precise. Steady-state and precision
+// detection follow the REPORT duration where there is one and client time
+// otherwise. steady is false when warm-up gave up without the values
+// settling; see Precise for whether recording did. Every sample is
+// recorded into the progress.Task ctx carries, if any.
+func Collect(ctx context.Context, n int, w stats.Warmup, p stats.Precision, measure func(i int) Sample) (samples []Sample, steady bool) {
+	task := progress.TaskFrom(ctx)
+	var xs []float64
+	for i := 0; ctx.Err() == nil; i++ {
+		done, ok := w.Done(i, xs)
+		if done {
+			steady = ok
+			break
+		}
+		s := measure(i)
+		s.Warmup = true
+		samples = append(samples, s)
+		v, ok := tracked(s)
+		if ok {
+			xs = append(xs, v)
+		}
+		task.Record(v, !ok, true)
+	}
+	warm := len(samples)
+	xs = nil
+	for i := warm; ctx.Err() == nil; i++ {
+		if recorded := i - warm; recorded >= n {
+			if done, _ := p.Done(recorded, xs); done {
+				break
+			}
+		}
+		s := measure(i)
+		samples = append(samples, s)
+		v, ok := tracked(s)
+		if ok {
+			xs = append(xs, v)
+		}
+		task.Record(v, !ok, false)
+	}
+	return samples, steady
+}
+
+// Precise reports whether the recorded samples' median is as precise as p
+// asks, and the 95% confidence interval's half-width relative to it.
+func Precise(samples []Sample, p stats.Precision) (bool, float64) {
+	var xs []float64
+	for _, s := range samples {
+		if v, ok := tracked(s); ok && !s.Warmup {
+			xs = append(xs, v)
+		}
+	}
+	width := stats.RelativeMedianCI(xs)
+	return p.Target == 0 || width <= p.Target, width
+}
+
+// tracked is the value of s Collect follows, if it succeeded.
+func tracked(s Sample) (float64, bool) {
+	if s.Error != "" {
+		return 0, false
+	}
+	if v, ok := s.Value(MetricDuration); ok {
+		return v, true
+	}
+	return s.ClientMS, true
+}
@@ -0,0 +1,855 @@
+// Package results defines the consolidated results file written by
+// ruchy-bench: one Run containing a Result per target, each holding the
+// raw per-invocation samples.
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"lambdaperf/pkg/async"
+	"lambdaperf/pkg/errorpath"
+	"lambdaperf/pkg/lambdalog"
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/schema"
+	"lambdaperf/pkg/stats"
+	"lambdaperf/pkg/stepfn"
+	"lambdaperf/pkg/telemetryext"
+	"lambdaperf/pkg/tracing"
+)
+
+// Run is one execution of the harness. Its JSON form is the results file
+// format pkg/schema publishes.
+type Run struct {
+	// SchemaVersion is the results format's version, schema.Version
+	// once Encode writes the run.
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"`
+	StartedAt     time.Time `json:"started_at"`
+	FinishedAt    time.Time `json:"finished_at"`
+	Mode          string    `json:"mode"`
+	// Metadata records what the run measured, so its numbers can be
+	// traced to the code and toolchains behind them; nil for runs
+	// recorded before it was captured.
+	Metadata *Metadata `json:"metadata,omitempty"`
+	Results  []Result  `json:"results"`
+}
+
+// Metadata is the provenance of a run.
+type Metadata struct {
+	// Commit is the full revision of the repository the run was started
+	// from; Dirty is set when its work tree had changes.
+	Commit string `json:"commit,omitempty"`
+	Dirty  bool   `json:"dirty,omitempty"`
+	// Toolchains holds the local toolchain version of each runtime, by
+	// runtime, as build.ToolchainVersion reports it: "go1.24.2" for go.
+	Toolchains map[string]string `json:"toolchains,omitempty"`
+	// LambdaGo is the aws-lambda-go version the Go baselines build with.
+	LambdaGo string `json:"aws_lambda_go,omitempty"`
+	// Packages holds the CodeSha256 Lambda reports for each function
+	// measured, by function name: the base64 SHA-256 of the package it
+	// ran. Packages are built reproducibly (pkg/pkgzip), so two runs with
+	// equal hashes measured identical code. Runs recorded before it was
+	// captured have none, so MissingMetadata does not require it.
+	Packages map[string]string `json:"packages,omitempty"`
+}
+
+// RequiredToolchains are the runtimes whose toolchain versions every run
+// records, whatever it measured: the Ruchy compiler and the Go toolchain
+// it is measured against.
+var RequiredToolchains = []string{"go", "ruchy"}
+
+// MissingMetadata lists what the run's metadata lacks, such as "commit"
+// or "lambda runtime of baseline-go-fibonacci"; nil when it is complete.
+func (run *Run) MissingMetadata() []string {
+	m := run.Metadata
+	if m == nil {
+		return []string{"metadata"}
+	}
+	var missing []string
+	if m.Commit == "" {
+		missing = append(missing, "commit")
+	}
+	for _, rt := range RequiredToolchains {
+		if m.Toolchains[rt] == "" {
+			missing = append(missing, rt+" toolchain version")
+		}
+	}
+	if m.LambdaGo == "" {
+		missing = append(missing, "aws-lambda-go version")
+	}
+	for _, r := range run.Results {
+		if r.Kind == "lambda" && r.LambdaRuntime == "" {
+			missing = append(missing, "lambda runtime of "+r.Function)
+		}
+	}
+	return missing
+}
+
+// Result is every sample collected for a single target.
+type Result struct {
+	Runtime  string `json:"runtime"`
+	Workload string `json:"workload"`
+	Kind     string `json:"kind"`
+	Arch     string `json:"arch,omitempty"`
+	Function string `json:"function,omitempty"`
+	MemoryMB int32  `json:"memory_mb,omitempty"`
+	// Region is the AWS region a Lambda result was measured in, when the
+	// run named regions (-region); empty means the AWS config's default.
+	Region string `json:"region,omitempty"`
+	// SnapStart is set for results measured on a SnapStart-enabled
+	// published version rather than $LATEST.
+	SnapStart bool `json:"snapstart,omitempty"`
+	// Package is discover.PackageImage for results measured on a function
+	// deployed from a container image; empty for zip packages.
+	Package string `json:"package,omitempty"`
+	// LambdaRuntime identifies the Lambda runtime a Lambda result ran on:
+	// the runtime identifier and, when Lambda reports one, its runtime
+	// version ARN, as "provided.al2023
+	// arn:aws:lambda:eu-west-1::runtime:0b1e...", or the resolved image
+	// URI of an image function.
+	LambdaRuntime string `json:"lambda_runtime,omitempty"`
+	// Extension is set for results measured with the noop-telemetry
+	// extension attached.
+	Extension bool `json:"extension,omitempty"`
+	// VPC is set for results of functions attached to the harness VPC.
+	VPC bool `json:"vpc,omitempty"`
+	// Chaos is set for results measured with the chaos extension
+	// contending for the function's CPU and memory; see pkg/chaos.
+	Chaos bool `json:"chaos,omitempty"`
+	// Serializer is the JSON serializer a Go baseline was built with,
+	// such as "jsoniter"; empty means encoding/json.
+	Serializer string `json:"serializer,omitempty"`
+	// Edge is set for results of Lambda@Edge functions, requested
+	// through CloudFront; see pkg/edge.
+	Edge bool `json:"edge,omitempty"`
+	// Sandbox is the backend a local result ran under, in a sandbox
+	// capped like a function of MemoryMB; see pkg/sandbox. Empty means
+	// the bare host.
+	Sandbox string `json:"sandbox,omitempty"`
+	// Optimum is set, to the power-tuning strategy, on the sweep result
+	// at the memory size that strategy chose for its function; see
+	// pkg/powertune.
+	Optimum string `json:"optimum,omitempty"`
+	// ProvisionedConcurrency is the number of provisioned environments
+	// the result was measured with; zero means on-demand.
+	ProvisionedConcurrency int32 `json:"provisioned_concurrency,omitempty"`
+	// BinaryBytes and PackageBytes are the stripped binary and deployment
+	// zip (or uncompressed image) sizes of the artifact measured, when known. Package size drives
+	// cold start, so it is compared alongside latency.
+	BinaryBytes  int64 `json:"binary_bytes,omitempty"`
+	PackageBytes int64 `json:"package_bytes,omitempty"`
+	// Input holds the workload inputs the payload set, by name, for
+	// results of ruchy-bench scale; empty means the workload's defaults.
+	Input map[string]int `json:"input,omitempty"`
+	// Load describes the load run the samples came from, if any.
+	Load *Load `json:"load,omitempty"`
+	// Burst describes the concurrency ramp the samples came from, if any.
+	Burst *Burst `json:"burst,omitempty"`
+	// Replay describes the recorded traffic the samples replayed, if
+	// any; Load then holds the rate and latency it was replayed with.
+	Replay *Replay `json:"replay,omitempty"`
+	// Imprecise is set when recording was extended towards a precision
+	// target and stopped at its cap short of it: the workload never
+	// stabilized enough for its median to be known that well.
+	Imprecise bool `json:"imprecise,omitempty"`
+	// CPUGeneration is set on the results ByCPU splits a result into,
+	// to the generation of the hosts their samples ran on.
+	CPUGeneration string   `json:"cpu_generation,omitempty"`
+	Samples       []Sample `json:"samples"`
+	Error         string   `json:"error,omitempty"`
+	// Stats summarizes the successful samples per metric.
+	Stats map[string]stats.Summary `json:"stats,omitempty"`
+	// Modes holds, per latency metric, the two modes of a distribution
+	// the dip test found bimodal: invocations split between host
+	// generations or disturbed by noisy neighbors, a platform effect the
+	// runtime should not be charged with. Stats of a metric listed here
+	// cover only its larger mode when the run summarized with
+	// stats.Options.Stratify.
+	Modes map[string]stats.Modes `json:"modes,omitempty"`
+}
+
+// Load is the configuration and outcome of a concurrent load run.
+type Load struct {
+	Workers     int     `json:"workers"`
+	TargetRPS   float64 `json:"target_rps,omitempty"` // zero: closed-loop
+	AchievedRPS float64 `json:"achieved_rps"`
+	DurationMS  float64 `json:"duration_ms"`
+	// Missed counts request slots no worker was free to take.
+	Missed    int `json:"missed"`
+	Throttled int `json:"throttled"`
+	// Client-observed latency quantiles of successful requests, taken from
+	// ClientHistogram with no outlier rejection: under load the tail is
+	// the measurement.
+	ClientP50MS  float64 `json:"client_p50_ms"`
+	ClientP99MS  float64 `json:"client_p99_ms"`
+	ClientP999MS float64 `json:"client_p999_ms"`
+	// ClientHistogram buckets the client-observed latency of successful
+	// requests.
+	ClientHistogram []Bucket `json:"client_histogram,omitempty"`
+}
+
+// Replay is the trace a replay run played and how faithfully it kept to
+// the trace's timing.
+type Replay struct {
+	// Trace is the file the events were read from.
+	Trace  string `json:"trace"`
+	Events int    `json:"events"`
+	// SpanMS is the time from the trace's first event to its last, as
+	// recorded; the replay took SpanMS/Speed plus the last replies.
+	SpanMS float64 `json:"span_ms"`
+	Speed  float64 `json:"speed"`
+	// Late counts events started over loadgen.LateAfter behind their
+	// time because the worker cap was reached, and MaxLagMS is the
+	// furthest behind one started.
+	Late     int     `json:"late"`
+	MaxLagMS float64 `json:"max_lag_ms,omitempty"`
+}
+
+// Burst is the outcome of a stepped concurrency ramp.
+type Burst struct {
+	Levels []BurstLevel `json:"levels"`
+	// What CloudWatch recorded for the function over the whole ramp, if
+	// Metrics says it was read. MetricsComplete is false when it was read
+	// before it had caught up with every invocation sent.
+	Invocations     int  `json:"invocations,omitempty"`
+	Throttles       int  `json:"throttles,omitempty"`
+	PeakConcurrency int  `json:"peak_concurrency,omitempty"`
+	Metrics         bool `json:"metrics"`
+	MetricsComplete bool `json:"metrics_complete,omitempty"`
+}
+
+// BurstLevel is one step of a concurrency ramp.
+type BurstLevel struct {
+	Concurrency int     `json:"concurrency"`
+	Requests    int     `json:"requests"`
+	AchievedRPS float64 `json:"achieved_rps"`
+	Errors      int     `json:"errors"`
+	// Throttled counts the requests Lambda rejected with 429.
+	Throttled int `json:"throttled"`
+	Cold      int `json:"cold"`
+	// ScaleUpMS is how long the level took for every one of its workers
+	// to get a reply; zero, with ScaledUp false, if some never did.
+	ScaleUpMS   float64 `json:"scale_up_ms"`
+	ScaledUp    bool    `json:"scaled_up"`
+	ClientP50MS float64 `json:"client_p50_ms"`
+	ClientP99MS float64 `json:"client_p99_ms"`
+}
+
+// Bucket is one latency histogram bucket.
+type Bucket struct {
+	UpperMS float64 `json:"le_ms"`
+	Count   int     `json:"count"`
+}
+
+// Metric names used as Stats keys.
+const (
+	MetricClient   = "client_ms"
+	MetricDuration = "duration_ms"
+	MetricWarm     = "warm_ms"
+	MetricBilled   = "billed_ms"
+	MetricInit     = "init_ms"
+	MetricRestore  = "restore_ms"
+	// MetricServer is the time Lambda reports the invocation took on its
+	// side: the REPORT duration plus, on a cold start, the init or
+	// restore duration the caller also waited for. MetricOverhead is the
+	// rest of the client time: the network between the harness and the
+	// region, and Lambda's front end routing the request to an execution
+	// environment. A difference between runtimes in client time that is
+	// not in server time is not the runtime's.
+	MetricServer   = "server_ms"
+	MetricOverhead = "overhead_ms"
+	// MetricSDK is time the handler itself reports spending in AWS SDK
+	// calls, for workloads that talk to other services.
+	MetricSDK = "sdk_ms"
+	// MetricDecode is time the handler reports spending decoding the
+	// invocation's event, which Go baselines do by reflection.
+	MetricDecode = "decode_ms"
+	// MetricTTFB is the time to the first byte of a streamed response
+	// body; see ruchy-bench stream.
+	MetricTTFB = "ttfb_ms"
+	// MetricThroughput is the data a handler reports having processed,
+	// in MB (2^20 bytes) per second of its duration, or of client time
+	// where there is no REPORT line.
+	MetricThroughput = "throughput_mb_s"
+	// MetricHTTPRequests, MetricHTTPNewConns and MetricHTTPTLS are what
+	// a handler reports of the HTTP requests it traced: how many it made,
+	// how many opened a connection instead of reusing one, and the time
+	// spent in TLS handshakes.
+	MetricHTTPRequests = "http_requests"
+	MetricHTTPNewConns = "http_new_conns"
+	MetricHTTPTLS      = "http_tls_ms"
+	// MetricWriteThroughput and MetricReadThroughput are the file I/O
+	// throughput a handler reports, in MB (2^20 bytes) per second of the
+	// time spent writing or reading; see ruchy-bench storage.
+	MetricWriteThroughput = "write_mb_s"
+	MetricReadThroughput  = "read_mb_s"
+	// MetricMaxMemory is the REPORT line's max memory used: the peak of
+	// the execution environment so far, not of the one invocation.
+	MetricMaxMemory = "max_memory_mb"
+	// MetricRSS is the peak resident set size of a local run.
+	MetricRSS = "max_rss_kb"
+	// MetricUser and MetricSystem are the CPU time a local run spent in
+	// user and kernel mode.
+	MetricUser   = "user_ms"
+	MetricSystem = "system_ms"
+	// Hardware counters of local runs on Linux, named as perf stat names
+	// them; see pkg/localbench.
+	MetricInstructions = "instructions"
+	MetricCycles       = "cycles"
+	MetricCacheRefs    = "cache-references"
+	MetricCacheMisses  = "cache-misses"
+	MetricBranchMisses = "branch-misses"
+	// X-Ray segment durations of traced invocations; see pkg/tracing.
+	// Invocation includes downstream calls.
+	MetricTraceInit       = "trace_init_ms"
+	MetricTraceInvocation = "trace_invocation_ms"
+	MetricTraceOverhead   = "trace_overhead_ms"
+	MetricTraceDownstream = "trace_downstream_ms"
+	// Go runtime activity during invocations of Go baselines deployed
+	// with runtime sampling; see pkg/lambdalog.GoRuntime.
+	MetricGoAllocBytes = "go_alloc_bytes"
+	MetricGoAllocs     = "go_allocs"
+	MetricGoGCCycles   = "go_gc_cycles"
+	MetricGoGCPause    = "go_gc_pause_ms"
+	MetricGoGoroutines = "go_goroutines"
+	MetricGoHeapBytes  = "go_heap_bytes"
+	// The Go side of cold starts of Go baselines, recorded on an
+	// environment's first invocation; see pkg/lambdalog.GoInit.
+	MetricGoInitToHandler = "go_init_to_handler_ms"
+	MetricGoFirstDecode   = "go_first_decode_ms"
+	// Telemetry API phase timings of invocations of functions deployed
+	// with the telemetry extension; see pkg/telemetryext. Init is only
+	// recorded on an environment's first invocation.
+	MetricTelemetryInit            = "telemetry_init_ms"
+	MetricTelemetryRuntime         = "telemetry_runtime_ms"
+	MetricTelemetryResponseLatency = "telemetry_response_latency_ms"
+	MetricTelemetryResponse        = "telemetry_response_ms"
+	MetricTelemetryOverhead        = "telemetry_overhead_ms"
+	// Step Functions executions chaining invocations of a baseline; see
+	// pkg/stepfn. Execution is the execution's duration, steps the server
+	// time of its invocations and transition the rest, per step.
+	MetricSFNExecution  = "sfn_execution_ms"
+	MetricSFNSteps      = "sfn_steps_ms"
+	MetricSFNTransition = "sfn_transition_ms"
+	// The median, p99 and standard deviation of the workload's run times
+	// within each invocation of Go baselines deployed with a histogram
+	// budget; see lambdalog.Histogram. They show the variance of the
+	// work itself, which one duration per invocation cannot.
+	MetricIterationP50    = "iteration_p50_ms"
+	MetricIterationP99    = "iteration_p99_ms"
+	MetricIterationStddev = "iteration_stddev_ms"
+)
+
+// Metrics lists every metric in reporting order.
+var Metrics = []string{MetricClient, MetricDuration, MetricWarm, MetricBilled, MetricInit, MetricRestore, MetricServer, MetricOverhead, MetricSDK, MetricDecode,
+	MetricTTFB, MetricThroughput, MetricHTTPRequests, MetricHTTPNewConns, MetricHTTPTLS, MetricWriteThroughput, MetricReadThroughput, MetricMaxMemory, MetricRSS, MetricUser, MetricSystem, MetricInstructions, MetricCycles, MetricCacheRefs, MetricCacheMisses, MetricBranchMisses,
+	MetricTraceInit, MetricTraceInvocation, MetricTraceOverhead, MetricTraceDownstream,
+	MetricGoAllocBytes, MetricGoAllocs, MetricGoGCCycles, MetricGoGCPause, MetricGoGoroutines, MetricGoHeapBytes,
+	MetricGoInitToHandler, MetricGoFirstDecode,
+	MetricTelemetryInit, MetricTelemetryRuntime, MetricTelemetryResponseLatency, MetricTelemetryResponse, MetricTelemetryOverhead,
+	MetricSFNExecution, MetricSFNSteps, MetricSFNTransition,
+	MetricIterationP50, MetricIterationP99, MetricIterationStddev}
+
+// Values returns metric for every successful sample that recorded it.
+// Warm-up samples only count towards the cold start metrics: a cold start
+// is measured the same whether or not warm-up follows it.
+func (r Result) Values(metric string) []float64 {
+	var xs []float64
+	for _, s := range r.Samples {
+		if s.Warmup && !coldMetrics[metric] {
+			continue
+		}
+		if v, ok := s.Value(metric); ok && s.Error == "" {
+			xs = append(xs, v)
+		}
+	}
+	return xs
+}
+
+// Iterations merges the histograms of r's successful samples, other than
+// warm-up ones, into one of every run of the workload they timed. It is
+// nil when none has one.
+func (r Result) Iterations() *lambdalog.Histogram {
+	var h *lambdalog.Histogram
+	for _, s := range r.Samples {
+		if s.Histogram == nil || s.Warmup || s.Error != "" {
+			continue
+		}
+		if h == nil {
+			h = &lambdalog.Histogram{}
+		}
+		h.Merge(s.Histogram)
+	}
+	return h
+}
+
+// coldMetrics are recorded on cold starts only.
+var coldMetrics = map[string]bool{
+	MetricInit:            true,
+	MetricRestore:         true,
+	MetricGoInitToHandler: true,
+	MetricGoFirstDecode:   true,
+}
+
+// InputLabel renders Input as sorted name=value pairs, "" when empty.
+func (r Result) InputLabel() string {
+	names := slices.Sorted(maps.Keys(r.Input))
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%d", name, r.Input[name])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Memory is the memory size in MB the result ran at: the configured size
+// when the harness set one, otherwise the first size Lambda reported. Zero
+// for local results.
+func (r Result) Memory() int32 {
+	if r.MemoryMB != 0 {
+		return r.MemoryMB
+	}
+	for _, s := range r.Samples {
+		if s.MemorySizeMB != 0 {
+			return int32(s.MemorySizeMB)
+		}
+	}
+	return 0
+}
+
+// ModalMetrics are the latency metrics Summarize tests for bimodality,
+// client time for local results and REPORT duration for Lambda ones.
+// Other metrics are stratified by neither: a bimodal init time is cold
+// starts with and without a cached image, and counters are not timings.
+var ModalMetrics = []string{MetricClient, MetricDuration}
+
+// Summarize recomputes Stats for every metric that has data, and Modes
+// for the ModalMetrics. opts.Stratify applies to those alone.
+func (r *Result) Summarize(opts stats.Options) {
+	r.Stats, r.Modes = nil, nil
+	for _, m := range Metrics {
+		xs := r.Values(m)
+		if len(xs) == 0 {
+			continue
+		}
+		if r.Stats == nil {
+			r.Stats = map[string]stats.Summary{}
+		}
+		o := opts
+		if slices.Contains(ModalMetrics, m) {
+			kept, _ := stats.Reject(xs, opts)
+			if modes, ok := stats.Bimodal(kept); ok {
+				if r.Modes == nil {
+					r.Modes = map[string]stats.Modes{}
+				}
+				r.Modes[m] = modes
+			}
+		} else {
+			o.Stratify = false
+		}
+		r.Stats[m] = stats.Summarize(xs, o)
+	}
+}
+
+// CPUGenerations returns the distinct CPU generations r's successful
+// samples report, sorted.
+func (r Result) CPUGenerations() []string {
+	var gens []string
+	for _, s := range r.Samples {
+		if s.Error == "" && s.Host != nil && s.Host.CPUGeneration != "" && !slices.Contains(gens, s.Host.CPUGeneration) {
+			gens = append(gens, s.Host.CPUGeneration)
+		}
+	}
+	slices.Sort(gens)
+	return gens
+}
+
+// ByCPU splits r into one result per CPU generation its samples ran on,
+// in the order of CPUGenerations, each summarized under opts. Samples
+// that report no host, from runtimes that do not, are left out; a result
+// none of whose samples do is returned whole.
+func (r Result) ByCPU(opts stats.Options) []Result {
+	gens := r.CPUGenerations()
+	if len(gens) == 0 {
+		return []Result{r}
+	}
+	split := make([]Result, len(gens))
+	for i, gen := range gens {
+		split[i] = r
+		split[i].CPUGeneration = gen
+		split[i].Samples = slices.DeleteFunc(slices.Clone(r.Samples), func(s Sample) bool {
+			return s.Host == nil || s.Host.CPUGeneration != gen
+		})
+		split[i].Summarize(opts)
+	}
+	return split
+}
+
+// Summarize recomputes Stats on every result.
+func (run *Run) Summarize(opts stats.Options) {
+	for i := range run.Results {
+		run.Results[i].Summarize(opts)
+	}
+}
+
+// Sample is one invocation.
+type Sample struct {
+	Iteration int     `json:"iteration"`
+	ClientMS  float64 `json:"client_ms"`
+	// The remaining metrics come from the Lambda REPORT line.
+	RequestID    string  `json:"request_id,omitempty"`
+	DurationMS   float64 `json:"duration_ms,omitempty"`
+	BilledMS     float64 `json:"billed_ms,omitempty"`
+	InitMS       float64 `json:"init_ms,omitempty"`
+	RestoreMS    float64 `json:"restore_ms,omitempty"`
+	MemorySizeMB int     `json:"memory_size_mb,omitempty"`
+	MaxMemoryMB  int     `json:"max_memory_mb,omitempty"`
+	Cold         bool    `json:"cold,omitempty"`
+	// Warmup is set on invocations made before recording began; see
+	// Collect.
+	Warmup bool `json:"warmup,omitempty"`
+	// SDKMS comes from the handler's response; see WithResponse.
+	SDKMS    float64 `json:"sdk_ms,omitempty"`
+	DecodeMS float64 `json:"decode_ms,omitempty"`
+	// TTFBMS is set on streamed invocations, whose ClientMS is the
+	// time to the end of the body.
+	TTFBMS float64 `json:"ttfb_ms,omitempty"`
+	// Bytes is the data the handler's response says it processed; see
+	// WithResponse.
+	Bytes int64 `json:"bytes,omitempty"`
+	// HTTP holds the http_* metrics of the requests the handler traced;
+	// see WithResponse.
+	HTTP map[string]float64 `json:"http,omitempty"`
+	// IO holds the write_mb_s and read_mb_s metrics of the file I/O the
+	// handler timed; see WithResponse.
+	IO map[string]float64 `json:"io,omitempty"`
+	// Deliveries is how many times a queued message or asynchronous event
+	// reached a handler: more than one when it failed and was redelivered
+	// or retried. See pkg/queue and pkg/async.
+	Deliveries int `json:"deliveries,omitempty"`
+	// QueuedMS is how long an asynchronous event waited between being
+	// submitted and its first attempt starting.
+	QueuedMS float64 `json:"queued_ms,omitempty"`
+	// DeadLetter is Lambda's record of an asynchronous event it gave up
+	// on, whose sample's client_ms is the time until it did.
+	DeadLetter *async.DeadLetter `json:"dead_letter,omitempty"`
+	// MaxRSSKB, UserMS, SystemMS and Counters are measured on local runs;
+	// see pkg/localbench.
+	MaxRSSKB int64              `json:"max_rss_kb,omitempty"`
+	UserMS   float64            `json:"user_ms,omitempty"`
+	SystemMS float64            `json:"system_ms,omitempty"`
+	Counters map[string]float64 `json:"counters,omitempty"`
+	// Segments holds the trace metrics of invocations X-Ray sampled; see
+	// WithTrace.
+	Segments map[string]float64 `json:"segments,omitempty"`
+	// GoRuntime holds the go_* metrics a Go baseline reported in its
+	// response: runtime activity when sampling it, and the init split
+	// on a cold start. See WithResponse.
+	GoRuntime map[string]float64 `json:"go_runtime,omitempty"`
+	// Telemetry holds the telemetry_* metrics of invocations the telemetry
+	// extension logged; see WithTelemetry.
+	Telemetry map[string]float64 `json:"telemetry,omitempty"`
+	// Orchestration holds the sfn_* metrics of a Step Functions
+	// execution; see WithExecution.
+	Orchestration map[string]float64 `json:"orchestration,omitempty"`
+	// Host is the hardware the handler's response says it ran on; see
+	// WithResponse.
+	Host *lambdalog.Host `json:"host,omitempty"`
+	// Histogram holds the times of the workload's runs within the
+	// invocation, from handlers with a histogram budget; see
+	// WithResponse.
+	Histogram *lambdalog.Histogram `json:"histogram,omitempty"`
+	// Surface is how the failure of an invocation of a workload that
+	// fails by design reached the caller; see pkg/errorpath.
+	Surface  *errorpath.Surface `json:"surface,omitempty"`
+	Response string             `json:"response,omitempty"`
+	Error    string             `json:"error,omitempty"`
+	// Retries is how many transiently failed attempts were retried before
+	// the one the sample measured.
+	Retries int `json:"retries,omitempty"`
+	// Excluded is set, to the class of failure such as "throttle", on a
+	// sample whose invocation still failed transiently after its retries.
+	// Like any failed sample it is left out of the statistics, but it
+	// says nothing about the target: reports count it apart.
+	Excluded string `json:"excluded,omitempty"`
+}
+
+// Value returns the named metric and whether the sample recorded it.
+// REPORT-line metrics are absent on samples without a request ID, init
+// and restore durations only exist on cold starts (restore only under
+// SnapStart), warm duration excludes them, hardware counters are only
+// present where the machine could count them, trace segments only on
+// sampled invocations, HTTP metrics only from handlers tracing requests,
+// I/O throughput only from handlers timing file I/O,
+// Go runtime metrics only from handlers sampling them, iteration metrics
+// only from handlers with a histogram budget, telemetry only from functions with the telemetry extension
+// and orchestration metrics only from Step Functions executions.
+func (s Sample) Value(metric string) (float64, bool) {
+	switch metric {
+	case MetricClient:
+		return s.ClientMS, true
+	case MetricDuration:
+		return s.DurationMS, s.RequestID != ""
+	case MetricWarm:
+		return s.DurationMS, s.RequestID != "" && !s.Cold
+	case MetricBilled:
+		return s.BilledMS, s.RequestID != ""
+	case MetricInit:
+		return s.InitMS, s.InitMS > 0
+	case MetricRestore:
+		return s.RestoreMS, s.RestoreMS > 0
+	case MetricServer:
+		return s.DurationMS + s.InitMS + s.RestoreMS, s.RequestID != ""
+	case MetricOverhead:
+		return s.ClientMS - s.DurationMS - s.InitMS - s.RestoreMS, s.RequestID != "" && s.ClientMS > 0
+	case MetricSDK:
+		return s.SDKMS, s.SDKMS > 0
+	case MetricDecode:
+		return s.DecodeMS, s.DecodeMS > 0
+	case MetricTTFB:
+		return s.TTFBMS, s.TTFBMS > 0
+	case MetricThroughput:
+		ms := s.ClientMS
+		if s.RequestID != "" {
+			ms = s.DurationMS
+		}
+		return float64(s.Bytes) / (1 << 20) / (ms / 1000), s.Bytes > 0 && ms > 0
+	case MetricMaxMemory:
+		return float64(s.MaxMemoryMB), s.RequestID != "" && s.MaxMemoryMB > 0
+	case MetricRSS:
+		return float64(s.MaxRSSKB), s.MaxRSSKB > 0
+	case MetricUser:
+		return s.UserMS, s.UserMS > 0
+	case MetricSystem:
+		return s.SystemMS, s.SystemMS > 0
+	case MetricIterationP50:
+		return s.Histogram.QuantileMS(0.5), s.Histogram != nil && s.Histogram.Count > 0
+	case MetricIterationP99:
+		return s.Histogram.QuantileMS(0.99), s.Histogram != nil && s.Histogram.Count > 0
+	case MetricIterationStddev:
+		return s.Histogram.StddevMS(), s.Histogram != nil && s.Histogram.Count > 0
+	}
+	if v, ok := s.Counters[metric]; ok {
+		return v, true
+	}
+	if v, ok := s.Segments[metric]; ok {
+		return v, true
+	}
+	if v, ok := s.HTTP[metric]; ok {
+		return v, true
+	}
+	if v, ok := s.IO[metric]; ok {
+		return v, true
+	}
+	if v, ok := s.GoRuntime[metric]; ok {
+		return v, true
+	}
+	if v, ok := s.Telemetry[metric]; ok {
+		return v, true
+	}
+	v, ok := s.Orchestration[metric]
+	return v, ok
+}
+
+// WithReport copies the REPORT line metrics into the sample.
+func (s Sample) WithReport(r reportparser.Report) Sample {
+	s.RequestID = r.RequestID
+	s.DurationMS = r.DurationMS
+	s.BilledMS = r.BilledDurationMS
+	s.InitMS = r.InitDurationMS
+	s.RestoreMS = r.RestoreDurationMS
+	s.MemorySizeMB = r.MemorySizeMB
+	s.MaxMemoryMB = r.MaxMemoryUsedMB
+	s.Cold = r.Cold()
+	return s
+}
+
+// WithTrace copies the segment breakdown of the invocation's trace into
+// the sample. Initialization is only recorded on cold starts.
+func (s Sample) WithTrace(b tracing.Breakdown) Sample {
+	s.Segments = map[string]float64{
+		MetricTraceInvocation: b.InvocationMS,
+		MetricTraceOverhead:   b.OverheadMS,
+		MetricTraceDownstream: b.DownstreamMS(),
+	}
+	if b.InitMS > 0 {
+		s.Segments[MetricTraceInit] = b.InitMS
+	}
+	return s
+}
+
+// WithTelemetry copies the phase timings of the invocation's telemetry
+// line into the sample. Initialization is only recorded on an
+// environment's first invocation.
+func (s Sample) WithTelemetry(l telemetryext.Line) Sample {
+	s.Telemetry = map[string]float64{
+		MetricTelemetryRuntime:         l.RuntimeMS,
+		MetricTelemetryResponseLatency: l.ResponseLatencyMS,
+		MetricTelemetryResponse:        l.ResponseMS,
+		MetricTelemetryOverhead:        l.OverheadMS,
+	}
+	if l.InitMS > 0 {
+		s.Telemetry[MetricTelemetryInit] = l.InitMS
+	}
+	return s
+}
+
+// WithExecution copies the breakdown of a Step Functions execution into
+// the sample, along with the handler's response to its last step.
+func (s Sample) WithExecution(b stepfn.Breakdown) Sample {
+	s.Orchestration = map[string]float64{
+		MetricSFNExecution:  b.ExecutionMS,
+		MetricSFNSteps:      b.StepsMS,
+		MetricSFNTransition: b.TransitionMS(),
+	}
+	s.Response = string(b.Response)
+	return s
+}
+
+// WrongResult prefixes the error of a sample whose handler responded with
+// something other than the expected result; see Verify.
+const WrongResult = "wrong result"
+
+// Verify fails a successful sample whose response does not report
+// expected, as a handler that returns the wrong answer (a broken build or
+// deployment) says nothing about the workload's performance. An empty
+// expected accepts any response.
+func (s Sample) Verify(expected string) Sample {
+	if s.Error != "" || expected == "" {
+		return s
+	}
+	if got := Body([]byte(s.Response)); got != expected {
+		s.Error = fmt.Sprintf("%s %q, want %q", WrongResult, truncate(got, 80), expected)
+	}
+	return s
+}
+
+// Wrong reports whether the sample failed Verify.
+func (s Sample) Wrong() bool { return strings.HasPrefix(s.Error, WrongResult) }
+
+// Body extracts the result a handler response reports: the "body" of an
+// API-style {"statusCode", "body"} response, a bare JSON string, or
+// otherwise the payload itself (text responses such as the minimal C++
+// handler's and local programs' output).
+func Body(payload []byte) string {
+	var resp struct {
+		Body *string `json:"body"`
+	}
+	if json.Unmarshal(payload, &resp) == nil && resp.Body != nil {
+		return strings.TrimSpace(*resp.Body)
+	}
+	var s string
+	if json.Unmarshal(payload, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	return strings.TrimSpace(string(payload))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// WithResponse stores the handler response in the sample, picking up the
+// "sdk_ms" field handlers that call other services include in it, the
+// "decode_ms" field of handlers that time their event decoding, the
+// "bytes" field of handlers that report throughput, the "http" object of
+// handlers that trace their requests, and the "go_runtime", "go_init",
+// "host" and "histogram" objects of Go baselines.
+func (s Sample) WithResponse(payload []byte) Sample {
+	s.Response = string(payload)
+	var timing struct {
+		SDKMS     float64              `json:"sdk_ms"`
+		DecodeMS  float64              `json:"decode_ms"`
+		Bytes     int64                `json:"bytes"`
+		HTTP      map[string]float64   `json:"http"`
+		IO        map[string]float64   `json:"io"`
+		GoRuntime map[string]float64   `json:"go_runtime"`
+		GoInit    map[string]float64   `json:"go_init"`
+		Host      *lambdalog.Host      `json:"host"`
+		Histogram *lambdalog.Histogram `json:"histogram"`
+	}
+	if json.Unmarshal(payload, &timing) == nil {
+		s.SDKMS, s.DecodeMS, s.Bytes, s.HTTP, s.IO = timing.SDKMS, timing.DecodeMS, timing.Bytes, timing.HTTP, timing.IO
+		s.GoRuntime = timing.GoRuntime
+		if len(timing.GoInit) > 0 && s.GoRuntime == nil {
+			s.GoRuntime = map[string]float64{}
+		}
+		maps.Copy(s.GoRuntime, timing.GoInit)
+		s.Host, s.Histogram = timing.Host, timing.Histogram
+	}
+	return s
+}
+
+// NewRun starts a run with an ID derived from the start time.
+func NewRun(mode string, now time.Time) *Run {
+	return &Run{
+		ID:        now.UTC().Format("20060102T150405Z"),
+		StartedAt: now.UTC(),
+		Mode:      mode,
+	}
+}
+
+// Milliseconds converts a duration to fractional milliseconds.
+func Milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Encode returns run as an indented JSON results file of the current
+// schema version.
+func Encode(run *Run) ([]byte, error) {
+	run.SchemaVersion = schema.Version
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Decode parses a results file of any schema version, migrating an
+// older one first.
+func Decode(data []byte) (*Run, error) {
+	data, _, err := schema.Upgrade(data)
+	if err != nil {
+		return nil, err
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Write stores the run as indented JSON at path, creating parent
+// directories as needed.
+func Write(path string, run *Run) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := Encode(run)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Read loads a run previously stored with Write.
+func Read(path string) (*Run, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	run, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return run, nil
+}
@@ -0,0 +1,347 @@
+package results
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"lambdaperf/pkg/lambdalog"
+	"lambdaperf/pkg/schema"
+	"lambdaperf/pkg/stats"
+	"lambdaperf/pkg/stepfn"
+	"lambdaperf/pkg/telemetryext"
+)
+
+func TestBody(t *testing.T) {
+	for payload, want := range map[string]string{
+		`{"statusCode":200,"body":"sieve(10000000)=664579"}`: "sieve(10000000)=664579",
+		`"fibonacci(35)=9227465"`:                            "fibonacci(35)=9227465",
+		"fibonacci(35)=9227465\n":                            "fibonacci(35)=9227465",
+		`{"statusCode":200}`:                                 `{"statusCode":200}`,
+	} {
+		if got := Body([]byte(payload)); got != want {
+			t.Errorf("Body(%s) = %q, want %q", payload, got, want)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const want = "fibonacci(35)=9227465"
+	ok := Sample{ClientMS: 500}.WithResponse([]byte(`{"statusCode":200,"body":"fibonacci(35)=9227465"}`))
+	if s := ok.Verify(want); s.Error != "" {
+		t.Errorf("correct response failed: %s", s.Error)
+	}
+
+	s := Sample{ClientMS: 2}.WithResponse([]byte(`{"statusCode":200,"body":"fibonacci(35)=0"}`)).Verify(want)
+	if !s.Wrong() || !strings.Contains(s.Error, `"fibonacci(35)=0"`) {
+		t.Errorf("wrong response: error = %q", s.Error)
+	}
+	r := Result{Samples: []Sample{ok, s}}
+	if xs := r.Values(MetricClient); len(xs) != 1 || xs[0] != 500 {
+		t.Errorf("client_ms values = %v, want only the correct sample", xs)
+	}
+
+	failed := Sample{Error: "Unhandled"}.Verify(want)
+	if failed.Error != "Unhandled" || failed.Wrong() {
+		t.Errorf("function error rewritten: %q", failed.Error)
+	}
+	if s := (Sample{Response: "anything"}).Verify(""); s.Error != "" {
+		t.Errorf("empty expected rejected a response: %q", s.Error)
+	}
+}
+
+func TestCollect(t *testing.T) {
+	// A cold start, then durations settling at 10 ms.
+	durations := []float64{300, 40, 15, 10, 10.1, 9.9, 10, 10.2, 10, 10, 10, 10, 10, 10}
+	measure := func(i int) Sample {
+		s := Sample{Iteration: i, ClientMS: durations[i] + 20, RequestID: "req", DurationMS: durations[i]}
+		if i == 0 {
+			s.Cold, s.InitMS = true, 90
+		}
+		return s
+	}
+	samples, steady := Collect(context.Background(), 4, stats.Warmup{Min: 1, CV: 0.05, Window: 4}, stats.Precision{}, measure)
+	if !steady || len(samples) != 11 {
+		t.Fatalf("%d samples (steady %v), want 7 warm-up and 4 recorded", len(samples), steady)
+	}
+	for i, s := range samples {
+		if s.Iteration != i || s.Warmup != (i < 7) {
+			t.Errorf("sample %d = %+v", i, s)
+		}
+	}
+	r := Result{Samples: samples}
+	if warm := r.Values(MetricWarm); len(warm) != 4 || warm[0] != 10.2 {
+		t.Errorf("warm values = %v, want the 4 recorded", warm)
+	}
+	if init := r.Values(MetricInit); len(init) != 1 || init[0] != 90 {
+		t.Errorf("init values = %v, want the warm-up cold start", init)
+	}
+
+	if samples, steady := Collect(context.Background(), 3, stats.Warmup{}, stats.Precision{}, measure); len(samples) != 3 || samples[0].Warmup || !steady {
+		t.Errorf("without warm-up: %d samples, first %+v", len(samples), samples[0])
+	}
+
+	// Asked for 4, recording goes on until the median is within ±2%.
+	p := stats.Precision{Target: 0.02, Max: 40}
+	samples, _ = Collect(context.Background(), 4, stats.Warmup{Min: 3}, p, measure)
+	if len(samples) != 9 {
+		t.Errorf("%d samples, want 3 warm-up and the 6 a median interval needs", len(samples))
+	}
+	if ok, width := Precise(samples, p); !ok || width > 0.02 {
+		t.Errorf("precise %v at ±%.1f%%", ok, 100*width)
+	}
+	noisy := func(i int) Sample { return Sample{Iteration: i, ClientMS: float64(1 + i%2*i)} }
+	samples, _ = Collect(context.Background(), 4, stats.Warmup{}, p, noisy)
+	if ok, _ := Precise(samples, p); len(samples) != 40 || ok {
+		t.Errorf("noisy: %d samples, precise %v; want the cap of 40, imprecise", len(samples), ok)
+	}
+}
+
+func TestWithResponse(t *testing.T) {
+	s := Sample{}.WithResponse([]byte(`{"statusCode":200,"body":"ok","sdk_ms":4.5,"decode_ms":0.125,` +
+		`"go_runtime":{"go_alloc_bytes":8192,"go_gc_cycles":0,"go_gc_pause_ms":0.25}}`))
+	if s.SDKMS != 4.5 {
+		t.Errorf("sdk_ms = %g", s.SDKMS)
+	}
+	if v, ok := s.Value(MetricDecode); !ok || v != 0.125 {
+		t.Errorf("%s = %g, %v", MetricDecode, v, ok)
+	}
+	if v, ok := s.Value(MetricGoAllocBytes); !ok || v != 8192 {
+		t.Errorf("%s = %g, %v", MetricGoAllocBytes, v, ok)
+	}
+	if v, ok := s.Value(MetricGoGCCycles); !ok || v != 0 {
+		t.Errorf("%s = %g, %v; want a recorded zero", MetricGoGCCycles, v, ok)
+	}
+	if _, ok := s.Value(MetricGoGoroutines); ok {
+		t.Errorf("%s present though the response had none", MetricGoGoroutines)
+	}
+	if _, ok := s.Value(MetricThroughput); ok {
+		t.Errorf("%s present though the response reported no bytes", MetricThroughput)
+	}
+
+	// 5 MB in a 250 ms REPORT duration, whatever the client waited.
+	compressed := Sample{ClientMS: 400, RequestID: "r", DurationMS: 250}.WithResponse([]byte(`{"statusCode":200,"body":"ok","bytes":5242880}`))
+	if v, ok := compressed.Value(MetricThroughput); !ok || v != 20 {
+		t.Errorf("%s = %g, %v; want 20", MetricThroughput, v, ok)
+	}
+	// A warm invocation reusing every connection records zero new ones.
+	traced := Sample{}.WithResponse([]byte(`{"statusCode":200,"body":"ok","http":{"http_requests":40,"http_new_conns":0,"http_tls_ms":0}}`))
+	if v, ok := traced.Value(MetricHTTPNewConns); !ok || v != 0 {
+		t.Errorf("%s = %g, %v; want a recorded zero", MetricHTTPNewConns, v, ok)
+	}
+	if v, ok := traced.Value(MetricHTTPRequests); !ok || v != 40 {
+		t.Errorf("%s = %g, %v", MetricHTTPRequests, v, ok)
+	}
+	if _, ok := s.Value(MetricHTTPTLS); ok {
+		t.Errorf("%s present though the response traced no requests", MetricHTTPTLS)
+	}
+	files := Sample{}.WithResponse([]byte(`{"statusCode":200,"body":"ok","io":{"write_mb_s":180.5,"read_mb_s":1250}}`))
+	if v, ok := files.Value(MetricReadThroughput); !ok || v != 1250 {
+		t.Errorf("%s = %g, %v; want 1250", MetricReadThroughput, v, ok)
+	}
+	if _, ok := s.Value(MetricWriteThroughput); ok {
+		t.Errorf("%s present though the response timed no I/O", MetricWriteThroughput)
+	}
+	if s := (Sample{}).WithResponse([]byte(`"fibonacci(35)=9227465"`)); s.GoRuntime != nil {
+		t.Errorf("plain response read as Go runtime stats: %v", s.GoRuntime)
+	}
+
+	// The init split of a cold start counts even on a warm-up sample.
+	cold := Sample{Warmup: true, Cold: true}.WithResponse([]byte(`{"statusCode":200,"body":"ok",` +
+		`"go_init":{"go_init_to_handler_ms":1.25,"go_first_decode_ms":0.05}}`))
+	r := Result{Samples: []Sample{cold}}
+	if xs := r.Values(MetricGoInitToHandler); len(xs) != 1 || xs[0] != 1.25 {
+		t.Errorf("%s = %v", MetricGoInitToHandler, xs)
+	}
+	if xs := r.Values(MetricGoFirstDecode); len(xs) != 1 || xs[0] != 0.05 {
+		t.Errorf("%s = %v", MetricGoFirstDecode, xs)
+	}
+
+	// 99 runs of 1 ms and one of 3 ms.
+	looped := `{"statusCode":200,"body":"ok","histogram":{"budget_ms":200,"count":100,"sum_ns":102000000,` +
+		`"min_ns":1000000,"max_ns":3000000,"counts":[[999424,99],[2998272,1]]}}`
+	r = Result{Samples: []Sample{Sample{}.WithResponse([]byte(looped)), cold, Sample{}.WithResponse([]byte(looped))}}
+	if xs := r.Values(MetricIterationP50); len(xs) != 2 || math.Abs(xs[0]-1) > 0.01 {
+		t.Errorf("%s = %v", MetricIterationP50, xs)
+	}
+	if v, _ := r.Samples[0].Value(MetricIterationP99); math.Abs(v-1) > 0.01 {
+		t.Errorf("%s = %g", MetricIterationP99, v)
+	}
+	if h := r.Iterations(); h == nil || h.Count != 200 || h.BudgetMS != 400 || math.Abs(h.QuantileMS(0.999)-3) > 0.01 {
+		t.Errorf("iterations = %+v", h)
+	}
+	if _, ok := cold.Value(MetricIterationStddev); ok || (Result{Samples: []Sample{cold}}).Iterations() != nil {
+		t.Error("iteration metrics without a histogram")
+	}
+}
+
+func TestOverhead(t *testing.T) {
+	// A cold start waited for its 90 ms init as well as its duration.
+	cold := Sample{ClientMS: 135, RequestID: "r", DurationMS: 20, InitMS: 90, Cold: true}
+	warm := Sample{ClientMS: 31.5, RequestID: "r", DurationMS: 12}
+	for _, tc := range []struct {
+		s              Sample
+		server, remain float64
+	}{{cold, 110, 25}, {warm, 12, 19.5}} {
+		if v, ok := tc.s.Value(MetricServer); !ok || v != tc.server {
+			t.Errorf("%s of %+v = %g, %v; want %g", MetricServer, tc.s, v, ok, tc.server)
+		}
+		if v, ok := tc.s.Value(MetricOverhead); !ok || v != tc.remain {
+			t.Errorf("%s of %+v = %g, %v; want %g", MetricOverhead, tc.s, v, ok, tc.remain)
+		}
+	}
+	// Without a REPORT line there is no server side to subtract.
+	if _, ok := (Sample{ClientMS: 31.5}).Value(MetricOverhead); ok {
+		t.Errorf("%s present without a REPORT line", MetricOverhead)
+	}
+}
+
+func TestWithTelemetry(t *testing.T) {
+	cold := Sample{}.WithTelemetry(telemetryext.Line{RequestID: "req-1", InitMS: 12.345, RuntimeMS: 26.789, OverheadMS: 1.101})
+	if v, ok := cold.Value(MetricTelemetryInit); !ok || v != 12.345 {
+		t.Errorf("%s = %g, %v", MetricTelemetryInit, v, ok)
+	}
+	if v, ok := cold.Value(MetricTelemetryOverhead); !ok || v != 1.101 {
+		t.Errorf("%s = %g, %v", MetricTelemetryOverhead, v, ok)
+	}
+	warm := Sample{}.WithTelemetry(telemetryext.Line{RequestID: "req-2", RuntimeMS: 3.21})
+	if _, ok := warm.Value(MetricTelemetryInit); ok {
+		t.Errorf("%s present on a warm invocation", MetricTelemetryInit)
+	}
+	if v, ok := warm.Value(MetricTelemetryRuntime); !ok || v != 3.21 {
+		t.Errorf("%s = %g, %v", MetricTelemetryRuntime, v, ok)
+	}
+}
+
+func TestWithExecution(t *testing.T) {
+	s := Sample{ClientMS: 160}.WithExecution(stepfn.Breakdown{ExecutionMS: 140, StepsMS: 80, Steps: 2, Response: []byte(`{"body":"ok"}`)})
+	if v, ok := s.Value(MetricSFNTransition); !ok || v != 30 {
+		t.Errorf("%s = %g, %v", MetricSFNTransition, v, ok)
+	}
+	if _, ok := s.Value(MetricDuration); ok {
+		t.Errorf("%s present on an execution", MetricDuration)
+	}
+	if s = s.Verify("ok"); s.Error != "" {
+		t.Errorf("last step's response not verified: %s", s.Error)
+	}
+}
+
+func TestSummarizeModes(t *testing.T) {
+	// Two thirds of the invocations on one host generation, a third on a
+	// slower one, using the same memory.
+	var r Result
+	for i := range 90 {
+		d := 12 + float64(i%10)*0.1
+		if i%3 == 0 {
+			d += 4
+		}
+		r.Samples = append(r.Samples, Sample{ClientMS: d + 20, RequestID: "r", DurationMS: d, MaxMemoryMB: 30 + i%2*20})
+	}
+	r.Summarize(stats.Options{})
+	m, ok := r.Modes[MetricDuration]
+	if !ok || m.Low.N != 60 || m.High.N != 30 {
+		t.Fatalf("modes %+v", r.Modes)
+	}
+	if _, ok := r.Modes[MetricMaxMemory]; ok {
+		t.Errorf("%s tested for modes", MetricMaxMemory)
+	}
+	if s := r.Stats[MetricDuration]; s.N != 90 || s.Stratified != 0 {
+		t.Errorf("unstratified %s %+v", MetricDuration, s)
+	}
+
+	r.Summarize(stats.Options{Stratify: true})
+	if s := r.Stats[MetricDuration]; s.N != 60 || s.Stratified != 30 || s.Max > 13 {
+		t.Errorf("stratified %s %+v", MetricDuration, s)
+	}
+	if s := r.Stats[MetricMaxMemory]; s.N != 90 || s.Stratified != 0 {
+		t.Errorf("%s stratified: %+v", MetricMaxMemory, s)
+	}
+}
+
+func TestByCPU(t *testing.T) {
+	var r Result
+	for i := range 10 {
+		gen, d := "cascadelake", 412.0
+		if i%2 == 0 {
+			gen, d = "icelake", 361.0
+		}
+		payload := fmt.Sprintf(`{"statusCode":200,"body":"ok","host":{"cpu_generation":%q,"sandbox":"%d"}}`, gen, i%3)
+		r.Samples = append(r.Samples, Sample{Iteration: i, RequestID: "r", DurationMS: d + float64(i)}.WithResponse([]byte(payload)))
+	}
+	r.Samples = append(r.Samples, Sample{Iteration: 10, RequestID: "r", DurationMS: 900}.WithResponse([]byte(`{"body":"ok"}`)))
+	if got := r.CPUGenerations(); !slices.Equal(got, []string{"cascadelake", "icelake"}) {
+		t.Fatalf("generations %v", got)
+	}
+	split := r.ByCPU(stats.Options{})
+	if len(split) != 2 || split[0].CPUGeneration != "cascadelake" || split[1].CPUGeneration != "icelake" {
+		t.Fatalf("split into %+v", split)
+	}
+	if s := split[1].Stats[MetricDuration]; s.N != 5 || s.Max != 369 {
+		t.Errorf("icelake %s %+v", MetricDuration, s)
+	}
+	if len(r.Samples) != 11 || r.CPUGeneration != "" {
+		t.Error("ByCPU changed the result it split")
+	}
+	whole := Result{Samples: []Sample{{DurationMS: 1, RequestID: "r"}}}
+	if split := whole.ByCPU(stats.Options{}); len(split) != 1 || len(split[0].Samples) != 1 {
+		t.Errorf("result without hosts split into %+v", split)
+	}
+}
+
+func TestMissingMetadata(t *testing.T) {
+	run := &Run{Results: []Result{
+		{Runtime: "go", Kind: "local"},
+		{Runtime: "go", Kind: "lambda", Function: "baseline-go-fibonacci"},
+	}}
+	if got := strings.Join(run.MissingMetadata(), ", "); got != "metadata" {
+		t.Errorf("without metadata = %s", got)
+	}
+	run.Metadata = &Metadata{Commit: "3f4e2a1c", Toolchains: map[string]string{"go": "go1.24.2"}}
+	if got := strings.Join(run.MissingMetadata(), ", "); got != "ruchy toolchain version, aws-lambda-go version, lambda runtime of baseline-go-fibonacci" {
+		t.Errorf("partial metadata = %s", got)
+	}
+	run.Metadata.Toolchains["ruchy"], run.Metadata.LambdaGo = "ruchy 3.208.0", "v1.50.0"
+	run.Results[1].LambdaRuntime = "provided.al2023"
+	if got := run.MissingMetadata(); got != nil {
+		t.Errorf("complete metadata missing %v", got)
+	}
+}
+
+func TestEncodeMatchesSchema(t *testing.T) {
+	run := NewRun("lambda", time.Date(2025, 11, 2, 10, 0, 0, 0, time.UTC))
+	run.FinishedAt = run.StartedAt.Add(time.Minute)
+	run.Metadata = &Metadata{Commit: "3f4e2a1c", Toolchains: map[string]string{"go": "go1.24.2"}}
+	r := Result{Runtime: "go", Workload: "fibonacci", Kind: "lambda", Arch: "x86_64", MemoryMB: 128, Input: map[string]int{"n": 35},
+		Load: &Load{Workers: 4}, Replay: &Replay{Trace: "trace.jsonl"}, CPUGeneration: "skylake"}
+	for i := range 30 {
+		s := Sample{Iteration: i, ClientMS: 20 + float64(i%3), RequestID: "req", DurationMS: 1.5 + float64(i%2)*0.2, BilledMS: 2,
+			MemorySizeMB: 128, Cold: i == 0, Warmup: i < 2, Counters: map[string]float64{"cycles": 1e6},
+			Host: &lambdalog.Host{CPUGeneration: "skylake"}, Histogram: &lambdalog.Histogram{}}
+		s.Histogram.Record(time.Duration(i) * time.Millisecond)
+		r.Samples = append(r.Samples, s)
+	}
+	run.Results = []Result{r, {Runtime: "ruchy", Workload: "fibonacci", Kind: "lambda", Error: "not deployed"}}
+	run.Summarize(stats.Options{})
+
+	data, err := Encode(run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate(data); err != nil {
+		t.Fatalf("encoded run fails its schema: %v", err)
+	}
+	got, err := Decode(data)
+	if err != nil || got.SchemaVersion != schema.Version || len(got.Results[0].Samples) != 30 {
+		t.Fatalf("Decode = %+v, %v", got, err)
+	}
+
+	// A file from before versioning reads the same.
+	v1 := strings.Replace(string(data), fmt.Sprintf(`"schema_version": %d,`, schema.Version), "", 1)
+	if old, err := Decode([]byte(v1)); err != nil || old.SchemaVersion != schema.Version || old.ID != run.ID {
+		t.Errorf("Decode(version 1) = %+v, %v", old, err)
+	}
+}
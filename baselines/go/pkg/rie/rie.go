@@ -0,0 +1,147 @@
+// Package rie runs Lambda container images locally under the Runtime
+// Interface Emulator that the AWS base images start when AWS_LAMBDA_RUNTIME_API
+// is unset. Invoking through it exercises the whole handler path, runtime
+// client and event decoding included, without AWS credentials or cost. It
+// does not emulate Lambda's CPU allocation, placement or billing.
+package rie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// port is where the emulator listens inside the container.
+	port         = 8080
+	startTimeout = 30 * time.Second
+	// reportWait bounds how long Logs waits for the REPORT line, which
+	// the emulator prints just after it has sent the response.
+	reportWait = time.Second
+)
+
+// Container is a running emulator container.
+type Container struct {
+	ID string
+	// URL is the emulator's base URL on the loopback interface.
+	URL string
+
+	seen int // bytes of stdout already returned by Logs
+}
+
+// Start runs image for the given GOARCH-style architecture (amd64, arm64),
+// publishing the emulator on a free loopback port, and waits until it
+// accepts requests. Images for another architecture than the host's need
+// QEMU binfmt support.
+func Start(ctx context.Context, image, arch string, env map[string]string) (*Container, error) {
+	hostPort, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"run", "--detach", "--rm", "--platform", "linux/" + arch,
+		"--publish", fmt.Sprintf("127.0.0.1:%d:%d", hostPort, port)}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--env", k+"="+env[k])
+	}
+	out, err := docker(ctx, append(args, image)...)
+	if err != nil {
+		return nil, err
+	}
+	c := &Container{
+		ID:  strings.TrimSpace(out),
+		URL: fmt.Sprintf("http://127.0.0.1:%d", hostPort),
+	}
+	if err := c.wait(ctx); err != nil {
+		c.Stop(context.WithoutCancel(ctx))
+		return nil, err
+	}
+	return c, nil
+}
+
+// Logs returns the container's standard output written since the last
+// call, waiting briefly for it to include a REPORT line. It has the
+// signature of invoke.RIE.Logs.
+func (c *Container) Logs(ctx context.Context) (string, error) {
+	deadline := time.Now().Add(reportWait)
+	for {
+		out, err := docker(ctx, "logs", c.ID)
+		if err != nil {
+			return "", err
+		}
+		if len(out) < c.seen {
+			return "", errors.New("container output shrank")
+		}
+		fresh := out[c.seen:]
+		if strings.Contains(fresh, "REPORT ") || time.Now().After(deadline) {
+			c.seen = len(out)
+			return fresh, nil
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// Stop stops the container, which removes it.
+func (c *Container) Stop(ctx context.Context) error {
+	_, err := docker(ctx, "stop", "--time", "1", c.ID)
+	return err
+}
+
+// wait polls the emulator until it answers HTTP. Docker publishes the port
+// before the emulator listens, and connections until then are reset.
+func (c *Container) wait(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+	client := &http.Client{Timeout: time.Second}
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL+"/", nil)
+		if err != nil {
+			return err
+		}
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("emulator in %s not ready: %w", c.ID, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// docker runs the docker CLI and returns its standard output.
+func docker(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "docker", args...).Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && len(exit.Stderr) > 0 {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(string(exit.Stderr)))
+	}
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,227 @@
+// Package sandbox runs local workloads under the limits Lambda puts on a
+// function, so that local numbers predict Lambda's better than runs on
+// the bare host do. A workload runs in a container capped at the memory
+// of a Lambda memory size and at the share of a vCPU Lambda allocates
+// for it: Lambda, like a CPU-capped container, enforces that share with
+// a CFS quota, so a function below 1769 MB runs in bursts and waits out
+// the rest of each period. No swap, no network and Lambda's process and
+// file limits complete the picture.
+//
+// The container runs under one of three Docker runtimes, in increasing
+// order of isolation and of resemblance to Lambda: runc, gVisor (runsc)
+// or a Firecracker microVM, which is what Lambda runs functions in,
+// through Kata Containers. Each must be registered with Docker under the
+// name Runtime gives it; runc always is.
+//
+// The lightest backend, Cgroup, runs no container: the workload runs on
+// the host as it otherwise would, in a cgroup with the CPU quota of the
+// memory size (see localbench.NewCgroup). It matches Lambda's CPU share
+// and nothing else, but needs no Docker and keeps the hardware counters.
+//
+// Images are the Lambda base images, so workloads run on Lambda's OS and
+// libraries. Workloads are the host's builds, mounted read-only, so the
+// host must be Linux and its binaries must run on Amazon Linux 2023.
+// Each run is timed inside the sandbox by baselines/go/timer, which
+// leaves docker exec's own overhead out of the wall time.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"lambdaperf/pkg/localbench"
+)
+
+// Backend is how a sandbox isolates its workload.
+type Backend string
+
+const (
+	// Cgroup caps the workload's CPU on the host, without a container.
+	Cgroup Backend = "cgroup"
+	// Container shares the host kernel, as an ordinary container does.
+	Container Backend = "container"
+	// GVisor intercepts the workload's system calls in a user-space
+	// kernel.
+	GVisor Backend = "gvisor"
+	// Firecracker boots a microVM per sandbox.
+	Firecracker Backend = "firecracker"
+)
+
+// Backends lists every backend.
+var Backends = []Backend{Cgroup, Container, GVisor, Firecracker}
+
+// Runtime is the Docker runtime b runs containers with. runsc is the name
+// gVisor's installer registers; kata-fc is the name Kata Containers' docs
+// give its Firecracker configuration.
+func (b Backend) Runtime() string {
+	switch b {
+	case GVisor:
+		return "runsc"
+	case Firecracker:
+		return "kata-fc"
+	}
+	return "runc"
+}
+
+// Parse returns the backend named s.
+func Parse(s string) (Backend, error) {
+	if b := Backend(s); slices.Contains(Backends, b) {
+		return b, nil
+	}
+	return "", fmt.Errorf("unknown sandbox %q: want cgroup, container, gvisor or firecracker", s)
+}
+
+const (
+	// fullCPUMB is the memory size at which a function gets a full vCPU;
+	// Lambda allocates CPU in proportion to memory.
+	fullCPUMB = 1769
+	// Lambda's limits on processes and open files.
+	maxProcesses = 1024
+	maxFiles     = 1024
+	// TimerPath is where the timer is mounted in the sandbox.
+	TimerPath = "/opt/ruchy-bench/timer"
+)
+
+// VCPUs is the number of vCPUs Lambda allocates a function of memoryMB.
+func VCPUs(memoryMB int32) float64 {
+	return float64(memoryMB) / fullCPUMB
+}
+
+// Image is the Lambda base image the workloads of runtime run on.
+func Image(runtime string) string {
+	if runtime == "python" {
+		return "public.ecr.aws/lambda/python:3.12"
+	}
+	return "public.ecr.aws/lambda/provided:al2023"
+}
+
+// Options configure a sandbox.
+type Options struct {
+	Backend  Backend
+	MemoryMB int32
+	Image    string
+	// Timer is the host path of the timer binary.
+	Timer string
+	// Mounts are host directories the workload needs, mounted read-only
+	// at the same paths.
+	Mounts []string
+}
+
+// Sandbox is a running sandbox container, idle until Exec runs a
+// workload in it.
+type Sandbox struct {
+	ID string
+}
+
+// Start starts the sandbox o describes.
+func Start(ctx context.Context, o Options) (*Sandbox, error) {
+	out, err := docker(ctx, nil, runArgs(o)...)
+	if err != nil {
+		return nil, err
+	}
+	return &Sandbox{ID: strings.TrimSpace(string(out))}, nil
+}
+
+// runArgs are the docker arguments that start the sandbox o describes.
+// The Lambda images' entrypoint would start the runtime interface
+// emulator, so the container sleeps instead until it is stopped.
+func runArgs(o Options) []string {
+	mb := strconv.Itoa(int(o.MemoryMB)) + "m"
+	args := []string{"run", "--detach", "--rm",
+		"--runtime", o.Backend.Runtime(),
+		"--cpus", strconv.FormatFloat(VCPUs(o.MemoryMB), 'f', 3, 64),
+		// A swap limit equal to the memory limit allows no swap.
+		"--memory", mb, "--memory-swap", mb,
+		"--network", "none",
+		"--pids-limit", strconv.Itoa(maxProcesses),
+		"--ulimit", fmt.Sprintf("nofile=%d:%d", maxFiles, maxFiles),
+		"--volume", o.Timer + ":" + TimerPath + ":ro",
+	}
+	for _, dir := range o.Mounts {
+		args = append(args, "--volume", dir+":"+dir+":ro")
+	}
+	return append(args, "--entrypoint", "sleep", o.Image, "infinity")
+}
+
+// Mounts returns the directories running command in dir needs: dir and
+// those of the command's absolute paths, such as a compiled binary.
+func Mounts(command []string, dir string) []string {
+	dirs := []string{dir}
+	for _, arg := range command {
+		if filepath.IsAbs(arg) {
+			dirs = append(dirs, filepath.Dir(arg))
+		}
+	}
+	slices.Sort(dirs)
+	return slices.Compact(dirs)
+}
+
+// Report is the timer's account of one run, printed as JSON.
+type Report struct {
+	WallNS   int64 `json:"wall_ns"`
+	UserNS   int64 `json:"user_ns"`
+	SystemNS int64 `json:"system_ns"`
+	// MaxRSSKB is the workload's peak resident set size from its rusage,
+	// which includes the few MB of the timer it was forked from.
+	MaxRSSKB int64  `json:"max_rss_kb"`
+	Output   string `json:"output"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Exec runs command once in dir in the sandbox, with stdin, and returns
+// the timer's measurement of it. It has the signature of
+// localbench.Runner.Exec.
+func (s *Sandbox) Exec(ctx context.Context, command []string, dir string, stdin []byte) (localbench.Measurement, error) {
+	args := append([]string{"exec", "--interactive", "--workdir", dir, s.ID, TimerPath}, command...)
+	out, err := docker(ctx, stdin, args...)
+	if err != nil {
+		return localbench.Measurement{}, err
+	}
+	var r Report
+	if err := json.Unmarshal(out, &r); err != nil {
+		return localbench.Measurement{}, fmt.Errorf("timer printed %q: %w", out, err)
+	}
+	m := localbench.Measurement{
+		Wall:     time.Duration(r.WallNS),
+		MaxRSSKB: r.MaxRSSKB,
+		User:     time.Duration(r.UserNS),
+		System:   time.Duration(r.SystemNS),
+		Output:   []byte(r.Output),
+	}
+	if r.Error != "" {
+		return m, errors.New(r.Error)
+	}
+	return m, nil
+}
+
+// Stop stops the sandbox, which removes it.
+func (s *Sandbox) Stop(ctx context.Context) error {
+	_, err := docker(ctx, nil, "stop", "--time", "1", s.ID)
+	return err
+}
+
+// docker runs the docker CLI with stdin and returns its standard output.
+func docker(ctx context.Context, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	out, err := cmd.Output()
+	var exit *exec.ExitError
+	if errors.As(err, &exit) && len(exit.Stderr) > 0 {
+		return nil, fmt.Errorf("docker %s: %w: %s", args[0], err, bytes.TrimSpace(exit.Stderr))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("docker %s: %w", args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package sandbox
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVCPUs(t *testing.T) {
+	for mb, want := range map[int32]float64{1769: 1, 3538: 2} {
+		if got := VCPUs(mb); got != want {
+			t.Errorf("VCPUs(%d) = %v, want %v", mb, got, want)
+		}
+	}
+	if got := VCPUs(128); got < 0.072 || got > 0.073 {
+		t.Errorf("VCPUs(128) = %v", got)
+	}
+}
+
+func TestRunArgs(t *testing.T) {
+	got := strings.Join(runArgs(Options{
+		Backend:  GVisor,
+		MemoryMB: 128,
+		Image:    Image("go"),
+		Timer:    "/b/timer/timer",
+		Mounts:   Mounts([]string{"/b/local/go/fibonacci/fibonacci"}, "/repo/baselines/go"),
+	}), " ")
+	for _, want := range []string{
+		"--runtime runsc --cpus 0.072 --memory 128m --memory-swap 128m --network none",
+		"--volume /b/timer/timer:" + TimerPath + ":ro",
+		"--volume /b/local/go/fibonacci:/b/local/go/fibonacci:ro --volume /repo/baselines/go:/repo/baselines/go:ro",
+		"--entrypoint sleep public.ecr.aws/lambda/provided:al2023 infinity",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("docker %s\nlacks %s", got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	if b, err := Parse("firecracker"); err != nil || b.Runtime() != "kata-fc" {
+		t.Errorf("Parse(firecracker) = %v, %v", b, err)
+	}
+	if _, err := Parse("qemu"); err == nil {
+		t.Error("Parse(qemu) succeeded")
+	}
+}
@@ -0,0 +1,335 @@
+// Package scaffold generates what a new runtime/workload pair needs, so
+// that adding a baseline does not mean hand-wiring five files: the
+// handler or local program from a template, the build wiring (a Cargo
+// [[bin]] for Rust handlers), the manifest entry, and Makefile targets
+// running the pair's parity check and benchmark.
+//
+// The generated source documents the manifest's expected result but
+// computes a placeholder, so parity-<runtime>-<workload> fails until the
+// workload is implemented. A new workload's expected result is a TODO in
+// the manifest for the same reason.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/manifest"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+// Runtimes lists the runtimes there are templates for, by kind.
+var Runtimes = map[discover.Kind][]string{
+	discover.KindLambda: {"go", "python", "rust"},
+	discover.KindLocal:  {"c", "go", "julia", "python", "ruchy", "rust"},
+}
+
+// localExt is the source extension discover maps to each local runtime.
+var localExt = map[string]string{
+	"c": ".c", "go": ".go", "julia": ".jl", "python": ".py", "ruchy": ".ruchy", "rust": ".rs",
+}
+
+// runtimeNames are the runtimes as the READMEs spell them.
+var runtimeNames = map[string]string{
+	"c": "C", "go": "Go", "julia": "Julia", "python": "Python", "ruchy": "Ruchy", "rust": "Rust",
+}
+
+var workloadName = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
+// Options select the pair to scaffold.
+type Options struct {
+	Kind     discover.Kind
+	Runtime  string
+	Workload string
+}
+
+// Change is a file Generate creates or rewrites.
+type Change struct {
+	// Path is relative to the repository root.
+	Path    string
+	Created bool
+	Content []byte
+}
+
+// Generate returns the changes scaffolding o under root takes, without
+// making them. It fails when the pair is already implemented.
+func Generate(root string, o Options) ([]Change, error) {
+	if !slices.Contains(Runtimes[o.Kind], o.Runtime) {
+		return nil, fmt.Errorf("no %s template for runtime %q: want one of %s", o.Kind, o.Runtime, strings.Join(Runtimes[o.Kind], ", "))
+	}
+	if !workloadName.MatchString(o.Workload) {
+		return nil, fmt.Errorf("invalid workload name %q: want lowercase words joined by hyphens", o.Workload)
+	}
+	man, err := os.ReadFile(filepath.Join(root, manifest.Path))
+	if err != nil {
+		return nil, err
+	}
+	m, err := manifest.Parse(man)
+	if err != nil {
+		return nil, err
+	}
+	src := sourcePath(o)
+	if _, err := os.Stat(filepath.Join(root, src)); err == nil {
+		return nil, fmt.Errorf("%s already exists", src)
+	}
+
+	d := data{
+		Workload:    o.Workload,
+		Title:       strings.ToUpper(o.Workload[:1]) + strings.ReplaceAll(o.Workload[1:], "-", " "),
+		Func:        strings.ReplaceAll(o.Workload, "-", "_"),
+		Expected:    fmt.Sprintf("TODO: %s(<input>)=<result>", o.Workload),
+		RuntimeName: runtimeNames[o.Runtime],
+		File:        filepath.Base(src),
+	}
+	if o.Runtime == "go" {
+		d.Func = camel(o.Workload)
+	}
+	w, declared := m.Workload(o.Workload)
+	if declared {
+		d.Expected = w.Expected
+		var inputs, goInputs []string
+		for _, name := range slices.Sorted(maps.Keys(w.Inputs)) {
+			in := w.Inputs[name]
+			inputs = append(inputs, fmt.Sprintf("{%q: %d..%d}, default %d", name, in.Min, in.Max, in.Default))
+			goInputs = append(goInputs, fmt.Sprintf("%q: {Default: %d, Min: %d, Max: %d}", name, in.Default, in.Min, in.Max))
+			if d.Input == "" {
+				d.Input = name
+			}
+		}
+		d.Inputs, d.GoInputs = strings.Join(inputs, "; "), strings.Join(goInputs, ", ")
+	}
+
+	var changes []Change
+	content, err := render(string(o.Kind)+"-"+o.Runtime+".tmpl", d)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, Change{Path: src, Created: true, Content: content})
+	if o.Kind == discover.KindLocal {
+		readme := filepath.Join(filepath.Dir(src), "README.md")
+		if _, err := os.Stat(filepath.Join(root, readme)); errors.Is(err, os.ErrNotExist) {
+			content, err := render("local-readme.tmpl", d)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, Change{Path: readme, Created: true, Content: content})
+		}
+	}
+	if o.Kind == discover.KindLambda && o.Runtime == "rust" {
+		c, err := edit(root, filepath.Join("baselines", "rust", "Cargo.toml"), func(b []byte) ([]byte, error) { return cargoBin(b, o.Workload) })
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	if !declared || !w.Implements(o.Kind, o.Runtime) {
+		c, err := edit(root, manifest.Path, func(b []byte) ([]byte, error) { return Declare(b, o) })
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	c, err := edit(root, "Makefile", func(b []byte) ([]byte, error) { return makeTargets(b, o), nil })
+	if err != nil {
+		return nil, err
+	}
+	if c.Content != nil {
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// Write makes changes under root.
+func Write(root string, changes []Change) error {
+	for _, c := range changes {
+		path := filepath.Join(root, c.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, c.Content, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// data fills the templates.
+type data struct {
+	Workload, Title, Func, Expected, RuntimeName, File string
+	// Inputs describes the workload's inputs as handler comments do, and
+	// GoInputs declares them to internal/handler; Input is the first.
+	Inputs, GoInputs, Input string
+}
+
+func render(name string, d data) ([]byte, error) {
+	t, err := template.ParseFS(templates, "templates/"+name)
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, d); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// sourcePath is where discover looks for o's source, relative to the
+// repository root.
+func sourcePath(o Options) string {
+	if o.Kind == discover.KindLocal {
+		return filepath.Join("benchmarks", "local-"+o.Workload, o.Workload+localExt[o.Runtime])
+	}
+	switch o.Runtime {
+	case "python":
+		return filepath.Join("baselines", "python", "index-"+o.Workload+".py")
+	case "rust":
+		return filepath.Join("baselines", "rust", "src", "main-"+o.Workload+".rs")
+	}
+	return filepath.Join("baselines", o.Runtime, "main-"+o.Workload+".go")
+}
+
+// edit returns the change f makes to the file at path, with nil Content
+// when it makes none.
+func edit(root, path string, f func([]byte) ([]byte, error)) (Change, error) {
+	old, err := os.ReadFile(filepath.Join(root, path))
+	if err != nil {
+		return Change{}, err
+	}
+	content, err := f(old)
+	if err != nil {
+		return Change{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if bytes.Equal(content, old) {
+		content = nil
+	}
+	return Change{Path: path, Content: content}, nil
+}
+
+// camel spells a workload name as a Go identifier: fibonacci-memo is
+// fibonacciMemo.
+func camel(workload string) string {
+	parts := strings.Split(workload, "-")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// cargoBin adds the lambda-perf-<workload> binary to the Rust baselines'
+// Cargo.toml, after the others.
+func cargoBin(toml []byte, workload string) ([]byte, error) {
+	s := string(toml)
+	bin := fmt.Sprintf("[[bin]]\nname = \"lambda-perf-%s\"\npath = \"src/main-%s.rs\"\n\n", workload, workload)
+	i := strings.Index(s, "[dependencies]")
+	if i < 0 {
+		return nil, errors.New("no [dependencies] section to add the binary before")
+	}
+	return []byte(s[:i] + bin + s[i:]), nil
+}
+
+// Declare adds o's runtime to its workload in the manifest src, or a new
+// workload with placeholders for o. It edits the text, so the manifest's
+// comments and layout survive.
+func Declare(src []byte, o Options) ([]byte, error) {
+	lines := strings.SplitAfter(string(src), "\n")
+	start := slices.IndexFunc(lines, func(l string) bool { return strings.TrimRight(l, "\n") == "  - name: "+o.Workload })
+	var out string
+	if start < 0 {
+		out = strings.TrimRight(string(src), "\n") + fmt.Sprintf(`
+
+  - name: %s
+    description: "TODO: what %s computes, and what it measures."
+    expected: "TODO: %s(<input>)=<result>"
+    runtimes:
+      %s: [%s]
+`, o.Workload, o.Workload, o.Workload, o.Kind, o.Runtime)
+	} else {
+		end := len(lines)
+		for i := start + 1; i < len(lines); i++ {
+			if strings.HasPrefix(lines[i], "  - name: ") {
+				end = i
+				break
+			}
+		}
+		r := slices.IndexFunc(lines[start:end], func(l string) bool { return strings.TrimSpace(l) == "runtimes:" })
+		if r < 0 {
+			return nil, fmt.Errorf("workload %s has no runtimes", o.Workload)
+		}
+		r += start
+		j, found := r+1, false
+		for ; j < end && strings.HasPrefix(lines[j], "      "); j++ {
+			list, ok := strings.CutPrefix(strings.TrimSpace(lines[j]), string(o.Kind)+": [")
+			if !ok {
+				continue
+			}
+			list, ok = strings.CutSuffix(list, "]")
+			if !ok {
+				return nil, fmt.Errorf("workload %s: %s runtimes are not a one-line list", o.Workload, o.Kind)
+			}
+			runtimes := []string{o.Runtime}
+			for _, rt := range strings.Split(list, ",") {
+				if rt = strings.TrimSpace(rt); rt != "" {
+					runtimes = append(runtimes, rt)
+				}
+			}
+			slices.Sort(runtimes)
+			lines[j] = fmt.Sprintf("      %s: [%s]\n", o.Kind, strings.Join(slices.Compact(runtimes), ", "))
+			found = true
+		}
+		if !found {
+			// Local comes before lambda throughout the manifest.
+			at := j
+			if o.Kind == discover.KindLocal {
+				at = r + 1
+			}
+			lines = slices.Insert(lines, at, fmt.Sprintf("      %s: [%s]\n", o.Kind, o.Runtime))
+		}
+		out = strings.Join(lines, "")
+	}
+	m, err := manifest.Parse([]byte(out))
+	if err != nil {
+		return nil, err
+	}
+	if w, ok := m.Workload(o.Workload); !ok || !w.Implements(o.Kind, o.Runtime) {
+		return nil, fmt.Errorf("could not declare %s/%s/%s", o.Kind, o.Runtime, o.Workload)
+	}
+	return []byte(out), nil
+}
+
+// makeTargets appends parity-<runtime>-<workload> and
+// bench-<runtime>-<workload> to the Makefile, unless it has them.
+func makeTargets(makefile []byte, o Options) []byte {
+	name := o.Runtime + "-" + o.Workload
+	if bytes.Contains(makefile, []byte("\nparity-"+name+":")) {
+		return makefile
+	}
+	sel := fmt.Sprintf("-kind %s -runtime %s -workload %s", o.Kind, o.Runtime, o.Workload)
+	var b strings.Builder
+	b.Write(bytes.TrimRight(makefile, "\n"))
+	check := "check it prints"
+	if o.Kind == discover.KindLambda {
+		check = "deploy it and check it returns"
+	}
+	fmt.Fprintf(&b, "\n\n# %s/%s (%s): %s the manifest's expected result, then benchmark it\n", o.Runtime, o.Workload, o.Kind, check)
+	fmt.Fprintf(&b, ".PHONY: parity-%s bench-%s\nparity-%s:\n", name, name, name)
+	if o.Kind == discover.KindLambda {
+		fmt.Fprintf(&b, "\t@cd baselines/go && go run ./cmd/ruchy-bench deploy -runtime %s -workload %s\n", o.Runtime, o.Workload)
+		sel += " -invoke"
+	}
+	fmt.Fprintf(&b, "\t@cd baselines/go && go run ./cmd/ruchy-bench verify-parity %s\n", sel)
+	fmt.Fprintf(&b, "\nbench-%s: parity-%s\n", name, name)
+	fmt.Fprintf(&b, "\t@cd baselines/go && go run ./cmd/ruchy-bench run -kind %s -runtime %s -workload %s\n", o.Kind, o.Runtime, o.Workload)
+	return []byte(b.String())
+}
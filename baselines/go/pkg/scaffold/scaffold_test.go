@@ -0,0 +1,141 @@
+package scaffold
+
+import (
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lambdaperf/pkg/discover"
+	"lambdaperf/pkg/manifest"
+)
+
+const testManifest = `# Benchmark workload manifest.
+workloads:
+  - name: matmul
+    description: Matrix multiplication.
+    inputs:
+      size: {default: 512, min: 1, max: 1024}
+    expected: matmul(512)=33519225.201954
+    runtimes:
+      local: [go, python, tinygo, wasm]
+      lambda: [go, tinygo, wasm]
+
+  - name: sieve
+    description: Prime sieve.
+    # Lambda only.
+    expected: sieve(10000000)=664579
+    runtimes:
+      lambda: [go, tinygo]
+`
+
+// repo lays out a repository root with the files Generate edits.
+func repo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		manifest.Path:                       testManifest,
+		"Makefile":                          ".PHONY: build\n\nbuild:\n\t@cargo build\n",
+		"baselines/rust/Cargo.toml":         "[package]\nname = \"lambda-perf\"\n\n[[bin]]\nname = \"lambda-perf\"\npath = \"src/main.rs\"\n\n[dependencies]\n",
+		"baselines/go/main-sieve.go":        "//go:build baseline\n",
+		"benchmarks/local-matmul/matmul.go": "// Expected result: matmul(512)=33519225.201954\n",
+		"benchmarks/local-matmul/matmul.py": "# Expected result: matmul(512)=33519225.201954\n",
+		"baselines/go/main-matmul.go":       "//go:build baseline\n",
+		"crates/bootstrap/src/.keep":        "",
+	} {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestGenerate(t *testing.T) {
+	root := repo(t)
+	for _, o := range []Options{
+		{Kind: discover.KindLambda, Runtime: "rust", Workload: "matmul"},
+		{Kind: discover.KindLambda, Runtime: "go", Workload: "collatz-steps"},
+		{Kind: discover.KindLocal, Runtime: "julia", Workload: "collatz-steps"},
+		{Kind: discover.KindLocal, Runtime: "rust", Workload: "sieve"},
+	} {
+		changes, err := Generate(root, o)
+		if err != nil {
+			t.Fatalf("%+v: %v", o, err)
+		}
+		if err := Write(root, changes); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	targets, err := discover.Discover(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := manifest.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range m.Coverage(targets) {
+		t.Error(g)
+	}
+	if w, _ := m.Workload("matmul"); !w.Implements(discover.KindLambda, "rust") || !w.Implements(discover.KindLocal, "python") {
+		t.Errorf("matmul runtimes = %v", w.Runtimes)
+	}
+	if w, _ := m.Workload("sieve"); !w.Implements(discover.KindLocal, "rust") {
+		t.Errorf("sieve runtimes = %v", w.Runtimes)
+	}
+
+	read := func(path string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(root, path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if man := read(manifest.Path); !strings.Contains(man, "    # Lambda only.\n") || !strings.Contains(man, "      local: [rust]\n      lambda: [go, tinygo]\n") {
+		t.Errorf("manifest lost its layout:\n%s", man)
+	}
+	src := read("baselines/go/main-collatz-steps.go")
+	if _, err := format.Source([]byte(src)); err != nil {
+		t.Errorf("generated Go does not parse: %v\n%s", err, src)
+	}
+	if !strings.Contains(src, "func collatzSteps() int") || !strings.Contains(src, "Expected result: TODO: collatz-steps(<input>)=<result>") {
+		t.Errorf("Go handler:\n%s", src)
+	}
+	if src := read("baselines/rust/src/main-matmul.rs"); !strings.Contains(src, `// Input: {"size": 1..1024}, default 512.`) ||
+		!strings.Contains(src, "// Expected result: matmul(512)=33519225.201954") {
+		t.Errorf("Rust handler:\n%s", src)
+	}
+	if cargo := read("baselines/rust/Cargo.toml"); !strings.Contains(cargo, "[[bin]]\nname = \"lambda-perf-matmul\"\npath = \"src/main-matmul.rs\"\n\n[dependencies]") {
+		t.Errorf("Cargo.toml:\n%s", cargo)
+	}
+	if readme := read("benchmarks/local-collatz-steps/README.md"); !strings.Contains(readme, "| **Julia** | `collatz-steps.jl` |") {
+		t.Errorf("README:\n%s", readme)
+	}
+	makefile := read("Makefile")
+	for _, want := range []string{
+		"parity-rust-matmul:\n\t@cd baselines/go && go run ./cmd/ruchy-bench deploy -runtime rust -workload matmul\n" +
+			"\t@cd baselines/go && go run ./cmd/ruchy-bench verify-parity -kind lambda -runtime rust -workload matmul -invoke\n",
+		"bench-julia-collatz-steps: parity-julia-collatz-steps\n",
+	} {
+		if !strings.Contains(makefile, want) {
+			t.Errorf("Makefile missing %q:\n%s", want, makefile)
+		}
+	}
+
+	if _, err := Generate(root, Options{Kind: discover.KindLambda, Runtime: "rust", Workload: "matmul"}); err == nil {
+		t.Error("scaffolded an existing pair twice")
+	}
+	if _, err := Generate(root, Options{Kind: discover.KindLambda, Runtime: "julia", Workload: "matmul"}); err == nil {
+		t.Error("scaffolded a Lambda runtime without a template")
+	}
+	if _, err := Generate(root, Options{Kind: discover.KindLocal, Runtime: "go", Workload: "Bad_Name"}); err == nil {
+		t.Error("accepted an invalid workload name")
+	}
+}
@@ -0,0 +1,93 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Version is the schema version of the results files this build writes.
+const Version = 2
+
+// migrations[i] upgrades a document of version i+1 to version i+2, in
+// place. Append one with every new Version.
+var migrations = []func(doc map[string]any) error{
+	// Version 1 is every file from before versioning. Version 2 is the
+	// same layout with schema_version added, which Migrate sets.
+	func(map[string]any) error { return nil },
+}
+
+// VersionOf returns the schema version a decoded results document
+// declares, 1 when it has no schema_version.
+func VersionOf(doc map[string]any) (int, error) {
+	raw, ok := doc["schema_version"]
+	if !ok {
+		return 1, nil
+	}
+	var v int
+	switch x := raw.(type) {
+	case json.Number:
+		n, err := x.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("schema_version %s is not an integer", x)
+		}
+		v = int(n)
+	case float64:
+		v = int(x)
+		if float64(v) != x {
+			return 0, fmt.Errorf("schema_version %v is not an integer", x)
+		}
+	default:
+		return 0, fmt.Errorf("schema_version %v is not an integer", raw)
+	}
+	switch {
+	case v < 1:
+		return 0, fmt.Errorf("schema_version %d is not a version", v)
+	case v > Version:
+		return 0, fmt.Errorf("schema_version %d is newer than this ruchy-bench reads (%d); upgrade it", v, Version)
+	}
+	return v, nil
+}
+
+// Migrate upgrades doc in place to Version, returning the version it
+// was.
+func Migrate(doc map[string]any) (from int, err error) {
+	from, err = VersionOf(doc)
+	if err != nil {
+		return 0, err
+	}
+	for v := from; v < Version; v++ {
+		if err := migrations[v-1](doc); err != nil {
+			return from, fmt.Errorf("migrate schema version %d to %d: %w", v, v+1, err)
+		}
+		doc["schema_version"] = v + 1
+	}
+	return from, nil
+}
+
+// Upgrade returns data, a results document, migrated to Version, and
+// the version it was. A document already at Version comes back as it
+// is.
+func Upgrade(data []byte) ([]byte, int, error) {
+	var probe struct {
+		Version json.RawMessage `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, 0, fmt.Errorf("schema: %w", err)
+	}
+	if string(probe.Version) == fmt.Sprint(Version) {
+		return data, Version, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, 0, fmt.Errorf("schema: %w", err)
+	}
+	from, err := Migrate(doc)
+	if err != nil {
+		return nil, from, err
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	return out, from, err
+}
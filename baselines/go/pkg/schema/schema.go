@@ -0,0 +1,264 @@
+// Package schema is the public contract of ruchy-bench's results files:
+// a versioned JSON Schema (results.schema.json) that the website and
+// other tools reading the numbers can hold a file to, a validator for
+// it, and the migrations that bring files of older versions up to date.
+//
+// Adding a property to an object leaves the version as it is: consumers
+// ignore what they do not know, and the schema allows it. Renaming,
+// removing or retyping one is a new version, with a migration from the
+// last, so every results file ever written still reads.
+//
+// The validator supports the part of JSON Schema the document uses:
+// type, const, required, properties, additionalProperties, items,
+// minimum, format date-time and $ref into $defs. It refuses a schema
+// using anything else rather than silently passing it.
+package schema
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Document is the JSON Schema of results files of Version.
+//
+//go:embed results.schema.json
+var Document []byte
+
+// Error lists the ways a document departs from the schema, each as a
+// JSON Pointer to the offending value and what is wrong with it.
+type Error struct {
+	Problems []string
+}
+
+// maxShown is how many problems Error's message lists.
+const maxShown = 5
+
+func (e *Error) Error() string {
+	shown := e.Problems[:min(len(e.Problems), maxShown)]
+	msg := strings.Join(shown, "; ")
+	if more := len(e.Problems) - len(shown); more > 0 {
+		msg += fmt.Sprintf("; and %d more", more)
+	}
+	return "schema: " + msg
+}
+
+// Validate checks data, a results document, against the schema of
+// Version, returning an *Error listing every problem it finds. A file
+// of an older version fails on schema_version; Upgrade it first.
+func Validate(data []byte) error {
+	root, err := compiled()
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("schema: %w", err)
+	}
+	v := validator{defs: root.Defs}
+	v.check(root, doc, "")
+	if len(v.problems) > 0 {
+		return &Error{Problems: v.problems}
+	}
+	return nil
+}
+
+// node is one schema of the document, compiled.
+type node struct {
+	// always is set for the boolean schemas true and false.
+	always *bool
+
+	Ref                  string           `json:"$ref"`
+	Type                 types            `json:"type"`
+	Const                json.RawMessage  `json:"const"`
+	Required             []string         `json:"required"`
+	Properties           map[string]*node `json:"properties"`
+	AdditionalProperties *node            `json:"additionalProperties"`
+	Items                *node            `json:"items"`
+	Minimum              *float64         `json:"minimum"`
+	Format               string           `json:"format"`
+	Defs                 map[string]*node `json:"$defs"`
+
+	// Annotations, which do not constrain.
+	Schema      string `json:"$schema"`
+	ID          string `json:"$id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+func (n *node) UnmarshalJSON(data []byte) error {
+	var b bool
+	if json.Unmarshal(data, &b) == nil {
+		n.always = &b
+		return nil
+	}
+	type plain node
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode((*plain)(n))
+}
+
+// types is a schema's type, one name or several.
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var one string
+	if json.Unmarshal(data, &one) == nil {
+		*t = types{one}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(t))
+}
+
+var compiled = sync.OnceValues(func() (*node, error) {
+	var root node
+	if err := json.Unmarshal(Document, &root); err != nil {
+		return nil, fmt.Errorf("schema document: %w", err)
+	}
+	return &root, nil
+})
+
+type validator struct {
+	defs     map[string]*node
+	problems []string
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	if path == "" {
+		path = "/"
+	}
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) check(n *node, value any, path string) {
+	if n.always != nil {
+		if !*n.always {
+			v.fail(path, "not allowed")
+		}
+		return
+	}
+	if n.Ref != "" {
+		name, ok := strings.CutPrefix(n.Ref, "#/$defs/")
+		def := v.defs[name]
+		if !ok || def == nil {
+			v.fail(path, "unresolved $ref %s", n.Ref)
+			return
+		}
+		v.check(def, value, path)
+		return
+	}
+	if len(n.Type) > 0 && !slices.ContainsFunc(n.Type, func(t string) bool { return is(t, value) }) {
+		v.fail(path, "want %s, got %s", strings.Join(n.Type, " or "), kind(value))
+		return
+	}
+	if n.Const != nil {
+		var want any
+		dec := json.NewDecoder(bytes.NewReader(n.Const))
+		dec.UseNumber()
+		dec.Decode(&want)
+		if !equal(want, value) {
+			v.fail(path, "want %s, got %s", n.Const, show(value))
+		}
+	}
+	switch x := value.(type) {
+	case map[string]any:
+		for _, k := range n.Required {
+			if _, ok := x[k]; !ok {
+				v.fail(path, "missing required %q", k)
+			}
+		}
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			child := path + "/" + escape(k)
+			if p, ok := n.Properties[k]; ok {
+				v.check(p, x[k], child)
+			} else if n.AdditionalProperties != nil {
+				v.check(n.AdditionalProperties, x[k], child)
+			}
+		}
+	case []any:
+		if n.Items != nil {
+			for i, item := range x {
+				v.check(n.Items, item, path+"/"+strconv.Itoa(i))
+			}
+		}
+	case json.Number:
+		if f, _ := x.Float64(); n.Minimum != nil && f < *n.Minimum {
+			v.fail(path, "%s is below the minimum %g", x, *n.Minimum)
+		}
+	case string:
+		if n.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, x); err != nil {
+				v.fail(path, "%q is not an RFC 3339 date-time", x)
+			}
+		}
+	}
+}
+
+// is reports whether value is of the JSON Schema type t.
+func is(t string, value any) bool {
+	switch x := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		f, err := x.Float64()
+		return t == "integer" && err == nil && f == math.Trunc(f)
+	}
+	return false
+}
+
+// kind names value's JSON type.
+func kind(value any) string {
+	for _, t := range []string{"null", "boolean", "string", "array", "object", "integer", "number"} {
+		if is(t, value) {
+			return t
+		}
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func equal(a, b any) bool {
+	if x, ok := a.(json.Number); ok {
+		y, ok := b.(json.Number)
+		fx, _ := x.Float64()
+		fy, _ := y.Float64()
+		return ok && fx == fy
+	}
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return bytes.Equal(ja, jb)
+}
+
+func show(value any) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// escape makes k one JSON Pointer reference token.
+func escape(k string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(k)
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const valid = `{
+  "schema_version": 2,
+  "id": "20251102T100000Z",
+  "started_at": "2025-11-02T10:00:00Z",
+  "finished_at": "2025-11-02T10:05:00.5Z",
+  "mode": "lambda",
+  "metadata": {"commit": "3f4e2a1c", "toolchains": {"go": "go1.24.2"}},
+  "results": [{
+    "runtime": "go", "workload": "fibonacci", "kind": "lambda", "arch": "x86_64", "memory_mb": 128,
+    "samples": [
+      {"iteration": 0, "client_ms": 912.5, "duration_ms": 880, "cold": true, "init_ms": 80, "host": {"cpu_generation": "skylake"}},
+      {"iteration": 1, "client_ms": 21.3, "duration_ms": 1.52, "counters": {"cycles": 1e6}, "added_later": [1, 2]}
+    ],
+    "stats": {"client_ms": {"n": 2, "mean": 1, "median": 1, "p95": 1, "p99": 1, "stddev": 0, "min": 1, "max": 1, "ci95_low": 1, "ci95_high": 1}}
+  }]
+}`
+
+func TestDocument(t *testing.T) {
+	root, err := compiled()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.Properties["schema_version"] == nil || string(root.Properties["schema_version"].Const) != "2" {
+		t.Errorf("the schema's schema_version is not Version %d", Version)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate([]byte(valid)); err != nil {
+		t.Fatalf("valid document: %v", err)
+	}
+	var doc map[string]any
+	json.Unmarshal([]byte(valid), &doc)
+	result := doc["results"].([]any)[0].(map[string]any)
+	sample := result["samples"].([]any)[1].(map[string]any)
+	delete(doc, "mode")
+	doc["started_at"] = "yesterday"
+	result["memory_mb"] = 128.5
+	sample["client_ms"] = "21.3"
+	sample["counters"].(map[string]any)["cycles"] = true
+	sample["retries"] = -1
+	data, _ := json.Marshal(doc)
+
+	var serr *Error
+	if err := Validate(data); !errors.As(err, &serr) {
+		t.Fatalf("invalid document: %v", err)
+	}
+	want := []string{
+		`/: missing required "mode"`,
+		`/results/0/memory_mb: want integer, got number`,
+		`/results/0/samples/1/client_ms: want number, got string`,
+		`/results/0/samples/1/counters/cycles: want number, got boolean`,
+		`/results/0/samples/1/retries: -1 is below the minimum 0`,
+		`/started_at: "yesterday" is not an RFC 3339 date-time`,
+	}
+	if strings.Join(serr.Problems, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(serr.Problems, "\n"), strings.Join(want, "\n"))
+	}
+	if msg := serr.Error(); !strings.HasSuffix(msg, "; and 1 more") {
+		t.Errorf("message %q", msg)
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	var doc map[string]any
+	json.Unmarshal([]byte(valid), &doc)
+	delete(doc, "schema_version")
+	v1, _ := json.Marshal(doc)
+	if err := Validate(v1); err == nil {
+		t.Error("a version 1 document validates as the current version")
+	}
+	data, from, err := Upgrade(v1)
+	if err != nil || from != 1 {
+		t.Fatalf("Upgrade = %d, %v", from, err)
+	}
+	if err := Validate(data); err != nil {
+		t.Errorf("upgraded document: %v", err)
+	}
+	if data, from, err := Upgrade([]byte(valid)); err != nil || from != Version || string(data) != valid {
+		t.Errorf("current document changed by Upgrade: %d, %v", from, err)
+	}
+	for _, v := range []string{`3`, `0`, `1.5`, `"2"`} {
+		if _, _, err := Upgrade([]byte(`{"schema_version": ` + v + `}`)); err == nil {
+			t.Errorf("schema_version %s upgraded", v)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package sink
+
+import (
+	"context"
+	"strings"
+
+	"lambdaperf/pkg/cwmetrics"
+	"lambdaperf/pkg/results"
+)
+
+// DefaultNamespace is the CloudWatch namespace runs are published to.
+const DefaultNamespace = "RuchyBench"
+
+// CloudWatch publishes a run as CloudWatch custom metrics: every metric
+// value of every successful sample, so CloudWatch computes percentiles
+// itself, dimensioned by the run's mode and the result's labels. Each
+// distinct set of dimensions is a metric CloudWatch bills for.
+type CloudWatch struct {
+	Client *cwmetrics.Client
+	// Namespace is the metrics' namespace; empty means DefaultNamespace.
+	Namespace string
+}
+
+func (c CloudWatch) Write(ctx context.Context, run *results.Run) error {
+	ns := c.Namespace
+	if ns == "" {
+		ns = DefaultNamespace
+	}
+	return c.Client.Put(ctx, ns, data(run))
+}
+
+// data turns run into metric data, splitting values between data as
+// cwmetrics.MaxValues requires.
+func data(run *results.Run) []cwmetrics.Datum {
+	var out []cwmetrics.Datum
+	for _, r := range run.Results {
+		dims := map[string]string{"Mode": run.Mode}
+		for _, l := range labels(r) {
+			dims[dimension(l.name)] = l.value
+		}
+		for _, m := range metrics(r) {
+			xs := r.Values(m)
+			for len(xs) > 0 {
+				n := min(len(xs), cwmetrics.MaxValues)
+				out = append(out, cwmetrics.Datum{
+					MetricName: m,
+					Dimensions: dims,
+					Unit:       unit(m),
+					Timestamp:  run.FinishedAt,
+					Values:     xs[:n],
+				})
+				xs = xs[n:]
+			}
+		}
+	}
+	return out
+}
+
+// dimension turns a label name such as memory_mb into a dimension name in
+// CloudWatch's style, MemoryMb.
+func dimension(label string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(label, "_") {
+		if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// unit is the CloudWatch unit of metric, known from its name's suffix.
+func unit(metric string) string {
+	switch {
+	case strings.HasSuffix(metric, "_ms"):
+		return "Milliseconds"
+	case strings.HasSuffix(metric, "_bytes"):
+		return "Bytes"
+	case strings.HasSuffix(metric, "_mb"):
+		return "Megabytes"
+	case strings.HasSuffix(metric, "_kb"):
+		return "Kilobytes"
+	}
+	return "Count"
+}
@@ -0,0 +1,160 @@
+package sink
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"lambdaperf/pkg/results"
+)
+
+// Grafana keeps a dashboard document of every run written to it: a point
+// per result and metric, and an annotation per run saying what was
+// measured. Both are flat arrays of objects with a millisecond "time"
+// field, the shape Grafana's JSON API data source reads through JSONPath
+// (points with $.points[*], annotations with $.annotations[*]). Each
+// write adds the run to the document at Path, replacing an earlier copy
+// of the same run, so the file grows into a long-term trend.
+type Grafana struct {
+	Path string
+	// Commit is the source revision the run measured, such as
+	// "3f4e2a1" or "3f4e2a1-dirty"; Versions the toolchain version of
+	// each runtime, by runtime. Both go into the run's annotation.
+	Commit   string
+	Versions map[string]string
+}
+
+// Dashboard is the document Grafana writes.
+type Dashboard struct {
+	Points      []Point      `json:"points"`
+	Annotations []Annotation `json:"annotations"`
+}
+
+// Point is one metric's summary for one result of a run.
+type Point struct {
+	// Time is when the run finished, in milliseconds since the epoch.
+	Time  int64  `json:"time"`
+	RunID string `json:"run_id"`
+	Mode  string `json:"mode"`
+	// Series names the result among the run's: its runtime/workload,
+	// kind and variant labels, as "go/fibonacci kind=lambda
+	// memory_mb=128", for grouping a chart's lines.
+	Series   string  `json:"series"`
+	Runtime  string  `json:"runtime"`
+	Workload string  `json:"workload"`
+	Kind     string  `json:"kind"`
+	MemoryMB int32   `json:"memory_mb,omitempty"`
+	Arch     string  `json:"arch,omitempty"`
+	Region   string  `json:"region,omitempty"`
+	Metric   string  `json:"metric"`
+	N        int     `json:"n"`
+	Mean     float64 `json:"mean"`
+	Median   float64 `json:"median"`
+	P95      float64 `json:"p95"`
+	P99      float64 `json:"p99"`
+}
+
+// Annotation marks a run on the time axis, from its start to its finish.
+type Annotation struct {
+	Time    int64  `json:"time"`
+	TimeEnd int64  `json:"timeEnd"`
+	RunID   string `json:"run_id"`
+	Title   string `json:"title"`
+	Text    string `json:"text"`
+	// Tags are the mode, the commit as "commit:<rev>", and "new-commit"
+	// when the commit differs from the previous run's: the deploy
+	// markers of a trend.
+	Tags     []string          `json:"tags"`
+	Commit   string            `json:"commit,omitempty"`
+	Versions map[string]string `json:"versions,omitempty"`
+	// MemoryMB lists the memory sizes the run's results were measured at.
+	MemoryMB []int32 `json:"memory_mb,omitempty"`
+}
+
+func (g Grafana) Write(_ context.Context, run *results.Run) error {
+	var d Dashboard
+	data, err := os.ReadFile(g.Path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &d); err != nil {
+			return fmt.Errorf("parse %s: %w", g.Path, err)
+		}
+	}
+	d.Points = slices.DeleteFunc(d.Points, func(p Point) bool { return p.RunID == run.ID })
+	d.Annotations = slices.DeleteFunc(d.Annotations, func(a Annotation) bool { return a.RunID == run.ID })
+
+	finished := run.FinishedAt.UnixMilli()
+	var sizes []int32
+	for _, r := range run.Results {
+		ls := labels(r)
+		var series strings.Builder
+		series.WriteString(r.Runtime + "/" + r.Workload)
+		for _, l := range ls[2:] {
+			if l.name != "function" {
+				fmt.Fprintf(&series, " %s=%s", l.name, l.value)
+			}
+		}
+		for _, m := range metrics(r) {
+			st := r.Stats[m]
+			d.Points = append(d.Points, Point{
+				Time: finished, RunID: run.ID, Mode: run.Mode, Series: series.String(),
+				Runtime: r.Runtime, Workload: r.Workload, Kind: r.Kind, MemoryMB: r.MemoryMB, Arch: r.Arch, Region: r.Region,
+				Metric: m, N: st.N, Mean: st.Mean, Median: st.Median, P95: st.P95, P99: st.P99,
+			})
+		}
+		if r.MemoryMB > 0 && !slices.Contains(sizes, r.MemoryMB) {
+			sizes = append(sizes, r.MemoryMB)
+		}
+	}
+	slices.Sort(sizes)
+
+	a := Annotation{
+		Time: run.StartedAt.UnixMilli(), TimeEnd: finished, RunID: run.ID,
+		Title:  fmt.Sprintf("%s %s", run.Mode, run.ID),
+		Tags:   []string{run.Mode},
+		Commit: g.Commit, Versions: g.Versions, MemoryMB: sizes,
+	}
+	text := []string{fmt.Sprintf("%d results", len(run.Results))}
+	if g.Commit != "" {
+		a.Tags = append(a.Tags, "commit:"+g.Commit)
+		text = append(text, "commit "+g.Commit)
+		// Annotations are kept in time order, so the previous run is the
+		// last one that started before this one.
+		var prev string
+		for _, p := range d.Annotations {
+			if p.Time < a.Time {
+				prev = p.Commit
+			}
+		}
+		if prev != "" && prev != g.Commit {
+			a.Tags = append(a.Tags, "new-commit")
+		}
+	}
+	for _, rt := range slices.Sorted(maps.Keys(g.Versions)) {
+		text = append(text, rt+" "+g.Versions[rt])
+	}
+	a.Text = strings.Join(text, "; ")
+	d.Annotations = append(d.Annotations, a)
+	slices.SortStableFunc(d.Annotations, func(x, y Annotation) int { return cmp.Compare(x.Time, y.Time) })
+	slices.SortStableFunc(d.Points, func(x, y Point) int { return cmp.Compare(x.Time, y.Time) })
+
+	if err := os.MkdirAll(filepath.Dir(g.Path), 0o755); err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(g.Path, append(out, '\n'), 0o644)
+}
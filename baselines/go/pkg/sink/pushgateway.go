@@ -0,0 +1,112 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"lambdaperf/pkg/results"
+)
+
+// DefaultJob is the Pushgateway job runs are pushed under.
+const DefaultJob = "ruchy_bench"
+
+// Pushgateway pushes a run's summaries to a Prometheus Pushgateway as
+// gauges, one per metric, such as ruchy_bench_duration_ms, with a stat
+// label for each summary statistic. Runs are grouped by mode, so a
+// coldstart run replaces the last coldstart run's gauges but leaves
+// those of run alone.
+type Pushgateway struct {
+	// URL is the gateway's base URL, such as http://localhost:9091.
+	URL string
+	// Job is the job label; empty means DefaultJob.
+	Job string
+	// HTTP pushes to the gateway; nil means http.DefaultClient.
+	HTTP *http.Client
+}
+
+func (p Pushgateway) Write(ctx context.Context, run *results.Run) error {
+	job := p.Job
+	if job == "" {
+		job = DefaultJob
+	}
+	endpoint := strings.TrimSuffix(p.URL, "/") + "/metrics/job/" + url.PathEscape(job) + "/mode/" + url.PathEscape(run.Mode)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(exposition(run)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+	client := p.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("push to %s: %w", p.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("push to %s: %s: %s", p.URL, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// exposition renders run in the Prometheus text format. Every sample of
+// a gauge has to follow its TYPE line, so samples are grouped by metric.
+func exposition(run *results.Run) []byte {
+	var buf bytes.Buffer
+	for _, m := range results.Metrics {
+		name := DefaultJob + "_" + promName(m)
+		var typed bool
+		for _, r := range run.Results {
+			st, ok := r.Stats[m]
+			if !ok {
+				continue
+			}
+			if !typed {
+				fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+				typed = true
+			}
+			ls := labels(r)
+			for _, v := range []struct {
+				stat  string
+				value float64
+			}{
+				{"n", float64(st.N)}, {"mean", st.Mean}, {"median", st.Median}, {"p95", st.P95}, {"p99", st.P99},
+				{"stddev", st.StdDev}, {"min", st.Min}, {"max", st.Max},
+			} {
+				buf.WriteString(name)
+				buf.WriteString("{")
+				for _, l := range append(ls, label{"stat", v.stat}) {
+					fmt.Fprintf(&buf, `%s="%s",`, l.name, promQuote.Replace(l.value))
+				}
+				buf.Truncate(buf.Len() - 1)
+				buf.WriteString("} ")
+				buf.WriteString(strconv.FormatFloat(v.value, 'g', -1, 64))
+				buf.WriteString("\n")
+			}
+		}
+	}
+	name := DefaultJob + "_run_finished_timestamp_seconds"
+	fmt.Fprintf(&buf, "# TYPE %s gauge\n%s %d\n", name, name, run.FinishedAt.Unix())
+	return buf.Bytes()
+}
+
+var invalidName = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// promName turns a metric name such as cache-references into a valid
+// Prometheus name.
+func promName(metric string) string {
+	return invalidName.ReplaceAllString(metric, "_")
+}
+
+// promQuote escapes a label value for the text format, which knows only
+// backslashes, double quotes and newlines.
+var promQuote = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
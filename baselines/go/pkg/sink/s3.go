@@ -0,0 +1,96 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"lambdaperf/pkg/results"
+)
+
+// S3API is the subset of the S3 client S3 uses.
+type S3API interface {
+	PutObject(ctx context.Context, in *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, in *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// S3 stores each run as a results file, the JSON sink's document, at
+// <Prefix><run ID>.json in Bucket. Run IDs are start times, so the
+// objects list in the order the runs were made and the bucket is a
+// history any machine can read, unlike the local history database.
+type S3 struct {
+	Client S3API
+	Bucket string
+	// Prefix is prepended to every key, such as "nightly/".
+	Prefix string
+}
+
+func (s S3) Write(ctx context.Context, run *results.Run) error {
+	data, err := results.Encode(run)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(s.key(run.ID)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		return fmt.Errorf("put s3://%s/%s: %w", s.Bucket, s.key(run.ID), err)
+	}
+	return nil
+}
+
+func (s S3) key(id string) string { return s.Prefix + id + ".json" }
+
+// Latest returns the newest stored run whose ID sorts before the given
+// one, nil when there is none. An empty before means the newest of all.
+func (s S3) Latest(ctx context.Context, before string) (*results.Run, error) {
+	var (
+		latest string
+		token  *string
+	)
+	for {
+		out, err := s.Client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(s.Bucket),
+			Prefix:            aws.String(s.Prefix),
+			ContinuationToken: token,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list s3://%s/%s: %w", s.Bucket, s.Prefix, err)
+		}
+		for _, obj := range out.Contents {
+			id, ok := strings.CutSuffix(strings.TrimPrefix(aws.ToString(obj.Key), s.Prefix), ".json")
+			if ok && !strings.Contains(id, "/") && (before == "" || id < before) && id > latest {
+				latest = id
+			}
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			break
+		}
+		token = out.NextContinuationToken
+	}
+	if latest == "" {
+		return nil, nil
+	}
+	out, err := s.Client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(s.Bucket), Key: aws.String(s.key(latest))})
+	if err != nil {
+		return nil, fmt.Errorf("get s3://%s/%s: %w", s.Bucket, s.key(latest), err)
+	}
+	defer out.Body.Close()
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, err
+	}
+	run, err := results.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse s3://%s/%s: %w", s.Bucket, s.key(latest), err)
+	}
+	return run, nil
+}
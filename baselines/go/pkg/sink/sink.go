@@ -0,0 +1,183 @@
+// Package sink writes a summarized run wherever its consumers read it.
+// The JSON results file is what the harness's own commands read; CSV
+// suits spreadsheets, and its per-sample form statistics packages, a Prometheus Pushgateway dashboards, CloudWatch
+// custom metrics alarms and CI in the benchmark account, and an S3 bucket
+// a results history shared between machines, such as ruchy-bench daemon's.
+package sink
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"lambdaperf/pkg/lambdalog"
+	"lambdaperf/pkg/results"
+)
+
+// Sink receives a run once it has been summarized.
+type Sink interface {
+	Write(ctx context.Context, run *results.Run) error
+}
+
+// JSON writes the run as a results file; see results.Write.
+type JSON struct {
+	Path string
+}
+
+func (s JSON) Write(_ context.Context, run *results.Run) error {
+	return results.Write(s.Path, run)
+}
+
+// CSV writes one row per result and metric: the run, the result's labels
+// and the metric's summary, creating parent directories as needed.
+type CSV struct {
+	Path string
+}
+
+var csvHeader = []string{"run_id", "mode", "started_at", "runtime", "workload", "kind", "arch", "package", "snapstart",
+	"extension", "vpc", "chaos", "serializer", "edge", "sandbox", "region", "memory_mb", "function", "input", "metric", "n", "mean", "median", "p95", "p99", "stddev",
+	"min", "max", "ci95_low", "ci95_high", "rejected"}
+
+func (s CSV) Write(_ context.Context, run *results.Run) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(s.Path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write(csvHeader)
+	num := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	for _, r := range run.Results {
+		for _, m := range metrics(r) {
+			st := r.Stats[m]
+			memory := ""
+			if r.MemoryMB > 0 {
+				memory = strconv.Itoa(int(r.MemoryMB))
+			}
+			w.Write([]string{run.ID, run.Mode, run.StartedAt.Format(time.RFC3339), r.Runtime, r.Workload,
+				r.Kind, r.Arch, r.Package, strconv.FormatBool(r.SnapStart), strconv.FormatBool(r.Extension), strconv.FormatBool(r.VPC), strconv.FormatBool(r.Chaos), r.Serializer, strconv.FormatBool(r.Edge), r.Sandbox, r.Region,
+				memory, r.Function, r.InputLabel(), m, strconv.Itoa(st.N), num(st.Mean), num(st.Median), num(st.P95),
+				num(st.P99), num(st.StdDev), num(st.Min), num(st.Max), num(st.CILow), num(st.CIHigh), strconv.Itoa(st.Rejected)})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Samples writes one row per sample and metric, failed and warm-up
+// samples included and marked as such: the raw data behind every summary,
+// in the long format R and pandas read, for analysis the harness does not
+// do. It creates parent directories as needed.
+type Samples struct {
+	Path string
+}
+
+var samplesHeader = []string{"run_id", "mode", "runtime", "workload", "kind", "arch", "package", "snapstart",
+	"extension", "vpc", "chaos", "serializer", "edge", "sandbox", "region", "memory_mb", "function", "input", "iteration", "warmup", "cold",
+	"cpu_generation", "host_sandbox", "retries", "excluded", "error", "metric", "value"}
+
+func (s Samples) Write(_ context.Context, run *results.Run) error {
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(s.Path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write(samplesHeader)
+	for _, r := range run.Results {
+		memory := ""
+		if m := r.Memory(); m > 0 {
+			memory = strconv.Itoa(int(m))
+		}
+		for _, sm := range r.Samples {
+			var host lambdalog.Host
+			if sm.Host != nil {
+				host = *sm.Host
+			}
+			for _, m := range results.Metrics {
+				v, ok := sm.Value(m)
+				if !ok {
+					continue
+				}
+				w.Write([]string{run.ID, run.Mode, r.Runtime, r.Workload, r.Kind, r.Arch, r.Package,
+					strconv.FormatBool(r.SnapStart), strconv.FormatBool(r.Extension), strconv.FormatBool(r.VPC), strconv.FormatBool(r.Chaos), r.Serializer, strconv.FormatBool(r.Edge),
+					r.Sandbox, r.Region, memory, r.Function, r.InputLabel(), strconv.Itoa(sm.Iteration),
+					strconv.FormatBool(sm.Warmup), strconv.FormatBool(sm.Cold), host.CPUGeneration, host.Sandbox, strconv.Itoa(sm.Retries), sm.Excluded, sm.Error,
+					m, strconv.FormatFloat(v, 'f', -1, 64)})
+			}
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// metrics are the metrics r has stats for, in results.Metrics order.
+func metrics(r results.Result) []string {
+	var ms []string
+	for _, m := range results.Metrics {
+		if _, ok := r.Stats[m]; ok {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// label is one of the names a result is told apart by.
+type label struct{ name, value string }
+
+// labels identify r among a run's results: its runtime, workload and
+// kind, and the variant fields that are set.
+func labels(r results.Result) []label {
+	ls := []label{{"runtime", r.Runtime}, {"workload", r.Workload}, {"kind", r.Kind}}
+	for _, l := range []label{
+		{"arch", r.Arch},
+		{"package", r.Package},
+		{"region", r.Region},
+		{"function", r.Function},
+		{"input", r.InputLabel()},
+	} {
+		if l.value != "" {
+			ls = append(ls, l)
+		}
+	}
+	if r.MemoryMB > 0 {
+		ls = append(ls, label{"memory_mb", strconv.Itoa(int(r.MemoryMB))})
+	}
+	if r.SnapStart {
+		ls = append(ls, label{"snapstart", "true"})
+	}
+	if r.Extension {
+		ls = append(ls, label{"extension", "true"})
+	}
+	if r.VPC {
+		ls = append(ls, label{"vpc", "true"})
+	}
+	if r.Chaos {
+		ls = append(ls, label{"chaos", "true"})
+	}
+	if r.Serializer != "" {
+		ls = append(ls, label{"serializer", r.Serializer})
+	}
+	if r.Edge {
+		ls = append(ls, label{"edge", "true"})
+	}
+	if r.Sandbox != "" {
+		ls = append(ls, label{"sandbox", r.Sandbox})
+	}
+	return ls
+}
@@ -0,0 +1,275 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"lambdaperf/pkg/cwmetrics"
+	"lambdaperf/pkg/lambdalog"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+func testRun() *results.Run {
+	run := results.NewRun("run", time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC))
+	run.FinishedAt = run.StartedAt.Add(time.Minute)
+	var samples []results.Sample
+	for i := range 200 {
+		samples = append(samples, results.Sample{Iteration: i, ClientMS: float64(10 + i%5), Cold: i == 0, RequestID: "r", DurationMS: 2})
+	}
+	run.Results = []results.Result{
+		{Runtime: "go", Workload: "fibonacci", Kind: "lambda", Function: "baseline-go-fibonacci", MemoryMB: 128, Samples: samples},
+		{Runtime: "ruchy", Workload: "fibonacci", Kind: "lambda", Region: `eu-west-1"\`, Extension: true, Samples: samples[:3]},
+	}
+	run.Summarize(stats.Options{})
+	return run
+}
+
+func TestCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "run.csv")
+	if err := (CSV{Path: path}).Write(context.Background(), testRun()); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// client, duration, warm, billed, server and overhead for each result.
+	if len(rows) != 1+2*6 || strings.Join(rows[0][:3], ",") != "run_id,mode,started_at" {
+		t.Fatalf("%d rows, header %v", len(rows), rows[0])
+	}
+	if got := strings.Join(rows[1][:21], ","); got != "20261014T100000Z,run,2026-10-14T10:00:00Z,go,fibonacci,lambda,,,false,false,false,false,,false,,,128,baseline-go-fibonacci,,client_ms,200" {
+		t.Errorf("first row = %s", got)
+	}
+}
+
+func TestSamples(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "samples.csv")
+	run := testRun()
+	run.Results[1].Samples[2].Error = "boom"
+	run.Results[0].Samples[0].Host = &lambdalog.Host{CPUGeneration: "icelake", Sandbox: "3fc0cf890b4e"}
+	if err := (Samples{Path: path}).Write(context.Background(), run); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// client, duration, billed, server and overhead for every sample, and
+	// warm for all but the cold one.
+	if want := 1 + 203*6 - 2; len(rows) != want {
+		t.Fatalf("%d rows, want %d", len(rows), want)
+	}
+	if got := strings.Join(rows[1], ","); got != "20261014T100000Z,run,go,fibonacci,lambda,,,false,false,false,false,,false,,,128,baseline-go-fibonacci,,0,false,true,icelake,3fc0cf890b4e,0,,,client_ms,10" {
+		t.Errorf("first row = %s", got)
+	}
+	if last := rows[len(rows)-1]; last[25] != "boom" || last[26] != "overhead_ms" {
+		t.Errorf("last row = %v", last)
+	}
+}
+
+func TestPushgateway(t *testing.T) {
+	var path, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		path, body = r.Method+" "+r.URL.Path, string(data)
+	}))
+	defer srv.Close()
+	if err := (Pushgateway{URL: srv.URL + "/"}).Write(context.Background(), testRun()); err != nil {
+		t.Fatal(err)
+	}
+	if path != "PUT /metrics/job/ruchy_bench/mode/run" {
+		t.Errorf("pushed %s", path)
+	}
+	for _, want := range []string{
+		"# TYPE ruchy_bench_client_ms gauge\n",
+		`ruchy_bench_client_ms{runtime="go",workload="fibonacci",kind="lambda",function="baseline-go-fibonacci",memory_mb="128",stat="n"} 200` + "\n",
+		`ruchy_bench_warm_ms{runtime="ruchy",workload="fibonacci",kind="lambda",region="eu-west-1\"\\",extension="true",stat="p99"} 2` + "\n",
+		"ruchy_bench_run_finished_timestamp_seconds 1791972060\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+	if n := strings.Count(body, "# TYPE ruchy_bench_warm_ms "); n != 1 {
+		t.Errorf("warm_ms typed %d times", n)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "pushed metrics are invalid", http.StatusBadRequest)
+	}))
+	defer failing.Close()
+	if err := (Pushgateway{URL: failing.URL}).Write(context.Background(), testRun()); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("rejected push: %v", err)
+	}
+}
+
+func TestCloudWatch(t *testing.T) {
+	type datum struct {
+		MetricName string
+		Dimensions []struct{ Name, Value string }
+		Unit       string
+		Values     []float64
+	}
+	var got []datum
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in struct {
+			Namespace  string
+			MetricData []datum
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		if in.Namespace != DefaultNamespace {
+			t.Errorf("namespace %q", in.Namespace)
+		}
+		got = append(got, in.MetricData...)
+	}))
+	defer srv.Close()
+	if err := (CloudWatch{Client: &cwmetrics.Client{Endpoint: srv.URL}}).Write(context.Background(), testRun()); err != nil {
+		t.Fatal(err)
+	}
+	// The go result's 200 client, duration, server and overhead values need
+	// two data each, its 199 warm ones too; the ruchy result's fit in one
+	// per metric.
+	if len(got) != 6*2+6 {
+		t.Fatalf("%d data", len(got))
+	}
+	first := got[0]
+	if first.MetricName != "client_ms" || first.Unit != "Milliseconds" || len(first.Values) != cwmetrics.MaxValues {
+		t.Errorf("first datum = %s in %s with %d values", first.MetricName, first.Unit, len(first.Values))
+	}
+	dims := map[string]string{}
+	for _, d := range first.Dimensions {
+		dims[d.Name] = d.Value
+	}
+	if dims["Mode"] != "run" || dims["Runtime"] != "go" || dims["MemoryMb"] != "128" || len(dims) != 6 {
+		t.Errorf("dimensions = %v", dims)
+	}
+}
+
+func TestGrafana(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grafana.json")
+	first := testRun()
+	g := Grafana{Path: path, Commit: "3f4e2a1", Versions: map[string]string{"go": "go1.24.2", "ruchy": "ruchy 3.208.0"}}
+	if err := g.Write(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	second := testRun()
+	second.ID, second.StartedAt, second.FinishedAt = "later", first.StartedAt.Add(time.Hour), first.FinishedAt.Add(time.Hour)
+	g.Commit = "9c0d1b2"
+	// Writing a run twice replaces it.
+	for range 2 {
+		if err := g.Write(context.Background(), second); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Dashboard
+	if err := json.Unmarshal(data, &d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Annotations) != 2 || len(d.Points) != 2*12 {
+		t.Fatalf("%d annotations, %d points", len(d.Annotations), len(d.Points))
+	}
+	a := d.Annotations[1]
+	if a.RunID != "later" || a.Time != 1791975600000 || a.TimeEnd != 1791975660000 ||
+		strings.Join(a.Tags, ",") != "run,commit:9c0d1b2,new-commit" ||
+		a.Text != "2 results; commit 9c0d1b2; go go1.24.2; ruchy ruchy 3.208.0" || len(a.MemoryMB) != 1 {
+		t.Errorf("second annotation = %+v", a)
+	}
+	if tags := strings.Join(d.Annotations[0].Tags, ","); tags != "run,commit:3f4e2a1" {
+		t.Errorf("first annotation tags = %s", tags)
+	}
+	p := d.Points[len(d.Points)-1]
+	if p.RunID != "later" || p.Series != `ruchy/fibonacci kind=lambda region=eu-west-1"\ extension=true` || p.Metric != "overhead_ms" {
+		t.Errorf("last point = %+v", p)
+	}
+}
+
+// bucket is an in-memory S3API that lists one key per page.
+type bucket map[string][]byte
+
+func (b bucket) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	b[aws.ToString(in.Key)] = data
+	return &s3.PutObjectOutput{}, err
+}
+
+func (b bucket) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	data, ok := b[aws.ToString(in.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (b bucket) ListObjectsV2(_ context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	keys := slices.Sorted(maps.Keys(b))
+	out := &s3.ListObjectsV2Output{}
+	for _, k := range keys {
+		if strings.HasPrefix(k, aws.ToString(in.Prefix)) && k > aws.ToString(in.ContinuationToken) {
+			out.Contents = []types.Object{{Key: aws.String(k)}}
+			out.IsTruncated, out.NextContinuationToken = aws.Bool(true), aws.String(k)
+			break
+		}
+	}
+	return out, nil
+}
+
+func TestS3(t *testing.T) {
+	ctx := context.Background()
+	b := bucket{"nightly/notes.txt": nil, "nightly/sub/20991231T000000Z.json": nil, "other/20991231T000000Z.json": nil}
+	s := S3{Client: b, Bucket: "bench", Prefix: "nightly/"}
+	if run, err := s.Latest(ctx, ""); err != nil || run != nil {
+		t.Fatalf("Latest of no runs = %v, %v", run, err)
+	}
+	older, newer := testRun(), testRun()
+	older.ID, newer.ID = "20261013T020000Z", "20261014T020000Z"
+	for _, run := range []*results.Run{newer, older} {
+		if err := s.Write(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := b["nightly/20261014T020000Z.json"]; !ok {
+		t.Errorf("stored keys %v", slices.Sorted(maps.Keys(b)))
+	}
+	for before, want := range map[string]string{"": newer.ID, newer.ID: older.ID, "20261014T000000Z": older.ID} {
+		run, err := s.Latest(ctx, before)
+		if err != nil || run == nil || run.ID != want || len(run.Results) != 2 {
+			t.Errorf("Latest(%q) = %v, %v; want run %s", before, run, err, want)
+		}
+	}
+	if run, err := s.Latest(ctx, older.ID); err != nil || run != nil {
+		t.Errorf("Latest before the oldest = %v, %v", run, err)
+	}
+}
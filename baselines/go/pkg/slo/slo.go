@@ -0,0 +1,109 @@
+// Package slo checks a run against the SLOs benchmarks/manifest.yaml
+// declares per workload, such as a mean cold start under 100 ms at 256 MB
+// or a warm p99 under 5 ms. A leaderboard says which runtime is fastest;
+// SLOs say whether a runtime is fast enough, which is what gates a Ruchy
+// release. ruchy-bench report renders the checks and fails on those of
+// the runtimes it gates.
+package slo
+
+import (
+	"fmt"
+	"sort"
+
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// Check is one result measured against one SLO of its workload.
+type Check struct {
+	Result results.Result
+	SLO    manifest.SLO
+	// Value is the bounded statistic of the result's samples, and N the
+	// number of samples behind it; both are zero for a failed result.
+	Value float64
+	N     int
+}
+
+// Met reports whether the result met the SLO. A failed result meets none.
+func (c Check) Met() bool {
+	return c.Result.Error == "" && c.N > 0 && c.Value <= c.SLO.Max
+}
+
+// Evaluate checks every result of run against its workload's SLOs, in
+// run order and then the manifest's. Results must be summarized first.
+// An SLO applies to a result at its memory size that measured the metric,
+// so a cold start SLO is not failed by a run that measured no cold
+// starts; failed results are checked against every SLO at their memory
+// size, and fail them.
+func Evaluate(run *results.Run, m *manifest.Manifest) []Check {
+	var checks []Check
+	for _, r := range run.Results {
+		w, ok := m.Workload(r.Workload)
+		if !ok {
+			continue
+		}
+		for _, s := range w.SLOs {
+			if s.MemoryMB != 0 && s.MemoryMB != r.Memory() {
+				continue
+			}
+			if r.Error != "" {
+				checks = append(checks, Check{Result: r, SLO: s})
+				continue
+			}
+			sum, ok := r.Stats[s.Metric]
+			if !ok || sum.N == 0 {
+				continue
+			}
+			checks = append(checks, Check{Result: r, SLO: s, Value: stat(sum, s.Stat), N: sum.N})
+		}
+	}
+	return checks
+}
+
+// stat picks the named statistic out of s.
+func stat(s stats.Summary, name string) float64 {
+	switch name {
+	case "mean":
+		return s.Mean
+	case "p50":
+		return s.Median
+	case "p95":
+		return s.P95
+	case "p99":
+		return s.P99
+	case "max":
+		return s.Max
+	}
+	panic(fmt.Sprintf("slo: unknown stat %q", name))
+}
+
+// Compliance is how many of a runtime's checks it met.
+type Compliance struct {
+	Runtime      string
+	Met, Checked int
+}
+
+// Passed reports whether the runtime met every SLO it was checked against.
+func (c Compliance) Passed() bool { return c.Met == c.Checked }
+
+// ByRuntime totals checks per runtime, sorted by runtime.
+func ByRuntime(checks []Check) []Compliance {
+	by := map[string]*Compliance{}
+	for _, c := range checks {
+		rt := c.Result.Runtime
+		if by[rt] == nil {
+			by[rt] = &Compliance{Runtime: rt}
+		}
+		by[rt].Checked++
+		if c.Met() {
+			by[rt].Met++
+		}
+	}
+	out := make([]Compliance, 0, len(by))
+	for _, c := range by {
+		out = append(out, *c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Runtime < out[j].Runtime })
+	return out
+}
@@ -0,0 +1,82 @@
+package slo
+
+import (
+	"testing"
+
+	"lambdaperf/pkg/manifest"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+const sample = `
+workloads:
+  - name: fibonacci
+    runtimes:
+      lambda: [go, python, ruchy]
+    slos:
+      - {metric: init_ms, stat: mean, max: 10, memory_mb: 128}
+      - {metric: warm_ms, stat: p99, max: 5}
+  - name: minimal
+    runtimes:
+      lambda: [go]
+`
+
+func lambda(rt string, initMS float64, warm ...float64) results.Result {
+	r := results.Result{Runtime: rt, Workload: "fibonacci", Kind: "lambda", MemoryMB: 128}
+	r.Samples = append(r.Samples, results.Sample{RequestID: "req", DurationMS: 100, MemorySizeMB: 128, Cold: true, InitMS: initMS})
+	for i, d := range warm {
+		r.Samples = append(r.Samples, results.Sample{Iteration: i + 1, RequestID: "req", DurationMS: d, MemorySizeMB: 128})
+	}
+	return r
+}
+
+func TestEvaluate(t *testing.T) {
+	m, err := manifest.Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := lambda("go", 40, 1, 2)
+	big.MemoryMB = 1024
+	for i := range big.Samples {
+		big.Samples[i].MemorySizeMB = 1024
+	}
+	run := &results.Run{Results: []results.Result{
+		lambda("ruchy", 8, 1, 2, 3),
+		lambda("go", 12, 1, 9),
+		big,
+		{Runtime: "python", Workload: "fibonacci", Kind: "lambda", MemoryMB: 128, Error: "not deployed"},
+		{Runtime: "go", Workload: "minimal", Kind: "lambda", Samples: []results.Sample{{RequestID: "req", DurationMS: 1}}},
+		{Runtime: "go", Workload: "fibonacci", Kind: "local", Samples: []results.Sample{{ClientMS: 30}}},
+	}}
+	run.Summarize(stats.Options{})
+	checks := Evaluate(run, m)
+	var got []string
+	for _, c := range checks {
+		got = append(got, c.Result.Runtime+" "+c.SLO.Metric+" "+map[bool]string{true: "met", false: "missed"}[c.Met()])
+	}
+	// The 1024 MB result is checked against the SLO without a memory
+	// size only, and the local result, with no warm or init samples, not
+	// at all.
+	want := []string{
+		"ruchy init_ms met", "ruchy warm_ms met",
+		"go init_ms missed", "go warm_ms missed",
+		"go warm_ms met",
+		"python init_ms missed", "python warm_ms missed",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("checks = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("check %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	if c := checks[0]; c.Value != 8 || c.N != 1 {
+		t.Errorf("ruchy init check = %+v", c)
+	}
+
+	by := ByRuntime(checks)
+	if len(by) != 3 || by[0] != (Compliance{"go", 1, 3}) || by[1] != (Compliance{"python", 0, 2}) || !by[2].Passed() {
+		t.Errorf("ByRuntime = %+v", by)
+	}
+}
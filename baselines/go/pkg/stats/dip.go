@@ -0,0 +1,308 @@
+package stats
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"sync"
+)
+
+// DipAlpha is the significance level at which Bimodal calls a sample
+// multimodal. It is stricter than 0.05 because every result tests two
+// metrics, and a run has dozens of results.
+const DipAlpha = 0.01
+
+// MinModalSamples is the smallest sample Bimodal tests: the dip test has
+// next to no power below it.
+const MinModalSamples = 20
+
+// dipReplicates is the number of uniform samples DipTest draws to place a
+// dip in its null distribution, which gives p-values to about ±0.003
+// around DipAlpha.
+const dipReplicates = 2000
+
+// Dip returns Hartigan's dip statistic of xs: the largest distance, over
+// all values, between the empirical distribution function and the
+// closest unimodal one. It ranges from 1/(2n), for evenly spaced values,
+// to 1/4, for two equal point masses. The computation is algorithm AS 217
+// (Hartigan 1985) as the R diptest package implements it.
+func Dip(xs []float64) float64 {
+	n := len(xs)
+	if n == 0 {
+		return 0
+	}
+	// 1-based, as the algorithm is written.
+	x := make([]float64, n+1)
+	copy(x[1:], xs)
+	sort.Float64s(x[1:])
+	dip := 1.0
+	if n < 2 || x[n] == x[1] {
+		return dip / float64(2*n)
+	}
+
+	// mn and mj link each point to the one before (after) it on the
+	// greatest convex minorant (least concave majorant) of the points up
+	// to (from) it.
+	mn, mj := make([]int, n+1), make([]int, n+1)
+	mn[1] = 1
+	for j := 2; j <= n; j++ {
+		mn[j] = j - 1
+		for {
+			mnj := mn[j]
+			mnmnj := mn[mnj]
+			if mnj == 1 || (x[j]-x[mnj])*float64(mnj-mnmnj) < (x[mnj]-x[mnmnj])*float64(j-mnj) {
+				break
+			}
+			mn[j] = mnmnj
+		}
+	}
+	mj[n] = n
+	for k := n - 1; k >= 1; k-- {
+		mj[k] = k + 1
+		for {
+			mjk := mj[k]
+			mjmjk := mj[mjk]
+			if mjk == n || (x[k]-x[mjk])*float64(mjk-mjmjk) < (x[mjk]-x[mjmjk])*float64(k-mjk) {
+				break
+			}
+			mj[k] = mjmjk
+		}
+	}
+
+	// Narrow the modal interval [low, high] until the fit inside it is
+	// no worse than the dip found outside it.
+	gcm, lcm := make([]int, n+2), make([]int, n+2)
+	low, high := 1, n
+	for {
+		gcm[1] = high
+		i := 1
+		for ; gcm[i] > low; i++ {
+			gcm[i+1] = mn[gcm[i]]
+		}
+		ig, lgcm := i, i
+		ix := ig - 1
+
+		lcm[1] = low
+		i = 1
+		for ; lcm[i] < high; i++ {
+			lcm[i+1] = mj[lcm[i]]
+		}
+		ih, llcm := i, i
+		iv := 2
+
+		// The largest distance between the minorant and the majorant
+		// over the interval.
+		d := 1.0
+		if lgcm != 2 || llcm != 2 {
+			d = 0
+			for {
+				gcmix, lcmiv := gcm[ix], lcm[iv]
+				if gcmix > lcmiv {
+					gcmi1 := gcm[ix+1]
+					dx := float64(lcmiv-gcmi1+1) - (x[lcmiv]-x[gcmi1])*float64(gcmix-gcmi1)/(x[gcmix]-x[gcmi1])
+					iv++
+					if dx >= d {
+						d, ig, ih = dx, ix+1, iv-1
+					}
+				} else {
+					lcmiv1 := lcm[iv-1]
+					dx := (x[gcmix]-x[lcmiv1])*float64(lcmiv-lcmiv1)/(x[lcmiv]-x[lcmiv1]) - float64(gcmix-lcmiv1-1)
+					ix--
+					if dx >= d {
+						d, ig, ih = dx, ix+1, iv
+					}
+				}
+				ix = max(ix, 1)
+				iv = min(iv, llcm)
+				if gcm[ix] == lcm[iv] {
+					break
+				}
+			}
+		}
+		if d < dip {
+			break
+		}
+
+		// The dips of the minorant and the majorant.
+		dipL := 0.0
+		for j := ig; j < lgcm; j++ {
+			maxT := 1.0
+			jb, je := gcm[j+1], gcm[j]
+			if je-jb > 1 && x[je] != x[jb] {
+				c := float64(je-jb) / (x[je] - x[jb])
+				for jj := jb; jj <= je; jj++ {
+					maxT = max(maxT, float64(jj-jb+1)-(x[jj]-x[jb])*c)
+				}
+			}
+			dipL = max(dipL, maxT)
+		}
+		dipU := 0.0
+		for j := ih; j < llcm; j++ {
+			maxT := 1.0
+			jb, je := lcm[j], lcm[j+1]
+			if je-jb > 1 && x[je] != x[jb] {
+				c := float64(je-jb) / (x[je] - x[jb])
+				for jj := jb; jj <= je; jj++ {
+					maxT = max(maxT, (x[jj]-x[jb])*c-float64(jj-jb-1))
+				}
+			}
+			dipU = max(dipU, maxT)
+		}
+		dip = max(dip, dipL, dipU)
+
+		if low == gcm[ig] && high == lcm[ih] {
+			break
+		}
+		low, high = gcm[ig], lcm[ih]
+	}
+	return dip / float64(2*n)
+}
+
+// dipNull caches, per sample size, the sorted dips of dipReplicates
+// uniform samples.
+var dipNull = struct {
+	sync.Mutex
+	dips map[int][]float64
+}{dips: map[int][]float64{}}
+
+// DipTest returns the dip of xs and the p-value of the null hypothesis
+// that xs comes from a unimodal distribution. The uniform distribution is
+// the least favorable unimodal one, so the p-value is the share of
+// uniform samples of the same size whose dip is at least as large,
+// estimated from a fixed-seed simulation that makes it reproducible.
+// Tied values are first spread evenly over their rounding interval; see
+// untie.
+func DipTest(xs []float64) (dip, p float64) {
+	n := len(xs)
+	dip = Dip(untie(xs))
+	if n < 2 {
+		return dip, 1
+	}
+	null := dipNullDist(n)
+	above := len(null) - sort.SearchFloat64s(null, dip)
+	return dip, float64(above+1) / float64(len(null)+1)
+}
+
+// untie returns xs sorted, with each run of k equal values v spread
+// evenly over [v-r/2, v+r/2), r being the smallest gap between distinct
+// values. Timings are rounded, to 0.01 ms in REPORT lines, and a narrow
+// distribution rounded to a few distinct values is a row of point
+// masses, which the dip test would call multimodal however smooth the
+// distribution underneath.
+func untie(xs []float64) []float64 {
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	r := math.Inf(1)
+	for i := 1; i < len(sorted); i++ {
+		if gap := sorted[i] - sorted[i-1]; gap > 0 {
+			r = min(r, gap)
+		}
+	}
+	if math.IsInf(r, 1) {
+		return sorted // all equal
+	}
+	for i := 0; i < len(sorted); {
+		j := i
+		for j < len(sorted) && sorted[j] == sorted[i] {
+			j++
+		}
+		if k := j - i; k > 1 {
+			v := sorted[i]
+			for m := range k {
+				sorted[i+m] = v + r*((float64(m)+0.5)/float64(k)-0.5)
+			}
+		}
+		i = j
+	}
+	return sorted
+}
+
+func dipNullDist(n int) []float64 {
+	dipNull.Lock()
+	defer dipNull.Unlock()
+	if null, ok := dipNull.dips[n]; ok {
+		return null
+	}
+	rng := rand.New(rand.NewPCG(uint64(n), 0x646970))
+	null := make([]float64, dipReplicates)
+	u := make([]float64, n)
+	for i := range null {
+		for j := range u {
+			u[j] = rng.Float64()
+		}
+		null[i] = Dip(u)
+	}
+	sort.Float64s(null)
+	dipNull.dips[n] = null
+	return null
+}
+
+// Modes describes a sample the dip test found bimodal, split in two
+// where the split leaves the least variance within each side: the modes
+// a benchmark's timings fall into when its invocations land on hosts of
+// different generations or beside noisy neighbors.
+type Modes struct {
+	Dip float64 `json:"dip"`
+	P   float64 `json:"p"`
+	// Split is the largest value of the lower mode.
+	Split float64 `json:"split"`
+	// LowShare is the fraction of the values in the lower mode.
+	LowShare float64 `json:"low_share"`
+	Low      Summary `json:"low"`
+	High     Summary `json:"high"`
+}
+
+// Larger returns the summary of the mode holding more of the values, the
+// lower one on a tie.
+func (m Modes) Larger() Summary {
+	if m.LowShare >= 0.5 {
+		return m.Low
+	}
+	return m.High
+}
+
+// Bimodal tests xs for multimodality at DipAlpha and, when it rejects
+// unimodality, returns xs's two modes.
+func Bimodal(xs []float64) (Modes, bool) {
+	if len(xs) < MinModalSamples {
+		return Modes{}, false
+	}
+	dip, p := DipTest(xs)
+	if p >= DipAlpha {
+		return Modes{}, false
+	}
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	k := splitTwo(sorted)
+	return Modes{
+		Dip:      dip,
+		P:        p,
+		Split:    sorted[k-1],
+		LowShare: float64(k) / float64(len(sorted)),
+		Low:      Summarize(sorted[:k], Options{}),
+		High:     Summarize(sorted[k:], Options{}),
+	}, true
+}
+
+// splitTwo returns the k for which sorted[:k] and sorted[k:] have the
+// least total squared deviation from their means, with both non-empty.
+func splitTwo(sorted []float64) int {
+	n := len(sorted)
+	var total float64
+	for _, x := range sorted {
+		total += x
+	}
+	best, bestK := 0.0, 1
+	var sum float64
+	for k := 1; k < n; k++ {
+		sum += sorted[k-1]
+		// Minimizing the within-side variance maximizes the between-side
+		// sum of squares, which needs only the sums.
+		right := total - sum
+		between := sum*sum/float64(k) + right*right/float64(n-k)
+		if k == 1 || between > best {
+			best, bestK = between, k
+		}
+	}
+	return bestK
+}
@@ -0,0 +1,86 @@
+package stats
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+func TestDip(t *testing.T) {
+	// Evenly spaced values have the least dip there is, 1/(2n).
+	even := make([]float64, 10)
+	for i := range even {
+		even[i] = float64(i)
+	}
+	if d := Dip(even); !approx(d, 1.0/20) {
+		t.Errorf("Dip(evenly spaced) = %v, want 0.05", d)
+	}
+	// Two tight, equal, far apart clusters approach the most, 1/4.
+	var two []float64
+	for i := range 50 {
+		two = append(two, float64(i)*0.01, 100+float64(i)*0.01)
+	}
+	if d := Dip(two); d < 0.24 || d > 0.25 {
+		t.Errorf("Dip(two clusters) = %v, want nearly 0.25", d)
+	}
+	if d := Dip([]float64{3, 3, 3}); !approx(d, 1.0/6) {
+		t.Errorf("Dip(identical) = %v", d)
+	}
+}
+
+// latencies returns n timings rounded to 0.01 ms as REPORT lines are:
+// around 1.5 ms with the given spread, and around 1.7 ms for every third
+// when bimodal.
+func latencies(rng *rand.Rand, n int, spread float64, bimodal bool) []float64 {
+	xs := make([]float64, n)
+	for i := range xs {
+		center := 1.5
+		if bimodal && i%3 == 0 {
+			center = 1.7
+		}
+		xs[i] = math.Round((center+rng.NormFloat64()*spread)*100) / 100
+	}
+	return xs
+}
+
+func TestBimodal(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 20 {
+		// Rounding leaves a narrow distribution only a few distinct
+		// values, which must not read as modes.
+		if m, ok := Bimodal(latencies(rng, 200, 0.02, false)); ok {
+			t.Fatalf("unimodal rounded sample called bimodal: %+v", m)
+		}
+		// Nor must a latency-like right skew.
+		skewed := make([]float64, 200)
+		for i := range skewed {
+			skewed[i] = math.Exp(1 + rng.NormFloat64()*0.5)
+		}
+		if m, ok := Bimodal(skewed); ok {
+			t.Fatalf("lognormal sample called bimodal: %+v", m)
+		}
+	}
+	m, ok := Bimodal(latencies(rng, 150, 0.02, true))
+	if !ok {
+		t.Fatal("two modes not found")
+	}
+	if m.P >= DipAlpha || m.Split != m.Low.Max || m.Split > 1.65 || math.Abs(m.LowShare-2.0/3) > 0.05 {
+		t.Errorf("modes %+v", m)
+	}
+	if m.Low.N+m.High.N != 150 || m.Larger().N != m.Low.N {
+		t.Errorf("mode sizes %d, %d", m.Low.N, m.High.N)
+	}
+	if _, ok := Bimodal(latencies(rng, MinModalSamples-1, 0.02, true)); ok {
+		t.Error("tested a sample below MinModalSamples")
+	}
+
+	// Stratifying summarizes the larger mode only.
+	xs := latencies(rng, 150, 0.02, true)
+	s := Summarize(xs, Options{Stratify: true})
+	if s.Stratified == 0 || s.N+s.Stratified != len(xs) || s.Max > 1.65 {
+		t.Errorf("stratified summary %+v", s)
+	}
+	if s := Summarize(xs, Options{}); s.Stratified != 0 || s.N != len(xs) {
+		t.Errorf("unstratified summary %+v", s)
+	}
+}
@@ -0,0 +1,502 @@
+// Package stats summarizes raw per-invocation measurements. Every number
+// the harness reports (tables, results files, comparisons) is computed
+// here so they all agree on percentile and interval definitions.
+package stats
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// Summary describes a sample of measurements in milliseconds.
+type Summary struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	P95    float64 `json:"p95"`
+	P99    float64 `json:"p99"`
+	StdDev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	// CILow and CIHigh bound the 95% confidence interval of the mean.
+	CILow  float64 `json:"ci95_low"`
+	CIHigh float64 `json:"ci95_high"`
+	// Rejected counts values discarded as outliers before summarizing.
+	Rejected int `json:"rejected,omitempty"`
+	// Stratified counts values left out as the smaller mode of a
+	// bimodal sample; see Options.Stratify.
+	Stratified int `json:"stratified,omitempty"`
+}
+
+// Options controls how a sample is summarized. Each outlier policy set
+// applies in turn, to what the one before kept.
+type Options struct {
+	// RejectOutliers drops values whose modified z-score exceeds
+	// MADThreshold before summarizing.
+	RejectOutliers bool
+	// MADThreshold defaults to DefaultMADThreshold.
+	MADThreshold float64
+	// IQRFactor, when positive, drops values more than that many
+	// interquartile ranges outside the quartiles; see RejectIQR.
+	IQRFactor float64
+	// Trim, when positive, drops that fraction of the values from each
+	// end; see TrimEnds.
+	Trim float64
+	// Stratify, when set, summarizes only the larger mode of a sample
+	// that Bimodal finds bimodal once the outlier policies have run, so
+	// that platform variance splitting a sample is not read as the
+	// spread of what was measured.
+	Stratify bool
+}
+
+const (
+	// DefaultMADThreshold is the modified z-score cut-off recommended by
+	// Iglewicz and Hoaglin.
+	DefaultMADThreshold = 3.5
+	// DefaultIQRFactor places Tukey's fences.
+	DefaultIQRFactor = 1.5
+)
+
+// Reject applies the outlier policies of opts to xs, returning the kept
+// values in their original order and the number dropped.
+func Reject(xs []float64, opts Options) ([]float64, int) {
+	n := len(xs)
+	if opts.RejectOutliers {
+		threshold := opts.MADThreshold
+		if threshold == 0 {
+			threshold = DefaultMADThreshold
+		}
+		xs, _ = RejectMAD(xs, threshold)
+	}
+	if opts.IQRFactor > 0 {
+		xs, _ = RejectIQR(xs, opts.IQRFactor)
+	}
+	if opts.Trim > 0 {
+		xs, _ = TrimEnds(xs, opts.Trim)
+	}
+	return xs, n - len(xs)
+}
+
+// Filter returns the values of xs that Summarize summarizes under opts,
+// in their original order: those the outlier policies keep, then only the
+// larger mode of a bimodal sample when opts.Stratify is set. It also
+// returns the number each step dropped. Tests on a summarized sample
+// should run on these values.
+func Filter(xs []float64, opts Options) (kept []float64, rejected, stratified int) {
+	xs, rejected = Reject(xs, opts)
+	if opts.Stratify {
+		if m, ok := Bimodal(xs); ok {
+			n := len(xs)
+			xs = slices.DeleteFunc(slices.Clone(xs), func(x float64) bool { return (x <= m.Split) != (m.LowShare >= 0.5) })
+			stratified = n - len(xs)
+		}
+	}
+	return xs, rejected, stratified
+}
+
+// Summarize computes the summary of xs. An empty sample yields the zero
+// Summary.
+func Summarize(xs []float64, opts Options) Summary {
+	xs, rejected, stratified := Filter(xs, opts)
+	if len(xs) == 0 {
+		return Summary{Rejected: rejected, Stratified: stratified}
+	}
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+
+	s := Summary{
+		N:          len(sorted),
+		Mean:       Mean(sorted),
+		Median:     Percentile(sorted, 50),
+		P95:        Percentile(sorted, 95),
+		P99:        Percentile(sorted, 99),
+		StdDev:     StdDev(sorted),
+		Min:        sorted[0],
+		Max:        sorted[len(sorted)-1],
+		Rejected:   rejected,
+		Stratified: stratified,
+	}
+	half := tCritical95(s.N-1) * s.StdDev / math.Sqrt(float64(s.N))
+	s.CILow, s.CIHigh = s.Mean-half, s.Mean+half
+	return s
+}
+
+// Mean returns the arithmetic mean of xs.
+func Mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// StdDev returns the sample (n-1) standard deviation of xs.
+func StdDev(xs []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	m := Mean(xs)
+	var ss float64
+	for _, x := range xs {
+		ss += (x - m) * (x - m)
+	}
+	return math.Sqrt(ss / float64(len(xs)-1))
+}
+
+// Percentile returns the p-th percentile (0-100) of an ascending sorted
+// sample using linear interpolation between closest ranks.
+func Percentile(sorted []float64, p float64) float64 {
+	switch len(sorted) {
+	case 0:
+		return 0
+	case 1:
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	if lo == hi {
+		return sorted[lo]
+	}
+	return sorted[lo] + (rank-float64(lo))*(sorted[hi]-sorted[lo])
+}
+
+// Median returns the median of xs, which need not be sorted.
+func Median(xs []float64) float64 {
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	return Percentile(sorted, 50)
+}
+
+// MAD returns the median absolute deviation of xs.
+func MAD(xs []float64) float64 {
+	m := Median(xs)
+	dev := make([]float64, len(xs))
+	for i, x := range xs {
+		dev[i] = math.Abs(x - m)
+	}
+	return Median(dev)
+}
+
+// RejectMAD drops values whose modified z-score 0.6745*|x-median|/MAD is
+// above threshold. It returns the kept values in their original order and
+// the number rejected. A zero MAD (over half the values identical) keeps
+// everything.
+func RejectMAD(xs []float64, threshold float64) ([]float64, int) {
+	mad := MAD(xs)
+	if mad == 0 {
+		return xs, 0
+	}
+	m := Median(xs)
+	kept := make([]float64, 0, len(xs))
+	for _, x := range xs {
+		if 0.6745*math.Abs(x-m)/mad <= threshold {
+			kept = append(kept, x)
+		}
+	}
+	return kept, len(xs) - len(kept)
+}
+
+// RejectIQR drops values below Q1-k*IQR or above Q3+k*IQR, Tukey's fences
+// when k is 1.5. Unlike the MAD, the quartiles ignore how far out the
+// tail goes, so a long tail cannot widen the fences that judge it. It
+// returns the kept values in their original order and the number
+// rejected.
+func RejectIQR(xs []float64, k float64) ([]float64, int) {
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	q1, q3 := Percentile(sorted, 25), Percentile(sorted, 75)
+	lo, hi := q1-k*(q3-q1), q3+k*(q3-q1)
+	kept := make([]float64, 0, len(xs))
+	for _, x := range xs {
+		if x >= lo && x <= hi {
+			kept = append(kept, x)
+		}
+	}
+	return kept, len(xs) - len(kept)
+}
+
+// TrimEnds drops the lowest and the highest fraction of xs, rounded down,
+// whatever their values: a trimmed mean's sample. It returns the kept
+// values in their original order and the number dropped.
+func TrimEnds(xs []float64, fraction float64) ([]float64, int) {
+	cut := int(fraction * float64(len(xs)))
+	if cut == 0 {
+		return xs, 0
+	}
+	// Rank values by position among equals too, so that exactly cut are
+	// dropped from each end.
+	order := make([]int, len(xs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return xs[order[a]] < xs[order[b]] })
+	drop := make([]bool, len(xs))
+	for _, i := range order[:cut] {
+		drop[i] = true
+	}
+	for _, i := range order[len(order)-cut:] {
+		drop[i] = true
+	}
+	kept := make([]float64, 0, len(xs)-2*cut)
+	for i, x := range xs {
+		if !drop[i] {
+			kept = append(kept, x)
+		}
+	}
+	return kept, len(xs) - len(kept)
+}
+
+// CV returns the coefficient of variation of xs, StdDev over Mean, or +Inf
+// when the mean is zero.
+func CV(xs []float64) float64 {
+	m := Mean(xs)
+	if m == 0 {
+		return math.Inf(1)
+	}
+	return StdDev(xs) / math.Abs(m)
+}
+
+// Defaults of Warmup's steady-state detection.
+const (
+	DefaultSteadyWindow = 5
+	DefaultMaxWarmup    = 50
+)
+
+// Warmup decides when a benchmark loop may start recording: after Min
+// invocations and, when CV is set, once the rolling coefficient of
+// variation of the last Window values is at most CV. The first invocations
+// of a fresh process or environment run cold caches, lazy initialization
+// and JIT compilation, and averaging them in skews warm latency.
+type Warmup struct {
+	Min int
+	// CV is the steady-state threshold, e.g. 0.05; zero stops after Min.
+	CV float64
+	// Window defaults to DefaultSteadyWindow.
+	Window int
+	// Max bounds the warm-up of a loop that never settles; zero means
+	// DefaultMaxWarmup or Min, whichever is larger.
+	Max int
+}
+
+// Done reports whether warm-up is over after n invocations, xs being the
+// values of the successful ones in order. steady is false when Done gave up
+// at Max without the values settling.
+func (w Warmup) Done(n int, xs []float64) (done, steady bool) {
+	if n < w.Min {
+		return false, false
+	}
+	if w.CV == 0 {
+		return true, true
+	}
+	window := w.Window
+	if window == 0 {
+		window = DefaultSteadyWindow
+	}
+	if len(xs) >= window && CV(xs[len(xs)-window:]) <= w.CV {
+		return true, true
+	}
+	limit := w.Max
+	if limit == 0 {
+		limit = max(DefaultMaxWarmup, w.Min)
+	}
+	return n >= limit, false
+}
+
+// MedianCI returns a distribution-free 95% confidence interval of the
+// median of xs: the order statistics whose ranks bound the normal
+// approximation to the binomial count of values below the median. ok is
+// false below six values, too few for the interval to exclude the
+// extremes.
+func MedianCI(xs []float64) (lo, hi float64, ok bool) {
+	n := len(xs)
+	if n < 6 {
+		return 0, 0, false
+	}
+	sorted := append([]float64(nil), xs...)
+	sort.Float64s(sorted)
+	half := 1.96 * math.Sqrt(float64(n)) / 2
+	j := int(math.Floor(float64(n)/2 - half))
+	k := int(math.Ceil(float64(n)/2 + 1 + half))
+	// 1-based ranks j and k, clamped for small n.
+	j, k = max(j, 1), min(k, n)
+	return sorted[j-1], sorted[k-1], true
+}
+
+// RelativeMedianCI returns the half-width of MedianCI as a fraction of the
+// median, +Inf when there is no interval or the median is zero.
+func RelativeMedianCI(xs []float64) float64 {
+	lo, hi, ok := MedianCI(xs)
+	m := Median(xs)
+	if !ok || m == 0 {
+		return math.Inf(1)
+	}
+	return (hi - lo) / 2 / math.Abs(m)
+}
+
+// DefaultMaxSamples caps the samples Precision extends a loop to.
+const DefaultMaxSamples = 500
+
+// Precision decides when a benchmark loop has recorded enough: once the
+// 95% confidence interval of the median is within Target of it either
+// way. A fixed sample count buys very different precision from a quiet
+// workload and a noisy one.
+type Precision struct {
+	// Target is the interval's half-width as a fraction of the median,
+	// e.g. 0.02 for ±2%; zero stops at the count asked for.
+	Target float64
+	// Max bounds the samples of a loop that never settles; zero means
+	// DefaultMaxSamples.
+	Max int
+}
+
+// Done reports whether recording may stop after n invocations, xs being
+// the values of the successful ones. precise is false when Done gave up
+// at Max without the interval narrowing to Target.
+func (p Precision) Done(n int, xs []float64) (done, precise bool) {
+	if p.Target == 0 {
+		return true, true
+	}
+	if RelativeMedianCI(xs) <= p.Target {
+		return true, true
+	}
+	limit := p.Max
+	if limit == 0 {
+		limit = DefaultMaxSamples
+	}
+	return n >= limit, false
+}
+
+// MannWhitney tests whether values of x tend to be greater than values of
+// y, without assuming either is normally distributed. It returns the U
+// statistic of x and the one-sided p-value from the normal approximation,
+// with continuity and tie corrections. The approximation is rough below
+// about eight values a side. p is 1 when either sample is empty or every
+// value is tied.
+func MannWhitney(x, y []float64) (u, p float64) {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return 0, 1
+	}
+	type value struct {
+		v float64
+		x bool
+	}
+	all := make([]value, 0, n1+n2)
+	for _, v := range x {
+		all = append(all, value{v, true})
+	}
+	for _, v := range y {
+		all = append(all, value{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+	// Tied values share the mean of their ranks.
+	var rankSum, ties float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].x {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	u = rankSum - float64(n1*(n1+1))/2
+	n := float64(n1 + n2)
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - ties/(n*(n-1))))
+	if sigma == 0 {
+		return u, 1
+	}
+	z := (u - float64(n1*n2)/2 - 0.5) / sigma
+	return u, 0.5 * math.Erfc(z/math.Sqrt2)
+}
+
+// MannWhitneyTwoSided is the two-sided p-value of the Mann-Whitney U
+// test: whether values of x and y tend to differ in either direction.
+func MannWhitneyTwoSided(x, y []float64) float64 {
+	_, greater := MannWhitney(x, y)
+	_, less := MannWhitney(y, x)
+	return min(1, 2*min(greater, less))
+}
+
+// CliffsDelta is the probability that a value of x is greater than a
+// value of y, less the probability that it is smaller: 1 when every x
+// exceeds every y, -1 for the reverse and 0 when neither tends to be
+// larger. Unlike a difference of means it ignores by how much values
+// differ, so a few slow outliers cannot make it. It is 0 when either
+// sample is empty.
+func CliffsDelta(x, y []float64) float64 {
+	if len(x) == 0 || len(y) == 0 {
+		return 0
+	}
+	// U counts the pairs where x is greater, ties as halves.
+	u, _ := MannWhitney(x, y)
+	return 2*u/float64(len(x)*len(y)) - 1
+}
+
+// Magnitude describes the size of an effect.
+type Magnitude string
+
+const (
+	Negligible Magnitude = "negligible"
+	Small      Magnitude = "small"
+	Medium     Magnitude = "medium"
+	Large      Magnitude = "large"
+)
+
+// DeltaMagnitude labels a Cliff's delta by the thresholds of Romano et
+// al. (2006), derived from Cohen's d of 0.2, 0.5 and 0.8.
+func DeltaMagnitude(delta float64) Magnitude {
+	switch d := math.Abs(delta); {
+	case d < 0.147:
+		return Negligible
+	case d < 0.33:
+		return Small
+	case d < 0.474:
+		return Medium
+	}
+	return Large
+}
+
+// tTable holds two-sided 95% Student's t critical values for 1-30 degrees
+// of freedom.
+var tTable = [...]float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tTail holds the critical values tables give past tTable by 1/df,
+// ending with the normal distribution's at infinite degrees of freedom.
+var tTail = [...]struct{ inv, t float64 }{
+	{1.0 / 30, 2.042}, {1.0 / 40, 2.021}, {1.0 / 60, 2.000}, {1.0 / 120, 1.980}, {0, 1.960},
+}
+
+// tCritical95 returns the two-sided 95% critical value for df degrees of
+// freedom, interpolating linearly in 1/df between the tabled values past
+// 30, which is accurate to the third decimal.
+func tCritical95(df int) float64 {
+	switch {
+	case df < 1:
+		return 0
+	case df <= len(tTable):
+		return tTable[df-1]
+	}
+	inv := 1 / float64(df)
+	i := 1
+	for tTail[i].inv > inv {
+		i++
+	}
+	lo, hi := tTail[i-1], tTail[i]
+	return lo.t + (lo.inv-inv)/(lo.inv-hi.inv)*(hi.t-lo.t)
+}
@@ -0,0 +1,207 @@
+package stats
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestSummarize(t *testing.T) {
+	xs := []float64{5, 1, 4, 2, 3}
+	s := Summarize(xs, Options{})
+	if s.N != 5 || s.Mean != 3 || s.Median != 3 || s.Min != 1 || s.Max != 5 {
+		t.Errorf("Summarize = %+v", s)
+	}
+	if !approx(s.StdDev, math.Sqrt(2.5)) {
+		t.Errorf("StdDev = %v, want sqrt(2.5)", s.StdDev)
+	}
+	if !approx(s.P95, 4.8) {
+		t.Errorf("P95 = %v, want 4.8", s.P95)
+	}
+	half := 2.776 * math.Sqrt(2.5) / math.Sqrt(5)
+	if !approx(s.CILow, 3-half) || !approx(s.CIHigh, 3+half) {
+		t.Errorf("CI = [%v, %v], want 3±%v", s.CILow, s.CIHigh, half)
+	}
+	if xs[0] != 5 {
+		t.Error("Summarize reordered its input")
+	}
+}
+
+func TestSummarizeEdgeCases(t *testing.T) {
+	if s := Summarize(nil, Options{}); s.N != 0 {
+		t.Errorf("empty sample: %+v", s)
+	}
+	s := Summarize([]float64{7}, Options{})
+	if s.N != 1 || s.Median != 7 || s.P99 != 7 || s.StdDev != 0 || s.CILow != 7 {
+		t.Errorf("single value: %+v", s)
+	}
+}
+
+func TestTCritical95(t *testing.T) {
+	// Exact values from a t table.
+	for df, want := range map[int]float64{1: 12.706, 30: 2.042, 31: 2.040, 35: 2.030, 45: 2.014, 60: 2.000, 80: 1.990, 120: 1.980, 1000: 1.962} {
+		if got := tCritical95(df); math.Abs(got-want) > 0.0015 {
+			t.Errorf("tCritical95(%d) = %.4f, want %.3f", df, got, want)
+		}
+	}
+	if tCritical95(0) != 0 {
+		t.Error("no degrees of freedom has a critical value")
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40}
+	tests := []struct{ p, want float64 }{
+		{0, 10}, {50, 25}, {100, 40}, {99, 39.7},
+	}
+	for _, tt := range tests {
+		if got := Percentile(sorted, tt.p); !approx(got, tt.want) {
+			t.Errorf("Percentile(%v) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestRejectMAD(t *testing.T) {
+	xs := []float64{10, 11, 9, 10, 12, 10, 250}
+	kept, rejected := RejectMAD(xs, DefaultMADThreshold)
+	if rejected != 1 || len(kept) != 6 {
+		t.Fatalf("RejectMAD kept %v, rejected %d", kept, rejected)
+	}
+	for _, x := range kept {
+		if x == 250 {
+			t.Error("outlier 250 kept")
+		}
+	}
+
+	s := Summarize(xs, Options{RejectOutliers: true})
+	if s.Rejected != 1 || s.Max != 12 {
+		t.Errorf("Summarize with rejection = %+v", s)
+	}
+}
+
+func TestRejectMADZeroSpread(t *testing.T) {
+	xs := []float64{5, 5, 5, 5, 9}
+	if kept, rejected := RejectMAD(xs, DefaultMADThreshold); rejected != 0 || len(kept) != 5 {
+		t.Errorf("zero MAD rejected values: kept %v", kept)
+	}
+}
+
+func TestRejectIQR(t *testing.T) {
+	// Quartiles 10 and 12: fences at 7 and 15.
+	xs := []float64{10, 11, 9, 10, 12, 10, 12, 16, 6, 15}
+	kept, rejected := RejectIQR(xs, DefaultIQRFactor)
+	if rejected != 2 || len(kept) != 8 || kept[7] != 15 {
+		t.Errorf("RejectIQR kept %v, rejected %d", kept, rejected)
+	}
+}
+
+func TestTrimEnds(t *testing.T) {
+	xs := []float64{5, 1, 9, 3, 3, 7, 1, 9, 3, 3}
+	kept, dropped := TrimEnds(xs, 0.2)
+	if dropped != 4 || len(kept) != 6 || slices.Min(kept) != 3 || slices.Max(kept) != 7 || kept[0] != 5 {
+		t.Errorf("TrimEnds(0.2) kept %v, dropped %d", kept, dropped)
+	}
+	if _, dropped := TrimEnds(xs, 0.05); dropped != 0 {
+		t.Errorf("TrimEnds(0.05) of 10 values dropped %d", dropped)
+	}
+	s := Summarize(xs, Options{Trim: 0.2})
+	if s.N != 6 || s.Rejected != 4 || s.Min != 3 {
+		t.Errorf("Summarize with trimming = %+v", s)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	// A JIT-style warm-up: slow first invocations settling near 10 ms.
+	xs := []float64{200, 80, 30, 12, 10.2, 10, 9.9, 10.1, 10, 10.05}
+	done := func(w Warmup) (int, bool) {
+		for n := 0; n <= len(xs); n++ {
+			if ok, steady := w.Done(n, xs[:n]); ok {
+				return n, steady
+			}
+		}
+		return -1, false
+	}
+	if n, steady := done(Warmup{Min: 3}); n != 3 || !steady {
+		t.Errorf("fixed warm-up done after %d (steady %v), want 3", n, steady)
+	}
+	if n, steady := done(Warmup{CV: 0.05}); n != 9 || !steady {
+		t.Errorf("steady state after %d (steady %v), want 9", n, steady)
+	}
+	if n, steady := done(Warmup{CV: 0.001, Max: 6}); n != 6 || steady {
+		t.Errorf("capped warm-up done after %d (steady %v), want 6 and not steady", n, steady)
+	}
+	if cv := CV([]float64{10, 10, 10}); cv != 0 {
+		t.Errorf("CV of constant values = %v", cv)
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	if _, _, ok := MedianCI([]float64{1, 2, 3, 4, 5}); ok {
+		t.Error("median interval of 5 values")
+	}
+	xs := []float64{10, 1, 9, 2, 8, 3, 7, 4, 6, 5, 100, 0}
+	if lo, hi, ok := MedianCI(xs); !ok || lo != 1 || hi != 10 {
+		t.Errorf("median interval = [%v, %v], want ranks 2 and 11: [1, 10]", lo, hi)
+	}
+	quiet := []float64{10, 10.1, 9.9, 10, 10.05, 9.95, 10, 10.1}
+	if done, precise := (Precision{Target: 0.02}).Done(len(quiet), quiet); !done || !precise {
+		t.Errorf("quiet values: done %v, precise %v", done, precise)
+	}
+	noisy := []float64{10, 20, 5, 40, 12, 30, 8, 25}
+	if done, _ := (Precision{Target: 0.02}).Done(len(noisy), noisy); done {
+		t.Error("noisy values done before the cap")
+	}
+	if done, precise := (Precision{Target: 0.02, Max: 8}).Done(len(noisy), noisy); !done || precise {
+		t.Errorf("noisy values at the cap: done %v, precise %v", done, precise)
+	}
+	if done, precise := (Precision{}).Done(0, nil); !done || !precise {
+		t.Error("no target kept a loop going")
+	}
+}
+
+func TestMannWhitney(t *testing.T) {
+	base := []float64{10.1, 9.8, 10.3, 10.0, 9.9, 10.2, 10.4, 9.7, 10.0, 10.1}
+	slower := []float64{11.0, 10.8, 11.3, 10.9, 11.1, 11.4, 10.7, 11.2, 11.0, 10.9}
+	u, p := MannWhitney(slower, base)
+	if u != 100 || p > 0.001 {
+		t.Errorf("clearly slower: U = %v, p = %v", u, p)
+	}
+	if _, p := MannWhitney(base, slower); p < 0.99 {
+		t.Errorf("faster reported as slower: p = %v", p)
+	}
+	// The same population shifted by noise is not significant.
+	noisy := []float64{10.0, 10.2, 9.8, 10.3, 9.9, 10.1, 10.4, 9.7, 10.2, 10.0}
+	if _, p := MannWhitney(noisy, base); p < 0.05 {
+		t.Errorf("noise significant: p = %v", p)
+	}
+	if _, p := MannWhitney([]float64{5, 5, 5}, []float64{5, 5}); p != 1 {
+		t.Errorf("all ties: p = %v", p)
+	}
+	if _, p := MannWhitney(nil, base); p != 1 {
+		t.Errorf("empty sample: p = %v", p)
+	}
+}
+
+func TestCliffsDelta(t *testing.T) {
+	x, y := []float64{1, 2, 3}, []float64{4, 5, 6}
+	if d := CliffsDelta(x, y); d != -1 {
+		t.Errorf("CliffsDelta(all smaller) = %v, want -1", d)
+	}
+	if d := CliffsDelta(y, x); d != 1 {
+		t.Errorf("CliffsDelta(all larger) = %v, want 1", d)
+	}
+	// Of the 9 pairs 1 is greater, 1 tied and 7 smaller.
+	if d := CliffsDelta([]float64{1, 4, 5}, []float64{4, 6, 7}); !approx(d, (1-7)/9.0) {
+		t.Errorf("CliffsDelta = %v, want %v", d, (1-7)/9.0)
+	}
+	for d, want := range map[float64]Magnitude{0.1: Negligible, -0.2: Small, 0.4: Medium, -0.9: Large} {
+		if got := DeltaMagnitude(d); got != want {
+			t.Errorf("DeltaMagnitude(%v) = %s, want %s", d, got, want)
+		}
+	}
+	if p := MannWhitneyTwoSided(x, x); p != 1 {
+		t.Errorf("MannWhitneyTwoSided of identical samples = %v, want 1", p)
+	}
+}
@@ -0,0 +1,192 @@
+package stepfn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+// Client calls the Step Functions JSON API. Like queue.Client it
+// implements only the calls the harness makes.
+type Client struct {
+	Config aws.Config
+	// Endpoint overrides https://states.<region>.amazonaws.com, and the
+	// https://sync-states.<region>.amazonaws.com synchronous executions
+	// are started on.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// APIError is an error response from Step Functions, such as
+// "StateMachineDoesNotExist".
+type APIError = awsapi.Error
+
+// TagKey marks state machines created by the harness, as deploy.TagKey
+// does functions.
+const TagKey = "ruchy-bench"
+
+// rolePoll and roleTimeout bound the wait for a role just created to
+// become assumable, which Step Functions checks on creation; variables so
+// tests need not wait.
+var (
+	rolePoll    = 5 * time.Second
+	roleTimeout = 2 * time.Minute
+)
+
+// StateMachine is a state machine ListStateMachines returned.
+type StateMachine struct {
+	Name string `json:"name"`
+	ARN  string `json:"stateMachineArn"`
+}
+
+// Ensure creates the Express state machine name with definition, running
+// as the role with ARN roleARN and tagged TagKey, or updates the one of
+// that name, returning its ARN. An update can take a few seconds to reach
+// every execution.
+func (c *Client) Ensure(ctx context.Context, name, definition, roleARN string) (string, error) {
+	machines, err := c.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range machines {
+		if m.Name == name {
+			in := map[string]any{"stateMachineArn": m.ARN, "definition": definition, "roleArn": roleARN}
+			if err := c.call(ctx, c.endpoint(""), "UpdateStateMachine", in, nil); err != nil {
+				return "", fmt.Errorf("update state machine %s: %w", name, err)
+			}
+			return m.ARN, nil
+		}
+	}
+	in := map[string]any{
+		"name":       name,
+		"definition": definition,
+		"roleArn":    roleARN,
+		"type":       "EXPRESS",
+		"tags":       []map[string]string{{"key": TagKey, "value": "true"}},
+	}
+	var out struct {
+		ARN string `json:"stateMachineArn"`
+	}
+	deadline := time.Now().Add(roleTimeout)
+	for {
+		err := c.call(ctx, c.endpoint(""), "CreateStateMachine", in, &out)
+		var api *APIError
+		if errors.As(err, &api) && api.Code == "AccessDeniedException" && time.Now().Before(deadline) {
+			// The role is not assumable yet.
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(rolePoll):
+			}
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("create state machine %s: %w", name, err)
+		}
+		return out.ARN, nil
+	}
+}
+
+// StartSync calls StartSyncExecution, running the state machine with ARN
+// arn on input to its end.
+func (c *Client) StartSync(ctx context.Context, arn string, input []byte) (Execution, error) {
+	in := map[string]any{"stateMachineArn": arn, "input": string(input)}
+	var out struct {
+		Status         string  `json:"status"`
+		Error          string  `json:"error"`
+		Cause          string  `json:"cause"`
+		StartDate      float64 `json:"startDate"`
+		StopDate       float64 `json:"stopDate"`
+		Output         string  `json:"output"`
+		BillingDetails struct {
+			BilledDurationInMilliseconds float64 `json:"billedDurationInMilliseconds"`
+		} `json:"billingDetails"`
+	}
+	if err := c.call(ctx, c.endpoint("sync-"), "StartSyncExecution", in, &out); err != nil {
+		return Execution{}, fmt.Errorf("start execution of %s: %w", arn, err)
+	}
+	return Execution{
+		Status:   out.Status,
+		Error:    out.Error,
+		Cause:    out.Cause,
+		Start:    epoch(out.StartDate),
+		Stop:     epoch(out.StopDate),
+		Output:   out.Output,
+		BilledMS: out.BillingDetails.BilledDurationInMilliseconds,
+	}, nil
+}
+
+// epoch converts the fractional seconds since the epoch the API
+// timestamps are in.
+func epoch(s float64) time.Time {
+	sec, frac := math.Modf(s)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+// List calls ListStateMachines, following every page.
+func (c *Client) List(ctx context.Context) ([]StateMachine, error) {
+	var machines []StateMachine
+	in := map[string]any{"maxResults": 1000}
+	for {
+		var out struct {
+			StateMachines []StateMachine `json:"stateMachines"`
+			NextToken     string         `json:"nextToken"`
+		}
+		if err := c.call(ctx, c.endpoint(""), "ListStateMachines", in, &out); err != nil {
+			return nil, fmt.Errorf("list state machines: %w", err)
+		}
+		machines = append(machines, out.StateMachines...)
+		if out.NextToken == "" {
+			return machines, nil
+		}
+		in["nextToken"] = out.NextToken
+	}
+}
+
+// Tags calls ListTagsForResource.
+func (c *Client) Tags(ctx context.Context, arn string) (map[string]string, error) {
+	var out struct {
+		Tags []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"tags"`
+	}
+	if err := c.call(ctx, c.endpoint(""), "ListTagsForResource", map[string]any{"resourceArn": arn}, &out); err != nil {
+		return nil, fmt.Errorf("list tags of %s: %w", arn, err)
+	}
+	tags := make(map[string]string, len(out.Tags))
+	for _, t := range out.Tags {
+		tags[t.Key] = t.Value
+	}
+	return tags, nil
+}
+
+// Delete calls DeleteStateMachine.
+func (c *Client) Delete(ctx context.Context, arn string) error {
+	if err := c.call(ctx, c.endpoint(""), "DeleteStateMachine", map[string]any{"stateMachineArn": arn}, nil); err != nil {
+		return fmt.Errorf("delete state machine %s: %w", arn, err)
+	}
+	return nil
+}
+
+// endpoint is the regional endpoint with the given host prefix.
+func (c *Client) endpoint(prefix string) string {
+	if c.Endpoint != "" {
+		return c.Endpoint
+	}
+	return "https://" + prefix + "states." + c.Config.Region + ".amazonaws.com"
+}
+
+// call calls action on endpoint with input in and decodes its output
+// into out, unless out is nil.
+func (c *Client) call(ctx context.Context, endpoint, action string, in, out any) error {
+	api := &awsapi.Client{Config: c.Config, Service: "states", Endpoint: endpoint, HTTP: c.HTTP}
+	return api.JSON(ctx, "1.0", "AWSStepFunctions."+action, in, out)
+}
@@ -0,0 +1,164 @@
+// Package stepfn measures what orchestrating a baseline with Step
+// Functions costs. An Express state machine chains Steps invocations of
+// one function, each a Task state calling it through the lambda:invoke
+// integration with the execution's input as payload. The harness starts
+// it synchronously (StartSyncExecution) and subtracts the steps' server
+// time, read from the REPORT lines in the log tails the states return,
+// from the execution's duration: what is left, per step, is the cost of
+// a transition, Step Functions' own Invoke call included.
+//
+// An execution's input must be a JSON object: each state adds its
+// result to it under the key s<i>.
+package stepfn
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"lambdaperf/pkg/reportparser"
+)
+
+const (
+	// DefaultSteps is how many invocations a state machine chains.
+	DefaultSteps = 10
+	// RoleName is the role state machines run as, which ruchy-bench
+	// creates with deploy.EnsureStatesRole.
+	RoleName = "ruchy-bench-states"
+	// namePrefix begins the name of every state machine the harness
+	// creates.
+	namePrefix = "ruchy-bench-"
+)
+
+// Name is the name of the state machine chaining fn.
+func Name(fn string) string {
+	return namePrefix + fn
+}
+
+// Definition returns the Amazon States Language definition of a state
+// machine invoking function, a name or ARN with an optional qualifier,
+// steps times in a row. Each state keeps the handler's response and log
+// tail and passes its input on; the next state invokes the function with
+// the execution's input again, so every step is the same invocation.
+func Definition(function string, steps int) (string, error) {
+	if steps < 1 {
+		return "", fmt.Errorf("state machine of %d steps: want at least 1", steps)
+	}
+	type state struct {
+		Type           string            `json:"Type"`
+		Resource       string            `json:"Resource"`
+		Parameters     map[string]string `json:"Parameters"`
+		ResultSelector map[string]string `json:"ResultSelector"`
+		ResultPath     string            `json:"ResultPath"`
+		Next           string            `json:"Next,omitempty"`
+		End            bool              `json:"End,omitempty"`
+	}
+	states := make(map[string]state, steps)
+	for i := range steps {
+		s := state{
+			Type:     "Task",
+			Resource: "arn:aws:states:::lambda:invoke",
+			Parameters: map[string]string{
+				"FunctionName": function,
+				"Payload.$":    "$$.Execution.Input",
+				"LogType":      "Tail",
+			},
+			ResultSelector: map[string]string{"response.$": "$.Payload", "log.$": "$.LogResult"},
+			ResultPath:     "$." + key(i),
+		}
+		if i == steps-1 {
+			s.End = true
+		} else {
+			s.Next = stateName(i + 1)
+		}
+		states[stateName(i)] = s
+	}
+	def, err := json.Marshal(map[string]any{
+		"Comment": fmt.Sprintf("ruchy-bench: %d invocations of %s in a row", steps, function),
+		"StartAt": stateName(0),
+		"States":  states,
+	})
+	return string(def), err
+}
+
+func stateName(i int) string { return fmt.Sprintf("Invoke%d", i+1) }
+
+// key is where state i leaves its result in the execution's output.
+func key(i int) string { return fmt.Sprintf("s%d", i) }
+
+// Execution is the outcome of a synchronous execution.
+type Execution struct {
+	// Status is SUCCEEDED, FAILED or TIMED_OUT.
+	Status      string
+	Error       string
+	Cause       string
+	Start, Stop time.Time
+	Output      string
+	// BilledMS is the duration Step Functions bills the execution for.
+	BilledMS float64
+}
+
+// Breakdown is where an execution's time went.
+type Breakdown struct {
+	// ExecutionMS is the execution's duration as Step Functions reports
+	// it, from its start to its stop.
+	ExecutionMS float64
+	// StepsMS is the server time of the invocations it made: their
+	// REPORT durations, and init or restore durations on cold starts.
+	StepsMS float64
+	Steps   int
+	// Cold is how many of the invocations started cold.
+	Cold int
+	// Reports are the invocations' REPORT lines, in step order.
+	Reports []reportparser.Report
+	// Response is the handler's response to the last step.
+	Response json.RawMessage
+}
+
+// TransitionMS is the execution time per step not spent in the
+// function.
+func (b Breakdown) TransitionMS() float64 {
+	return (b.ExecutionMS - b.StepsMS) / float64(b.Steps)
+}
+
+// Analyze breaks a succeeded execution of a state machine of steps steps
+// down.
+func Analyze(e Execution, steps int) (Breakdown, error) {
+	if e.Status != "SUCCEEDED" {
+		return Breakdown{}, fmt.Errorf("execution %s: %s: %s", e.Status, e.Error, e.Cause)
+	}
+	var out map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(e.Output), &out); err != nil {
+		return Breakdown{}, fmt.Errorf("decode execution output: %w", err)
+	}
+	b := Breakdown{ExecutionMS: float64(e.Stop.Sub(e.Start)) / float64(time.Millisecond), Steps: steps}
+	for i := range steps {
+		var step struct {
+			Response json.RawMessage `json:"response"`
+			Log      string          `json:"log"`
+		}
+		raw, ok := out[key(i)]
+		if !ok {
+			return Breakdown{}, fmt.Errorf("execution output lacks step %d", i+1)
+		}
+		if err := json.Unmarshal(raw, &step); err != nil {
+			return Breakdown{}, fmt.Errorf("decode step %d: %w", i+1, err)
+		}
+		logs, err := base64.StdEncoding.DecodeString(step.Log)
+		if err != nil {
+			return Breakdown{}, fmt.Errorf("decode log tail of step %d: %w", i+1, err)
+		}
+		r, ok := reportparser.Last(string(logs))
+		if !ok {
+			return Breakdown{}, fmt.Errorf("no REPORT line in the log tail of step %d", i+1)
+		}
+		b.Reports = append(b.Reports, r)
+		b.StepsMS += r.DurationMS + r.InitDurationMS + r.RestoreDurationMS
+		if r.Cold() {
+			b.Cold++
+		}
+		b.Response = step.Response
+	}
+	return b, nil
+}
@@ -0,0 +1,152 @@
+package stepfn
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefinition(t *testing.T) {
+	def, err := Definition("arn:aws:lambda:us-east-1:1:function:f", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var asl struct {
+		StartAt string
+		States  map[string]struct {
+			Parameters map[string]string
+			ResultPath string
+			Next       string
+			End        bool
+		}
+	}
+	if err := json.Unmarshal([]byte(def), &asl); err != nil {
+		t.Fatal(err)
+	}
+	// Walk the chain from the start to its end.
+	var path []string
+	for name := asl.StartAt; name != ""; name = asl.States[name].Next {
+		s, ok := asl.States[name]
+		if !ok || s.Parameters["FunctionName"] != "arn:aws:lambda:us-east-1:1:function:f" || s.Parameters["Payload.$"] != "$$.Execution.Input" {
+			t.Fatalf("state %q = %+v", name, s)
+		}
+		path = append(path, s.ResultPath)
+		if s.End {
+			break
+		}
+	}
+	if got := strings.Join(path, ","); got != "$.s0,$.s1,$.s2" || len(asl.States) != 3 {
+		t.Errorf("chain writes %s across %d states", got, len(asl.States))
+	}
+	if _, err := Definition("f", 0); err == nil {
+		t.Error("empty state machine defined")
+	}
+}
+
+// step is a state's result: the handler response and its log tail.
+func step(report string) string {
+	return fmt.Sprintf(`{"response":{"statusCode":200,"body":"ok"},"log":%q}`, base64.StdEncoding.EncodeToString([]byte("START\n"+report+"\n")))
+}
+
+func TestAnalyze(t *testing.T) {
+	cold := "REPORT RequestId: a\tDuration: 10.00 ms\tBilled Duration: 60 ms\tMemory Size: 128 MB\tMax Memory Used: 20 MB\tInit Duration: 50.00 ms\t"
+	warm := "REPORT RequestId: b\tDuration: 20.00 ms\tBilled Duration: 20 ms\tMemory Size: 128 MB\tMax Memory Used: 20 MB\t"
+	start := time.Unix(100, 0)
+	e := Execution{
+		Status: "SUCCEEDED",
+		Start:  start,
+		Stop:   start.Add(140 * time.Millisecond),
+		Output: `{"n":1,"s0":` + step(cold) + `,"s1":` + step(warm) + `}`,
+	}
+	b, err := Analyze(e, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.ExecutionMS != 140 || b.StepsMS != 80 || b.Cold != 1 || len(b.Reports) != 2 || b.Reports[1].RequestID != "b" {
+		t.Errorf("Analyze = %+v", b)
+	}
+	if b.TransitionMS() != 30 {
+		t.Errorf("TransitionMS = %v, want 30", b.TransitionMS())
+	}
+	if string(b.Response) != `{"statusCode":200,"body":"ok"}` {
+		t.Errorf("response = %s", b.Response)
+	}
+	if _, err := Analyze(e, 3); err == nil {
+		t.Error("output missing a step analyzed")
+	}
+	if _, err := Analyze(Execution{Status: "FAILED", Error: "Lambda.Unknown"}, 2); err == nil || !strings.Contains(err.Error(), "Lambda.Unknown") {
+		t.Errorf("failed execution: %v", err)
+	}
+}
+
+func TestClient(t *testing.T) {
+	var created int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]any
+		json.NewDecoder(r.Body).Decode(&in)
+		switch r.Header.Get("X-Amz-Target") {
+		case "AWSStepFunctions.ListStateMachines":
+			w.Write([]byte(`{"stateMachines":[{"name":"old","stateMachineArn":"arn:sm:old"}]}`))
+		case "AWSStepFunctions.UpdateStateMachine":
+			if in["stateMachineArn"] != "arn:sm:old" {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		case "AWSStepFunctions.CreateStateMachine":
+			// The first attempt comes before the role is assumable.
+			if created++; created == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type":"com.amazonaws.swf.service.v2.model#AccessDeniedException","message":"Neither the global service principal states.amazonaws.com, nor the regional one is authorized to assume the provided role."}`))
+				return
+			}
+			if in["type"] != "EXPRESS" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"stateMachineArn":"arn:sm:new"}`))
+		case "AWSStepFunctions.StartSyncExecution":
+			w.Write([]byte(`{"status":"SUCCEEDED","startDate":1.76e9,"stopDate":1760000000.25,"output":"{}","billingDetails":{"billedDurationInMilliseconds":300}}`))
+		case "AWSStepFunctions.ListTagsForResource":
+			w.Write([]byte(`{"tags":[{"key":"ruchy-bench","value":"true"}]}`))
+		case "AWSStepFunctions.DeleteStateMachine":
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"StateMachineDoesNotExist","message":"State Machine Does Not Exist"}`))
+		}
+	}))
+	defer srv.Close()
+	rolePoll = time.Millisecond
+	c := &Client{Endpoint: srv.URL}
+	ctx := context.Background()
+
+	if arn, err := c.Ensure(ctx, "old", "{}", "arn:role"); err != nil || arn != "arn:sm:old" {
+		t.Errorf("Ensure of an existing state machine = %q, %v", arn, err)
+	}
+	if arn, err := c.Ensure(ctx, "new", "{}", "arn:role"); err != nil || arn != "arn:sm:new" || created != 2 {
+		t.Errorf("Ensure of a new state machine = %q, %v after %d attempts", arn, err, created)
+	}
+	e, err := c.StartSync(ctx, "arn:sm:new", []byte(`{}`))
+	if err != nil || e.Status != "SUCCEEDED" || e.BilledMS != 300 {
+		t.Fatalf("StartSync = %+v, %v", e, err)
+	}
+	if d := e.Stop.Sub(e.Start); math.Abs(float64(d-250*time.Millisecond)) > float64(time.Millisecond) {
+		t.Errorf("execution took %v, want 250ms", d)
+	}
+	if tags, err := c.Tags(ctx, "arn:sm:new"); err != nil || tags[TagKey] != "true" {
+		t.Errorf("Tags = %v, %v", tags, err)
+	}
+	if err := c.Delete(ctx, "arn:sm:new"); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+	var apiErr *APIError
+	if err := c.call(ctx, srv.URL, "DescribeStateMachine", map[string]any{}, nil); !errors.As(err, &apiErr) || apiErr.Code != "StateMachineDoesNotExist" {
+		t.Errorf("call of a missing state machine: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RunIDs returns the ID of every stored run, oldest first.
+func (s *Store) RunIDs(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM runs ORDER BY started_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("list runs: %w", err)
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Artifacts returns every recorded build, oldest first.
+func (s *Store) Artifacts(ctx context.Context) ([]Artifact, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT kind, runtime, workload, arch, package, built_at, binary_bytes, package_bytes
+		FROM artifacts ORDER BY built_at`)
+	if err != nil {
+		return nil, fmt.Errorf("list artifacts: %w", err)
+	}
+	defer rows.Close()
+	var out []Artifact
+	for rows.Next() {
+		var (
+			a     Artifact
+			built string
+		)
+		if err := rows.Scan(&a.Kind, &a.Runtime, &a.Workload, &a.Arch, &a.Package, &built, &a.BinaryBytes, &a.PackageBytes); err != nil {
+			return nil, err
+		}
+		if a.BuiltAt, err = time.Parse(time.RFC3339Nano, built); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// Merge copies into s every run and artifact of src it does not already
+// hold, returning how many of each it copied. Runs are matched by ID: a
+// run is never changed once saved, so a copy with a known ID is the same
+// run, recorded on both sides. Artifacts match when every field does.
+func (s *Store) Merge(ctx context.Context, src *Store) (runs, artifacts int, err error) {
+	have, err := s.RunIDs(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	known := map[string]bool{}
+	for _, id := range have {
+		known[id] = true
+	}
+	ids, err := src.RunIDs(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, id := range ids {
+		if known[id] {
+			continue
+		}
+		run, err := src.Run(ctx, id)
+		if err != nil {
+			return runs, 0, err
+		}
+		if err := s.Save(ctx, run); err != nil {
+			return runs, 0, err
+		}
+		runs++
+	}
+
+	built, err := s.Artifacts(ctx)
+	if err != nil {
+		return runs, 0, err
+	}
+	recorded := map[Artifact]bool{}
+	for _, a := range built {
+		recorded[a] = true
+	}
+	theirs, err := src.Artifacts(ctx)
+	if err != nil {
+		return runs, 0, err
+	}
+	for _, a := range theirs {
+		if recorded[a] {
+			continue
+		}
+		if err := s.SaveArtifact(ctx, a); err != nil {
+			return runs, artifacts, err
+		}
+		recorded[a] = true
+		artifacts++
+	}
+	return runs, artifacts, nil
+}
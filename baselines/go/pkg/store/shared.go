@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3API is the subset of the S3 client Shared uses.
+type S3API interface {
+	GetObject(ctx context.Context, in *s3.GetObjectInput, opts ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, in *s3.PutObjectInput, opts ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// MaxAttempts is how many times Update tries to apply its change before
+// giving up on a database others keep writing.
+const MaxAttempts = 5
+
+// ErrContended is returned by Update when every attempt lost the race
+// to another writer.
+var ErrContended = errors.New("results database kept changing; try again")
+
+// Shared is a results database kept as an S3 object, so the machines
+// that record runs (CI, laptops, ruchy-bench daemon) write to one history
+// rather than each to its own. SQLite cannot open an object in place:
+// Pull downloads it to Path, the local copy, and Update uploads a changed
+// copy only if nobody else uploaded one since it was downloaded (an S3
+// conditional write), retrying on the newer version otherwise. That makes
+// every write a merge: each change is applied to whatever the object
+// holds when it lands, and none is lost to a concurrent one.
+type Shared struct {
+	Client      S3API
+	Bucket, Key string
+	// Path is the local copy, which Pull and Update leave at the latest
+	// version they saw.
+	Path string
+}
+
+// Location returns the object's s3:// URL.
+func (sh *Shared) Location() string { return "s3://" + sh.Bucket + "/" + sh.Key }
+
+// Pull downloads the object to Path and returns its ETag, or "" when
+// the object does not exist yet, in which case Path is an empty
+// database.
+func (sh *Shared) Pull(ctx context.Context) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(sh.Path), 0o755); err != nil {
+		return "", err
+	}
+	out, err := sh.Client.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(sh.Bucket), Key: aws.String(sh.Key)})
+	var missing *types.NoSuchKey
+	if errors.As(err, &missing) {
+		if err := os.Remove(sh.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get %s: %w", sh.Location(), err)
+	}
+	defer out.Body.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(sh.Path), filepath.Base(sh.Path)+".*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, out.Body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("download %s: %w", sh.Location(), err)
+	}
+	if err := os.Rename(tmp.Name(), sh.Path); err != nil {
+		return "", err
+	}
+	return aws.ToString(out.ETag), nil
+}
+
+// Update applies change to the latest version of the database and
+// uploads the result, trying again from a fresh download when another
+// writer got there first. change may therefore run more than once, each
+// time on a newer copy.
+func (sh *Shared) Update(ctx context.Context, change func(*Store) error) error {
+	for range MaxAttempts {
+		etag, err := sh.Pull(ctx)
+		if err != nil {
+			return err
+		}
+		s, err := Open(sh.Path)
+		if err != nil {
+			return err
+		}
+		err = change(s)
+		if cerr := s.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(sh.Path)
+		if err != nil {
+			return err
+		}
+		in := &s3.PutObjectInput{Bucket: aws.String(sh.Bucket), Key: aws.String(sh.Key), Body: f,
+			ContentType: aws.String("application/vnd.sqlite3")}
+		if etag == "" {
+			in.IfNoneMatch = aws.String("*")
+		} else {
+			in.IfMatch = aws.String(etag)
+		}
+		_, err = sh.Client.PutObject(ctx, in)
+		f.Close()
+		if !lostRace(err) {
+			if err != nil {
+				return fmt.Errorf("put %s: %w", sh.Location(), err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("update %s: %w", sh.Location(), ErrContended)
+}
+
+// lostRace reports whether a conditional write failed because the object
+// changed since it was read (412), or another conditional write to it
+// was in flight (409).
+func lostRace(err error) bool {
+	// The S3 client's errors are *http.ResponseError of aws/transport/http.
+	var resp interface{ HTTPStatusCode() int }
+	if !errors.As(err, &resp) {
+		return false
+	}
+	code := resp.HTTPStatusCode()
+	return code == http.StatusPreconditionFailed || code == http.StatusConflict
+}
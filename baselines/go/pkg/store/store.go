@@ -0,0 +1,542 @@
+// Package store persists benchmark runs in a local SQLite database so
+// results can be compared across runs. Results files are snapshots of a
+// single run; the store is what lets a regression show up as a trend.
+// Shared keeps one database in S3 for every machine that records runs.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // pure-Go driver; the harness builds with CGO_ENABLED=0
+
+	"lambdaperf/pkg/results"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          TEXT PRIMARY KEY,
+	mode        TEXT NOT NULL,
+	started_at  TEXT NOT NULL,
+	finished_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS results (
+	id        INTEGER PRIMARY KEY,
+	run_id    TEXT NOT NULL REFERENCES runs(id) ON DELETE CASCADE,
+	runtime   TEXT NOT NULL,
+	workload  TEXT NOT NULL,
+	kind      TEXT NOT NULL,
+	arch      TEXT NOT NULL,
+	function  TEXT NOT NULL,
+	memory_mb INTEGER NOT NULL,
+	error     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS results_workload ON results(workload, runtime);
+CREATE TABLE IF NOT EXISTS samples (
+	result_id      INTEGER NOT NULL REFERENCES results(id) ON DELETE CASCADE,
+	iteration      INTEGER NOT NULL,
+	client_ms      REAL NOT NULL,
+	request_id     TEXT NOT NULL,
+	duration_ms    REAL NOT NULL,
+	billed_ms      REAL NOT NULL,
+	init_ms        REAL NOT NULL,
+	memory_size_mb INTEGER NOT NULL,
+	max_memory_mb  INTEGER NOT NULL,
+	cold           INTEGER NOT NULL,
+	response       TEXT NOT NULL,
+	error          TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS samples_result ON samples(result_id);
+`
+
+// migrations upgrade databases created before a column existed. Entry i
+// moves a database from user_version i to i+1; the base schema is
+// version 0.
+var migrations = []string{
+	`ALTER TABLE results ADD COLUMN snapstart INTEGER NOT NULL DEFAULT 0;
+	 ALTER TABLE samples ADD COLUMN restore_ms REAL NOT NULL DEFAULT 0;`,
+	`ALTER TABLE results ADD COLUMN provisioned_concurrency INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE samples ADD COLUMN sdk_ms REAL NOT NULL DEFAULT 0;`,
+	`ALTER TABLE results ADD COLUMN binary_bytes INTEGER NOT NULL DEFAULT 0;
+	 ALTER TABLE results ADD COLUMN package_bytes INTEGER NOT NULL DEFAULT 0;
+	 CREATE TABLE artifacts (
+		kind          TEXT NOT NULL,
+		runtime       TEXT NOT NULL,
+		workload      TEXT NOT NULL,
+		arch          TEXT NOT NULL,
+		built_at      TEXT NOT NULL,
+		binary_bytes  INTEGER NOT NULL,
+		package_bytes INTEGER NOT NULL
+	 );
+	 CREATE INDEX artifacts_target ON artifacts(kind, runtime, workload, arch, built_at);`,
+	`ALTER TABLE samples ADD COLUMN max_rss_kb INTEGER NOT NULL DEFAULT 0;
+	 ALTER TABLE samples ADD COLUMN counters TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE samples ADD COLUMN user_ms REAL NOT NULL DEFAULT 0;
+	 ALTER TABLE samples ADD COLUMN system_ms REAL NOT NULL DEFAULT 0;`,
+	`ALTER TABLE results ADD COLUMN package TEXT NOT NULL DEFAULT '';
+	 ALTER TABLE artifacts ADD COLUMN package TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE samples ADD COLUMN segments TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE results ADD COLUMN input TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE samples ADD COLUMN warmup INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE samples ADD COLUMN go_runtime TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE samples ADD COLUMN ttfb_ms REAL NOT NULL DEFAULT 0;`,
+	`ALTER TABLE samples ADD COLUMN deliveries INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE results ADD COLUMN extension INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE samples ADD COLUMN telemetry TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE results ADD COLUMN region TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE runs ADD COLUMN metadata TEXT NOT NULL DEFAULT '';
+	 ALTER TABLE results ADD COLUMN lambda_runtime TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE samples ADD COLUMN retries INTEGER NOT NULL DEFAULT 0;
+	 ALTER TABLE samples ADD COLUMN excluded TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE samples ADD COLUMN bytes INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE samples ADD COLUMN http TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE samples ADD COLUMN io TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE results ADD COLUMN vpc INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE results ADD COLUMN edge INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE results ADD COLUMN sandbox TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE results ADD COLUMN imprecise INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE results ADD COLUMN optimum TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE results ADD COLUMN serializer TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE samples ADD COLUMN host TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE samples ADD COLUMN histogram TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE results ADD COLUMN chaos INTEGER NOT NULL DEFAULT 0;`,
+}
+
+// Store is an open results database.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens or creates the database at path.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initialize %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	for ; version < len(migrations); version++ {
+		if _, err := db.Exec(migrations[version]); err != nil {
+			return fmt.Errorf("migration %d: %w", version+1, err)
+		}
+		if _, err := db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, version+1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error { return s.db.Close() }
+
+// Save records run and every raw sample. Saving a run ID again replaces
+// the earlier copy.
+func (s *Store) Save(ctx context.Context, run *results.Run) (err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM runs WHERE id = ?`, run.ID); err != nil {
+		return fmt.Errorf("replace run %s: %w", run.ID, err)
+	}
+	var metadata []byte
+	if run.Metadata != nil {
+		if metadata, err = json.Marshal(run.Metadata); err != nil {
+			return err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO runs (id, mode, started_at, finished_at, metadata) VALUES (?, ?, ?, ?, ?)`,
+		run.ID, run.Mode, formatTime(run.StartedAt), formatTime(run.FinishedAt), string(metadata)); err != nil {
+		return fmt.Errorf("save run %s: %w", run.ID, err)
+	}
+	for _, r := range run.Results {
+		input, err := encodeMap(r.Input)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `INSERT INTO results
+			(run_id, runtime, workload, kind, arch, function, memory_mb, region, snapstart, package, extension, vpc, chaos, serializer, edge, sandbox,
+			 lambda_runtime, provisioned_concurrency, binary_bytes, package_bytes, input, imprecise, optimum, error)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			run.ID, r.Runtime, r.Workload, r.Kind, r.Arch, r.Function, r.MemoryMB, r.Region, r.SnapStart, r.Package, r.Extension, r.VPC, r.Chaos, r.Serializer, r.Edge, r.Sandbox,
+			r.LambdaRuntime, r.ProvisionedConcurrency, r.BinaryBytes, r.PackageBytes, input, r.Imprecise, r.Optimum, r.Error)
+		if err != nil {
+			return fmt.Errorf("save result %s/%s: %w", r.Runtime, r.Workload, err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		for _, sm := range r.Samples {
+			counters, err := encodeMap(sm.Counters)
+			if err != nil {
+				return err
+			}
+			segments, err := encodeMap(sm.Segments)
+			if err != nil {
+				return err
+			}
+			goRuntime, err := encodeMap(sm.GoRuntime)
+			if err != nil {
+				return err
+			}
+			telemetry, err := encodeMap(sm.Telemetry)
+			if err != nil {
+				return err
+			}
+			httpStats, err := encodeMap(sm.HTTP)
+			if err != nil {
+				return err
+			}
+			fileIO, err := encodeMap(sm.IO)
+			if err != nil {
+				return err
+			}
+			host := ""
+			if sm.Host != nil {
+				data, err := json.Marshal(sm.Host)
+				if err != nil {
+					return err
+				}
+				host = string(data)
+			}
+			histogram := ""
+			if sm.Histogram != nil {
+				data, err := json.Marshal(sm.Histogram)
+				if err != nil {
+					return err
+				}
+				histogram = string(data)
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO samples
+				(result_id, iteration, client_ms, request_id, duration_ms, billed_ms, init_ms, restore_ms,
+				 sdk_ms, ttfb_ms, memory_size_mb, max_memory_mb, max_rss_kb, user_ms, system_ms, counters, segments,
+				 go_runtime, telemetry, deliveries, cold, warmup, response, error, retries, excluded, bytes, http, io, host, histogram)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				id, sm.Iteration, sm.ClientMS, sm.RequestID, sm.DurationMS, sm.BilledMS, sm.InitMS, sm.RestoreMS,
+				sm.SDKMS, sm.TTFBMS, sm.MemorySizeMB, sm.MaxMemoryMB, sm.MaxRSSKB, sm.UserMS, sm.SystemMS, counters, segments,
+				goRuntime, telemetry, sm.Deliveries, sm.Cold, sm.Warmup, sm.Response, sm.Error, sm.Retries, sm.Excluded, sm.Bytes, httpStats, fileIO, host, histogram); err != nil {
+				return fmt.Errorf("save sample %d of %s/%s: %w", sm.Iteration, r.Runtime, r.Workload, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// encodeMap stores a map column, such as a sample's counters or a
+// result's input, as JSON, or "" when empty.
+func encodeMap[V any](m map[string]V) (string, error) {
+	if len(m) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+// Query selects historical results. Empty fields match everything.
+type Query struct {
+	RunID    string
+	Workload string
+	Runtimes []string
+	Kind     string
+	Arch     string
+	// Since excludes runs started before it. Zero means no bound.
+	Since time.Time
+	// Limit keeps only the most recent Limit runs. Zero means all.
+	Limit int
+}
+
+// Entry is one stored result with the run it belongs to. Result.Stats is
+// not stored; callers summarize with the options they want.
+type Entry struct {
+	RunID     string
+	Mode      string
+	StartedAt time.Time
+	Result    results.Result
+}
+
+// History returns matching results, oldest run first.
+func (s *Store) History(ctx context.Context, q Query) ([]Entry, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, vals ...any) {
+		where = append(where, cond)
+		args = append(args, vals...)
+	}
+	if q.RunID != "" {
+		add("u.id = ?", q.RunID)
+	}
+	if q.Workload != "" {
+		add("r.workload = ?", q.Workload)
+	}
+	if len(q.Runtimes) > 0 {
+		marks := strings.TrimSuffix(strings.Repeat("?,", len(q.Runtimes)), ",")
+		vals := make([]any, len(q.Runtimes))
+		for i, rt := range q.Runtimes {
+			vals[i] = rt
+		}
+		add("r.runtime IN ("+marks+")", vals...)
+	}
+	if q.Kind != "" {
+		add("r.kind = ?", q.Kind)
+	}
+	if q.Arch != "" {
+		add("r.arch = ?", q.Arch)
+	}
+	if !q.Since.IsZero() {
+		add("u.started_at >= ?", formatTime(q.Since))
+	}
+	cond := "1 = 1"
+	if len(where) > 0 {
+		cond = strings.Join(where, " AND ")
+	}
+	const from = ` FROM results r JOIN runs u ON u.id = r.run_id WHERE `
+	query := `SELECT r.id, u.id, u.mode, u.started_at, r.runtime, r.workload, r.kind, r.arch,
+		r.function, r.memory_mb, r.region, r.snapstart, r.package, r.extension, r.vpc, r.chaos, r.serializer, r.edge, r.sandbox, r.lambda_runtime, r.provisioned_concurrency,
+		r.binary_bytes, r.package_bytes, r.input, r.imprecise, r.optimum, r.error` + from + cond
+	if q.Limit > 0 {
+		query += ` AND u.id IN (SELECT u.id` + from + cond +
+			fmt.Sprintf(` GROUP BY u.id ORDER BY u.started_at DESC LIMIT %d)`, q.Limit)
+		args = append(args, args...)
+	}
+	query += " ORDER BY u.started_at, r.id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query history: %w", err)
+	}
+	defer rows.Close()
+	var (
+		entries []Entry
+		ids     []int64
+	)
+	for rows.Next() {
+		var (
+			e       Entry
+			id      int64
+			started string
+			input   string
+		)
+		r := &e.Result
+		if err := rows.Scan(&id, &e.RunID, &e.Mode, &started, &r.Runtime, &r.Workload, &r.Kind,
+			&r.Arch, &r.Function, &r.MemoryMB, &r.Region, &r.SnapStart, &r.Package, &r.Extension, &r.VPC, &r.Chaos, &r.Serializer, &r.Edge, &r.Sandbox, &r.LambdaRuntime, &r.ProvisionedConcurrency,
+			&r.BinaryBytes, &r.PackageBytes, &input, &r.Imprecise, &r.Optimum, &r.Error); err != nil {
+			return nil, err
+		}
+		if input != "" {
+			if err := json.Unmarshal([]byte(input), &r.Input); err != nil {
+				return nil, fmt.Errorf("run %s input: %w", e.RunID, err)
+			}
+		}
+		if e.StartedAt, err = time.Parse(time.RFC3339Nano, started); err != nil {
+			return nil, fmt.Errorf("run %s: %w", e.RunID, err)
+		}
+		entries = append(entries, e)
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Result.Samples, err = s.samples(ctx, ids[i]); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+// Run loads the stored run id with every result and sample. As with
+// History, Stats are left for the caller to summarize.
+func (s *Store) Run(ctx context.Context, id string) (*results.Run, error) {
+	run := &results.Run{ID: id}
+	var started, finished, metadata string
+	err := s.db.QueryRowContext(ctx, `SELECT mode, started_at, finished_at, metadata FROM runs WHERE id = ?`, id).
+		Scan(&run.Mode, &started, &finished, &metadata)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no run %s in the results database", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load run %s: %w", id, err)
+	}
+	if run.StartedAt, err = time.Parse(time.RFC3339Nano, started); err != nil {
+		return nil, fmt.Errorf("run %s: %w", id, err)
+	}
+	if run.FinishedAt, err = time.Parse(time.RFC3339Nano, finished); err != nil {
+		return nil, fmt.Errorf("run %s: %w", id, err)
+	}
+	if metadata != "" {
+		if err := json.Unmarshal([]byte(metadata), &run.Metadata); err != nil {
+			return nil, fmt.Errorf("run %s metadata: %w", id, err)
+		}
+	}
+	entries, err := s.History(ctx, Query{RunID: id})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		run.Results = append(run.Results, e.Result)
+	}
+	return run, nil
+}
+
+// Previous loads the newest stored run of mode other than the run id,
+// started no later than it, as the run to compare id with: the one
+// before it. It returns nil when there is none.
+func (s *Store) Previous(ctx context.Context, mode, id string, started time.Time) (*results.Run, error) {
+	var prev string
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM runs WHERE mode = ? AND id <> ? AND started_at <= ?
+		ORDER BY started_at DESC, id DESC LIMIT 1`, mode, id, formatTime(started)).Scan(&prev)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find the run before %s: %w", id, err)
+	}
+	return s.Run(ctx, prev)
+}
+
+func (s *Store) samples(ctx context.Context, resultID int64) ([]results.Sample, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT iteration, client_ms, request_id, duration_ms, billed_ms,
+		init_ms, restore_ms, sdk_ms, ttfb_ms, memory_size_mb, max_memory_mb, max_rss_kb, user_ms, system_ms, counters,
+		segments, go_runtime, telemetry, deliveries, cold, warmup, response, error, retries, excluded, bytes, http, io, host, histogram
+		FROM samples WHERE result_id = ? ORDER BY iteration`, resultID)
+	if err != nil {
+		return nil, fmt.Errorf("query samples: %w", err)
+	}
+	defer rows.Close()
+	var out []results.Sample
+	for rows.Next() {
+		var (
+			sm                                                                           results.Sample
+			counters, segments, goRuntime, telemetry, httpStats, fileIO, host, histogram string
+		)
+		if err := rows.Scan(&sm.Iteration, &sm.ClientMS, &sm.RequestID, &sm.DurationMS, &sm.BilledMS,
+			&sm.InitMS, &sm.RestoreMS, &sm.SDKMS, &sm.TTFBMS, &sm.MemorySizeMB, &sm.MaxMemoryMB, &sm.MaxRSSKB, &sm.UserMS, &sm.SystemMS,
+			&counters, &segments, &goRuntime, &telemetry, &sm.Deliveries, &sm.Cold, &sm.Warmup, &sm.Response, &sm.Error,
+			&sm.Retries, &sm.Excluded, &sm.Bytes, &httpStats, &fileIO, &host, &histogram); err != nil {
+			return nil, err
+		}
+		if counters != "" {
+			if err := json.Unmarshal([]byte(counters), &sm.Counters); err != nil {
+				return nil, fmt.Errorf("sample %d counters: %w", sm.Iteration, err)
+			}
+		}
+		if segments != "" {
+			if err := json.Unmarshal([]byte(segments), &sm.Segments); err != nil {
+				return nil, fmt.Errorf("sample %d segments: %w", sm.Iteration, err)
+			}
+		}
+		if goRuntime != "" {
+			if err := json.Unmarshal([]byte(goRuntime), &sm.GoRuntime); err != nil {
+				return nil, fmt.Errorf("sample %d Go runtime: %w", sm.Iteration, err)
+			}
+		}
+		if telemetry != "" {
+			if err := json.Unmarshal([]byte(telemetry), &sm.Telemetry); err != nil {
+				return nil, fmt.Errorf("sample %d telemetry: %w", sm.Iteration, err)
+			}
+		}
+		if httpStats != "" {
+			if err := json.Unmarshal([]byte(httpStats), &sm.HTTP); err != nil {
+				return nil, fmt.Errorf("sample %d HTTP: %w", sm.Iteration, err)
+			}
+		}
+		if fileIO != "" {
+			if err := json.Unmarshal([]byte(fileIO), &sm.IO); err != nil {
+				return nil, fmt.Errorf("sample %d I/O: %w", sm.Iteration, err)
+			}
+		}
+		if host != "" {
+			if err := json.Unmarshal([]byte(host), &sm.Host); err != nil {
+				return nil, fmt.Errorf("sample %d host: %w", sm.Iteration, err)
+			}
+		}
+		if histogram != "" {
+			if err := json.Unmarshal([]byte(histogram), &sm.Histogram); err != nil {
+				return nil, fmt.Errorf("sample %d histogram: %w", sm.Iteration, err)
+			}
+		}
+		out = append(out, sm)
+	}
+	return out, rows.Err()
+}
+
+// Artifact is the measured size of one build of a target.
+type Artifact struct {
+	Kind     string
+	Runtime  string
+	Workload string
+	Arch     string
+	// Package is discover.PackageImage for container images, whose
+	// PackageBytes is the image size; empty for zips.
+	Package      string
+	BuiltAt      time.Time
+	BinaryBytes  int64
+	PackageBytes int64
+}
+
+// SaveArtifact records a build. Lambda results are measured against
+// whatever was last deployed, so sizes are kept per build rather than
+// per run; see LatestArtifact.
+func (s *Store) SaveArtifact(ctx context.Context, a Artifact) error {
+	_, err := s.db.ExecContext(ctx, `INSERT INTO artifacts
+		(kind, runtime, workload, arch, package, built_at, binary_bytes, package_bytes) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.Kind, a.Runtime, a.Workload, a.Arch, a.Package, formatTime(a.BuiltAt), a.BinaryBytes, a.PackageBytes)
+	if err != nil {
+		return fmt.Errorf("save artifact %s/%s: %w", a.Runtime, a.Workload, err)
+	}
+	return nil
+}
+
+// LatestArtifact returns the most recent build of a target in the given
+// package type, reporting false when it was never recorded.
+func (s *Store) LatestArtifact(ctx context.Context, kind, runtime, workload, arch, pkg string) (Artifact, bool, error) {
+	a := Artifact{Kind: kind, Runtime: runtime, Workload: workload, Arch: arch, Package: pkg}
+	var built string
+	err := s.db.QueryRowContext(ctx, `SELECT built_at, binary_bytes, package_bytes FROM artifacts
+		WHERE kind = ? AND runtime = ? AND workload = ? AND arch = ? AND package = ?
+		ORDER BY built_at DESC LIMIT 1`, kind, runtime, workload, arch, pkg).Scan(&built, &a.BinaryBytes, &a.PackageBytes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Artifact{}, false, nil
+	}
+	if err != nil {
+		return Artifact{}, false, fmt.Errorf("query artifact %s/%s: %w", runtime, workload, err)
+	}
+	if a.BuiltAt, err = time.Parse(time.RFC3339Nano, built); err != nil {
+		return Artifact{}, false, err
+	}
+	return a, true, nil
+}
+
+func formatTime(t time.Time) string { return t.UTC().Format(time.RFC3339Nano) }
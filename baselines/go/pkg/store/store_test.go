@@ -0,0 +1,336 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"lambdaperf/pkg/lambdalog"
+	"lambdaperf/pkg/results"
+)
+
+func testRun(id string, started time.Time, runtime string, ms ...float64) *results.Run {
+	r := results.Result{Runtime: runtime, Workload: "fibonacci", Kind: "lambda", Arch: "x86_64"}
+	for i, v := range ms {
+		r.Samples = append(r.Samples, results.Sample{Iteration: i, ClientMS: v, RequestID: "req", DurationMS: v, Cold: i == 0})
+	}
+	return &results.Run{ID: id, Mode: "lambda", StartedAt: started, FinishedAt: started.Add(time.Minute), Results: []results.Result{r}}
+}
+
+func TestSaveAndHistory(t *testing.T) {
+	ctx := context.Background()
+	s, err := Open(filepath.Join(t.TempDir(), "nested", "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	runs := []*results.Run{
+		testRun("r1", t0, "go", 10, 11),
+		testRun("r2", t0.Add(time.Hour), "go", 12, 13, 14),
+		testRun("r3", t0.Add(2*time.Hour), "ruchy", 5),
+	}
+	runs[2].Results[0].SnapStart = true
+	runs[2].Results[0].Extension = true
+	runs[2].Results[0].VPC = true
+	runs[2].Results[0].Chaos = true
+	runs[2].Results[0].Serializer = "jsoniter"
+	runs[2].Results[0].Edge = true
+	runs[2].Results[0].Sandbox = "gvisor"
+	runs[2].Results[0].Imprecise = true
+	runs[2].Results[0].Optimum = "balanced"
+	runs[2].Results[0].Region = "eu-west-1"
+	runs[2].Results[0].Package = "image"
+	runs[2].Results[0].LambdaRuntime = "123456789012.dkr.ecr.eu-west-1.amazonaws.com/ruchy@sha256:ab12"
+	runs[1].Metadata = &results.Metadata{Commit: "3f4e2a1c", Dirty: true, Toolchains: map[string]string{"go": "go1.24.2"}, LambdaGo: "v1.50.0",
+		Packages: map[string]string{"baseline-go-fibonacci": "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="}}
+	runs[2].Results[0].Samples[0].RestoreMS = 240
+	runs[2].Results[0].Samples[0].Warmup = true
+	runs[2].Results[0].Samples[0].SDKMS = 31.5
+	runs[2].Results[0].Samples[0].MaxRSSKB = 1536
+	runs[2].Results[0].Samples[0].UserMS, runs[2].Results[0].Samples[0].SystemMS = 4.5, 0.5
+	runs[2].Results[0].Samples[0].Counters = map[string]float64{"instructions": 4.2e9}
+	runs[2].Results[0].Samples[0].Segments = map[string]float64{"trace_init_ms": 38.5}
+	runs[2].Results[0].Samples[0].GoRuntime = map[string]float64{"go_gc_pause_ms": 0.75}
+	runs[2].Results[0].Samples[0].Telemetry = map[string]float64{"telemetry_runtime_ms": 3.125}
+	runs[2].Results[0].Samples[0].TTFBMS = 42.5
+	runs[2].Results[0].Samples[0].Deliveries = 2
+	runs[2].Results[0].Samples[0].Bytes = 5 << 20
+	runs[2].Results[0].Samples[0].HTTP = map[string]float64{"http_new_conns": 0, "http_tls_ms": 18.25}
+	runs[2].Results[0].Samples[0].IO = map[string]float64{"write_mb_s": 180.5}
+	runs[2].Results[0].Samples[0].Host = &lambdalog.Host{CPUGeneration: "icelake", Sandbox: "3fc0cf890b4e"}
+	runs[2].Results[0].Samples[0].Histogram = &lambdalog.Histogram{BudgetMS: 50}
+	runs[2].Results[0].Samples[0].Histogram.Record(2 * time.Millisecond)
+	runs[2].Results[0].Samples[0].Retries, runs[2].Results[0].Samples[0].Excluded = 3, "throttle"
+	runs[2].Results[0].ProvisionedConcurrency = 5
+	runs[2].Results[0].Input = map[string]int{"n": 30}
+	runs[2].Results[0].BinaryBytes, runs[2].Results[0].PackageBytes = 401_000, 180_000
+	for _, run := range runs {
+		if err := s.Save(ctx, run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Saving again replaces rather than duplicates.
+	if err := s.Save(ctx, runs[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.History(ctx, Query{Workload: "fibonacci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].RunID != "r1" || got[2].RunID != "r3" {
+		t.Fatalf("History = %+v", got)
+	}
+	e := got[1]
+	if !e.StartedAt.Equal(t0.Add(time.Hour)) || e.Mode != "lambda" || e.Result.Arch != "x86_64" {
+		t.Errorf("entry = %+v", e)
+	}
+	if len(e.Result.Samples) != 3 || e.Result.Samples[2].DurationMS != 14 || !e.Result.Samples[0].Cold {
+		t.Errorf("samples = %+v", e.Result.Samples)
+	}
+
+	got, _ = s.History(ctx, Query{Workload: "fibonacci", Runtimes: []string{"go"}, Limit: 1})
+	if len(got) != 1 || got[0].RunID != "r2" {
+		t.Errorf("go, limit 1 = %+v", got)
+	}
+	got, _ = s.History(ctx, Query{Limit: 2})
+	if len(got) != 2 || got[0].RunID != "r2" {
+		t.Errorf("limit 2 = %+v", got)
+	}
+	got, _ = s.History(ctx, Query{Since: t0.Add(90 * time.Minute)})
+	if len(got) != 1 || got[0].RunID != "r3" {
+		t.Errorf("since = %+v", got)
+	}
+	if r := got[0].Result; !r.SnapStart || !r.Extension || !r.VPC || !r.Chaos || r.Serializer != "jsoniter" || !r.Edge || r.Sandbox != "gvisor" || !r.Imprecise || r.Optimum != "balanced" || r.Region != "eu-west-1" || r.Package != "image" || r.Samples[0].RestoreMS != 240 || !r.Samples[0].Warmup || r.Samples[0].SDKMS != 31.5 || r.ProvisionedConcurrency != 5 ||
+		r.Samples[0].MaxRSSKB != 1536 || r.Samples[0].UserMS != 4.5 || r.Samples[0].SystemMS != 0.5 || r.Samples[0].Counters["instructions"] != 4.2e9 ||
+		r.Samples[0].Segments["trace_init_ms"] != 38.5 || r.Samples[0].GoRuntime["go_gc_pause_ms"] != 0.75 || r.Samples[0].Telemetry["telemetry_runtime_ms"] != 3.125 || r.Samples[0].TTFBMS != 42.5 || r.Samples[0].Deliveries != 2 ||
+		r.Samples[0].Bytes != 5<<20 || len(r.Samples[0].HTTP) != 2 || r.Samples[0].HTTP["http_tls_ms"] != 18.25 || r.Samples[0].IO["write_mb_s"] != 180.5 || r.Samples[0].Retries != 3 || r.Samples[0].Excluded != "throttle" || r.Input["n"] != 30 ||
+		r.Samples[0].Host == nil || r.Samples[0].Host.CPUGeneration != "icelake" ||
+		r.Samples[0].Histogram == nil || r.Samples[0].Histogram.Count != 1 || r.Samples[0].Histogram.QuantileMS(0.5) < 1.9 ||
+		r.LambdaRuntime == "" || r.BinaryBytes != 401_000 || r.PackageBytes != 180_000 {
+		t.Errorf("configuration fields not round-tripped: %+v", r)
+	}
+	if got, _ := s.History(ctx, Query{Workload: "json"}); len(got) != 0 {
+		t.Errorf("unknown workload = %+v", got)
+	}
+
+	run, err := s.Run(ctx, "r2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !run.FinishedAt.Equal(t0.Add(time.Hour+time.Minute)) || len(run.Results) != 1 || len(run.Results[0].Samples) != 3 {
+		t.Errorf("Run(r2) = %+v", run)
+	}
+	if m := run.Metadata; m == nil || m.Commit != "3f4e2a1c" || !m.Dirty || m.Toolchains["go"] != "go1.24.2" || m.LambdaGo != "v1.50.0" ||
+		m.Packages["baseline-go-fibonacci"] != "47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=" {
+		t.Errorf("Run(r2) metadata = %+v", m)
+	}
+	if run, _ := s.Run(ctx, "r1"); run.Metadata != nil {
+		t.Errorf("Run(r1) metadata = %+v", run.Metadata)
+	}
+	if _, err := s.Run(ctx, "r9"); err == nil {
+		t.Error("Run of an unknown ID succeeded")
+	}
+
+	if prev, err := s.Previous(ctx, "lambda", "r3", runs[2].StartedAt); err != nil || prev == nil || prev.ID != "r2" {
+		t.Errorf("Previous(r3) = %+v, %v", prev, err)
+	}
+	// A run not yet saved has every stored one before it.
+	if prev, _ := s.Previous(ctx, "lambda", "r4", t0.Add(3*time.Hour)); prev == nil || prev.ID != "r3" {
+		t.Errorf("Previous(r4) = %+v", prev)
+	}
+	if prev, err := s.Previous(ctx, "lambda", "r1", t0); err != nil || prev != nil {
+		t.Errorf("Previous(r1) = %+v, %v", prev, err)
+	}
+	if prev, _ := s.Previous(ctx, "local", "r4", t0.Add(3*time.Hour)); prev != nil {
+		t.Errorf("Previous of another mode = %+v", prev)
+	}
+}
+
+func TestLatestArtifact(t *testing.T) {
+	ctx := context.Background()
+	s, err := Open(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, size := range []int64{500, 400} {
+		a := Artifact{Kind: "lambda", Runtime: "ruchy", Workload: "fibonacci", Arch: "arm64",
+			BuiltAt: t0.Add(time.Duration(i) * time.Hour), BinaryBytes: size * 2, PackageBytes: size}
+		if err := s.SaveArtifact(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, ok, err := s.LatestArtifact(ctx, "lambda", "ruchy", "fibonacci", "arm64", "")
+	if err != nil || !ok {
+		t.Fatalf("LatestArtifact: ok=%v err=%v", ok, err)
+	}
+	if a.PackageBytes != 400 || a.BinaryBytes != 800 || !a.BuiltAt.Equal(t0.Add(time.Hour)) {
+		t.Errorf("latest = %+v", a)
+	}
+	if _, ok, err := s.LatestArtifact(ctx, "lambda", "ruchy", "fibonacci", "x86_64", ""); ok || err != nil {
+		t.Errorf("other arch: ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := s.LatestArtifact(ctx, "lambda", "ruchy", "fibonacci", "arm64", "image"); ok || err != nil {
+		t.Errorf("image package: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	a, err := Open(filepath.Join(dir, "a.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := Open(filepath.Join(dir, "b.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	shared := testRun("r1", t0, "go", 10, 11)
+	shared.Metadata = &results.Metadata{Commit: "3f4e2a1c"}
+	build := Artifact{Kind: "lambda", Runtime: "go", Workload: "fibonacci", Arch: "x86_64", BuiltAt: t0, BinaryBytes: 1 << 20}
+	for _, s := range []*Store{a, b} {
+		if err := s.Save(ctx, shared); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveArtifact(ctx, build); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Save(ctx, testRun("r2", t0.Add(time.Hour), "ruchy", 5)); err != nil {
+		t.Fatal(err)
+	}
+	build.BuiltAt = t0.Add(time.Hour)
+	if err := b.SaveArtifact(ctx, build); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, artifacts, err := a.Merge(ctx, b)
+	if err != nil || runs != 1 || artifacts != 1 {
+		t.Fatalf("Merge = %d runs, %d artifacts, %v; want 1, 1", runs, artifacts, err)
+	}
+	if ids, _ := a.RunIDs(ctx); len(ids) != 2 || ids[1] != "r2" {
+		t.Errorf("merged runs %v", ids)
+	}
+	got, err := a.Run(ctx, "r2")
+	if err != nil || len(got.Results) != 1 || got.Results[0].Runtime != "ruchy" || len(got.Results[0].Samples) != 1 {
+		t.Errorf("merged run r2 = %+v, %v", got, err)
+	}
+	if runs, artifacts, err := a.Merge(ctx, b); err != nil || runs != 0 || artifacts != 0 {
+		t.Errorf("merging again = %d runs, %d artifacts, %v; want nothing new", runs, artifacts, err)
+	}
+	if built, _ := a.Artifacts(ctx); len(built) != 2 {
+		t.Errorf("artifacts after merge %+v", built)
+	}
+}
+
+// statusError is an S3 error response of a status code.
+type statusError int
+
+func (e statusError) Error() string       { return fmt.Sprintf("status %d", int(e)) }
+func (e statusError) HTTPStatusCode() int { return int(e) }
+
+// object is an in-memory S3 object honoring conditional writes. beforePut,
+// when set, runs before each write, as a writer racing it would.
+type object struct {
+	data      []byte
+	version   int
+	puts      int
+	beforePut func()
+}
+
+func (o *object) etag() string { return fmt.Sprintf(`"v%d"`, o.version) }
+
+func (o *object) GetObject(_ context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	if o.data == nil {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(o.data)), ETag: aws.String(o.etag())}, nil
+}
+
+func (o *object) PutObject(_ context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if o.beforePut != nil {
+		o.beforePut()
+	}
+	o.puts++
+	switch {
+	case in.IfNoneMatch != nil && o.data != nil, in.IfMatch != nil && aws.ToString(in.IfMatch) != o.etag():
+		return nil, statusError(http.StatusPreconditionFailed)
+	}
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	o.data = data
+	o.version++
+	return &s3.PutObjectOutput{ETag: aws.String(o.etag())}, nil
+}
+
+func TestSharedUpdate(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	obj := &object{}
+	laptop := &Shared{Client: obj, Bucket: "bench", Key: "results.db", Path: filepath.Join(dir, "laptop", "results.db")}
+	ci := &Shared{Client: obj, Bucket: "bench", Key: "results.db", Path: filepath.Join(dir, "ci", "results.db")}
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	save := func(run *results.Run) func(*Store) error {
+		return func(s *Store) error { return s.Save(ctx, run) }
+	}
+
+	if err := laptop.Update(ctx, save(testRun("r1", t0, "go", 10))); err != nil {
+		t.Fatal(err)
+	}
+	// CI saves r2 while the laptop is saving r3: the laptop's first write
+	// loses, and its retry lands on top of r2.
+	obj.beforePut = func() {
+		obj.beforePut = nil
+		if err := ci.Update(ctx, save(testRun("r2", t0.Add(time.Hour), "ruchy", 5))); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := laptop.Update(ctx, save(testRun("r3", t0.Add(2*time.Hour), "go", 12))); err != nil {
+		t.Fatal(err)
+	}
+	if obj.puts != 4 {
+		t.Errorf("%d writes, want 4: r1, r2, r3 refused, r3 again", obj.puts)
+	}
+	if _, err := ci.Pull(ctx); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(ci.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if ids, _ := s.RunIDs(ctx); len(ids) != 3 {
+		t.Errorf("shared database holds runs %v, want r1, r2 and r3", ids)
+	}
+
+	obj.beforePut = func() { obj.version++ }
+	if err := laptop.Update(ctx, func(*Store) error { return nil }); !errors.Is(err, ErrContended) {
+		t.Errorf("Update against a writer that always wins: %v", err)
+	}
+	obj.beforePut = nil
+	if err := laptop.Update(ctx, func(*Store) error { return errors.New("refused") }); err == nil || obj.puts != 9 {
+		t.Errorf("failing change: %v after %d writes; want it reported without a write", err, obj.puts)
+	}
+}
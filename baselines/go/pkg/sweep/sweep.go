@@ -0,0 +1,192 @@
+// Package sweep reconfigures one deployed function across a range of
+// memory sizes and benchmarks it at each. Lambda allocates CPU in
+// proportion to memory, so a comparison at a single size says little
+// about how runtimes scale. It sweeps ephemeral storage (/tmp) sizes the
+// same way.
+package sweep
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"lambdaperf/pkg/canary"
+	"lambdaperf/pkg/coldstart"
+	"lambdaperf/pkg/invoke"
+	"lambdaperf/pkg/progress"
+	"lambdaperf/pkg/reportparser"
+	"lambdaperf/pkg/results"
+	"lambdaperf/pkg/stats"
+)
+
+// DefaultSizes are the memory sizes (MB) swept when none are given. 1769 MB
+// is the point where a function gets one full vCPU.
+var DefaultSizes = []int32{128, 256, 512, 1024, 1769, 3008}
+
+// DefaultEphemeralSizes are the ephemeral storage sizes (MB) swept when
+// none are given, from Lambda's default of 512 MB to its 10 GB maximum.
+var DefaultEphemeralSizes = []int32{512, 1024, 2048, 4096, 10240}
+
+// Point is the measurements taken at one memory and ephemeral storage
+// size, of which one is the swept size and the other the function's own.
+type Point struct {
+	MemoryMB    int32
+	EphemeralMB int32
+	Samples     []results.Sample
+}
+
+// Runner sweeps one function.
+type Runner struct {
+	Client       coldstart.LambdaAPI
+	FunctionName string
+	Sizes        []int32
+	// Ephemeral sweeps the ephemeral storage size instead of memory;
+	// Sizes then default to DefaultEphemeralSizes.
+	Ephemeral bool
+	// Invocations per size, after the first (cold) invocation that
+	// follows every reconfiguration.
+	Invocations int
+	// Warmup runs at every size before the recorded invocations; the cold
+	// one is then part of it. See results.Collect.
+	Warmup stats.Warmup
+	// Precision extends the recorded invocations at every size.
+	Precision stats.Precision
+	Payload   []byte
+	// Expected is the result every response must report; invocations
+	// that return anything else fail. Empty accepts any response.
+	Expected string
+	// Runtime, if set, is the runtime the function was deployed from. The
+	// first invocation at every size is then a canary.Check against it
+	// and Expected, and a failed check ends the sweep before the size's
+	// samples are collected.
+	Runtime string
+	// UpdateTimeout bounds each configuration update. Zero means two
+	// minutes.
+	UpdateTimeout time.Duration
+	// Backoff retries configuration updates and invocations that fail
+	// transiently; the zero value tries each once.
+	Backoff invoke.Backoff
+}
+
+// Run benchmarks the function at every size and restores its original
+// memory or ephemeral storage setting afterwards, even on failure.
+func (r *Runner) Run(ctx context.Context) (points []Point, err error) {
+	cfg, err := r.Client.GetFunctionConfiguration(ctx, &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(r.FunctionName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get configuration of %s: %w", r.FunctionName, err)
+	}
+	// Lambda's default ephemeral storage, for a configuration without one.
+	memory, ephemeral := aws.ToInt32(cfg.MemorySize), int32(512)
+	if cfg.EphemeralStorage != nil {
+		ephemeral = aws.ToInt32(cfg.EphemeralStorage.Size)
+	}
+	original := memory
+	if r.Ephemeral {
+		original = ephemeral
+	}
+	defer func() {
+		// Restore with a fresh context so an interrupted sweep still
+		// leaves the function as it found it.
+		if rerr := r.setSize(context.WithoutCancel(ctx), original); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	sizes := r.Sizes
+	switch {
+	case len(sizes) > 0:
+	case r.Ephemeral:
+		sizes = DefaultEphemeralSizes
+	default:
+		sizes = DefaultSizes
+	}
+	inv := &invoke.Retry{Invoker: &invoke.Lambda{Client: r.Client, FunctionName: r.FunctionName}, Backoff: r.Backoff}
+	for _, size := range sizes {
+		if _, err := r.Backoff.Do(ctx, func() error { return r.setSize(ctx, size) }); err != nil {
+			return points, err
+		}
+		p := Point{MemoryMB: size, EphemeralMB: ephemeral}
+		if r.Ephemeral {
+			p.MemoryMB, p.EphemeralMB = memory, size
+		}
+		label := fmt.Sprintf("%s at %d MB", r.FunctionName, size)
+		if r.Ephemeral {
+			label = fmt.Sprintf("%s with %d MB of /tmp", r.FunctionName, size)
+		}
+		// The first invocation after an update is always cold; keep it,
+		// flagged, so warm statistics can exclude it. It is the canary
+		// invoke when there is one.
+		var checked *invoke.Response
+		if r.Runtime != "" {
+			res, err := canary.Check(ctx, inv, r.Payload, r.Runtime, r.Expected)
+			if err != nil {
+				return points, fmt.Errorf("%s: %w", label, err)
+			}
+			checked = &res.Response
+		}
+		pctx, task := progress.Track(ctx, label, r.Invocations+1)
+		p.Samples, _ = results.Collect(pctx, r.Invocations+1, r.Warmup, r.Precision, func(i int) results.Sample {
+			if i == 0 && checked != nil {
+				return r.sample(i, *checked, nil)
+			}
+			resp, err := inv.Invoke(ctx, r.Payload)
+			return r.sample(i, resp, err)
+		})
+		task.Finish()
+		points = append(points, p)
+		if ctx.Err() != nil {
+			return points, ctx.Err()
+		}
+	}
+	return points, nil
+}
+
+// sample records invocation i, which returned resp and err.
+func (r *Runner) sample(i int, resp invoke.Response, err error) results.Sample {
+	s := results.Sample{
+		Iteration: i,
+		ClientMS:  results.Milliseconds(resp.Elapsed),
+		Retries:   resp.Retries,
+	}.WithResponse(resp.Payload)
+	if rep, ok := reportparser.Last(resp.LogTail); ok {
+		s = s.WithReport(rep)
+	}
+	switch {
+	case err != nil:
+		s.Error, s.Excluded = err.Error(), string(invoke.Classify(err))
+	case resp.FunctionError != "":
+		s.Error = resp.FunctionError
+	default:
+		s = s.Verify(r.Expected)
+	}
+	return s
+}
+
+// setSize sets the swept setting, memory or ephemeral storage, to size.
+func (r *Runner) setSize(ctx context.Context, size int32) error {
+	in := &lambda.UpdateFunctionConfigurationInput{FunctionName: aws.String(r.FunctionName)}
+	setting := "memory"
+	if r.Ephemeral {
+		in.EphemeralStorage, setting = &types.EphemeralStorage{Size: aws.Int32(size)}, "ephemeral storage"
+	} else {
+		in.MemorySize = aws.Int32(size)
+	}
+	if _, err := r.Client.UpdateFunctionConfiguration(ctx, in); err != nil {
+		return fmt.Errorf("set %s %s to %d MB: %w", r.FunctionName, setting, size, err)
+	}
+	timeout := r.UpdateTimeout
+	if timeout == 0 {
+		timeout = 2 * time.Minute
+	}
+	waiter := lambda.NewFunctionUpdatedV2Waiter(r.Client)
+	if err := waiter.Wait(ctx, &lambda.GetFunctionInput{FunctionName: aws.String(r.FunctionName)}, timeout); err != nil {
+		return fmt.Errorf("wait for %s update: %w", r.FunctionName, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package sweep
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// fakeLambda reports a billed duration that halves every time memory
+// doubles, as a CPU-bound function would, and an init duration on the
+// first invocation after an update.
+type fakeLambda struct {
+	memory    int32
+	ephemeral int32
+	updates   []int32
+	calls     int
+	updated   bool
+	// payload is what every invocation returns.
+	payload string
+}
+
+func (f *fakeLambda) Invoke(_ context.Context, _ *lambda.InvokeInput, _ ...func(*lambda.Options)) (*lambda.InvokeOutput, error) {
+	f.calls++
+	ms := 128 * 1000 / float64(f.memory)
+	tail := fmt.Sprintf("REPORT RequestId: r%d\tDuration: %.2f ms\tBilled Duration: %.0f ms\tMemory Size: %d MB\tMax Memory Used: 20 MB\t", f.calls, ms, ms, f.memory)
+	if f.updated {
+		tail += "Init Duration: 50.00 ms\t"
+		f.updated = false
+	}
+	return &lambda.InvokeOutput{
+		StatusCode: 200,
+		Payload:    []byte(f.payload),
+		LogResult:  aws.String(base64.StdEncoding.EncodeToString([]byte(tail + "\n"))),
+	}, nil
+}
+
+func (f *fakeLambda) GetFunction(_ context.Context, _ *lambda.GetFunctionInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	return &lambda.GetFunctionOutput{Configuration: &types.FunctionConfiguration{
+		State:            types.StateActive,
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+	}}, nil
+}
+
+func (f *fakeLambda) GetFunctionConfiguration(_ context.Context, _ *lambda.GetFunctionConfigurationInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionConfigurationOutput, error) {
+	out := &lambda.GetFunctionConfigurationOutput{MemorySize: aws.Int32(f.memory)}
+	if f.ephemeral > 0 {
+		out.EphemeralStorage = &types.EphemeralStorage{Size: aws.Int32(f.ephemeral)}
+	}
+	return out, nil
+}
+
+func (f *fakeLambda) UpdateFunctionConfiguration(_ context.Context, in *lambda.UpdateFunctionConfigurationInput, _ ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error) {
+	f.updated = true
+	if in.EphemeralStorage != nil {
+		f.ephemeral = aws.ToInt32(in.EphemeralStorage.Size)
+		f.updates = append(f.updates, f.ephemeral)
+		return &lambda.UpdateFunctionConfigurationOutput{}, nil
+	}
+	f.memory = aws.ToInt32(in.MemorySize)
+	f.updates = append(f.updates, f.memory)
+	return &lambda.UpdateFunctionConfigurationOutput{}, nil
+}
+
+func TestRunSweepsAndRestores(t *testing.T) {
+	fake := &fakeLambda{memory: 128}
+	r := &Runner{Client: fake, FunctionName: "baseline-go-fibonacci", Sizes: []int32{256, 1024}, Invocations: 3}
+	points, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 {
+		t.Fatalf("got %d points, want 2", len(points))
+	}
+	for _, p := range points {
+		if len(p.Samples) != 4 {
+			t.Errorf("%d MB: %d samples, want 4 (1 cold + 3 warm)", p.MemoryMB, len(p.Samples))
+		}
+		if got := p.Samples[0].MemorySizeMB; int32(got) != p.MemoryMB {
+			t.Errorf("%d MB point measured at %d MB", p.MemoryMB, got)
+		}
+	}
+	if want := []int32{256, 1024, 128}; fmt.Sprint(fake.updates) != fmt.Sprint(want) {
+		t.Errorf("memory updates = %v, want %v", fake.updates, want)
+	}
+}
+
+func TestRunSweepsEphemeralStorage(t *testing.T) {
+	fake := &fakeLambda{memory: 1024}
+	r := &Runner{Client: fake, FunctionName: "baseline-go-tmpio", Sizes: []int32{2048, 10240}, Ephemeral: true, Invocations: 1}
+	points, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 || points[0].EphemeralMB != 2048 || points[1].EphemeralMB != 10240 || points[1].MemoryMB != 1024 {
+		t.Fatalf("points = %+v, want 2048 and 10240 MB of storage at 1024 MB of memory", points)
+	}
+	// Memory is left alone, and the storage restored to Lambda's default.
+	if want := []int32{2048, 10240, 512}; fmt.Sprint(fake.updates) != fmt.Sprint(want) || fake.memory != 1024 {
+		t.Errorf("updates = %v at %d MB, want %v", fake.updates, fake.memory, want)
+	}
+}
+
+func TestRunCanary(t *testing.T) {
+	body := `{"statusCode":200,"body":"fibonacci(35)=9227465","runtime":"go"}`
+	fake := &fakeLambda{memory: 128, payload: body}
+	r := &Runner{Client: fake, FunctionName: "baseline-go-fibonacci", Sizes: []int32{256, 1024}, Invocations: 3,
+		Runtime: "go", Expected: "fibonacci(35)=9227465"}
+	points, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The canary invoke is each size's cold first sample, not an extra
+	// invocation.
+	if fake.calls != 8 {
+		t.Errorf("%d invocations, want 8", fake.calls)
+	}
+	for _, p := range points {
+		if len(p.Samples) != 4 || !p.Samples[0].Cold || p.Samples[1].Cold || p.Samples[0].Error != "" {
+			t.Errorf("%d MB: samples %+v, want 4 with only the first cold", p.MemoryMB, p.Samples)
+		}
+	}
+
+	// A handler of another runtime stops the sweep before any samples.
+	fake = &fakeLambda{memory: 128, payload: `{"statusCode":200,"body":"fibonacci(35)=9227465","runtime":"python"}`}
+	r.Client = fake
+	points, err = r.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "canary") || len(points) != 0 || fake.calls != 1 {
+		t.Errorf("wrong runtime: %d points after %d invocations, err %v", len(points), fake.calls, err)
+	}
+	if fake.memory != 128 {
+		t.Errorf("memory left at %d MB, want 128 restored", fake.memory)
+	}
+}
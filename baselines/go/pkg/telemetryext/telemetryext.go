@@ -0,0 +1,307 @@
+// Package telemetryext is the telemetry extension
+// (baselines/go/extensions/telemetry): an external Lambda extension that
+// subscribes to the Lambda Telemetry API and writes one line per
+// invocation to its standard output, which Lambda sends to the function's
+// log group. A line carries the invocation's platform.runtimeDone metrics
+// and spans and, on an environment's first invocation, the
+// platform.initStart to platform.initRuntimeDone phase before it. Lambda
+// measures those to the microsecond; the REPORT line rounds them, and has
+// no breakdown of the time around the response at all.
+//
+// Lines are keyed by request ID, so the harness joins them with the
+// REPORT line as it joins lambdalog's invocation lines (see
+// reportparser.FetchTelemetry). Like lambdalog it links nothing beyond
+// the standard library: it runs inside every environment it measures.
+package telemetryext
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Type is the "type" of telemetry lines, which tells them apart from
+// whatever else the function logs.
+const Type = "telemetry"
+
+// ListenAddr is where the extension receives telemetry. The sandbox
+// hostname resolves inside the execution environment only; the port is
+// not the noop-telemetry extension's, so both can be attached at once.
+const ListenAddr = "sandbox.localdomain:4244"
+
+// Line is one telemetry line: the platform's view of one invocation.
+type Line struct {
+	Type      string `json:"type"`
+	RequestID string `json:"request_id"`
+	Status    string `json:"status"`
+	// InitType and InitMS are the initialization of the environment,
+	// set on its first invocation only. InitMS is platform.initReport's
+	// duration, or from platform.initStart to platform.initRuntimeDone
+	// when Lambda sent no report.
+	InitType string  `json:"init_type,omitempty"`
+	InitMS   float64 `json:"init_ms,omitempty"`
+	// RuntimeMS is platform.runtimeDone's duration: from the runtime
+	// receiving the event to it asking for the next one.
+	RuntimeMS float64 `json:"runtime_ms"`
+	// The runtimeDone spans: until the response started, of sending it,
+	// and of the runtime's work after it.
+	ResponseLatencyMS float64 `json:"response_latency_ms,omitempty"`
+	ResponseMS        float64 `json:"response_ms,omitempty"`
+	OverheadMS        float64 `json:"overhead_ms,omitempty"`
+	ProducedBytes     int64   `json:"produced_bytes,omitempty"`
+}
+
+// Parse parses a single telemetry line, reporting false for any other
+// line.
+func Parse(line string) (Line, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "{") {
+		return Line{}, false
+	}
+	var l Line
+	if err := json.Unmarshal([]byte(line), &l); err != nil || l.Type != Type || l.RequestID == "" {
+		return Line{}, false
+	}
+	return l, true
+}
+
+// Event is one event of a batch the Telemetry API delivers.
+type Event struct {
+	Time   time.Time       `json:"time"`
+	Type   string          `json:"type"`
+	Record json.RawMessage `json:"record"`
+}
+
+// record holds the fields of the platform event records used here.
+type record struct {
+	RequestID          string `json:"requestId"`
+	InitializationType string `json:"initializationType"`
+	Status             string `json:"status"`
+	Metrics            struct {
+		DurationMS    float64 `json:"durationMs"`
+		ProducedBytes int64   `json:"producedBytes"`
+	} `json:"metrics"`
+	Spans []struct {
+		Name       string  `json:"name"`
+		DurationMS float64 `json:"durationMs"`
+	} `json:"spans"`
+}
+
+// Collector turns Telemetry API batches into lines. It is the HTTP
+// handler the subscription delivers to.
+type Collector struct {
+	out io.Writer
+
+	mu      sync.Mutex
+	init    Line      // the pending init phase, until an invocation takes it
+	started time.Time // of the pending init phase
+	done    map[string]chan struct{}
+}
+
+// NewCollector returns a Collector writing lines to out.
+func NewCollector(out io.Writer) *Collector {
+	return &Collector{out: out, done: map[string]chan struct{}{}}
+}
+
+// ServeHTTP reads a batch of events.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var events []Event
+	if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := c.Add(events); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Add reads events in order, writing a line for every platform.runtimeDone
+// among them. Events of other types are ignored.
+func (c *Collector) Add(events []Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ev := range events {
+		var rec record
+		switch ev.Type {
+		case "platform.initStart", "platform.initRuntimeDone", "platform.initReport", "platform.runtimeDone":
+			if err := json.Unmarshal(ev.Record, &rec); err != nil {
+				return fmt.Errorf("%s record: %w", ev.Type, err)
+			}
+		default:
+			continue
+		}
+		switch ev.Type {
+		case "platform.initStart":
+			c.init, c.started = Line{InitType: rec.InitializationType}, ev.Time
+		case "platform.initRuntimeDone":
+			if c.init.InitMS == 0 && !c.started.IsZero() {
+				c.init.InitMS = float64(ev.Time.Sub(c.started).Microseconds()) / 1000
+			}
+		case "platform.initReport":
+			c.init.InitType = rec.InitializationType
+			c.init.InitMS = rec.Metrics.DurationMS
+		case "platform.runtimeDone":
+			if err := c.write(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// write writes the line of an invocation's runtimeDone record and marks
+// the invocation done.
+func (c *Collector) write(rec record) error {
+	l := c.init
+	c.init, c.started = Line{}, time.Time{}
+	l.Type, l.RequestID, l.Status = Type, rec.RequestID, rec.Status
+	l.RuntimeMS, l.ProducedBytes = rec.Metrics.DurationMS, rec.Metrics.ProducedBytes
+	for _, s := range rec.Spans {
+		switch s.Name {
+		case "responseLatency":
+			l.ResponseLatencyMS = s.DurationMS
+		case "responseDuration":
+			l.ResponseMS = s.DurationMS
+		case "runtimeOverhead":
+			l.OverheadMS = s.DurationMS
+		}
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	if _, err := c.out.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if ch, ok := c.done[rec.RequestID]; ok {
+		delete(c.done, rec.RequestID)
+		close(ch)
+	} else {
+		// Nobody is waiting yet: leave a closed channel for Done.
+		ch := make(chan struct{})
+		close(ch)
+		c.done[rec.RequestID] = ch
+	}
+	return nil
+}
+
+// Done returns a channel closed once the line of the invocation with
+// requestID has been written. Call it once per invocation.
+func (c *Collector) Done(requestID string) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch, ok := c.done[requestID]
+	if !ok {
+		ch = make(chan struct{})
+		c.done[requestID] = ch
+		return ch
+	}
+	// Written before it was waited for.
+	delete(c.done, requestID)
+	return ch
+}
+
+// Subscription is the Telemetry API subscription delivering platform
+// events to uri. Lambda buffers events for at least 25ms; the minimum
+// keeps an invocation's line close behind it.
+func Subscription(uri string) []byte {
+	return []byte(`{"schemaVersion":"2022-12-13","types":["platform"],` +
+		`"buffering":{"maxItems":1000,"maxBytes":262144,"timeoutMs":25},` +
+		`"destination":{"protocol":"HTTP","URI":"` + uri + `"}}`)
+}
+
+// Extension is a registered extension's client of the Extensions and
+// Telemetry APIs.
+type Extension struct {
+	// API is the runtime API's base URL, from AWS_LAMBDA_RUNTIME_API.
+	API  string
+	ID   string
+	HTTP *http.Client
+}
+
+// NextEvent is the event the Extensions API hands the extension next.
+type NextEvent struct {
+	EventType string `json:"eventType"`
+	RequestID string `json:"requestId"`
+	// DeadlineMS is when the invocation times out, in Unix milliseconds.
+	DeadlineMS int64 `json:"deadlineMs"`
+}
+
+// Deadline returns DeadlineMS as a time.
+func (e NextEvent) Deadline() time.Time {
+	return time.UnixMilli(e.DeadlineMS)
+}
+
+// Register registers an extension named name (its file name under
+// /opt/extensions) for INVOKE and SHUTDOWN events.
+func Register(api, name string) (*Extension, error) {
+	e := &Extension{API: api, HTTP: http.DefaultClient}
+	req, err := http.NewRequest(http.MethodPost, api+"/2020-01-01/extension/register", strings.NewReader(`{"events":["INVOKE","SHUTDOWN"]}`))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Lambda-Extension-Name", name)
+	resp, err := e.do(req, nil)
+	if err != nil {
+		return nil, fmt.Errorf("register: %w", err)
+	}
+	if e.ID = resp.Header.Get("Lambda-Extension-Identifier"); e.ID == "" {
+		return nil, errors.New("register: no extension identifier")
+	}
+	return e, nil
+}
+
+// Subscribe subscribes to platform telemetry delivered to uri.
+func (e *Extension) Subscribe(uri string) error {
+	if err := e.call(http.MethodPut, "/2022-07-01/telemetry", Subscription(uri), nil); err != nil {
+		return fmt.Errorf("subscribe to telemetry: %w", err)
+	}
+	return nil
+}
+
+// Next blocks until the next event.
+func (e *Extension) Next() (NextEvent, error) {
+	var ev NextEvent
+	if err := e.call(http.MethodGet, "/2020-01-01/extension/event/next", nil, &ev); err != nil {
+		return NextEvent{}, fmt.Errorf("next event: %w", err)
+	}
+	return ev, nil
+}
+
+func (e *Extension) call(method, path string, body []byte, out any) error {
+	req, err := http.NewRequest(method, e.API+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Lambda-Extension-Identifier", e.ID)
+	_, err = e.do(req, out)
+	return err
+}
+
+// do sends req and decodes the response into out, if not nil.
+func (e *Extension) do(req *http.Request, out any) (*http.Response, error) {
+	resp, err := e.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,136 @@
+package telemetryext
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// batches are a cold start and a warm invocation as the Telemetry API
+// delivers them, split across batches the way buffering may split them.
+var batches = []string{
+	`[{"time":"2026-10-14T09:00:00.100Z","type":"platform.initStart","record":{"initializationType":"on-demand","phase":"init","runtimeVersion":"x"}},
+	  {"time":"2026-10-14T09:00:00.112Z","type":"platform.initRuntimeDone","record":{"initializationType":"on-demand","phase":"init","status":"success"}}]`,
+	`[{"time":"2026-10-14T09:00:00.112Z","type":"platform.initReport","record":{"initializationType":"on-demand","phase":"init","status":"success","metrics":{"durationMs":12.345}}},
+	  {"time":"2026-10-14T09:00:00.113Z","type":"platform.start","record":{"requestId":"req-1"}},
+	  {"time":"2026-10-14T09:00:00.140Z","type":"platform.runtimeDone","record":{"requestId":"req-1","status":"success",
+	    "spans":[{"name":"responseLatency","start":"2026-10-14T09:00:00.113Z","durationMs":25.123},{"name":"responseDuration","start":"2026-10-14T09:00:00.138Z","durationMs":0.042},{"name":"runtimeOverhead","start":"2026-10-14T09:00:00.139Z","durationMs":1.101}],
+	    "metrics":{"durationMs":26.789,"producedBytes":24}}}]`,
+	`[{"time":"2026-10-14T09:00:01.000Z","type":"platform.runtimeDone","record":{"requestId":"req-2","status":"success","metrics":{"durationMs":3.21,"producedBytes":24}}}]`,
+}
+
+func TestCollector(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewCollector(&buf)
+	waiting := c.Done("req-1")
+	for _, b := range batches {
+		var events []Event
+		if err := json.Unmarshal([]byte(b), &events); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Add(events); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case <-waiting:
+	default:
+		t.Error("waiting invocation not done")
+	}
+	select {
+	case <-c.Done("req-2"):
+	default:
+		t.Error("invocation written before it was waited for not done")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("wrote %d lines: %q", len(lines), buf.String())
+	}
+	cold, ok := Parse(lines[0])
+	want := Line{Type: Type, RequestID: "req-1", Status: "success", InitType: "on-demand", InitMS: 12.345,
+		RuntimeMS: 26.789, ResponseLatencyMS: 25.123, ResponseMS: 0.042, OverheadMS: 1.101, ProducedBytes: 24}
+	if !ok || cold != want {
+		t.Errorf("cold line = %+v, %v\nwant %+v", cold, ok, want)
+	}
+	warm, ok := Parse(lines[1])
+	if !ok || warm.RequestID != "req-2" || warm.InitMS != 0 || warm.InitType != "" || warm.RuntimeMS != 3.21 {
+		t.Errorf("warm line = %+v, %v", warm, ok)
+	}
+}
+
+func TestCollectorInitWithoutReport(t *testing.T) {
+	var buf bytes.Buffer
+	c := NewCollector(&buf)
+	var events []Event
+	if err := json.Unmarshal([]byte(batches[0]), &events); err != nil {
+		t.Fatal(err)
+	}
+	events = append(events, Event{Type: "platform.runtimeDone", Record: json.RawMessage(`{"requestId":"req-1","status":"success"}`)})
+	if err := c.Add(events); err != nil {
+		t.Fatal(err)
+	}
+	if l, ok := Parse(buf.String()); !ok || l.InitMS != 12 {
+		t.Errorf("line = %+v, %v; want init from the phase's timestamps", l, ok)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, line := range []string{
+		"REPORT RequestId: req-1\tDuration: 1.00 ms",
+		`{"type":"invocation","request_id":"req-1"}`,
+		`{"type":"telemetry"}`,
+		`{"type":`,
+	} {
+		if l, ok := Parse(line); ok {
+			t.Errorf("Parse(%q) = %+v", line, l)
+		}
+	}
+}
+
+func TestExtension(t *testing.T) {
+	var subscribed string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method + " " + r.URL.Path {
+		case "POST /2020-01-01/extension/register":
+			if r.Header.Get("Lambda-Extension-Name") != "telemetry" {
+				http.Error(w, "bad name", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Lambda-Extension-Identifier", "ext-1")
+			io.WriteString(w, `{}`)
+		case "PUT /2022-07-01/telemetry":
+			body, _ := io.ReadAll(r.Body)
+			subscribed = r.Header.Get("Lambda-Extension-Identifier") + " " + string(body)
+			io.WriteString(w, "OK")
+		case "GET /2020-01-01/extension/event/next":
+			io.WriteString(w, `{"eventType":"INVOKE","requestId":"req-1","deadlineMs":1791968400000}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ext, err := Register(srv.URL, "telemetry")
+	if err != nil || ext.ID != "ext-1" {
+		t.Fatalf("Register = %+v, %v", ext, err)
+	}
+	if err := ext.Subscribe("http://" + ListenAddr); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(subscribed, "ext-1 ") || !json.Valid([]byte(subscribed[len("ext-1 "):])) ||
+		!strings.Contains(subscribed, `"URI":"http://sandbox.localdomain:4244"`) {
+		t.Errorf("subscribed %q", subscribed)
+	}
+	ev, err := ext.Next()
+	if err != nil || ev.EventType != "INVOKE" || ev.RequestID != "req-1" || ev.Deadline().UnixMilli() != 1791968400000 {
+		t.Errorf("Next = %+v, %v", ev, err)
+	}
+	if _, err := Register(srv.URL, "other"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("rejected registration: %v", err)
+	}
+}
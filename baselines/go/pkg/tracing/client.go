@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+// Client reads traces from the X-Ray API. Only the two read calls the
+// harness needs are implemented.
+type Client struct {
+	Config aws.Config
+	// Endpoint overrides https://xray.<region>.amazonaws.com.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// TraceIDs calls GetTraceSummaries, following pagination.
+func (c *Client) TraceIDs(ctx context.Context, start, end time.Time, filter string) ([]string, error) {
+	var (
+		ids   []string
+		token string
+	)
+	for {
+		in := map[string]any{
+			"StartTime":        float64(start.UnixMilli()) / 1000,
+			"EndTime":          float64(end.UnixMilli()) / 1000,
+			"FilterExpression": filter,
+		}
+		if token != "" {
+			in["NextToken"] = token
+		}
+		var out struct {
+			TraceSummaries []struct {
+				ID string `json:"Id"`
+			}
+			NextToken string
+		}
+		if err := c.post(ctx, "/TraceSummaries", in, &out); err != nil {
+			return nil, fmt.Errorf("get trace summaries: %w", err)
+		}
+		for _, s := range out.TraceSummaries {
+			ids = append(ids, s.ID)
+		}
+		if token = out.NextToken; token == "" {
+			return ids, nil
+		}
+	}
+}
+
+// Traces calls BatchGetTraces, following pagination.
+func (c *Client) Traces(ctx context.Context, ids []string) ([]Trace, error) {
+	var (
+		traces []Trace
+		token  string
+	)
+	for {
+		in := map[string]any{"TraceIds": ids}
+		if token != "" {
+			in["NextToken"] = token
+		}
+		var out struct {
+			Traces []struct {
+				ID       string `json:"Id"`
+				Segments []struct {
+					Document string
+				}
+			}
+			NextToken string
+		}
+		if err := c.post(ctx, "/Traces", in, &out); err != nil {
+			return nil, fmt.Errorf("batch get traces: %w", err)
+		}
+		for _, t := range out.Traces {
+			trace := Trace{ID: t.ID}
+			for _, s := range t.Segments {
+				trace.Segments = append(trace.Segments, s.Document)
+			}
+			traces = append(traces, trace)
+		}
+		if token = out.NextToken; token == "" {
+			return traces, nil
+		}
+	}
+}
+
+// post sends in to path and decodes the response into out.
+func (c *Client) post(ctx context.Context, path string, in, out any) error {
+	api := &awsapi.Client{Config: c.Config, Service: "xray", Endpoint: c.Endpoint, HTTP: c.HTTP}
+	return api.REST(ctx, http.MethodPost, path, in, out)
+}
@@ -0,0 +1,201 @@
+// Package tracing fetches the AWS X-Ray traces of benchmark invocations
+// and breaks each into the segments Lambda records for it: initialization
+// (cold starts only), the handler's invocation, the runtime's overhead
+// after the response, and the downstream calls made during the
+// invocation. Functions must be deployed with active tracing; see
+// deploy.Config.Tracing.
+//
+// Lambda samples traces (by default the first request each second and 5%
+// of the rest), so only some invocations of a benchmark get a Breakdown.
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Breakdown is where one traced invocation spent its time.
+type Breakdown struct {
+	RequestID string
+	// InitMS is the Initialization subsegment, zero on warm invocations.
+	InitMS float64
+	// InvocationMS is the handler's run, downstream calls included.
+	InvocationMS float64
+	// OverheadMS is the time the runtime took after the response was
+	// sent, until it asked for the next event.
+	OverheadMS float64
+	// Downstream sums the subsegments directly under the invocation by
+	// name ("S3", "DynamoDB", ...). Only calls made through an X-Ray
+	// instrumented client are recorded.
+	Downstream map[string]float64
+}
+
+// DownstreamMS is the total time of the downstream calls.
+func (b Breakdown) DownstreamMS() float64 {
+	total := 0.0
+	for _, ms := range b.Downstream {
+		total += ms
+	}
+	return total
+}
+
+// Trace is a trace as BatchGetTraces returns it: the raw documents of
+// its segments.
+type Trace struct {
+	ID       string
+	Segments []string
+}
+
+// API is the part of the X-Ray API used to look traces up; Client
+// implements it.
+type API interface {
+	// TraceIDs lists the traces matching filter started between start
+	// and end.
+	TraceIDs(ctx context.Context, start, end time.Time, filter string) ([]string, error)
+	// Traces returns the traces with the given IDs, at most
+	// MaxBatch at a time.
+	Traces(ctx context.Context, ids []string) ([]Trace, error)
+}
+
+// MaxBatch is the most trace IDs BatchGetTraces accepts per call.
+const MaxBatch = 5
+
+// FunctionFilter is the filter expression selecting the traces of
+// function.
+func FunctionFilter(function string) string {
+	return fmt.Sprintf(`service(id(name: %q, type: "AWS::Lambda::Function"))`, function)
+}
+
+// segment is the part of a segment document the breakdown reads.
+type segment struct {
+	Name        string    `json:"name"`
+	Origin      string    `json:"origin"`
+	StartTime   float64   `json:"start_time"` // epoch seconds
+	EndTime     float64   `json:"end_time"`
+	Subsegments []segment `json:"subsegments"`
+	AWS         struct {
+		RequestID string `json:"request_id"`
+	} `json:"aws"`
+}
+
+func (s segment) ms() float64 { return (s.EndTime - s.StartTime) * 1000 }
+
+// Parse breaks a trace down. It reports false until both the Lambda
+// service segment, which carries the request ID, and the function segment
+// are in the trace: X-Ray assembles traces as segments arrive.
+func Parse(t Trace) (Breakdown, bool, error) {
+	var (
+		b        Breakdown
+		function bool
+	)
+	for _, doc := range t.Segments {
+		var s segment
+		if err := json.Unmarshal([]byte(doc), &s); err != nil {
+			return Breakdown{}, false, fmt.Errorf("trace %s: segment: %w", t.ID, err)
+		}
+		if s.AWS.RequestID != "" {
+			b.RequestID = s.AWS.RequestID
+		}
+		if s.Origin != "AWS::Lambda::Function" {
+			continue
+		}
+		function = true
+		for _, sub := range s.Subsegments {
+			// Lambda's newer trace format names them Init and Invoke.
+			switch sub.Name {
+			case "Initialization", "Init":
+				b.InitMS += sub.ms()
+			case "Invocation", "Invoke":
+				b.InvocationMS += sub.ms()
+				for _, call := range sub.Subsegments {
+					if b.Downstream == nil {
+						b.Downstream = map[string]float64{}
+					}
+					b.Downstream[call.Name] += call.ms()
+				}
+			case "Overhead":
+				b.OverheadMS += sub.ms()
+			}
+		}
+	}
+	return b, function && b.RequestID != "", nil
+}
+
+// Fetcher waits for the traces of a batch of invocations to be indexed.
+type Fetcher struct {
+	API API
+	// Wait bounds how long Fetch polls; zero means one minute. Traces are
+	// usually searchable within a few seconds of the invocation.
+	Wait time.Duration
+	// Interval is the pause between polls; zero means five seconds.
+	Interval time.Duration
+}
+
+// Fetch returns the breakdowns of the traced invocations of function
+// among requestIDs, keyed by request ID, for invocations made since
+// start. Since not every invocation is sampled it stops once a poll finds
+// nothing new after an earlier one found traces, as well as when every
+// request is accounted for or Wait has passed.
+func (f *Fetcher) Fetch(ctx context.Context, function string, start time.Time, requestIDs []string) (map[string]Breakdown, error) {
+	want := map[string]bool{}
+	for _, id := range requestIDs {
+		want[id] = true
+	}
+	found := map[string]Breakdown{}
+	done := map[string]bool{} // trace IDs fully parsed
+	deadline := time.Now().Add(orDefault(f.Wait, time.Minute))
+	// Trace IDs embed the time the trace started, a little before the
+	// client's clock may say the invocation did.
+	from := start.Add(-time.Minute)
+	for {
+		ids, err := f.API.TraceIDs(ctx, from, time.Now(), FunctionFilter(function))
+		if err != nil {
+			return found, err
+		}
+		var pending []string
+		for _, id := range ids {
+			if !done[id] {
+				pending = append(pending, id)
+			}
+		}
+		before := len(found)
+		for len(pending) > 0 {
+			batch := pending[:min(MaxBatch, len(pending))]
+			pending = pending[len(batch):]
+			traces, err := f.API.Traces(ctx, batch)
+			if err != nil {
+				return found, err
+			}
+			for _, t := range traces {
+				b, complete, err := Parse(t)
+				if err != nil {
+					return found, err
+				}
+				if !complete {
+					continue
+				}
+				done[t.ID] = true
+				if want[b.RequestID] {
+					found[b.RequestID] = b
+				}
+			}
+		}
+		if len(found) == len(want) || (before > 0 && len(found) == before) || time.Now().After(deadline) {
+			return found, nil
+		}
+		select {
+		case <-ctx.Done():
+			return found, ctx.Err()
+		case <-time.After(orDefault(f.Interval, 5*time.Second)):
+		}
+	}
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Segment documents as Lambda writes them for a cold invocation that
+// reads from S3.
+const (
+	serviceDoc  = `{"id":"a1","name":"ruchy-bench-go-s3","origin":"AWS::Lambda","start_time":100.000,"end_time":100.120,"aws":{"request_id":"req-1"}}`
+	functionDoc = `{"id":"b2","name":"ruchy-bench-go-s3","origin":"AWS::Lambda::Function","start_time":100.040,"end_time":100.118,
+		"subsegments":[
+			{"name":"Initialization","start_time":100.000,"end_time":100.040},
+			{"name":"Invocation","start_time":100.040,"end_time":100.110,"subsegments":[
+				{"name":"S3","start_time":100.045,"end_time":100.075},
+				{"name":"S3","start_time":100.080,"end_time":100.100}]},
+			{"name":"Overhead","start_time":100.110,"end_time":100.118}]}`
+)
+
+func near(got, want float64) bool { return math.Abs(got-want) < 1e-6 }
+
+func TestParse(t *testing.T) {
+	b, ok, err := Parse(Trace{ID: "1-abc", Segments: []string{serviceDoc, functionDoc}})
+	if err != nil || !ok {
+		t.Fatalf("Parse = %v, %v", ok, err)
+	}
+	if b.RequestID != "req-1" || !near(b.InitMS, 40) || !near(b.InvocationMS, 70) || !near(b.OverheadMS, 8) ||
+		len(b.Downstream) != 1 || !near(b.DownstreamMS(), 50) {
+		t.Errorf("breakdown = %+v", b)
+	}
+
+	// Until the function segment arrives the trace is incomplete.
+	if _, ok, err := Parse(Trace{ID: "1-abc", Segments: []string{serviceDoc}}); ok || err != nil {
+		t.Errorf("partial trace: ok = %v, err = %v", ok, err)
+	}
+	if _, _, err := Parse(Trace{ID: "1-abc", Segments: []string{"{"}}); err == nil {
+		t.Error("malformed segment accepted")
+	}
+}
+
+type fakeAPI struct {
+	polls  int
+	traces map[string]Trace
+	// visible is how many traces each successive poll lists.
+	visible []int
+	order   []string
+}
+
+func (f *fakeAPI) TraceIDs(ctx context.Context, start, end time.Time, filter string) ([]string, error) {
+	n := f.visible[min(f.polls, len(f.visible)-1)]
+	f.polls++
+	return f.order[:n], nil
+}
+
+func (f *fakeAPI) Traces(ctx context.Context, ids []string) ([]Trace, error) {
+	var out []Trace
+	for _, id := range ids {
+		out = append(out, f.traces[id])
+	}
+	return out, nil
+}
+
+func TestFetch(t *testing.T) {
+	api := &fakeAPI{traces: map[string]Trace{}, visible: []int{0, 1, 2, 2}}
+	for _, id := range []string{"req-1", "req-2"} {
+		doc := `{"origin":"AWS::Lambda","aws":{"request_id":"` + id + `"}}`
+		api.traces["t-"+id] = Trace{ID: "t-" + id, Segments: []string{doc, `{"origin":"AWS::Lambda::Function"}`}}
+		api.order = append(api.order, "t-"+id)
+	}
+	f := &Fetcher{API: api, Interval: time.Millisecond}
+
+	got, err := f.Fetch(context.Background(), "fn", time.Now(), []string{"req-1", "req-2"})
+	if err != nil || len(got) != 2 || api.polls != 3 {
+		t.Errorf("Fetch = %v, %v after %d polls", got, err, api.polls)
+	}
+
+	// An unsampled request ends the wait once no more traces turn up.
+	api.polls = 0
+	got, err = f.Fetch(context.Background(), "fn", time.Now(), []string{"req-1", "req-3"})
+	if err != nil || len(got) != 1 || api.polls != 3 {
+		t.Errorf("Fetch with unsampled request = %v, %v after %d polls", got, err, api.polls)
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]any
+		json.NewDecoder(r.Body).Decode(&in)
+		switch {
+		case r.URL.Path == "/TraceSummaries" && in["NextToken"] == nil:
+			w.Write([]byte(`{"TraceSummaries":[{"Id":"t1"}],"NextToken":"p2"}`))
+		case r.URL.Path == "/TraceSummaries":
+			w.Write([]byte(`{"TraceSummaries":[{"Id":"t2"}]}`))
+		case r.URL.Path == "/Traces":
+			w.Write([]byte(`{"Traces":[{"Id":"t1","Segments":[{"Id":"s","Document":"{}"}]}]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"unknown operation"}`))
+		}
+	}))
+	defer srv.Close()
+	c := &Client{Endpoint: srv.URL}
+	ctx := context.Background()
+
+	ids, err := c.TraceIDs(ctx, time.Now().Add(-time.Minute), time.Now(), FunctionFilter("fn"))
+	if err != nil || len(ids) != 2 || ids[1] != "t2" {
+		t.Errorf("TraceIDs = %v, %v", ids, err)
+	}
+	traces, err := c.Traces(ctx, []string{"t1"})
+	if err != nil || len(traces) != 1 || traces[0].Segments[0] != "{}" {
+		t.Errorf("Traces = %+v, %v", traces, err)
+	}
+	c.Endpoint = srv.URL + "/nope"
+	if _, err := c.Traces(ctx, []string{"t1"}); err == nil {
+		t.Error("error response not reported")
+	}
+}
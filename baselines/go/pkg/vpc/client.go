@@ -0,0 +1,114 @@
+package vpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	"lambdaperf/internal/awsapi"
+)
+
+// apiVersion is the EC2 API version requests are made against.
+const apiVersion = "2016-11-15"
+
+// Client makes the EC2 Query API calls that provision and tear down a
+// network, and no others.
+type Client struct {
+	Config aws.Config
+	// Endpoint overrides https://ec2.<region>.amazonaws.com.
+	Endpoint string
+	HTTP     *http.Client
+}
+
+// APIError is an error response from EC2, such as "DependencyViolation".
+type APIError = awsapi.Error
+
+func isCode(err error, codes ...string) bool {
+	var e *APIError
+	return errors.As(err, &e) && slices.Contains(codes, e.Code)
+}
+
+// params are a request's parameters, in the Query API's flattened form.
+type params map[string]string
+
+// filter adds the filter name=values as the next Filter.N.
+func (p params) filter(name string, values ...string) params {
+	n := 1
+	for p["Filter."+strconv.Itoa(n)+".Name"] != "" {
+		n++
+	}
+	prefix := "Filter." + strconv.Itoa(n)
+	p[prefix+".Name"] = name
+	for i, v := range values {
+		p[prefix+".Value."+strconv.Itoa(i+1)] = v
+	}
+	return p
+}
+
+// named tags the created resource of type kind with Name and TagKey.
+func (p params) named(kind, name string) params {
+	p["TagSpecification.1.ResourceType"] = kind
+	p["TagSpecification.1.Tag.1.Key"] = "Name"
+	p["TagSpecification.1.Tag.1.Value"] = name
+	p["TagSpecification.1.Tag.2.Key"] = TagKey
+	p["TagSpecification.1.Tag.2.Value"] = "true"
+	return p
+}
+
+// call calls action with the parameters p and decodes the XML response
+// into out, which may be nil.
+func (c *Client) call(ctx context.Context, action string, p params, out any) error {
+	api := &awsapi.Client{Config: c.Config, Service: "ec2", Endpoint: c.Endpoint, HTTP: c.HTTP}
+	return api.Query(ctx, apiVersion, action, p, out)
+}
+
+// ids calls a Describe action and returns the IDs its response lists,
+// each the element of an item the path, such as "vpcSet>item>vpcId",
+// names below the response element.
+func (c *Client) ids(ctx context.Context, action, path string, p params) ([]string, error) {
+	var raw struct {
+		Inner []byte `xml:",innerxml"`
+	}
+	if err := c.call(ctx, action, p, &raw); err != nil {
+		return nil, err
+	}
+	// The path differs by action, and struct tags are fixed at compile
+	// time, so walk the tokens instead.
+	steps := strings.Split(path, ">")
+	dec := xml.NewDecoder(bytes.NewReader(raw.Inner))
+	var (
+		ids   []string
+		stack []string
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return ids, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if stack = append(stack, t.Name.Local); !slices.Equal(stack, steps) {
+				continue
+			}
+			var id string
+			if err := dec.DecodeElement(&id, &t); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+			stack = stack[:len(stack)-1]
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
@@ -0,0 +1,390 @@
+// Package vpc provisions the network VPC-attached baselines are deployed
+// into, so their cold starts and invocations can be compared with the
+// same functions outside a VPC. Lambda reaches a function's subnets
+// through Hyperplane network interfaces it creates when the function is
+// created or its VPC configuration changes, not on every cold start, but
+// teams still report VPC attachment as where their cold-start pain lives;
+// ruchy-bench -vpc measures how much there is.
+//
+// The network is a VPC with a private subnet in each of two availability
+// zones and a security group allowing no inbound and all outbound
+// traffic. Private subnets have no route out, which suits the CPU
+// workloads; functions calling AWS APIs or the internet need the optional
+// NAT gateway, in a public subnet behind an internet gateway, which
+// AWS bills by the hour while it exists. Every resource is tagged
+// TagKey and named after the network, which is how Ensure finds what an
+// earlier call created and Delete what to remove.
+package vpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// TagKey marks resources created by the harness, as deploy.TagKey
+	// does functions.
+	TagKey = "ruchy-bench"
+	// DefaultName names the network ruchy-bench vpc creates.
+	DefaultName = "ruchy-bench"
+	// Subnets is how many private subnets Ensure creates, each in its own
+	// availability zone, as Lambda recommends for availability.
+	Subnets = 2
+)
+
+// cidr is the VPC's address range; private subnet i is 10.42.i+1.0/24
+// and the public one 10.42.100.0/24.
+const (
+	cidr       = "10.42.0.0/16"
+	publicCIDR = "10.42.100.0/24"
+)
+
+// ErrNotFound is returned by Find when the region has no network of the
+// name.
+var ErrNotFound = errors.New("no harness VPC")
+
+// Variables so tests need not wait.
+var (
+	pollInterval = 5 * time.Second
+	natTimeout   = 10 * time.Minute
+)
+
+// Network is the harness VPC, in the terms of a function's VPC
+// configuration.
+type Network struct {
+	Name  string
+	VPCID string
+	// SubnetIDs are the private subnets, one per availability zone.
+	SubnetIDs       []string
+	SecurityGroupID string
+	// NATGatewayID is set when the private subnets route to the internet
+	// through a NAT gateway.
+	NATGatewayID string
+}
+
+// Ensure returns the network of the given name, creating whatever part of
+// it does not exist yet, and a NAT gateway when nat is set. Ensure never
+// removes a NAT gateway; Delete does.
+func (c *Client) Ensure(ctx context.Context, name string, nat bool) (Network, error) {
+	n := Network{Name: name}
+	var err error
+	n.VPCID, err = c.ensure(ctx, "DescribeVpcs", "vpcSet>item>vpcId", name, nil, func() (string, error) {
+		return c.create(ctx, "CreateVpc", "vpc>vpcId", params{"CidrBlock": cidr}.named("vpc", name))
+	})
+	if err != nil {
+		return n, fmt.Errorf("create VPC %s: %w", name, err)
+	}
+	zones, err := c.ids(ctx, "DescribeAvailabilityZones", "availabilityZoneInfo>item>zoneName",
+		params{}.filter("state", "available").filter("zone-type", "availability-zone"))
+	if err != nil {
+		return n, fmt.Errorf("list availability zones: %w", err)
+	}
+	if len(zones) < Subnets {
+		return n, fmt.Errorf("the region has %d availability zones; want %d", len(zones), Subnets)
+	}
+	for i := range Subnets {
+		subnet, err := c.ensureSubnet(ctx, n.VPCID, fmt.Sprintf("%s-private-%d", name, i+1), fmt.Sprintf("10.42.%d.0/24", i+1), zones[i])
+		if err != nil {
+			return n, err
+		}
+		n.SubnetIDs = append(n.SubnetIDs, subnet)
+	}
+	byGroup := params{}.filter("vpc-id", n.VPCID).filter("group-name", name)
+	n.SecurityGroupID, err = c.ensure(ctx, "DescribeSecurityGroups", "securityGroupInfo>item>groupId", "", byGroup, func() (string, error) {
+		// A new group allows all outbound traffic and no inbound.
+		return c.create(ctx, "CreateSecurityGroup", "groupId", params{
+			"GroupName":        name,
+			"GroupDescription": "ruchy-bench VPC-attached baselines: no inbound, all outbound",
+			"VpcId":            n.VPCID,
+		}.named("security-group", name))
+	})
+	if err != nil {
+		return n, fmt.Errorf("create security group %s: %w", name, err)
+	}
+	if nat {
+		if n.NATGatewayID, err = c.ensureNAT(ctx, n, zones[0]); err != nil {
+			return n, err
+		}
+	} else if n.NATGatewayID, err = c.natGateway(ctx, name); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// ensureNAT gives n's private subnets a route to the internet through a
+// NAT gateway in a public subnet in zone.
+func (c *Client) ensureNAT(ctx context.Context, n Network, zone string) (string, error) {
+	public, err := c.ensureSubnet(ctx, n.VPCID, n.Name+"-public", publicCIDR, zone)
+	if err != nil {
+		return "", err
+	}
+	igw, err := c.ensure(ctx, "DescribeInternetGateways", "internetGatewaySet>item>internetGatewayId", n.Name+"-igw", nil, func() (string, error) {
+		return c.create(ctx, "CreateInternetGateway", "internetGateway>internetGatewayId", params{}.named("internet-gateway", n.Name+"-igw"))
+	})
+	if err != nil {
+		return "", fmt.Errorf("create internet gateway: %w", err)
+	}
+	err = c.call(ctx, "AttachInternetGateway", params{"InternetGatewayId": igw, "VpcId": n.VPCID}, nil)
+	if err != nil && !isCode(err, "Resource.AlreadyAssociated") {
+		return "", fmt.Errorf("attach internet gateway %s: %w", igw, err)
+	}
+	if err := c.ensureRoutes(ctx, n, n.Name+"-public", params{"GatewayId": igw}, public); err != nil {
+		return "", err
+	}
+
+	eip, err := c.ensure(ctx, "DescribeAddresses", "addressesSet>item>allocationId", n.Name+"-nat", nil, func() (string, error) {
+		return c.create(ctx, "AllocateAddress", "allocationId", params{"Domain": "vpc"}.named("elastic-ip", n.Name+"-nat"))
+	})
+	if err != nil {
+		return "", fmt.Errorf("allocate elastic IP: %w", err)
+	}
+	gw, err := c.natGateway(ctx, n.Name)
+	if err != nil {
+		return "", err
+	}
+	if gw == "" {
+		gw, err = c.create(ctx, "CreateNatGateway", "natGateway>natGatewayId",
+			params{"SubnetId": public, "AllocationId": eip}.named("natgateway", n.Name+"-nat"))
+		if err != nil {
+			return "", fmt.Errorf("create NAT gateway: %w", err)
+		}
+	}
+	if err := c.waitNAT(ctx, gw, "available"); err != nil {
+		return "", err
+	}
+	return gw, c.ensureRoutes(ctx, n, n.Name+"-private", params{"NatGatewayId": gw}, n.SubnetIDs...)
+}
+
+// natGateway returns the ID of the network's pending or available NAT
+// gateway, "" if it has none.
+func (c *Client) natGateway(ctx context.Context, name string) (string, error) {
+	ids, err := c.ids(ctx, "DescribeNatGateways", "natGatewaySet>item>natGatewayId",
+		params{}.filter("tag:Name", name+"-nat").filter("state", "pending", "available"))
+	if err != nil || len(ids) == 0 {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// waitNAT waits for NAT gateway id to reach state, failing if it fails.
+func (c *Client) waitNAT(ctx context.Context, id, state string) error {
+	deadline := time.Now().Add(natTimeout)
+	for {
+		for _, s := range []string{state, "failed"} {
+			ids, err := c.ids(ctx, "DescribeNatGateways", "natGatewaySet>item>natGatewayId",
+				params{}.filter("nat-gateway-id", id).filter("state", s))
+			if err != nil {
+				return fmt.Errorf("describe NAT gateway %s: %w", id, err)
+			}
+			switch {
+			case len(ids) > 0 && s == "failed":
+				return fmt.Errorf("NAT gateway %s failed", id)
+			case len(ids) > 0:
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("NAT gateway %s not %s after %s", id, state, natTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// ensureRoutes gives the route table of the given name a default route
+// to target and associates it with subnets.
+func (c *Client) ensureRoutes(ctx context.Context, n Network, name string, target params, subnets ...string) error {
+	table, err := c.ensure(ctx, "DescribeRouteTables", "routeTableSet>item>routeTableId", name, nil, func() (string, error) {
+		return c.create(ctx, "CreateRouteTable", "routeTable>routeTableId", params{"VpcId": n.VPCID}.named("route-table", name))
+	})
+	if err != nil {
+		return fmt.Errorf("create route table %s: %w", name, err)
+	}
+	route := params{"RouteTableId": table, "DestinationCidrBlock": "0.0.0.0/0"}
+	for k, v := range target {
+		route[k] = v
+	}
+	if err := c.call(ctx, "CreateRoute", route, nil); err != nil && !isCode(err, "RouteAlreadyExists") {
+		return fmt.Errorf("add default route to %s: %w", name, err)
+	}
+	for _, s := range subnets {
+		err := c.call(ctx, "AssociateRouteTable", params{"RouteTableId": table, "SubnetId": s}, nil)
+		if err != nil && !isCode(err, "Resource.AlreadyAssociated") {
+			return fmt.Errorf("associate %s with %s: %w", name, s, err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) ensureSubnet(ctx context.Context, vpcID, name, block, zone string) (string, error) {
+	id, err := c.ensure(ctx, "DescribeSubnets", "subnetSet>item>subnetId", name, nil, func() (string, error) {
+		return c.create(ctx, "CreateSubnet", "subnet>subnetId",
+			params{"VpcId": vpcID, "CidrBlock": block, "AvailabilityZone": zone}.named("subnet", name))
+	})
+	if err != nil {
+		return "", fmt.Errorf("create subnet %s: %w", name, err)
+	}
+	return id, nil
+}
+
+// ensure returns the first ID a Describe action lists for the resource
+// named name, or matching by when name is empty, calling create when
+// there is none.
+func (c *Client) ensure(ctx context.Context, action, path, name string, by params, create func() (string, error)) (string, error) {
+	if by == nil {
+		by = params{}.filter("tag:Name", name).filter("tag-key", TagKey)
+	}
+	ids, err := c.ids(ctx, action, path, by)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) > 0 {
+		return ids[0], nil
+	}
+	return create()
+}
+
+// create calls a Create action and returns the ID at path in its
+// response.
+func (c *Client) create(ctx context.Context, action, path string, p params) (string, error) {
+	ids, err := c.ids(ctx, action, path, p)
+	if err != nil {
+		return "", err
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("%s response has no %s", action, path)
+	}
+	return ids[0], nil
+}
+
+// Find returns the network of the given name as Ensure last left it, or
+// ErrNotFound.
+func (c *Client) Find(ctx context.Context, name string) (Network, error) {
+	n := Network{Name: name}
+	vpcs, err := c.ids(ctx, "DescribeVpcs", "vpcSet>item>vpcId", params{}.filter("tag:Name", name).filter("tag-key", TagKey))
+	if err != nil {
+		return n, fmt.Errorf("find VPC %s: %w", name, err)
+	}
+	if len(vpcs) == 0 {
+		return n, fmt.Errorf("%w %s in %s; create it with ruchy-bench vpc", ErrNotFound, name, c.Config.Region)
+	}
+	n.VPCID = vpcs[0]
+	for i := range Subnets {
+		subnet, err := c.ids(ctx, "DescribeSubnets", "subnetSet>item>subnetId",
+			params{}.filter("tag:Name", fmt.Sprintf("%s-private-%d", name, i+1)).filter("vpc-id", n.VPCID))
+		if err != nil {
+			return n, fmt.Errorf("find subnets of %s: %w", name, err)
+		}
+		if len(subnet) == 0 {
+			return n, fmt.Errorf("VPC %s is missing subnet %d; rerun ruchy-bench vpc", name, i+1)
+		}
+		n.SubnetIDs = append(n.SubnetIDs, subnet[0])
+	}
+	groups, err := c.ids(ctx, "DescribeSecurityGroups", "securityGroupInfo>item>groupId",
+		params{}.filter("vpc-id", n.VPCID).filter("group-name", name))
+	if err != nil {
+		return n, fmt.Errorf("find security group of %s: %w", name, err)
+	}
+	if len(groups) == 0 {
+		return n, fmt.Errorf("VPC %s is missing its security group; rerun ruchy-bench vpc", name)
+	}
+	n.SecurityGroupID = groups[0]
+	n.NATGatewayID, err = c.natGateway(ctx, name)
+	return n, err
+}
+
+// Delete removes the network of the given name, reporting whether there
+// was one. Functions still attached to it must be deleted first, and
+// Lambda releases their network interfaces some minutes later; until then
+// the subnets and security group cannot be deleted, and Delete fails
+// saying so. Rerunning it finishes the job.
+func (c *Client) Delete(ctx context.Context, name string) (bool, error) {
+	vpcs, err := c.ids(ctx, "DescribeVpcs", "vpcSet>item>vpcId", params{}.filter("tag:Name", name).filter("tag-key", TagKey))
+	if err != nil || len(vpcs) == 0 {
+		return false, err
+	}
+	vpcID := vpcs[0]
+	byVPC := func() params { return params{}.filter("vpc-id", vpcID) }
+
+	// The NAT gateway holds the elastic IP and routes through the
+	// internet gateway, so it goes first; deleting it takes a minute or so.
+	gws, err := c.ids(ctx, "DescribeNatGateways", "natGatewaySet>item>natGatewayId", byVPC().filter("state", "pending", "available"))
+	if err != nil {
+		return false, fmt.Errorf("find NAT gateways of %s: %w", name, err)
+	}
+	for _, gw := range gws {
+		if err := c.call(ctx, "DeleteNatGateway", params{"NatGatewayId": gw}, nil); err != nil {
+			return false, fmt.Errorf("delete NAT gateway %s: %w", gw, err)
+		}
+		if err := c.waitNAT(ctx, gw, "deleted"); err != nil {
+			return false, err
+		}
+	}
+	eips, err := c.ids(ctx, "DescribeAddresses", "addressesSet>item>allocationId", params{}.filter("tag:Name", name+"-nat"))
+	if err != nil {
+		return false, fmt.Errorf("find elastic IPs of %s: %w", name, err)
+	}
+	for _, eip := range eips {
+		if err := c.call(ctx, "ReleaseAddress", params{"AllocationId": eip}, nil); err != nil {
+			return false, fmt.Errorf("release elastic IP %s: %w", eip, err)
+		}
+	}
+	igws, err := c.ids(ctx, "DescribeInternetGateways", "internetGatewaySet>item>internetGatewayId", params{}.filter("attachment.vpc-id", vpcID))
+	if err != nil {
+		return false, fmt.Errorf("find internet gateways of %s: %w", name, err)
+	}
+	for _, igw := range igws {
+		if err := c.call(ctx, "DetachInternetGateway", params{"InternetGatewayId": igw, "VpcId": vpcID}, nil); err != nil {
+			return false, fmt.Errorf("detach internet gateway %s: %w", igw, err)
+		}
+		if err := c.call(ctx, "DeleteInternetGateway", params{"InternetGatewayId": igw}, nil); err != nil {
+			return false, fmt.Errorf("delete internet gateway %s: %w", igw, err)
+		}
+	}
+
+	subnets, err := c.ids(ctx, "DescribeSubnets", "subnetSet>item>subnetId", byVPC())
+	if err != nil {
+		return false, fmt.Errorf("find subnets of %s: %w", name, err)
+	}
+	for _, s := range subnets {
+		if err := c.call(ctx, "DeleteSubnet", params{"SubnetId": s}, nil); err != nil {
+			return false, inUse(fmt.Errorf("delete subnet %s: %w", s, err))
+		}
+	}
+	tables, err := c.ids(ctx, "DescribeRouteTables", "routeTableSet>item>routeTableId", byVPC().filter("tag-key", TagKey))
+	if err != nil {
+		return false, fmt.Errorf("find route tables of %s: %w", name, err)
+	}
+	for _, t := range tables {
+		if err := c.call(ctx, "DeleteRouteTable", params{"RouteTableId": t}, nil); err != nil {
+			return false, fmt.Errorf("delete route table %s: %w", t, err)
+		}
+	}
+	groups, err := c.ids(ctx, "DescribeSecurityGroups", "securityGroupInfo>item>groupId", byVPC().filter("group-name", name))
+	if err != nil {
+		return false, fmt.Errorf("find security group of %s: %w", name, err)
+	}
+	for _, g := range groups {
+		if err := c.call(ctx, "DeleteSecurityGroup", params{"GroupId": g}, nil); err != nil {
+			return false, inUse(fmt.Errorf("delete security group %s: %w", g, err))
+		}
+	}
+	if err := c.call(ctx, "DeleteVpc", params{"VpcId": vpcID}, nil); err != nil {
+		return false, fmt.Errorf("delete VPC %s: %w", vpcID, err)
+	}
+	return true, nil
+}
+
+// inUse explains a DependencyViolation: the network interfaces of
+// functions attached to the VPC.
+func inUse(err error) error {
+	if !isCode(err, "DependencyViolation") {
+		return err
+	}
+	return fmt.Errorf("%w (Lambda network interfaces still use it: delete the -vpc functions with ruchy-bench teardown -vpc, wait for Lambda to release their interfaces, up to about 20 minutes, and retry)", err)
+}
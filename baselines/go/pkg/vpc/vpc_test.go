@@ -0,0 +1,276 @@
+package vpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// resource is one object of fakeEC2, with the attributes its filters
+// match on by filter name.
+type resource struct {
+	kind, id string
+	attrs    map[string]string
+}
+
+// fakeEC2 answers the Query API calls Client makes from memory. NAT
+// gateways become available, and deleted, on the next describe.
+type fakeEC2 struct {
+	t         *testing.T
+	resources []*resource
+	// inUse fails the first DeleteSubnet with DependencyViolation, as
+	// while Lambda holds network interfaces in the subnet.
+	inUse bool
+}
+
+// describes maps Describe actions to the kind they list and their
+// response's set and ID elements.
+var describes = map[string][3]string{
+	"DescribeVpcs":             {"vpc", "vpcSet", "vpcId"},
+	"DescribeSubnets":          {"subnet", "subnetSet", "subnetId"},
+	"DescribeSecurityGroups":   {"security-group", "securityGroupInfo", "groupId"},
+	"DescribeInternetGateways": {"internet-gateway", "internetGatewaySet", "internetGatewayId"},
+	"DescribeRouteTables":      {"route-table", "routeTableSet", "routeTableId"},
+	"DescribeAddresses":        {"elastic-ip", "addressesSet", "allocationId"},
+	"DescribeNatGateways":      {"natgateway", "natGatewaySet", "natGatewayId"},
+}
+
+// creates maps Create actions to the kind they create and the response
+// elements around its ID.
+var creates = map[string][2]string{
+	"CreateVpc":             {"vpc", "<vpc><vpcId>%s</vpcId></vpc>"},
+	"CreateSubnet":          {"subnet", "<subnet><subnetId>%s</subnetId></subnet>"},
+	"CreateSecurityGroup":   {"security-group", "<return>true</return><groupId>%s</groupId>"},
+	"CreateInternetGateway": {"internet-gateway", "<internetGateway><internetGatewayId>%s</internetGatewayId></internetGateway>"},
+	"CreateRouteTable":      {"route-table", "<routeTable><routeTableId>%s</routeTableId></routeTable>"},
+	"AllocateAddress":       {"elastic-ip", "<publicIp>203.0.113.7</publicIp><allocationId>%s</allocationId>"},
+	"CreateNatGateway":      {"natgateway", "<natGateway><natGatewayId>%s</natGatewayId><state>pending</state></natGateway>"},
+}
+
+// deletes maps Delete actions to the parameter naming what they delete.
+var deletes = map[string]string{
+	"DeleteVpc":             "VpcId",
+	"DeleteSubnet":          "SubnetId",
+	"DeleteSecurityGroup":   "GroupId",
+	"DeleteInternetGateway": "InternetGatewayId",
+	"DeleteRouteTable":      "RouteTableId",
+	"ReleaseAddress":        "AllocationId",
+}
+
+func (f *fakeEC2) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Form.Get("Version") != apiVersion {
+		f.t.Errorf("bad request %v: %v", r.Form, err)
+	}
+	action := r.Form.Get("Action")
+	fail := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `<Response><Errors><Error><Code>%s</Code><Message>%s failed</Message></Error></Errors><RequestID>1</RequestID></Response>`, code, action)
+	}
+	reply := func(inner string) {
+		fmt.Fprintf(w, `<%sResponse xmlns="http://ec2.amazonaws.com/doc/%s/"><requestId>1</requestId>%s</%sResponse>`, action, apiVersion, inner, action)
+	}
+	switch {
+	case action == "DescribeAvailabilityZones":
+		reply(`<availabilityZoneInfo><item><zoneName>eu-west-1a</zoneName></item><item><zoneName>eu-west-1b</zoneName></item></availabilityZoneInfo>`)
+	case describes[action] != [3]string{}:
+		d := describes[action]
+		if action == "DescribeNatGateways" {
+			// Time passes between describes.
+			for _, res := range f.resources {
+				switch res.attrs["state"] {
+				case "pending":
+					res.attrs["state"] = "available"
+				case "deleting":
+					res.attrs["state"] = "deleted"
+				}
+			}
+		}
+		var items strings.Builder
+		for _, res := range f.matching(d[0], r) {
+			fmt.Fprintf(&items, "<item><%s>%s</%[1]s><tagSet><item><key>Name</key><value>%s</value></item></tagSet></item>", d[2], res.id, res.attrs["tag:Name"])
+		}
+		reply("<" + d[1] + ">" + items.String() + "</" + d[1] + ">")
+	case creates[action] != [2]string{}:
+		c := creates[action]
+		res := &resource{kind: c[0], id: fmt.Sprintf("%s-%d", c[0], len(f.resources)+1), attrs: map[string]string{
+			"vpc-id":     r.Form.Get("VpcId"),
+			"group-name": r.Form.Get("GroupName"),
+			"state":      "available",
+		}}
+		if r.Form.Get("TagSpecification.1.ResourceType") != c[0] {
+			f.t.Errorf("%s tags a %q", action, r.Form.Get("TagSpecification.1.ResourceType"))
+		}
+		for i := 1; r.Form.Get("TagSpecification.1.Tag."+strconv.Itoa(i)+".Key") != ""; i++ {
+			key := r.Form.Get("TagSpecification.1.Tag." + strconv.Itoa(i) + ".Key")
+			res.attrs["tag:"+key] = r.Form.Get("TagSpecification.1.Tag." + strconv.Itoa(i) + ".Value")
+		}
+		if res.kind == "natgateway" {
+			res.attrs["state"] = "pending"
+			res.attrs["vpc-id"] = f.find(r.Form.Get("SubnetId")).attrs["vpc-id"]
+		}
+		res.attrs["nat-gateway-id"] = res.id
+		f.resources = append(f.resources, res)
+		reply(fmt.Sprintf(c[1], res.id))
+	case action == "AttachInternetGateway":
+		igw := f.find(r.Form.Get("InternetGatewayId"))
+		if igw.attrs["attachment.vpc-id"] != "" {
+			fail("Resource.AlreadyAssociated")
+			return
+		}
+		igw.attrs["attachment.vpc-id"] = r.Form.Get("VpcId")
+		reply("<return>true</return>")
+	case action == "DetachInternetGateway":
+		f.find(r.Form.Get("InternetGatewayId")).attrs["attachment.vpc-id"] = ""
+		reply("<return>true</return>")
+	case action == "CreateRoute":
+		table := f.find(r.Form.Get("RouteTableId"))
+		if table.attrs["route"] != "" {
+			fail("RouteAlreadyExists")
+			return
+		}
+		table.attrs["route"] = r.Form.Get("GatewayId") + r.Form.Get("NatGatewayId")
+		reply("<return>true</return>")
+	case action == "AssociateRouteTable":
+		subnet := f.find(r.Form.Get("SubnetId"))
+		if subnet.attrs["route-table"] == r.Form.Get("RouteTableId") {
+			fail("Resource.AlreadyAssociated")
+			return
+		}
+		subnet.attrs["route-table"] = r.Form.Get("RouteTableId")
+		reply("<associationId>rtbassoc-1</associationId>")
+	case action == "DeleteNatGateway":
+		f.find(r.Form.Get("NatGatewayId")).attrs["state"] = "deleting"
+		reply("<natGatewayId>" + r.Form.Get("NatGatewayId") + "</natGatewayId>")
+	case deletes[action] != "":
+		if action == "DeleteSubnet" && f.inUse {
+			f.inUse = false
+			fail("DependencyViolation")
+			return
+		}
+		id := r.Form.Get(deletes[action])
+		f.resources = slices.DeleteFunc(f.resources, func(res *resource) bool { return res.id == id })
+		reply("<return>true</return>")
+	default:
+		f.t.Errorf("unexpected action %s", action)
+		fail("InvalidAction")
+	}
+}
+
+func (f *fakeEC2) find(id string) *resource {
+	for _, res := range f.resources {
+		if res.id == id {
+			return res
+		}
+	}
+	f.t.Fatalf("no resource %s", id)
+	return nil
+}
+
+// matching returns the resources of kind every Filter.N of r matches.
+func (f *fakeEC2) matching(kind string, r *http.Request) []*resource {
+	var out []*resource
+	for _, res := range f.resources {
+		ok := res.kind == kind
+		for n := 1; ok && r.Form.Get("Filter."+strconv.Itoa(n)+".Name") != ""; n++ {
+			prefix := "Filter." + strconv.Itoa(n)
+			var values []string
+			for i := 1; r.Form.Get(prefix+".Value."+strconv.Itoa(i)) != ""; i++ {
+				values = append(values, r.Form.Get(prefix+".Value."+strconv.Itoa(i)))
+			}
+			if name := r.Form.Get(prefix + ".Name"); name == "tag-key" {
+				ok = slices.ContainsFunc(values, func(key string) bool { _, tagged := res.attrs["tag:"+key]; return tagged })
+			} else {
+				ok = slices.Contains(values, res.attrs[name])
+			}
+		}
+		if ok {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+func newFake(t *testing.T) (*fakeEC2, *Client) {
+	pollInterval, natTimeout = time.Millisecond, time.Second
+	fake := &fakeEC2{t: t}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, &Client{Config: aws.Config{Region: "eu-west-1"}, Endpoint: srv.URL}
+}
+
+func TestEnsureIsIdempotent(t *testing.T) {
+	fake, c := newFake(t)
+	ctx := context.Background()
+	n, err := c.Ensure(ctx, "bench", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.VPCID == "" || len(n.SubnetIDs) != Subnets || n.SecurityGroupID == "" || n.NATGatewayID != "" {
+		t.Fatalf("network = %+v", n)
+	}
+	created := len(fake.resources)
+	if created != 1+Subnets+1 {
+		t.Errorf("created %d resources, want a VPC, %d subnets and a security group", created, Subnets)
+	}
+
+	// Adding the NAT gateway keeps what exists and routes the private
+	// subnets through it.
+	withNAT, err := c.Ensure(ctx, "bench", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withNAT.VPCID != n.VPCID || !slices.Equal(withNAT.SubnetIDs, n.SubnetIDs) || withNAT.NATGatewayID == "" {
+		t.Fatalf("network with NAT = %+v, want %+v plus a gateway", withNAT, n)
+	}
+	for _, s := range n.SubnetIDs {
+		table := fake.find(fake.find(s).attrs["route-table"])
+		if table.attrs["route"] != withNAT.NATGatewayID {
+			t.Errorf("subnet %s routes through %q, want the NAT gateway", s, table.attrs["route"])
+		}
+	}
+	before := len(fake.resources)
+	again, err := c.Ensure(ctx, "bench", true)
+	if err != nil || len(fake.resources) != before || again.NATGatewayID != withNAT.NATGatewayID {
+		t.Errorf("second Ensure created %d resources, %v", len(fake.resources)-before, err)
+	}
+	found, err := c.Find(ctx, "bench")
+	if err != nil || fmt.Sprint(found) != fmt.Sprint(withNAT) {
+		t.Errorf("Find = %+v, %v; want %+v", found, err, withNAT)
+	}
+	if _, err := c.Find(ctx, "other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Find of a missing network = %v", err)
+	}
+}
+
+func TestDeleteRemovesEverything(t *testing.T) {
+	fake, c := newFake(t)
+	ctx := context.Background()
+	if _, err := c.Ensure(ctx, "bench", true); err != nil {
+		t.Fatal(err)
+	}
+	fake.inUse = true
+	if _, err := c.Delete(ctx, "bench"); !isCode(err, "DependencyViolation") || !strings.Contains(err.Error(), "teardown -vpc") {
+		t.Fatalf("Delete while in use = %v, want the interfaces explained", err)
+	}
+	deleted, err := c.Delete(ctx, "bench")
+	if err != nil || !deleted {
+		t.Fatalf("Delete = %v, %v", deleted, err)
+	}
+	for _, res := range fake.resources {
+		if res.kind != "natgateway" || res.attrs["state"] != "deleted" {
+			t.Errorf("left %s %s behind", res.kind, res.id)
+		}
+	}
+	if deleted, err := c.Delete(ctx, "bench"); deleted || err != nil {
+		t.Errorf("second Delete = %v, %v; want nothing to delete", deleted, err)
+	}
+}
@@ -0,0 +1,108 @@
+// Command probe is the synthetic client ruchy-bench edge deploys in each
+// probe region. Each invocation requests a Lambda@Edge function through
+// CloudFront and invokes its regional twin, alternately so both see the
+// same network, and reports every round trip as timed from the probe's
+// region; see pkg/edge.
+//
+// It is packaged like a Go baseline, as a bootstrap binary on
+// provided.al2023; see build.BuildProbe.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	lambdasvc "github.com/aws/aws-sdk-go-v2/service/lambda"
+
+	"lambdaperf/pkg/edge"
+)
+
+// The default transport keeps the connection to CloudFront, and the SDK
+// its connection to Lambda, open between requests.
+var client = &http.Client{Timeout: 30 * time.Second}
+
+func ms(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+
+func get(ctx context.Context, url string, res *edge.ProbeResult) edge.Timing {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return edge.Timing{Error: err.Error()}
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return edge.Timing{MS: ms(time.Since(start)), Error: err.Error()}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	t := edge.Timing{MS: ms(time.Since(start))}
+	switch {
+	case err != nil:
+		t.Error = err.Error()
+	case resp.StatusCode != http.StatusOK:
+		t.Error = fmt.Sprintf("GET %s: %s %q", url, resp.Status, body)
+	default:
+		res.EdgeBody = string(body)
+	}
+	if pop := resp.Header.Get("X-Amz-Cf-Pop"); pop != "" {
+		res.POP = pop
+	}
+	return t
+}
+
+func invoke(ctx context.Context, fns *lambdasvc.Client, ev edge.ProbeEvent, res *edge.ProbeResult) edge.Timing {
+	start := time.Now()
+	out, err := fns.Invoke(ctx, &lambdasvc.InvokeInput{FunctionName: aws.String(ev.Function), Payload: ev.Payload})
+	t := edge.Timing{MS: ms(time.Since(start))}
+	if err != nil {
+		t.Error = err.Error()
+		return t
+	}
+	// The Python baselines answer {"statusCode": ..., "body": ...}.
+	var resp struct {
+		StatusCode int    `json:"statusCode"`
+		Body       string `json:"body"`
+	}
+	switch {
+	case out.FunctionError != nil:
+		t.Error = fmt.Sprintf("%s: %s", aws.ToString(out.FunctionError), out.Payload)
+	case json.Unmarshal(out.Payload, &resp) != nil || resp.StatusCode != http.StatusOK:
+		t.Error = fmt.Sprintf("%s answered %s", ev.Function, out.Payload)
+	default:
+		res.RegionalBody = resp.Body
+	}
+	return t
+}
+
+func handle(ctx context.Context, ev edge.ProbeEvent) (edge.ProbeResult, error) {
+	if ev.URL == "" || ev.Function == "" || ev.N < 1 {
+		return edge.ProbeResult{}, errors.New("want url, function and n")
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(ev.FunctionRegion))
+	if err != nil {
+		return edge.ProbeResult{}, err
+	}
+	// A retry would add its attempts to the round trip.
+	fns := lambdasvc.NewFromConfig(cfg, func(o *lambdasvc.Options) { o.RetryMaxAttempts = 1 })
+	var res edge.ProbeResult
+	for range ev.N + 1 {
+		res.Edge = append(res.Edge, get(ctx, ev.URL, &res))
+		res.Regional = append(res.Regional, invoke(ctx, fns, ev, &res))
+		if ctx.Err() != nil {
+			return res, ctx.Err()
+		}
+	}
+	return res, nil
+}
+
+func main() {
+	lambda.Start(handle)
+}
@@ -0,0 +1,52 @@
+//go:build linux
+
+// Command timer runs a workload once inside a sandbox and prints how it
+// went as sandbox.Report JSON. Timing the run where it happens keeps the
+// cost of reaching the sandbox (docker exec, and a VM exit under
+// Firecracker) out of its wall time; see pkg/sandbox.
+//
+// Usage:
+//
+//	timer command [args...]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"lambdaperf/pkg/sandbox"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: timer command [args...]")
+		os.Exit(2)
+	}
+	cmd := exec.Command(os.Args[1], os.Args[2:]...)
+	// The workload reads the timer's stdin directly, as it would the
+	// harness's pipe when run on the host.
+	cmd.Stdin = os.Stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	r := sandbox.Report{WallNS: time.Since(start).Nanoseconds(), Output: stdout.String()}
+	if ps := cmd.ProcessState; ps != nil {
+		r.UserNS, r.SystemNS = ps.UserTime().Nanoseconds(), ps.SystemTime().Nanoseconds()
+		if ru, ok := ps.SysUsage().(*syscall.Rusage); ok {
+			r.MaxRSSKB = ru.Maxrss
+		}
+	}
+	if err != nil {
+		r.Error = fmt.Sprintf("%s: %v: %s", os.Args[1], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(r); err != nil {
+		os.Exit(1)
+	}
+}
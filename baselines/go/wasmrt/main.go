@@ -0,0 +1,52 @@
+// Command wasmrt is the bootstrap of Lambda wasm targets: a custom runtime
+// on provided.al2023 that runs a workload compiled to a WASI module under
+// Wasmtime. The package holds it, a pinned wasmtime binary and the module,
+// which is precompiled for the function's architecture so that no
+// invocation pays for Cranelift. Each invocation runs the module once, as
+// wasmtime run does a WASI command, and answers with what it printed,
+// through internal/handler like any Go baseline; see pkg/build's wasm.go.
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"lambdaperf/internal/handler"
+	"lambdaperf/pkg/discover"
+)
+
+// Set by pkg/build with -X.
+var (
+	workload        = "wasm"
+	wasmtimeVersion = ""
+)
+
+var root = cmp.Or(os.Getenv("LAMBDA_TASK_ROOT"), "/var/task")
+
+func run(ctx context.Context, _ handler.NoEvent) (string, error) {
+	// The module is precompiled, so there is nothing to cache; the
+	// package is read-only in any case.
+	cmd := exec.CommandContext(ctx, filepath.Join(root, "wasmtime"), "run", "-C", "cache=n", "--allow-precompiled",
+		filepath.Join(root, "module.cwasm"))
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("wasmtime: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func main() {
+	handler.Start(handler.Workload[handler.NoEvent]{
+		Name:    workload,
+		Params:  handler.Params{"wasmtime": wasmtimeVersion},
+		Runtime: discover.Wasm,
+		Run:     run,
+	})
+}
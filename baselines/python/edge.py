@@ -0,0 +1,27 @@
+#!/usr/bin/env python3
+# Lambda@Edge adapter for the Python baselines - Python 3.12
+# Runs a baseline's index.handler, packaged beside it, as a CloudFront
+# viewer-request trigger: the query string is the workload's input and
+# its result the response CloudFront returns, so no request reaches the
+# origin. See baselines/go/pkg/edge.
+from urllib.parse import parse_qsl
+
+import index
+
+def handler(event, context):
+    request = event['Records'][0]['cf']['request']
+    payload = {}
+    for name, value in parse_qsl(request.get('querystring', '')):
+        try:
+            payload[name] = int(value)
+        except ValueError:
+            payload[name] = value
+    result = index.handler(payload, context)
+    return {
+        'status': str(result.get('statusCode', 200)),
+        'headers': {
+            'content-type': [{'key': 'Content-Type', 'value': 'text/plain'}],
+            'cache-control': [{'key': 'Cache-Control', 'value': 'no-store'}],
+        },
+        'body': result.get('body', ''),
+    }
@@ -0,0 +1,19 @@
+#!/usr/bin/env python3
+# Payload round-trip Lambda handler - Python 3.12
+# Source: baselines/go/main-echo.go
+# Input: any JSON payload; ruchy-bench payloads sends 1 KB to 6 MB documents.
+
+import json
+import zlib
+
+def handler(event, context):
+    # The runtime has decoded the payload; encode it back the way Go does,
+    # compact with sorted keys, and report the length and CRC-32
+    body = json.dumps(event, sort_keys=True, separators=(',', ':')).encode()
+
+    return {
+        'statusCode': 200,
+        'body': f'echo({len(body)})={zlib.crc32(body):08x}',
+        'bytes': len(body),
+        'runtime': 'python'
+    }
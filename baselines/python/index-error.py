@@ -0,0 +1,8 @@
+#!/usr/bin/env python3
+# Error-path Lambda handler - Python 3.12
+# Source: baselines/go/main-error.go
+# Python has no error returns: raising is how a handler fails, and the
+# runtime reports the exception and keeps the execution environment.
+
+def handler(event, context):
+    raise RuntimeError('deliberate error')
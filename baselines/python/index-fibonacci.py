@@ -1,6 +1,9 @@
 #!/usr/bin/env python3
 # Fibonacci Lambda handler - Python 3.12
 # Source: ruchy-book bench-007-fibonacci.py
+# Input: {"n": 0..40}, default 35.
+
+DEFAULT_N, MIN_N, MAX_N = 35, 0, 40
 
 def fibonacci(n):
     """Calculate nth Fibonacci number recursively"""
@@ -8,11 +11,32 @@ def fibonacci(n):
         return n
     return fibonacci(n - 1) + fibonacci(n - 2)
 
+def refuse(message):
+    return {
+        'statusCode': 400,
+        'body': message,
+        'runtime': 'python'
+    }
+
 def handler(event, context):
-    # Calculate fibonacci(35) - standard Lambda benchmark
-    result = fibonacci(35)
+    # Calculate fibonacci(n), 35 unless the event says otherwise - standard Lambda benchmark
+    event = event or {}
+    if not isinstance(event, dict):
+        return refuse('payload is not a JSON object')
+    for name in event:
+        if name != 'n':
+            return refuse(f'unknown input "{name}"')
+    n = event.get('n', DEFAULT_N)
+    if isinstance(n, float) and n.is_integer():
+        n = int(n)
+    if isinstance(n, bool) or not isinstance(n, int):
+        return refuse('n must be an integer')
+    if not MIN_N <= n <= MAX_N:
+        return refuse(f'n must be between {MIN_N} and {MAX_N}')
+    result = fibonacci(n)
 
     return {
         'statusCode': 200,
-        'body': f'fibonacci(35)={result}'
+        'body': f'fibonacci({n})={result}',
+        'runtime': 'python'
     }
@@ -0,0 +1,12 @@
+#!/usr/bin/env python3
+# Timeout-path Lambda handler - Python 3.12
+# Source: baselines/go/main-timeout.go
+# Sleeps a second past the deadline; deployed with a 1-second timeout.
+
+import time
+
+def handler(event, context):
+    time.sleep(context.get_remaining_time_in_millis() / 1000 + 1)
+    return {
+        'statusCode': 200
+    }
@@ -0,0 +1,76 @@
+#!/usr/bin/env python3
+# Ephemeral storage Lambda handler - Python 3.12
+# Source: baselines/go/main-tmpio.go
+# Input: {"mb": 1..10240}, default 512.
+
+import os
+import tempfile
+import time
+
+DEFAULT_MB, MIN_MB, MAX_MB = 512, 1, 10240
+CHUNK = 4 << 20
+
+# A chunk without its index: a repeating 0..255 pattern
+PATTERN = bytes(range(256)) * (CHUNK // 256)
+
+def refuse(message):
+    return {
+        'statusCode': 400,
+        'body': message
+    }
+
+def handler(event, context):
+    # Write mb MB to /tmp in 4 MB chunks, fsync, read them back checked, delete
+    event = event or {}
+    if not isinstance(event, dict):
+        return refuse('payload is not a JSON object')
+    for name in event:
+        if name != 'mb':
+            return refuse(f'unknown input "{name}"')
+    mb = event.get('mb', DEFAULT_MB)
+    if isinstance(mb, float) and mb.is_integer():
+        mb = int(mb)
+    if isinstance(mb, bool) or not isinstance(mb, int):
+        return refuse('mb must be an integer')
+    if not MIN_MB <= mb <= MAX_MB:
+        return refuse(f'mb must be between {MIN_MB} and {MAX_MB}')
+    size = mb << 20
+    path = os.path.join(tempfile.gettempdir(), 'tmpio.dat')
+
+    try:
+        buf = bytearray(PATTERN)
+        chunks = 0
+        start = time.perf_counter()
+        with open(path, 'wb', buffering=0) as f:
+            for off in range(0, size, CHUNK):
+                buf[:8] = chunks.to_bytes(8, 'big')
+                f.write(memoryview(buf)[:min(CHUNK, size - off)])
+                chunks += 1
+            os.fsync(f.fileno())
+        write_s = time.perf_counter() - start
+
+        expect = bytearray(PATTERN)
+        start = time.perf_counter()
+        with open(path, 'rb', buffering=0) as f:
+            for i in range(chunks):
+                n = min(CHUNK, size - i * CHUNK)
+                if f.readinto(memoryview(buf)[:n]) != n:
+                    raise OSError(f'chunk {i} read back short')
+                expect[:8] = i.to_bytes(8, 'big')
+                same = buf == expect if n == CHUNK else buf[:n] == expect[:n]
+                if not same:
+                    raise OSError(f'chunk {i} read back corrupted')
+        read_s = time.perf_counter() - start
+    finally:
+        if os.path.exists(path):
+            os.remove(path)
+
+    return {
+        'statusCode': 200,
+        'body': f'tmpio({mb})={chunks} chunks',
+        'runtime': 'python',
+        'io': {
+            'write_mb_s': mb / write_s,
+            'read_mb_s': mb / read_s
+        }
+    }
@@ -0,0 +1,55 @@
+#!/usr/bin/env python3
+# Binary tree Lambda handler - Python 3.12
+# Source: benchmarks/local-tree/tree.py
+# Input: {"depth": 1..22}, default 19.
+
+DEFAULT_DEPTH, MIN_DEPTH, MAX_DEPTH = 19, 1, 22
+
+class Node:
+    __slots__ = ("left", "right", "item")
+
+    def __init__(self, item, left, right):
+        self.item = item
+        self.left = left
+        self.right = right
+
+def build(item, depth):
+    """Build a tree rooted at item whose children are 2*item and 2*item+1"""
+    if depth == 1:
+        return Node(item, None, None)
+    return Node(item, build(2 * item, depth - 1), build(2 * item + 1, depth - 1))
+
+def total(node):
+    """Sum the items of every node"""
+    if node is None:
+        return 0
+    return node.item + total(node.left) + total(node.right)
+
+def refuse(message):
+    return {
+        'statusCode': 400,
+        'body': message
+    }
+
+def handler(event, context):
+    # Build and sum a tree of 2^depth-1 nodes, depth 19 unless the event says otherwise
+    event = event or {}
+    if not isinstance(event, dict):
+        return refuse('payload is not a JSON object')
+    for name in event:
+        if name != 'depth':
+            return refuse(f'unknown input "{name}"')
+    depth = event.get('depth', DEFAULT_DEPTH)
+    if isinstance(depth, float) and depth.is_integer():
+        depth = int(depth)
+    if isinstance(depth, bool) or not isinstance(depth, int):
+        return refuse('depth must be an integer')
+    if not MIN_DEPTH <= depth <= MAX_DEPTH:
+        return refuse(f'depth must be between {MIN_DEPTH} and {MAX_DEPTH}')
+    result = total(build(1, depth))
+
+    return {
+        'statusCode': 200,
+        'body': f'tree({depth})={result}',
+        'runtime': 'python'
+    }
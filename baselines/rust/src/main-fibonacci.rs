@@ -3,6 +3,11 @@ use serde_json::{json, Value};
 
 // Fibonacci recursive implementation
 // Source: ruchy-book bench-007-fibonacci pattern
+// Input: {"n": 0..40}, default 35.
+const DEFAULT_N: i64 = 35;
+const MIN_N: i64 = 0;
+const MAX_N: i64 = 40;
+
 fn fibonacci(n: i32) -> i32 {
     if n <= 1 {
         n
@@ -18,12 +23,50 @@ async fn main() -> Result<(), Error> {
     Ok(())
 }
 
-async fn func(_: LambdaEvent<Value>) -> Result<Value, Error> {
-    // Calculate fibonacci(35) - standard Lambda benchmark
-    let result = fibonacci(35);
+fn refuse(message: String) -> Value {
+    json!({
+        "statusCode": 400,
+        "body": message,
+        "runtime": "rust"
+    })
+}
+
+// input reads n from the event: absent means the default, anything but an
+// integer within bounds is refused.
+fn input(event: &Value) -> Result<i64, String> {
+    let fields = match event {
+        Value::Null => return Ok(DEFAULT_N),
+        Value::Object(fields) => fields,
+        _ => return Err("payload is not a JSON object".to_string()),
+    };
+    if let Some(name) = fields.keys().find(|name| *name != "n") {
+        return Err(format!("unknown input \"{}\"", name));
+    }
+    let n = match fields.get("n") {
+        None => return Ok(DEFAULT_N),
+        Some(v) => match (v.as_i64(), v.as_f64()) {
+            (Some(n), _) => n,
+            (None, Some(f)) if f.fract() == 0.0 && f.abs() < 1e15 => f as i64,
+            _ => return Err("n must be an integer".to_string()),
+        },
+    };
+    if n < MIN_N || n > MAX_N {
+        return Err(format!("n must be between {} and {}", MIN_N, MAX_N));
+    }
+    Ok(n)
+}
+
+async fn func(event: LambdaEvent<Value>) -> Result<Value, Error> {
+    // Calculate fibonacci(n), 35 unless the event says otherwise - standard Lambda benchmark
+    let n = match input(&event.payload) {
+        Ok(n) => n as i32,
+        Err(message) => return Ok(refuse(message)),
+    };
+    let result = fibonacci(n);
 
     Ok(json!({
         "statusCode": 200,
-        "body": format!("fibonacci(35)={}", result)
+        "body": format!("fibonacci({})={}", n, result),
+        "runtime": "rust"
     }))
 }
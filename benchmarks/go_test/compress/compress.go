@@ -0,0 +1 @@
+../../local-compress/compress.go
\ No newline at end of file
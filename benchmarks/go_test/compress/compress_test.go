@@ -0,0 +1,9 @@
+package main
+
+import (
+	"testing"
+
+	"lambdaperf/benchmarks/internal/localbench"
+)
+
+func BenchmarkCompress(b *testing.B) { localbench.Run(b, "compress.go", main) }
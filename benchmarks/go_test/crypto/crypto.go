@@ -0,0 +1 @@
+../../local-crypto/crypto.go
\ No newline at end of file
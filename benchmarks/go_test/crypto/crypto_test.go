@@ -0,0 +1,9 @@
+package main
+
+import (
+	"testing"
+
+	"lambdaperf/benchmarks/internal/localbench"
+)
+
+func BenchmarkCrypto(b *testing.B) { localbench.Run(b, "crypto.go", main) }
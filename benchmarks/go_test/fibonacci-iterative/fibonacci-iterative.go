@@ -0,0 +1 @@
+../../local-fibonacci/fibonacci-iterative.go
\ No newline at end of file
@@ -0,0 +1,9 @@
+package main
+
+import (
+	"testing"
+
+	"lambdaperf/benchmarks/internal/localbench"
+)
+
+func BenchmarkFibonacciIterative(b *testing.B) { localbench.Run(b, "fibonacci-iterative.go", main) }
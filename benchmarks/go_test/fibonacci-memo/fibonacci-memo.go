@@ -0,0 +1 @@
+../../local-fibonacci/fibonacci-memo.go
\ No newline at end of file
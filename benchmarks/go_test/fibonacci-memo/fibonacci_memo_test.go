@@ -0,0 +1,9 @@
+package main
+
+import (
+	"testing"
+
+	"lambdaperf/benchmarks/internal/localbench"
+)
+
+func BenchmarkFibonacciMemo(b *testing.B) { localbench.Run(b, "fibonacci-memo.go", main) }
@@ -0,0 +1 @@
+../../local-fibonacci/fibonacci.go
\ No newline at end of file
@@ -0,0 +1,9 @@
+package main
+
+import (
+	"testing"
+
+	"lambdaperf/benchmarks/internal/localbench"
+)
+
+func BenchmarkFibonacci(b *testing.B) { localbench.Run(b, "fibonacci.go", main) }
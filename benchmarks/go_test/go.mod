@@ -0,0 +1,3 @@
+module lambdaperf/benchmarks
+
+go 1.24
@@ -0,0 +1,99 @@
+// Package localbench runs the local Go programs of benchmarks/local-*/ as
+// testing.B benchmarks, so that go test -bench, -cpuprofile and benchstat
+// work on the same code ruchy-bench runs as a process.
+//
+// Each package under benchmarks/go_test links one program into a test
+// binary: the program's source is a symlink, and a one-line benchmark
+// hands its main to Run.
+package localbench
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// expectedPrefix starts the header line of every local program that
+// states the result it prints, which ruchy-bench checks too.
+const expectedPrefix = "// Expected result: "
+
+// Run benchmarks main, the main function of the program at source, which
+// is relative to the benchmark's package. Each iteration is one run of
+// the program less its process start. main runs in the program's own
+// directory, where its relative paths to fixtures resolve, with its output
+// discarded once a first run printed the expected result.
+func Run(b *testing.B, source string, main func()) {
+	b.Helper()
+	src, err := filepath.Abs(source)
+	if err == nil {
+		src, err = filepath.EvalSymlinks(src)
+	}
+	if err != nil {
+		b.Fatal(err)
+	}
+	want, err := expected(src)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Chdir(filepath.Dir(src))
+
+	got, err := output(b, main)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if got != want {
+		b.Fatalf("%s printed %q, want %q", filepath.Base(src), got, want)
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer devNull.Close()
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+	b.ReportAllocs()
+	for b.Loop() {
+		main()
+	}
+}
+
+// expected returns the result the program at src states it prints.
+func expected(src string) (string, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if want, ok := strings.CutPrefix(sc.Text(), expectedPrefix); ok {
+			return strings.TrimSpace(want), nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("%s states no %q", src, strings.TrimSpace(expectedPrefix))
+}
+
+// output runs main once and returns what it printed, trimmed.
+func output(b *testing.B, main func()) (string, error) {
+	f, err := os.CreateTemp(b.TempDir(), "stdout")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	stdout := os.Stdout
+	os.Stdout = f
+	func() {
+		defer func() { os.Stdout = stdout }()
+		main()
+	}()
+	data, err := os.ReadFile(f.Name())
+	return strings.TrimSpace(string(data)), err
+}
@@ -0,0 +1,48 @@
+package localbench
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEveryProgramBenchmarked checks that every local Go program has its
+// package under benchmarks/go_test, named after the program, and that it
+// states the result it prints.
+func TestEveryProgramBenchmarked(t *testing.T) {
+	programs, err := filepath.Glob(filepath.Join("..", "..", "..", "local-*", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(programs) == 0 {
+		t.Fatal("no local Go programs found")
+	}
+	for _, src := range programs {
+		name := strings.TrimSuffix(filepath.Base(src), ".go")
+		link := filepath.Join("..", "..", name, filepath.Base(src))
+		target, err := filepath.EvalSymlinks(link)
+		if err != nil {
+			t.Errorf("%s has no benchmark: %v", src, err)
+			continue
+		}
+		if same, err := sameFile(target, src); err != nil || !same {
+			t.Errorf("%s links %s, want %s", link, target, src)
+		}
+		if _, err := expected(src); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func sameFile(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ai, bi), nil
+}
@@ -0,0 +1 @@
+../../local-json/json.go
\ No newline at end of file
@@ -0,0 +1 @@
+../../local-logparse/logparse.go
\ No newline at end of file
@@ -0,0 +1,9 @@
+package main
+
+import (
+	"testing"
+
+	"lambdaperf/benchmarks/internal/localbench"
+)
+
+func BenchmarkLogparse(b *testing.B) { localbench.Run(b, "logparse.go", main) }
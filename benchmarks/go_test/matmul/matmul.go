@@ -0,0 +1 @@
+../../local-matmul/matmul.go
\ No newline at end of file
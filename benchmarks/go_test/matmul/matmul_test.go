@@ -0,0 +1,9 @@
+package main
+
+import (
+	"testing"
+
+	"lambdaperf/benchmarks/internal/localbench"
+)
+
+func BenchmarkMatmul(b *testing.B) { localbench.Run(b, "matmul.go", main) }
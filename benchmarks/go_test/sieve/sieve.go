@@ -0,0 +1 @@
+../../local-sieve/sieve.go
\ No newline at end of file
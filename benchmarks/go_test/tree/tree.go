@@ -0,0 +1 @@
+../../local-tree/tree.go
\ No newline at end of file
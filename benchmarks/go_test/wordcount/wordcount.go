@@ -0,0 +1 @@
+../../local-wordcount/wordcount.go
\ No newline at end of file
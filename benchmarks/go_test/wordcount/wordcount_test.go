@@ -0,0 +1,9 @@
+package main
+
+import (
+	"testing"
+
+	"lambdaperf/benchmarks/internal/localbench"
+)
+
+func BenchmarkWordcount(b *testing.B) { localbench.Run(b, "wordcount.go", main) }
@@ -0,0 +1,65 @@
+// gzip and base64 round trip over 5 MB - Go
+// Gzips a deterministic 5 MB JSON-lines payload at level 6, base64-encodes
+// it, then decodes and gunzips it back and checks the result.
+// Measures compression and codec throughput.
+// Matches AWS Lambda baseline implementation (baselines/go/main-compress.go)
+// Expected result: compress(5)=bytes:5242880,sha256:7fba765722313d5a
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+const mb = 5
+
+var regions = []string{"us-east-1", "eu-west-1", "ap-south-1", "sa-east-1"}
+
+var statuses = []string{"pending", "shipped", "delivered"}
+
+func payload(n int) []byte {
+	var b bytes.Buffer
+	b.Grow(n + 128)
+	for i := 0; b.Len() < n; i++ {
+		fmt.Fprintf(&b, `{"id":%d,"sku":"SKU-%06d","qty":%d,"price_cents":%d,"region":"%s","status":"%s"}`+"\n",
+			i, i*7919%1000000, i%17+1, i*104729%100000, regions[i%4], statuses[i%3])
+	}
+	return b.Bytes()[:n]
+}
+
+func main() {
+	data := payload(mb << 20)
+	var gz bytes.Buffer
+	w, err := gzip.NewWriterLevel(&gz, 6)
+	if err != nil {
+		panic(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		panic(err)
+	}
+	if err := w.Close(); err != nil {
+		panic(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(gz.Bytes())
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		panic(err)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(decoded))
+	if err != nil {
+		panic(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		panic(err)
+	}
+	sum := sha256.Sum256(out)
+	result := fmt.Sprintf("compress(%d)=bytes:%d,sha256:%x", mb, len(out), sum[:8])
+	fmt.Println(result) // checked against the expected result by ruchy-bench
+}
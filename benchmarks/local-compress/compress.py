@@ -0,0 +1,37 @@
+#!/usr/bin/env python3
+# gzip and base64 round trip over 5 MB - Python
+# Gzips a deterministic 5 MB JSON-lines payload at level 6, base64-encodes
+# it, then decodes and gunzips it back and checks the result.
+# Measures compression and codec throughput.
+# Matches AWS Lambda baseline implementation (baselines/go/main-compress.go)
+# Expected result: compress(5)=bytes:5242880,sha256:7fba765722313d5a
+
+import base64
+import gzip
+import hashlib
+
+MB = 5
+
+REGIONS = ["us-east-1", "eu-west-1", "ap-south-1", "sa-east-1"]
+STATUSES = ["pending", "shipped", "delivered"]
+
+def payload(n):
+    """n bytes of order records, one JSON object per line"""
+    lines, size, i = [], 0, 0
+    while size < n:
+        line = '{"id":%d,"sku":"SKU-%06d","qty":%d,"price_cents":%d,"region":"%s","status":"%s"}\n' % (
+            i, i * 7919 % 1000000, i % 17 + 1, i * 104729 % 100000, REGIONS[i % 4], STATUSES[i % 3])
+        lines.append(line)
+        size += len(line)
+        i += 1
+    return "".join(lines).encode()[:n]
+
+def main():
+    data = payload(MB << 20)
+    encoded = base64.b64encode(gzip.compress(data, compresslevel=6))
+    out = gzip.decompress(base64.b64decode(encoded))
+    result = "compress(%d)=bytes:%d,sha256:%s" % (MB, len(out), hashlib.sha256(out).hexdigest()[:16])
+    print(result)  # checked against the expected result by ruchy-bench
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,43 @@
+// SHA-256 and AES-256-GCM over 10 MB - Go
+// Hashes a deterministic 10 MB buffer, then seals it under a fixed key and
+// nonce. Measures crypto throughput, hardware-accelerated where the CPU
+// allows it.
+// Matches AWS Lambda baseline implementation (baselines/go/main-crypto.go)
+// Expected result: crypto(10)=sha256:44f9296993796e20,gcm:f258271894e936fb8653169fc47dc5b5
+
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"fmt"
+)
+
+const mb = 10
+
+// pattern returns n bytes cycling through 0..250.
+func pattern(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i % 251)
+	}
+	return b
+}
+
+func main() {
+	buf := pattern(mb << 20)
+	sum := sha256.Sum256(buf)
+	block, err := aes.NewCipher(pattern(32))
+	if err != nil {
+		panic(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	sealed := gcm.Seal(nil, pattern(12), buf, nil)
+	tag := sealed[len(sealed)-gcm.Overhead():]
+	result := fmt.Sprintf("crypto(%d)=sha256:%x,gcm:%x", mb, sum[:8], tag)
+	fmt.Println(result) // checked against the expected result by ruchy-bench
+}
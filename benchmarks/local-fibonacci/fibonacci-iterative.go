@@ -0,0 +1,29 @@
+// Fibonacci iterative - Go
+// Sums fibonacci(80..90) computed with a loop over 100000 repetitions,
+// wrapping at 2^64. Isolates loop and integer arithmetic from the call
+// overhead that dominates the recursive variant.
+// Matches AWS Lambda baseline implementation
+// Expected result: fibonacci-iterative(100000)=2232225216200996121
+
+package main
+
+import "fmt"
+
+const repetitions = 100000
+
+func fibonacci(n int) uint64 {
+	var a, b uint64 = 0, 1
+	for i := 0; i < n; i++ {
+		a, b = b, a+b
+	}
+	return a
+}
+
+func main() {
+	var sum uint64
+	for i := 0; i < repetitions; i++ {
+		sum += fibonacci(80 + i%11)
+	}
+	result := fmt.Sprintf("fibonacci-iterative(%d)=%d", repetitions, sum)
+	fmt.Println(result) // checked against the expected result by ruchy-bench
+}
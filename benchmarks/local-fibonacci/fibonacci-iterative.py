@@ -0,0 +1,27 @@
+#!/usr/bin/env python3
+# Fibonacci iterative - Python
+# Sums fibonacci(80..90) computed with a loop over 100000 repetitions,
+# wrapping at 2^64. Isolates loop and integer arithmetic from the call
+# overhead that dominates the recursive variant.
+# Matches AWS Lambda baseline implementation
+# Expected result: fibonacci-iterative(100000)=2232225216200996121
+
+REPETITIONS = 100000
+MASK = (1 << 64) - 1
+
+def fibonacci(n):
+    """Calculate nth Fibonacci number with a loop"""
+    a, b = 0, 1
+    for _ in range(n):
+        a, b = b, (a + b) & MASK
+    return a
+
+def main():
+    total = 0
+    for i in range(REPETITIONS):
+        total = (total + fibonacci(80 + i % 11)) & MASK
+    result = "fibonacci-iterative(%d)=%d" % (REPETITIONS, total)
+    print(result)  # checked against the expected result by ruchy-bench
+
+if __name__ == "__main__":
+    main()
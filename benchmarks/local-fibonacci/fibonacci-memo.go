@@ -0,0 +1,33 @@
+// Fibonacci memoized - Go
+// Sums fibonacci(80..90) computed recursively with a fresh memo table per
+// call over 10000 repetitions, wrapping at 2^64. Measures hash map inserts
+// and lookups plus shallow recursion.
+// Matches AWS Lambda baseline implementation
+// Expected result: fibonacci-memo(10000)=12697144346765014788
+
+package main
+
+import "fmt"
+
+const repetitions = 10000
+
+func fibonacci(n int, memo map[int]uint64) uint64 {
+	if n <= 1 {
+		return uint64(n)
+	}
+	if v, ok := memo[n]; ok {
+		return v
+	}
+	v := fibonacci(n-1, memo) + fibonacci(n-2, memo)
+	memo[n] = v
+	return v
+}
+
+func main() {
+	var sum uint64
+	for i := 0; i < repetitions; i++ {
+		sum += fibonacci(80+i%11, map[int]uint64{})
+	}
+	result := fmt.Sprintf("fibonacci-memo(%d)=%d", repetitions, sum)
+	fmt.Println(result) // checked against the expected result by ruchy-bench
+}
@@ -0,0 +1,30 @@
+#!/usr/bin/env python3
+# Fibonacci memoized - Python
+# Sums fibonacci(80..90) computed recursively with a fresh memo table per
+# call over 10000 repetitions, wrapping at 2^64. Measures hash map inserts
+# and lookups plus shallow recursion.
+# Matches AWS Lambda baseline implementation
+# Expected result: fibonacci-memo(10000)=12697144346765014788
+
+REPETITIONS = 10000
+MASK = (1 << 64) - 1
+
+def fibonacci(n, memo):
+    """Calculate nth Fibonacci number recursively, caching results in memo"""
+    if n <= 1:
+        return n
+    if n in memo:
+        return memo[n]
+    v = (fibonacci(n - 1, memo) + fibonacci(n - 2, memo)) & MASK
+    memo[n] = v
+    return v
+
+def main():
+    total = 0
+    for i in range(REPETITIONS):
+        total = (total + fibonacci(80 + i % 11, {})) & MASK
+    result = "fibonacci-memo(%d)=%d" % (REPETITIONS, total)
+    print(result)  # checked against the expected result by ruchy-bench
+
+if __name__ == "__main__":
+    main()
@@ -1,6 +1,6 @@
 // Fibonacci recursive (n=35) - C
 // Matches AWS Lambda baseline implementation
-// Expected result: 9227465
+// Expected result: fibonacci(35)=9227465
 
 #include <stdio.h>
 
@@ -13,6 +13,6 @@ int fibonacci(int n) {
 
 int main() {
     int result = fibonacci(35);
-    // Silent for benchmarking
+    printf("fibonacci(35)=%d\n", result); // checked against the expected result by ruchy-bench
     return 0;
 }
@@ -1,9 +1,11 @@
 // Fibonacci recursive (n=35) - Go
 // Matches AWS Lambda baseline implementation
-// Expected result: 9227465
+// Expected result: fibonacci(35)=9227465
 
 package main
 
+import "fmt"
+
 func fibonacci(n int) int {
 	if n <= 1 {
 		return n
@@ -12,6 +14,6 @@ func fibonacci(n int) int {
 }
 
 func main() {
-	result := fibonacci(35)
-	_ = result // Silent for benchmarking
+	result := fmt.Sprintf("fibonacci(35)=%d", fibonacci(35))
+	fmt.Println(result) // checked against the expected result by ruchy-bench
 }
@@ -1,5 +1,6 @@
 #!/usr/bin/env julia
 # Julia fibonacci(35) benchmark
+# Expected result: fibonacci(35)=9227465
 # Expected: ~25ms after JIT warmup (matches Rust performance)
 # Runtime: ~200MB (Julia + LLVM)
 
@@ -13,7 +14,7 @@ end
 
 function main()
     result = fibonacci(35)
-    # Silent for benchmarking
+    println("fibonacci(35)=", result)  # checked against the expected result by ruchy-bench
 end
 
 main()
@@ -1,7 +1,7 @@
 #!/usr/bin/env python3
 # Fibonacci recursive (n=35) - Python
 # Matches AWS Lambda baseline implementation
-# Expected result: 9227465
+# Expected result: fibonacci(35)=9227465
 
 def fibonacci(n):
     """Calculate nth Fibonacci number recursively"""
@@ -10,8 +10,8 @@ def fibonacci(n):
     return fibonacci(n - 1) + fibonacci(n - 2)
 
 def main():
-    result = fibonacci(35)
-    # Silent for benchmarking
+    result = "fibonacci(35)=%d" % fibonacci(35)
+    print(result)  # checked against the expected result by ruchy-bench
 
 if __name__ == "__main__":
     main()
@@ -1,6 +1,6 @@
 // Fibonacci recursive (n=35) - Rust
 // Matches AWS Lambda baseline implementation
-// Expected result: 9227465
+// Expected result: fibonacci(35)=9227465
 
 fn fibonacci(n: i32) -> i32 {
     if n <= 1 {
@@ -12,5 +12,5 @@ fn fibonacci(n: i32) -> i32 {
 
 fn main() {
     let result = fibonacci(35);
-    let _ = result; // Silent for benchmarking
+    println!("fibonacci(35)={}", result); // checked against the expected result by ruchy-bench
 }
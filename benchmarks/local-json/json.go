@@ -0,0 +1,59 @@
+// JSON round-trip (~1.1 MB nested document) - Go
+// Matches AWS Lambda baseline implementation (baselines/go/main-json.go)
+// Expected result: json(1115300)=31fa7abb
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+)
+
+func document(n int) map[string]any {
+	recs := make([]any, n)
+	for i := 0; i < n; i++ {
+		children := make([]any, 3)
+		for k := 0; k < 3; k++ {
+			children[k] = map[string]any{
+				"id":    i*10 + k,
+				"label": fmt.Sprintf("child-%d-%d", i, k),
+				"depth": k,
+			}
+		}
+		recs[i] = map[string]any{
+			"id":     i,
+			"name":   fmt.Sprintf("record-%d", i),
+			"active": i%3 == 0,
+			"tags":   []any{fmt.Sprintf("t%d", i%7), fmt.Sprintf("t%d", i%11), fmt.Sprintf("t%d", i%13)},
+			"metrics": map[string]any{
+				"count":   i * 3,
+				"score":   (i * 7919) % 1000,
+				"buckets": []any{i % 5, i % 9, i % 17, i % 31},
+			},
+			"children": children,
+		}
+	}
+	return map[string]any{"version": 1, "records": recs}
+}
+
+func roundTrip(payload []byte) string {
+	var doc any
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		panic(err)
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("json(%d)=%08x", len(out), crc32.ChecksumIEEE(out))
+}
+
+func main() {
+	payload, err := json.Marshal(document(4000))
+	if err != nil {
+		panic(err)
+	}
+	result := roundTrip(payload)
+	fmt.Println(result) // checked against the expected result by ruchy-bench
+}
@@ -0,0 +1,55 @@
+#!/usr/bin/env python3
+# JSON round-trip (~1.1 MB nested document) - Python
+# Matches AWS Lambda baseline implementation (baselines/go/main-json.go)
+# Expected result: json(1115300)=31fa7abb
+
+import sys
+
+# This script shares its name with the stdlib module it benchmarks; drop
+# the script directory from the import path so `import json` finds the
+# standard library.
+del sys.path[0]
+
+import json  # noqa: E402
+import zlib  # noqa: E402
+
+
+def document(n):
+    """Build the deterministic benchmark document"""
+    records = []
+    for i in range(n):
+        records.append({
+            "id": i,
+            "name": f"record-{i}",
+            "active": i % 3 == 0,
+            "tags": [f"t{i % 7}", f"t{i % 11}", f"t{i % 13}"],
+            "metrics": {
+                "count": i * 3,
+                "score": (i * 7919) % 1000,
+                "buckets": [i % 5, i % 9, i % 17, i % 31],
+            },
+            "children": [
+                {"id": i * 10 + k, "label": f"child-{i}-{k}", "depth": k}
+                for k in range(3)
+            ],
+        })
+    return {"version": 1, "records": records}
+
+
+def encode(doc):
+    """Compact, key-sorted encoding shared by every runtime"""
+    return json.dumps(doc, separators=(",", ":"), sort_keys=True)
+
+
+def round_trip(payload):
+    out = encode(json.loads(payload)).encode()
+    return f"json({len(out)})={zlib.crc32(out):08x}"
+
+
+def main():
+    payload = encode(document(4000))
+    result = round_trip(payload)
+    print(result)  # checked against the expected result by ruchy-bench
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,37 @@
+#!/usr/bin/env python3
+# Regex log parsing over a bundled ~1 MB log - Python
+# Counts the matches of seven regular expressions (addresses, request
+# lines with error statuses, timestamps, UUIDs, e-mail addresses, slow
+# warnings and bot user agents) over baselines/go/corpus/app.log.
+# Measures the regex engine.
+# Matches AWS Lambda baseline implementation (baselines/go/main-logparse.go)
+# Expected result: logparse(lines=6768)=ipv4:3354,http_error:1587,timestamp:3414,uuid:3414,email:3414,slow:413,bot:1277
+
+import re
+
+LOG_PATH = "../../baselines/go/corpus/app.log"
+
+# Byte patterns, so \b and (?i) are ASCII-only as they are in Go.
+PATTERNS = [
+    ("ipv4", re.compile(rb"\b(?:[0-9]{1,3}\.){3}[0-9]{1,3}\b")),
+    ("http_error", re.compile(rb'"(?:GET|POST|PUT|DELETE|PATCH|HEAD) [^" ]+ HTTP/1\.[01]" [45][0-9]{2} ')),
+    ("timestamp", re.compile(rb"[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}\.[0-9]{3}Z")),
+    ("uuid", re.compile(rb"\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b")),
+    ("email", re.compile(rb"[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b")),
+    ("slow", re.compile(rb"level=(?:ERROR|WARN) [^\n]*duration_ms=[0-9]{3,}")),
+    ("bot", re.compile(rb'(?i)"[^"\n]*(?:bot|crawler|spider)[^"\n]*"')),
+]
+
+def logparse(text):
+    """Count the matches of every pattern in text"""
+    counts = ",".join("%s:%d" % (name, sum(1 for _ in p.finditer(text))) for name, p in PATTERNS)
+    return "logparse(lines=%d)=%s" % (text.count(b"\n"), counts)
+
+def main():
+    with open(LOG_PATH, "rb") as f:
+        text = f.read()
+    result = logparse(text)
+    print(result)  # checked against the expected result by ruchy-bench
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,61 @@
+// Dense matrix multiplication (512x512 float64) - Go
+// Multiplies two matrices filled from a fixed-seed LCG and sums the
+// product. Every runtime performs the same operations in the same order,
+// so the checksum matches bit for bit.
+// Matches AWS Lambda baseline implementation
+// Expected result: matmul(512)=33519225.201954
+
+package main
+
+import "fmt"
+
+const size = 512
+
+// lcg is a 64-bit linear congruential generator (Knuth's MMIX constants)
+// yielding floats in [0, 1) from the top 53 bits.
+type lcg uint64
+
+func (x *lcg) next() float64 {
+	*x = *x*6364136223846793005 + 1442695040888963407
+	return float64(uint64(*x)>>11) / (1 << 53)
+}
+
+func fill(rng *lcg) []float64 {
+	m := make([]float64, size*size)
+	for i := range m {
+		m[i] = rng.next()
+	}
+	return m
+}
+
+// multiply uses i-k-j order. The explicit float64 conversion forbids fused
+// multiply-add, which Go would otherwise emit on arm64 and which changes
+// the rounding.
+func multiply(a, b []float64) []float64 {
+	c := make([]float64, size*size)
+	for i := 0; i < size; i++ {
+		for k := 0; k < size; k++ {
+			aik := a[i*size+k]
+			for j := 0; j < size; j++ {
+				c[i*size+j] += float64(aik * b[k*size+j])
+			}
+		}
+	}
+	return c
+}
+
+func checksum(c []float64) string {
+	var sum float64
+	for _, v := range c {
+		sum += v
+	}
+	return fmt.Sprintf("matmul(%d)=%.6f", size, sum)
+}
+
+func main() {
+	rng := lcg(42)
+	a := fill(&rng)
+	b := fill(&rng)
+	result := checksum(multiply(a, b))
+	fmt.Println(result) // checked against the expected result by ruchy-bench
+}
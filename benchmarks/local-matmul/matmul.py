@@ -0,0 +1,57 @@
+#!/usr/bin/env python3
+# Dense matrix multiplication (512x512 float64) - Python
+# Multiplies two matrices filled from a fixed-seed LCG and sums the
+# product. Every runtime performs the same operations in the same order,
+# so the checksum matches bit for bit. Pure Python, no NumPy: this
+# measures the interpreter, and takes tens of seconds.
+# Matches AWS Lambda baseline implementation
+# Expected result: matmul(512)=33519225.201954
+
+SIZE = 512
+MASK = (1 << 64) - 1
+
+
+class LCG:
+    """64-bit linear congruential generator (Knuth's MMIX constants)"""
+
+    def __init__(self, seed):
+        self.x = seed
+
+    def next(self):
+        self.x = (self.x * 6364136223846793005 + 1442695040888963407) & MASK
+        return (self.x >> 11) / (1 << 53)
+
+
+def fill(rng):
+    return [rng.next() for _ in range(SIZE * SIZE)]
+
+
+def multiply(a, b):
+    """i-k-j order, one rounded multiply and add per step"""
+    c = [0.0] * (SIZE * SIZE)
+    for i in range(SIZE):
+        row = i * SIZE
+        for k in range(SIZE):
+            aik = a[row + k]
+            col = k * SIZE
+            for j in range(SIZE):
+                c[row + j] += aik * b[col + j]
+    return c
+
+
+def checksum(c):
+    total = 0.0
+    for v in c:
+        total += v
+    return "matmul(%d)=%.6f" % (SIZE, total)
+
+
+def main():
+    rng = LCG(42)
+    a = fill(rng)
+    b = fill(rng)
+    result = checksum(multiply(a, b))
+    print(result)  # checked against the expected result by ruchy-bench
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,31 @@
+// Sieve of Eratosthenes up to 10 million - Go
+// Counts the primes up to 10,000,000 with a freshly allocated []bool.
+// Measures a large allocation and strided memory writes.
+// Matches AWS Lambda baseline implementation (baselines/go/main-sieve.go)
+// Expected result: sieve(10000000)=664579
+
+package main
+
+import "fmt"
+
+const limit = 10_000_000
+
+func sieve(n int) int {
+	composite := make([]bool, n+1)
+	count := 0
+	for i := 2; i <= n; i++ {
+		if composite[i] {
+			continue
+		}
+		count++
+		for j := i * i; j <= n; j += i {
+			composite[j] = true
+		}
+	}
+	return count
+}
+
+func main() {
+	result := fmt.Sprintf("sieve(%d)=%d", limit, sieve(limit))
+	fmt.Println(result) // checked against the expected result by ruchy-bench
+}
@@ -0,0 +1,27 @@
+#!/usr/bin/env python3
+# Sieve of Eratosthenes up to 10 million - Python
+# Counts the primes up to 10,000,000 with a freshly allocated bytearray.
+# Measures a large allocation and strided memory writes.
+# Matches AWS Lambda baseline implementation (baselines/go/main-sieve.go)
+# Expected result: sieve(10000000)=664579
+
+LIMIT = 10_000_000
+
+def sieve(n):
+    """Count the primes up to n"""
+    composite = bytearray(n + 1)
+    count = 0
+    for i in range(2, n + 1):
+        if composite[i]:
+            continue
+        count += 1
+        if i * i <= n:
+            composite[i * i::i] = b"\x01" * len(range(i * i, n + 1, i))
+    return count
+
+def main():
+    result = "sieve(%d)=%d" % (LIMIT, sieve(LIMIT))
+    print(result)  # checked against the expected result by ruchy-bench
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,54 @@
+// Binary tree of depth 19 - C
+// Builds a complete tree of 524,287 malloc'd nodes, sums their items and
+// frees them. Measures allocation.
+// Matches AWS Lambda baseline implementation (baselines/go/main-tree.go)
+// Expected result: tree(19)=137438691328
+
+#include <stdio.h>
+#include <stdlib.h>
+
+#define DEPTH 19
+
+struct node {
+    struct node *left, *right;
+    long item;
+};
+
+struct node *build(long item, int depth) {
+    struct node *n = malloc(sizeof *n);
+    if (n == NULL) {
+        perror("malloc");
+        exit(1);
+    }
+    n->item = item;
+    n->left = n->right = NULL;
+    if (depth > 1) {
+        n->left = build(2 * item, depth - 1);
+        n->right = build(2 * item + 1, depth - 1);
+    }
+    return n;
+}
+
+long sum(const struct node *n) {
+    if (n == NULL) {
+        return 0;
+    }
+    return n->item + sum(n->left) + sum(n->right);
+}
+
+void release(struct node *n) {
+    if (n == NULL) {
+        return;
+    }
+    release(n->left);
+    release(n->right);
+    free(n);
+}
+
+int main() {
+    struct node *tree = build(1, DEPTH);
+    long result = sum(tree);
+    release(tree);
+    printf("tree(%d)=%ld\n", DEPTH, result); // checked against the expected result by ruchy-bench
+    return 0;
+}
@@ -0,0 +1,37 @@
+// Binary tree of depth 19 - Go
+// Builds a complete tree of 524,287 heap-allocated nodes and sums their
+// items. Measures allocation and garbage collection.
+// Matches AWS Lambda baseline implementation (baselines/go/main-tree.go)
+// Expected result: tree(19)=137438691328
+
+package main
+
+import "fmt"
+
+const depth = 19
+
+type node struct {
+	left, right *node
+	item        int
+}
+
+func build(item, depth int) *node {
+	n := &node{item: item}
+	if depth > 1 {
+		n.left = build(2*item, depth-1)
+		n.right = build(2*item+1, depth-1)
+	}
+	return n
+}
+
+func (n *node) sum() int {
+	if n == nil {
+		return 0
+	}
+	return n.item + n.left.sum() + n.right.sum()
+}
+
+func main() {
+	result := fmt.Sprintf("tree(%d)=%d", depth, build(1, depth).sum())
+	fmt.Println(result) // checked against the expected result by ruchy-bench
+}
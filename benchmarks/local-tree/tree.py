@@ -0,0 +1,35 @@
+#!/usr/bin/env python3
+# Binary tree of depth 19 - Python
+# Builds a complete tree of 524,287 heap-allocated nodes and sums their
+# items. Measures allocation and garbage collection.
+# Matches AWS Lambda baseline implementation (baselines/go/main-tree.go)
+# Expected result: tree(19)=137438691328
+
+DEPTH = 19
+
+class Node:
+    __slots__ = ("left", "right", "item")
+
+    def __init__(self, item, left, right):
+        self.item = item
+        self.left = left
+        self.right = right
+
+def build(item, depth):
+    """Build a tree rooted at item whose children are 2*item and 2*item+1"""
+    if depth == 1:
+        return Node(item, None, None)
+    return Node(item, build(2 * item, depth - 1), build(2 * item + 1, depth - 1))
+
+def total(node):
+    """Sum the items of every node"""
+    if node is None:
+        return 0
+    return node.item + total(node.left) + total(node.right)
+
+def main():
+    result = "tree(%d)=%d" % (DEPTH, total(build(1, DEPTH)))
+    print(result)  # checked against the expected result by ruchy-bench
+
+if __name__ == "__main__":
+    main()
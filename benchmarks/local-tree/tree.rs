@@ -0,0 +1,34 @@
+// Binary tree of depth 19 - Rust
+// Builds a complete tree of 524,287 boxed nodes and sums their items.
+// Measures allocation; the tree is freed when it goes out of scope.
+// Matches AWS Lambda baseline implementation (baselines/go/main-tree.go)
+// Expected result: tree(19)=137438691328
+
+const DEPTH: u32 = 19;
+
+struct Node {
+    left: Option<Box<Node>>,
+    right: Option<Box<Node>>,
+    item: u64,
+}
+
+fn build(item: u64, depth: u32) -> Box<Node> {
+    let (left, right) = if depth > 1 {
+        (Some(build(2 * item, depth - 1)), Some(build(2 * item + 1, depth - 1)))
+    } else {
+        (None, None)
+    };
+    Box::new(Node { left, right, item })
+}
+
+fn sum(node: &Option<Box<Node>>) -> u64 {
+    match node {
+        Some(n) => n.item + sum(&n.left) + sum(&n.right),
+        None => 0,
+    }
+}
+
+fn main() {
+    let tree = Some(build(1, DEPTH));
+    println!("tree({})={}", DEPTH, sum(&tree)); // checked against the expected result by ruchy-bench
+}
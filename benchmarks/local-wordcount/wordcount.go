@@ -0,0 +1,56 @@
+// Word count over a bundled ~2 MB text corpus - Go
+// Lowercases and tokenizes baselines/go/corpus/corpus.txt into runs of
+// ASCII letters, counts each word and reports the totals and the most
+// frequent word. Measures byte scanning branches and hash map updates.
+// Matches AWS Lambda baseline implementation (baselines/go/main-wordcount.go)
+// Expected result: wordcount(words=376128,unique=1124,top=the:37764)
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const corpusPath = "../../baselines/go/corpus/corpus.txt"
+
+func wordcount(text []byte) string {
+	counts := make(map[string]int)
+	words := 0
+	word := make([]byte, 0, 32)
+	for i := 0; i <= len(text); i++ {
+		var c byte
+		if i < len(text) {
+			c = text[i]
+		}
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if 'a' <= c && c <= 'z' {
+			word = append(word, c)
+			continue
+		}
+		if len(word) > 0 {
+			counts[string(word)]++
+			words++
+			word = word[:0]
+		}
+	}
+
+	top, topCount := "", 0
+	for w, n := range counts {
+		if n > topCount || n == topCount && w < top {
+			top, topCount = w, n
+		}
+	}
+	return fmt.Sprintf("wordcount(words=%d,unique=%d,top=%s:%d)", words, len(counts), top, topCount)
+}
+
+func main() {
+	text, err := os.ReadFile(corpusPath)
+	if err != nil {
+		panic(err)
+	}
+	result := wordcount(text)
+	fmt.Println(result) // checked against the expected result by ruchy-bench
+}
@@ -0,0 +1,28 @@
+#!/usr/bin/env python3
+# Word count over a bundled ~2 MB text corpus - Python
+# Lowercases and tokenizes baselines/go/corpus/corpus.txt into runs of
+# ASCII letters, counts each word and reports the totals and the most
+# frequent word. Measures byte scanning branches and hash map updates.
+# Matches AWS Lambda baseline implementation (baselines/go/main-wordcount.go)
+# Expected result: wordcount(words=376128,unique=1124,top=the:37764)
+
+import re
+from collections import Counter
+
+CORPUS_PATH = "../../baselines/go/corpus/corpus.txt"
+
+def wordcount(text):
+    """Count the ASCII-letter words of text, case-insensitively"""
+    counts = Counter(re.findall(rb"[a-z]+", text.lower()))
+    top, top_count = min(counts.items(), key=lambda kv: (-kv[1], kv[0]))
+    return "wordcount(words=%d,unique=%d,top=%s:%d)" % (
+        sum(counts.values()), len(counts), top.decode(), top_count)
+
+def main():
+    with open(CORPUS_PATH, "rb") as f:
+        text = f.read()
+    result = wordcount(text)
+    print(result)  # checked against the expected result by ruchy-bench
+
+if __name__ == "__main__":
+    main()
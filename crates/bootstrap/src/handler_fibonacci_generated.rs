@@ -6,10 +6,67 @@ pub fn fibonacci(n: i32) -> i32 {
         fibonacci(n - 1) + fibonacci(n - 2)
     }
 }
+fn refuse(message: &str) -> String {
+    {
+        let quoted = message.replace("\"", "\\\"");
+        format!(
+            "{}{}",
+            String::from("{\"statusCode\":400,\"body\":\"".to_string()) + &quoted,
+            "\",\"runtime\":\"ruchy\"}"
+        )
+    }
+}
+fn is_number(s: &str) -> bool {
+    {
+        let mut first = true;
+        for c in s.chars() {
+            {
+                let allowed = if first { "-0123456789" } else { "+-.0123456789eE" };
+                if !allowed.contains(c) {
+                    return false;
+                }
+                first = false;
+            }
+        }
+        !first
+    }
+}
 #[allow(clippy::all)]
-pub fn lambda_handler(_request_id: &str, _body: &str) -> String {
+pub fn lambda_handler(_request_id: &str, body: &str) -> String {
     {
-        let n = 35;
+        let payload = body.trim();
+        let mut n = 35;
+        if !payload.is_empty() && payload != "null" {
+            if !(payload.starts_with("{") && payload.ends_with("}")) {
+                return refuse("payload is not a JSON object");
+            }
+            let fields = payload[1..payload.len() - 1].trim();
+            if !fields.is_empty() {
+                if !fields.starts_with("\"n\"") {
+                    return refuse("payload must be {} or {\"n\": <integer>}");
+                }
+                let field = fields[3..].trim_start();
+                if !field.starts_with(":") {
+                    return refuse("payload must be {} or {\"n\": <integer>}");
+                }
+                let value = field[1..].trim();
+                if !is_number(value) {
+                    return refuse("n must be an integer");
+                }
+                let parsed = value.parse::<f64>();
+                if parsed.is_err() {
+                    return refuse("n must be an integer");
+                }
+                let v = parsed.unwrap();
+                if v != v.trunc() {
+                    return refuse("n must be an integer");
+                }
+                if v < 0.0 || v > 40.0 {
+                    return refuse("n must be between 0 and 40");
+                }
+                n = v as i32;
+            }
+        }
         ({
             let result = fibonacci(n);
             {
@@ -17,9 +74,11 @@ pub fn lambda_handler(_request_id: &str, _body: &str) -> String {
                 {
                     let response = format!(
                         "{}{}",
-                        String::from("{\"statusCode\":200,\"body\":\"fibonacci(35)=".to_string())
+                        String::from("{\"statusCode\":200,\"body\":\"fibonacci(".to_string())
+                            + &n.to_string()
+                            + ")="
                             + &result_str,
-                        "\"}"
+                        "\",\"runtime\":\"ruchy\"}"
                     );
                     response
                 }
@@ -30,21 +30,32 @@ printf "✅ Authenticated as account: %s\\n\\n" "$ACCOUNT_ID"
 # Function to deploy Lambda
 deploy_baseline() {
     local LANG=$1
-    local FUNCTION_NAME="baseline-${LANG}"
     local RUNTIME=$2
+    local ARCH=${3:-x86_64}
+    local FUNCTION_NAME="baseline-${LANG}"
+    local PACKAGE_NAME="function.zip"
     local BUILD_DIR="${PROJECT_ROOT}/baselines/${LANG}"
 
-    printf "\\n📦 Deploying %s baseline...\\n" "$LANG"
+    if [ "$ARCH" = "arm64" ]; then
+        FUNCTION_NAME="baseline-${LANG}-arm64"
+        PACKAGE_NAME="function-arm64.zip"
+    fi
+
+    printf "\\n📦 Deploying %s baseline (%s)...\\n" "$LANG" "$ARCH"
     printf "========================================\\n"
 
     # Build package
     cd "$BUILD_DIR"
     if [ -f "build.sh" ]; then
         printf "Building %s...\\n" "$LANG"
-        ./build.sh
+        if [ "$ARCH" = "arm64" ]; then
+            ./build.sh main.go arm64
+        else
+            ./build.sh
+        fi
     fi
 
-    local PACKAGE_PATH="${BUILD_DIR}/function.zip"
+    local PACKAGE_PATH="${BUILD_DIR}/${PACKAGE_NAME}"
 
     if [ ! -f "$PACKAGE_PATH" ]; then
         printf "❌ Package not found: %s\\n" "$PACKAGE_PATH" >&2
@@ -68,6 +79,7 @@ deploy_baseline() {
             --runtime "$RUNTIME" \
             --role "$ROLE_ARN" \
             --handler "bootstrap" \
+            --architectures "$ARCH" \
             --zip-file "fileb://${PACKAGE_PATH}" \
             --timeout 30 \
             --memory-size "$MEMORY_SIZE" \
@@ -81,8 +93,9 @@ deploy_baseline() {
 # Deploy all baselines
 printf "Building and deploying baselines...\\n\\n"
 
-# Go
+# Go (x86_64 and Graviton arm64)
 deploy_baseline "go" "$RUNTIME_CUSTOM"
+deploy_baseline "go" "$RUNTIME_CUSTOM" "arm64"
 
 # Rust
 deploy_baseline "rust" "$RUNTIME_CUSTOM"